@@ -64,6 +64,16 @@ type MatchCondition struct {
 	// Header specifies the header condition to match.
 	// +optional
 	Header *HeaderMatchCondition `json:"header,omitempty"`
+
+	// QueryParameter specifies the query parameter condition to match.
+	// +optional
+	QueryParameter *QueryParameterMatchCondition `json:"queryParameter,omitempty"`
+
+	// TLS specifies the condition to match on the TLS connection
+	// of the request. It is only valid on an HTTPProxy whose root
+	// virtual host validates client certificates.
+	// +optional
+	TLS *TLSMatchCondition `json:"tls,omitempty"`
 }
 
 // HeaderMatchCondition specifies how to conditionally match against HTTP
@@ -101,6 +111,61 @@ type HeaderMatchCondition struct {
 	NotExact string `json:"notexact,omitempty"`
 }
 
+// QueryParameterMatchCondition specifies how to conditionally match against
+// HTTP query parameters. The Name field is required, but only one of the
+// remaining match fields should be be provided.
+type QueryParameterMatchCondition struct {
+	// Name is the name of the query parameter to match against. Name is required.
+	// Query parameter names are case sensitive.
+	Name string `json:"name"`
+
+	// Exact specifies a string that the query parameter value must be equal to.
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Prefix specifies a string prefix that the query parameter
+	// value must start with.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix specifies a string suffix that the query parameter
+	// value must end with.
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+
+	// Regex specifies a regular expression pattern that must match the
+	// query parameter value.
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Contains specifies a substring that must be present in
+	// the query parameter value.
+	// +optional
+	Contains string `json:"contains,omitempty"`
+
+	// IgnoreCase specifies that string matching should be case insensitive.
+	// Note that this has no effect on the Regex parameter.
+	// +optional
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+
+	// Present specifies that condition is true when the named query
+	// parameter is present, regardless of its value. Note that setting
+	// Present to false does not make the condition true if the named
+	// query parameter is absent.
+	// +optional
+	Present bool `json:"present,omitempty"`
+}
+
+// TLSMatchCondition specifies how to conditionally match against
+// properties of the TLS connection that a request was received on.
+type TLSMatchCondition struct {
+	// ClientCertificatePresented specifies that the condition is
+	// true when the client presented a certificate, if true, or
+	// when it did not, if false. A request received over plain
+	// HTTP has no client certificate.
+	ClientCertificatePresented bool `json:"clientCertificatePresented"`
+}
+
 // ExtensionServiceReference names an ExtensionService resource.
 type ExtensionServiceReference struct {
 	// API version of the referent.
@@ -206,6 +271,70 @@ type VirtualHost struct {
 	// Specifies the cross-origin policy to apply to the VirtualHost.
 	// +optional
 	CORSPolicy *CORSPolicy `json:"corsPolicy,omitempty"`
+
+	// RateLimitPolicy sets a default rate limit policy for the
+	// routes of the virtual host. This policy will be used unless
+	// overridden by individual routes.
+	// +optional
+	RateLimitPolicy *RateLimitPolicy `json:"rateLimitPolicy,omitempty"`
+
+	// ForwardProxy configures the virtual host as an egress proxy
+	// that forwards each request to the host named in its Host
+	// header, rather than to Services. The Fqdn may then be a
+	// wildcard such as "*.example.com" to allow every subdomain.
+	// A forward proxy cannot have TLS, authorization, a CORS
+	// policy, Routes, Includes or a TCPProxy, and is only
+	// available if enabled in the Contour configuration.
+	// +optional
+	ForwardProxy *ForwardProxy `json:"forwardProxy,omitempty"`
+
+	// GRPCJSONTranscoder translates JSON requests from REST clients
+	// into gRPC requests to the services of the virtual host, and
+	// translates the gRPC responses back into JSON. It can only be
+	// configured on virtual hosts that have TLS enabled.
+	// +optional
+	GRPCJSONTranscoder *GRPCJSONTranscoder `json:"grpcJSONTranscoder,omitempty"`
+
+	// ResponseTimeoutHeader names a request header whose value, in
+	// milliseconds, overrides the response timeout of the routes of
+	// the virtual host. It is only honored on requests from internal
+	// clients, which Envoy identifies by their private source address,
+	// so batch clients in the cluster can ask for longer deadlines.
+	// +optional
+	ResponseTimeoutHeader string `json:"responseTimeoutHeader,omitempty"`
+
+	// Listener is the name of an additional HTTP listener, configured
+	// in Contour's configuration file, that serves this virtual host
+	// in a route configuration of its own, instead of the default
+	// HTTP listener. It cannot be combined with TLS.
+	// +optional
+	Listener string `json:"listener,omitempty"`
+}
+
+// GRPCJSONTranscoder configures the translation of JSON requests
+// into gRPC requests, using the HTTP annotations of the gRPC
+// methods in a protobuf descriptor set.
+type GRPCJSONTranscoder struct {
+	// DescriptorSecretName is the name of a Secret in the namespace
+	// of the HTTPProxy. Its "descriptor.pb" key holds the protobuf
+	// descriptor set of the gRPC services, as generated by protoc
+	// with the --include_imports and --descriptor_set_out flags.
+	// +kubebuilder:validation:MinLength=1
+	DescriptorSecretName string `json:"descriptorSecretName"`
+	// Services are the fully qualified names of the gRPC services
+	// to transcode, for example "helloworld.Greeter".
+	// +kubebuilder:validation:MinItems=1
+	Services []string `json:"services"`
+}
+
+// ForwardProxy configures a virtual host that forwards requests to
+// the host named in each request, resolving the host with DNS.
+type ForwardProxy struct {
+	// ResponseTimeout is how long Envoy waits for the destination
+	// to respond. If omitted, Envoy's default of 15 seconds applies.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+|infinity|infinite)$`
+	ResponseTimeout string `json:"responseTimeout,omitempty"`
 }
 
 // TLS describes tls properties. The SNI names that will be matched on
@@ -216,6 +345,13 @@ type TLS struct {
 	// If specified, the named secret must contain a matching certificate
 	// for the virtual host's FQDN.
 	SecretName string `json:"secretName,omitempty"`
+	// SecondarySecretName is the name of an optional second TLS secret
+	// in the current namespace. The certificates in SecretName and
+	// SecondarySecretName must have different key types, one RSA and
+	// one ECDSA. Clients that support ECDSA are served the ECDSA
+	// certificate, and other clients are served the RSA certificate.
+	// +optional
+	SecondarySecretName string `json:"secondarySecretName,omitempty"`
 	// Minimum TLS version this vhost should negotiate
 	// +optional
 	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
@@ -230,7 +366,7 @@ type TLS struct {
 	// This setting:
 	//
 	// 1. Enables TLS client certificate validation.
-	// 2. Requires clients to present a TLS certificate (i.e. not optional validation).
+	// 2. Requires clients to present a TLS certificate, unless optionalClientCertificate is set.
 	// 3. Specifies how the client certificate will be validated.
 	// +optional
 	ClientValidation *DownstreamValidation `json:"clientValidation,omitempty"`
@@ -238,6 +374,64 @@ type TLS struct {
 	// EnableFallbackCertificate defines if the vhost should allow a default certificate to
 	// be applied which handles all requests which don't match the SNI defined in this vhost.
 	EnableFallbackCertificate bool `json:"enableFallbackCertificate,omitempty"`
+
+	// HTTPVersions restricts the HTTP versions this virtual host
+	// negotiates with clients over ALPN, overriding the default HTTP
+	// versions set in the Contour configuration. Values may be
+	// "HTTP/1.1" and "HTTP/2". If omitted, the defaults apply.
+	// +optional
+	HTTPVersions []HTTPVersion `json:"httpVersions,omitempty"`
+
+	// Shadow serves the routes of this virtual host under a second FQDN
+	// with its own certificate. This allows a new certificate, such as
+	// one issued by a staging CA, to be validated before it replaces
+	// the certificate in SecretName. Shadow cannot be combined with
+	// Passthrough.
+	// +optional
+	Shadow *ShadowVirtualHost `json:"shadow,omitempty"`
+
+	// OCSPStaplePolicy defines how Envoy staples the OCSP response
+	// stored under the "tls.ocsp-staple" key of the TLS secrets.
+	// "LenientStapling" staples the response if it is present and
+	// valid. "StrictStapling" also stops serving a certificate whose
+	// response has expired. "MustStaple" requires every certificate
+	// to have a response. If omitted, "LenientStapling" applies.
+	// +optional
+	OCSPStaplePolicy OCSPStaplePolicy `json:"ocspStaplePolicy,omitempty"`
+}
+
+// OCSPStaplePolicy is the policy for stapling OCSP responses to
+// the certificates of a virtual host.
+// +kubebuilder:validation:Enum=LenientStapling;StrictStapling;MustStaple
+type OCSPStaplePolicy string
+
+const (
+	// LenientStapling staples an OCSP response if one is present and valid.
+	LenientStapling OCSPStaplePolicy = "LenientStapling"
+
+	// StrictStapling staples an OCSP response if one is present,
+	// and does not serve a certificate whose response has expired.
+	StrictStapling OCSPStaplePolicy = "StrictStapling"
+
+	// MustStaple requires an OCSP response for every certificate.
+	MustStaple OCSPStaplePolicy = "MustStaple"
+)
+
+// HTTPVersion is an HTTP protocol version that may be negotiated with clients.
+// +kubebuilder:validation:Enum="HTTP/1.1";"HTTP/2"
+type HTTPVersion string
+
+// ShadowVirtualHost is a second, TLS only, name for a virtual host.
+type ShadowVirtualHost struct {
+	// Fqdn is the fully qualified domain name clients use to reach
+	// the shadow virtual host. It must differ from the FQDN of every
+	// root HTTPProxy.
+	// +kubebuilder:validation:MinLength=1
+	Fqdn string `json:"fqdn"`
+	// SecretName is the name of the TLS secret in the current
+	// namespace served for the shadow FQDN.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
 }
 
 // CORSHeaderValue specifies the value of the string headers returned by a cross-domain request.
@@ -280,13 +474,17 @@ type Route struct {
 	// route invalid.
 	// +optional
 	Conditions []MatchCondition `json:"conditions,omitempty"`
-	// Services are the services to proxy traffic.
-	// +kubebuilder:validation:MinItems=1
-	// +kubebuilder:validation:Required
-	Services []Service `json:"services"`
+	// Services are the services to proxy traffic. Services
+	// are required unless OriginalDestination is set.
+	// +optional
+	Services []Service `json:"services,omitempty"`
 	// Enables websocket support for the route.
 	// +optional
 	EnableWebsockets bool `json:"enableWebsockets,omitempty"`
+	// UpgradeTypes enables additional HTTP upgrade types for the route,
+	// for example "spdy/3.1". Websockets are enabled with EnableWebsockets.
+	// +optional
+	UpgradeTypes []string `json:"upgradeTypes,omitempty"`
 	// Allow this path to respond to insecure requests over HTTP which are normally
 	// not permitted when a `virtualhost.tls` block is present.
 	// +optional
@@ -305,6 +503,11 @@ type Route struct {
 	// The health check policy for this route.
 	// +optional
 	HealthCheckPolicy *HTTPHealthCheckPolicy `json:"healthCheckPolicy,omitempty"`
+	// The gRPC health check policy for this route. It may only be
+	// set if all services of the route use the h2 or h2c protocol,
+	// and cannot be combined with healthCheckPolicy.
+	// +optional
+	GRPCHealthCheckPolicy *GRPCHealthCheckPolicy `json:"grpcHealthCheckPolicy,omitempty"`
 	// The load balancing policy for this route.
 	// +optional
 	LoadBalancerPolicy *LoadBalancerPolicy `json:"loadBalancerPolicy,omitempty"`
@@ -320,6 +523,31 @@ type Route struct {
 	// Rewriting the 'Host' header is not supported.
 	// +optional
 	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+	// The policy for rate limiting on the route.
+	// +optional
+	RateLimitPolicy *RateLimitPolicy `json:"rateLimitPolicy,omitempty"`
+	// The policy for limiting the size of request bodies on the route.
+	// +optional
+	RequestBodyPolicy *RequestBodyPolicy `json:"requestBodyPolicy,omitempty"`
+	// The policy for caching responses to the route.
+	// +optional
+	CachePolicy *CachePolicy `json:"cachePolicy,omitempty"`
+	// The policy for injecting faults into requests to the route.
+	// +optional
+	FaultPolicy *FaultPolicy `json:"faultPolicy,omitempty"`
+	// OriginalDestination, if set, forwards requests to the
+	// address named in each request's x-envoy-original-dst-host
+	// header instead of to Services. It cannot be combined
+	// with Services.
+	// +optional
+	OriginalDestination *OriginalDestination `json:"originalDestination,omitempty"`
+	// ActiveServiceSet, if set, sends all of the route's traffic to
+	// the services whose serviceSet matches it. The other services
+	// on the route receive no traffic, so changing this field moves
+	// traffic between service sets, for example between blue and
+	// green deployments, in a single update.
+	// +optional
+	ActiveServiceSet string `json:"activeServiceSet,omitempty"`
 }
 
 // TCPProxy contains the set of services to proxy TCP connections.
@@ -378,6 +606,11 @@ type Service struct {
 	// UpstreamValidation defines how to verify the backend service's certificate
 	// +optional
 	UpstreamValidation *UpstreamValidation `json:"validation,omitempty"`
+	// UpstreamTLS defines the SNI and client certificate Envoy uses
+	// when establishing a TLS connection to the backend service.
+	// Only applicable when the protocol is tls or h2.
+	// +optional
+	UpstreamTLS *UpstreamTLS `json:"upstreamTLS,omitempty"`
 	// If Mirror is true the Service will receive a read only mirror of the traffic for this route.
 	Mirror bool `json:"mirror,omitempty"`
 	// The policy for managing request headers during proxying.
@@ -388,6 +621,23 @@ type Service struct {
 	// Rewriting the 'Host' header is not supported.
 	// +optional
 	ResponseHeadersPolicy *HeadersPolicy `json:"responseHeadersPolicy,omitempty"`
+	// External, if set, proxies traffic to a backend outside of the
+	// cluster rather than to a Kubernetes Service. Name then only
+	// identifies the backend in cluster names and metrics.
+	// +optional
+	External *ExternalBackend `json:"external,omitempty"`
+	// ServiceSet names the set of services this service belongs to.
+	// It is used with the route's activeServiceSet.
+	// +optional
+	ServiceSet string `json:"serviceSet,omitempty"`
+}
+
+// ExternalBackend defines a backend that is not a Kubernetes Service.
+type ExternalBackend struct {
+	// Address is the DNS name or IP address of the backend.
+	// DNS names are resolved by Envoy.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
 }
 
 // HTTPHealthCheckPolicy defines health checks on the upstream service.
@@ -414,6 +664,33 @@ type HTTPHealthCheckPolicy struct {
 	HealthyThresholdCount int64 `json:"healthyThresholdCount"`
 }
 
+// GRPCHealthCheckPolicy defines gRPC health checks on the upstream service
+// using the grpc.health.v1.Health protocol.
+type GRPCHealthCheckPolicy struct {
+	// The name of the gRPC service to check. If left empty, the
+	// overall health of the upstream server is checked.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// The value of the :authority header in the gRPC health check
+	// request. If left empty, the name of the upstream cluster is used.
+	// +optional
+	Authority string `json:"authority,omitempty"`
+	// The interval (seconds) between health checks
+	// +optional
+	IntervalSeconds int64 `json:"intervalSeconds"`
+	// The time to wait (seconds) for a health check response
+	// +optional
+	TimeoutSeconds int64 `json:"timeoutSeconds"`
+	// The number of unhealthy health checks required before a host is marked unhealthy
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UnhealthyThresholdCount int64 `json:"unhealthyThresholdCount"`
+	// The number of healthy health checks required before a host is marked healthy
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	HealthyThresholdCount int64 `json:"healthyThresholdCount"`
+}
+
 // TCPHealthCheckPolicy defines health checks on the upstream service.
 type TCPHealthCheckPolicy struct {
 	// The interval (seconds) between health checks
@@ -497,6 +774,44 @@ type RetryPolicy struct {
 	// This field is only respected when you include `retriable-status-codes` in the `RetryOn` field.
 	// +optional
 	RetriableStatusCodes []uint32 `json:"retriableStatusCodes,omitempty"`
+	// Budget limits the share of active requests to the route's
+	// services that may be retries, overriding the default
+	// retry budget set in the Contour configuration.
+	// +optional
+	Budget *RetryBudget `json:"budget,omitempty"`
+	// Backoff spaces out retry attempts with exponential backoff.
+	// If not supplied, Envoy's default backoff of 25ms base
+	// interval and 250ms maximum interval is used.
+	// +optional
+	Backoff *RetryBackoff `json:"backoff,omitempty"`
+}
+
+// RetryBackoff configures the exponential backoff between retries.
+type RetryBackoff struct {
+	// BaseInterval is the base interval between retries. Each retry
+	// waits a random time up to an interval that doubles with every
+	// attempt, starting at the base interval.
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+)$`
+	BaseInterval string `json:"baseInterval"`
+	// MaxInterval is the maximum interval between retries. It must
+	// not be less than the base interval. If not supplied, the
+	// maximum interval is ten times the base interval.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+)$`
+	MaxInterval string `json:"maxInterval,omitempty"`
+}
+
+// RetryBudget limits the number of concurrent retries to an upstream
+// as a percentage of its active requests.
+type RetryBudget struct {
+	// BudgetPercent is the percentage of active requests that may be retries.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	BudgetPercent uint32 `json:"budgetPercent"`
+	// MinRetryConcurrency is the number of concurrent retries that are
+	// always allowed, regardless of the budget.
+	// +optional
+	MinRetryConcurrency uint32 `json:"minRetryConcurrency,omitempty"`
 }
 
 // ReplacePrefix describes a path prefix replacement.
@@ -541,13 +856,257 @@ type PathRewritePolicy struct {
 type LoadBalancerPolicy struct {
 	// Strategy specifies the policy used to balance requests
 	// across the pool of backend pods. Valid policy names are
-	// `Random`, `RoundRobin`, `WeightedLeastRequest`, `Random`
-	// and `Cookie`. If an unknown strategy name is specified
-	// or no policy is supplied, the default `RoundRobin` policy
-	// is used.
+	// `Random`, `RoundRobin`, `WeightedLeastRequest`, `Random`,
+	// `Cookie`, `RequestHash` and `Maglev`. If an unknown strategy
+	// name is specified or no policy is supplied, the default
+	// `RoundRobin` policy is used.
 	Strategy string `json:"strategy,omitempty"`
+
+	// RequestHashPolicies contains a list of hash policies to apply
+	// when the `RequestHash` or `Maglev` load balancing strategy is
+	// chosen. At least one policy is required for these strategies.
+	// +optional
+	RequestHashPolicies []RequestHashPolicy `json:"requestHashPolicies,omitempty"`
+
+	// RingHash tunes the hash ring used by the `RequestHash` and
+	// `Cookie` load balancing strategies.
+	// +optional
+	RingHash *RingHashOptions `json:"ringHash,omitempty"`
+
+	// Maglev tunes the lookup table used by the `Maglev` load
+	// balancing strategy.
+	// +optional
+	Maglev *MaglevOptions `json:"maglev,omitempty"`
+}
+
+// RequestHashPolicy contains configuration for an individual hash policy
+// on a request attribute. Exactly one of HeaderHashOptions,
+// CookieHashOptions or HashSourceIP must be set.
+type RequestHashPolicy struct {
+	// Terminal is a flag that allows for short-circuiting computing of a hash
+	// for a given request. If set to true, and the request attribute specified
+	// in the attribute hash options is present, no further hash policies will
+	// be used to calculate a hash for the request.
+	// +optional
+	Terminal bool `json:"terminal,omitempty"`
+
+	// HeaderHashOptions configures a hash policy that hashes the
+	// value of a request header.
+	// +optional
+	HeaderHashOptions *HeaderHashOptions `json:"headerHashOptions,omitempty"`
+
+	// CookieHashOptions configures a hash policy that hashes the
+	// value of a request cookie.
+	// +optional
+	CookieHashOptions *CookieHashOptions `json:"cookieHashOptions,omitempty"`
+
+	// HashSourceIP, if set to true, hashes the client IP address.
+	// The client address is taken from the X-Forwarded-For header
+	// when Contour is configured to trust additional proxy hops.
+	// +optional
+	HashSourceIP bool `json:"hashSourceIP,omitempty"`
+}
+
+// HeaderHashOptions contains options to configure a HTTP request header hash
+// policy, used in request attribute hash based load balancing.
+type HeaderHashOptions struct {
+	// HeaderName is the name of the HTTP request header that will be used to
+	// calculate the hash key. If the header specified is not present on a
+	// request, no hash will be produced.
+	// +kubebuilder:validation:MinLength=1
+	HeaderName string `json:"headerName,omitempty"`
+}
+
+// CookieHashOptions contains options to configure a HTTP request cookie hash
+// policy, used in request attribute hash based load balancing.
+type CookieHashOptions struct {
+	// CookieName is the name of the cookie that will be used to
+	// calculate the hash key.
+	// +kubebuilder:validation:MinLength=1
+	CookieName string `json:"cookieName,omitempty"`
+
+	// TTL is the lifetime of a cookie that Envoy generates when
+	// the request does not have one. If not set, Envoy does not
+	// generate a cookie.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+)$`
+	TTL string `json:"ttl,omitempty"`
+
+	// Path is the path of the cookie that Envoy generates.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// RingHashOptions tunes the hash ring used by ring hash load balancing.
+type RingHashOptions struct {
+	// MinimumRingSize is the minimum number of entries in the hash
+	// ring. Larger rings distribute load more evenly at the cost
+	// of memory. Defaults to 1024.
+	// +optional
+	MinimumRingSize uint64 `json:"minimumRingSize,omitempty"`
+
+	// MaximumRingSize is the maximum number of entries in the hash
+	// ring. Defaults to 8M.
+	// +optional
+	MaximumRingSize uint64 `json:"maximumRingSize,omitempty"`
+}
+
+// MaglevOptions tunes the lookup table used by Maglev load balancing.
+type MaglevOptions struct {
+	// TableSize is the number of entries in the Maglev lookup table.
+	// It must be a prime number. Defaults to 65537.
+	// +optional
+	TableSize uint64 `json:"tableSize,omitempty"`
+}
+
+// RequestBodyPolicy defines limits on the body of requests to a route.
+type RequestBodyPolicy struct {
+	// MaxRequestBytes is the maximum size, in bytes, of a request body.
+	// Envoy buffers the request body before forwarding it upstream, and
+	// rejects requests with larger bodies with a 413 response.
+	// +kubebuilder:validation:Minimum=1
+	MaxRequestBytes uint32 `json:"maxRequestBytes"`
+}
+
+// OriginalDestination forwards requests to the address chosen
+// by a component in front of Envoy, which names it in the
+// x-envoy-original-dst-host header as "ip:port".
+type OriginalDestination struct{}
+
+// FaultPolicy defines faults that Envoy injects into requests to
+// a route, for testing how clients and services handle failures.
+type FaultPolicy struct {
+	// Delay delays a percentage of requests before they are
+	// forwarded to the upstream.
+	// +optional
+	Delay *FaultDelay `json:"delay,omitempty"`
+	// Abort answers a percentage of requests with an error
+	// status instead of forwarding them to the upstream.
+	// +optional
+	Abort *FaultAbort `json:"abort,omitempty"`
+}
+
+// FaultDelay defines a delay injected into requests.
+type FaultDelay struct {
+	// Duration is how long requests are delayed for.
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+)$`
+	Duration string `json:"duration"`
+	// Percentage is the percentage of requests that are delayed.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage uint32 `json:"percentage"`
+}
+
+// FaultAbort defines an error response injected in place of
+// the upstream response.
+type FaultAbort struct {
+	// StatusCode is the HTTP status code of the error response.
+	// +kubebuilder:validation:Minimum=200
+	// +kubebuilder:validation:Maximum=599
+	StatusCode uint32 `json:"statusCode"`
+	// Percentage is the percentage of requests that are aborted.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Percentage uint32 `json:"percentage"`
 }
 
+// CachePolicy defines how Envoy caches responses to a route.
+type CachePolicy struct {
+	// TTL is how long Envoy caches responses for. Contour sets the
+	// Cache-Control response header to "max-age=<ttl>" on responses
+	// whose upstream does not set Cache-Control itself.
+	// +kubebuilder:validation:Pattern=`^(((\d*(\.\d*)?h)|(\d*(\.\d*)?m)|(\d*(\.\d*)?s)|(\d*(\.\d*)?ms)|(\d*(\.\d*)?us)|(\d*(\.\d*)?µs)|(\d*(\.\d*)?ns))+)$`
+	TTL string `json:"ttl"`
+}
+
+// RateLimitPolicy defines rate limiting parameters.
+type RateLimitPolicy struct {
+	// Global defines global rate limiting parameters, i.e. parameters
+	// defining descriptors that are sent to an external rate limit
+	// service (RLS) for a rate limit decision on each request.
+	// +optional
+	Global *GlobalRateLimitPolicy `json:"global,omitempty"`
+}
+
+// GlobalRateLimitPolicy defines global rate limiting parameters.
+type GlobalRateLimitPolicy struct {
+	// When true, requests are not sent to the rate limit
+	// service for the scope of the policy, so a route can
+	// opt out of the policy of its virtual host. Descriptors
+	// cannot be set on a disabled policy.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Descriptors defines the list of descriptors that will
+	// be generated and sent to the rate limit service. Each
+	// descriptor contains 1+ key-value pair entries.
+	// It is required unless the policy is disabled.
+	// +optional
+	// +kubebuilder:validation:MinItems=1
+	Descriptors []RateLimitDescriptor `json:"descriptors,omitempty"`
+}
+
+// RateLimitDescriptor defines a list of key-value pair generators.
+type RateLimitDescriptor struct {
+	// Entries is the list of key-value pair generators.
+	// +required
+	// +kubebuilder:validation:MinItems=1
+	Entries []RateLimitDescriptorEntry `json:"entries,omitempty"`
+}
+
+// RateLimitDescriptorEntry is a key-value pair generator. Exactly
+// one field on this struct must be non-nil.
+type RateLimitDescriptorEntry struct {
+	// GenericKey defines a descriptor entry with a static key and value.
+	// +optional
+	GenericKey *GenericKeyDescriptor `json:"genericKey,omitempty"`
+
+	// RequestHeader defines a descriptor entry that's populated only if
+	// a given header is present on the request. The descriptor key is static,
+	// and the descriptor value is equal to the value of the header.
+	// +optional
+	RequestHeader *RequestHeaderDescriptor `json:"requestHeader,omitempty"`
+
+	// RemoteAddress defines a descriptor entry with a key of "remote_address"
+	// and a value equal to the client's IP address (from x-forwarded-for).
+	// +optional
+	RemoteAddress *RemoteAddressDescriptor `json:"remoteAddress,omitempty"`
+}
+
+// GenericKeyDescriptor defines a descriptor entry with a static key and
+// value.
+type GenericKeyDescriptor struct {
+	// Key defines the key of the descriptor entry. If not set, the
+	// key is set to "generic_key".
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Value defines the value of the descriptor entry.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Value string `json:"value,omitempty"`
+}
+
+// RequestHeaderDescriptor defines a descriptor entry that's populated only
+// if a given header is present on the request. The value of the descriptor
+// entry is equal to the value of the header (if present).
+type RequestHeaderDescriptor struct {
+	// HeaderName defines the name of the header to look for on the request.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	HeaderName string `json:"headerName,omitempty"`
+
+	// DescriptorKey defines the key to use on the descriptor entry.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	DescriptorKey string `json:"descriptorKey,omitempty"`
+}
+
+// RemoteAddressDescriptor defines a descriptor entry with a key of
+// "remote_address" and a value equal to the client's IP address
+// (from x-forwarded-for).
+type RemoteAddressDescriptor struct{}
+
 // HeadersPolicy defines how headers are managed during forwarding.
 // The `Host` header is treated specially and if set in a HTTP response
 // will be used as the SNI server name when forwarding over TLS. It is an
@@ -577,18 +1136,51 @@ type HeaderValue struct {
 // UpstreamValidation defines how to verify the backend service's certificate
 type UpstreamValidation struct {
 	// Name of the Kubernetes secret be used to validate the certificate presented by the backend
-	CACertificate string `json:"caSecret"`
+	// +optional
+	CACertificate string `json:"caSecret,omitempty"`
+	// Name of a Kubernetes ConfigMap that contains a CA certificate bundle
+	// in its `ca.crt` key. Exactly one of caSecret and caConfigMap must be set.
+	// +optional
+	CAConfigMap string `json:"caConfigMap,omitempty"`
 	// Key which is expected to be present in the 'subjectAltName' of the presented certificate
 	SubjectName string `json:"subjectName"`
 }
 
+// UpstreamTLS defines the TLS parameters Envoy uses when connecting to a backend service.
+type UpstreamTLS struct {
+	// SNI is the server name sent to the backend during the TLS handshake.
+	// It overrides the SNI derived from the Host header rewrite or external name.
+	// +optional
+	SNI string `json:"sni,omitempty"`
+	// ClientCertificate is the name of a Kubernetes secret of type kubernetes.io/tls
+	// that contains the client certificate and private key Envoy presents to the backend.
+	// It overrides the Envoy client certificate set in the Contour configuration.
+	// The secret may be in another namespace if a TLSCertificateDelegation permits it.
+	// +optional
+	ClientCertificate string `json:"clientCertificate,omitempty"`
+}
+
 // DownstreamValidation defines how to verify the client certificate.
 type DownstreamValidation struct {
 	// Name of a Kubernetes secret that contains a CA certificate bundle.
 	// The client certificate must validate against the certificates in the bundle.
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:MinLength=1
-	CACertificate string `json:"caSecret"`
+	// +optional
+	CACertificate string `json:"caSecret,omitempty"`
+	// Name of a Kubernetes ConfigMap that contains a CA certificate bundle
+	// in its `ca.crt` key. Exactly one of caSecret and caConfigMap must be set.
+	// +optional
+	CAConfigMap string `json:"caConfigMap,omitempty"`
+	// OptionalClientCertificate lets clients connect without
+	// presenting a certificate. A certificate that is presented
+	// must still validate against the CA bundle. Routes can use
+	// the tls match condition to handle such clients differently.
+	// +optional
+	OptionalClientCertificate bool `json:"optionalClientCertificate,omitempty"`
+	// Name of a Kubernetes secret that contains a PEM encoded
+	// certificate revocation list in its `crl.pem` key. Client
+	// certificates that the list revokes are rejected.
+	// +optional
+	CertificateRevocationList string `json:"crlSecret,omitempty"`
 }
 
 // HTTPProxyStatus reports the current state of the HTTPProxy.
@@ -618,6 +1210,21 @@ type HTTPProxyStatus struct {
 	// +listType=map
 	// +listMapKey=type
 	Conditions []DetailedCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// +optional
+	// ServiceSets reports the service set that is receiving traffic
+	// for each route of this HTTPProxy that has an activeServiceSet.
+	ServiceSets []ServiceSetStatus `json:"serviceSets,omitempty"`
+}
+
+// ServiceSetStatus reports the service set that is receiving
+// the traffic of a route.
+type ServiceSetStatus struct {
+	// Route is the index of the route in spec.routes.
+	Route int `json:"route"`
+	// Active is the service set that is receiving the route's traffic.
+	Active string `json:"active"`
+	// Services are the names of the services in the active set.
+	Services []string `json:"services"`
 }
 
 // +genclient
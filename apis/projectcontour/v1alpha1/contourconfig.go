@@ -0,0 +1,132 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ContourConfigurationSpec defines the settings that Contour applies
+// to every Ingress and HTTPProxy. Fields that are not set keep the
+// value from the Contour configuration file.
+type ContourConfigurationSpec struct {
+	// TLS holds the default TLS settings of secure virtual hosts.
+	//
+	// +optional
+	TLS *ContourConfigurationTLS `json:"tls,omitempty"`
+
+	// AccessLogFormat sets the format of the Envoy access logs.
+	// Values may be envoy or json.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum=envoy;json
+	AccessLogFormat string `json:"accessLogFormat,omitempty"`
+
+	// Timeouts holds the timeouts of the connections and
+	// requests that Envoy accepts.
+	//
+	// +optional
+	Timeouts *ContourConfigurationTimeouts `json:"timeouts,omitempty"`
+}
+
+// ContourConfigurationTLS holds the default TLS settings.
+type ContourConfigurationTLS struct {
+	// MinimumProtocolVersion is the minimum TLS version that
+	// secure virtual hosts accept, unless they set their own.
+	//
+	// +optional
+	// +kubebuilder:validation:Enum="1.2";"1.3"
+	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
+}
+
+// ContourConfigurationTimeouts holds the timeouts of the connections
+// and requests that Envoy accepts. Each timeout is a duration, or
+// "infinity" to disable it.
+type ContourConfigurationTimeouts struct {
+	// Request is the timeout for an entire request.
+	//
+	// +optional
+	Request string `json:"request,omitempty"`
+
+	// ConnectionIdle is how long a connection without any
+	// active requests is kept open.
+	//
+	// +optional
+	ConnectionIdle string `json:"connectionIdle,omitempty"`
+
+	// StreamIdle is how long a request or stream without
+	// any activity is kept open.
+	//
+	// +optional
+	StreamIdle string `json:"streamIdle,omitempty"`
+
+	// MaxConnectionDuration is how long a connection is kept
+	// open, whether it is active or not.
+	//
+	// +optional
+	MaxConnectionDuration string `json:"maxConnectionDuration,omitempty"`
+
+	// ConnectionShutdownGracePeriod is how long Envoy waits
+	// between the two GOAWAY frames it sends when it closes
+	// an HTTP/2 connection.
+	//
+	// +optional
+	ConnectionShutdownGracePeriod string `json:"connectionShutdownGracePeriod,omitempty"`
+}
+
+// ContourConfigurationStatus defines the observed state of a
+// ContourConfiguration resource.
+type ContourConfigurationStatus struct {
+	// Conditions contains the current status of the ContourConfiguration resource.
+	//
+	// Contour will update a single condition, `Valid`, that is in normal-true polarity.
+	//
+	// Contour will not modify any other Conditions set in this block,
+	// in case some other controller wants to add a Condition.
+	//
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []contour_api_v1.DetailedCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced,shortName=contourconfig;contourconfigs
+
+// ContourConfiguration is the schema for the Contour configuration API.
+// A ContourConfiguration resource holds settings that would otherwise
+// be set in the Contour configuration file or with command line flags,
+// so that they can be managed like other Kubernetes resources.
+type ContourConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ContourConfigurationSpec   `json:"spec,omitempty"`
+	Status ContourConfigurationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ContourConfigurationList contains a list of ContourConfiguration resources.
+type ContourConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ContourConfiguration `json:"items"`
+}
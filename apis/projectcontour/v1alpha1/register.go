@@ -21,6 +21,8 @@ import (
 
 var ExtensionServiceGVR = GroupVersion.WithResource("extensionservices")
 
+var ContourConfigurationGVR = GroupVersion.WithResource("contourconfigurations")
+
 var (
 	// GroupVersion is group version used to register these objects
 	GroupVersion = schema.GroupVersion{Group: "projectcontour.io", Version: "v1alpha1"}
@@ -35,6 +37,8 @@ var (
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(
 		GroupVersion,
+		&ContourConfiguration{},
+		&ContourConfigurationList{},
 		&ExtensionService{},
 		&ExtensionServiceList{},
 	)
@@ -14,6 +14,10 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/projectcontour/contour/internal/envoy"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
@@ -21,19 +25,61 @@ import (
 // registerBootstrap registers the bootstrap subcommand and flags
 // with the Application provided.
 func registerBootstrap(app *kingpin.Application) (*kingpin.CmdClause, *envoy.BootstrapConfig) {
-	var config envoy.BootstrapConfig
+	config := envoy.BootstrapConfig{
+		ListenerMaxConnections: map[string]uint64{},
+	}
 
 	bootstrap := app.Command("bootstrap", "Generate bootstrap configuration.")
 	bootstrap.Arg("path", "Configuration file ('-' for standard output).").Required().StringVar(&config.Path)
 	bootstrap.Flag("resources-dir", "Directory where configuration files will be written to.").StringVar(&config.ResourcesDir)
 	bootstrap.Flag("admin-address", "Envoy admin interface address.").StringVar(&config.AdminAddress)
 	bootstrap.Flag("admin-port", "Envoy admin interface port.").IntVar(&config.AdminPort)
+	bootstrap.Flag("admin-socket-path", "Unix domain socket path for the Envoy admin interface.").StringVar(&config.AdminSocketPath)
+	bootstrap.Flag("disable-admin", "Disable the Envoy admin interface.").BoolVar(&config.AdminDisabled)
 	bootstrap.Flag("xds-address", "xDS gRPC API address.").StringVar(&config.XDSAddress)
 	bootstrap.Flag("xds-port", "xDS gRPC API port.").IntVar(&config.XDSGRPCPort)
+	bootstrap.Flag("stats-sink", "Stats sink type that Envoy flushes statistics to.").EnumVar((*string)(&config.StatsSink), string(envoy.StatsdStatsSink), string(envoy.DogStatsdStatsSink))
+	bootstrap.Flag("stats-sink-address", "Stats sink UDP address.").StringVar(&config.StatsSinkAddress)
+	bootstrap.Flag("stats-sink-port", "Stats sink UDP port.").IntVar(&config.StatsSinkPort)
+	bootstrap.Flag("stats-sink-prefix", "Prefix for statistic names sent to the stats sink.").StringVar(&config.StatsSinkPrefix)
+	bootstrap.Flag("stats-tag", "Fixed tag added to all Envoy statistics, as name=value (may be repeated).").StringMapVar(&config.StatsTags)
+	bootstrap.Flag("overload-max-heap", "Maximum heap size in bytes, after which Envoy stops accepting requests.").Uint64Var(&config.OverloadMaxHeapBytes)
+	bootstrap.Flag("overload-max-downstream-conn", "Maximum number of active downstream connections across all listeners.").Uint64Var(&config.OverloadMaxDownstreamConnections)
+	bootstrap.Flag("listener-max-conn", "Maximum number of active connections to a listener, as name=value (may be repeated).").SetValue(uint64Map(config.ListenerMaxConnections))
 	bootstrap.Flag("envoy-cafile", "CA Filename for Envoy secure xDS gRPC communication.").Envar("ENVOY_CAFILE").StringVar(&config.GrpcCABundle)
 	bootstrap.Flag("envoy-cert-file", "Client certificate filename for Envoy secure xDS gRPC communication.").Envar("ENVOY_CERT_FILE").StringVar(&config.GrpcClientCert)
 	bootstrap.Flag("envoy-key-file", "Client key filename for Envoy secure xDS gRPC communication.").Envar("ENVOY_KEY_FILE").StringVar(&config.GrpcClientKey)
+	bootstrap.Flag("spire-agent-socket", "Unix domain socket path of the SPIRE agent's SDS API.").StringVar(&config.SPIREAgentSocketPath)
+	bootstrap.Flag("locality-region", "Region of the Envoy node, for zone aware routing.").StringVar(&config.LocalityRegion)
+	bootstrap.Flag("locality-zone", "Zone of the Envoy node, for zone aware routing.").StringVar(&config.LocalityZone)
 	bootstrap.Flag("namespace", "The namespace the Envoy container will run in.").Envar("CONTOUR_NAMESPACE").Default("projectcontour").StringVar(&config.Namespace)
 	bootstrap.Flag("xds-resource-version", "The versions of the xDS resources to request from Contour.").Default("v3").StringVar((*string)(&config.XDSResourceVersion))
 	return bootstrap, &config
 }
+
+// uint64Map is a repeatable flag value of name=value pairs
+// with unsigned integer values.
+type uint64Map map[string]uint64
+
+func (m uint64Map) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+
+	n, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for %q: %w", parts[0], err)
+	}
+
+	m[parts[0]] = n
+	return nil
+}
+
+func (m uint64Map) String() string {
+	return fmt.Sprintf("%v", map[string]uint64(m))
+}
+
+func (m uint64Map) IsCumulative() bool {
+	return true
+}
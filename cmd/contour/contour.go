@@ -59,6 +59,10 @@ func main() {
 	sds := cli.Command("sds", "Watch secrets.")
 	sds.Arg("resources", "SDS resource filter").StringsVar(&resources)
 
+	lint, lintCtx := registerLint(app)
+
+	outputsSchema, outputsCtx := registerOutputs(app)
+
 	serve, serveCtx := registerServe(app)
 	version := app.Command("version", "Build information for Contour.")
 
@@ -93,6 +97,18 @@ func main() {
 	case sds.FullCommand():
 		stream := client.RouteStream()
 		watchstream(stream, resource_v3.SecretType, resources)
+	case lint.FullCommand():
+		problems, err := doLint(lintCtx, os.Stdout)
+		if err != nil {
+			log.WithError(err).Fatal("failed to lint manifests")
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+	case outputsSchema.FullCommand():
+		if err := doOutputsSchema(outputsCtx, os.Stdout); err != nil {
+			log.WithError(err).Fatal("failed to write output schema")
+		}
 	case serve.FullCommand():
 		// Parse args a second time so cli flags are applied
 		// on top of any values sourced from -c's config file.
@@ -0,0 +1,140 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// loadContourConfiguration applies the spec of the named
+// ContourConfiguration to params, and records whether the
+// resulting configuration is valid in its status. params is
+// unchanged if the configuration is not valid.
+func loadContourConfiguration(
+	clients *k8s.Clients,
+	converter *k8s.UnstructuredConverter,
+	name types.NamespacedName,
+	params *config.Parameters,
+) error {
+	client := clients.DynamicClient().Resource(contour_api_v1alpha1.ContourConfigurationGVR).Namespace(name.Namespace)
+
+	var applied config.Parameters
+	var invalid error
+
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		res, err := client.Get(context.Background(), name.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting contour configuration %s: %w", name, err)
+		}
+
+		obj, err := converter.FromUnstructured(res)
+		if err != nil {
+			return fmt.Errorf("error converting contour configuration %s: %w", name, err)
+		}
+
+		cc, ok := obj.(*contour_api_v1alpha1.ContourConfiguration)
+		if !ok {
+			return fmt.Errorf("error converting contour configuration %s", name)
+		}
+
+		applied = *params
+		applyContourConfiguration(&applied, &cc.Spec)
+		invalid = applied.Validate()
+
+		cc = cc.DeepCopy()
+		setContourConfigurationStatus(cc, invalid)
+
+		us, err := converter.ToUnstructured(cc)
+		if err != nil {
+			return fmt.Errorf("error converting contour configuration %s: %w", name, err)
+		}
+
+		_, err = client.UpdateStatus(context.Background(), us, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if invalid != nil {
+		return fmt.Errorf("invalid contour configuration %s: %w", name, invalid)
+	}
+
+	*params = applied
+	return nil
+}
+
+// applyContourConfiguration overrides the parameters that are
+// set in the supplied ContourConfiguration spec.
+func applyContourConfiguration(params *config.Parameters, spec *contour_api_v1alpha1.ContourConfigurationSpec) {
+	if spec.TLS != nil && spec.TLS.MinimumProtocolVersion != "" {
+		params.TLS.MinimumProtocolVersion = spec.TLS.MinimumProtocolVersion
+	}
+
+	if spec.AccessLogFormat != "" {
+		params.AccessLogFormat = config.AccessLogType(spec.AccessLogFormat)
+	}
+
+	if t := spec.Timeouts; t != nil {
+		set := func(param *string, value string) {
+			if value != "" {
+				*param = value
+			}
+		}
+
+		set(&params.Timeouts.RequestTimeout, t.Request)
+		set(&params.Timeouts.ConnectionIdleTimeout, t.ConnectionIdle)
+		set(&params.Timeouts.StreamIdleTimeout, t.StreamIdle)
+		set(&params.Timeouts.MaxConnectionDuration, t.MaxConnectionDuration)
+		set(&params.Timeouts.ConnectionShutdownGracePeriod, t.ConnectionShutdownGracePeriod)
+	}
+}
+
+// setContourConfigurationStatus sets the Valid condition of cc
+// from the result of validating the configuration.
+func setContourConfigurationStatus(cc *contour_api_v1alpha1.ContourConfiguration, invalid error) {
+	cond := contour_api_v1.DetailedCondition{
+		Condition: contour_api_v1.Condition{
+			Type:               contour_api_v1.ValidConditionType,
+			Status:             contour_api_v1.ConditionTrue,
+			ObservedGeneration: cc.Generation,
+			LastTransitionTime: metav1.NewTime(time.Now()),
+			Reason:             "Valid",
+			Message:            "Valid ContourConfiguration",
+		},
+	}
+
+	if invalid != nil {
+		cond.AddError(contour_api_v1.ConditionTypeSpecError, "ConfigurationNotValid", invalid.Error())
+	}
+
+	if curr := cc.Status.GetConditionFor(contour_api_v1.ValidConditionType); curr != nil {
+		if curr.Status == cond.Status {
+			cond.LastTransitionTime = curr.LastTransitionTime
+		}
+		cond.DeepCopyInto(curr)
+		return
+	}
+
+	cc.Status.Conditions = append(cc.Status.Conditions, cond)
+}
@@ -0,0 +1,70 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"github.com/projectcontour/contour/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyContourConfiguration(t *testing.T) {
+	params := config.Defaults()
+	params.Timeouts.RequestTimeout = "30s"
+	params.Timeouts.StreamIdleTimeout = "5m"
+
+	applyContourConfiguration(&params, &contour_api_v1alpha1.ContourConfigurationSpec{
+		TLS: &contour_api_v1alpha1.ContourConfigurationTLS{
+			MinimumProtocolVersion: "1.3",
+		},
+		AccessLogFormat: "json",
+		Timeouts: &contour_api_v1alpha1.ContourConfigurationTimeouts{
+			Request:        "infinity",
+			ConnectionIdle: "60s",
+		},
+	})
+
+	assert.Equal(t, "1.3", params.TLS.MinimumProtocolVersion)
+	assert.Equal(t, config.JSONAccessLog, params.AccessLogFormat)
+	assert.Equal(t, "infinity", params.Timeouts.RequestTimeout)
+	assert.Equal(t, "60s", params.Timeouts.ConnectionIdleTimeout)
+
+	// Fields that are not set keep their value.
+	assert.Equal(t, "5m", params.Timeouts.StreamIdleTimeout)
+}
+
+func TestSetContourConfigurationStatus(t *testing.T) {
+	cc := &contour_api_v1alpha1.ContourConfiguration{}
+
+	setContourConfigurationStatus(cc, nil)
+	assert.Len(t, cc.Status.Conditions, 1)
+	assert.Equal(t, contour_api_v1.ConditionTrue, cc.Status.Conditions[0].Status)
+
+	// The existing condition is replaced.
+	setContourConfigurationStatus(cc, errors.New("invalid request timeout"))
+	assert.Len(t, cc.Status.Conditions, 1)
+
+	cond := cc.Status.Conditions[0]
+	assert.Equal(t, contour_api_v1.ConditionFalse, cond.Status)
+	assert.Equal(t, []contour_api_v1.SubCondition{{
+		Type:    contour_api_v1.ConditionTypeSpecError,
+		Status:  contour_api_v1.ConditionTrue,
+		Reason:  "ConfigurationNotValid",
+		Message: "invalid request timeout",
+	}}, cond.Errors)
+}
@@ -0,0 +1,127 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Register the Any types used by the default
+	// listeners, so that they can be rendered as JSON.
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/gzip/compressor/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/xdscache"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// TestGolden translates each manifest in testdata/golden and compares
+// the resulting Envoy configuration with the matching .golden file.
+// Run with -update to regenerate the golden files after an intended
+// change to the translated configuration.
+func TestGolden(t *testing.T) {
+	manifests, err := filepath.Glob(filepath.Join("testdata", "golden", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, manifests)
+
+	for _, manifest := range manifests {
+		manifest := manifest
+		t.Run(filepath.Base(manifest), func(t *testing.T) {
+			got := translateManifests(t, manifest)
+			golden := strings.TrimSuffix(manifest, ".yaml") + ".golden"
+
+			if *update {
+				require.NoError(t, ioutil.WriteFile(golden, got, 0644)) // nolint:gosec
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			require.NoError(t, err, "run with -update to create the golden file")
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
+
+// translateManifests builds the DAG for the objects in the given
+// manifests and renders the resulting xDS resources as YAML.
+func translateManifests(t *testing.T, paths ...string) []byte {
+	t.Helper()
+
+	objs, err := loadManifests(paths)
+	require.NoError(t, err)
+
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: log,
+		},
+		Processors: []dag.Processor{
+			&dag.IngressProcessor{
+				FieldLogger: log,
+			},
+			&dag.ExtensionServiceProcessor{
+				FieldLogger: log,
+			},
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{},
+		},
+	}
+
+	for _, obj := range objs {
+		builder.Source.Insert(obj)
+	}
+
+	listeners := xdscache_v3.NewListenerCache(xdscache_v3.ListenerConfig{}, "0.0.0.0", 8002)
+	routes := &xdscache_v3.RouteCache{}
+	clusters := &xdscache_v3.ClusterCache{}
+	secrets := &xdscache_v3.SecretCache{}
+
+	resources := []xdscache.ResourceCache{listeners, routes, clusters, secrets}
+	dag.ComposeObservers(xdscache.ObserversOf(resources)...).OnChange(builder.Build())
+
+	out, err := yaml.Marshal(map[string][]interface{}{
+		"listeners": toYAML(t, listeners.Contents()),
+		"routes":    toYAML(t, routes.Contents()),
+		"clusters":  toYAML(t, clusters.Contents()),
+		"secrets":   toYAML(t, secrets.Contents()),
+	})
+	require.NoError(t, err)
+
+	return out
+}
+
+// toYAML converts each message to a value that marshals
+// to the YAML form of the message's JSON representation.
+func toYAML(t *testing.T, msgs []proto.Message) []interface{} {
+	values := make([]interface{}, 0, len(msgs))
+	for _, msg := range msgs {
+		var v interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(protobuf.MustMarshalJSON(msg)), &v))
+		values = append(values, v)
+	}
+	return values
+}
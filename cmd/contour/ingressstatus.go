@@ -22,6 +22,8 @@ import (
 	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -51,7 +53,12 @@ type loadBalancerStatusWriter struct {
 	lbStatus      chan v1.LoadBalancerStatus
 	statusUpdater k8s.StatusUpdater
 	ingressClass  string
+	ingressGVR    schema.GroupVersionResource
 	Converter     k8s.Converter
+
+	// domainSuffixes restricts the updated objects to those
+	// with a host that this Contour instance owns.
+	domainSuffixes []string
 }
 
 func (isw *loadBalancerStatusWriter) Start(stop <-chan struct{}) error {
@@ -75,16 +82,17 @@ func (isw *loadBalancerStatusWriter) Start(stop <-chan struct{}) error {
 
 			return log
 		}(),
-		IngressClass:  isw.ingressClass,
-		StatusUpdater: isw.statusUpdater,
-		Converter:     isw.Converter,
+		IngressClass:   isw.ingressClass,
+		StatusUpdater:  isw.statusUpdater,
+		Converter:      isw.Converter,
+		DomainSuffixes: isw.domainSuffixes,
 	}
 
 	// Create informers for the types that need load balancer
 	// address status. The client should have already started
 	// informers, so new informers will auto-start.
 	for _, r := range []schema.GroupVersionResource{
-		v1beta1.SchemeGroupVersion.WithResource("ingresses"),
+		isw.ingressGVR,
 		contour_api_v1.HTTPProxyGVR,
 	} {
 		inf, err := isw.clients.InformerForResource(r)
@@ -110,13 +118,12 @@ func (isw *loadBalancerStatusWriter) Start(stop <-chan struct{}) error {
 
 			u.Set(lbs)
 
-			var ingressList v1beta1.IngressList
 			var proxyList contour_api_v1.HTTPProxyList
 
-			if err := isw.clients.Cache().List(context.Background(), &ingressList); err != nil {
+			if ingresses, err := isw.listIngresses(); err != nil {
 				isw.log.WithError(err).WithField("kind", "Ingress").Error("failed to list objects")
 			} else {
-				for _, i := range ingressList.Items {
+				for _, i := range ingresses {
 					u.OnAdd(i)
 				}
 			}
@@ -132,6 +139,41 @@ func (isw *loadBalancerStatusWriter) Start(stop <-chan struct{}) error {
 	}
 }
 
+// listIngresses lists the Ingress objects of the watched
+// Ingress API version from the informer cache.
+func (isw *loadBalancerStatusWriter) listIngresses() ([]interface{}, error) {
+	var ingresses []interface{}
+
+	switch isw.ingressGVR.GroupVersion() {
+	case networking_v1.SchemeGroupVersion:
+		var list networking_v1.IngressList
+		if err := isw.clients.Cache().List(context.Background(), &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	case extensions_v1beta1.SchemeGroupVersion:
+		var list extensions_v1beta1.IngressList
+		if err := isw.clients.Cache().List(context.Background(), &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	default:
+		var list v1beta1.IngressList
+		if err := isw.clients.Cache().List(context.Background(), &list); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			ingresses = append(ingresses, &list.Items[i])
+		}
+	}
+
+	return ingresses, nil
+}
+
 func parseStatusFlag(status string) v1.LoadBalancerStatus {
 	// Support ','-separated lists.
 	var ingresses []v1.LoadBalancerIngress
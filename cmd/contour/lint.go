@@ -0,0 +1,264 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/internal/status"
+	"github.com/sirupsen/logrus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	v1 "k8s.io/api/core/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// registerLint registers the lint subcommand and flags
+// with the Application provided.
+func registerLint(app *kingpin.Application) (*kingpin.CmdClause, *lintContext) {
+	var ctx lintContext
+
+	lint := app.Command("lint", "Validate Ingress and HTTPProxy manifests without a Kubernetes cluster.")
+	lint.Flag("ingress-class-name", "Contour IngressClass name.").StringVar(&ctx.IngressClass)
+	lint.Flag("root-namespaces", "Restrict root HTTPProxies to these namespaces.").StringVar(&ctx.RootNamespaces)
+	lint.Arg("paths", "YAML or JSON manifest files, or directories containing them.").Required().ExistingFilesOrDirsVar(&ctx.Paths)
+
+	return lint, &ctx
+}
+
+// lintContext holds the configuration for the lint command.
+type lintContext struct {
+	// Paths are the manifest files and directories to validate.
+	Paths []string
+
+	// IngressClass is the Contour ingress class name.
+	IngressClass string
+
+	// RootNamespaces is a comma separated list of
+	// namespaces that may contain root HTTPProxies.
+	RootNamespaces string
+}
+
+// lintHook collects the errors that Contour logs when it skips
+// all or part of an object during translation.
+type lintHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *lintHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel}
+}
+
+func (h *lintHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+// doLint loads the manifests in ctx.Paths, builds a DAG from them and
+// writes each problem found to out. It returns the number of problems.
+func doLint(ctx *lintContext, out io.Writer) (int, error) {
+	objs, err := loadManifests(ctx.Paths)
+	if err != nil {
+		return 0, err
+	}
+
+	hook := &lintHook{}
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	log.AddHook(hook)
+
+	var rootNamespaces []string
+	for _, ns := range strings.Split(ctx.RootNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			rootNamespaces = append(rootNamespaces, ns)
+		}
+	}
+
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			RootNamespaces: rootNamespaces,
+			IngressClass:   ctx.IngressClass,
+			FieldLogger:    log,
+		},
+		Processors: []dag.Processor{
+			&dag.IngressProcessor{
+				FieldLogger: log.WithField("kind", "Ingress"),
+			},
+			&dag.ExtensionServiceProcessor{
+				FieldLogger: log.WithField("kind", "ExtensionService"),
+			},
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{},
+		},
+	}
+
+	for _, obj := range objs {
+		builder.Source.Insert(obj)
+	}
+
+	d := builder.Build()
+
+	var problems []string
+	for _, entry := range hook.entries {
+		problems = append(problems, formatLogProblem(entry))
+	}
+
+	for _, pu := range d.StatusCache.GetProxyUpdates() {
+		validCond := pu.ConditionFor(status.ValidCondition)
+		if validCond.Status != contour_api_v1.ConditionFalse {
+			continue
+		}
+		for _, e := range validCond.Errors {
+			problems = append(problems, fmt.Sprintf("HTTPProxy %s: %s: %s", pu.Fullname, e.Reason, e.Message))
+		}
+	}
+
+	sort.Strings(problems)
+	for _, p := range problems {
+		fmt.Fprintln(out, p)
+	}
+
+	return len(problems), nil
+}
+
+// formatLogProblem renders a translation error logged by Contour as a
+// single line, identifying the object by the logged kind and name.
+func formatLogProblem(entry *logrus.Entry) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s/%s: %s", entry.Data["kind"], entry.Data["namespace"], entry.Data["name"], entry.Message)
+
+	var keys []string
+	for k := range entry.Data {
+		switch k {
+		case "kind", "namespace", "name", "version", logrus.ErrorKey:
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, entry.Data[k])
+	}
+
+	if err, ok := entry.Data[logrus.ErrorKey]; ok && err != nil {
+		fmt.Fprintf(&b, ": %v", err)
+	}
+
+	return b.String()
+}
+
+// loadManifests decodes the objects that Contour translates from the
+// manifests in paths. Directories are searched for files with a YAML
+// or JSON extension. Objects of other kinds are ignored.
+func loadManifests(paths []string) ([]interface{}, error) {
+	scheme, err := k8s.NewContourScheme()
+	if err != nil {
+		return nil, err
+	}
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			// Files named explicitly are always loaded.
+			switch filepath.Ext(p) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, p)
+			default:
+				if p == path {
+					files = append(files, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objs []interface{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		reader := yaml.NewYAMLReader(bufio.NewReader(f))
+		for {
+			doc, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+
+			obj, _, err := decoder.Decode(doc, nil, nil)
+			switch {
+			case runtime.IsNotRegisteredError(err), runtime.IsMissingKind(err):
+				// Not a kind that Contour translates.
+				continue
+			case err != nil:
+				f.Close()
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			switch obj := obj.(type) {
+			case *v1.Secret, *v1.Service,
+				*v1beta1.Ingress, *networking_v1.Ingress, *extensions_v1beta1.Ingress,
+				*contour_api_v1.HTTPProxy, *contour_api_v1.TLSCertificateDelegation,
+				*contour_api_v1alpha1.ExtensionService:
+				// Objects without a namespace are created in the
+				// default namespace, as they would be by kubectl.
+				if meta := obj.(k8s.Object).GetObjectMeta(); meta.GetNamespace() == "" {
+					meta.SetNamespace("default")
+				}
+				objs = append(objs, obj)
+			}
+		}
+
+		f.Close()
+	}
+
+	return objs, nil
+}
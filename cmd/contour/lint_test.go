@@ -0,0 +1,138 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintService = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: kuard
+  namespace: default
+spec:
+  ports:
+  - name: http
+    port: 80
+`
+
+func TestLint(t *testing.T) {
+	tests := map[string]struct {
+		manifests string
+		want      string
+	}{
+		"valid manifests": {
+			manifests: lintService + `
+---
+# Kinds that Contour does not translate are ignored.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kuard
+---
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: kuard
+spec:
+  virtualhost:
+    fqdn: kuard.example.com
+  routes:
+  - services:
+    - name: kuard
+      port: 80
+`,
+			want: "",
+		},
+		"ingress with missing secret and service": {
+			manifests: `
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: broken
+  namespace: default
+spec:
+  tls:
+  - hosts:
+    - example.com
+    secretName: missing
+  rules:
+  - host: example.com
+    http:
+      paths:
+      - backend:
+          serviceName: missing
+          servicePort: 80
+`,
+			want: `Ingress default/broken: unresolved secret reference secret=default/missing: Secret not found
+Ingress default/broken: unresolved service reference: service "default/missing" not found
+`,
+		},
+		"httpproxy without fqdn": {
+			manifests: lintService + `
+---
+apiVersion: projectcontour.io/v1
+kind: HTTPProxy
+metadata:
+  name: nofqdn
+spec:
+  virtualhost: {}
+  routes:
+  - services:
+    - name: kuard
+      port: 80
+`,
+			want: `HTTPProxy default/nofqdn: FQDNNotSpecified: Spec.VirtualHost.Fqdn must be specified
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "lint")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "manifests.yaml"), []byte(tc.manifests), 0600))
+
+			var out bytes.Buffer
+			problems, err := doLint(&lintContext{Paths: []string{dir}}, &out)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, out.String())
+			assert.Equal(t, tc.want == "", problems == 0)
+		})
+	}
+}
+
+func TestLintInvalidManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("kind: HTTPProxy\napiVersion: projectcontour.io/v1\nspec: [\n"), 0600))
+
+	_, err = doLint(&lintContext{Paths: []string{path}}, ioutil.Discard)
+	assert.Error(t, err)
+}
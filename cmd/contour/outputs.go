@@ -0,0 +1,324 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/projectcontour/contour/internal/build"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/pkg/config"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// registerOutputs registers the outputs subcommands and flags
+// with the Application provided.
+func registerOutputs(app *kingpin.Application) (*kingpin.CmdClause, *outputsContext) {
+	var ctx outputsContext
+
+	outputs := app.Command("outputs", "Describe the configuration that Contour emits.")
+	schema := outputs.Command("schema", "Write a JSON schema of the xDS resources Contour serves to Envoy.")
+	schema.Flag("xds-resource-version", "The version of the xDS resources to describe.").Default(string(config.XDSv3)).StringVar((*string)(&ctx.XDSResourceVersion))
+
+	return schema, &ctx
+}
+
+// outputsContext holds the configuration for the outputs command.
+type outputsContext struct {
+	// XDSResourceVersion is the version of the xDS
+	// resources to describe.
+	XDSResourceVersion config.ResourceVersion
+}
+
+// xdsResourceTypes are the type URLs of the resources that Contour
+// serves, for each xDS resource version.
+var xdsResourceTypes = map[config.ResourceVersion][]string{
+	config.XDSv3: {
+		resource_v3.ClusterType,
+		resource_v3.EndpointType,
+		resource_v3.ListenerType,
+		resource_v3.RouteType,
+		envoy_v3.ScopedRouteType,
+		resource_v3.SecretType,
+	},
+}
+
+// xdsExtensionTypes are the messages that Contour embeds in the
+// typed_config fields of the resources it serves, for each xDS
+// resource version.
+var xdsExtensionTypes = map[config.ResourceVersion][]protoreflect.FullName{
+	config.XDSv3: envoy_v3.ExtensionTypes,
+}
+
+// xdsEmptyExtensionTypes are the messages that Contour embeds in
+// typed_config fields without configuration, and whose definitions
+// are not available, for each xDS resource version.
+var xdsEmptyExtensionTypes = map[config.ResourceVersion][]protoreflect.FullName{
+	config.XDSv3: envoy_v3.EmptyExtensionTypes,
+}
+
+// outputSchema is a JSON schema (draft-07) that describes the
+// resources Contour serves, in their proto3 JSON mapping.
+type outputSchema struct {
+	Schema             string                 `json:"$schema"`
+	Title              string                 `json:"title"`
+	ContourVersion     string                 `json:"contourVersion"`
+	XDSResourceVersion config.ResourceVersion `json:"xdsResourceVersion"`
+
+	// Resources maps each xDS type URL to the definition
+	// of its resource message.
+	Resources map[string]*jsonSchema `json:"resources"`
+
+	// Extensions maps the type URL of each message that may
+	// appear in a typed_config field to its definition.
+	Extensions map[string]*jsonSchema `json:"extensions"`
+
+	Definitions map[string]*jsonSchema `json:"definitions"`
+}
+
+// jsonSchema is the subset of a JSON schema that is needed to
+// describe a proto3 message.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	OneOf                [][]string             `json:"x-oneof,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+}
+
+// doOutputsSchema writes the schema of the resources that Contour
+// serves for the configured xDS resource version to w.
+func doOutputsSchema(ctx *outputsContext, w io.Writer) error {
+	schema, err := buildOutputSchema(ctx.XDSResourceVersion)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+func buildOutputSchema(version config.ResourceVersion) (*outputSchema, error) {
+	if err := version.Validate(); err != nil {
+		return nil, err
+	}
+
+	schema := &outputSchema{
+		Schema:             "http://json-schema.org/draft-07/schema#",
+		Title:              "Contour xDS " + string(version) + " resources",
+		ContourVersion:     build.Version,
+		XDSResourceVersion: version,
+		Resources:          map[string]*jsonSchema{},
+		Extensions:         map[string]*jsonSchema{},
+		Definitions:        map[string]*jsonSchema{},
+	}
+
+	for _, typeURL := range xdsResourceTypes[version] {
+		md, err := findMessage(protoreflect.FullName(strings.TrimPrefix(typeURL, typeURLPrefix)))
+		if err != nil {
+			return nil, err
+		}
+		schema.Resources[typeURL] = schema.define(md)
+	}
+
+	for _, name := range xdsExtensionTypes[version] {
+		md, err := findMessage(name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Extensions[typeURLPrefix+string(name)] = schema.define(md)
+	}
+
+	for _, name := range xdsEmptyExtensionTypes[version] {
+		schema.Extensions[typeURLPrefix+string(name)] = &jsonSchema{Type: "object"}
+	}
+
+	return schema, nil
+}
+
+const typeURLPrefix = "type.googleapis.com/"
+
+// findMessage returns the descriptor of the named message from
+// the messages that are linked into this binary.
+func findMessage(name protoreflect.FullName) (protoreflect.MessageDescriptor, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message %q: %w", name, err)
+	}
+
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message", name)
+	}
+	return md, nil
+}
+
+// define adds the definition of the message, and of every message
+// that it refers to, to the schema and returns a reference to it.
+func (s *outputSchema) define(md protoreflect.MessageDescriptor) *jsonSchema {
+	if wk := wellKnownSchema(md); wk != nil {
+		return wk
+	}
+
+	name := string(md.FullName())
+	ref := &jsonSchema{Ref: "#/definitions/" + name}
+	if _, ok := s.Definitions[name]; ok {
+		return ref
+	}
+
+	def := &jsonSchema{
+		Type:       "object",
+		Properties: map[string]*jsonSchema{},
+		Deprecated: isDeprecated(md),
+	}
+
+	// Add the definition before visiting the fields,
+	// since messages may refer to themselves.
+	s.Definitions[name] = def
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		prop := s.field(fd)
+		prop.Deprecated = isDeprecated(fd)
+		def.Properties[fd.JSONName()] = prop
+	}
+
+	oneofs := md.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		var members []string
+		for j := 0; j < od.Fields().Len(); j++ {
+			members = append(members, od.Fields().Get(j).JSONName())
+		}
+		sort.Strings(members)
+		def.OneOf = append(def.OneOf, members)
+	}
+
+	return ref
+}
+
+// field returns the schema of the JSON value of a field.
+func (s *outputSchema) field(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch {
+	case fd.IsMap():
+		return &jsonSchema{
+			Type:                 "object",
+			AdditionalProperties: s.singular(fd.MapValue()),
+		}
+	case fd.IsList():
+		return &jsonSchema{
+			Type:  "array",
+			Items: s.singular(fd),
+		}
+	default:
+		return s.singular(fd)
+	}
+}
+
+// singular returns the schema of a single value of a field.
+func (s *outputSchema) singular(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &jsonSchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &jsonSchema{Type: "integer"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// The proto3 JSON mapping encodes 64 bit integers as strings.
+		return &jsonSchema{Type: "string", Format: "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return &jsonSchema{Type: "number"}
+	case protoreflect.StringKind:
+		return &jsonSchema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &jsonSchema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		return enumSchema(fd.Enum())
+	default:
+		return s.define(fd.Message())
+	}
+}
+
+func enumSchema(ed protoreflect.EnumDescriptor) *jsonSchema {
+	values := ed.Values()
+	schema := &jsonSchema{Type: "string"}
+	for i := 0; i < values.Len(); i++ {
+		schema.Enum = append(schema.Enum, string(values.Get(i).Name()))
+	}
+	return schema
+}
+
+// wellKnownSchema returns the schema of the well known types that
+// have a special JSON mapping, or nil if md is not one of them.
+func wellKnownSchema(md protoreflect.MessageDescriptor) *jsonSchema {
+	switch md.FullName() {
+	case "google.protobuf.Any":
+		// The contents of an Any are described by the
+		// extensions of the schema, keyed by "@type".
+		return &jsonSchema{Type: "object"}
+	case "google.protobuf.Struct":
+		return &jsonSchema{Type: "object"}
+	case "google.protobuf.Value":
+		return &jsonSchema{}
+	case "google.protobuf.ListValue":
+		return &jsonSchema{Type: "array"}
+	case "google.protobuf.Duration":
+		return &jsonSchema{Type: "string", Format: "duration"}
+	case "google.protobuf.Timestamp":
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case "google.protobuf.FieldMask":
+		return &jsonSchema{Type: "string"}
+	case "google.protobuf.Empty":
+		return &jsonSchema{Type: "object"}
+	case "google.protobuf.BoolValue":
+		return &jsonSchema{Type: "boolean"}
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return &jsonSchema{Type: "integer"}
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return &jsonSchema{Type: "string", Format: "int64"}
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return &jsonSchema{Type: "number"}
+	case "google.protobuf.StringValue":
+		return &jsonSchema{Type: "string"}
+	case "google.protobuf.BytesValue":
+		return &jsonSchema{Type: "string", Format: "byte"}
+	default:
+		return nil
+	}
+}
+
+// isDeprecated returns true if the message or field
+// has the deprecated option set.
+func isDeprecated(d protoreflect.Descriptor) bool {
+	type deprecatable interface {
+		GetDeprecated() bool
+	}
+
+	opts, ok := d.Options().(deprecatable)
+	return ok && opts.GetDeprecated()
+}
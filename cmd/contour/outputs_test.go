@@ -0,0 +1,113 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/xdscache"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	"github.com/projectcontour/contour/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestOutputsSchema(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, doOutputsSchema(&outputsContext{XDSResourceVersion: config.XDSv3}, &buf))
+
+	var schema outputSchema
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &schema))
+	assert.Equal(t, config.XDSv3, schema.XDSResourceVersion)
+
+	// Every resource cache must be described by the schema.
+	caches := []xdscache.ResourceCache{
+		&xdscache_v3.ClusterCache{},
+		&xdscache_v3.EndpointsTranslator{},
+		&xdscache_v3.ListenerCache{},
+		&xdscache_v3.RouteCache{},
+		&xdscache_v3.ScopedRouteCache{},
+		&xdscache_v3.SecretCache{},
+	}
+	assert.Len(t, schema.Resources, len(caches))
+	for _, c := range caches {
+		assert.Contains(t, schema.Resources, c.TypeURL())
+	}
+
+	// The default listeners must only use fields that the
+	// schema describes.
+	listeners := xdscache_v3.NewListenerCache(xdscache_v3.ListenerConfig{}, "0.0.0.0", 8002)
+	for _, l := range listeners.Contents() {
+		data, err := protojson.Marshal(proto.MessageV2(l))
+		require.NoError(t, err)
+
+		var value interface{}
+		require.NoError(t, json.Unmarshal(data, &value))
+		checkSchema(t, &schema, schema.Resources[listeners.TypeURL()], value, "")
+	}
+}
+
+func TestOutputsSchemaInvalidVersion(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, doOutputsSchema(&outputsContext{XDSResourceVersion: "v2"}, &buf))
+	assert.Empty(t, buf.String())
+}
+
+// checkSchema asserts that every object property in value is
+// described by the given schema.
+func checkSchema(t *testing.T, schema *outputSchema, s *jsonSchema, value interface{}, path string) {
+	t.Helper()
+
+	if s.Ref != "" {
+		def, ok := schema.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+		require.True(t, ok, "%s: missing definition %s", path, s.Ref)
+		s = def
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if typeURL, ok := v["@type"].(string); ok {
+			ext, ok := schema.Extensions[typeURL]
+			require.True(t, ok, "%s: missing extension %s", path, typeURL)
+			delete(v, "@type")
+			checkSchema(t, schema, ext, v, path)
+			return
+		}
+		if s.AdditionalProperties != nil {
+			for k, e := range v {
+				checkSchema(t, schema, s.AdditionalProperties, e, path+"."+k)
+			}
+			return
+		}
+		if s.Properties == nil {
+			// An opaque object, such as a Struct.
+			return
+		}
+		for k, e := range v {
+			prop, ok := s.Properties[k]
+			require.True(t, ok, "%s: undefined property %q", path, k)
+			checkSchema(t, schema, prop, e, path+"."+k)
+		}
+	case []interface{}:
+		require.NotNil(t, s.Items, "%s: unexpected array", path)
+		for _, e := range v {
+			checkSchema(t, schema, s.Items, e, path+"[]")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// resyncHandler replaces the objects it was informed of
+// with a fresh listing of every one of them.
+type resyncHandler interface {
+	Resync(objs []interface{})
+}
+
+// resyncer lists the resources that Contour is informed of directly
+// from the API server, bypassing the informer caches, and hands them
+// to the event handlers. This recovers from missed watch events
+// without restarting Contour.
+type resyncer struct {
+	logrus.FieldLogger
+
+	client    dynamic.Interface
+	converter k8s.Converter
+
+	// resources are listed in every namespace.
+	resources []schema.GroupVersionResource
+
+	// namespaced resources, such as secrets, are listed in
+	// namespaces, or in every namespace if namespaces is empty.
+	namespaced []schema.GroupVersionResource
+	namespaces []string
+
+	// endpoints, either Endpoints or EndpointSlices, are listed in
+	// every namespace and handed to endpointHandler rather than
+	// eventHandler.
+	endpoints []schema.GroupVersionResource
+
+	eventHandler    resyncHandler
+	endpointHandler resyncHandler
+}
+
+// resync replaces the contents of the event handler's and endpoint
+// handler's caches with a fresh listing of every resource, which
+// rebuilds the DAG and pushes every xDS resource to the connected
+// Envoys. Objects that were deleted without a watch event are
+// removed from both caches.
+func (r *resyncer) resync(ctx context.Context) error {
+	var objs []interface{}
+	for _, gvr := range r.resources {
+		list, err := r.list(ctx, gvr, metav1.NamespaceAll)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, list...)
+	}
+
+	namespaces := r.namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	for _, gvr := range r.namespaced {
+		for _, ns := range namespaces {
+			list, err := r.list(ctx, gvr, ns)
+			if err != nil {
+				return err
+			}
+			objs = append(objs, list...)
+		}
+	}
+
+	var endpoints []interface{}
+	for _, gvr := range r.endpoints {
+		list, err := r.list(ctx, gvr, metav1.NamespaceAll)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, list...)
+	}
+
+	r.eventHandler.Resync(objs)
+	r.endpointHandler.Resync(endpoints)
+
+	r.WithField("objects", len(objs)).WithField("endpoints", len(endpoints)).Info("resynced resources from the API server")
+	return nil
+}
+
+// list returns the objects of the given resource in namespace,
+// converted from unstructured objects to their Go types.
+func (r *resyncer) list(ctx context.Context, gvr schema.GroupVersionResource, namespace string) ([]interface{}, error) {
+	list, err := r.client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", gvr.GroupResource(), err)
+	}
+
+	objs := make([]interface{}, 0, len(list.Items))
+	for i := range list.Items {
+		obj, err := r.converter.FromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, fmt.Errorf("error converting %s %s/%s: %w", gvr.GroupResource(), list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+		}
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
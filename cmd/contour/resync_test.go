@@ -0,0 +1,93 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// resyncRecorder records the names of the
+// objects of each call to Resync.
+type resyncRecorder struct {
+	names [][]string
+}
+
+func (r *resyncRecorder) Resync(objs []interface{}) {
+	names := []string{}
+	for _, obj := range objs {
+		meta := obj.(metav1.Object)
+		names = append(names, meta.GetNamespace()+"/"+meta.GetName())
+	}
+	r.names = append(r.names, names)
+}
+
+func TestResync(t *testing.T) {
+	scheme, err := k8s.NewContourScheme()
+	require.NoError(t, err)
+	converter, err := k8s.NewUnstructuredConverter()
+	require.NoError(t, err)
+
+	// The fake client only lists unstructured objects.
+	object := func(kind, namespace, name string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+
+	client := fake.NewSimpleDynamicClient(scheme,
+		object("Service", "a", "kuard"),
+		object("Service", "b", "kuard"),
+		object("Secret", "a", "cert"),
+		object("Secret", "b", "cert"),
+		object("Endpoints", "a", "kuard"),
+	)
+
+	var eventHandler, endpointHandler resyncRecorder
+	r := &resyncer{
+		FieldLogger:     fixture.NewTestLogger(t),
+		client:          client,
+		converter:       converter,
+		resources:       []schema.GroupVersionResource{v1.SchemeGroupVersion.WithResource("services")},
+		namespaced:      k8s.SecretsResources(),
+		namespaces:      []string{"b"},
+		endpoints:       k8s.EndpointsResources(),
+		eventHandler:    &eventHandler,
+		endpointHandler: &endpointHandler,
+	}
+	require.NoError(t, r.resync(context.Background()))
+
+	// Services are listed in every namespace, and Secrets
+	// only in the namespaces that Contour is informed of.
+	assert.Len(t, eventHandler.names, 1)
+	assert.ElementsMatch(t, []string{"a/kuard", "b/kuard", "b/cert"}, eventHandler.names[0])
+
+	// Endpoints are replaced as a whole, so that the
+	// deleted ones are removed from the endpoint cache.
+	assert.Equal(t, [][]string{{"a/kuard"}}, endpointHandler.names)
+}
@@ -22,15 +22,18 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	envoy_server_v3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
 	"github.com/projectcontour/contour/internal/annotation"
 	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/debug"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
 	"github.com/projectcontour/contour/internal/health"
 	"github.com/projectcontour/contour/internal/httpsvc"
 	"github.com/projectcontour/contour/internal/k8s"
@@ -51,12 +54,21 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 // Add RBAC policy to support leader election.
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=create;get;update
 
+// Add RBAC policy to support reading the ContourConfiguration.
+// +kubebuilder:rbac:groups="projectcontour.io",resources=contourconfigurations,verbs=get
+// +kubebuilder:rbac:groups="projectcontour.io",resources=contourconfigurations/status,verbs=create;get;update
+
+// Add RBAC policy to support recording events.
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 // Add RBAC policy to support getting CRDs.
 // +kubebuilder:rbac:groups="apiextensions.k8s.io",resources=customresourcedefinitions,verbs=list
 
@@ -106,15 +118,19 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 	}
 
 	serve.Flag("config-path", "Path to base configuration.").Short('c').Action(parseConfig).ExistingFileVar(&configFile)
+	serve.Flag("contour-config-name", "Name of the ContourConfiguration resource in the Contour namespace that overrides the base configuration.").StringVar(&ctx.contourConfigName)
 
 	serve.Flag("incluster", "Use in cluster configuration.").BoolVar(&ctx.Config.InCluster)
 	serve.Flag("kubeconfig", "Path to kubeconfig (if not in running inside a cluster).").StringVar(&ctx.Config.Kubeconfig)
 
 	serve.Flag("xds-address", "xDS gRPC API address.").StringVar(&ctx.xdsAddr)
 	serve.Flag("xds-port", "xDS gRPC API port.").IntVar(&ctx.xdsPort)
+	serve.Flag("xds-push-interval", "Minimum time between two xDS responses on a stream.").DurationVar(&ctx.Config.Server.PushInterval)
 
 	serve.Flag("stats-address", "Envoy /stats interface address.").StringVar(&ctx.statsAddr)
 	serve.Flag("stats-port", "Envoy /stats interface port.").IntVar(&ctx.statsPort)
+	serve.Flag("envoy-metrics-address", "Envoy /stats/prometheus and /ready interface address.").StringVar(&ctx.envoyMetricsAddr)
+	serve.Flag("envoy-metrics-port", "Envoy /stats/prometheus and /ready interface port (disabled if not set).").IntVar(&ctx.envoyMetricsPort)
 
 	serve.Flag("debug-http-address", "Address the debug http endpoint will bind to.").StringVar(&ctx.debugAddr)
 	serve.Flag("debug-http-port", "Port the debug http endpoint will bind to.").IntVar(&ctx.debugPort)
@@ -132,6 +148,8 @@ func registerServe(app *kingpin.Application) (*kingpin.CmdClause, *serveContext)
 
 	serve.Flag("ingress-class-name", "Contour IngressClass name.").StringVar(&ctx.ingressClass)
 	serve.Flag("ingress-status-address", "Address to set in Ingress object status.").StringVar(&ctx.Config.IngressStatusAddress)
+	serve.Flag("enable-nginx-annotations", "Translate nginx-ingress annotations on Ingress objects.").BoolVar(&ctx.Config.EnableNginxAnnotations)
+	serve.Flag("watch-domain-suffix", "Only program virtual hosts in this domain (may be repeated).").StringsVar(&ctx.Config.WatchDomainSuffixes)
 	serve.Flag("envoy-http-access-log", "Envoy HTTP access log.").StringVar(&ctx.httpAccessLog)
 	serve.Flag("envoy-https-access-log", "Envoy HTTPS access log.").StringVar(&ctx.httpsAccessLog)
 	serve.Flag("envoy-service-http-address", "Kubernetes Service address for HTTP requests.").StringVar(&ctx.httpAddr)
@@ -185,6 +203,55 @@ func validateCRDs(dynamicClient dynamic.Interface, log logrus.FieldLogger) {
 	}
 }
 
+// loadRateLimitConfig builds the global rate limit configuration, taking
+// the request timeout from the referenced ExtensionService.
+func loadRateLimitConfig(
+	clients *k8s.Clients,
+	converter *k8s.UnstructuredConverter,
+	name types.NamespacedName,
+	params config.RateLimitServiceParameters,
+) (*xdscache_v3.RateLimitConfig, error) {
+	client := clients.DynamicClient().Resource(contour_api_v1alpha1.ExtensionServiceGVR).Namespace(name.Namespace)
+
+	// Ensure the specified ExtensionService exists.
+	res, err := client.Get(context.Background(), name.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting rate limit extension service %s: %w", name, err)
+	}
+
+	obj, err := converter.FromUnstructured(res)
+	if err != nil {
+		return nil, fmt.Errorf("error converting rate limit extension service %s: %w", name, err)
+	}
+
+	ext, ok := obj.(*contour_api_v1alpha1.ExtensionService)
+	if !ok {
+		return nil, fmt.Errorf("error converting rate limit extension service %s", name)
+	}
+
+	responseTimeout := timeout.DefaultSetting()
+	if tp := ext.Spec.TimeoutPolicy; tp != nil {
+		responseTimeout, err = timeout.Parse(tp.Response)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rate limit extension service %s response timeout: %w", name, err)
+		}
+	}
+
+	retryAfter, err := params.RetryAfterDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	return &xdscache_v3.RateLimitConfig{
+		ExtensionService:        name,
+		Domain:                  params.Domain,
+		Timeout:                 responseTimeout,
+		FailOpen:                params.FailOpen,
+		EnableXRateLimitHeaders: params.EnableXRateLimitHeaders,
+		RetryAfter:              retryAfter,
+	}, nil
+}
+
 // doServe runs the contour serve subcommand.
 func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	// Establish k8s core & dynamic client connections.
@@ -196,11 +263,40 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	// Validate that Contour CRDs have been updated to v1.
 	validateCRDs(clients.DynamicClient(), log)
 
+	// Before we can build the event handler, we need to initialize the converter we'll
+	// use to convert from Unstructured. Thanks to kubebuilder types from service-apis, this now can
+	// return an error.
+	converter, err := k8s.NewUnstructuredConverter()
+	if err != nil {
+		return err
+	}
+
+	if ctx.contourConfigName != "" {
+		name := types.NamespacedName{
+			Namespace: config.GetenvOr("CONTOUR_NAMESPACE", "projectcontour"),
+			Name:      ctx.contourConfigName,
+		}
+		if err := loadContourConfiguration(clients, converter, name, &ctx.Config); err != nil {
+			return err
+		}
+		log.WithField("context", "contour-configuration").Infof("applied settings from contour configuration %q", name)
+	}
+
+	annotation.SetPrefixes(ctx.Config.Annotations.Prefix, ctx.Config.Annotations.LegacyPrefixes...)
+
 	// informerNamespaces is a list of namespaces that we should start informers for.
 	var informerNamespaces []string
 
 	fallbackCert := namespacedNameOf(ctx.Config.TLS.FallbackCertificate)
 	clientCert := namespacedNameOf(ctx.Config.TLS.ClientCertificate)
+	sessionTicketKeys := namespacedNameOf(ctx.Config.TLS.SessionTicketKeys)
+	hstsPolicy := hstsPolicyOf(ctx.Config.TLS.HSTS)
+	retryBudget := retryBudgetOf(ctx.Config.Cluster.RetryBudget)
+
+	namespaceDefaults, err := namespaceDefaultsOf(ctx.Config.NamespaceDefaults)
+	if err != nil {
+		return err
+	}
 
 	if rootNamespaces := ctx.proxyRootNamespaces(); len(rootNamespaces) > 0 {
 		informerNamespaces = append(informerNamespaces, rootNamespaces...)
@@ -220,6 +316,14 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 				Infof("client certificate namespace %q not defined in 'root-namespaces', adding namespace to watch",
 					ctx.Config.TLS.ClientCertificate.Namespace)
 		}
+
+		// Add the session ticket keys namespace to informerNamespaces if it isn't present.
+		if !contains(informerNamespaces, ctx.Config.TLS.SessionTicketKeys.Namespace) && sessionTicketKeys != nil {
+			informerNamespaces = append(informerNamespaces, ctx.Config.TLS.SessionTicketKeys.Namespace)
+			log.WithField("context", "session-ticket-keys").
+				Infof("session ticket keys namespace %q not defined in 'root-namespaces', adding namespace to watch",
+					ctx.Config.TLS.SessionTicketKeys.Namespace)
+		}
 	}
 
 	var configuredSecretRefs []*types.NamespacedName
@@ -229,20 +333,15 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	if clientCert != nil {
 		configuredSecretRefs = append(configuredSecretRefs, clientCert)
 	}
+	if sessionTicketKeys != nil {
+		configuredSecretRefs = append(configuredSecretRefs, sessionTicketKeys)
+	}
 
 	// Set up Prometheus registry and register base metrics.
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 	registry.MustRegister(prometheus.NewGoCollector())
 
-	// Before we can build the event handler, we need to initialize the converter we'll
-	// use to convert from Unstructured. Thanks to kubebuilder types from service-apis, this now can
-	// return an error.
-	converter, err := k8s.NewUnstructuredConverter()
-	if err != nil {
-		return err
-	}
-
 	// XXX(jpeach) we know the config file validated, so all
 	// the timeouts will parse. Shall we add a `timeout.MustParse()`
 	// and use it here?
@@ -267,17 +366,68 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	if err != nil {
 		return fmt.Errorf("error parsing request timeout: %w", err)
 	}
+	connectTimeout, err := ctx.Config.Cluster.ConnectTimeoutDuration()
+	if err != nil {
+		return fmt.Errorf("error parsing cluster connect timeout: %w", err)
+	}
+	srvRefreshInterval, err := ctx.Config.Cluster.DNSSRVRefreshIntervalDuration()
+	if err != nil {
+		return fmt.Errorf("error parsing cluster DNS SRV refresh interval: %w", err)
+	}
+
+	errorPages, err := loadErrorPages(clients.ClientSet(), ctx.Config.ErrorPages)
+	if err != nil {
+		return fmt.Errorf("error loading error pages: %w", err)
+	}
+
+	if ctx.Config.Network.DualStack {
+		ctx.httpAddr = dualStackAddress(ctx.httpAddr)
+		ctx.httpsAddr = dualStackAddress(ctx.httpsAddr)
+		ctx.statsAddr = dualStackAddress(ctx.statsAddr)
+		ctx.envoyMetricsAddr = dualStackAddress(ctx.envoyMetricsAddr)
+	}
+
+	if err := ctx.verifyHTTPListeners(); err != nil {
+		return err
+	}
+
+	// Additional HTTP listeners default to the same
+	// wildcard address as the HTTP listener.
+	var httpListeners []config.HTTPListenerParameters
+	var httpListenerNames []string
+	for _, l := range ctx.Config.Network.HTTPListeners {
+		if l.Address == "" {
+			l.Address = xdscache_v3.DEFAULT_HTTP_LISTENER_ADDRESS
+		}
+		if ctx.Config.Network.DualStack {
+			l.Address = dualStackAddress(l.Address)
+		}
+		httpListeners = append(httpListeners, l)
+		httpListenerNames = append(httpListenerNames, l.Name)
+	}
 
 	listenerConfig := xdscache_v3.ListenerConfig{
 		UseProxyProto:                 ctx.useProxyProto,
+		PerConnectionBufferLimitBytes: ctx.Config.Network.PerConnectionBufferLimitBytes,
+		SocketOptions:                 ctx.Config.Network.SocketOptions,
+		DrainType:                     ctx.Config.Network.ListenerDrainType,
+		HTTPListeners:                 httpListeners,
+		ScopedRoutes:                  ctx.Config.Server.ScopedRoutes,
 		HTTPAddress:                   ctx.httpAddr,
 		HTTPPort:                      ctx.httpPort,
 		HTTPAccessLog:                 ctx.httpAccessLog,
 		HTTPSAddress:                  ctx.httpsAddr,
 		HTTPSPort:                     ctx.httpsPort,
 		HTTPSAccessLog:                ctx.httpsAccessLog,
+		MetricsAddress:                ctx.envoyMetricsAddr,
+		MetricsPort:                   ctx.envoyMetricsPort,
 		AccessLogType:                 ctx.Config.AccessLogFormat,
 		AccessLogFields:               ctx.Config.AccessLogFields,
+		AccessLogFilter:               ctx.Config.AccessLogFilter,
+		HealthCheckPaths:              ctx.Config.HealthCheckPaths,
+		HealthCheckVirtualHostPath:    ctx.Config.HealthCheckVirtualHost.Path,
+		AccessLogHeaders:              ctx.Config.AccessLogHeaders,
+		AccessLogGRPC:                 ctx.Config.AccessLogGRPC,
 		MinimumTLSVersion:             annotation.MinTLSVersion(ctx.Config.TLS.MinimumProtocolVersion, "1.2"),
 		RequestTimeout:                requestTimeout,
 		ConnectionIdleTimeout:         connectionIdleTimeout,
@@ -285,33 +435,112 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		MaxConnectionDuration:         maxConnectionDuration,
 		ConnectionShutdownGracePeriod: connectionShutdownGracePeriod,
 		DefaultHTTPVersions:           parseDefaultHTTPVersions(ctx.Config.DefaultHTTPVersions),
+		ALPNProtocols:                 alpnProtocolsOf(ctx.Config.TLS.ALPNProtocols),
+		ErrorPages:                    errorPages,
+		XffNumTrustedHops:             ctx.Config.Network.XffNumTrustedHops,
+		TrustedProxyCIDRs:             ctx.Config.Network.TrustedProxyNetworks(),
+		MaxRequestHeadersKB:           ctx.Config.Network.MaxRequestHeadersKB,
+		MaxRequestHeadersCount:        ctx.Config.Network.MaxRequestHeadersCount,
+		StripMatchingHostPort:         ctx.Config.Network.StripMatchingHostPort,
+		DisableHTTP10:                 ctx.Config.Network.DisableHTTP10,
+		HTTP10DefaultHost:             ctx.Config.Network.HTTP10DefaultHost,
+		ServerHeader:                  ctx.Config.Network.ServerHeader,
+		ResponseCache:                 ctx.Config.ResponseCache,
+		Tracing:                       ctx.Config.Tracing,
+	}
+
+	if extSvc := namespacedNameOf(ctx.Config.RateLimitService.ExtensionService); extSvc != nil {
+		rateLimitConfig, err := loadRateLimitConfig(clients, converter, *extSvc, ctx.Config.RateLimitService)
+		if err != nil {
+			return err
+		}
+
+		listenerConfig.RateLimitConfig = rateLimitConfig
 	}
 
 	contourMetrics := metrics.NewMetrics(registry)
 
+	// streams records the xDS streams that Envoy opens.
+	streams := &xds.StreamTracker{
+		Metrics: contourMetrics,
+	}
+
 	// Endpoints updates are handled directly by the EndpointsTranslator
 	// due to their high update rate and their orthogonal nature.
 	endpointHandler := xdscache_v3.NewEndpointsTranslator(log.WithField("context", "endpointstranslator"))
 
+	// Batch Endpoints churn, such as during a rollout, so each
+	// affected cluster is recalculated and pushed once per window.
+	endpointHandler.HoldoffDelay = ctx.Config.Server.EndpointsHoldoffDelay
+
+	// Serve the endpoints of the Envoy Service as the local cluster
+	// that Envoy uses for zone aware routing when it is bootstrapped
+	// with a locality.
+	endpointHandler.LocalCluster = &dag.ServiceCluster{
+		ClusterName: envoy_v3.LocalClusterName,
+		Services: []dag.WeightedService{{
+			Weight:           1,
+			ServiceName:      ctx.Config.EnvoyServiceName,
+			ServiceNamespace: ctx.Config.EnvoyServiceNamespace,
+			ServicePort:      corev1.ServicePort{Name: "http"},
+		}},
+	}
+
 	resources := []xdscache.ResourceCache{
 		xdscache_v3.NewListenerCache(listenerConfig, ctx.statsAddr, ctx.statsPort),
 		&xdscache_v3.SecretCache{},
-		&xdscache_v3.RouteCache{},
-		&xdscache_v3.ClusterCache{},
+		&xdscache_v3.RouteCache{
+			HealthCheckPaths: ctx.Config.HealthCheckPaths,
+			ScopedRoutes:     ctx.Config.Server.ScopedRoutes,
+			Maintenance:      maintenanceOf(ctx.Config.Maintenance),
+		},
+		&xdscache_v3.ClusterCache{
+			PerConnectionBufferLimitBytes: ctx.Config.Cluster.PerConnectionBufferLimitBytes,
+			ConnectTimeout:                connectTimeout,
+			HealthyPanicThreshold:         ctx.Config.Cluster.HealthyPanicThreshold,
+			SPIREIdentity:                 ctx.Config.TLS.SPIRE.Identity,
+		},
 		endpointHandler,
 	}
 
+	if ctx.Config.Server.ScopedRoutes {
+		resources = append(resources, &xdscache_v3.ScopedRouteCache{})
+	}
+
+	// In endpoints-only mode, another control plane serves
+	// everything but the endpoints.
+	if ctx.Config.Server.EndpointsOnly {
+		resources = []xdscache.ResourceCache{endpointHandler}
+	}
+
 	// snapshotHandler is used to produce new snapshots when the internal state changes for any xDS resource.
 	snapshotHandler := xdscache.NewSnapshotHandler(resources, log.WithField("context", "snapshotHandler"))
 
+	// pauser holds back xDS pushes while paused from the debug service.
+	// On resume, the snapshot handler generates a single snapshot of
+	// the changes made in between.
+	pauser := &xds.Pauser{}
+	snapshotHandler.Pauser = pauser
+	pauser.OnResume(snapshotHandler.Refresh)
+
+	// setEndpointsMetric records the endpoints of each cluster
+	// whenever they change, on Endpoints updates or DAG rebuilds.
+	setEndpointsMetric := func() {
+		contourMetrics.SetClusterEndpointsMetric(endpointHandler.EndpointCounts())
+	}
+
 	// register observer for endpoints updates.
-	endpointHandler.Observer = contour.ComposeObservers(snapshotHandler)
+	endpointHandler.Observer = contour.ComposeObservers(snapshotHandler, contour.ObserverFunc(setEndpointsMetric))
+
+	// DAG rebuilds may add or remove clusters, and hence their endpoints.
+	observers := append(xdscache.ObserversOf(resources), snapshotHandler,
+		dag.ObserverFunc(func(*dag.DAG) { setEndpointsMetric() }))
 
 	// Build the core Kubernetes event handler.
 	eventHandler := &contour.EventHandler{
 		HoldoffDelay:    100 * time.Millisecond,
 		HoldoffMaxDelay: 500 * time.Millisecond,
-		Observer:        dag.ComposeObservers(append(xdscache.ObserversOf(resources), snapshotHandler)...),
+		Observer:        dag.ComposeObservers(observers...),
 		Builder: dag.Builder{
 			Source: dag.KubernetesCache{
 				RootNamespaces:       ctx.proxyRootNamespaces(),
@@ -323,21 +552,55 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 				&dag.IngressProcessor{
 					FieldLogger:       log.WithField("context", "IngressProcessor"),
 					ClientCertificate: clientCert,
+					HSTSPolicy:        hstsPolicy,
+					RetryBudget:       retryBudget,
+					NginxAnnotations:  ctx.Config.EnableNginxAnnotations,
+					DNSLookupFamily:   ctx.Config.Cluster.DNSLookupFamily,
+					DNSResolvers:      ctx.Config.Cluster.DNSResolvers,
+					NamespaceDefaults: namespaceDefaults,
+					DomainSuffixes:    ctx.Config.WatchDomainSuffixes,
+					NamespaceQuota:    namespaceQuotaOf(ctx.Config.NamespaceQuotas.Default),
+					NamespaceQuotas:   namespaceQuotasOf(ctx.Config.NamespaceQuotas),
+					BackendResolvers: map[schema.GroupKind]dag.IngressBackendResolver{
+						{Kind: "Service"}: dag.ServiceBackendResolver,
+					},
 				},
 				&dag.ExtensionServiceProcessor{
 					FieldLogger:       log.WithField("context", "ExtensionServiceProcessor"),
 					ClientCertificate: clientCert,
 				},
 				&dag.HTTPProxyProcessor{
-					DisablePermitInsecure: ctx.Config.DisablePermitInsecure,
-					FallbackCertificate:   fallbackCert,
-					DNSLookupFamily:       ctx.Config.Cluster.DNSLookupFamily,
-					ClientCertificate:     clientCert,
+					DisablePermitInsecure:     ctx.Config.DisablePermitInsecure,
+					FallbackCertificate:       fallbackCert,
+					DNSLookupFamily:           ctx.Config.Cluster.DNSLookupFamily,
+					DNSResolvers:              ctx.Config.Cluster.DNSResolvers,
+					ClientCertificate:         clientCert,
+					EnableExternalBackends:    ctx.Config.EnableExternalBackends,
+					EnableForwardProxy:        ctx.Config.EnableForwardProxy,
+					EnableOriginalDestination: ctx.Config.EnableOriginalDestination,
+					Listeners:                 httpListenerNames,
+					HSTSPolicy:                hstsPolicy,
+					RetryBudget:               retryBudget,
+					NamespaceQuota:            namespaceQuotaOf(ctx.Config.NamespaceQuotas.Default),
+					NamespaceQuotas:           namespaceQuotasOf(ctx.Config.NamespaceQuotas),
+					NamespaceDefaults:         namespaceDefaults,
+					DomainSuffixes:            ctx.Config.WatchDomainSuffixes,
+				},
+				&dag.ListenerProcessor{
+					FieldLogger:       log.WithField("context", "ListenerProcessor"),
+					SessionTicketKeys: sessionTicketKeys,
 				},
-				&dag.ListenerProcessor{},
 			},
 		},
 		FieldLogger: log.WithField("context", "contourEventHandler"),
+		Ready:       make(chan struct{}),
+	}
+
+	if ctx.Config.Server.EndpointsOnly {
+		eventHandler.Builder.Processors = []dag.Processor{
+			&dag.EndpointsProcessor{},
+		}
+		log.WithField("context", "endpoints-only").Info("serving only the endpoints of every service")
 	}
 
 	// Log that we're using the fallback certificate if configured.
@@ -345,10 +608,18 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		log.WithField("context", "fallback-certificate").Infof("enabled fallback certificate with secret: %q", fallbackCert)
 	}
 
+	if len(ctx.Config.WatchDomainSuffixes) > 0 {
+		log.WithField("context", "watch-domain-suffixes").Infof("only programming virtual hosts in domains: %s", strings.Join(ctx.Config.WatchDomainSuffixes, ", "))
+	}
+
 	if clientCert != nil {
 		log.WithField("context", "envoy-client-certificate").Infof("enabled client certificate with secret: %q", clientCert)
 	}
 
+	if sessionTicketKeys != nil {
+		log.WithField("context", "session-ticket-keys").Infof("enabled TLS session ticket keys with secret: %q", sessionTicketKeys)
+	}
+
 	// Wrap eventHandler in a converter for objects from the dynamic client.
 	// and an EventRecorder which tracks API server events.
 	dynamicHandler := k8s.DynamicClientHandler{
@@ -360,6 +631,17 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		Logger:    log.WithField("context", "dynamicHandler"),
 	}
 
+	// Record the resources that are informed on, so that the
+	// debug service can list them again on request.
+	resync := &resyncer{
+		FieldLogger:     log.WithField("context", "resync"),
+		client:          clients.DynamicClient(),
+		converter:       converter,
+		namespaces:      informerNamespaces,
+		eventHandler:    eventHandler,
+		endpointHandler: endpointHandler,
+	}
+
 	// Inform on DefaultResources.
 	for _, r := range k8s.DefaultResources() {
 		inf, err := clients.InformerForResource(r)
@@ -368,8 +650,21 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		}
 
 		inf.AddEventHandler(&dynamicHandler)
+		resync.resources = append(resync.resources, r)
 	}
 
+	// Inform on the preferred Ingress version that the API server serves.
+	ingressGVR, err := preferredIngressResource(clients)
+	if err != nil {
+		log.WithError(err).Fatal("failed to find an Ingress resource")
+	}
+	log.WithField("resource", ingressGVR).Info("watching Ingress resources")
+
+	if err := informOnResource(clients, ingressGVR, &dynamicHandler); err != nil {
+		log.WithError(err).WithField("resource", ingressGVR).Fatal("failed to create informer")
+	}
+	resync.resources = append(resync.resources, ingressGVR)
+
 	// Inform on service-apis types if they are present.
 	if ctx.UseExperimentalServiceAPITypes {
 		for _, r := range k8s.ServiceAPIResources() {
@@ -381,11 +676,13 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 			if err := informOnResource(clients, r, &dynamicHandler); err != nil {
 				log.WithError(err).WithField("resource", r).Fatal("failed to create informer")
 			}
+			resync.resources = append(resync.resources, r)
 		}
 	}
 
-	// Inform on secrets, filtering by root namespaces.
-	for _, r := range k8s.SecretsResources() {
+	// Inform on secrets, and on the configmaps that may hold CA
+	// certificate bundles, filtering by root namespaces.
+	for _, r := range append(k8s.SecretsResources(), k8s.ConfigMapsResources()...) {
 		var handler cache.ResourceEventHandler = &dynamicHandler
 
 		// If root namespaces are defined, filter for secrets in only those namespaces.
@@ -396,10 +693,17 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		if err := informOnResource(clients, r, handler); err != nil {
 			log.WithError(err).WithField("resource", r).Fatal("failed to create informer")
 		}
+		resync.namespaced = append(resync.namespaced, r)
 	}
 
-	// Inform on endpoints.
-	for _, r := range k8s.EndpointsResources() {
+	// Inform on endpoints. In dual-stack clusters, Endpoints only
+	// hold the addresses of a Service's primary family, so read
+	// the EndpointSlices of every family instead.
+	endpointsResources := k8s.EndpointsResources()
+	if ctx.Config.Network.DualStack {
+		endpointsResources = k8s.EndpointSliceResources()
+	}
+	for _, r := range endpointsResources {
 		if err := informOnResource(clients, r, &k8s.DynamicClientHandler{
 			Next: &contour.EventRecorder{
 				Next:    endpointHandler,
@@ -410,6 +714,27 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		}); err != nil {
 			log.WithError(err).WithField("resource", r).Fatal("failed to create informer")
 		}
+		resync.endpoints = append(resync.endpoints, r)
+	}
+
+	// Resolve the external names of ExternalName Services with the
+	// projectcontour.io/dns-srv annotation to their Endpoints.
+	srvResolver := &k8s.SRVResolver{
+		Next: &contour.EventRecorder{
+			Next:    endpointHandler,
+			Counter: contourMetrics.EventHandlerOperations,
+		},
+		Interval:    srvRefreshInterval,
+		FieldLogger: log.WithField("context", "srvresolver"),
+	}
+	for _, r := range k8s.ServicesResources() {
+		if err := informOnResource(clients, r, &k8s.DynamicClientHandler{
+			Next:      srvResolver,
+			Converter: converter,
+			Logger:    log.WithField("context", "srvresolver"),
+		}); err != nil {
+			log.WithError(err).WithField("resource", r).Fatal("failed to create informer")
+		}
 	}
 
 	// Set up workgroup runner and register informers.
@@ -433,6 +758,9 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	// Register our event handler with the workgroup.
 	g.Add(eventHandler.Start())
 
+	// Register the SRV resolver with the workgroup.
+	g.Add(srvResolver.Start)
+
 	// Create metrics service and register with workgroup.
 	metricsvc := httpsvc.Service{
 		Addr:        ctx.metricsAddr,
@@ -447,6 +775,7 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		h := health.Handler(clients.ClientSet())
 		metricsvc.ServeMux.Handle("/health", h)
 		metricsvc.ServeMux.Handle("/healthz", h)
+		metricsvc.ServeMux.Handle("/ready", health.ReadyHandler(eventHandler.Ready))
 	}
 
 	g.Add(metricsvc.Start)
@@ -462,6 +791,7 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		h := health.Handler(clients.ClientSet())
 		healthsvc.ServeMux.Handle("/health", h)
 		healthsvc.ServeMux.Handle("/healthz", h)
+		healthsvc.ServeMux.Handle("/ready", health.ReadyHandler(eventHandler.Ready))
 
 		g.Add(healthsvc.Start)
 	}
@@ -474,6 +804,9 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 			FieldLogger: log.WithField("context", "debugsvc"),
 		},
 		Builder: &eventHandler.Builder,
+		Streams: streams,
+		Resync:  resync.resync,
+		Pauser:  pauser,
 	}
 	g.Add(debugsvc.Start)
 
@@ -504,15 +837,28 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	// status updates from the DAG, and send them to the status update handler.
 	eventHandler.StatusUpdater = sh.Writer()
 
+	// Record the warnings of each DAG, such as exceeded
+	// namespace quotas, as events on the objects involved.
+	scheme, err := k8s.NewContourScheme()
+	if err != nil {
+		return fmt.Errorf("unable to create event recorder scheme: %w", err)
+	}
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clients.ClientSet().CoreV1().Events("")})
+	defer eventBroadcaster.Shutdown()
+	eventHandler.EventRecorder = eventBroadcaster.NewRecorder(scheme, corev1.EventSource{Component: "contour"})
+
 	// Set up ingress load balancer status writer.
 	lbsw := loadBalancerStatusWriter{
-		log:           log.WithField("context", "loadBalancerStatusWriter"),
-		clients:       clients,
-		isLeader:      eventHandler.IsLeader,
-		lbStatus:      make(chan corev1.LoadBalancerStatus, 1),
-		ingressClass:  ctx.ingressClass,
-		statusUpdater: sh.Writer(),
-		Converter:     converter,
+		log:            log.WithField("context", "loadBalancerStatusWriter"),
+		clients:        clients,
+		isLeader:       eventHandler.IsLeader,
+		lbStatus:       make(chan corev1.LoadBalancerStatus, 1),
+		ingressClass:   ctx.ingressClass,
+		ingressGVR:     ingressGVR,
+		statusUpdater:  sh.Writer(),
+		Converter:      converter,
+		domainSuffixes: ctx.Config.WatchDomainSuffixes,
 	}
 	g.Add(lbsw.Start)
 
@@ -548,6 +894,28 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 			Info("Watching Service for Ingress status")
 	}
 
+	// Request certificates from cert-manager for annotated HTTPProxies if cert-manager is installed.
+	if clients.ResourcesExist(k8s.CertificateResources()...) {
+		cw := k8s.CertificateWriter{
+			Log:           log.WithField("context", "certificateWriter"),
+			Client:        clients.DynamicClient(),
+			LeaderElected: eventHandler.IsLeader,
+			IngressClass:  ctx.ingressClass,
+			UpdateChannel: make(chan *unstructured.Unstructured, 100),
+		}
+		g.Add(cw.Start)
+
+		if err := informOnResource(clients, contour_api_v1.HTTPProxyGVR, &k8s.DynamicClientHandler{
+			Next:      &cw,
+			Converter: converter,
+			Logger:    log.WithField("context", "certificateWriter"),
+		}); err != nil {
+			log.WithError(err).WithField("resource", contour_api_v1.HTTPProxyGVR).Fatal("failed to create informer")
+		}
+	} else {
+		log.Info("cert-manager Certificate resource not present on API server, not requesting certificates")
+	}
+
 	g.Add(func(stop <-chan struct{}) error {
 		log := log.WithField("context", "xds")
 
@@ -557,15 +925,30 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 		}
 		log.Printf("informer caches synced")
 
+		// Build the first complete DAG from the synced caches.
+		eventHandler.Synced()
+
+		if ctx.Config.Server.WaitForFirstSnapshot {
+			log.Printf("waiting for the first snapshot")
+			select {
+			case <-eventHandler.Ready:
+			case <-stop:
+				return nil
+			}
+		}
+
 		grpcServer := xds.NewServer(registry, ctx.grpcOptions(log)...)
 
 		switch ctx.Config.Server.XDSServerType {
 		case config.EnvoyServerType:
 			v3cache := contour_xds_v3.NewSnapshotCache(false, log)
 			snapshotHandler.AddSnapshotter(v3cache)
-			contour_xds_v3.RegisterServer(envoy_server_v3.NewServer(context.Background(), v3cache, contour_xds_v3.NewRequestLoggingCallbacks(log)), grpcServer)
+			if ctx.Config.Server.PushInterval > 0 {
+				log.Warn("the xDS push interval is not supported by the envoy xDS server")
+			}
+			contour_xds_v3.RegisterServer(envoy_server_v3.NewServer(context.Background(), v3cache, contour_xds_v3.NewRequestLoggingCallbacks(log, streams)), grpcServer)
 		case config.ContourServerType:
-			contour_xds_v3.RegisterServer(contour_xds_v3.NewContourServer(log, xdscache.ResourcesOf(resources)...), grpcServer)
+			contour_xds_v3.RegisterServer(contour_xds_v3.NewContourServer(log, streams, ctx.Config.Server.PushInterval, pauser, xdscache.ResourcesOf(resources)...), grpcServer)
 		default:
 			// This can't happen due to config validation.
 			log.Fatalf("invalid xDS server type %q", ctx.Config.Server.XDSServerType)
@@ -615,6 +998,15 @@ func doServe(log logrus.FieldLogger, ctx *serveContext) error {
 	return g.Run(context.Background())
 }
 
+// dualStackAddress returns "::" if address is the IPv4 wildcard
+// address. Envoy listeners bound to "::" also accept IPv4.
+func dualStackAddress(address string) string {
+	if address == "0.0.0.0" {
+		return "::"
+	}
+	return address
+}
+
 func contains(namespaces []string, ns string) bool {
 	for _, namespace := range namespaces {
 		if ns == namespace {
@@ -624,6 +1016,18 @@ func contains(namespaces []string, ns string) bool {
 	return false
 }
 
+// preferredIngressResource returns the first of the supported
+// Ingress resources that the API server serves.
+func preferredIngressResource(clients *k8s.Clients) (schema.GroupVersionResource, error) {
+	for _, r := range k8s.IngressResources() {
+		if clients.ResourcesExist(r) {
+			return r, nil
+		}
+	}
+
+	return schema.GroupVersionResource{}, errors.New("no supported Ingress resource is served by the API server")
+}
+
 func informOnResource(clients *k8s.Clients, gvr schema.GroupVersionResource, handler cache.ResourceEventHandler) error {
 	inf, err := clients.InformerForResource(gvr)
 	if err != nil {
@@ -14,23 +14,29 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/projectcontour/contour/internal/dag"
 	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/timeout"
 	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
 	"github.com/projectcontour/contour/pkg/config"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 )
 
 type serveContext struct {
@@ -59,10 +65,18 @@ type serveContext struct {
 	// ingress class
 	ingressClass string
 
+	// contourConfigName is the name of the ContourConfiguration
+	// resource in the Contour namespace to read settings from.
+	contourConfigName string
+
 	// envoy's stats listener parameters
 	statsAddr string
 	statsPort int
 
+	// envoy's metrics listener parameters
+	envoyMetricsAddr string
+	envoyMetricsPort int
+
 	// envoy's listener parameters
 	useProxyProto bool
 
@@ -219,6 +233,28 @@ func (ctx *serveContext) verifyTLSFlags() error {
 	return nil
 }
 
+// verifyHTTPListeners indicates if the additional HTTP listeners
+// have ports that are not used by the other Envoy listeners.
+func (ctx *serveContext) verifyHTTPListeners() error {
+	ports := map[int]string{
+		ctx.httpPort:  "HTTP",
+		ctx.httpsPort: "HTTPS",
+		ctx.statsPort: "stats",
+	}
+	if ctx.envoyMetricsPort != 0 {
+		ports[ctx.envoyMetricsPort] = "metrics"
+	}
+
+	for _, l := range ctx.Config.Network.HTTPListeners {
+		if used, ok := ports[l.Port]; ok {
+			return fmt.Errorf("invalid network http-listeners %q port %d: used by the Envoy %s listener", l.Name, l.Port, used)
+		}
+		ports[l.Port] = fmt.Sprintf("%q", l.Name)
+	}
+
+	return nil
+}
+
 // proxyRootNamespaces returns a slice of namespaces restricting where
 // contour should look for httpproxy roots.
 func (ctx *serveContext) proxyRootNamespaces() []string {
@@ -255,6 +291,17 @@ func parseDefaultHTTPVersions(versions []config.HTTPVersionType) []envoy_v3.HTTP
 	return parsed
 }
 
+// alpnProtocolsOf returns the configured ALPN protocol names
+// for the HTTPS listener, preserving their order of preference.
+func alpnProtocolsOf(protocols []config.ALPNProtocolType) []string {
+	var names []string
+	for _, p := range protocols {
+		names = append(names, string(p))
+	}
+
+	return names
+}
+
 func namespacedNameOf(n config.NamespacedName) *types.NamespacedName {
 	if len(strings.TrimSpace(n.Name)) == 0 && len(strings.TrimSpace(n.Namespace)) == 0 {
 		return nil
@@ -265,3 +312,180 @@ func namespacedNameOf(n config.NamespacedName) *types.NamespacedName {
 		Name:      n.Name,
 	}
 }
+
+// hstsPolicyOf returns the DAG HSTS policy for the configured
+// parameters, or nil if no max-age is set.
+func hstsPolicyOf(h config.HSTSParameters) *dag.HSTSPolicy {
+	if h.MaxAge == 0 {
+		return nil
+	}
+
+	return &dag.HSTSPolicy{
+		MaxAge:            h.MaxAge,
+		IncludeSubdomains: h.IncludeSubdomains,
+		Preload:           h.Preload,
+	}
+}
+
+// retryBudgetOf returns the DAG retry budget for the configured
+// parameters, or nil if no budget percent is set.
+func retryBudgetOf(r config.RetryBudgetParameters) *dag.RetryBudget {
+	if r.BudgetPercent == 0 {
+		return nil
+	}
+
+	return &dag.RetryBudget{
+		BudgetPercent:       r.BudgetPercent,
+		MinRetryConcurrency: r.MinRetryConcurrency,
+	}
+}
+
+// namespaceDefaultsOf returns the DAG route defaults of the
+// namespaces listed in the configured parameters, or nil if
+// there are none.
+func namespaceDefaultsOf(params map[string]config.RouteDefaultsParameters) (map[string]*dag.RouteDefaults, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	defaults := make(map[string]*dag.RouteDefaults, len(params))
+	for ns, p := range params {
+		d, err := routeDefaultsOf(p)
+		if err != nil {
+			return nil, fmt.Errorf("namespace defaults for %q: %w", ns, err)
+		}
+		defaults[ns] = d
+	}
+
+	return defaults, nil
+}
+
+// routeDefaultsOf returns the DAG route defaults for the
+// configured parameters.
+func routeDefaultsOf(p config.RouteDefaultsParameters) (*dag.RouteDefaults, error) {
+	response, err := timeout.Parse(p.TimeoutPolicy.Response)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response timeout: %w", err)
+	}
+
+	idle, err := timeout.Parse(p.TimeoutPolicy.Idle)
+	if err != nil {
+		return nil, fmt.Errorf("invalid idle timeout: %w", err)
+	}
+
+	reqHP, err := dag.HeadersPolicyOf(p.RequestHeaders.Set, p.RequestHeaders.Remove)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request headers: %w", err)
+	}
+
+	respHP, err := dag.HeadersPolicyOf(p.ResponseHeaders.Set, p.ResponseHeaders.Remove)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response headers: %w", err)
+	}
+
+	d := &dag.RouteDefaults{
+		TimeoutPolicy: dag.TimeoutPolicy{
+			ResponseTimeout: response,
+			IdleTimeout:     idle,
+		},
+		RequestHeadersPolicy:  reqHP,
+		ResponseHeadersPolicy: respHP,
+	}
+
+	if p.RetryPolicy.RetryOn != "" {
+		perTry, err := timeout.Parse(p.RetryPolicy.PerTryTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid per-try timeout: %w", err)
+		}
+
+		numRetries := p.RetryPolicy.NumRetries
+		if numRetries == 0 {
+			numRetries = 1
+		}
+
+		d.RetryPolicy = &dag.RetryPolicy{
+			RetryOn:       p.RetryPolicy.RetryOn,
+			NumRetries:    numRetries,
+			PerTryTimeout: perTry,
+		}
+	}
+
+	return d, nil
+}
+
+// maintenanceOf returns the maintenance response for the
+// configured parameters, or nil if maintenance mode is off.
+func maintenanceOf(m config.MaintenanceParameters) *xdscache_v3.Maintenance {
+	if !m.Enabled {
+		return nil
+	}
+
+	maintenance := &xdscache_v3.Maintenance{
+		Hosts:       m.Hosts,
+		StatusCode:  m.StatusCode,
+		Body:        m.Body,
+		ContentType: m.ContentType,
+	}
+
+	if maintenance.StatusCode == 0 {
+		maintenance.StatusCode = http.StatusServiceUnavailable
+	}
+
+	if maintenance.Body != "" && maintenance.ContentType == "" {
+		maintenance.ContentType = "text/plain"
+	}
+
+	return maintenance
+}
+
+// namespaceQuotaOf returns the DAG namespace quota for the
+// configured parameters.
+func namespaceQuotaOf(q config.NamespaceQuotaParameters) dag.NamespaceQuota {
+	return dag.NamespaceQuota{
+		MaxVirtualHosts: int(q.MaxVirtualHosts),
+		MaxRoutes:       int(q.MaxRoutes),
+	}
+}
+
+// namespaceQuotasOf returns the DAG quotas of the namespaces
+// listed in the configured parameters, or nil if there are none.
+func namespaceQuotasOf(q config.NamespaceQuotasParameters) map[string]dag.NamespaceQuota {
+	if len(q.Namespaces) == 0 {
+		return nil
+	}
+
+	quotas := make(map[string]dag.NamespaceQuota, len(q.Namespaces))
+	for ns, quota := range q.Namespaces {
+		quotas[ns] = namespaceQuotaOf(quota)
+	}
+
+	return quotas
+}
+
+// loadErrorPages fetches the response body of each configured error
+// page from its ConfigMap. ConfigMaps are only read once, so changes
+// to them take effect on the next Contour restart.
+func loadErrorPages(client kubernetes.Interface, pages []config.ErrorPageParameters) ([]envoy_v3.ErrorPage, error) {
+	var loaded []envoy_v3.ErrorPage
+
+	for _, p := range pages {
+		cm, err := client.CoreV1().ConfigMaps(p.ConfigMap.Namespace).Get(context.TODO(), p.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error page for status code %d: %w", p.StatusCode, err)
+		}
+
+		body, ok := cm.Data[p.Key]
+		if !ok {
+			return nil, fmt.Errorf("error page for status code %d: key %q not found in configmap %s/%s",
+				p.StatusCode, p.Key, p.ConfigMap.Namespace, p.ConfigMap.Name)
+		}
+
+		loaded = append(loaded, envoy_v3.ErrorPage{
+			StatusCode:  uint32(p.StatusCode),
+			Body:        body,
+			ContentType: p.ContentType,
+		})
+	}
+
+	return loaded, nil
+}
@@ -31,6 +31,9 @@ import (
 	"github.com/projectcontour/contour/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestServeContextProxyRootNamespaces(t *testing.T) {
@@ -120,6 +123,57 @@ func TestServeContextTLSParams(t *testing.T) {
 	}
 }
 
+func TestServeContextHTTPListeners(t *testing.T) {
+	tests := map[string]struct {
+		listeners   []config.HTTPListenerParameters
+		expecterror bool
+	}{
+		"unused ports": {
+			listeners: []config.HTTPListenerParameters{
+				{Name: "ingress_internal", Port: 8081},
+				{Name: "ingress_admin", Port: 8082},
+			},
+			expecterror: false,
+		},
+		"http port": {
+			listeners:   []config.HTTPListenerParameters{{Name: "ingress_internal", Port: 8080}},
+			expecterror: true,
+		},
+		"https port": {
+			listeners:   []config.HTTPListenerParameters{{Name: "ingress_internal", Port: 8443}},
+			expecterror: true,
+		},
+		"stats port": {
+			listeners:   []config.HTTPListenerParameters{{Name: "ingress_internal", Port: 8002}},
+			expecterror: true,
+		},
+		"metrics port": {
+			listeners:   []config.HTTPListenerParameters{{Name: "ingress_internal", Port: 8003}},
+			expecterror: true,
+		},
+		"same port": {
+			listeners: []config.HTTPListenerParameters{
+				{Name: "ingress_internal", Port: 8081},
+				{Name: "ingress_admin", Port: 8081},
+			},
+			expecterror: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := newServeContext()
+			ctx.envoyMetricsPort = 8003
+			ctx.Config.Network.HTTPListeners = tc.listeners
+
+			err := ctx.verifyHTTPListeners()
+			goterror := err != nil
+			if goterror != tc.expecterror {
+				t.Errorf("HTTP listeners: %v", err)
+			}
+		})
+	}
+}
+
 // Testdata for this test case can be re-generated by running:
 // make gencerts
 // cp certs/*.pem cmd/contour/testdata/X/
@@ -364,3 +418,46 @@ func TestParseHTTPVersions(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadErrorPages(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "error-pages",
+			Namespace: "projectcontour",
+		},
+		Data: map[string]string{
+			"503.html": "<h1>down for maintenance</h1>",
+		},
+	})
+
+	pages, err := loadErrorPages(client, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, pages)
+
+	pages, err = loadErrorPages(client, []config.ErrorPageParameters{{
+		StatusCode:  503,
+		ConfigMap:   config.NamespacedName{Name: "error-pages", Namespace: "projectcontour"},
+		Key:         "503.html",
+		ContentType: "text/plain",
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []envoy_v3.ErrorPage{{
+		StatusCode:  503,
+		Body:        "<h1>down for maintenance</h1>",
+		ContentType: "text/plain",
+	}}, pages)
+
+	_, err = loadErrorPages(client, []config.ErrorPageParameters{{
+		StatusCode: 502,
+		ConfigMap:  config.NamespacedName{Name: "error-pages", Namespace: "projectcontour"},
+		Key:        "502.html",
+	}})
+	assert.Error(t, err)
+
+	_, err = loadErrorPages(client, []config.ErrorPageParameters{{
+		StatusCode: 504,
+		ConfigMap:  config.NamespacedName{Name: "missing", Namespace: "projectcontour"},
+		Key:        "504.html",
+	}})
+	assert.Error(t, err)
+}
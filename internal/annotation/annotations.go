@@ -15,10 +15,15 @@ package annotation
 
 import (
 	"fmt"
+	"math"
+	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/projectcontour/contour/internal/timeout"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -26,10 +31,54 @@ import (
 // DEFAULT_INGRESS_CLASS is the Contour default.
 const DEFAULT_INGRESS_CLASS = "contour"
 
+// DefaultPrefix is the default prefix of Contour annotations.
+const DefaultPrefix = "projectcontour.io"
+
+// prefixes are the prefixes of Contour annotations, in order
+// of precedence.
+var prefixes = []string{DefaultPrefix}
+
+// SetPrefixes sets the prefix of Contour annotations, and the legacy
+// prefixes that are honored after it, in order, so that annotations
+// can be migrated from one prefix to another. An empty prefix is
+// replaced by DefaultPrefix. SetPrefixes must be called before any
+// objects are translated.
+func SetPrefixes(prefix string, legacy ...string) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	prefixes = append([]string{prefix}, legacy...)
+}
+
+// contourKey returns the key of a Contour annotation with the
+// DefaultPrefix, and true, if the key has a Contour prefix.
+func contourKey(key string) (string, bool) {
+	for _, p := range prefixes {
+		if strings.HasPrefix(key, p+"/") {
+			return DefaultPrefix + "/" + strings.TrimPrefix(key, p+"/"), true
+		}
+	}
+
+	return key, false
+}
+
+// lookup returns the value of the Contour annotation with the
+// first prefix that is present in the annotations.
+func lookup(a map[string]string, key string) (string, bool) {
+	for _, p := range prefixes {
+		if val, ok := a[p+"/"+key]; ok {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
 // IsKnown checks if an annotation is one Contour knows about.
 func IsKnown(key string) bool {
 	// We should know about everything with a Contour prefix.
-	if strings.HasPrefix(key, "projectcontour.io/") {
+	if _, ok := contourKey(key); ok {
 		return true
 	}
 
@@ -52,8 +101,10 @@ func IsKnown(key string) bool {
 var annotationsByKind = map[string]map[string]struct{}{
 	"Ingress": {
 		"ingress.kubernetes.io/force-ssl-redirect":       {},
+		"ingress.kubernetes.io/whitelist-source-range":   {},
 		"kubernetes.io/ingress.allow-http":               {},
 		"kubernetes.io/ingress.class":                    {},
+		"projectcontour.io/disabled":                     {},
 		"projectcontour.io/ingress.class":                {},
 		"projectcontour.io/num-retries":                  {},
 		"projectcontour.io/response-timeout":             {},
@@ -62,29 +113,37 @@ var annotationsByKind = map[string]map[string]struct{}{
 		"projectcontour.io/websocket-routes":             {},
 	},
 	"Service": {
-		"projectcontour.io/max-connections":       {},
-		"projectcontour.io/max-pending-requests":  {},
-		"projectcontour.io/max-requests":          {},
-		"projectcontour.io/max-retries":           {},
-		"projectcontour.io/upstream-protocol.h2":  {},
-		"projectcontour.io/upstream-protocol.h2c": {},
-		"projectcontour.io/upstream-protocol.tls": {},
+		"projectcontour.io/connect-timeout":                      {},
+		"projectcontour.io/dns-srv":                              {},
+		"projectcontour.io/http2-initial-connection-window-size": {},
+		"projectcontour.io/http2-initial-stream-window-size":     {},
+		"projectcontour.io/max-connections":                      {},
+		"projectcontour.io/max-pending-requests":                 {},
+		"projectcontour.io/max-requests":                         {},
+		"projectcontour.io/max-requests-per-connection":          {},
+		"projectcontour.io/max-retries":                          {},
+		"projectcontour.io/upstream-protocol.h2":                 {},
+		"projectcontour.io/upstream-protocol.h2c":                {},
+		"projectcontour.io/upstream-protocol.tls":                {},
 	},
 	"HTTPProxy": {
 		"kubernetes.io/ingress.class":     {},
+		"projectcontour.io/disabled":      {},
 		"projectcontour.io/ingress.class": {},
 	},
 }
 
 // ValidForKind checks if a particular annotation is valid for a given Kind.
 func ValidForKind(kind string, key string) bool {
+	key, isContour := contourKey(key)
+
 	if a, ok := annotationsByKind[kind]; ok {
 		_, ok := a[key]
 		return ok
 	}
 
 	// We should know about every kind with a Contour annotation prefix.
-	if strings.HasPrefix(key, "projectcontour.io/") {
+	if isContour {
 		return false
 	}
 
@@ -92,12 +151,11 @@ func ValidForKind(kind string, key string) bool {
 	return true
 }
 
-// ContourAnnotation checks the Object for the given annotation with the
-// "projectcontour.io/" prefix.
+// ContourAnnotation checks the Object for the given annotation with
+// the Contour annotation prefix, or else with each legacy prefix.
 func ContourAnnotation(o metav1.ObjectMetaAccessor, key string) string {
-	a := o.GetObjectMeta().GetAnnotations()
-
-	return a["projectcontour.io/"+key]
+	val, _ := lookup(o.GetObjectMeta().GetAnnotations(), key)
+	return val
 }
 
 // ParseUInt32 parses the supplied string as if it were a uint32.
@@ -117,7 +175,7 @@ func ParseUpstreamProtocols(m map[string]string) map[string]string {
 	protocols := []string{"h2", "h2c", "tls"}
 	up := make(map[string]string)
 	for _, protocol := range protocols {
-		ports := m[fmt.Sprintf("projectcontour.io/upstream-protocol.%s", protocol)]
+		ports, _ := lookup(m, "upstream-protocol."+protocol)
 		for _, v := range strings.Split(ports, ",") {
 			port := strings.TrimSpace(v)
 			if port != "" {
@@ -140,11 +198,117 @@ func TLSRequired(i *v1beta1.Ingress) bool {
 	return i.Annotations["ingress.kubernetes.io/force-ssl-redirect"] == "true"
 }
 
+// WhitelistSourceRange parses the comma separated list of CIDR ranges
+// in the ingress.kubernetes.io/whitelist-source-range annotation. Bare
+// addresses are treated as ranges containing a single address.
+func WhitelistSourceRange(i *v1beta1.Ingress) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, v := range strings.Split(i.Annotations["ingress.kubernetes.io/whitelist-source-range"], ",") {
+		cidr := strings.TrimSpace(v)
+		if cidr == "" {
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid source range %q", cidr)
+			}
+
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				bits = net.IPv4len * 8
+			}
+
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source range %q", cidr)
+		}
+		ranges = append(ranges, ipnet)
+	}
+	return ranges, nil
+}
+
+// NginxAnnotation checks the Object for the given annotation with the
+// "nginx.ingress.kubernetes.io/" prefix.
+func NginxAnnotation(o metav1.ObjectMetaAccessor, key string) string {
+	a := o.GetObjectMeta().GetAnnotations()
+
+	return a["nginx.ingress.kubernetes.io/"+key]
+}
+
+// NginxSSLRedirect returns true if either of the
+// nginx.ingress.kubernetes.io/ssl-redirect or
+// nginx.ingress.kubernetes.io/force-ssl-redirect
+// annotations is present and set to true.
+func NginxSSLRedirect(i *v1beta1.Ingress) bool {
+	return NginxAnnotation(i, "ssl-redirect") == "true" ||
+		NginxAnnotation(i, "force-ssl-redirect") == "true"
+}
+
+// NginxProxyBodySize parses the nginx.ingress.kubernetes.io/proxy-body-size
+// annotation, an nginx size such as "8m", into a number of bytes. Zero means
+// that the annotation is not present, or that request bodies are not limited.
+func NginxProxyBodySize(i *v1beta1.Ingress) (uint32, error) {
+	size := strings.TrimSpace(NginxAnnotation(i, "proxy-body-size"))
+	if size == "" {
+		return 0, nil
+	}
+
+	multiplier := uint64(1)
+	switch size[len(size)-1] {
+	case 'k', 'K':
+		multiplier = 1 << 10
+	case 'm', 'M':
+		multiplier = 1 << 20
+	case 'g', 'G':
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		size = size[:len(size)-1]
+	}
+
+	v, err := strconv.ParseUint(size, 10, 32)
+	if err != nil || v*multiplier > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid proxy body size %q", NginxAnnotation(i, "proxy-body-size"))
+	}
+	return uint32(v * multiplier), nil
+}
+
+// NginxUpstreamHashByHeader parses the nginx.ingress.kubernetes.io/upstream-hash-by
+// annotation and returns the name of the request header to hash. Only the
+// nginx "$http_<name>" header variables are supported; underscores in the
+// variable name are translated to dashes. An empty string means that the
+// annotation is not present.
+func NginxUpstreamHashByHeader(i *v1beta1.Ingress) (string, error) {
+	hashBy := strings.TrimSpace(NginxAnnotation(i, "upstream-hash-by"))
+	if hashBy == "" {
+		return "", nil
+	}
+
+	name := strings.TrimPrefix(hashBy, "$http_")
+	if name == hashBy || name == "" {
+		return "", fmt.Errorf("unsupported upstream hash key %q", hashBy)
+	}
+	return strings.ReplaceAll(name, "_", "-"), nil
+}
+
+// Disabled returns true if the projectcontour.io/disabled annotation
+// is present and set to true.
+func Disabled(o metav1.ObjectMetaAccessor) bool {
+	return ContourAnnotation(o, "disabled") == "true"
+}
+
 // WebsocketRoutes retrieves the details of routes that should have websockets enabled from the
 // associated websocket-routes annotation.
 func WebsocketRoutes(i *v1beta1.Ingress) map[string]bool {
 	routes := make(map[string]bool)
-	for _, v := range strings.Split(i.Annotations["projectcontour.io/websocket-routes"], ",") {
+	for _, v := range strings.Split(ContourAnnotation(i, "websocket-routes"), ",") {
 		route := strings.TrimSpace(v)
 		if route != "" {
 			routes[route] = true
@@ -168,14 +332,30 @@ func PerTryTimeout(i *v1beta1.Ingress) (timeout.Setting, error) {
 // annotations:
 // 1. projectcontour.io/ingress.class
 // 2. kubernetes.io/ingress.class
+// If neither annotation is present, the spec.ingressClassName field of an
+// Ingress is used.
 func IngressClass(o metav1.ObjectMetaAccessor) string {
 	a := o.GetObjectMeta().GetAnnotations()
-	if class, ok := a["projectcontour.io/ingress.class"]; ok {
+	if class, ok := lookup(a, "ingress.class"); ok {
 		return class
 	}
 	if class, ok := a["kubernetes.io/ingress.class"]; ok {
 		return class
 	}
+
+	var className *string
+	switch o := o.(type) {
+	case *v1beta1.Ingress:
+		className = o.Spec.IngressClassName
+	case *networking_v1.Ingress:
+		className = o.Spec.IngressClassName
+	case *extensions_v1beta1.Ingress:
+		className = o.Spec.IngressClassName
+	}
+	if className != nil {
+		return *className
+	}
+
 	return ""
 }
 
@@ -244,3 +424,65 @@ func MaxRequests(o metav1.ObjectMetaAccessor) uint32 {
 func MaxRetries(o metav1.ObjectMetaAccessor) uint32 {
 	return parseUInt32(ContourAnnotation(o, "max-retries"))
 }
+
+// MaxRequestsPerConnection returns the value of the first matching
+// max-requests-per-connection annotation for the following annotations:
+// 1. projectcontour.io/max-requests-per-connection
+//
+// '0' is returned if the annotation is absent or unparsable.
+func MaxRequestsPerConnection(o metav1.ObjectMetaAccessor) uint32 {
+	return parseUInt32(ContourAnnotation(o, "max-requests-per-connection"))
+}
+
+// ConnectTimeout returns the value of the first matching
+// connect-timeout annotation for the following annotations:
+// 1. projectcontour.io/connect-timeout
+//
+// '0' is returned if the annotation is absent, unparsable or not positive.
+func ConnectTimeout(o metav1.ObjectMetaAccessor) time.Duration {
+	d, err := time.ParseDuration(ContourAnnotation(o, "connect-timeout"))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// DNSSRV returns true if the value of the first matching dns-srv
+// annotation for the following annotations is "true":
+// 1. projectcontour.io/dns-srv
+//
+// The external name of such a Service is resolved with DNS SRV
+// records, instead of address records.
+func DNSSRV(o metav1.ObjectMetaAccessor) bool {
+	return ContourAnnotation(o, "dns-srv") == "true"
+}
+
+// HTTP2InitialStreamWindowSize returns the value of the first matching
+// http2-initial-stream-window-size annotation for the following annotations:
+// 1. projectcontour.io/http2-initial-stream-window-size
+//
+// '0' is returned if the annotation is absent, unparsable or outside
+// of the range accepted by Envoy.
+func HTTP2InitialStreamWindowSize(o metav1.ObjectMetaAccessor) uint32 {
+	return http2WindowSize(ContourAnnotation(o, "http2-initial-stream-window-size"))
+}
+
+// HTTP2InitialConnectionWindowSize returns the value of the first matching
+// http2-initial-connection-window-size annotation for the following annotations:
+// 1. projectcontour.io/http2-initial-connection-window-size
+//
+// '0' is returned if the annotation is absent, unparsable or outside
+// of the range accepted by Envoy.
+func HTTP2InitialConnectionWindowSize(o metav1.ObjectMetaAccessor) uint32 {
+	return http2WindowSize(ContourAnnotation(o, "http2-initial-connection-window-size"))
+}
+
+// http2WindowSize parses s as an HTTP/2 window size, which Envoy
+// requires to be between 65535 and 2147483647 bytes.
+func http2WindowSize(s string) uint32 {
+	v := parseUInt32(s)
+	if v < 65535 || v > 2147483647 {
+		return 0
+	}
+	return v
+}
@@ -15,14 +15,18 @@ package annotation
 
 import (
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 )
 
 func TestParseUint32(t *testing.T) {
@@ -62,6 +66,48 @@ func TestParseUint32(t *testing.T) {
 	}
 }
 
+func TestConnectTimeout(t *testing.T) {
+	tests := map[string]struct {
+		s    string
+		want time.Duration
+	}{
+		"blank":    {s: "", want: 0},
+		"valid":    {s: "2s", want: 2 * time.Second},
+		"negative": {s: "-1s", want: 0},
+		"invalid":  {s: "forever", want: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"projectcontour.io/connect-timeout": tc.s},
+				},
+			}
+			assert.Equal(t, tc.want, ConnectTimeout(svc))
+		})
+	}
+}
+
+func TestHTTP2WindowSize(t *testing.T) {
+	tests := map[string]struct {
+		s    string
+		want uint32
+	}{
+		"blank":     {s: "", want: 0},
+		"minimum":   {s: "65535", want: 65535},
+		"maximum":   {s: "2147483647", want: 2147483647},
+		"too small": {s: "1024", want: 0},
+		"too large": {s: "2147483648", want: 0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, http2WindowSize(tc.s))
+		})
+	}
+}
+
 func TestParseUpstreamProtocols(t *testing.T) {
 	tests := map[string]struct {
 		a    map[string]string
@@ -184,6 +230,125 @@ func TestWebsocketRoutes(t *testing.T) {
 	}
 }
 
+func TestWhitelistSourceRange(t *testing.T) {
+	ingress := func(value string) *v1beta1.Ingress {
+		return &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"ingress.kubernetes.io/whitelist-source-range": value,
+				},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		a       *v1beta1.Ingress
+		want    []*net.IPNet
+		wantErr bool
+	}{
+		"not set": {
+			a:    &v1beta1.Ingress{},
+			want: nil,
+		},
+		"empty with spaces": {
+			a:    ingress(" , "),
+			want: nil,
+		},
+		"ipv4 and ipv6 ranges": {
+			a: ingress("10.0.0.0/8, 2001:db8::/32"),
+			want: []*net.IPNet{
+				{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+				{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+			},
+		},
+		"range with host bits set": {
+			a: ingress("192.168.1.10/24"),
+			want: []*net.IPNet{
+				{IP: net.IP{192, 168, 1, 0}, Mask: net.CIDRMask(24, 32)},
+			},
+		},
+		"bare addresses": {
+			a: ingress("192.168.1.1,::1"),
+			want: []*net.IPNet{
+				{IP: net.IP{192, 168, 1, 1}, Mask: net.CIDRMask(32, 32)},
+				{IP: net.ParseIP("::1"), Mask: net.CIDRMask(128, 128)},
+			},
+		},
+		"invalid range": {
+			a:       ingress("10.0.0.0/8,10.0.0.0/33"),
+			wantErr: true,
+		},
+		"invalid address": {
+			a:       ingress("banana"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := WhitelistSourceRange(tc.a)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNginxProxyBodySize(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    uint32
+		wantErr bool
+	}{
+		"not set":          {value: "", want: 0},
+		"unlimited":        {value: "0", want: 0},
+		"bytes":            {value: "1024", want: 1024},
+		"kilobytes":        {value: "64k", want: 64 << 10},
+		"megabytes":        {value: "8M", want: 8 << 20},
+		"gigabytes":        {value: "1g", want: 1 << 30},
+		"too large":        {value: "4g", wantErr: true},
+		"invalid unit":     {value: "8mb", wantErr: true},
+		"negative":         {value: "-1", wantErr: true},
+		"missing quantity": {value: "m", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NginxProxyBodySize(&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"nginx.ingress.kubernetes.io/proxy-body-size": tc.value,
+					},
+				},
+			})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNginxSSLRedirect(t *testing.T) {
+	redirect := func(annotations map[string]string) bool {
+		return NginxSSLRedirect(&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: annotations,
+			},
+		})
+	}
+
+	assert.False(t, redirect(nil))
+	assert.False(t, redirect(map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "false"}))
+	assert.True(t, redirect(map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"}))
+	assert.True(t, redirect(map[string]string{"nginx.ingress.kubernetes.io/force-ssl-redirect": "true"}))
+}
+
 func TestHttpAllowed(t *testing.T) {
 	tests := map[string]struct {
 		i     *v1beta1.Ingress
@@ -273,6 +438,51 @@ func TestAnnotationCompat(t *testing.T) {
 	}
 }
 
+func TestAnnotationPrefixes(t *testing.T) {
+	SetPrefixes("example.com", "projectcontour.io", "contour.heptio.com")
+	defer SetPrefixes("")
+
+	svc := func(annotations map[string]string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: annotations,
+			},
+		}
+	}
+
+	// The prefix takes precedence over the legacy prefixes,
+	// which are honored in order.
+	assert.Equal(t, "1", ContourAnnotation(svc(map[string]string{
+		"example.com/max-requests":        "1",
+		"projectcontour.io/max-requests":  "2",
+		"contour.heptio.com/max-requests": "3",
+	}), "max-requests"))
+	assert.Equal(t, "2", ContourAnnotation(svc(map[string]string{
+		"projectcontour.io/max-requests":  "2",
+		"contour.heptio.com/max-requests": "3",
+	}), "max-requests"))
+	assert.Equal(t, "3", ContourAnnotation(svc(map[string]string{
+		"contour.heptio.com/max-requests": "3",
+	}), "max-requests"))
+
+	assert.Equal(t, map[string]string{"80": "h2c", "443": "tls"}, ParseUpstreamProtocols(map[string]string{
+		"example.com/upstream-protocol.h2c":        "80",
+		"contour.heptio.com/upstream-protocol.tls": "443",
+	}))
+
+	assert.True(t, IsKnown("example.com/max-requests"))
+	assert.True(t, ValidForKind("Service", "example.com/max-requests"))
+	assert.True(t, ValidForKind("Service", "contour.heptio.com/max-requests"))
+	assert.False(t, ValidForKind("HTTPProxy", "example.com/max-requests"))
+
+	// Annotations with other prefixes are ignored.
+	SetPrefixes("example.com")
+	assert.Equal(t, "", ContourAnnotation(svc(map[string]string{
+		"projectcontour.io/max-requests": "2",
+	}), "max-requests"))
+	assert.False(t, IsKnown("contour.heptio.com/max-requests"))
+}
+
 func TestAnnotationKindValidation(t *testing.T) {
 	type status struct {
 		known bool
@@ -304,6 +514,9 @@ func TestAnnotationKindValidation(t *testing.T) {
 				"projectcontour.io/websocket-routes": {
 					known: true, valid: false,
 				},
+				"projectcontour.io/disabled": {
+					known: true, valid: true,
+				},
 			},
 		},
 		"secrets": {
@@ -417,6 +630,45 @@ func TestMatchIngressClass(t *testing.T) {
 			},
 			want: []bool{true, false},
 		},
+		"ingress contour spec.ingressClassName": {
+			fixture: &v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "classname",
+					Namespace: "default",
+				},
+				Spec: v1beta1.IngressSpec{
+					IngressClassName: pointer.StringPtr(DEFAULT_INGRESS_CLASS),
+				},
+			},
+			want: []bool{true, true},
+		},
+		"networking v1 ingress nginx spec.ingressClassName": {
+			fixture: &networking_v1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "classname",
+					Namespace: "default",
+				},
+				Spec: networking_v1.IngressSpec{
+					IngressClassName: pointer.StringPtr("nginx"),
+				},
+			},
+			want: []bool{false, false},
+		},
+		"annotation overrides spec.ingressClassName": {
+			fixture: &v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "classname",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"projectcontour.io/ingress.class": DEFAULT_INGRESS_CLASS,
+					},
+				},
+				Spec: v1beta1.IngressSpec{
+					IngressClassName: pointer.StringPtr("nginx"),
+				},
+			},
+			want: []bool{true, true},
+		},
 	}
 
 	for name, tc := range tests {
@@ -462,3 +714,37 @@ func kindOf(obj interface{}) string {
 		return ""
 	}
 }
+
+func TestNginxUpstreamHashByHeader(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		"not set":        {value: "", want: ""},
+		"header":         {value: "$http_x_user_id", want: "x-user-id"},
+		"single word":    {value: "$http_authorization", want: "authorization"},
+		"missing name":   {value: "$http_", wantErr: true},
+		"remote address": {value: "$remote_addr", wantErr: true},
+		"request uri":    {value: "$request_uri", wantErr: true},
+		"plain header":   {value: "X-User-Id", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NginxUpstreamHashByHeader(&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"nginx.ingress.kubernetes.io/upstream-hash-by": tc.value,
+					},
+				},
+			})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
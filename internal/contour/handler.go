@@ -25,7 +25,9 @@ import (
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 // EventHandler implements cache.ResourceEventHandler, filters k8s events towards
@@ -39,6 +41,12 @@ type EventHandler struct {
 
 	StatusUpdater k8s.StatusUpdater
 
+	// EventRecorder, if not nil, records the events of each
+	// DAG as Kubernetes events while this EventHandler is the
+	// leader. An event is recorded again only after it has
+	// been absent from a DAG.
+	EventRecorder record.EventRecorder
+
 	logrus.FieldLogger
 
 	// IsLeader will become ready to read when this EventHandler becomes
@@ -58,6 +66,23 @@ type EventHandler struct {
 	// seq is the sequence counter of the number of times
 	// an event has been received.
 	seq int
+
+	// Ready, if not nil, is closed once the first DAG built
+	// after Synced is called has been sent to the Observer.
+	Ready chan struct{}
+
+	// synced records that Synced has been called.
+	synced bool
+
+	// recorded holds the events of the last DAG
+	// that were recorded.
+	recorded map[eventKey]bool
+}
+
+// eventKey identifies a dag.Event.
+type eventKey struct {
+	kind, namespace, name string
+	reason, message       string
 }
 
 type opAdd struct {
@@ -72,6 +97,12 @@ type opDelete struct {
 	obj interface{}
 }
 
+type opResync struct {
+	objs []interface{}
+}
+
+type opSynced struct{}
+
 func (e *EventHandler) OnAdd(obj interface{}) {
 	e.update <- opAdd{obj: obj}
 }
@@ -84,6 +115,22 @@ func (e *EventHandler) OnDelete(obj interface{}) {
 	e.update <- opDelete{obj: obj}
 }
 
+// Resync replaces the objects in the DAG builder's cache with objs,
+// which are expected to be a fresh listing of every resource the
+// EventHandler is informed of, and enqueues a DAG update subject to
+// the holdoff timer. This recovers from missed watch events.
+func (e *EventHandler) Resync(objs []interface{}) {
+	e.update <- opResync{objs: objs}
+}
+
+// Synced records that the informer caches have synced, and
+// enqueues a DAG update subject to the holdoff timer. Ready
+// is closed once this update, which is built from a complete
+// cache, has been sent to the Observer.
+func (e *EventHandler) Synced() {
+	e.update <- opSynced{}
+}
+
 // UpdateNow enqueues a DAG update subject to the holdoff timer.
 func (e *EventHandler) UpdateNow() {
 	e.update <- true
@@ -161,6 +208,9 @@ func (e *EventHandler) run(stop <-chan struct{}) error {
 			e.rebuildDAG()
 			e.incSequence()
 			lastDAGRebuild = time.Now()
+			if e.synced {
+				e.markReady()
+			}
 		case <-stop:
 			// shutdown
 			return nil
@@ -189,6 +239,15 @@ func (e *EventHandler) onUpdate(op interface{}) bool {
 		return remove || insert
 	case opDelete:
 		return e.Builder.Source.Remove(op.obj)
+	case opResync:
+		e.Builder.Source.Reset()
+		for _, obj := range op.objs {
+			e.Builder.Source.Insert(obj)
+		}
+		return true
+	case opSynced:
+		e.synced = true
+		return true
 	case bool:
 		return op
 	default:
@@ -207,6 +266,19 @@ func (e *EventHandler) incSequence() {
 	}
 }
 
+// markReady closes e.Ready, if it is set and not yet closed.
+func (e *EventHandler) markReady() {
+	if e.Ready == nil {
+		return
+	}
+	select {
+	case <-e.Ready:
+	default:
+		e.Info("first DAG built from synced informer caches")
+		close(e.Ready)
+	}
+}
+
 // rebuildDAG builds a new DAG and sends it to the Observer,
 // the updates the status on objects, and updates the metrics.
 func (e *EventHandler) rebuildDAG() {
@@ -217,4 +289,40 @@ func (e *EventHandler) rebuildDAG() {
 		e.StatusUpdater.Send(upd)
 	}
 
+	e.recordEvents(latestDAG.Events)
+}
+
+// recordEvents records the events that were not
+// part of the previous DAG, if this EventHandler
+// is the leader.
+func (e *EventHandler) recordEvents(events []dag.Event) {
+	if e.EventRecorder == nil {
+		return
+	}
+
+	select {
+	case <-e.IsLeader:
+	default:
+		return
+	}
+
+	recorded := make(map[eventKey]bool, len(events))
+	for _, ev := range events {
+		meta, ok := ev.Object.(metav1.Object)
+		if !ok {
+			continue
+		}
+		key := eventKey{
+			kind:      k8s.KindOf(ev.Object),
+			namespace: meta.GetNamespace(),
+			name:      meta.GetName(),
+			reason:    ev.Reason,
+			message:   ev.Message,
+		}
+		if !e.recorded[key] {
+			e.EventRecorder.Event(ev.Object, v1.EventTypeWarning, ev.Reason, ev.Message)
+		}
+		recorded[key] = true
+	}
+	e.recorded = recorded
 }
@@ -17,6 +17,7 @@
 package contour
 
 import (
+	"strconv"
 	"time"
 
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
@@ -25,6 +26,7 @@ import (
 	"github.com/projectcontour/contour/internal/metrics"
 	"github.com/projectcontour/contour/internal/status"
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -79,10 +81,14 @@ func (m *RebuildMetricsObserver) OnChange(d *dag.DAG) {
 	m.NextObserver.OnChange(d)
 	timer.ObserveDuration()
 
+	m.Metrics.SetVirtualHostRoutesMetric(calculateVirtualHostRoutesMetric(d))
+	m.Metrics.SetListenerSecretsMetric(calculateListenerSecretsMetric(d))
+
 	select {
 	// If we are leader, the IsLeader channel is closed.
 	case <-m.IsLeader:
 		m.Metrics.SetHTTPProxyMetric(calculateRouteMetric(d.StatusCache.GetProxyUpdates()))
+		m.Metrics.SetRejectedObjectsMetric(calculateRejectedMetric(d))
 	default:
 	}
 }
@@ -124,3 +130,118 @@ func calcMetrics(u *status.ProxyUpdate, metricValid map[metrics.Meta]int, metric
 	}
 	metricTotal[metrics.Meta{Namespace: u.Fullname.Namespace}]++
 }
+
+// listenerName returns the name of the Envoy listener
+// that is generated for the DAG listener.
+func listenerName(l *dag.Listener) string {
+	if l.Name != "" {
+		return l.Name
+	}
+
+	switch l.Port {
+	case 80:
+		return "ingress_http"
+	case 443:
+		return "ingress_https"
+	default:
+		return strconv.Itoa(l.Port)
+	}
+}
+
+// calculateVirtualHostRoutesMetric counts the routes
+// of each virtual host on each listener of the DAG.
+func calculateVirtualHostRoutesMetric(d *dag.DAG) map[metrics.VirtualHostMeta]int {
+	routes := make(map[metrics.VirtualHostMeta]int)
+
+	d.Visit(func(vertex dag.Vertex) {
+		l, ok := vertex.(*dag.Listener)
+		if !ok {
+			return
+		}
+
+		for _, vh := range l.VirtualHosts {
+			var name string
+			switch vh := vh.(type) {
+			case *dag.VirtualHost:
+				name = vh.Name
+			case *dag.SecureVirtualHost:
+				name = vh.Name
+			default:
+				continue
+			}
+
+			count := 0
+			vh.Visit(func(v dag.Vertex) {
+				if _, ok := v.(*dag.Route); ok {
+					count++
+				}
+			})
+			routes[metrics.VirtualHostMeta{Listener: listenerName(l), VHost: name}] = count
+		}
+	})
+
+	return routes
+}
+
+// calculateListenerSecretsMetric counts the distinct Secrets
+// that the secure virtual hosts of each listener serve
+// certificates from.
+func calculateListenerSecretsMetric(d *dag.DAG) map[string]int {
+	secrets := make(map[string]int)
+
+	d.Visit(func(vertex dag.Vertex) {
+		l, ok := vertex.(*dag.Listener)
+		if !ok {
+			return
+		}
+
+		seen := map[types.NamespacedName]bool{}
+		for _, vh := range l.VirtualHosts {
+			svh, ok := vh.(*dag.SecureVirtualHost)
+			if !ok {
+				continue
+			}
+			for _, s := range []*dag.Secret{svh.Secret, svh.SecondarySecret, svh.FallbackCertificate} {
+				if s != nil {
+					seen[k8s.NamespacedNameOf(s.Object)] = true
+				}
+			}
+		}
+
+		secrets[listenerName(l)] = len(seen)
+	})
+
+	return secrets
+}
+
+// calculateRejectedMetric counts the objects rejected while building
+// the DAG. Each invalid HTTPProxy is counted once for every distinct
+// reason in its status; orphaned HTTPProxies are counted separately
+// by calculateRouteMetric.
+func calculateRejectedMetric(d *dag.DAG) map[metrics.RejectedMeta]int {
+	rejected := make(map[metrics.RejectedMeta]int)
+
+	for obj, count := range d.RejectedObjects {
+		rejected[metrics.RejectedMeta{Kind: obj.Kind, Namespace: obj.Namespace, Reason: obj.Reason}] += count
+	}
+
+	for _, u := range d.StatusCache.GetProxyUpdates() {
+		validCond := u.ConditionFor(status.ValidCondition)
+		if validCond.Status != contour_api_v1.ConditionFalse {
+			continue
+		}
+		if _, ok := validCond.GetError(contour_api_v1.ConditionTypeOrphanedError); ok {
+			continue
+		}
+
+		reasons := map[string]bool{}
+		for _, e := range validCond.Errors {
+			reasons[e.Reason] = true
+		}
+		for reason := range reasons {
+			rejected[metrics.RejectedMeta{Kind: "HTTPProxy", Namespace: u.Fullname.Namespace, Reason: reason}]++
+		}
+	}
+
+	return rejected
+}
@@ -22,7 +22,9 @@ import (
 	"github.com/projectcontour/contour/internal/metrics"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestHTTPProxyMetrics(t *testing.T) {
@@ -553,3 +555,128 @@ func TestHTTPProxyMetrics(t *testing.T) {
 		},
 	})
 }
+
+func TestRejectedObjectsMetrics(t *testing.T) {
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []dag.Processor{
+			&dag.IngressProcessor{
+				FieldLogger: fixture.NewTestLogger(t),
+			},
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{},
+		},
+	}
+
+	// An Ingress with a missing TLS secret and a missing
+	// backend service.
+	builder.Source.Insert(&v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "broken",
+			Namespace: "default",
+		},
+		Spec: v1beta1.IngressSpec{
+			TLS: []v1beta1.IngressTLS{{
+				Hosts:      []string{"example.com"},
+				SecretName: "missing",
+			}},
+			Rules: []v1beta1.IngressRule{{
+				Host: "example.com",
+				IngressRuleValue: v1beta1.IngressRuleValue{
+					HTTP: &v1beta1.HTTPIngressRuleValue{
+						Paths: []v1beta1.HTTPIngressPath{{
+							Backend: v1beta1.IngressBackend{
+								ServiceName: "missing",
+								ServicePort: intstr.FromInt(80),
+							},
+						}},
+					},
+				},
+			}},
+		},
+	})
+
+	// An HTTPProxy without an fqdn.
+	builder.Source.Insert(&contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nofqdn",
+			Namespace: "roots",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{},
+		},
+	})
+
+	got := calculateRejectedMetric(builder.Build())
+
+	assert.Equal(t, map[metrics.RejectedMeta]int{
+		{Kind: "Ingress", Namespace: "default", Reason: "SecretNotValid"}:             1,
+		{Kind: "Ingress", Namespace: "default", Reason: "ServiceUnresolvedReference"}: 1,
+		{Kind: "HTTPProxy", Namespace: "roots", Reason: "FQDNNotSpecified"}:           1,
+	}, got)
+}
+
+func TestVirtualHostMetrics(t *testing.T) {
+	builder := dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []dag.Processor{
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{},
+		},
+	}
+
+	builder.Source.Insert(fixture.SecretRootsCert)
+	builder.Source.Insert(fixture.ServiceRootsKuard)
+	builder.Source.Insert(&contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "roots",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName: fixture.SecretRootsCert.Name,
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Conditions: []contour_api_v1.MatchCondition{{Prefix: "/"}},
+				Services:   []contour_api_v1.Service{{Name: fixture.ServiceRootsKuard.Name, Port: 8080}},
+			}, {
+				Conditions: []contour_api_v1.MatchCondition{{Prefix: "/api"}},
+				Services:   []contour_api_v1.Service{{Name: fixture.ServiceRootsKuard.Name, Port: 8080}},
+			}},
+		},
+	})
+	builder.Source.Insert(&contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "plain",
+			Namespace: "roots",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "plain.example.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{Name: fixture.ServiceRootsKuard.Name, Port: 8080}},
+			}},
+		},
+	})
+
+	d := builder.Build()
+
+	assert.Equal(t, map[metrics.VirtualHostMeta]int{
+		{Listener: "ingress_http", VHost: "example.com"}:       2,
+		{Listener: "ingress_http", VHost: "plain.example.com"}: 1,
+		{Listener: "ingress_https", VHost: "example.com"}:      2,
+	}, calculateVirtualHostRoutesMetric(d))
+
+	assert.Equal(t, map[string]int{
+		"ingress_http":  0,
+		"ingress_https": 1,
+	}, calculateListenerSecretsMetric(d))
+}
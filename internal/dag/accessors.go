@@ -20,6 +20,7 @@ import (
 	"github.com/projectcontour/contour/internal/annotation"
 	"github.com/projectcontour/contour/internal/k8s"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -74,10 +75,35 @@ func (dag *DAG) EnsureService(meta types.NamespacedName, port intstr.IntOrString
 		MaxRequests:        annotation.MaxRequests(svc),
 		MaxRetries:         annotation.MaxRetries(svc),
 		ExternalName:       externalName(svc),
+		ClientIPAffinity:   svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP,
+
+		MaxRequestsPerConnection:         annotation.MaxRequestsPerConnection(svc),
+		ConnectTimeout:                   annotation.ConnectTimeout(svc),
+		HTTP2InitialStreamWindowSize:     annotation.HTTP2InitialStreamWindowSize(svc),
+		HTTP2InitialConnectionWindowSize: annotation.HTTP2InitialConnectionWindowSize(svc),
 	}
 	return dagSvc, nil
 }
 
+// ExternalService returns a DAG service for a backend outside of the
+// cluster with the given address. The service is identified by the
+// namespace and name, but is not backed by a Kubernetes Service.
+func ExternalService(meta types.NamespacedName, address string, port int32) *Service {
+	return &Service{
+		Weighted: WeightedService{
+			ServiceName:      meta.Name,
+			ServiceNamespace: meta.Namespace,
+			ServicePort: v1.ServicePort{
+				Protocol: v1.ProtocolTCP,
+				Port:     port,
+			},
+			Weight: 1,
+		},
+		ExternalName: address,
+		External:     true,
+	}
+}
+
 func upstreamProtocol(svc *v1.Service, port v1.ServicePort) string {
 	up := annotation.ParseUpstreamProtocols(svc.Annotations)
 	protocol := up[port.Name]
@@ -87,8 +113,12 @@ func upstreamProtocol(svc *v1.Service, port v1.ServicePort) string {
 	return protocol
 }
 
+// externalName returns the external name of an ExternalName Service
+// that Envoy resolves itself. The external name of a Service that is
+// resolved with DNS SRV records is not returned, since its endpoints
+// are discovered like those of any other Service.
 func externalName(svc *v1.Service) string {
-	if svc.Spec.Type != v1.ServiceTypeExternalName {
+	if svc.Spec.Type != v1.ServiceTypeExternalName || annotation.DNSSRV(svc) {
 		return ""
 	}
 	return svc.Spec.ExternalName
@@ -101,6 +131,11 @@ type serviceGetter map[RouteServiceName]*Service
 func (s serviceGetter) visit(vertex Vertex) {
 	switch obj := vertex.(type) {
 	case *Service:
+		if obj.External {
+			// External backends are not Kubernetes Services,
+			// so must not be returned for Service lookups.
+			return
+		}
 		s[RouteServiceName{
 			Name:      obj.Weighted.ServiceName,
 			Namespace: obj.Weighted.ServiceNamespace,
@@ -237,3 +272,25 @@ func validSecret(s *v1.Secret) error {
 
 	return nil
 }
+
+// rejectObject records that obj was rejected for the given reason.
+func (dag *DAG) rejectObject(obj k8s.Object, reason string) {
+	if dag.RejectedObjects == nil {
+		dag.RejectedObjects = map[RejectedObject]int{}
+	}
+
+	dag.RejectedObjects[RejectedObject{
+		Kind:      k8s.KindOf(obj),
+		Namespace: obj.GetObjectMeta().GetNamespace(),
+		Reason:    reason,
+	}]++
+}
+
+// recordEvent records a warning event about obj.
+func (dag *DAG) recordEvent(obj runtime.Object, reason, message string) {
+	dag.Events = append(dag.Events, Event{
+		Object:  obj,
+		Reason:  reason,
+		Message: message,
+	})
+}
@@ -25,6 +25,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -1039,6 +1040,30 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	proxyRequestBodyPolicy := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bar-com",
+			Namespace: "default",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "bar.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				Conditions: []contour_api_v1.MatchCondition{{
+					Prefix: "/",
+				}},
+				RequestBodyPolicy: &contour_api_v1.RequestBodyPolicy{
+					MaxRequestBytes: 8192,
+				},
+				Services: []contour_api_v1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
 	proxyRetryPolicyInvalidTimeout := &contour_api_v1.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "bar-com",
@@ -1176,6 +1201,10 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	// s1clientip has ClientIP session affinity
+	s1clientip := s1.DeepCopy()
+	s1clientip.Spec.SessionAffinity = v1.ServiceAffinityClientIP
+
 	// s1a carries the tls annotation
 	s1a := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1373,6 +1402,10 @@ func TestDAGInsert(t *testing.T) {
 		},
 	}
 
+	s14srv := s14.DeepCopy()
+	s14srv.Annotations = map[string]string{"projectcontour.io/dns-srv": "true"}
+	s14srv.Spec.ExternalName = "_http._tcp.externalservice.io"
+
 	proxyDelegatedTLSSecret := &contour_api_v1.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "app-with-tls-delegation",
@@ -2981,6 +3014,104 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert ingress w/ default backend w/ client ip session affinity": {
+			objs: []interface{}{
+				i1,
+				s1clientip,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("*", &Route{
+							PathMatchCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Weighted: WeightedService{
+										Weight:           1,
+										ServiceName:      s1.Name,
+										ServiceNamespace: s1.Namespace,
+										ServicePort:      s1.Spec.Ports[0],
+									},
+									ClientIPAffinity: true,
+								},
+								LoadBalancerPolicy: "RequestHash",
+							}},
+							RequestHashPolicies: []RequestHashPolicy{{HashSourceIP: true}},
+						}),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ client ip session affinity": {
+			objs: []interface{}{
+				proxy1,
+				s1clientip,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathMatchCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Weighted: WeightedService{
+										Weight:           1,
+										ServiceName:      s1.Name,
+										ServiceNamespace: s1.Namespace,
+										ServicePort:      s1.Spec.Ports[0],
+									},
+									ClientIPAffinity: true,
+								},
+								LoadBalancerPolicy: "RequestHash",
+							}},
+							RequestHashPolicies: []RequestHashPolicy{{HashSourceIP: true}},
+						}),
+					),
+				},
+			),
+		},
+		"insert httpproxy w/ load balancer policy ignores client ip session affinity": {
+			objs: []interface{}{
+				&contour_api_v1.HTTPProxy{
+					ObjectMeta: proxy1.ObjectMeta,
+					Spec: contour_api_v1.HTTPProxySpec{
+						VirtualHost: proxy1.Spec.VirtualHost,
+						Routes: []contour_api_v1.Route{{
+							Conditions: proxy1.Spec.Routes[0].Conditions,
+							Services:   proxy1.Spec.Routes[0].Services,
+							LoadBalancerPolicy: &contour_api_v1.LoadBalancerPolicy{
+								Strategy: "Random",
+							},
+						}},
+					},
+				},
+				s1clientip,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathMatchCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Weighted: WeightedService{
+										Weight:           1,
+										ServiceName:      s1.Name,
+										ServiceNamespace: s1.Namespace,
+										ServicePort:      s1.Spec.Ports[0],
+									},
+									ClientIPAffinity: true,
+								},
+								LoadBalancerPolicy: "Random",
+							}},
+						}),
+					),
+				},
+			),
+		},
 		"insert ingress w/ single unnamed backend w/o matching service": {
 			objs: []interface{}{
 				i2,
@@ -3774,6 +3905,24 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert httpproxy with request body policy": {
+			objs: []interface{}{
+				proxyRequestBodyPolicy,
+				s1,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("bar.com", &Route{
+							PathMatchCondition:  prefix("/"),
+							Clusters:            clustermap(s1),
+							MaxRequestBodyBytes: 8192,
+						}),
+					),
+				},
+			),
+		},
 		"insert httpproxy with invalid PerTryTimeout": {
 			objs: []interface{}{
 				proxyRetryPolicyInvalidTimeout,
@@ -5257,6 +5406,32 @@ func TestDAGInsert(t *testing.T) {
 				},
 			),
 		},
+		"insert proxy with DNS SRV externalName service": {
+			objs: []interface{}{
+				proxyExternalNameService,
+				s14srv,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("example.com", &Route{
+							PathMatchCondition: prefix("/"),
+							Clusters: []*Cluster{{
+								Upstream: &Service{
+									Weighted: WeightedService{
+										Weight:           1,
+										ServiceName:      s14.Name,
+										ServiceNamespace: s14.Namespace,
+										ServicePort:      s14.Spec.Ports[0],
+									},
+								},
+							}},
+						}),
+					),
+				},
+			),
+		},
 		"insert proxy with replace header policy - route - host header": {
 			objs: []interface{}{
 				proxyReplaceHostHeaderRoute,
@@ -6085,6 +6260,468 @@ func TestDAGRootNamespaces(t *testing.T) {
 	}
 }
 
+func TestDAGDomainSuffixes(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+
+	ingress := func(hosts ...string) *v1beta1.Ingress {
+		i := &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+		}
+		for _, h := range hosts {
+			i.Spec.Rules = append(i.Spec.Rules, v1beta1.IngressRule{
+				Host:             h,
+				IngressRuleValue: ingressrulevalue(backend("kuard", intstr.FromInt(8080))),
+			})
+		}
+		return i
+	}
+
+	tests := map[string]struct {
+		domainSuffixes []string
+		objs           []interface{}
+		want           []string
+	}{
+		"no domain suffixes": {
+			objs: []interface{}{s1, ingress("a.example.com", "b.example.org", "")},
+			want: []string{"*", "a.example.com", "b.example.org"},
+		},
+		"single domain suffix": {
+			domainSuffixes: []string{"example.com"},
+			objs:           []interface{}{s1, ingress("a.example.com", "b.example.org", "")},
+			want:           []string{"a.example.com"},
+		},
+		"domain suffix matches exact host": {
+			domainSuffixes: []string{".Example.com"},
+			objs:           []interface{}{s1, ingress("example.com", "notexample.com")},
+			want:           []string{"example.com"},
+		},
+		"multiple domain suffixes": {
+			domainSuffixes: []string{"example.com", "example.org"},
+			objs:           []interface{}{s1, ingress("a.example.com", "b.example.org", "c.example.net")},
+			want:           []string{"a.example.com", "b.example.org"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: fixture.NewTestLogger(t),
+				},
+				Processors: []Processor{
+					&IngressProcessor{
+						FieldLogger:    fixture.NewTestLogger(t),
+						DomainSuffixes: tc.domainSuffixes,
+					},
+					&ListenerProcessor{},
+				},
+			}
+
+			for _, o := range tc.objs {
+				builder.Source.Insert(o)
+			}
+			dag := builder.Build()
+
+			var got []string
+			dag.Visit(func(v Vertex) {
+				v.Visit(func(v Vertex) {
+					if vh, ok := v.(*VirtualHost); ok {
+						got = append(got, vh.Name)
+					}
+				})
+			})
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIngressBackendResource(t *testing.T) {
+	s1 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Protocol: "TCP",
+				Port:     8080,
+			}},
+		},
+	}
+
+	s2 := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "multiport",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Name:     "http",
+				Protocol: "TCP",
+				Port:     8080,
+			}, {
+				Name:     "admin",
+				Protocol: "TCP",
+				Port:     9000,
+			}},
+		},
+	}
+
+	group := "storage.example.com"
+	bucket := &v1.TypedLocalObjectReference{
+		APIGroup: &group,
+		Kind:     "Bucket",
+		Name:     "static",
+	}
+
+	// kuardResolver sends the traffic for every resource to s1.
+	kuardResolver := IngressBackendResolverFunc(func(dag *DAG, source *KubernetesCache, namespace string, _ *v1.TypedLocalObjectReference) (*Service, error) {
+		return dag.EnsureService(types.NamespacedName{Name: "kuard", Namespace: namespace}, intstr.FromInt(8080), source)
+	})
+
+	tests := map[string]struct {
+		resource     *v1.TypedLocalObjectReference
+		resolvers    map[schema.GroupKind]IngressBackendResolver
+		want         []Vertex
+		wantRejected map[RejectedObject]int
+		wantEvents   []string
+	}{
+		"unsupported backend resource": {
+			want: listeners(),
+			wantRejected: map[RejectedObject]int{
+				{Kind: "Ingress", Namespace: "default", Reason: "BackendResourceNotSupported"}: 1,
+			},
+			wantEvents: []string{`BackendResourceNotSupported: backend resource Bucket "static" is not supported`},
+		},
+		"resolver for another kind": {
+			resolvers: map[schema.GroupKind]IngressBackendResolver{
+				{Group: group, Kind: "Object"}: kuardResolver,
+			},
+			want: listeners(),
+			wantRejected: map[RejectedObject]int{
+				{Kind: "Ingress", Namespace: "default", Reason: "BackendResourceNotSupported"}: 1,
+			},
+			wantEvents: []string{`BackendResourceNotSupported: backend resource Bucket "static" is not supported`},
+		},
+		"service backend resource": {
+			resource: &v1.TypedLocalObjectReference{Kind: "Service", Name: "kuard"},
+			resolvers: map[schema.GroupKind]IngressBackendResolver{
+				{Kind: "Service"}: ServiceBackendResolver,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("*", prefixroute("/", service(s1))),
+					),
+				},
+			),
+		},
+		"service backend resource with several ports": {
+			resource: &v1.TypedLocalObjectReference{Kind: "Service", Name: "multiport"},
+			resolvers: map[schema.GroupKind]IngressBackendResolver{
+				{Kind: "Service"}: ServiceBackendResolver,
+			},
+			want: listeners(),
+			wantRejected: map[RejectedObject]int{
+				{Kind: "Ingress", Namespace: "default", Reason: "ServiceUnresolvedReference"}: 1,
+			},
+			wantEvents: []string{`ServiceUnresolvedReference: service "default/multiport" must have exactly one port to be used as a backend resource`},
+		},
+		"resolved backend resource": {
+			resolvers: map[schema.GroupKind]IngressBackendResolver{
+				{Group: group, Kind: "Bucket"}: kuardResolver,
+			},
+			want: listeners(
+				&Listener{
+					Port: 80,
+					VirtualHosts: virtualhosts(
+						virtualhost("*", prefixroute("/", service(s1))),
+					),
+				},
+			),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			builder := Builder{
+				Source: KubernetesCache{
+					FieldLogger: fixture.NewTestLogger(t),
+				},
+				Processors: []Processor{
+					&IngressProcessor{
+						FieldLogger:      fixture.NewTestLogger(t),
+						BackendResolvers: tc.resolvers,
+					},
+					&ListenerProcessor{},
+				},
+			}
+
+			resource := tc.resource
+			if resource == nil {
+				resource = bucket
+			}
+
+			builder.Source.Insert(s1)
+			builder.Source.Insert(s2)
+			builder.Source.Insert(&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bucket",
+					Namespace: "default",
+				},
+				Spec: v1beta1.IngressSpec{
+					Backend: &v1beta1.IngressBackend{
+						Resource: resource,
+					},
+				},
+			})
+			dag := builder.Build()
+
+			got := make(map[int]*Listener)
+			dag.Visit(listenerMap(got).Visit)
+
+			want := make(map[int]*Listener)
+			for _, v := range tc.want {
+				if l, ok := v.(*Listener); ok {
+					want[l.Port] = l
+				}
+			}
+			assert.Equal(t, want, got)
+			assert.Equal(t, tc.wantRejected, dag.RejectedObjects)
+
+			var events []string
+			for _, ev := range dag.Events {
+				events = append(events, ev.Reason+": "+ev.Message)
+			}
+			assert.Equal(t, tc.wantEvents, events)
+		})
+	}
+}
+
+func TestNamespaceDefaults(t *testing.T) {
+	svc := func(namespace string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: namespace,
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol: "TCP",
+					Port:     8080,
+				}},
+			},
+		}
+	}
+
+	ingress := func(namespace, host string, annotations map[string]string) *v1beta1.Ingress {
+		return &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        host,
+				Namespace:   namespace,
+				Annotations: annotations,
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{{
+					Host:             host,
+					IngressRuleValue: ingressrulevalue(backend("kuard", intstr.FromInt(8080))),
+				}},
+			},
+		}
+	}
+
+	proxy := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "team-a",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "proxy.example.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				TimeoutPolicy: &contour_api_v1.TimeoutPolicy{
+					Response: "1s",
+				},
+				RequestHeadersPolicy: &contour_api_v1.HeadersPolicy{
+					Set:    []contour_api_v1.HeaderValue{{Name: "X-Team", Value: "proxy"}},
+					Remove: []string{"X-Env"},
+				},
+				Services: []contour_api_v1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	defaults := &RouteDefaults{
+		TimeoutPolicy: TimeoutPolicy{
+			ResponseTimeout: timeout.DurationSetting(10 * time.Second),
+		},
+		RetryPolicy: &RetryPolicy{
+			RetryOn:    "5xx",
+			NumRetries: 3,
+		},
+		RequestHeadersPolicy: &HeadersPolicy{
+			Set:    map[string]string{"X-Team": "a", "X-Env": "prod"},
+			Remove: []string{"X-Debug"},
+		},
+	}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&IngressProcessor{
+				FieldLogger:       fixture.NewTestLogger(t),
+				NamespaceDefaults: map[string]*RouteDefaults{"team-a": defaults},
+			},
+			&HTTPProxyProcessor{
+				NamespaceDefaults: map[string]*RouteDefaults{"team-a": defaults},
+			},
+			&ListenerProcessor{},
+		},
+	}
+
+	for _, o := range []interface{}{
+		svc("team-a"),
+		svc("team-b"),
+		ingress("team-a", "a.example.com", nil),
+		ingress("team-a", "retry.example.com", map[string]string{"projectcontour.io/retry-on": "reset"}),
+		ingress("team-b", "b.example.com", nil),
+		proxy,
+	} {
+		builder.Source.Insert(o)
+	}
+	dag := builder.Build()
+
+	got := map[string]*Route{}
+	dag.Visit(func(v Vertex) {
+		v.Visit(func(v Vertex) {
+			if vh, ok := v.(*VirtualHost); ok {
+				vh.Visit(func(v Vertex) {
+					if r, ok := v.(*Route); ok {
+						got[vh.Name] = r
+					}
+				})
+			}
+		})
+	})
+
+	// Ingress routes without policies of their own take the defaults.
+	a := got["a.example.com"]
+	assert.Equal(t, timeout.DurationSetting(10*time.Second), a.TimeoutPolicy.ResponseTimeout)
+	assert.Equal(t, defaults.RetryPolicy, a.RetryPolicy)
+	assert.Equal(t, defaults.RequestHeadersPolicy, a.RequestHeadersPolicy)
+
+	// Annotations take precedence over the defaults.
+	assert.Equal(t, "reset", got["retry.example.com"].RetryPolicy.RetryOn)
+
+	// Other namespaces are not affected.
+	b := got["b.example.com"]
+	assert.True(t, b.TimeoutPolicy.ResponseTimeout.UseDefault())
+	assert.Nil(t, b.RetryPolicy)
+	assert.Nil(t, b.RequestHeadersPolicy)
+
+	// HTTPProxy routes keep what they set, and headers they
+	// set or remove are not changed by the defaults.
+	p := got["proxy.example.com"]
+	assert.Equal(t, timeout.DurationSetting(time.Second), p.TimeoutPolicy.ResponseTimeout)
+	assert.Equal(t, defaults.RetryPolicy, p.RetryPolicy)
+	assert.Equal(t, &HeadersPolicy{
+		Set:    map[string]string{"X-Team": "proxy"},
+		Remove: []string{"X-Debug", "X-Env"},
+	}, p.RequestHeadersPolicy)
+}
+
+func TestEndpointsProcessor(t *testing.T) {
+	ports := []v1.ServicePort{{
+		Name:     "http",
+		Protocol: "TCP",
+		Port:     80,
+	}, {
+		Name:     "https",
+		Protocol: "TCP",
+		Port:     443,
+	}}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&EndpointsProcessor{},
+		},
+	}
+
+	builder.Source.Insert(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Ports: ports,
+		},
+	})
+	builder.Source.Insert(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "external",
+			Namespace: "default",
+		},
+		Spec: v1.ServiceSpec{
+			Type:         v1.ServiceTypeExternalName,
+			ExternalName: "example.com",
+			Ports:        ports[:1],
+		},
+	})
+	dag := builder.Build()
+
+	var got []Vertex
+	dag.Visit(func(v Vertex) {
+		got = append(got, v)
+	})
+
+	// ExternalName Services have no endpoints, so no clusters.
+	assert.Equal(t, []Vertex{
+		&ServiceCluster{
+			ClusterName: "default/kuard/http",
+			Services: []WeightedService{{
+				Weight:           1,
+				ServiceName:      "kuard",
+				ServiceNamespace: "default",
+				ServicePort:      ports[0],
+			}},
+		},
+		&ServiceCluster{
+			ClusterName: "default/kuard/https",
+			Services: []WeightedService{{
+				Weight:           1,
+				ServiceName:      "kuard",
+				ServiceNamespace: "default",
+				ServicePort:      ports[1],
+			}},
+		},
+	}, got)
+}
+
 func TestHttpPaths(t *testing.T) {
 	tests := map[string]struct {
 		rule v1beta1.IngressRule
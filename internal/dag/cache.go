@@ -49,6 +49,7 @@ type KubernetesCache struct {
 	ingresses            map[types.NamespacedName]*v1beta1.Ingress
 	httpproxies          map[types.NamespacedName]*contour_api_v1.HTTPProxy
 	secrets              map[types.NamespacedName]*v1.Secret
+	configmaps           map[types.NamespacedName]*v1.ConfigMap
 	httpproxydelegations map[types.NamespacedName]*contour_api_v1.TLSCertificateDelegation
 	services             map[types.NamespacedName]*v1.Service
 	gatewayclasses       map[types.NamespacedName]*serviceapis.GatewayClass
@@ -67,6 +68,7 @@ func (kc *KubernetesCache) init() {
 	kc.ingresses = make(map[types.NamespacedName]*v1beta1.Ingress)
 	kc.httpproxies = make(map[types.NamespacedName]*contour_api_v1.HTTPProxy)
 	kc.secrets = make(map[types.NamespacedName]*v1.Secret)
+	kc.configmaps = make(map[types.NamespacedName]*v1.ConfigMap)
 	kc.httpproxydelegations = make(map[types.NamespacedName]*contour_api_v1.TLSCertificateDelegation)
 	kc.services = make(map[types.NamespacedName]*v1.Service)
 	kc.gatewayclasses = make(map[types.NamespacedName]*serviceapis.GatewayClass)
@@ -76,6 +78,13 @@ func (kc *KubernetesCache) init() {
 	kc.extensions = make(map[types.NamespacedName]*contour_api_v1alpha1.ExtensionService)
 }
 
+// Reset removes every object from the KubernetesCache,
+// keeping its configuration.
+func (kc *KubernetesCache) Reset() {
+	kc.initialize.Do(func() {})
+	kc.init()
+}
+
 // matchesIngressClass returns true if the given Kubernetes object
 // belongs to the Ingress class that this cache is using.
 func (kc *KubernetesCache) matchesIngressClass(obj k8s.Object) bool {
@@ -104,6 +113,12 @@ func (kc *KubernetesCache) matchesIngressClass(obj k8s.Object) bool {
 func (kc *KubernetesCache) Insert(obj interface{}) bool {
 	kc.initialize.Do(kc.init)
 
+	// Ingresses of every supported API version are
+	// stored as networking.k8s.io/v1beta1 Ingresses.
+	if ing := k8s.ConvertIngress(obj); ing != nil {
+		obj = ing
+	}
+
 	if obj, ok := obj.(k8s.Object); ok {
 		kind := k8s.KindOf(obj)
 		for key := range obj.GetObjectMeta().GetAnnotations() {
@@ -129,7 +144,7 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 	switch obj := obj.(type) {
 	case *v1.Secret:
 		valid, err := isValidSecret(obj)
-		if !valid {
+		if !valid && (err != nil || !kc.isConfiguredSecret(k8s.NamespacedNameOf(obj))) {
 			if err != nil {
 				om := obj.GetObjectMeta()
 				kc.WithField("name", om.GetName()).
@@ -143,6 +158,17 @@ func (kc *KubernetesCache) Insert(obj interface{}) bool {
 
 		kc.secrets[k8s.NamespacedNameOf(obj)] = obj
 		return kc.secretTriggersRebuild(obj)
+	case *v1.ConfigMap:
+		// Only ConfigMaps that hold a CA certificate bundle
+		// can be referenced, so don't cache the others.
+		m := k8s.NamespacedNameOf(obj)
+		if len(caBundle(obj)) == 0 {
+			_, ok := kc.configmaps[m]
+			delete(kc.configmaps, m)
+			return ok
+		}
+		kc.configmaps[m] = obj
+		return true
 	case *v1.Service:
 		kc.services[k8s.NamespacedNameOf(obj)] = obj
 		return kc.serviceTriggersRebuild(obj)
@@ -214,12 +240,21 @@ func (kc *KubernetesCache) Remove(obj interface{}) bool {
 }
 
 func (kc *KubernetesCache) remove(obj interface{}) bool {
+	if ing := k8s.ConvertIngress(obj); ing != nil {
+		obj = ing
+	}
+
 	switch obj := obj.(type) {
 	case *v1.Secret:
 		m := k8s.NamespacedNameOf(obj)
 		_, ok := kc.secrets[m]
 		delete(kc.secrets, m)
 		return ok
+	case *v1.ConfigMap:
+		m := k8s.NamespacedNameOf(obj)
+		_, ok := kc.configmaps[m]
+		delete(kc.configmaps, m)
+		return ok
 	case *v1.Service:
 		m := k8s.NamespacedNameOf(obj)
 		_, ok := kc.services[m]
@@ -347,6 +382,18 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 		return true
 	}
 
+	if _, isDescriptor := secret.Data[GRPCDescriptorKey]; isDescriptor {
+		// As with CA secrets, assume that any change to
+		// a gRPC descriptor secret triggers a rebuild.
+		return true
+	}
+
+	if _, isCRL := secret.Data[CRLKey]; isCRL {
+		// As with CA secrets, assume that any change to a
+		// certificate revocation list secret triggers a rebuild.
+		return true
+	}
+
 	delegations := make(map[string]bool) // targetnamespace/secretname to bool
 
 	// TODO(youngnick): Check if this is required.
@@ -410,9 +457,38 @@ func (kc *KubernetesCache) secretTriggersRebuild(secret *v1.Secret) bool {
 		}
 	}
 
+	// Client certificates referred to by a service's upstreamTLS
+	// may appear on any proxy, not only roots.
+	for _, proxy := range kc.httpproxies {
+		for _, route := range proxy.Spec.Routes {
+			for _, service := range route.Services {
+				ut := service.UpstreamTLS
+				if ut == nil || ut.ClientCertificate == "" {
+					continue
+				}
+				if proxy.Namespace == secret.Namespace && ut.ClientCertificate == secret.Name {
+					return true
+				}
+				if ut.ClientCertificate == secret.Namespace+"/"+secret.Name {
+					if delegations[proxy.Namespace+"/"+secret.Name] || delegations["*/"+secret.Name] {
+						return true
+					}
+				}
+			}
+		}
+	}
+
 	// Secrets referred by the configuration file shall also trigger rebuild.
+	return kc.isConfiguredSecret(k8s.NamespacedNameOf(secret))
+}
+
+// isConfiguredSecret returns true if the configuration file refers
+// to the named Secret. Such Secrets are cached even if they do not
+// hold certificates, since the processor that looks them up
+// validates their contents.
+func (kc *KubernetesCache) isConfiguredSecret(name types.NamespacedName) bool {
 	for _, s := range kc.ConfiguredSecretRefs {
-		if s.Namespace == secret.Namespace && s.Name == secret.Name {
+		if s.Namespace == name.Namespace && s.Name == name.Name {
 			return true
 		}
 	}
@@ -445,11 +521,10 @@ func (kc *KubernetesCache) LookupUpstreamValidation(uv *contour_api_v1.UpstreamV
 		return nil, nil
 	}
 
-	secretName := types.NamespacedName{Name: uv.CACertificate, Namespace: namespace}
-	cacert, err := kc.LookupSecret(secretName, validCA)
+	cacert, err := kc.lookupCACertificate(uv.CACertificate, uv.CAConfigMap, namespace)
 	if err != nil {
 		// UpstreamValidation is requested, but cert is missing or not configured
-		return nil, fmt.Errorf("invalid CA Secret %q: %s", secretName, err)
+		return nil, err
 	}
 
 	if uv.SubjectName == "" {
@@ -464,16 +539,86 @@ func (kc *KubernetesCache) LookupUpstreamValidation(uv *contour_api_v1.UpstreamV
 }
 
 func (kc *KubernetesCache) LookupDownstreamValidation(vc *contour_api_v1.DownstreamValidation, namespace string) (*PeerValidationContext, error) {
-	secretName := types.NamespacedName{Name: vc.CACertificate, Namespace: namespace}
-	cacert, err := kc.LookupSecret(secretName, validCA)
+	cacert, err := kc.lookupCACertificate(vc.CACertificate, vc.CAConfigMap, namespace)
 	if err != nil {
 		// PeerValidationContext is requested, but cert is missing or not configured.
-		return nil, fmt.Errorf("invalid CA Secret %q: %s", secretName, err)
+		return nil, err
 	}
 
-	return &PeerValidationContext{
-		CACertificate: cacert,
-	}, nil
+	pvc := &PeerValidationContext{
+		CACertificate:             cacert,
+		OptionalClientCertificate: vc.OptionalClientCertificate,
+	}
+
+	if vc.CertificateRevocationList != "" {
+		crlName := types.NamespacedName{Name: vc.CertificateRevocationList, Namespace: namespace}
+		crl, err := kc.LookupSecret(crlName, validCRL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CRL Secret %q: %s", crlName, err)
+		}
+		pvc.CRL = crl
+	}
+
+	return pvc, nil
+}
+
+// lookupCACertificate returns the CA certificate bundle held by either
+// the named Secret or the named ConfigMap. Exactly one of them must be
+// given.
+func (kc *KubernetesCache) lookupCACertificate(secret, configMap, namespace string) (*Secret, error) {
+	switch {
+	case secret != "" && configMap != "":
+		return nil, errors.New("both a CA Secret and a CA ConfigMap are specified")
+	case configMap != "":
+		configMapName := types.NamespacedName{Name: configMap, Namespace: namespace}
+		cacert, err := kc.LookupCAConfigMap(configMapName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA ConfigMap %q: %s", configMapName, err)
+		}
+		return cacert, nil
+	case secret != "":
+		secretName := types.NamespacedName{Name: secret, Namespace: namespace}
+		cacert, err := kc.LookupSecret(secretName, validCA)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA Secret %q: %s", secretName, err)
+		}
+		return cacert, nil
+	default:
+		return nil, errors.New("missing CA Secret or ConfigMap")
+	}
+}
+
+// LookupCAConfigMap returns the CA certificate bundle of the named
+// ConfigMap, wrapped in a Secret so that it can be used wherever a
+// CA Secret is.
+func (kc *KubernetesCache) LookupCAConfigMap(name types.NamespacedName) (*Secret, error) {
+	kc.initialize.Do(kc.init)
+
+	cm, ok := kc.configmaps[name]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap not found")
+	}
+
+	s := &Secret{
+		Object: &v1.Secret{
+			ObjectMeta: *cm.ObjectMeta.DeepCopy(),
+			Type:       v1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				CACertificateKey: caBundle(cm),
+			},
+		},
+	}
+
+	return s, nil
+}
+
+// caBundle returns the CA certificate bundle held by the ConfigMap,
+// which may be either text or binary data.
+func caBundle(cm *v1.ConfigMap) []byte {
+	if ca, ok := cm.Data[CACertificateKey]; ok {
+		return []byte(ca)
+	}
+	return cm.BinaryData[CACertificateKey]
 }
 
 // DelegationPermitted returns true if the referenced secret has been delegated
@@ -511,6 +656,32 @@ func (kc *KubernetesCache) DelegationPermitted(secret types.NamespacedName, targ
 	return false
 }
 
+// SessionTicketKeyLength is the length in bytes of a TLS session
+// ticket key.
+const SessionTicketKeyLength = 80
+
+// validSessionTicketKeys returns an error unless the Secret holds
+// one or more session ticket keys of the required length.
+func validSessionTicketKeys(s *v1.Secret) error {
+	switch s.Type {
+	case v1.SecretTypeOpaque, "":
+	default:
+		return fmt.Errorf("Secret type is not %q", v1.SecretTypeOpaque)
+	}
+
+	if len(s.Data) == 0 {
+		return errors.New("no session ticket keys")
+	}
+
+	for name, key := range s.Data {
+		if len(key) != SessionTicketKeyLength {
+			return fmt.Errorf("session ticket key %q is %d bytes, not %d", name, len(key), SessionTicketKeyLength)
+		}
+	}
+
+	return nil
+}
+
 func validCA(s *v1.Secret) error {
 	if len(s.Data[CACertificateKey]) == 0 {
 		return fmt.Errorf("empty %q key", CACertificateKey)
@@ -519,6 +690,22 @@ func validCA(s *v1.Secret) error {
 	return nil
 }
 
+func validCRL(s *v1.Secret) error {
+	if len(s.Data[CRLKey]) == 0 {
+		return fmt.Errorf("empty %q key", CRLKey)
+	}
+
+	return nil
+}
+
+func validGRPCDescriptor(s *v1.Secret) error {
+	if len(s.Data[GRPCDescriptorKey]) == 0 {
+		return fmt.Errorf("empty %q key", GRPCDescriptorKey)
+	}
+
+	return nil
+}
+
 // LookupService returns the Kubernetes service and port matching the provided parameters,
 // or an error if a match can't be found.
 func (kc *KubernetesCache) LookupService(meta types.NamespacedName, port intstr.IntOrString) (*v1.Service, v1.ServicePort, error) {
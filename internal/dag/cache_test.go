@@ -24,10 +24,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 	serviceapis "sigs.k8s.io/service-apis/api/v1alpha1"
 )
 
@@ -63,6 +66,62 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert configmap w/ ca.crt": {
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ca",
+					Namespace: "default",
+				},
+				Data: map[string]string{
+					CACertificateKey: fixture.CERTIFICATE,
+				},
+			},
+			want: true,
+		},
+		"insert configmap w/ binary ca.crt": {
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ca",
+					Namespace: "default",
+				},
+				BinaryData: map[string][]byte{
+					CACertificateKey: []byte(fixture.CERTIFICATE),
+				},
+			},
+			want: true,
+		},
+		"insert configmap w/o ca.crt": {
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "settings",
+					Namespace: "default",
+				},
+				Data: map[string]string{
+					"key": "value",
+				},
+			},
+			want: false,
+		},
+		"insert configmap whose ca.crt was removed": {
+			pre: []interface{}{
+				&v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ca",
+						Namespace: "default",
+					},
+					Data: map[string]string{
+						CACertificateKey: fixture.CERTIFICATE,
+					},
+				},
+			},
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ca",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
 		"insert CA secret w/ explanatory text": {
 			obj: &v1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
@@ -370,6 +429,37 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			// any CA secret causes a rebuild.
 			want: true,
 		},
+		"insert client certificate secret referenced by httpproxy upstreamTLS": {
+			pre: []interface{}{
+				&contour_api_v1.HTTPProxy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "child",
+						Namespace: "default",
+					},
+					Spec: contour_api_v1.HTTPProxySpec{
+						Routes: []contour_api_v1.Route{{
+							Services: []contour_api_v1.Service{{
+								Name:     "kuard",
+								Port:     443,
+								Protocol: pointer.StringPtr("tls"),
+								UpstreamTLS: &contour_api_v1.UpstreamTLS{
+									ClientCertificate: "clientcert",
+								},
+							}},
+						}},
+					},
+				},
+			},
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "clientcert",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeTLS,
+				Data: secretdata(fixture.CERTIFICATE, fixture.RSA_PRIVATE_KEY),
+			},
+			want: true,
+		},
 		"insert certificate secret referenced by httpproxy": {
 			pre: []interface{}{
 				&contour_api_v1.HTTPProxy{
@@ -466,6 +556,36 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert networking.k8s.io/v1 ingress": {
+			obj: &networking_v1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "v1",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"insert networking.k8s.io/v1 ingress incorrect ingressClassName": {
+			obj: &networking_v1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "v1",
+					Namespace: "default",
+				},
+				Spec: networking_v1.IngressSpec{
+					IngressClassName: pointer.StringPtr("nginx"),
+				},
+			},
+			want: false,
+		},
+		"insert extensions/v1beta1 ingress": {
+			obj: &extensions_v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "extensions",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
 		"insert httpproxy empty ingress annotation": {
 			obj: &contour_api_v1.HTTPProxy{
 				ObjectMeta: metav1.ObjectMeta{
@@ -699,6 +819,32 @@ func TestKubernetesCacheInsert(t *testing.T) {
 			},
 			want: true,
 		},
+		"insert opaque secret that is referred by configuration file": {
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secretReferredByConfigFile",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"key-0": make([]byte, SessionTicketKeyLength),
+				},
+			},
+			want: true,
+		},
+		"insert opaque secret that is not referred by configuration file": {
+			obj: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "secret",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"key-0": make([]byte, SessionTicketKeyLength),
+				},
+			},
+			want: false,
+		},
 	}
 
 	for name, tc := range tests {
@@ -754,6 +900,34 @@ func TestKubernetesCacheRemove(t *testing.T) {
 			},
 			want: true,
 		},
+		"remove configmap": {
+			cache: cache(&v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ca",
+					Namespace: "default",
+				},
+				Data: map[string]string{
+					CACertificateKey: fixture.CERTIFICATE,
+				},
+			}),
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ca",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
+		"remove uncached configmap": {
+			cache: cache(),
+			obj: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "settings",
+					Namespace: "default",
+				},
+			},
+			want: false,
+		},
 		"remove service": {
 			cache: cache(&v1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -784,6 +958,21 @@ func TestKubernetesCacheRemove(t *testing.T) {
 			},
 			want: true,
 		},
+		"remove networking.k8s.io/v1 ingress": {
+			cache: cache(&networking_v1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingress",
+					Namespace: "default",
+				},
+			}),
+			obj: &networking_v1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingress",
+					Namespace: "default",
+				},
+			},
+			want: true,
+		},
 		"remove ingress incorrect ingressclass": {
 			cache: cache(&v1beta1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
@@ -1021,3 +1210,26 @@ func TestLookupService(t *testing.T) {
 		})
 	}
 }
+
+func TestKubernetesCacheReset(t *testing.T) {
+	cache := KubernetesCache{
+		IngressClass: "contour",
+		FieldLogger:  fixture.NewTestLogger(t),
+	}
+
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: "default",
+		},
+	}
+
+	cache.Insert(service)
+	cache.Reset()
+
+	assert.Empty(t, cache.services)
+	assert.Equal(t, "contour", cache.IngressClass)
+
+	cache.Insert(service)
+	assert.Contains(t, cache.services, types.NamespacedName{Name: "kuard", Namespace: "default"})
+}
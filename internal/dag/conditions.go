@@ -180,6 +180,148 @@ func headerMatchConditionsValid(conditions []contour_api_v1.MatchCondition) erro
 	return nil
 }
 
+func mergeQueryParamMatchConditions(conds []contour_api_v1.MatchCondition) []QueryParamMatchCondition {
+	var qpc []QueryParamMatchCondition
+	for _, cond := range conds {
+		switch {
+		case cond.QueryParameter == nil:
+			// skip it
+		case cond.QueryParameter.Exact != "":
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:       cond.QueryParameter.Name,
+				Value:      cond.QueryParameter.Exact,
+				MatchType:  "exact",
+				IgnoreCase: cond.QueryParameter.IgnoreCase,
+			})
+		case cond.QueryParameter.Prefix != "":
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:       cond.QueryParameter.Name,
+				Value:      cond.QueryParameter.Prefix,
+				MatchType:  "prefix",
+				IgnoreCase: cond.QueryParameter.IgnoreCase,
+			})
+		case cond.QueryParameter.Suffix != "":
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:       cond.QueryParameter.Name,
+				Value:      cond.QueryParameter.Suffix,
+				MatchType:  "suffix",
+				IgnoreCase: cond.QueryParameter.IgnoreCase,
+			})
+		case cond.QueryParameter.Regex != "":
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:      cond.QueryParameter.Name,
+				Value:     cond.QueryParameter.Regex,
+				MatchType: "regex",
+			})
+		case cond.QueryParameter.Contains != "":
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:       cond.QueryParameter.Name,
+				Value:      cond.QueryParameter.Contains,
+				MatchType:  "contains",
+				IgnoreCase: cond.QueryParameter.IgnoreCase,
+			})
+		case cond.QueryParameter.Present:
+			qpc = append(qpc, QueryParamMatchCondition{
+				Name:      cond.QueryParameter.Name,
+				MatchType: "present",
+			})
+		}
+	}
+	return qpc
+}
+
+// queryParameterMatchConditionsValid validates that the query parameter
+// conditions within a slice of MatchConditions are valid. Specifically,
+// it returns an error for any of the following scenarios:
+//	- a condition without a query parameter name
+//	- a condition that does not specify exactly one match type
+//	- a 'regex' condition with an invalid regular expression
+//	- more than 1 'exact' condition for the same query parameter
+func queryParameterMatchConditionsValid(conditions []contour_api_v1.MatchCondition) error {
+	paramsWithExactMatch := map[string]bool{}
+
+	for _, v := range conditions {
+		if v.QueryParameter == nil {
+			continue
+		}
+
+		if v.QueryParameter.Name == "" {
+			return errors.New("query parameter conditions must specify a name")
+		}
+
+		matchTypes := 0
+		for _, set := range []bool{
+			v.QueryParameter.Exact != "",
+			v.QueryParameter.Prefix != "",
+			v.QueryParameter.Suffix != "",
+			v.QueryParameter.Regex != "",
+			v.QueryParameter.Contains != "",
+			v.QueryParameter.Present,
+		} {
+			if set {
+				matchTypes++
+			}
+		}
+		if matchTypes != 1 {
+			return fmt.Errorf("query parameter condition for %q must specify exactly one match type", v.QueryParameter.Name)
+		}
+
+		switch {
+		case v.QueryParameter.Regex != "":
+			if err := ValidateRegex(v.QueryParameter.Regex); err != nil {
+				return fmt.Errorf("query parameter condition for %q has an invalid regex: %w", v.QueryParameter.Name, err)
+			}
+		case v.QueryParameter.Exact != "":
+			if paramsWithExactMatch[v.QueryParameter.Name] {
+				return errors.New("cannot specify duplicate query parameter 'exact match' conditions in the same route")
+			}
+			paramsWithExactMatch[v.QueryParameter.Name] = true
+		}
+	}
+
+	return nil
+}
+
+// mergeTLSMatchConditions returns the TLS condition of the given
+// slice of MatchConditions, or nil if there is none.
+// tlsMatchConditionsValid guarantees that all of the TLS conditions
+// agree, so the last one is used.
+func mergeTLSMatchConditions(conds []contour_api_v1.MatchCondition) *TLSMatchCondition {
+	var tc *TLSMatchCondition
+	for _, cond := range conds {
+		if cond.TLS != nil {
+			tc = &TLSMatchCondition{
+				ClientCertificatePresented: cond.TLS.ClientCertificatePresented,
+			}
+		}
+	}
+	return tc
+}
+
+// tlsMatchConditionsValid validates that the TLS conditions within a
+// slice of MatchConditions are valid. Specifically, it returns an error
+// if there are TLS conditions and client certificates are not validated,
+// or if the TLS conditions do not agree.
+func tlsMatchConditionsValid(conditions []contour_api_v1.MatchCondition, clientValidation bool) error {
+	var presented *bool
+	for _, v := range conditions {
+		if v.TLS == nil {
+			continue
+		}
+
+		if !clientValidation {
+			return errors.New("tls conditions require the root HTTPProxy to validate client certificates")
+		}
+
+		if presented != nil && *presented != v.TLS.ClientCertificatePresented {
+			return errors.New("cannot specify conflicting tls conditions in the same route")
+		}
+		presented = &v.TLS.ClientCertificatePresented
+	}
+
+	return nil
+}
+
 // ValidateRegex returns an error if the supplied
 // RE2 regex syntax is invalid.
 func ValidateRegex(regex string) error {
@@ -504,3 +504,238 @@ func TestValidateHeaderMatchConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryParamMatchConditions(t *testing.T) {
+	tests := map[string]struct {
+		matchconditions []contour_api_v1.MatchCondition
+		want            []QueryParamMatchCondition
+	}{
+		"empty condition list": {
+			matchconditions: nil,
+			want:            nil,
+		},
+		"prefix": {
+			matchconditions: []contour_api_v1.MatchCondition{{
+				Prefix: "/",
+			}},
+			want: nil,
+		},
+		"query parameter exact": {
+			matchconditions: []contour_api_v1.MatchCondition{{
+				QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+					Name:  "variant",
+					Exact: "beta",
+				},
+			}},
+			want: []QueryParamMatchCondition{{
+				Name:      "variant",
+				Value:     "beta",
+				MatchType: "exact",
+			}},
+		},
+		"query parameter prefix ignoring case": {
+			matchconditions: []contour_api_v1.MatchCondition{{
+				QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+					Name:       "variant",
+					Prefix:     "beta",
+					IgnoreCase: true,
+				},
+			}},
+			want: []QueryParamMatchCondition{{
+				Name:       "variant",
+				Value:      "beta",
+				MatchType:  "prefix",
+				IgnoreCase: true,
+			}},
+		},
+		"query parameter regex and present": {
+			matchconditions: []contour_api_v1.MatchCondition{{
+				QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+					Name:  "variant",
+					Regex: "^beta-[0-9]+$",
+				},
+			}, {
+				QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+					Name:    "debug",
+					Present: true,
+				},
+			}},
+			want: []QueryParamMatchCondition{{
+				Name:      "variant",
+				Value:     "^beta-[0-9]+$",
+				MatchType: "regex",
+			}, {
+				Name:      "debug",
+				MatchType: "present",
+			}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := mergeQueryParamMatchConditions(tc.matchconditions)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateQueryParameterMatchConditions(t *testing.T) {
+	tests := map[string]struct {
+		matchconditions []contour_api_v1.MatchCondition
+		wantErr         bool
+	}{
+		"empty condition list": {
+			matchconditions: nil,
+			wantErr:         false,
+		},
+		"valid matchconditions": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					Prefix: "/blog",
+				}, {
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:  "variant",
+						Exact: "beta",
+					},
+				}, {
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:     "user",
+						Contains: "test",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		"missing name": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Exact: "beta",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"missing match type": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name: "variant",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"multiple match types": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:   "variant",
+						Exact:  "beta",
+						Prefix: "b",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"invalid regex": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:  "variant",
+						Regex: "^beta-[",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"duplicate exact match": {
+			matchconditions: []contour_api_v1.MatchCondition{
+				{
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:  "variant",
+						Exact: "beta",
+					},
+				}, {
+					QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+						Name:  "variant",
+						Exact: "alpha",
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotErr := queryParameterMatchConditionsValid(tc.matchconditions)
+
+			if !tc.wantErr {
+				assert.NoError(t, gotErr)
+			}
+
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestValidateTLSMatchConditions(t *testing.T) {
+	presented := contour_api_v1.MatchCondition{
+		TLS: &contour_api_v1.TLSMatchCondition{ClientCertificatePresented: true},
+	}
+	absent := contour_api_v1.MatchCondition{
+		TLS: &contour_api_v1.TLSMatchCondition{ClientCertificatePresented: false},
+	}
+
+	tests := map[string]struct {
+		matchconditions  []contour_api_v1.MatchCondition
+		clientValidation bool
+		wantErr          bool
+		want             *TLSMatchCondition
+	}{
+		"empty condition list": {
+			matchconditions: nil,
+			wantErr:         false,
+			want:            nil,
+		},
+		"client certificate presented": {
+			matchconditions:  []contour_api_v1.MatchCondition{{Prefix: "/"}, presented},
+			clientValidation: true,
+			wantErr:          false,
+			want:             &TLSMatchCondition{ClientCertificatePresented: true},
+		},
+		"repeated condition": {
+			matchconditions:  []contour_api_v1.MatchCondition{absent, absent},
+			clientValidation: true,
+			wantErr:          false,
+			want:             &TLSMatchCondition{ClientCertificatePresented: false},
+		},
+		"conflicting conditions": {
+			matchconditions:  []contour_api_v1.MatchCondition{presented, absent},
+			clientValidation: true,
+			wantErr:          true,
+		},
+		"no client validation": {
+			matchconditions: []contour_api_v1.MatchCondition{presented},
+			wantErr:         true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotErr := tlsMatchConditionsValid(tc.matchconditions, tc.clientValidation)
+
+			if !tc.wantErr {
+				assert.NoError(t, gotErr)
+				assert.Equal(t, tc.want, mergeTLSMatchConditions(tc.matchconditions))
+			}
+
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			}
+		})
+	}
+}
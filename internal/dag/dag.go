@@ -18,6 +18,8 @@ package dag
 import (
 	"errors"
 	"fmt"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +29,7 @@ import (
 	"github.com/projectcontour/contour/internal/timeout"
 	"github.com/projectcontour/contour/internal/xds"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -68,10 +71,35 @@ type DAG struct {
 	// StatusCache holds a cache of status updates to send.
 	StatusCache status.Cache
 
+	// RejectedObjects counts the objects that were skipped, in
+	// whole or in part, while building this DAG. HTTPProxy
+	// rejections are reported through the StatusCache instead.
+	RejectedObjects map[RejectedObject]int
+
+	// Events holds the warnings to record as Kubernetes events
+	// against the objects that were rejected while building
+	// this DAG.
+	Events []Event
+
 	// roots are the root vertices of this DAG.
 	roots []Vertex
 }
 
+// RejectedObject identifies the kind and namespace of an
+// object that was rejected, and the reason it was rejected.
+type RejectedObject struct {
+	Kind      string
+	Namespace string
+	Reason    string
+}
+
+// Event is a warning about a Kubernetes object.
+type Event struct {
+	Object  runtime.Object
+	Reason  string
+	Message string
+}
+
 // Visit calls fn on each root of this DAG.
 func (d *DAG) Visit(fn func(Vertex)) {
 	for _, r := range d.roots {
@@ -116,13 +144,40 @@ type MatchCondition interface {
 	fmt.Stringer
 }
 
+// PrefixMatchType represents different types of prefix matching alternatives.
+type PrefixMatchType int
+
+const (
+	// PrefixMatchString represents a prefix match that functions like a
+	// string prefix match, i.e. prefix /foo matches /foobar
+	PrefixMatchString PrefixMatchType = iota
+	// PrefixMatchSegment represents a prefix match that only matches full path
+	// segments, i.e. prefix /foo matches /foo/bar but not /foobar
+	PrefixMatchSegment
+)
+
 // PrefixMatchCondition matches the start of a URL.
 type PrefixMatchCondition struct {
-	Prefix string
+	Prefix          string
+	PrefixMatchType PrefixMatchType
 }
 
 func (pc *PrefixMatchCondition) String() string {
-	return "prefix: " + pc.Prefix
+	switch pc.PrefixMatchType {
+	case PrefixMatchSegment:
+		return "segment-prefix: " + pc.Prefix
+	default:
+		return "prefix: " + pc.Prefix
+	}
+}
+
+// ExactMatchCondition matches the entire path of a URL.
+type ExactMatchCondition struct {
+	Path string
+}
+
+func (ec *ExactMatchCondition) String() string {
+	return "exact: " + ec.Path
 }
 
 // RegexMatchCondition matches the URL by regular expression.
@@ -153,6 +208,35 @@ func (hc *HeaderMatchCondition) String() string {
 	return "header: " + details
 }
 
+// QueryParamMatchCondition matches querystring parameters by MatchType
+type QueryParamMatchCondition struct {
+	Name       string
+	Value      string
+	MatchType  string
+	IgnoreCase bool
+}
+
+func (qc *QueryParamMatchCondition) String() string {
+	details := strings.Join([]string{
+		"name=" + qc.Name,
+		"value=" + qc.Value,
+		"matchtype=" + qc.MatchType,
+		"ignorecase=" + strconv.FormatBool(qc.IgnoreCase),
+	}, "&")
+
+	return "queryparam: " + details
+}
+
+// TLSMatchCondition matches requests on properties of the
+// TLS connection they were received on.
+type TLSMatchCondition struct {
+	ClientCertificatePresented bool
+}
+
+func (tc *TLSMatchCondition) String() string {
+	return "tls: clientcertificatepresented=" + strconv.FormatBool(tc.ClientCertificatePresented)
+}
+
 // Route defines the properties of a route to a Cluster.
 type Route struct {
 
@@ -164,6 +248,14 @@ type Route struct {
 	// match on the request headers.
 	HeaderMatchConditions []HeaderMatchCondition
 
+	// QueryParamMatchConditions specifies a set of additional Conditions to
+	// match on the querystring parameters.
+	QueryParamMatchConditions []QueryParamMatchCondition
+
+	// TLSMatchCondition specifies an optional Condition to
+	// match on the TLS connection of the request.
+	TLSMatchCondition *TLSMatchCondition
+
 	Clusters []*Cluster
 
 	// Should this route generate a 301 upgrade if accessed
@@ -182,6 +274,10 @@ type Route struct {
 	// TODO(dfc) this should go on the service
 	Websocket bool
 
+	// UpgradeTypes are the additional HTTP upgrade
+	// types enabled on this route.
+	UpgradeTypes []string
+
 	// TimeoutPolicy defines the timeout request/idle
 	TimeoutPolicy TimeoutPolicy
 
@@ -199,6 +295,44 @@ type Route struct {
 
 	// ResponseHeadersPolicy defines how headers are managed during forwarding
 	ResponseHeadersPolicy *HeadersPolicy
+
+	// RateLimitPolicy defines if/how requests for the route are rate limited.
+	RateLimitPolicy *RateLimitPolicy
+
+	// RequestHashPolicies is a list of policies for configuring hashes on
+	// request attributes.
+	RequestHashPolicies []RequestHashPolicy
+
+	// MaxRequestBodyBytes is the maximum size of a request body
+	// accepted by this route. Zero means the size is not limited.
+	MaxRequestBodyBytes uint32
+
+	// CacheTTL is how long Envoy caches responses to this
+	// route. Zero means responses are not cached.
+	CacheTTL time.Duration
+
+	// AllowedSourceRanges restricts the route to requests whose
+	// client address is in one of these ranges. An empty list
+	// means requests from any address are allowed.
+	AllowedSourceRanges []*net.IPNet
+
+	// ForwardProxy, if set, forwards requests to the host
+	// named in each request instead of to Clusters.
+	ForwardProxy *ForwardProxy
+
+	// OriginalDestination, if set, forwards requests to the
+	// address named in each request's x-envoy-original-dst-host
+	// header instead of to Clusters.
+	OriginalDestination *OriginalDestination
+
+	// FaultPolicy, if set, defines the faults injected
+	// into requests to this route.
+	FaultPolicy *FaultPolicy
+
+	// ResponseTimeoutHeader, if set, names a request header
+	// whose value, in milliseconds, overrides the response
+	// timeout of requests from internal clients.
+	ResponseTimeoutHeader string
 }
 
 // HasPathPrefix returns whether this route has a PrefixPathCondition.
@@ -239,8 +373,138 @@ type RetryPolicy struct {
 	// PerTryTimeout specifies the timeout per retry attempt.
 	// Ignored if RetryOn is blank.
 	PerTryTimeout timeout.Setting
+
+	// Backoff, if not nil, sets the interval between retries.
+	Backoff *RetryBackoff
+}
+
+// FaultPolicy defines the faults injected into requests to a route.
+type FaultPolicy struct {
+	// DelayDuration is how long delayed requests are delayed for.
+	DelayDuration time.Duration
+
+	// DelayPercent is the percentage of requests that are delayed.
+	DelayPercent uint32
+
+	// AbortStatusCode is the status code of the response
+	// to aborted requests.
+	AbortStatusCode uint32
+
+	// AbortPercent is the percentage of requests that are aborted.
+	AbortPercent uint32
+}
+
+// RetryBackoff configures the exponential backoff between retries.
+type RetryBackoff struct {
+	// BaseInterval is the base interval between retries.
+	BaseInterval time.Duration
+
+	// MaxInterval is the maximum interval between retries.
+	// If zero, Envoy uses ten times the base interval.
+	MaxInterval time.Duration
+}
+
+// RetryBudget limits the number of concurrent retries to an
+// upstream cluster as a percentage of its active requests.
+type RetryBudget struct {
+	// BudgetPercent is the percentage of active requests
+	// that may be retries.
+	BudgetPercent uint32
+
+	// MinRetryConcurrency is the number of concurrent retries
+	// that are always allowed, regardless of the budget.
+	MinRetryConcurrency uint32
+}
+
+// RequestHashPolicy holds the configuration for a request attribute
+// that is hashed to select an upstream host.
+type RequestHashPolicy struct {
+	// Terminal determines if the request attribute is present, hash
+	// calculation should stop with this element.
+	Terminal bool
+
+	// HeaderHashOptions is set when a header hash is desired.
+	HeaderHashOptions *HeaderHashOptions
+
+	// CookieHashOptions is set when a cookie hash is desired.
+	CookieHashOptions *CookieHashOptions
+
+	// HashSourceIP is set to true when source ip hashing is desired.
+	HashSourceIP bool
+}
+
+// HeaderHashOptions contains options for hashing a HTTP header.
+type HeaderHashOptions struct {
+	// HeaderName is the name of the header to hash.
+	HeaderName string
+}
+
+// CookieHashOptions contains options for hashing a HTTP cookie.
+type CookieHashOptions struct {
+	// CookieName is the name of the cookie to hash.
+	CookieName string
+
+	// TTL is the lifetime of the cookie generated when the request
+	// does not have one. Zero means no cookie is generated.
+	TTL time.Duration
+
+	// Path is the path of the generated cookie.
+	Path string
+}
+
+// RingHashConfig tunes the ring hash load balancer.
+type RingHashConfig struct {
+	MinimumRingSize uint64
+	MaximumRingSize uint64
+}
+
+// MaglevConfig tunes the Maglev load balancer.
+type MaglevConfig struct {
+	TableSize uint64
+}
+
+// RateLimitPolicy holds rate limiting parameters.
+type RateLimitPolicy struct {
+	Global *GlobalRateLimitPolicy
+}
+
+// GlobalRateLimitPolicy holds global rate limiting parameters.
+type GlobalRateLimitPolicy struct {
+	Descriptors []*RateLimitDescriptor
+}
+
+// RateLimitDescriptor is a list of rate limit descriptor entries.
+type RateLimitDescriptor struct {
+	Entries []RateLimitDescriptorEntry
+}
+
+// RateLimitDescriptorEntry is an entry in a rate limit descriptor.
+// Exactly one field should be non-nil.
+type RateLimitDescriptorEntry struct {
+	GenericKey    *GenericKeyDescriptorEntry
+	HeaderMatch   *HeaderMatchDescriptorEntry
+	RemoteAddress *RemoteAddressDescriptorEntry
+}
+
+// GenericKeyDescriptorEntry configures a descriptor entry
+// that has a static key & value.
+type GenericKeyDescriptorEntry struct {
+	Key   string
+	Value string
+}
+
+// HeaderMatchDescriptorEntry configures a descriptor entry
+// that's populated only if the specified header is present
+// on the request.
+type HeaderMatchDescriptorEntry struct {
+	HeaderName string
+	Key        string
 }
 
+// RemoteAddressDescriptorEntry configures a descriptor entry
+// that contains the remote address (i.e. client IP).
+type RemoteAddressDescriptorEntry struct{}
+
 // MirrorPolicy defines the mirroring policy for a route.
 type MirrorPolicy struct {
 	Cluster *Cluster
@@ -255,6 +519,24 @@ type HeadersPolicy struct {
 	Remove []string
 }
 
+// RouteDefaults holds the policies applied to routes
+// that do not set their own.
+type RouteDefaults struct {
+	// TimeoutPolicy holds the timeouts of routes that
+	// use the default timeouts.
+	TimeoutPolicy TimeoutPolicy
+
+	// RetryPolicy is the retry policy of routes
+	// without one.
+	RetryPolicy *RetryPolicy
+
+	// RequestHeadersPolicy and ResponseHeadersPolicy are
+	// merged into the headers policies of routes. Headers
+	// that a route sets or removes itself are left as is.
+	RequestHeadersPolicy  *HeadersPolicy
+	ResponseHeadersPolicy *HeadersPolicy
+}
+
 // CORSPolicy allows setting the CORS policy
 type CORSPolicy struct {
 	// Specifies whether the resource allows credentials.
@@ -271,6 +553,20 @@ type CORSPolicy struct {
 	MaxAge timeout.Setting
 }
 
+// HSTSPolicy defines the Strict-Transport-Security header
+// added to responses from secure virtual hosts.
+type HSTSPolicy struct {
+	// MaxAge is how long clients should only access
+	// the host over HTTPS.
+	MaxAge time.Duration
+	// IncludeSubdomains extends the policy to all subdomains
+	// of the host.
+	IncludeSubdomains bool
+	// Preload signals consent to be included in browser
+	// HSTS preload lists.
+	Preload bool
+}
+
 type HeaderValue struct {
 	// Name represents a key of a header
 	Key string
@@ -286,6 +582,12 @@ type PeerValidationContext struct {
 	// SubjectName holds an optional subject name which Envoy will check against the
 	// certificate presented by the upstream.
 	SubjectName string
+	// OptionalClientCertificate is set if downstream clients
+	// may connect without presenting a certificate.
+	OptionalClientCertificate bool
+	// CRL holds an optional reference to the Secret containing the
+	// certificate revocation list used to verify downstream clients.
+	CRL *Secret
 }
 
 // GetCACertificate returns the CA certificate from PeerValidationContext.
@@ -297,6 +599,14 @@ func (pvc *PeerValidationContext) GetCACertificate() []byte {
 	return pvc.CACertificate.Object.Data[CACertificateKey]
 }
 
+// GetCRL returns the certificate revocation list from PeerValidationContext.
+func (pvc *PeerValidationContext) GetCRL() []byte {
+	if pvc == nil || pvc.CRL == nil {
+		return nil
+	}
+	return pvc.CRL.Object.Data[CRLKey]
+}
+
 // GetSubjectName returns the SubjectName from PeerValidationContext.
 func (pvc *PeerValidationContext) GetSubjectName() string {
 	if pvc == nil {
@@ -315,8 +625,29 @@ func (r *Route) Visit(f func(Vertex)) {
 	if r.MirrorPolicy != nil && r.MirrorPolicy.Cluster != nil {
 		f(r.MirrorPolicy.Cluster)
 	}
+	if r.ForwardProxy != nil {
+		f(r.ForwardProxy)
+	}
+	if r.OriginalDestination != nil {
+		f(r.OriginalDestination)
+	}
+}
+
+// ForwardProxy forwards requests to the host named in each
+// request, resolving the host with DNS.
+type ForwardProxy struct {
+	// DNSLookupFamily defines how the hosts are looked up.
+	DNSLookupFamily string
 }
 
+func (f *ForwardProxy) Visit(func(Vertex)) {}
+
+// OriginalDestination forwards requests to the address named in
+// each request's x-envoy-original-dst-host header.
+type OriginalDestination struct{}
+
+func (o *OriginalDestination) Visit(func(Vertex)) {}
+
 // A VirtualHost represents a named L4/L7 service.
 type VirtualHost struct {
 	// Name is the fully qualified domain name of a network host,
@@ -326,6 +657,15 @@ type VirtualHost struct {
 	// CORSPolicy is the cross-origin policy to apply to the VirtualHost.
 	CORSPolicy *CORSPolicy
 
+	// Disabled is set if every request to the VirtualHost
+	// should be answered with a 503 instead of being routed.
+	Disabled bool
+
+	// Listener, if set, is the name of the additional HTTP
+	// listener that serves the VirtualHost, instead of the
+	// default HTTP listener.
+	Listener string
+
 	routes map[string]*Route
 }
 
@@ -341,6 +681,12 @@ func conditionsToString(r *Route) string {
 	for _, cond := range r.HeaderMatchConditions {
 		s = append(s, cond.String())
 	}
+	for _, cond := range r.QueryParamMatchConditions {
+		s = append(s, cond.String())
+	}
+	if r.TLSMatchCondition != nil {
+		s = append(s, r.TLSMatchCondition.String())
+	}
 	return strings.Join(s, ",")
 }
 
@@ -365,9 +711,26 @@ type SecureVirtualHost struct {
 	// The cert and key for this host.
 	Secret *Secret
 
+	// SecondarySecret is an optional second cert and key for
+	// this host, with a different key type than Secret.
+	SecondarySecret *Secret
+
+	// ALPNProtocols is the optional list of ALPN protocol
+	// names, "h2" and "http/1.1", negotiated with clients of
+	// this host. If empty, the listener defaults apply.
+	ALPNProtocols []string
+
+	// OCSPStaplePolicy defines how OCSP responses are
+	// stapled to the certificates of this host.
+	OCSPStaplePolicy OCSPStaplePolicy
+
 	// FallbackCertificate
 	FallbackCertificate *Secret
 
+	// HSTSPolicy, if set, adds a Strict-Transport-Security
+	// header to responses from this host.
+	HSTSPolicy *HSTSPolicy
+
 	// Service to TCP proxy all incoming connections.
 	*TCPProxy
 
@@ -388,8 +751,43 @@ type SecureVirtualHost struct {
 	// only reason to set this to `true` is when you are migrating
 	// from internal to external authorization.
 	AuthorizationFailOpen bool
+
+	// GRPCJSONTranscoder, if set, translates JSON requests
+	// to this host into gRPC requests.
+	GRPCJSONTranscoder *GRPCJSONTranscoder
 }
 
+// GRPCJSONTranscoder translates JSON requests into requests to
+// the methods of gRPC services.
+type GRPCJSONTranscoder struct {
+	// Descriptor is the serialized protobuf descriptor
+	// set of the gRPC services.
+	Descriptor []byte
+
+	// Services are the fully qualified names of
+	// the gRPC services to transcode.
+	Services []string
+}
+
+// OCSPStaplePolicy defines how OCSP responses are stapled to
+// certificates.
+type OCSPStaplePolicy int
+
+const (
+	// OCSPLenientStapling staples an OCSP response if one is
+	// present and valid.
+	OCSPLenientStapling OCSPStaplePolicy = iota
+
+	// OCSPStrictStapling staples an OCSP response if one is
+	// present, and stops using a certificate whose response
+	// has expired.
+	OCSPStrictStapling
+
+	// OCSPMustStaple requires an OCSP response for every
+	// certificate.
+	OCSPMustStaple
+)
+
 func (s *SecureVirtualHost) Visit(f func(Vertex)) {
 	s.VirtualHost.Visit(f)
 	if s.TCPProxy != nil {
@@ -398,6 +796,9 @@ func (s *SecureVirtualHost) Visit(f func(Vertex)) {
 	if s.Secret != nil {
 		f(s.Secret) // secret is not required if vhost is using tls passthrough
 	}
+	if s.SecondarySecret != nil {
+		f(s.SecondarySecret)
+	}
 }
 
 func (s *SecureVirtualHost) Valid() bool {
@@ -411,6 +812,12 @@ func (s *SecureVirtualHost) Valid() bool {
 // incoming connections.
 type Listener struct {
 
+	// Name, if set, is the name of the additional HTTP
+	// listener that serves the virtual hosts bound to it.
+	// Otherwise, the listener is the default HTTP or HTTPS
+	// listener, according to its Port.
+	Name string
+
 	// Address is the TCP address to listen on.
 	// If blank 0.0.0.0, or ::/0 for IPv6, is assumed.
 	Address string
@@ -419,6 +826,10 @@ type Listener struct {
 	Port int
 
 	VirtualHosts []Vertex
+
+	// SessionTicketKeys, if set, holds the keys used to
+	// encrypt and decrypt TLS session tickets.
+	SessionTicketKeys *Secret
 }
 
 func (l *Listener) Visit(f func(Vertex)) {
@@ -433,6 +844,10 @@ type TCPProxy struct {
 	// Clusters is the, possibly weighted, set
 	// of upstream services to forward decrypted traffic.
 	Clusters []*Cluster
+
+	// HashSourceIP is true if connections are hashed on
+	// their source IP for clusters that use hashing.
+	HashSourceIP bool
 }
 
 func (t *TCPProxy) Visit(f func(Vertex)) {
@@ -467,8 +882,32 @@ type Service struct {
 	// Envoy will allow to the upstream cluster.
 	MaxRetries uint32
 
+	// Connection pool settings
+
+	// MaxRequestsPerConnection is the maximum number of requests
+	// that Envoy will send over a single upstream connection.
+	MaxRequestsPerConnection uint32
+
+	// ConnectTimeout is the timeout for new upstream connections.
+	// Zero means the Envoy cluster default.
+	ConnectTimeout time.Duration
+
+	// HTTP2InitialStreamWindowSize and HTTP2InitialConnectionWindowSize
+	// set the HTTP/2 flow control windows for h2 and h2c upstreams.
+	HTTP2InitialStreamWindowSize     uint32
+	HTTP2InitialConnectionWindowSize uint32
+
 	// ExternalName is an optional field referencing a dns entry for Service type "ExternalName"
 	ExternalName string
+
+	// External is true if the service is a backend outside of
+	// the cluster that is not backed by a Kubernetes Service.
+	// ExternalName then holds the address of the backend.
+	External bool
+
+	// ClientIPAffinity is true if the Kubernetes Service has
+	// ClientIP session affinity.
+	ClientIPAffinity bool
 }
 
 // Visit applies the visitor function to the Service vertex.
@@ -511,12 +950,23 @@ type Cluster struct {
 	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/cluster.proto#enum-config-cluster-v3-cluster-lbpolicy
 	LoadBalancerPolicy string
 
+	// RingHashConfig tunes the ring hash load balancer, if it
+	// is used by LoadBalancerPolicy.
+	RingHashConfig *RingHashConfig
+
+	// MaglevConfig tunes the Maglev load balancer, if it is
+	// used by LoadBalancerPolicy.
+	MaglevConfig *MaglevConfig
+
 	// Cluster http health check policy
 	*HTTPHealthCheckPolicy
 
 	// Cluster tcp health check policy
 	*TCPHealthCheckPolicy
 
+	// Cluster grpc health check policy
+	*GRPCHealthCheckPolicy
+
 	// RequestHeadersPolicy defines how headers are managed during forwarding
 	RequestHeadersPolicy *HeadersPolicy
 
@@ -540,9 +990,35 @@ type Cluster struct {
 	// Note: This only applies to externalName clusters.
 	DNSLookupFamily string
 
+	// DNSResolvers are the addresses of the DNS servers
+	// that resolve external names, as an IP address with an
+	// optional port. If empty, Envoy uses the resolvers of
+	// the host it runs on.
+	DNSResolvers []string
+
 	// ClientCertificate is the optional identifier of the TLS secret containing client certificate and
 	// private key to be used when establishing TLS connection to upstream cluster.
 	ClientCertificate *Secret
+
+	// UpstreamTLS records the SNI and client certificate set
+	// on the service, if any. SNI and ClientCertificate already
+	// carry the effective values; UpstreamTLS is kept so that
+	// per-service overrides result in distinct clusters.
+	UpstreamTLS *UpstreamTLS
+
+	// RetryBudget limits the share of active requests to
+	// this cluster that may be retries.
+	RetryBudget *RetryBudget
+}
+
+// UpstreamTLS holds the per-service TLS parameters
+// used when connecting to an upstream.
+type UpstreamTLS struct {
+	// SNI is the server name sent to the upstream.
+	SNI string
+
+	// ClientCertificate is the secret presented to the upstream.
+	ClientCertificate *Secret
 }
 
 func (c Cluster) Visit(f func(Vertex)) {
@@ -676,6 +1152,27 @@ func (s *Secret) PrivateKey() []byte {
 	return s.Object.Data[v1.TLSPrivateKeyKey]
 }
 
+// OCSPStaple returns the secret's DER encoded OCSP response.
+func (s *Secret) OCSPStaple() []byte {
+	return s.Object.Data[OCSPStapleKey]
+}
+
+// SessionTicketKeys returns the secret's TLS session ticket
+// keys, ordered by their data key names.
+func (s *Secret) SessionTicketKeys() [][]byte {
+	names := make([]string, 0, len(s.Object.Data))
+	for name := range s.Object.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([][]byte, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, s.Object.Data[name])
+	}
+	return keys
+}
+
 // Cluster http health check policy
 type HTTPHealthCheckPolicy struct {
 	Path               string
@@ -694,6 +1191,16 @@ type TCPHealthCheckPolicy struct {
 	HealthyThreshold   uint32
 }
 
+// Cluster grpc health check policy
+type GRPCHealthCheckPolicy struct {
+	ServiceName        string
+	Authority          string
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold uint32
+	HealthyThreshold   uint32
+}
+
 // ExtensionCluster generates an Envoy cluster (aka ClusterLoadAssignment)
 // for an ExtensionService resource.
 type ExtensionCluster struct {
@@ -243,3 +243,14 @@ func TestServiceClusterRebalance(t *testing.T) {
 	}
 
 }
+
+func TestQueryParamMatchConditionString(t *testing.T) {
+	qc := &QueryParamMatchCondition{
+		Name:       "variant",
+		Value:      "beta",
+		MatchType:  "exact",
+		IgnoreCase: true,
+	}
+
+	assert.Equal(t, "queryparam: name=variant&value=beta&matchtype=exact&ignorecase=true", qc.String())
+}
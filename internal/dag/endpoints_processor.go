@@ -0,0 +1,63 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"sort"
+
+	"github.com/projectcontour/contour/internal/xds"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// EndpointsProcessor adds a ServiceCluster to the DAG for each
+// port of every Service, so that the endpoints of all Services
+// are served over EDS even though no route refers to them. The
+// clusters have the same names as the EDS clusters of routes.
+type EndpointsProcessor struct{}
+
+var _ Processor = &EndpointsProcessor{}
+
+// Run adds the ServiceClusters of the Services in the cache to the DAG.
+func (p *EndpointsProcessor) Run(dag *DAG, cache *KubernetesCache) {
+	names := make([]types.NamespacedName, 0, len(cache.services))
+	for name := range cache.services {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Namespace != names[j].Namespace {
+			return names[i].Namespace < names[j].Namespace
+		}
+		return names[i].Name < names[j].Name
+	})
+
+	for _, name := range names {
+		svc := cache.services[name]
+
+		// ExternalName Services have no endpoints.
+		if svc.Spec.Type == v1.ServiceTypeExternalName {
+			continue
+		}
+
+		for _, port := range svc.Spec.Ports {
+			cluster := &ServiceCluster{
+				ClusterName: xds.ClusterLoadAssignmentName(name, port.Name),
+			}
+			cluster.AddService(name, port)
+
+			dag.AddRoot(cluster)
+		}
+	}
+}
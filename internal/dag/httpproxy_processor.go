@@ -14,10 +14,13 @@
 package dag
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 
+	"github.com/golang/protobuf/proto"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
 	"github.com/projectcontour/contour/internal/annotation"
@@ -25,9 +28,11 @@ import (
 	"github.com/projectcontour/contour/internal/status"
 	"github.com/projectcontour/contour/internal/timeout"
 	"github.com/projectcontour/contour/pkg/config"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // defaultExtensionRef populates the unset fields in ref with default values.
@@ -43,9 +48,22 @@ func defaultExtensionRef(ref contour_api_v1.ExtensionServiceReference) contour_a
 // HTTPProxyProcessor translates HTTPProxies into DAG
 // objects and adds them to the DAG.
 type HTTPProxyProcessor struct {
-	dag      *DAG
-	source   *KubernetesCache
-	orphaned map[types.NamespacedName]bool
+	dag       *DAG
+	source    *KubernetesCache
+	orphaned  map[types.NamespacedName]bool
+	overQuota map[types.NamespacedName]quotaViolation
+
+	// foreign holds the HTTPProxies that belong to the virtual
+	// hosts of other Contour instances. See DomainSuffixes.
+	foreign map[types.NamespacedName]bool
+
+	// coalescing holds, for each root HTTPProxy, the hosts of
+	// the other TLS virtual hosts that its certificate covers.
+	coalescing map[types.NamespacedName][]string
+
+	// overlaps holds the root HTTPProxies whose fqdn overlaps
+	// another virtual host. See overlappingFqdns.
+	overlaps map[types.NamespacedName]string
 
 	// DisablePermitInsecure disables the use of the
 	// permitInsecure field in HTTPProxy.
@@ -56,6 +74,10 @@ type HTTPProxyProcessor struct {
 	// request.
 	FallbackCertificate *types.NamespacedName
 
+	// Listeners are the names of the additional HTTP
+	// listeners that virtual hosts may be bound to.
+	Listeners []string
+
 	// DNSLookupFamily defines how external names are looked up
 	// When configured as V4, the DNS resolver will only perform a lookup
 	// for addresses in the IPv4 family. If V6 is configured, the DNS resolver
@@ -66,9 +88,55 @@ type HTTPProxyProcessor struct {
 	// Note: This only applies to externalName clusters.
 	DNSLookupFamily config.ClusterDNSFamilyType
 
+	// DNSResolvers are the optional addresses of the DNS
+	// servers that resolve external names.
+	DNSResolvers []string
+
 	// ClientCertificate is the optional identifier of the TLS secret containing client certificate and
 	// private key to be used when establishing TLS connection to upstream cluster.
 	ClientCertificate *types.NamespacedName
+
+	// EnableExternalBackends allows HTTPProxy routes to proxy
+	// to backends outside of the cluster that are not
+	// Kubernetes Services.
+	EnableExternalBackends bool
+
+	// EnableForwardProxy allows root HTTPProxies to configure
+	// their virtual host as a forward proxy.
+	EnableForwardProxy bool
+
+	// EnableOriginalDestination allows HTTPProxy routes to
+	// forward requests to the address named in each request's
+	// x-envoy-original-dst-host header.
+	EnableOriginalDestination bool
+
+	// HSTSPolicy is the optional Strict-Transport-Security
+	// policy applied to secure virtual hosts.
+	HSTSPolicy *HSTSPolicy
+
+	// RetryBudget is the optional retry budget applied to
+	// clusters of routes that do not set their own.
+	RetryBudget *RetryBudget
+
+	// NamespaceQuota limits the virtual hosts and routes
+	// that the HTTPProxies of each namespace may define.
+	NamespaceQuota NamespaceQuota
+
+	// NamespaceQuotas replaces NamespaceQuota for the
+	// namespaces it lists.
+	NamespaceQuotas map[string]NamespaceQuota
+
+	// NamespaceDefaults holds the policies applied to the
+	// routes of each listed namespace that do not set
+	// their own, keyed by namespace name.
+	NamespaceDefaults map[string]*RouteDefaults
+
+	// DomainSuffixes, if not empty, restricts the processor to the
+	// root HTTPProxies whose fqdn is equal to, or a subdomain of,
+	// one of the suffixes. Other roots, and the HTTPProxies they
+	// include, are neither processed nor given a status, as they
+	// are left to the Contour instance that owns them.
+	DomainSuffixes []string
 }
 
 // Run translates HTTPProxies into DAG objects and
@@ -77,19 +145,39 @@ func (p *HTTPProxyProcessor) Run(dag *DAG, source *KubernetesCache) {
 	p.dag = dag
 	p.source = source
 	p.orphaned = make(map[types.NamespacedName]bool, len(p.orphaned))
+	p.foreign = make(map[types.NamespacedName]bool)
+	p.overQuota = map[types.NamespacedName]quotaViolation{}
+	for obj, v := range quotaViolations(source, p.NamespaceQuota, p.NamespaceQuotas) {
+		if obj.kind == "HTTPProxy" {
+			p.overQuota[obj.name] = v
+		}
+	}
+	p.coalescing = p.coalescingHosts()
+	p.overlaps = overlappingFqdns(source)
 
 	// reset the processor when we're done
 	defer func() {
 		p.dag = nil
 		p.source = nil
 		p.orphaned = nil
+		p.foreign = nil
+		p.overQuota = nil
+		p.coalescing = nil
+		p.overlaps = nil
 	}()
 
 	for _, proxy := range p.validHTTPProxies() {
+		if p.foreign[k8s.NamespacedNameOf(proxy)] {
+			continue
+		}
 		p.computeHTTPProxy(proxy)
 	}
 
 	for meta := range p.orphaned {
+		if p.foreign[meta] {
+			continue
+		}
+
 		proxy, ok := p.source.httpproxies[meta]
 		if ok {
 			pa, commit := p.dag.StatusCache.ProxyAccessor(proxy)
@@ -129,6 +217,17 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 		return
 	}
 
+	if v, ok := p.overQuota[k8s.NamespacedNameOf(proxy)]; ok {
+		validCond.AddError(v.conditionType, v.reason, v.message)
+		p.dag.recordEvent(proxy, v.reason, v.message)
+		return
+	}
+
+	if proxy.Spec.VirtualHost.ForwardProxy != nil {
+		p.computeForwardProxy(validCond, proxy)
+		return
+	}
+
 	if strings.Contains(host, "*") {
 		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "WildCardNotAllowed",
 			"Spec.VirtualHost.Fqdn %q cannot use wildcards", host)
@@ -141,6 +240,33 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 		return
 	}
 
+	if proxy.Spec.VirtualHost.GRPCJSONTranscoder != nil {
+		if tls := proxy.Spec.VirtualHost.TLS; tls == nil || tls.Passthrough {
+			validCond.AddError(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderRequiresTLS",
+				"Spec.VirtualHost.GRPCJSONTranscoder requires that Spec.VirtualHost.TLS.SecretName be set")
+			return
+		}
+	}
+
+	if listener := proxy.Spec.VirtualHost.Listener; listener != "" {
+		if proxy.Spec.VirtualHost.TLS != nil {
+			validCond.AddError(contour_api_v1.ConditionTypeVirtualHostError, "ListenerNotValid",
+				"Spec.VirtualHost.Listener cannot be combined with Spec.VirtualHost.TLS")
+			return
+		}
+		if !p.listenerConfigured(listener) {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "ListenerNotFound",
+				"Spec.VirtualHost.Listener %q is not a configured HTTP listener", listener)
+			return
+		}
+	}
+
+	if _, err := rateLimitPolicy(proxy.Spec.VirtualHost.RateLimitPolicy); err != nil {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "RateLimitPolicyNotValid",
+			"Spec.VirtualHost.RateLimitPolicy is invalid: %s", err)
+		return
+	}
+
 	var tlsEnabled bool
 	if tls := proxy.Spec.VirtualHost.TLS; tls != nil {
 		if !isBlank(tls.SecretName) && tls.Passthrough {
@@ -155,6 +281,18 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 			return
 		}
 
+		if tls.Passthrough && !isBlank(tls.SecondarySecretName) {
+			validCond.AddError(contour_api_v1.ConditionTypeTLSError, "TLSConfigNotValid",
+				"Spec.VirtualHost.TLS: both Passthrough and SecondarySecretName were specified")
+			return
+		}
+
+		if tls.Passthrough && tls.Shadow != nil {
+			validCond.AddError(contour_api_v1.ConditionTypeTLSError, "TLSConfigNotValid",
+				"Spec.VirtualHost.TLS: both Passthrough and Shadow were specified")
+			return
+		}
+
 		if tls.Passthrough && tls.ClientValidation != nil {
 			validCond.AddError(contour_api_v1.ConditionTypeTLSError, "TLSIncompatibleFeatures",
 				"Spec.VirtualHost.TLS passthrough cannot be combined with tls.clientValidation")
@@ -179,10 +317,50 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 				return
 			}
 
+			var secondary *Secret
+			if !isBlank(tls.SecondarySecretName) {
+				secondaryName := k8s.NamespacedNameFrom(tls.SecondarySecretName, k8s.DefaultNamespace(proxy.Namespace))
+				secondary, err = p.source.LookupSecret(secondaryName, validSecret)
+				if err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "SecretNotValid",
+						"Spec.VirtualHost.TLS Secret %q is invalid: %s", tls.SecondarySecretName, err)
+					return
+				}
+
+				if !p.source.DelegationPermitted(secondaryName, proxy.Namespace) {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "DelegationNotPermitted",
+						"Spec.VirtualHost.TLS Secret %q certificate delegation not permitted", tls.SecondarySecretName)
+					return
+				}
+
+				if err := validateCertificateKeyTypes(sec.Cert(), secondary.Cert()); err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "TLSConfigNotValid",
+						"Spec.VirtualHost.TLS Secrets %q and %q can not be used together: %s", tls.SecretName, tls.SecondarySecretName, err)
+					return
+				}
+			}
+
+			staplePolicy, err := ocspStaplePolicy(tls.OCSPStaplePolicy, sec, secondary)
+			if err != nil {
+				validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "OCSPStaplePolicyNotValid",
+					"Spec.VirtualHost.TLS.OCSPStaplePolicy is invalid: %s", err)
+				return
+			}
+
 			svhost := p.dag.EnsureSecureVirtualHost(host)
 			svhost.Secret = sec
+			svhost.SecondarySecret = secondary
+			svhost.OCSPStaplePolicy = staplePolicy
+			svhost.HSTSPolicy = p.HSTSPolicy
 			// default to a minimum TLS version of 1.2 if it's not specified
 			svhost.MinTLSVersion = annotation.MinTLSVersion(tls.MinimumProtocolVersion, "1.2")
+			svhost.ALPNProtocols = alpnProtocols(tls.HTTPVersions)
+
+			if hosts, ok := p.coalescing[k8s.NamespacedNameOf(proxy)]; ok {
+				validCond.AddWarningf(contour_api_v1.ConditionTypeTLSError, "ConnectionCoalescing",
+					"Spec.VirtualHost.TLS Secret %q certificate also covers %s, so HTTP/2 clients may reuse connections for this virtual host to send requests for those hosts, which are answered with 421 Misdirected Request",
+					tls.SecretName, strings.Join(hosts, ", "))
+			}
 
 			// Check if FallbackCertificate && ClientValidation are both enabled in the same vhost
 			if tls.EnableFallbackCertificate && tls.ClientValidation != nil {
@@ -253,7 +431,7 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 					Namespace: stringOrDefault(ref.Namespace, proxy.Namespace),
 				}
 
-				ext := p.dag.GetExtensionCluster(extensionClusterName(extensionName))
+				ext := p.dag.GetExtensionCluster(ExtensionClusterName(extensionName))
 				if ext == nil {
 					validCond.AddErrorf(contour_api_v1.ConditionTypeAuthError, "ExtensionServiceNotFound",
 						"Spec.Virtualhost.Authorization.ServiceRef extension service %q not found", extensionName)
@@ -276,6 +454,37 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 					svhost.AuthorizationResponseTimeout = timeout
 				}
 			}
+
+			if transcoder := proxy.Spec.VirtualHost.GRPCJSONTranscoder; transcoder != nil {
+				if tls.EnableFallbackCertificate {
+					validCond.AddError(contour_api_v1.ConditionTypeTLSError, "TLSIncompatibleFeatures",
+						"Spec.Virtualhost.TLS fallback & gRPC JSON transcoding are incompatible")
+					return
+				}
+
+				descriptorName := types.NamespacedName{Name: transcoder.DescriptorSecretName, Namespace: proxy.Namespace}
+				descriptor, err := p.source.LookupSecret(descriptorName, validGRPCDescriptor)
+				if err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderNotValid",
+						"Spec.VirtualHost.GRPCJSONTranscoder Secret %q is invalid: %s", transcoder.DescriptorSecretName, err)
+					return
+				}
+
+				if err := grpcServicesDescribed(descriptor.Object.Data[GRPCDescriptorKey], transcoder.Services); err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderNotValid",
+						"Spec.VirtualHost.GRPCJSONTranscoder is invalid: %s", err)
+					return
+				}
+
+				svhost.GRPCJSONTranscoder = &GRPCJSONTranscoder{
+					Descriptor: descriptor.Object.Data[GRPCDescriptorKey],
+					Services:   transcoder.Services,
+				}
+			}
+
+			if tls.Shadow != nil && !p.computeShadowVirtualHost(validCond, proxy, svhost) {
+				return
+			}
 		}
 	}
 
@@ -290,7 +499,19 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 		}
 	}
 
-	routes := p.computeRoutes(validCond, proxy, proxy, nil, nil, tlsEnabled)
+	routes := p.computeRoutes(pa, proxy, proxy, nil, nil, tlsEnabled)
+
+	if header := proxy.Spec.VirtualHost.ResponseTimeoutHeader; header != "" {
+		if msgs := validation.IsHTTPHeaderName(header); len(msgs) != 0 {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "ResponseTimeoutHeaderInvalid",
+				"Spec.VirtualHost.ResponseTimeoutHeader %q is invalid: %s", header, strings.Join(msgs, ", "))
+			return
+		}
+		for _, route := range routes {
+			route.ResponseTimeoutHeader = header
+		}
+	}
+
 	insecure := p.dag.EnsureVirtualHost(host)
 	cp, err := toCORSPolicy(proxy.Spec.VirtualHost.CORSPolicy)
 	if err != nil {
@@ -299,6 +520,8 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 		return
 	}
 	insecure.CORSPolicy = cp
+	insecure.Disabled = insecure.Disabled || annotation.Disabled(proxy)
+	insecure.Listener = proxy.Spec.VirtualHost.Listener
 	addRoutes(insecure, routes)
 
 	// if TLS is enabled for this virtual host and there is no tcp proxy defined,
@@ -306,10 +529,209 @@ func (p *HTTPProxyProcessor) computeHTTPProxy(proxy *contour_api_v1.HTTPProxy) {
 	if tlsEnabled && proxy.Spec.TCPProxy == nil {
 		secure := p.dag.EnsureSecureVirtualHost(host)
 		secure.CORSPolicy = cp
+		secure.Disabled = secure.Disabled || annotation.Disabled(proxy)
 		addRoutes(secure, routes)
+
+		if shadow := proxy.Spec.VirtualHost.TLS.Shadow; shadow != nil {
+			svhost := p.dag.EnsureSecureVirtualHost(shadow.Fqdn)
+			svhost.CORSPolicy = cp
+			svhost.Disabled = secure.Disabled
+			addRoutes(svhost, routes)
+		}
 	}
 }
 
+// listenerConfigured returns true if name is one of
+// the additional HTTP listeners.
+func (p *HTTPProxyProcessor) listenerConfigured(name string) bool {
+	for _, l := range p.Listeners {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// computeForwardProxy validates a root proxy whose virtual host is a
+// forward proxy, and adds a virtual host that forwards every request
+// to the host it names.
+func (p *HTTPProxyProcessor) computeForwardProxy(validCond *contour_api_v1.DetailedCondition, proxy *contour_api_v1.HTTPProxy) {
+	vh := proxy.Spec.VirtualHost
+
+	if !p.EnableForwardProxy {
+		validCond.AddError(contour_api_v1.ConditionTypeVirtualHostError, "ForwardProxyNotEnabled",
+			"Spec.VirtualHost.ForwardProxy is not enabled in the Contour configuration")
+		return
+	}
+
+	// A forward proxy may allow every subdomain of a domain, but
+	// Envoy only supports a wildcard as the leftmost label.
+	if strings.Contains(strings.TrimPrefix(vh.Fqdn, "*."), "*") {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "WildCardNotAllowed",
+			"Spec.VirtualHost.Fqdn %q can only use a wildcard as its first label", vh.Fqdn)
+		return
+	}
+
+	if vh.TLS != nil || vh.AuthorizationConfigured() || vh.CORSPolicy != nil {
+		validCond.AddError(contour_api_v1.ConditionTypeVirtualHostError, "ForwardProxyIncompatibleFeatures",
+			"Spec.VirtualHost.ForwardProxy cannot be combined with TLS, authorization or a CORS policy")
+		return
+	}
+
+	if len(proxy.Spec.Routes) > 0 || len(proxy.Spec.Includes) > 0 || proxy.Spec.TCPProxy != nil {
+		validCond.AddError(contour_api_v1.ConditionTypeSpecError, "ForwardProxyIncompatibleFeatures",
+			"Spec.VirtualHost.ForwardProxy cannot be combined with Routes, Includes or a TCPProxy")
+		return
+	}
+
+	responseTimeout, err := timeout.Parse(vh.ForwardProxy.ResponseTimeout)
+	if err != nil {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "ForwardProxyNotValid",
+			"Spec.VirtualHost.ForwardProxy.ResponseTimeout is invalid: %s", err)
+		return
+	}
+
+	insecure := p.dag.EnsureVirtualHost(vh.Fqdn)
+	insecure.Disabled = insecure.Disabled || annotation.Disabled(proxy)
+	insecure.addRoute(&Route{
+		PathMatchCondition: &PrefixMatchCondition{Prefix: "/"},
+		TimeoutPolicy: TimeoutPolicy{
+			ResponseTimeout: responseTimeout,
+		},
+		ForwardProxy: &ForwardProxy{
+			DNSLookupFamily: string(p.DNSLookupFamily),
+		},
+	})
+}
+
+// computeShadowVirtualHost validates the shadow virtual host of the
+// supplied root proxy and adds it to the DAG, sharing the TLS settings
+// of its primary secure virtual host. Routes are added to the shadow
+// virtual host along with those of the primary.
+func (p *HTTPProxyProcessor) computeShadowVirtualHost(validCond *contour_api_v1.DetailedCondition, proxy *contour_api_v1.HTTPProxy, primary *SecureVirtualHost) bool {
+	shadow := proxy.Spec.VirtualHost.TLS.Shadow
+	fqdn := shadow.Fqdn
+
+	switch {
+	case isBlank(fqdn):
+		validCond.AddError(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNNotValid",
+			"Spec.VirtualHost.TLS.Shadow.Fqdn must be specified")
+		return false
+	case strings.Contains(fqdn, "*"):
+		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "WildCardNotAllowed",
+			"Spec.VirtualHost.TLS.Shadow.Fqdn %q cannot use wildcards", fqdn)
+		return false
+	case strings.EqualFold(fqdn, proxy.Spec.VirtualHost.Fqdn) || p.fqdnInUse(proxy, fqdn):
+		validCond.AddErrorf(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNConflict",
+			"Spec.VirtualHost.TLS.Shadow.Fqdn %q is already used by a virtual host", fqdn)
+		return false
+	}
+
+	secretName := k8s.NamespacedNameFrom(shadow.SecretName, k8s.DefaultNamespace(proxy.Namespace))
+	sec, err := p.source.LookupSecret(secretName, validSecret)
+	if err != nil {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "SecretNotValid",
+			"Spec.VirtualHost.TLS.Shadow Secret %q is invalid: %s", shadow.SecretName, err)
+		return false
+	}
+
+	if !p.source.DelegationPermitted(secretName, proxy.Namespace) {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "DelegationNotPermitted",
+			"Spec.VirtualHost.TLS.Shadow Secret %q certificate delegation not permitted", shadow.SecretName)
+		return false
+	}
+
+	if _, err := ocspStaplePolicy(proxy.Spec.VirtualHost.TLS.OCSPStaplePolicy, sec); err != nil {
+		validCond.AddErrorf(contour_api_v1.ConditionTypeTLSError, "OCSPStaplePolicyNotValid",
+			"Spec.VirtualHost.TLS.OCSPStaplePolicy is invalid for the Shadow Secret: %s", err)
+		return false
+	}
+
+	svhost := p.dag.EnsureSecureVirtualHost(fqdn)
+	svhost.Secret = sec
+	svhost.OCSPStaplePolicy = primary.OCSPStaplePolicy
+	svhost.HSTSPolicy = primary.HSTSPolicy
+	svhost.MinTLSVersion = primary.MinTLSVersion
+	svhost.ALPNProtocols = primary.ALPNProtocols
+	svhost.DownstreamValidation = primary.DownstreamValidation
+	svhost.AuthorizationService = primary.AuthorizationService
+	svhost.AuthorizationResponseTimeout = primary.AuthorizationResponseTimeout
+	svhost.AuthorizationFailOpen = primary.AuthorizationFailOpen
+	svhost.GRPCJSONTranscoder = primary.GRPCJSONTranscoder
+	return true
+}
+
+// grpcServicesDescribed returns an error if data is not a protobuf
+// descriptor set, or if any of the named gRPC services is missing
+// from it.
+func grpcServicesDescribed(data []byte, services []string) error {
+	if len(services) == 0 {
+		return errors.New("services must have at least one entry")
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("invalid descriptor set: %v", err)
+	}
+
+	described := map[string]bool{}
+	for _, file := range set.GetFile() {
+		for _, service := range file.GetService() {
+			name := service.GetName()
+			if pkg := file.GetPackage(); pkg != "" {
+				name = pkg + "." + name
+			}
+			described[name] = true
+		}
+	}
+
+	for _, service := range services {
+		if !described[service] {
+			return fmt.Errorf("service %q is not in the descriptor set", service)
+		}
+	}
+
+	return nil
+}
+
+// fqdnInUse returns true if the fqdn is the host of an Ingress, or
+// the virtual host or shadow virtual host name of a root HTTPProxy
+// other than the supplied proxy. Host names are compared regardless
+// of case, as Envoy matches them. Root HTTPProxies that are dropped
+// because their fqdn overlaps another virtual host are ignored.
+func (p *HTTPProxyProcessor) fqdnInUse(proxy *contour_api_v1.HTTPProxy, fqdn string) bool {
+	for _, ing := range p.source.ingresses {
+		for _, rule := range ing.Spec.Rules {
+			if strings.EqualFold(rule.Host, fqdn) {
+				return true
+			}
+		}
+		for _, tls := range ing.Spec.TLS {
+			for _, host := range tls.Hosts {
+				if strings.EqualFold(host, fqdn) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, other := range p.source.httpproxies {
+		if other == proxy || other.Spec.VirtualHost == nil {
+			continue
+		}
+		if _, ok := p.overlaps[k8s.NamespacedNameOf(other)]; ok {
+			continue
+		}
+		if strings.EqualFold(other.Spec.VirtualHost.Fqdn, fqdn) {
+			return true
+		}
+		if tls := other.Spec.VirtualHost.TLS; tls != nil && tls.Shadow != nil && strings.EqualFold(tls.Shadow.Fqdn, fqdn) {
+			return true
+		}
+	}
+	return false
+}
+
 type vhost interface {
 	addRoute(*Route)
 }
@@ -322,13 +744,15 @@ func addRoutes(vhost vhost, routes []*Route) {
 }
 
 func (p *HTTPProxyProcessor) computeRoutes(
-	validCond *contour_api_v1.DetailedCondition,
+	pa *status.ProxyUpdate,
 	rootProxy *contour_api_v1.HTTPProxy,
 	proxy *contour_api_v1.HTTPProxy,
 	conditions []contour_api_v1.MatchCondition,
 	visited []*contour_api_v1.HTTPProxy,
 	enforceTLS bool,
 ) []*Route {
+	validCond := pa.ConditionFor(status.ValidCondition)
+
 	for _, v := range visited {
 		// ensure we are not following an edge that produces a cycle
 		var path []string
@@ -343,6 +767,14 @@ func (p *HTTPProxyProcessor) computeRoutes(
 		}
 	}
 
+	// The quota of a root HTTPProxy is checked before its
+	// routes are computed, so this only applies to includes.
+	if v, ok := p.overQuota[k8s.NamespacedNameOf(proxy)]; ok {
+		validCond.AddError(v.conditionType, v.reason, v.message)
+		p.dag.recordEvent(proxy, v.reason, v.message)
+		return nil
+	}
+
 	visited = append(visited, proxy)
 	var routes []*Route
 
@@ -379,15 +811,14 @@ func (p *HTTPProxyProcessor) computeRoutes(
 		}
 
 		inc, incCommit := p.dag.StatusCache.ProxyAccessor(includedProxy)
-		incValidCond := inc.ConditionFor(status.ValidCondition)
-		routes = append(routes, p.computeRoutes(incValidCond, rootProxy, includedProxy, append(conditions, include.Conditions...), visited, enforceTLS)...)
+		routes = append(routes, p.computeRoutes(inc, rootProxy, includedProxy, append(conditions, include.Conditions...), visited, enforceTLS)...)
 		incCommit()
 
 		// dest is not an orphaned httpproxy, as there is an httpproxy that points to it
 		delete(p.orphaned, types.NamespacedName{Name: includedProxy.Name, Namespace: includedProxy.Namespace})
 	}
 
-	for _, route := range proxy.Spec.Routes {
+	for i, route := range proxy.Spec.Routes {
 		if err := pathMatchConditionsValid(route.Conditions); err != nil {
 			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "PathMatchConditionsNotValid",
 				"route: %s", err)
@@ -403,6 +834,20 @@ func (p *HTTPProxyProcessor) computeRoutes(
 			return nil
 		}
 
+		// Look for invalid query parameter conditions on this route
+		if err := queryParameterMatchConditionsValid(conds); err != nil {
+			validCond.AddError(contour_api_v1.ConditionTypeRouteError, "QueryParameterMatchConditionsNotValid",
+				err.Error())
+			return nil
+		}
+
+		// Look for invalid TLS conditions on this route
+		if err := tlsMatchConditionsValid(conds, rootProxy.Spec.VirtualHost.TLS != nil && rootProxy.Spec.VirtualHost.TLS.ClientValidation != nil); err != nil {
+			validCond.AddError(contour_api_v1.ConditionTypeRouteError, "TLSMatchConditionsNotValid",
+				err.Error())
+			return nil
+		}
+
 		reqHP, err := headersPolicyRoute(route.RequestHeadersPolicy, true /* allow Host */)
 		if err != nil {
 			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "RequestHeadersPolicyInvalid",
@@ -417,12 +862,30 @@ func (p *HTTPProxyProcessor) computeRoutes(
 			return nil
 		}
 
-		if len(route.Services) < 1 {
+		if route.OriginalDestination != nil {
+			if !p.EnableOriginalDestination {
+				validCond.AddError(contour_api_v1.ConditionTypeRouteError, "OriginalDestinationNotEnabled",
+					"route.originalDestination is not enabled in the Contour configuration")
+				return nil
+			}
+			if len(route.Services) > 0 {
+				validCond.AddError(contour_api_v1.ConditionTypeRouteError, "OriginalDestinationIncompatibleFeatures",
+					"route.originalDestination cannot be combined with route.services")
+				return nil
+			}
+		} else if len(route.Services) < 1 {
 			validCond.AddError(contour_api_v1.ConditionTypeRouteError, "NoServicesPresent",
 				"route.services must have at least one entry")
 			return nil
 		}
 
+		services := activeServices(route)
+		if len(services) < 1 && route.OriginalDestination == nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "ActiveServiceSetNotValid",
+				"route.activeServiceSet %q does not match the serviceSet of any service", route.ActiveServiceSet)
+			return nil
+		}
+
 		tp, err := timeoutPolicy(route.TimeoutPolicy)
 		if err != nil {
 			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "TimeoutPolicyNotValid",
@@ -430,17 +893,107 @@ func (p *HTTPProxyProcessor) computeRoutes(
 			return nil
 		}
 
+		rlp, err := rateLimitPolicy(route.RateLimitPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "RateLimitPolicyNotValid",
+				"route.rateLimitPolicy is invalid: %s", err)
+			return nil
+		}
+
+		// Take the default rate limit policy from the
+		// virtual host, unless this route has a policy,
+		// which may disable rate limiting altogether. The
+		// root proxy has already validated its policy.
+		if route.RateLimitPolicy == nil {
+			rlp, _ = rateLimitPolicy(rootProxy.Spec.VirtualHost.RateLimitPolicy)
+		}
+
+		rhp, err := requestHashPolicies(route.LoadBalancerPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "LoadBalancerPolicyNotValid",
+				"route.loadBalancerPolicy is invalid: %s", err)
+			return nil
+		}
+
+		rhc, err := ringHashConfig(route.LoadBalancerPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "LoadBalancerPolicyNotValid",
+				"route.loadBalancerPolicy is invalid: %s", err)
+			return nil
+		}
+
+		mc, err := maglevConfig(route.LoadBalancerPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "LoadBalancerPolicyNotValid",
+				"route.loadBalancerPolicy is invalid: %s", err)
+			return nil
+		}
+
+		if route.HealthCheckPolicy != nil && route.GRPCHealthCheckPolicy != nil {
+			validCond.AddError(contour_api_v1.ConditionTypeRouteError, "HealthCheckPolicyNotValid",
+				"route.healthCheckPolicy and route.grpcHealthCheckPolicy cannot both be specified")
+			return nil
+		}
+
+		upgrades, err := upgradeTypes(route.UpgradeTypes)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "UpgradeTypesNotValid",
+				"route.upgradeTypes is invalid: %s", err)
+			return nil
+		}
+
+		cacheTTL, err := cacheTTL(route.CachePolicy, respHP)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "CachePolicyNotValid",
+				"route.cachePolicy is invalid: %s", err)
+			return nil
+		}
+
+		rp, err := retryPolicy(route.RetryPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "RetryPolicyNotValid",
+				"route.retryPolicy is invalid: %s", err)
+			return nil
+		}
+
+		fp, err := faultPolicy(route.FaultPolicy)
+		if err != nil {
+			validCond.AddErrorf(contour_api_v1.ConditionTypeRouteError, "FaultPolicyNotValid",
+				"route.faultPolicy is invalid: %s", err)
+			return nil
+		}
+
 		r := &Route{
-			PathMatchCondition:    mergePathMatchConditions(conds),
-			HeaderMatchConditions: mergeHeaderMatchConditions(conds),
-			Websocket:             route.EnableWebsockets,
-			HTTPSUpgrade:          routeEnforceTLS(enforceTLS, route.PermitInsecure && !p.DisablePermitInsecure),
-			TimeoutPolicy:         tp,
-			RetryPolicy:           retryPolicy(route.RetryPolicy),
-			RequestHeadersPolicy:  reqHP,
-			ResponseHeadersPolicy: respHP,
+			PathMatchCondition:        mergePathMatchConditions(conds),
+			HeaderMatchConditions:     mergeHeaderMatchConditions(conds),
+			QueryParamMatchConditions: mergeQueryParamMatchConditions(conds),
+			TLSMatchCondition:         mergeTLSMatchConditions(conds),
+			Websocket:                 route.EnableWebsockets,
+			UpgradeTypes:              upgrades,
+			HTTPSUpgrade:              routeEnforceTLS(enforceTLS, route.PermitInsecure && !p.DisablePermitInsecure),
+			TimeoutPolicy:             tp,
+			RetryPolicy:               rp,
+			RequestHeadersPolicy:      reqHP,
+			ResponseHeadersPolicy:     respHP,
+			RateLimitPolicy:           rlp,
+			RequestHashPolicies:       rhp,
+			FaultPolicy:               fp,
 		}
 
+		if d, ok := p.NamespaceDefaults[proxy.Namespace]; ok {
+			d.apply(r)
+		}
+
+		if route.OriginalDestination != nil {
+			r.OriginalDestination = &OriginalDestination{}
+		}
+
+		if route.RequestBodyPolicy != nil {
+			r.MaxRequestBodyBytes = route.RequestBodyPolicy.MaxRequestBytes
+		}
+
+		r.CacheTTL = cacheTTL
+
 		// If the enclosing root proxy enabled authorization,
 		// enable it on the route and propagate defaults
 		// downwards.
@@ -501,18 +1054,34 @@ func (p *HTTPProxyProcessor) computeRoutes(
 
 		}
 
-		for _, service := range route.Services {
+		for _, service := range services {
 			if service.Port < 1 || service.Port > 65535 {
 				validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "ServicePortInvalid",
 					"service %q: port must be in the range 1-65535", service.Name)
 				return nil
 			}
 			m := types.NamespacedName{Name: service.Name, Namespace: proxy.Namespace}
-			s, err := p.dag.EnsureService(m, intstr.FromInt(service.Port), p.source)
-			if err != nil {
-				validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "ServiceUnresolvedReference",
-					"Spec.Routes unresolved service reference: %s", err)
-				return nil
+
+			var s *Service
+			if service.External != nil {
+				if !p.EnableExternalBackends {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "ExternalBackendsDisabled",
+						"service %q: external backends are not enabled", service.Name)
+					return nil
+				}
+				if err := externalBackendValid(service.External); err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "ExternalBackendNotValid",
+						"service %q: %s", service.Name, err)
+					return nil
+				}
+				s = ExternalService(m, service.External.Address, int32(service.Port))
+			} else {
+				s, err = p.dag.EnsureService(m, intstr.FromInt(service.Port), p.source)
+				if err != nil {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "ServiceUnresolvedReference",
+						"Spec.Routes unresolved service reference: %s", err)
+					return nil
+				}
 			}
 
 			// Determine the protocol to use to speak to this Cluster.
@@ -522,6 +1091,12 @@ func (p *HTTPProxyProcessor) computeRoutes(
 				return nil
 			}
 
+			if route.GRPCHealthCheckPolicy != nil && protocol != "h2" && protocol != "h2c" {
+				validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "GRPCHealthCheckNotValid",
+					"service %q: gRPC health checks require the h2 or h2c protocol", service.Name)
+				return nil
+			}
+
 			var uv *PeerValidationContext
 			if protocol == "tls" || protocol == "h2" {
 				// we can only validate TLS connections to services that talk TLS
@@ -557,18 +1132,59 @@ func (p *HTTPProxyProcessor) computeRoutes(
 				}
 			}
 
+			sni := determineSNI(r.RequestHeadersPolicy, reqHP, s)
+
+			var upstreamTLS *UpstreamTLS
+			if ut := service.UpstreamTLS; ut != nil {
+				if protocol != "tls" && protocol != "h2" {
+					validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "UpstreamTLSNotValid",
+						"service %q: upstreamTLS requires the tls or h2 protocol", service.Name)
+					return nil
+				}
+
+				upstreamTLS = &UpstreamTLS{SNI: ut.SNI}
+				if ut.SNI != "" {
+					sni = ut.SNI
+				}
+
+				if ut.ClientCertificate != "" {
+					secretName := k8s.NamespacedNameFrom(ut.ClientCertificate, k8s.DefaultNamespace(proxy.Namespace))
+					sec, err := p.source.LookupSecret(secretName, validSecret)
+					if err != nil {
+						validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "SecretNotValid",
+							"service %q: upstreamTLS client certificate Secret %q is invalid: %s", service.Name, secretName, err)
+						return nil
+					}
+
+					if !p.source.DelegationPermitted(secretName, proxy.Namespace) {
+						validCond.AddErrorf(contour_api_v1.ConditionTypeServiceError, "DelegationNotPermitted",
+							"service %q: upstreamTLS client certificate Secret %q is not configured for certificate delegation", service.Name, secretName)
+						return nil
+					}
+
+					upstreamTLS.ClientCertificate = sec
+					clientCertSecret = sec
+				}
+			}
+
 			c := &Cluster{
 				Upstream:              s,
 				LoadBalancerPolicy:    loadBalancerPolicy(route.LoadBalancerPolicy),
+				RingHashConfig:        rhc,
+				MaglevConfig:          mc,
 				Weight:                uint32(service.Weight),
 				HTTPHealthCheckPolicy: httpHealthCheckPolicy(route.HealthCheckPolicy),
+				GRPCHealthCheckPolicy: grpcHealthCheckPolicy(route.GRPCHealthCheckPolicy),
 				UpstreamValidation:    uv,
 				RequestHeadersPolicy:  reqHP,
 				ResponseHeadersPolicy: respHP,
 				Protocol:              protocol,
-				SNI:                   determineSNI(r.RequestHeadersPolicy, reqHP, s),
+				SNI:                   sni,
 				DNSLookupFamily:       string(p.DNSLookupFamily),
+				DNSResolvers:          p.DNSResolvers,
 				ClientCertificate:     clientCertSecret,
+				UpstreamTLS:           upstreamTLS,
+				RetryBudget:           retryBudget(route.RetryPolicy, p.RetryBudget),
 			}
 			if service.Mirror && r.MirrorPolicy != nil {
 				validCond.AddError(contour_api_v1.ConditionTypeServiceError, "OnlyOneMirror",
@@ -583,6 +1199,25 @@ func (p *HTTPProxyProcessor) computeRoutes(
 				r.Clusters = append(r.Clusters, c)
 			}
 		}
+
+		// Follow the Service's ClientIP session affinity, unless
+		// the route chooses a load balancer policy of its own.
+		if route.LoadBalancerPolicy == nil && clientIPAffinity(r.Clusters) {
+			r.RequestHashPolicies = []RequestHashPolicy{{HashSourceIP: true}}
+		}
+
+		if route.ActiveServiceSet != "" {
+			set := contour_api_v1.ServiceSetStatus{
+				Route:    i,
+				Active:   route.ActiveServiceSet,
+				Services: []string{},
+			}
+			for _, service := range services {
+				set.Services = append(set.Services, service.Name)
+			}
+			pa.AddServiceSet(set)
+		}
+
 		routes = append(routes, r)
 	}
 
@@ -591,6 +1226,23 @@ func (p *HTTPProxyProcessor) computeRoutes(
 	return routes
 }
 
+// activeServices returns the services of the route that receive
+// traffic. If the route has an active service set, these are the
+// services in that set, otherwise they are all of its services.
+func activeServices(route contour_api_v1.Route) []contour_api_v1.Service {
+	if route.ActiveServiceSet == "" {
+		return route.Services
+	}
+
+	var services []contour_api_v1.Service
+	for _, service := range route.Services {
+		if service.ServiceSet == route.ActiveServiceSet {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
 // processHTTPProxyTCPProxy processes the spec.tcpproxy stanza in a HTTPProxy document
 // following the chain of spec.tcpproxy.include references. It returns true if processing
 // was successful, otherwise false if an error was encountered. The details of the error
@@ -634,6 +1286,9 @@ func (p *HTTPProxyProcessor) processHTTPProxyTCPProxy(validCond *contour_api_v1.
 				TCPHealthCheckPolicy: tcpHealthCheckPolicy(tcpproxy.HealthCheckPolicy),
 			})
 		}
+		if tcpproxy.LoadBalancerPolicy == nil {
+			proxy.HashSourceIP = clientIPAffinity(proxy.Clusters)
+		}
 		secure := p.dag.EnsureSecureVirtualHost(host)
 		secure.TCPProxy = &proxy
 
@@ -705,6 +1360,20 @@ func (p *HTTPProxyProcessor) validHTTPProxies() []*contour_api_v1.HTTPProxy {
 			valid = append(valid, proxy)
 			continue
 		}
+		if !k8s.MatchesDomainSuffix(proxy.Spec.VirtualHost.Fqdn, p.DomainSuffixes) {
+			p.setForeign(proxy)
+			continue
+		}
+		if msg, ok := p.overlaps[k8s.NamespacedNameOf(proxy)]; ok {
+			pa, commit := p.dag.StatusCache.ProxyAccessor(proxy)
+			pa.Vhost = proxy.Spec.VirtualHost.Fqdn
+			pa.ConditionFor(status.ValidCondition).AddError(contour_api_v1.ConditionTypeVirtualHostError,
+				"OverlappingVhost",
+				msg)
+			commit()
+			p.dag.recordEvent(proxy, "OverlappingVhost", msg)
+			continue
+		}
 		fqdnHTTPProxies[proxy.Spec.VirtualHost.Fqdn] = append(fqdnHTTPProxies[proxy.Spec.VirtualHost.Fqdn], proxy)
 	}
 
@@ -733,6 +1402,175 @@ func (p *HTTPProxyProcessor) validHTTPProxies() []*contour_api_v1.HTTPProxy {
 	return valid
 }
 
+// overlappingFqdns returns a message for each HTTPProxy whose fqdn
+// differs from the host of an Ingress, or from the fqdn of an older
+// HTTPProxy, but shares an Envoy virtual host domain with it. Envoy
+// matches domains regardless of case, and rejects the whole route
+// configuration if two of its virtual hosts share a domain, so the
+// newer HTTPProxy is rejected instead. Ingress hosts are always in
+// lower case, so an HTTPProxy never takes precedence over an Ingress.
+// HTTPProxies with the same fqdn are left to validHTTPProxies.
+//
+// The shadow fqdn of an older HTTPProxy also claims its domains, so
+// a newer HTTPProxy with the same fqdn is rejected. A shadow fqdn
+// that is already claimed is rejected by computeShadowVirtualHost.
+func overlappingFqdns(source *KubernetesCache) map[types.NamespacedName]string {
+	type claim struct {
+		fqdn   string
+		owner  string
+		shadow bool
+	}
+
+	claims := map[string]claim{}
+	for _, ing := range source.ingresses {
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == "" {
+				continue
+			}
+			for _, d := range envoyDomains(rule.Host) {
+				if _, ok := claims[d]; !ok {
+					claims[d] = claim{fqdn: rule.Host, owner: "Ingress " + ing.Namespace + "/" + ing.Name}
+				}
+			}
+		}
+	}
+
+	overlaps := map[types.NamespacedName]string{}
+	for _, proxy := range byCreation(source.httpproxies) {
+		if proxy.Spec.VirtualHost == nil {
+			continue
+		}
+
+		fqdn := proxy.Spec.VirtualHost.Fqdn
+		domains := envoyDomains(fqdn)
+
+		for _, d := range domains {
+			c, ok := claims[d]
+			if !ok || (c.fqdn == fqdn && !c.shadow) {
+				continue
+			}
+			kind := "fqdn"
+			if c.shadow {
+				kind = "shadow fqdn"
+			}
+			overlaps[k8s.NamespacedNameOf(proxy)] = fmt.Sprintf("fqdn %q overlaps with %s %q of %s", fqdn, kind, c.fqdn, c.owner)
+			break
+		}
+
+		if _, ok := overlaps[k8s.NamespacedNameOf(proxy)]; ok {
+			continue
+		}
+
+		owner := "HTTPProxy " + proxy.Namespace + "/" + proxy.Name
+		for _, d := range domains {
+			if _, ok := claims[d]; !ok {
+				claims[d] = claim{fqdn: fqdn, owner: owner}
+			}
+		}
+
+		if tls := proxy.Spec.VirtualHost.TLS; tls != nil && tls.Shadow != nil && !isBlank(tls.Shadow.Fqdn) && !strings.Contains(tls.Shadow.Fqdn, "*") {
+			for _, d := range envoyDomains(tls.Shadow.Fqdn) {
+				if _, ok := claims[d]; !ok {
+					claims[d] = claim{fqdn: tls.Shadow.Fqdn, owner: owner, shadow: true}
+				}
+			}
+		}
+	}
+
+	return overlaps
+}
+
+// coalescingHosts returns, for each root HTTPProxy that terminates
+// TLS for HTTP, the hosts of the other TLS virtual hosts that its
+// certificate also covers, such as with a shared wildcard
+// certificate. HTTP/2 clients may reuse a connection for any host
+// the certificate covers, but each virtual host has its own filter
+// chain, so requests for the other hosts are misdirected.
+func (p *HTTPProxyProcessor) coalescingHosts() map[types.NamespacedName][]string {
+	terminatesHTTP := func(proxy *contour_api_v1.HTTPProxy) bool {
+		vh := proxy.Spec.VirtualHost
+		return vh != nil && vh.TLS != nil && !isBlank(vh.TLS.SecretName) &&
+			!vh.TLS.Passthrough && proxy.Spec.TCPProxy == nil
+	}
+
+	hosts := map[string]bool{}
+	for _, ing := range p.source.ingresses {
+		for _, tls := range ing.Spec.TLS {
+			for _, host := range tls.Hosts {
+				hosts[strings.ToLower(host)] = true
+			}
+		}
+	}
+	for _, proxy := range p.source.httpproxies {
+		if terminatesHTTP(proxy) {
+			hosts[strings.ToLower(proxy.Spec.VirtualHost.Fqdn)] = true
+		}
+	}
+
+	coalescing := map[types.NamespacedName][]string{}
+	for _, proxy := range p.source.httpproxies {
+		if !terminatesHTTP(proxy) {
+			continue
+		}
+
+		secretName := k8s.NamespacedNameFrom(proxy.Spec.VirtualHost.TLS.SecretName, k8s.DefaultNamespace(proxy.Namespace))
+		sec, err := p.source.LookupSecret(secretName, validSecret)
+		if err != nil {
+			continue
+		}
+
+		names := certificateNames(sec.Cert())
+		fqdn := strings.ToLower(proxy.Spec.VirtualHost.Fqdn)
+
+		var covered []string
+		for host := range hosts {
+			if host != fqdn && certificateCovers(names, host) {
+				covered = append(covered, host)
+			}
+		}
+
+		if len(covered) > 0 {
+			sort.Strings(covered)
+			coalescing[k8s.NamespacedNameOf(proxy)] = covered
+		}
+	}
+
+	return coalescing
+}
+
+// envoyDomains returns the domains, in lower case, of the Envoy
+// virtual host for fqdn. See envoy_v3.VirtualHost.
+func envoyDomains(fqdn string) []string {
+	domain := strings.ToLower(fqdn)
+	if strings.HasPrefix(domain, "*") {
+		return []string{domain}
+	}
+	return []string{domain, domain + ":*"}
+}
+
+// byCreation returns the HTTPProxies ordered by creation time,
+// oldest first. HTTPProxies created at the same time are ordered
+// by namespace and name.
+func byCreation(httpproxies map[types.NamespacedName]*contour_api_v1.HTTPProxy) []*contour_api_v1.HTTPProxy {
+	proxies := make([]*contour_api_v1.HTTPProxy, 0, len(httpproxies))
+	for _, proxy := range httpproxies {
+		proxies = append(proxies, proxy)
+	}
+
+	sort.Slice(proxies, func(i, j int) bool {
+		a, b := proxies[i], proxies[j]
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return proxies
+}
+
 // rootAllowed returns true if the HTTPProxy lives in a permitted root namespace.
 func (p *HTTPProxyProcessor) rootAllowed(namespace string) bool {
 	if len(p.source.RootNamespaces) == 0 {
@@ -755,6 +1593,37 @@ func (p *HTTPProxyProcessor) setOrphaned(obj k8s.Object) {
 	p.orphaned[m] = true
 }
 
+// setForeign records that proxy, and the HTTPProxies it includes,
+// belong to the virtual host of another Contour instance.
+func (p *HTTPProxyProcessor) setForeign(proxy *contour_api_v1.HTTPProxy) {
+	m := k8s.NamespacedNameOf(proxy)
+	if p.foreign[m] {
+		return
+	}
+	p.foreign[m] = true
+
+	include := func(name, namespace string) {
+		if namespace == "" {
+			namespace = proxy.Namespace
+		}
+		if included, ok := p.source.httpproxies[types.NamespacedName{Name: name, Namespace: namespace}]; ok {
+			p.setForeign(included)
+		}
+	}
+
+	for _, inc := range proxy.Spec.Includes {
+		include(inc.Name, inc.Namespace)
+	}
+	if tcpproxy := proxy.Spec.TCPProxy; tcpproxy != nil {
+		if tcpproxy.Include != nil {
+			include(tcpproxy.Include.Name, tcpproxy.Include.Namespace)
+		}
+		if tcpproxy.IncludesDeprecated != nil {
+			include(tcpproxy.IncludesDeprecated.Name, tcpproxy.IncludesDeprecated.Namespace)
+		}
+	}
+}
+
 // expandPrefixMatches adds new Routes to account for the difference
 // between prefix replacement when matching on '/foo' and '/foo/'.
 //
@@ -846,6 +1715,20 @@ func expandPrefixMatches(routes []*Route) []*Route {
 	return expandedRoutes
 }
 
+// externalBackendValid returns an error if the address of the
+// external backend is neither an IP address nor a DNS name.
+func externalBackendValid(backend *contour_api_v1.ExternalBackend) error {
+	if net.ParseIP(backend.Address) != nil {
+		return nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(backend.Address); len(errs) > 0 {
+		return fmt.Errorf("invalid address %q: must be an IP address or a DNS name", backend.Address)
+	}
+
+	return nil
+}
+
 func getProtocol(service contour_api_v1.Service, s *Service) (string, error) {
 	// Determine the protocol to use to speak to this Cluster.
 	var protocol string
@@ -920,7 +1803,9 @@ func includeMatchConditionsIdentical(includes []contour_api_v1.Include) bool {
 		// Now compare each include's set of conditions
 		for _, cA := range includes[i].Conditions {
 			for _, cB := range includes[j].Conditions {
-				if (cA.Prefix == cB.Prefix) && equality.Semantic.DeepEqual(cA.Header, cB.Header) {
+				if (cA.Prefix == cB.Prefix) &&
+					equality.Semantic.DeepEqual(cA.Header, cB.Header) &&
+					equality.Semantic.DeepEqual(cA.QueryParameter, cB.QueryParameter) {
 					return true
 				}
 			}
@@ -14,15 +14,50 @@
 package dag
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/projectcontour/contour/internal/annotation"
 	"github.com/projectcontour/contour/internal/k8s"
+	"github.com/projectcontour/contour/pkg/config"
 	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// IngressBackendResolver resolves Ingress backends that refer to
+// a resource other than a Service.
+type IngressBackendResolver interface {
+	// Resolve returns the Service that receives the traffic for
+	// the resource, which is in the supplied namespace.
+	Resolve(dag *DAG, source *KubernetesCache, namespace string, resource *v1.TypedLocalObjectReference) (*Service, error)
+}
+
+// IngressBackendResolverFunc adapts a function to the IngressBackendResolver interface.
+type IngressBackendResolverFunc func(*DAG, *KubernetesCache, string, *v1.TypedLocalObjectReference) (*Service, error)
+
+func (f IngressBackendResolverFunc) Resolve(dag *DAG, source *KubernetesCache, namespace string, resource *v1.TypedLocalObjectReference) (*Service, error) {
+	return f(dag, source, namespace, resource)
+}
+
+// ServiceBackendResolver resolves backends that refer to a Service
+// as a resource. As the reference has no port, the Service must
+// have exactly one port.
+var ServiceBackendResolver = IngressBackendResolverFunc(func(dag *DAG, source *KubernetesCache, namespace string, resource *v1.TypedLocalObjectReference) (*Service, error) {
+	m := types.NamespacedName{Name: resource.Name, Namespace: namespace}
+	svc, ok := source.services[m]
+	if !ok {
+		return nil, fmt.Errorf("service %q not found", m)
+	}
+	if len(svc.Spec.Ports) != 1 {
+		return nil, fmt.Errorf("service %q must have exactly one port to be used as a backend resource", m)
+	}
+	return dag.EnsureService(m, intstr.FromInt(int(svc.Spec.Ports[0].Port)), source)
+})
+
 // IngressProcessor translates Ingresses into DAG
 // objects and adds them to the DAG.
 type IngressProcessor struct {
@@ -31,9 +66,56 @@ type IngressProcessor struct {
 	dag    *DAG
 	source *KubernetesCache
 
+	// overQuota holds the Ingresses that exceed
+	// the quota of their namespace.
+	overQuota map[types.NamespacedName]quotaViolation
+
 	// ClientCertificate is the optional identifier of the TLS secret containing client certificate and
 	// private key to be used when establishing TLS connection to upstream cluster.
 	ClientCertificate *types.NamespacedName
+
+	// HSTSPolicy is the optional Strict-Transport-Security
+	// policy applied to secure virtual hosts.
+	HSTSPolicy *HSTSPolicy
+
+	// RetryBudget is the optional retry budget applied
+	// to Ingress clusters.
+	RetryBudget *RetryBudget
+
+	// DNSLookupFamily defines how external names are
+	// looked up. See HTTPProxyProcessor.DNSLookupFamily.
+	DNSLookupFamily config.ClusterDNSFamilyType
+
+	// DNSResolvers are the optional addresses of the DNS
+	// servers that resolve external names.
+	DNSResolvers []string
+
+	// NginxAnnotations enables the translation of nginx-ingress
+	// annotations to their Contour equivalents.
+	NginxAnnotations bool
+
+	// BackendResolvers resolve backends that refer to a resource
+	// instead of a Service, keyed by the resource's group and kind.
+	// Ingresses with backends of any other kind are rejected.
+	BackendResolvers map[schema.GroupKind]IngressBackendResolver
+
+	// NamespaceDefaults holds the policies applied to the
+	// routes of each listed namespace that do not set
+	// their own. See HTTPProxyProcessor.NamespaceDefaults.
+	NamespaceDefaults map[string]*RouteDefaults
+
+	// DomainSuffixes, if not empty, restricts the processor to
+	// the Ingress hosts that are equal to, or a subdomain of,
+	// one of the suffixes. Other hosts are left to the Contour
+	// instance that owns them.
+	DomainSuffixes []string
+
+	// NamespaceQuota is the quota of the namespaces that are not
+	// listed in NamespaceQuotas. See HTTPProxyProcessor.NamespaceQuota.
+	NamespaceQuota NamespaceQuota
+
+	// NamespaceQuotas holds the quota of each listed namespace.
+	NamespaceQuotas map[string]NamespaceQuota
 }
 
 // Run translates Ingresses into DAG objects and
@@ -41,13 +123,22 @@ type IngressProcessor struct {
 func (p *IngressProcessor) Run(dag *DAG, source *KubernetesCache) {
 	p.dag = dag
 	p.source = source
+	p.overQuota = map[types.NamespacedName]quotaViolation{}
+	for obj, v := range quotaViolations(source, p.NamespaceQuota, p.NamespaceQuotas) {
+		if obj.kind == "Ingress" {
+			p.overQuota[obj.name] = v
+		}
+	}
 
 	// reset the processor when we're done
 	defer func() {
 		p.dag = nil
 		p.source = nil
+		p.overQuota = nil
 	}()
 
+	p.rejectOverQuota()
+
 	// setup secure vhosts if there is a matching secret
 	// we do this first so that the set of active secure vhosts is stable
 	// during computeIngresses.
@@ -55,11 +146,34 @@ func (p *IngressProcessor) Run(dag *DAG, source *KubernetesCache) {
 	p.computeIngresses()
 }
 
+// rejectOverQuota rejects the Ingresses that exceed
+// the quota of their namespace.
+func (p *IngressProcessor) rejectOverQuota() {
+	for _, ing := range p.source.ingresses {
+		v, ok := p.overQuota[k8s.NamespacedNameOf(ing)]
+		if !ok || !p.ownsAnyHost(ingressHosts(ing)) {
+			continue
+		}
+		p.WithField("name", ing.GetName()).
+			WithField("namespace", ing.GetNamespace()).
+			Error(v.message)
+		p.dag.rejectObject(ing, v.reason)
+		p.dag.recordEvent(ing, v.reason, v.message)
+	}
+}
+
 // computeSecureVirtualhosts populates tls parameters of
 // secure virtual hosts.
 func (p *IngressProcessor) computeSecureVirtualhosts() {
 	for _, ing := range p.source.ingresses {
+		if _, ok := p.overQuota[k8s.NamespacedNameOf(ing)]; ok {
+			continue
+		}
 		for _, tls := range ing.Spec.TLS {
+			if !p.ownsAnyHost(tls.Hosts) {
+				continue
+			}
+
 			secretName := k8s.NamespacedNameFrom(tls.SecretName, k8s.DefaultNamespace(ing.GetNamespace()))
 			sec, err := p.source.LookupSecret(secretName, validSecret)
 			if err != nil {
@@ -68,6 +182,7 @@ func (p *IngressProcessor) computeSecureVirtualhosts() {
 					WithField("namespace", ing.GetNamespace()).
 					WithField("secret", secretName).
 					Error("unresolved secret reference")
+				p.dag.rejectObject(ing, "SecretNotValid")
 				continue
 			}
 
@@ -77,6 +192,7 @@ func (p *IngressProcessor) computeSecureVirtualhosts() {
 					WithField("namespace", ing.GetNamespace()).
 					WithField("secret", secretName).
 					Error("certificate delegation not permitted")
+				p.dag.rejectObject(ing, "DelegationNotPermitted")
 				continue
 			}
 
@@ -84,8 +200,13 @@ func (p *IngressProcessor) computeSecureVirtualhosts() {
 			// ahead and create the SecureVirtualHost for this
 			// Ingress.
 			for _, host := range tls.Hosts {
+				if !k8s.MatchesDomainSuffix(host, p.DomainSuffixes) {
+					continue
+				}
+
 				svhost := p.dag.EnsureSecureVirtualHost(host)
 				svhost.Secret = sec
+				svhost.HSTSPolicy = p.HSTSPolicy
 				// default to a minimum TLS version of 1.2 if it's not specified
 				svhost.MinTLSVersion = annotation.MinTLSVersion(annotation.ContourAnnotation(ing, "tls-minimum-protocol-version"), "1.2")
 			}
@@ -96,19 +217,60 @@ func (p *IngressProcessor) computeSecureVirtualhosts() {
 func (p *IngressProcessor) computeIngresses() {
 	// deconstruct each ingress into routes and virtualhost entries
 	for _, ing := range p.source.ingresses {
+		if _, ok := p.overQuota[k8s.NamespacedNameOf(ing)]; ok {
+			continue
+		}
 
 		// rewrite the default ingress to a stock ingress rule.
 		rules := rulesFromSpec(ing.Spec)
 		for _, rule := range rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			if !k8s.MatchesDomainSuffix(host, p.DomainSuffixes) {
+				continue
+			}
+
 			p.computeIngressRule(ing, rule)
 		}
 	}
 }
 
+// ingressHosts returns the hosts of the rules of ing, using "*"
+// for the rules that do not set one.
+func ingressHosts(ing *v1beta1.Ingress) []string {
+	var hosts []string
+	for _, rule := range rulesFromSpec(ing.Spec) {
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// ownsAnyHost returns true if any of hosts matches the
+// domain suffixes of the processor.
+func (p *IngressProcessor) ownsAnyHost(hosts []string) bool {
+	for _, host := range hosts {
+		if k8s.MatchesDomainSuffix(host, p.DomainSuffixes) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *IngressProcessor) computeIngressRule(ing *v1beta1.Ingress, rule v1beta1.IngressRule) {
 	host := rule.Host
 	if strings.Contains(host, "*") {
 		// reject hosts with wildcard characters.
+		p.WithField("name", ing.GetName()).
+			WithField("namespace", ing.GetNamespace()).
+			WithField("host", host).
+			Error("wildcard hosts are not supported")
+		p.dag.rejectObject(ing, "WildCardNotAllowed")
 		return
 	}
 	if host == "" {
@@ -126,32 +288,84 @@ func (p *IngressProcessor) computeIngressRule(ing *v1beta1.Ingress, rule v1beta1
 				WithField("namespace", ing.GetNamespace()).
 				WithField("secret", p.ClientCertificate).
 				Error("tls.envoy-client-certificate contains unresolved secret reference")
+			p.dag.rejectObject(ing, "SecretNotValid")
 			return
 		}
 	}
 
+	sourceRanges, err := annotation.WhitelistSourceRange(ing)
+	if err != nil {
+		p.WithError(err).
+			WithField("name", ing.GetName()).
+			WithField("namespace", ing.GetNamespace()).
+			Error("ingress.kubernetes.io/whitelist-source-range is not valid")
+		p.dag.rejectObject(ing, "SourceRangeNotValid")
+		return
+	}
+
 	for _, httppath := range httppaths(rule) {
 		path := stringOrDefault(httppath.Path, "/")
 		be := httppath.Backend
-		m := types.NamespacedName{Name: be.ServiceName, Namespace: ing.Namespace}
-		s, err := p.dag.EnsureService(m, be.ServicePort, p.source)
+		if be.Resource != nil && p.backendResolver(be.Resource) == nil {
+			p.WithField("name", ing.GetName()).
+				WithField("namespace", ing.GetNamespace()).
+				WithField("kind", be.Resource.Kind).
+				WithField("resource", be.Resource.Name).
+				Error("backend resource kind is not supported")
+			p.dag.rejectObject(ing, "BackendResourceNotSupported")
+			p.dag.recordEvent(ing, "BackendResourceNotSupported",
+				fmt.Sprintf("backend resource %s %q is not supported", be.Resource.Kind, be.Resource.Name))
+			continue
+		}
+
+		s, err := p.backendService(ing, be)
 		if err != nil {
+			p.WithError(err).
+				WithField("name", ing.GetName()).
+				WithField("namespace", ing.GetNamespace()).
+				Error("unresolved service reference")
+			p.dag.rejectObject(ing, "ServiceUnresolvedReference")
+			if be.Resource != nil {
+				p.dag.recordEvent(ing, "ServiceUnresolvedReference", err.Error())
+			}
 			continue
 		}
 
-		r, err := route(ing, path, s, clientCertSecret, p.FieldLogger)
+		r, err := route(ing, path, httppath.PathType, s, clientCertSecret, p.FieldLogger)
 		if err != nil {
 			p.WithError(err).
 				WithField("name", ing.GetName()).
 				WithField("namespace", ing.GetNamespace()).
 				WithField("regex", path).
 				Errorf("path regex is not valid")
+			p.dag.rejectObject(ing, "PathMatchConditionsNotValid")
 			return
 		}
 
+		for _, c := range r.Clusters {
+			c.RetryBudget = p.RetryBudget
+			c.DNSLookupFamily = string(p.DNSLookupFamily)
+			c.DNSResolvers = p.DNSResolvers
+		}
+
+		r.AllowedSourceRanges = sourceRanges
+
+		if p.NginxAnnotations {
+			p.applyNginxAnnotations(ing, r)
+		}
+
+		if d, ok := p.NamespaceDefaults[ing.Namespace]; ok {
+			d.apply(r)
+		}
+
+		if len(r.RequestHashPolicies) == 0 && clientIPAffinity(r.Clusters) {
+			r.RequestHashPolicies = []RequestHashPolicy{{HashSourceIP: true}}
+		}
+
 		// should we create port 80 routes for this ingress
 		if annotation.TLSRequired(ing) || annotation.HTTPAllowed(ing) {
 			vhost := p.dag.EnsureVirtualHost(host)
+			vhost.Disabled = vhost.Disabled || annotation.Disabled(ing)
 			vhost.addRoute(r)
 		}
 
@@ -159,13 +373,36 @@ func (p *IngressProcessor) computeIngressRule(ing *v1beta1.Ingress, rule v1beta1
 		// with the names of tls enabled ingress objects. If host exists then
 		// it is correctly configured for TLS.
 		if svh := p.dag.GetSecureVirtualHost(host); svh != nil && host != "*" {
+			svh.Disabled = svh.Disabled || annotation.Disabled(ing)
 			svh.addRoute(r)
 		}
 	}
 }
 
+// backendResolver returns the resolver for backends that refer
+// to the supplied resource, or nil if there is none.
+func (p *IngressProcessor) backendResolver(resource *v1.TypedLocalObjectReference) IngressBackendResolver {
+	gk := schema.GroupKind{Kind: resource.Kind}
+	if resource.APIGroup != nil {
+		gk.Group = *resource.APIGroup
+	}
+
+	return p.BackendResolvers[gk]
+}
+
+// backendService returns the Service that traffic to the
+// backend is sent to.
+func (p *IngressProcessor) backendService(ing *v1beta1.Ingress, be v1beta1.IngressBackend) (*Service, error) {
+	if be.Resource != nil {
+		return p.backendResolver(be.Resource).Resolve(p.dag, p.source, ing.Namespace, be.Resource)
+	}
+
+	m := types.NamespacedName{Name: be.ServiceName, Namespace: ing.Namespace}
+	return p.dag.EnsureService(m, be.ServicePort, p.source)
+}
+
 // route builds a dag.Route for the supplied Ingress.
-func route(ingress *v1beta1.Ingress, path string, service *Service, clientCertSecret *Secret, log logrus.FieldLogger) (*Route, error) {
+func route(ingress *v1beta1.Ingress, path string, pathType *v1beta1.PathType, service *Service, clientCertSecret *Secret, log logrus.FieldLogger) (*Route, error) {
 	log = log.WithFields(logrus.Fields{
 		"name":      ingress.Name,
 		"namespace": ingress.Namespace,
@@ -183,6 +420,27 @@ func route(ingress *v1beta1.Ingress, path string, service *Service, clientCertSe
 		}},
 	}
 
+	if pathType != nil {
+		switch *pathType {
+		case v1beta1.PathTypeExact:
+			r.PathMatchCondition = &ExactMatchCondition{Path: path}
+			return r, nil
+		case v1beta1.PathTypePrefix:
+			// The Kubernetes API server validates that the path is
+			// absolute. A trailing slash is ignored, so that /foo/
+			// matches /foo, and the root path matches every path.
+			prefix := strings.TrimRight(path, "/")
+			if prefix == "" {
+				r.PathMatchCondition = &PrefixMatchCondition{Prefix: "/"}
+			} else {
+				r.PathMatchCondition = &PrefixMatchCondition{Prefix: prefix, PrefixMatchType: PrefixMatchSegment}
+			}
+			return r, nil
+		}
+	}
+
+	// ImplementationSpecific paths, and paths without a path type,
+	// are string prefixes, unless they contain regex characters.
 	if strings.ContainsAny(path, "^+*[]%") {
 		// validate the regex
 		if err := ValidateRegex(path); err != nil {
@@ -197,6 +455,62 @@ func route(ingress *v1beta1.Ingress, path string, service *Service, clientCertSe
 	return r, nil
 }
 
+// applyNginxAnnotations translates the nginx-ingress annotations
+// of the Ingress that Contour supports into settings on r. Invalid
+// annotation values are logged and ignored.
+func (p *IngressProcessor) applyNginxAnnotations(ing *v1beta1.Ingress, r *Route) {
+	log := p.WithField("name", ing.GetName()).
+		WithField("namespace", ing.GetNamespace())
+
+	if annotation.NginxSSLRedirect(ing) {
+		r.HTTPSUpgrade = true
+	}
+
+	if target := annotation.NginxAnnotation(ing, "rewrite-target"); target != "" {
+		if strings.Contains(target, "$") {
+			log.WithField("rewrite-target", target).
+				Warn("nginx.ingress.kubernetes.io/rewrite-target capture groups are not supported")
+		} else {
+			r.PrefixRewrite = target
+		}
+	}
+
+	size, err := annotation.NginxProxyBodySize(ing)
+	if err != nil {
+		log.WithError(err).Warn("nginx.ingress.kubernetes.io/proxy-body-size is not valid")
+	} else {
+		r.MaxRequestBodyBytes = size
+	}
+
+	switch affinity := annotation.NginxAnnotation(ing, "affinity"); affinity {
+	case "":
+	case "cookie":
+		for _, c := range r.Clusters {
+			c.LoadBalancerPolicy = "Cookie"
+		}
+	default:
+		log.WithField("affinity", affinity).
+			Warn("nginx.ingress.kubernetes.io/affinity is not supported")
+	}
+
+	header, err := annotation.NginxUpstreamHashByHeader(ing)
+	switch {
+	case err != nil:
+		log.WithError(err).Warn("nginx.ingress.kubernetes.io/upstream-hash-by is not valid")
+	case header != "":
+		r.RequestHashPolicies = []RequestHashPolicy{{
+			HeaderHashOptions: &HeaderHashOptions{
+				HeaderName: header,
+			},
+		}}
+		for _, c := range r.Clusters {
+			if c.LoadBalancerPolicy == "" {
+				c.LoadBalancerPolicy = "RequestHash"
+			}
+		}
+	}
+}
+
 // rulesFromSpec merges the IngressSpec's Rules with a synthetic
 // rule representing the default backend.
 func rulesFromSpec(spec v1beta1.IngressSpec) []v1beta1.IngressRule {
@@ -217,6 +531,7 @@ func defaultBackendRule(be *v1beta1.IngressBackend) v1beta1.IngressRule {
 					Backend: v1beta1.IngressBackend{
 						ServiceName: be.ServiceName,
 						ServicePort: be.ServicePort,
+						Resource:    be.Resource,
 					},
 				}},
 			},
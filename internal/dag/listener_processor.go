@@ -13,26 +13,39 @@
 
 package dag
 
-import "sort"
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+)
 
 // ListenerProcessor adds an HTTP and an HTTPS listener to
 // the DAG if there are virtual hosts and secure virtual
 // hosts already defined as roots in the DAG.
-type ListenerProcessor struct{}
+type ListenerProcessor struct {
+	logrus.FieldLogger
+
+	// SessionTicketKeys, if set, is the name of the Secret holding
+	// the keys that the HTTPS listener uses to encrypt and decrypt
+	// TLS session tickets.
+	SessionTicketKeys *types.NamespacedName
+}
 
 // Run adds HTTP and HTTPS listeners to the DAG if there are
 // virtual hosts and secure virtual hosts already defined as
 // roots in the DAG.
-func (p *ListenerProcessor) Run(dag *DAG, _ *KubernetesCache) {
+func (p *ListenerProcessor) Run(dag *DAG, cache *KubernetesCache) {
 	p.buildHTTPListener(dag)
-	p.buildHTTPSListener(dag)
+	p.buildHTTPSListener(dag, cache)
 }
 
-// buildHTTPListener builds a *dag.Listener for the vhosts bound to port 80.
+// buildHTTPListener builds a *dag.Listener for the vhosts bound to port 80,
+// and one for the vhosts bound to each additional HTTP listener.
 // The list of virtual hosts will attached to the listener will be sorted
 // by hostname.
 func (p *ListenerProcessor) buildHTTPListener(dag *DAG) {
-	var virtualhosts []Vertex
+	virtualhosts := map[string][]Vertex{}
 	var remove []Vertex
 
 	for _, root := range dag.roots {
@@ -41,7 +54,7 @@ func (p *ListenerProcessor) buildHTTPListener(dag *DAG) {
 			remove = append(remove, obj)
 
 			if obj.Valid() {
-				virtualhosts = append(virtualhosts, obj)
+				virtualhosts[obj.Listener] = append(virtualhosts[obj.Listener], obj)
 			}
 		}
 	}
@@ -51,26 +64,32 @@ func (p *ListenerProcessor) buildHTTPListener(dag *DAG) {
 		dag.RemoveRoot(r)
 	}
 
-	if len(virtualhosts) == 0 {
-		return
+	names := make([]string, 0, len(virtualhosts))
+	for name := range virtualhosts {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		vhosts := virtualhosts[name]
+		sort.SliceStable(vhosts, func(i, j int) bool {
+			return vhosts[i].(*VirtualHost).Name < vhosts[j].(*VirtualHost).Name
+		})
+
+		http := &Listener{
+			Name:         name,
+			Port:         80,
+			VirtualHosts: vhosts,
+		}
 
-	sort.SliceStable(virtualhosts, func(i, j int) bool {
-		return virtualhosts[i].(*VirtualHost).Name < virtualhosts[j].(*VirtualHost).Name
-	})
-
-	http := &Listener{
-		Port:         80,
-		VirtualHosts: virtualhosts,
+		dag.AddRoot(http)
 	}
-
-	dag.AddRoot(http)
 }
 
 // buildHTTPSListener builds a *dag.Listener for the vhosts bound to port 443.
 // The list of virtual hosts will attached to the listener will be sorted
 // by hostname.
-func (p *ListenerProcessor) buildHTTPSListener(dag *DAG) {
+func (p *ListenerProcessor) buildHTTPSListener(dag *DAG, cache *KubernetesCache) {
 	var virtualhosts []Vertex
 	var remove []Vertex
 
@@ -99,9 +118,31 @@ func (p *ListenerProcessor) buildHTTPSListener(dag *DAG) {
 	})
 
 	https := &Listener{
-		Port:         443,
-		VirtualHosts: virtualhosts,
+		Port:              443,
+		VirtualHosts:      virtualhosts,
+		SessionTicketKeys: p.sessionTicketKeys(cache),
 	}
 
 	dag.AddRoot(https)
 }
+
+// sessionTicketKeys returns the configured session ticket keys
+// Secret, or nil if it is not configured or not valid. Without
+// shared keys, each Envoy generates its own keys and clients can
+// only resume sessions on the replica that issued the ticket.
+func (p *ListenerProcessor) sessionTicketKeys(cache *KubernetesCache) *Secret {
+	if p.SessionTicketKeys == nil {
+		return nil
+	}
+
+	sec, err := cache.LookupSecret(*p.SessionTicketKeys, validSessionTicketKeys)
+	if err != nil {
+		p.WithError(err).
+			WithField("name", p.SessionTicketKeys.Name).
+			WithField("namespace", p.SessionTicketKeys.Namespace).
+			Error("invalid TLS session ticket keys")
+		return nil
+	}
+
+	return sec
+}
@@ -14,8 +14,11 @@
 package dag
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -42,9 +45,9 @@ func retryOn(ro []contour_api_v1.RetryOn) string {
 	return strings.Join(ss, ",")
 }
 
-func retryPolicy(rp *contour_api_v1.RetryPolicy) *RetryPolicy {
+func retryPolicy(rp *contour_api_v1.RetryPolicy) (*RetryPolicy, error) {
 	if rp == nil {
-		return nil
+		return nil, nil
 	}
 
 	// If PerTryTimeout is not a valid duration string, use the Envoy default
@@ -57,11 +60,64 @@ func retryPolicy(rp *contour_api_v1.RetryPolicy) *RetryPolicy {
 		perTryTimeout = timeout.DurationSetting(perTryDuration)
 	}
 
+	backoff, err := retryBackoff(rp.Backoff)
+	if err != nil {
+		return nil, err
+	}
+
 	return &RetryPolicy{
 		RetryOn:              retryOn(rp.RetryOn),
 		RetriableStatusCodes: rp.RetriableStatusCodes,
 		NumRetries:           max(1, uint32(rp.NumRetries)),
 		PerTryTimeout:        perTryTimeout,
+		Backoff:              backoff,
+	}, nil
+}
+
+// retryBackoff parses the intervals of a retry backoff. The base
+// interval must be positive, and the maximum interval must not be
+// less than the base interval.
+func retryBackoff(rb *contour_api_v1.RetryBackoff) (*RetryBackoff, error) {
+	if rb == nil {
+		return nil, nil
+	}
+
+	base, err := time.ParseDuration(rb.BaseInterval)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing backoff base interval: %w", err)
+	}
+	if base <= 0 {
+		return nil, fmt.Errorf("backoff base interval %q must be greater than zero", rb.BaseInterval)
+	}
+
+	var maxInterval time.Duration
+	if rb.MaxInterval != "" {
+		maxInterval, err = time.ParseDuration(rb.MaxInterval)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing backoff max interval: %w", err)
+		}
+		if maxInterval < base {
+			return nil, fmt.Errorf("backoff max interval %q must not be less than the base interval %q", rb.MaxInterval, rb.BaseInterval)
+		}
+	}
+
+	return &RetryBackoff{
+		BaseInterval: base,
+		MaxInterval:  maxInterval,
+	}, nil
+}
+
+// retryBudget returns the retry budget for clusters of a route with the
+// given retry policy. A budget set in the retry policy overrides the
+// default budget.
+func retryBudget(rp *contour_api_v1.RetryPolicy, defaultBudget *RetryBudget) *RetryBudget {
+	if rp == nil || rp.Budget == nil {
+		return defaultBudget
+	}
+
+	return &RetryBudget{
+		BudgetPercent:       rp.Budget.BudgetPercent,
+		MinRetryConcurrency: rp.Budget.MinRetryConcurrency,
 	}
 }
 
@@ -122,6 +178,84 @@ func headersPolicyRoute(policy *contour_api_v1.HeadersPolicy, allowHostRewrite b
 	}, nil
 }
 
+// HeadersPolicyOf returns the headers policy that sets and
+// removes the supplied headers, or nil if there are none. The
+// Host header can't be set.
+func HeadersPolicyOf(set map[string]string, remove []string) (*HeadersPolicy, error) {
+	if len(set) == 0 && len(remove) == 0 {
+		return nil, nil
+	}
+
+	policy := &contour_api_v1.HeadersPolicy{
+		Remove: remove,
+	}
+	for name, value := range set {
+		policy.Set = append(policy.Set, contour_api_v1.HeaderValue{Name: name, Value: value})
+	}
+
+	return headersPolicyService(policy)
+}
+
+// apply sets the policies of r that are not set from the
+// route defaults.
+func (d *RouteDefaults) apply(r *Route) {
+	if r.TimeoutPolicy.ResponseTimeout.UseDefault() {
+		r.TimeoutPolicy.ResponseTimeout = d.TimeoutPolicy.ResponseTimeout
+	}
+	if r.TimeoutPolicy.IdleTimeout.UseDefault() {
+		r.TimeoutPolicy.IdleTimeout = d.TimeoutPolicy.IdleTimeout
+	}
+	if r.RetryPolicy == nil {
+		r.RetryPolicy = d.RetryPolicy
+	}
+	r.RequestHeadersPolicy = mergeHeadersPolicy(r.RequestHeadersPolicy, d.RequestHeadersPolicy)
+	r.ResponseHeadersPolicy = mergeHeadersPolicy(r.ResponseHeadersPolicy, d.ResponseHeadersPolicy)
+}
+
+// mergeHeadersPolicy returns a copy of policy with the headers
+// of defaults added, unless policy sets or removes them itself.
+func mergeHeadersPolicy(policy, defaults *HeadersPolicy) *HeadersPolicy {
+	if defaults == nil {
+		return policy
+	}
+	if policy == nil {
+		return defaults
+	}
+
+	merged := &HeadersPolicy{
+		HostRewrite: policy.HostRewrite,
+		Set:         map[string]string{},
+	}
+
+	own := sets.NewString(policy.Remove...)
+	for k, v := range policy.Set {
+		merged.Set[k] = v
+		own.Insert(k)
+	}
+
+	remove := sets.NewString(policy.Remove...)
+	for k, v := range defaults.Set {
+		if !own.Has(k) {
+			merged.Set[k] = v
+		}
+	}
+	for _, k := range defaults.Remove {
+		if !own.Has(k) {
+			remove.Insert(k)
+		}
+	}
+
+	if len(merged.Set) == 0 {
+		merged.Set = nil
+	}
+	merged.Remove = remove.List()
+	if len(merged.Remove) == 0 {
+		merged.Remove = nil
+	}
+
+	return merged
+}
+
 func escapeHeaderValue(value string) string {
 	// Envoy supports %-encoded variables, so literal %'s in the header's value must be escaped.  See:
 	// https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#custom-request-response-headers
@@ -205,6 +339,75 @@ func timeoutPolicy(tp *contour_api_v1.TimeoutPolicy) (TimeoutPolicy, error) {
 	}, nil
 }
 
+// cacheTTL returns the TTL for caching responses to a route, or
+// zero if the route has no cache policy. Since the TTL is applied
+// with the Cache-Control header, a cache policy can't be combined
+// with a response headers policy that also sets Cache-Control.
+func cacheTTL(cp *contour_api_v1.CachePolicy, respHP *HeadersPolicy) (time.Duration, error) {
+	if cp == nil {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(cp.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing ttl: %w", err)
+	}
+
+	if ttl < time.Second {
+		return 0, fmt.Errorf("ttl %q must be at least one second", cp.TTL)
+	}
+
+	if respHP != nil {
+		if _, ok := respHP.Set["Cache-Control"]; ok {
+			return 0, errors.New("cannot be combined with a Cache-Control response header")
+		}
+	}
+
+	return ttl, nil
+}
+
+func faultPolicy(fp *contour_api_v1.FaultPolicy) (*FaultPolicy, error) {
+	if fp == nil {
+		return nil, nil
+	}
+
+	if fp.Delay == nil && fp.Abort == nil {
+		return nil, errors.New("at least one of delay or abort must be specified")
+	}
+
+	var policy FaultPolicy
+
+	if fp.Delay != nil {
+		d, err := time.ParseDuration(fp.Delay.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing delay duration: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("delay duration %q must be positive", fp.Delay.Duration)
+		}
+		if fp.Delay.Percentage > 100 {
+			return nil, fmt.Errorf("delay percentage %d must be at most 100", fp.Delay.Percentage)
+		}
+
+		policy.DelayDuration = d
+		policy.DelayPercent = fp.Delay.Percentage
+	}
+
+	if fp.Abort != nil {
+		if fp.Abort.StatusCode < 200 || fp.Abort.StatusCode > 599 {
+			return nil, fmt.Errorf("abort status code %d must be between 200 and 599", fp.Abort.StatusCode)
+		}
+		if fp.Abort.Percentage > 100 {
+			return nil, fmt.Errorf("abort percentage %d must be at most 100", fp.Abort.Percentage)
+		}
+
+		policy.AbortStatusCode = fp.Abort.StatusCode
+		policy.AbortPercent = fp.Abort.Percentage
+	}
+
+	return &policy, nil
+}
+
 func httpHealthCheckPolicy(hc *contour_api_v1.HTTPHealthCheckPolicy) *HTTPHealthCheckPolicy {
 	if hc == nil {
 		return nil
@@ -219,6 +422,20 @@ func httpHealthCheckPolicy(hc *contour_api_v1.HTTPHealthCheckPolicy) *HTTPHealth
 	}
 }
 
+func grpcHealthCheckPolicy(hc *contour_api_v1.GRPCHealthCheckPolicy) *GRPCHealthCheckPolicy {
+	if hc == nil {
+		return nil
+	}
+	return &GRPCHealthCheckPolicy{
+		ServiceName:        hc.ServiceName,
+		Authority:          hc.Authority,
+		Interval:           time.Duration(hc.IntervalSeconds) * time.Second,
+		Timeout:            time.Duration(hc.TimeoutSeconds) * time.Second,
+		UnhealthyThreshold: uint32(hc.UnhealthyThresholdCount),
+		HealthyThreshold:   uint32(hc.HealthyThresholdCount),
+	}
+}
+
 func tcpHealthCheckPolicy(hc *contour_api_v1.TCPHealthCheckPolicy) *TCPHealthCheckPolicy {
 	if hc == nil {
 		return nil
@@ -231,8 +448,78 @@ func tcpHealthCheckPolicy(hc *contour_api_v1.TCPHealthCheckPolicy) *TCPHealthChe
 	}
 }
 
+// alpnProtocols returns the ALPN protocol names for the given
+// HTTP versions, in Envoy's preference order.
+func alpnProtocols(versions []contour_api_v1.HTTPVersion) []string {
+	wanted := map[contour_api_v1.HTTPVersion]bool{}
+	for _, v := range versions {
+		wanted[v] = true
+	}
+
+	var protos []string
+	if wanted["HTTP/2"] {
+		protos = append(protos, "h2")
+	}
+	if wanted["HTTP/1.1"] {
+		protos = append(protos, "http/1.1")
+	}
+
+	return protos
+}
+
+// ocspStaplePolicy returns the OCSP staple policy, or an error if
+// the policy requires a staple that one of the secrets lacks.
+func ocspStaplePolicy(policy contour_api_v1.OCSPStaplePolicy, secrets ...*Secret) (OCSPStaplePolicy, error) {
+	switch policy {
+	case "", contour_api_v1.LenientStapling:
+		return OCSPLenientStapling, nil
+	case contour_api_v1.StrictStapling:
+		return OCSPStrictStapling, nil
+	case contour_api_v1.MustStaple:
+		for _, s := range secrets {
+			if s != nil && len(s.OCSPStaple()) == 0 {
+				return 0, fmt.Errorf("Secret %q has no %q key", s.Name(), OCSPStapleKey)
+			}
+		}
+		return OCSPMustStaple, nil
+	default:
+		return 0, fmt.Errorf("unsupported policy %q", policy)
+	}
+}
+
+// upgradeTypeRegex matches an HTTP upgrade protocol, which
+// is a token optionally followed by a "/" and a version token.
+var upgradeTypeRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9a-z-]+(/[!#$%&'*+.^_`|~0-9a-z-]+)?$")
+
+// upgradeTypes returns the lower cased upgrade types, or an error
+// if any upgrade type is not valid or is listed more than once.
+func upgradeTypes(types []string) ([]string, error) {
+	var upgrades []string
+	seen := sets.NewString()
+
+	for _, t := range types {
+		t = strings.ToLower(t)
+
+		switch {
+		case t == "websocket":
+			return nil, errors.New("websocket upgrades are enabled with enableWebsockets")
+		case !upgradeTypeRegex.MatchString(t):
+			return nil, fmt.Errorf("invalid upgrade type %q", t)
+		case seen.Has(t):
+			return nil, fmt.Errorf("duplicate upgrade type %q", t)
+		}
+
+		seen.Insert(t)
+		upgrades = append(upgrades, t)
+	}
+
+	return upgrades, nil
+}
+
 // loadBalancerPolicy returns the load balancer strategy or
-// blank if no valid strategy is supplied.
+// blank if no valid strategy is supplied. The RequestHash and
+// Maglev strategies are only valid with request hash policies,
+// since there is nothing to hash otherwise.
 func loadBalancerPolicy(lbp *contour_api_v1.LoadBalancerPolicy) string {
 	if lbp == nil {
 		return ""
@@ -244,11 +531,155 @@ func loadBalancerPolicy(lbp *contour_api_v1.LoadBalancerPolicy) string {
 		return "Random"
 	case "Cookie":
 		return "Cookie"
+	case "RequestHash":
+		if len(lbp.RequestHashPolicies) == 0 {
+			return ""
+		}
+		return "RequestHash"
+	case "Maglev":
+		if len(lbp.RequestHashPolicies) == 0 {
+			return ""
+		}
+		return "Maglev"
 	default:
 		return ""
 	}
 }
 
+// clientIPAffinity sets the RequestHash load balancer policy on
+// the clusters without one whose Service has ClientIP session
+// affinity, and returns true if it set any. The caller must then
+// hash on the source IP, so that each client keeps being sent to
+// the same endpoint, as kube-proxy would do.
+func clientIPAffinity(clusters []*Cluster) bool {
+	found := false
+	for _, c := range clusters {
+		if c.Upstream.ClientIPAffinity && c.LoadBalancerPolicy == "" {
+			c.LoadBalancerPolicy = "RequestHash"
+			found = true
+		}
+	}
+	return found
+}
+
+// maxRingSize is the largest ring size Envoy accepts.
+const maxRingSize = 8 * 1024 * 1024
+
+// maxMaglevTableSize is the largest table size Envoy accepts.
+const maxMaglevTableSize = 5000011
+
+// requestHashPolicies returns the request hash policies for the
+// RequestHash and Maglev load balancer strategies, or nil for any
+// other strategy.
+func requestHashPolicies(lbp *contour_api_v1.LoadBalancerPolicy) ([]RequestHashPolicy, error) {
+	switch loadBalancerPolicy(lbp) {
+	case "RequestHash", "Maglev":
+	default:
+		return nil, nil
+	}
+
+	var policies []RequestHashPolicy
+	for _, hp := range lbp.RequestHashPolicies {
+		set := 0
+		policy := RequestHashPolicy{
+			Terminal:     hp.Terminal,
+			HashSourceIP: hp.HashSourceIP,
+		}
+		if hp.HashSourceIP {
+			set++
+		}
+
+		if hp.HeaderHashOptions != nil {
+			set++
+			if hp.HeaderHashOptions.HeaderName == "" {
+				return nil, errors.New("request hash policy header name must be set")
+			}
+			policy.HeaderHashOptions = &HeaderHashOptions{
+				HeaderName: hp.HeaderHashOptions.HeaderName,
+			}
+		}
+
+		if hp.CookieHashOptions != nil {
+			set++
+			if hp.CookieHashOptions.CookieName == "" {
+				return nil, errors.New("request hash policy cookie name must be set")
+			}
+
+			var ttl time.Duration
+			if hp.CookieHashOptions.TTL != "" {
+				var err error
+				if ttl, err = time.ParseDuration(hp.CookieHashOptions.TTL); err != nil {
+					return nil, fmt.Errorf("request hash policy cookie ttl is invalid: %w", err)
+				}
+			}
+
+			policy.CookieHashOptions = &CookieHashOptions{
+				CookieName: hp.CookieHashOptions.CookieName,
+				TTL:        ttl,
+				Path:       hp.CookieHashOptions.Path,
+			}
+		}
+
+		if set != 1 {
+			return nil, errors.New("request hash policy must set exactly one of headerHashOptions, cookieHashOptions or hashSourceIP")
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// ringHashConfig returns the ring hash tuning for the RequestHash and
+// Cookie load balancer strategies, or nil if none is set.
+func ringHashConfig(lbp *contour_api_v1.LoadBalancerPolicy) (*RingHashConfig, error) {
+	switch loadBalancerPolicy(lbp) {
+	case "RequestHash", "Cookie":
+	default:
+		return nil, nil
+	}
+
+	if lbp.RingHash == nil {
+		return nil, nil
+	}
+
+	minSize, maxSize := lbp.RingHash.MinimumRingSize, lbp.RingHash.MaximumRingSize
+	if minSize > maxRingSize || maxSize > maxRingSize {
+		return nil, fmt.Errorf("ring size must not be greater than %d", maxRingSize)
+	}
+	if minSize > 0 && maxSize > 0 && minSize > maxSize {
+		return nil, fmt.Errorf("minimum ring size %d is greater than maximum ring size %d", minSize, maxSize)
+	}
+	if minSize == 0 && maxSize == 0 {
+		return nil, nil
+	}
+
+	return &RingHashConfig{
+		MinimumRingSize: minSize,
+		MaximumRingSize: maxSize,
+	}, nil
+}
+
+// maglevConfig returns the Maglev tuning for the Maglev load balancer
+// strategy, or nil if none is set.
+func maglevConfig(lbp *contour_api_v1.LoadBalancerPolicy) (*MaglevConfig, error) {
+	if loadBalancerPolicy(lbp) != "Maglev" || lbp.Maglev == nil || lbp.Maglev.TableSize == 0 {
+		return nil, nil
+	}
+
+	size := lbp.Maglev.TableSize
+	if size > maxMaglevTableSize {
+		return nil, fmt.Errorf("maglev table size must not be greater than %d", maxMaglevTableSize)
+	}
+	if !big.NewInt(int64(size)).ProbablyPrime(0) {
+		return nil, fmt.Errorf("maglev table size %d is not a prime number", size)
+	}
+
+	return &MaglevConfig{
+		TableSize: size,
+	}, nil
+}
+
 func max(a, b uint32) uint32 {
 	if a > b {
 		return a
@@ -274,3 +705,71 @@ func prefixReplacementsAreValid(replacements []contour_api_v1.ReplacePrefix) (st
 
 	return "", nil
 }
+
+func rateLimitPolicy(in *contour_api_v1.RateLimitPolicy) (*RateLimitPolicy, error) {
+	if in == nil || in.Global == nil {
+		return nil, nil
+	}
+
+	if in.Global.Disabled {
+		if len(in.Global.Descriptors) > 0 {
+			return nil, errors.New("a disabled rate limit policy cannot have descriptors")
+		}
+		return nil, nil
+	}
+
+	if len(in.Global.Descriptors) == 0 {
+		return nil, nil
+	}
+
+	rp := &RateLimitPolicy{
+		Global: &GlobalRateLimitPolicy{},
+	}
+
+	for _, d := range in.Global.Descriptors {
+		var rld RateLimitDescriptor
+
+		for _, entry := range d.Entries {
+			// ensure exactly one field is populated on the entry
+			var set int
+
+			if entry.GenericKey != nil {
+				set++
+
+				rld.Entries = append(rld.Entries, RateLimitDescriptorEntry{
+					GenericKey: &GenericKeyDescriptorEntry{
+						Key:   entry.GenericKey.Key,
+						Value: entry.GenericKey.Value,
+					},
+				})
+			}
+
+			if entry.RequestHeader != nil {
+				set++
+
+				rld.Entries = append(rld.Entries, RateLimitDescriptorEntry{
+					HeaderMatch: &HeaderMatchDescriptorEntry{
+						HeaderName: entry.RequestHeader.HeaderName,
+						Key:        entry.RequestHeader.DescriptorKey,
+					},
+				})
+			}
+
+			if entry.RemoteAddress != nil {
+				set++
+
+				rld.Entries = append(rld.Entries, RateLimitDescriptorEntry{
+					RemoteAddress: &RemoteAddressDescriptorEntry{},
+				})
+			}
+
+			if set != 1 {
+				return nil, errors.New("rate limit descriptor entry must have exactly one field set")
+			}
+		}
+
+		rp.Global.Descriptors = append(rp.Global.Descriptors, &rld)
+	}
+
+	return rp, nil
+}
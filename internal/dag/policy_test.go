@@ -117,8 +117,9 @@ func TestRetryPolicyIngress(t *testing.T) {
 
 func TestRetryPolicy(t *testing.T) {
 	tests := map[string]struct {
-		rp   *contour_api_v1.RetryPolicy
-		want *RetryPolicy
+		rp      *contour_api_v1.RetryPolicy
+		want    *RetryPolicy
+		wantErr bool
 	}{
 		"nil retry policy": {
 			rp:   nil,
@@ -179,11 +180,199 @@ func TestRetryPolicy(t *testing.T) {
 				NumRetries:           1,
 			},
 		},
+		"backoff": {
+			rp: &contour_api_v1.RetryPolicy{
+				Backoff: &contour_api_v1.RetryBackoff{
+					BaseInterval: "100ms",
+					MaxInterval:  "2s",
+				},
+			},
+			want: &RetryPolicy{
+				RetryOn:    "5xx",
+				NumRetries: 1,
+				Backoff: &RetryBackoff{
+					BaseInterval: 100 * time.Millisecond,
+					MaxInterval:  2 * time.Second,
+				},
+			},
+		},
+		"backoff without max interval": {
+			rp: &contour_api_v1.RetryPolicy{
+				Backoff: &contour_api_v1.RetryBackoff{
+					BaseInterval: "100ms",
+				},
+			},
+			want: &RetryPolicy{
+				RetryOn:    "5xx",
+				NumRetries: 1,
+				Backoff: &RetryBackoff{
+					BaseInterval: 100 * time.Millisecond,
+				},
+			},
+		},
+		"backoff invalid base interval": {
+			rp: &contour_api_v1.RetryPolicy{
+				Backoff: &contour_api_v1.RetryBackoff{
+					BaseInterval: "fast",
+				},
+			},
+			wantErr: true,
+		},
+		"backoff zero base interval": {
+			rp: &contour_api_v1.RetryPolicy{
+				Backoff: &contour_api_v1.RetryBackoff{
+					BaseInterval: "0s",
+				},
+			},
+			wantErr: true,
+		},
+		"backoff max interval less than base interval": {
+			rp: &contour_api_v1.RetryPolicy{
+				Backoff: &contour_api_v1.RetryBackoff{
+					BaseInterval: "1s",
+					MaxInterval:  "100ms",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := retryPolicy(tc.rp)
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			} else {
+				assert.Equal(t, tc.want, got)
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	defaultBudget := &RetryBudget{BudgetPercent: 20}
+
+	tests := map[string]struct {
+		rp   *contour_api_v1.RetryPolicy
+		def  *RetryBudget
+		want *RetryBudget
+	}{
+		"nil retry policy without default": {
+			rp:   nil,
+			want: nil,
+		},
+		"nil retry policy": {
+			rp:   nil,
+			def:  defaultBudget,
+			want: defaultBudget,
+		},
+		"retry policy without budget": {
+			rp: &contour_api_v1.RetryPolicy{
+				NumRetries: 3,
+			},
+			def:  defaultBudget,
+			want: defaultBudget,
+		},
+		"retry policy budget overrides default": {
+			rp: &contour_api_v1.RetryPolicy{
+				Budget: &contour_api_v1.RetryBudget{
+					BudgetPercent:       50,
+					MinRetryConcurrency: 5,
+				},
+			},
+			def: defaultBudget,
+			want: &RetryBudget{
+				BudgetPercent:       50,
+				MinRetryConcurrency: 5,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := retryBudget(tc.rp, tc.def)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestALPNProtocols(t *testing.T) {
+	assert.Equal(t, []string(nil), alpnProtocols(nil))
+	assert.Equal(t, []string{"http/1.1"}, alpnProtocols([]contour_api_v1.HTTPVersion{"HTTP/1.1"}))
+	assert.Equal(t, []string{"h2"}, alpnProtocols([]contour_api_v1.HTTPVersion{"HTTP/2", "HTTP/2"}))
+	assert.Equal(t, []string{"h2", "http/1.1"}, alpnProtocols([]contour_api_v1.HTTPVersion{"HTTP/1.1", "HTTP/2"}))
+}
+
+func TestUpgradeTypes(t *testing.T) {
+	got, err := upgradeTypes(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string(nil), got)
+
+	got, err = upgradeTypes([]string{"SPDY/3.1", "h2c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spdy/3.1", "h2c"}, got)
+
+	for _, types := range [][]string{
+		{"websocket"},
+		{""},
+		{"spdy 3.1"},
+		{"spdy/3.1/x"},
+		{"spdy/3.1", "Spdy/3.1"},
+	} {
+		_, err := upgradeTypes(types)
+		assert.Error(t, err, "upgrade types %q", types)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := map[string]struct {
+		cp      *contour_api_v1.CachePolicy
+		respHP  *HeadersPolicy
+		want    time.Duration
+		wantErr bool
+	}{
+		"nil cache policy": {
+			cp:   nil,
+			want: 0,
+		},
+		"valid ttl": {
+			cp:   &contour_api_v1.CachePolicy{TTL: "1m30s"},
+			want: 90 * time.Second,
+		},
+		"invalid ttl": {
+			cp:      &contour_api_v1.CachePolicy{TTL: "forever"},
+			wantErr: true,
+		},
+		"ttl too short": {
+			cp:      &contour_api_v1.CachePolicy{TTL: "500ms"},
+			wantErr: true,
+		},
+		"other response headers": {
+			cp: &contour_api_v1.CachePolicy{TTL: "1m"},
+			respHP: &HeadersPolicy{
+				Set: map[string]string{"X-Foo": "bar"},
+			},
+			want: time.Minute,
+		},
+		"cache-control response header": {
+			cp: &contour_api_v1.CachePolicy{TTL: "1m"},
+			respHP: &HeadersPolicy{
+				Set: map[string]string{"Cache-Control": "no-store"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			got := retryPolicy(tc.rp)
+			got, err := cacheTTL(tc.cp, tc.respHP)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
 			assert.Equal(t, tc.want, got)
 		})
 	}
@@ -286,6 +475,36 @@ func TestLoadBalancerPolicy(t *testing.T) {
 			},
 			want: "",
 		},
+		"RequestHash": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					HashSourceIP: true,
+				}},
+			},
+			want: "RequestHash",
+		},
+		"RequestHash without hash policies": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+			},
+			want: "",
+		},
+		"Maglev": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Maglev",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					HashSourceIP: true,
+				}},
+			},
+			want: "Maglev",
+		},
+		"Maglev without hash policies": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Maglev",
+			},
+			want: "",
+		},
 	}
 
 	for name, tc := range tests {
@@ -295,3 +514,453 @@ func TestLoadBalancerPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestHashPolicies(t *testing.T) {
+	tests := map[string]struct {
+		lbp     *contour_api_v1.LoadBalancerPolicy
+		want    []RequestHashPolicy
+		wantErr bool
+	}{
+		"nil": {
+			lbp:  nil,
+			want: nil,
+		},
+		"not a request hash strategy": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Random",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					HashSourceIP: true,
+				}},
+			},
+			want: nil,
+		},
+		"all hash sources": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					Terminal: true,
+					HeaderHashOptions: &contour_api_v1.HeaderHashOptions{
+						HeaderName: "X-User-Id",
+					},
+				}, {
+					CookieHashOptions: &contour_api_v1.CookieHashOptions{
+						CookieName: "session",
+						TTL:        "1h",
+						Path:       "/",
+					},
+				}, {
+					HashSourceIP: true,
+				}},
+			},
+			want: []RequestHashPolicy{{
+				Terminal: true,
+				HeaderHashOptions: &HeaderHashOptions{
+					HeaderName: "X-User-Id",
+				},
+			}, {
+				CookieHashOptions: &CookieHashOptions{
+					CookieName: "session",
+					TTL:        time.Hour,
+					Path:       "/",
+				},
+			}, {
+				HashSourceIP: true,
+			}},
+		},
+		"no hash source": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Maglev",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					Terminal: true,
+				}},
+			},
+			wantErr: true,
+		},
+		"multiple hash sources": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					HeaderHashOptions: &contour_api_v1.HeaderHashOptions{
+						HeaderName: "X-User-Id",
+					},
+					HashSourceIP: true,
+				}},
+			},
+			wantErr: true,
+		},
+		"missing header name": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					HeaderHashOptions: &contour_api_v1.HeaderHashOptions{},
+				}},
+			},
+			wantErr: true,
+		},
+		"invalid cookie ttl": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "RequestHash",
+				RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+					CookieHashOptions: &contour_api_v1.CookieHashOptions{
+						CookieName: "session",
+						TTL:        "forever",
+					},
+				}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := requestHashPolicies(tc.lbp)
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+				return
+			}
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRingHashConfig(t *testing.T) {
+	tests := map[string]struct {
+		lbp     *contour_api_v1.LoadBalancerPolicy
+		want    *RingHashConfig
+		wantErr bool
+	}{
+		"nil": {
+			lbp:  nil,
+			want: nil,
+		},
+		"cookie strategy": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				RingHash: &contour_api_v1.RingHashOptions{
+					MinimumRingSize: 4096,
+					MaximumRingSize: 65536,
+				},
+			},
+			want: &RingHashConfig{
+				MinimumRingSize: 4096,
+				MaximumRingSize: 65536,
+			},
+		},
+		"not a ring hash strategy": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Random",
+				RingHash: &contour_api_v1.RingHashOptions{
+					MinimumRingSize: 4096,
+				},
+			},
+			want: nil,
+		},
+		"minimum greater than maximum": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				RingHash: &contour_api_v1.RingHashOptions{
+					MinimumRingSize: 4096,
+					MaximumRingSize: 1024,
+				},
+			},
+			wantErr: true,
+		},
+		"too large": {
+			lbp: &contour_api_v1.LoadBalancerPolicy{
+				Strategy: "Cookie",
+				RingHash: &contour_api_v1.RingHashOptions{
+					MaximumRingSize: 16 * 1024 * 1024,
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := ringHashConfig(tc.lbp)
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+				return
+			}
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestMaglevConfig(t *testing.T) {
+	maglev := func(size uint64) *contour_api_v1.LoadBalancerPolicy {
+		return &contour_api_v1.LoadBalancerPolicy{
+			Strategy: "Maglev",
+			RequestHashPolicies: []contour_api_v1.RequestHashPolicy{{
+				HashSourceIP: true,
+			}},
+			Maglev: &contour_api_v1.MaglevOptions{
+				TableSize: size,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		lbp     *contour_api_v1.LoadBalancerPolicy
+		want    *MaglevConfig
+		wantErr bool
+	}{
+		"nil": {
+			lbp:  nil,
+			want: nil,
+		},
+		"prime table size": {
+			lbp:  maglev(131071),
+			want: &MaglevConfig{TableSize: 131071},
+		},
+		"non-prime table size": {
+			lbp:     maglev(65536),
+			wantErr: true,
+		},
+		"too large": {
+			lbp:     maglev(10000019),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := maglevConfig(tc.lbp)
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+				return
+			}
+			assert.NoError(t, gotErr)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	tests := map[string]struct {
+		in      *contour_api_v1.RateLimitPolicy
+		want    *RateLimitPolicy
+		wantErr bool
+	}{
+		"nil input": {
+			in:   nil,
+			want: nil,
+		},
+		"nil global rate limit policy": {
+			in:   &contour_api_v1.RateLimitPolicy{},
+			want: nil,
+		},
+		"global rate limit policy with all descriptor entry types": {
+			in: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Descriptors: []contour_api_v1.RateLimitDescriptor{
+						{
+							Entries: []contour_api_v1.RateLimitDescriptorEntry{
+								{
+									GenericKey: &contour_api_v1.GenericKeyDescriptor{
+										Key:   "generic-key-key",
+										Value: "generic-key-value",
+									},
+								},
+								{
+									RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{},
+								},
+							},
+						},
+						{
+							Entries: []contour_api_v1.RateLimitDescriptorEntry{
+								{
+									RequestHeader: &contour_api_v1.RequestHeaderDescriptor{
+										HeaderName:    "X-Api-Key",
+										DescriptorKey: "api-key",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &RateLimitPolicy{
+				Global: &GlobalRateLimitPolicy{
+					Descriptors: []*RateLimitDescriptor{
+						{
+							Entries: []RateLimitDescriptorEntry{
+								{
+									GenericKey: &GenericKeyDescriptorEntry{
+										Key:   "generic-key-key",
+										Value: "generic-key-value",
+									},
+								},
+								{
+									RemoteAddress: &RemoteAddressDescriptorEntry{},
+								},
+							},
+						},
+						{
+							Entries: []RateLimitDescriptorEntry{
+								{
+									HeaderMatch: &HeaderMatchDescriptorEntry{
+										HeaderName: "X-Api-Key",
+										Key:        "api-key",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"descriptor entry with no fields set": {
+			in: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Descriptors: []contour_api_v1.RateLimitDescriptor{
+						{
+							Entries: []contour_api_v1.RateLimitDescriptorEntry{{}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"descriptor entry with multiple fields set": {
+			in: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Descriptors: []contour_api_v1.RateLimitDescriptor{
+						{
+							Entries: []contour_api_v1.RateLimitDescriptorEntry{
+								{
+									GenericKey: &contour_api_v1.GenericKeyDescriptor{
+										Value: "generic-key-value",
+									},
+									RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		"disabled global rate limit policy": {
+			in: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Disabled: true,
+				},
+			},
+			want: nil,
+		},
+		"disabled global rate limit policy with descriptors": {
+			in: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Disabled: true,
+					Descriptors: []contour_api_v1.RateLimitDescriptor{
+						{
+							Entries: []contour_api_v1.RateLimitDescriptorEntry{
+								{
+									RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := rateLimitPolicy(tc.in)
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			} else {
+				assert.Equal(t, tc.want, got)
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestFaultPolicy(t *testing.T) {
+	tests := map[string]struct {
+		fp      *contour_api_v1.FaultPolicy
+		want    *FaultPolicy
+		wantErr bool
+	}{
+		"nil fault policy": {
+			fp:   nil,
+			want: nil,
+		},
+		"delay and abort": {
+			fp: &contour_api_v1.FaultPolicy{
+				Delay: &contour_api_v1.FaultDelay{Duration: "2s", Percentage: 10},
+				Abort: &contour_api_v1.FaultAbort{StatusCode: 503, Percentage: 5},
+			},
+			want: &FaultPolicy{
+				DelayDuration:   2 * time.Second,
+				DelayPercent:    10,
+				AbortStatusCode: 503,
+				AbortPercent:    5,
+			},
+		},
+		"abort only": {
+			fp: &contour_api_v1.FaultPolicy{
+				Abort: &contour_api_v1.FaultAbort{StatusCode: 500, Percentage: 100},
+			},
+			want: &FaultPolicy{
+				AbortStatusCode: 500,
+				AbortPercent:    100,
+			},
+		},
+		"no faults": {
+			fp:      &contour_api_v1.FaultPolicy{},
+			wantErr: true,
+		},
+		"invalid delay duration": {
+			fp: &contour_api_v1.FaultPolicy{
+				Delay: &contour_api_v1.FaultDelay{Duration: "soon", Percentage: 10},
+			},
+			wantErr: true,
+		},
+		"zero delay duration": {
+			fp: &contour_api_v1.FaultPolicy{
+				Delay: &contour_api_v1.FaultDelay{Duration: "0s", Percentage: 10},
+			},
+			wantErr: true,
+		},
+		"delay percentage too large": {
+			fp: &contour_api_v1.FaultPolicy{
+				Delay: &contour_api_v1.FaultDelay{Duration: "1s", Percentage: 101},
+			},
+			wantErr: true,
+		},
+		"invalid abort status code": {
+			fp: &contour_api_v1.FaultPolicy{
+				Abort: &contour_api_v1.FaultAbort{StatusCode: 99, Percentage: 10},
+			},
+			wantErr: true,
+		},
+		"abort percentage too large": {
+			fp: &contour_api_v1.FaultPolicy{
+				Abort: &contour_api_v1.FaultAbort{StatusCode: 503, Percentage: 200},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := faultPolicy(tc.fp)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
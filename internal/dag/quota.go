@@ -0,0 +1,218 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NamespaceQuota limits the HTTPProxies and Ingresses of a
+// namespace. A zero limit means that there is no limit.
+type NamespaceQuota struct {
+	// MaxVirtualHosts is the number of root HTTPProxies and
+	// Ingress hosts the namespace may have.
+	MaxVirtualHosts int
+
+	// MaxRoutes is the number of routes the HTTPProxies and
+	// Ingress paths of the namespace may have in total.
+	MaxRoutes int
+}
+
+// quotaViolation is the error reported on the status of an
+// HTTPProxy, or recorded against an Ingress, that exceeds the
+// quota of its namespace.
+type quotaViolation struct {
+	conditionType string
+	reason        string
+	message       string
+}
+
+// quotaObject identifies an HTTPProxy or Ingress that is counted
+// against the quota of its namespace.
+type quotaObject struct {
+	kind string
+	name types.NamespacedName
+}
+
+// quotaUsage is what an HTTPProxy or Ingress counts against the
+// quota of its namespace.
+type quotaUsage struct {
+	quotaObject
+	created metav1.Time
+	vhosts  int
+	routes  int
+}
+
+// quotaViolations returns the HTTPProxies and Ingresses in source
+// that exceed the quota of their namespace. Objects are counted in
+// the order they were created, so that a new object in a namespace
+// at its quota does not invalidate the objects already there.
+//
+// Only valid root HTTPProxies, and the HTTPProxies they include,
+// are counted. A root is not counted if it is rejected for its
+// virtual host, for example because its fqdn is missing, is a
+// wildcard, or is claimed by another HTTPProxy or Ingress.
+func quotaViolations(source *KubernetesCache, defaultQuota NamespaceQuota, quotas map[string]NamespaceQuota) map[quotaObject]quotaViolation {
+	if defaultQuota == (NamespaceQuota{}) && len(quotas) == 0 {
+		return nil
+	}
+
+	var usages []quotaUsage
+	for _, proxy := range countedHTTPProxies(source) {
+		vhosts := 0
+		if proxy.Spec.VirtualHost != nil {
+			vhosts = 1
+		}
+		usages = append(usages, quotaUsage{
+			quotaObject: quotaObject{kind: k8s.KindOf(proxy), name: k8s.NamespacedNameOf(proxy)},
+			created:     proxy.CreationTimestamp,
+			vhosts:      vhosts,
+			routes:      len(proxy.Spec.Routes),
+		})
+	}
+	for _, ing := range source.ingresses {
+		hosts := map[string]bool{}
+		routes := 0
+		for _, rule := range rulesFromSpec(ing.Spec) {
+			hosts[rule.Host] = true
+			routes += len(httppaths(rule))
+		}
+		usages = append(usages, quotaUsage{
+			quotaObject: quotaObject{kind: k8s.KindOf(ing), name: k8s.NamespacedNameOf(ing)},
+			created:     ing.CreationTimestamp,
+			vhosts:      len(hosts),
+			routes:      routes,
+		})
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		if !a.created.Equal(&b.created) {
+			return a.created.Before(&b.created)
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.name.Namespace != b.name.Namespace {
+			return a.name.Namespace < b.name.Namespace
+		}
+		return a.name.Name < b.name.Name
+	})
+
+	vhosts := map[string]int{}
+	routes := map[string]int{}
+	violations := map[quotaObject]quotaViolation{}
+
+	for _, u := range usages {
+		ns := u.name.Namespace
+		quota, ok := quotas[ns]
+		if !ok {
+			quota = defaultQuota
+		}
+
+		switch {
+		case quota.MaxVirtualHosts > 0 && vhosts[ns]+u.vhosts > quota.MaxVirtualHosts:
+			violations[u.quotaObject] = quotaViolation{
+				conditionType: contour_api_v1.ConditionTypeVirtualHostError,
+				reason:        "VirtualHostQuotaExceeded",
+				message:       fmt.Sprintf("namespace %q is limited to %d virtual hosts", ns, quota.MaxVirtualHosts),
+			}
+		case quota.MaxRoutes > 0 && routes[ns]+u.routes > quota.MaxRoutes:
+			violations[u.quotaObject] = quotaViolation{
+				conditionType: contour_api_v1.ConditionTypeRouteError,
+				reason:        "RouteQuotaExceeded",
+				message:       fmt.Sprintf("namespace %q is limited to %d routes", ns, quota.MaxRoutes),
+			}
+		default:
+			vhosts[ns] += u.vhosts
+			routes[ns] += u.routes
+		}
+	}
+
+	return violations
+}
+
+// countedHTTPProxies returns the root HTTPProxies that are not
+// rejected for their virtual host, and the HTTPProxies that they
+// include, directly or indirectly.
+func countedHTTPProxies(source *KubernetesCache) []*contour_api_v1.HTTPProxy {
+	rootAllowed := func(namespace string) bool {
+		if len(source.RootNamespaces) == 0 {
+			return true
+		}
+		for _, ns := range source.RootNamespaces {
+			if ns == namespace {
+				return true
+			}
+		}
+		return false
+	}
+
+	fqdns := map[string]int{}
+	for _, proxy := range source.httpproxies {
+		if proxy.Spec.VirtualHost != nil {
+			fqdns[proxy.Spec.VirtualHost.Fqdn]++
+		}
+	}
+	overlaps := overlappingFqdns(source)
+
+	counted := map[types.NamespacedName]*contour_api_v1.HTTPProxy{}
+
+	var include func(*contour_api_v1.HTTPProxy)
+	include = func(proxy *contour_api_v1.HTTPProxy) {
+		m := k8s.NamespacedNameOf(proxy)
+		if _, ok := counted[m]; ok {
+			return
+		}
+		counted[m] = proxy
+
+		for _, inc := range proxy.Spec.Includes {
+			namespace := inc.Namespace
+			if namespace == "" {
+				namespace = proxy.Namespace
+			}
+			child, ok := source.httpproxies[types.NamespacedName{Name: inc.Name, Namespace: namespace}]
+			if ok && child.Spec.VirtualHost == nil {
+				include(child)
+			}
+		}
+	}
+
+	for _, proxy := range source.httpproxies {
+		vh := proxy.Spec.VirtualHost
+		if vh == nil {
+			continue
+		}
+		if _, ok := overlaps[k8s.NamespacedNameOf(proxy)]; ok {
+			continue
+		}
+		if isBlank(vh.Fqdn) || strings.Contains(vh.Fqdn, "*") || fqdns[vh.Fqdn] > 1 || !rootAllowed(proxy.Namespace) {
+			continue
+		}
+		include(proxy)
+	}
+
+	proxies := make([]*contour_api_v1.HTTPProxy, 0, len(counted))
+	for _, proxy := range counted {
+		proxies = append(proxies, proxy)
+	}
+	return proxies
+}
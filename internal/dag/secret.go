@@ -21,12 +21,25 @@ import (
 	"fmt"
 	"strings"
 
+	"golang.org/x/crypto/ocsp"
 	v1 "k8s.io/api/core/v1"
 )
 
 // CACertificateKey is the key name for accessing TLS CA certificate bundles in Kubernetes Secrets.
 const CACertificateKey = "ca.crt"
 
+// GRPCDescriptorKey is the key name for accessing the protobuf
+// descriptor set of gRPC services in Kubernetes Secrets.
+const GRPCDescriptorKey = "descriptor.pb"
+
+// CRLKey is the key name for accessing the PEM encoded certificate
+// revocation list in Kubernetes Secrets.
+const CRLKey = "crl.pem"
+
+// OCSPStapleKey is the key name for accessing the DER encoded OCSP
+// response that is stapled to the TLS certificate in Kubernetes Secrets.
+const OCSPStapleKey = "tls.ocsp-staple"
+
 // isValidSecret returns true if the secret is interesting and well
 // formed. TLS certificate/key pairs must be secrets of type
 // "kubernetes.io/tls". Certificate bundles may be "kubernetes.io/tls"
@@ -53,6 +66,12 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, fmt.Errorf("invalid TLS private key: %v", err)
 		}
 
+		if data := secret.Data[OCSPStapleKey]; len(data) > 0 {
+			if err := validateOCSPStaple(data, secret.Data[v1.TLSCertKey]); err != nil {
+				return false, fmt.Errorf("invalid OCSP staple: %v", err)
+			}
+		}
+
 	// Generic secrets may have a 'ca.crt' only.
 	case v1.SecretTypeOpaque, "":
 		if _, ok := secret.Data[v1.TLSCertKey]; ok {
@@ -63,10 +82,16 @@ func isValidSecret(secret *v1.Secret) (bool, error) {
 			return false, nil
 		}
 
-		if data := secret.Data[CACertificateKey]; len(data) == 0 {
+		if len(secret.Data[CACertificateKey]) == 0 && len(secret.Data[GRPCDescriptorKey]) == 0 && len(secret.Data[CRLKey]) == 0 {
 			return false, nil
 		}
 
+		if data := secret.Data[CRLKey]; len(data) > 0 {
+			if err := validateCRL(data); err != nil {
+				return false, fmt.Errorf("invalid certificate revocation list: %v", err)
+			}
+		}
+
 	default:
 		return false, nil
 
@@ -145,6 +170,60 @@ func validateCertificate(data []byte) error {
 	return nil
 }
 
+// validateCRL returns an error unless data holds one or
+// more PEM encoded certificate revocation lists.
+func validateCRL(data []byte) error {
+	var exists bool
+
+	for containsPEMHeader(data) {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return errors.New("failed to parse PEM block")
+		}
+		if block.Type != "X509 CRL" {
+			return fmt.Errorf("unexpected block type '%s'", block.Type)
+		}
+		if _, err := x509.ParseDERCRL(block.Bytes); err != nil {
+			return err
+		}
+
+		exists = true
+	}
+
+	if !exists {
+		return errors.New("failed to locate certificate revocation list")
+	}
+
+	return nil
+}
+
+// validateOCSPStaple returns an error unless the staple is an OCSP
+// response for the first certificate in the given PEM data. Envoy
+// rejects staples that do not match their certificate.
+func validateOCSPStaple(staple []byte, certData []byte) error {
+	resp, err := ocsp.ParseResponse(staple, nil)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		return errors.New("failed to locate certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	if resp.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return fmt.Errorf("response is for serial number %s, not %s", resp.SerialNumber, cert.SerialNumber)
+	}
+
+	return nil
+}
+
 func hasCommonName(c *x509.Certificate) bool {
 	return strings.TrimSpace(c.Subject.CommonName) != ""
 }
@@ -153,6 +232,50 @@ func hasSubjectAltNames(c *x509.Certificate) bool {
 	return len(c.DNSNames) > 0 || len(c.IPAddresses) > 0
 }
 
+// certificateNames returns the DNS names of the first certificate
+// in data, or its common name if it has no DNS names, in lower case.
+func certificateNames(data []byte) []string {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	names := cert.DNSNames
+	if len(names) == 0 && hasCommonName(cert) {
+		names = []string{cert.Subject.CommonName}
+	}
+
+	for i := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(names[i]))
+	}
+	return names
+}
+
+// certificateCovers returns true if one of the certificate names
+// matches host. A wildcard name matches exactly one leftmost label.
+func certificateCovers(names []string, host string) bool {
+	if strings.Contains(host, "*") {
+		return false
+	}
+
+	for _, name := range names {
+		if name == host {
+			return true
+		}
+		if strings.HasPrefix(name, "*.") {
+			if i := strings.Index(host, "."); i > 0 && host[i:] == name[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func validatePrivateKey(data []byte) error {
 	var keys int
 
@@ -194,3 +317,47 @@ func validatePrivateKey(data []byte) error {
 		return errors.New("multiple private keys")
 	}
 }
+
+// certificateKeyAlgorithm returns the public key algorithm of
+// the first certificate in the given PEM data.
+func certificateKeyAlgorithm(data []byte) (x509.PublicKeyAlgorithm, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return x509.UnknownPublicKeyAlgorithm, errors.New("failed to locate certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return x509.UnknownPublicKeyAlgorithm, err
+	}
+
+	return cert.PublicKeyAlgorithm, nil
+}
+
+// validateCertificateKeyTypes checks that the two certificates can
+// be served together, which requires that one has an RSA key and
+// the other has an ECDSA key.
+func validateCertificateKeyTypes(primary, secondary []byte) error {
+	var algs []x509.PublicKeyAlgorithm
+
+	for _, data := range [][]byte{primary, secondary} {
+		alg, err := certificateKeyAlgorithm(data)
+		if err != nil {
+			return err
+		}
+
+		switch alg {
+		case x509.RSA, x509.ECDSA:
+		default:
+			return fmt.Errorf("unsupported certificate key type %s", alg)
+		}
+
+		algs = append(algs, alg)
+	}
+
+	if algs[0] == algs[1] {
+		return fmt.Errorf("both certificates have %s keys", algs[0])
+	}
+
+	return nil
+}
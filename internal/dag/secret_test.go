@@ -70,6 +70,104 @@ func TestIsValidSecret(t *testing.T) {
 	}
 }
 
+func TestValidateCertificateKeyTypes(t *testing.T) {
+	tests := map[string]struct {
+		primary, secondary string
+		want               error
+	}{
+		"RSA and ECDSA": {
+			primary:   fixture.CERTIFICATE,
+			secondary: fixture.EC_CERTIFICATE,
+			want:      nil,
+		},
+		"ECDSA and RSA": {
+			primary:   fixture.EC_CERTIFICATE,
+			secondary: fixture.CERTIFICATE,
+			want:      nil,
+		},
+		"both RSA": {
+			primary:   fixture.CERTIFICATE,
+			secondary: fixture.CERTIFICATE,
+			want:      errors.New("both certificates have RSA keys"),
+		},
+		"both ECDSA": {
+			primary:   fixture.EC_CERTIFICATE,
+			secondary: fixture.EC_CERTIFICATE,
+			want:      errors.New("both certificates have ECDSA keys"),
+		},
+		"missing certificate": {
+			primary:   fixture.CERTIFICATE,
+			secondary: "",
+			want:      errors.New("failed to locate certificate"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, validateCertificateKeyTypes([]byte(tc.primary), []byte(tc.secondary)))
+		})
+	}
+}
+
+func TestValidateOCSPStaple(t *testing.T) {
+	tests := map[string]struct {
+		staple  []byte
+		cert    string
+		wantErr bool
+	}{
+		"matching certificate": {
+			staple: fixture.OCSPStaple(t, fixture.CERTIFICATE),
+			cert:   fixture.CERTIFICATE,
+		},
+		"other certificate": {
+			staple:  fixture.OCSPStaple(t, fixture.EC_CERTIFICATE),
+			cert:    fixture.CERTIFICATE,
+			wantErr: true,
+		},
+		"not an OCSP response": {
+			staple:  []byte("staple"),
+			cert:    fixture.CERTIFICATE,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateOCSPStaple(tc.staple, []byte(tc.cert))
+			assert.Equal(t, tc.wantErr, err != nil, "%v", err)
+		})
+	}
+}
+
+func TestValidateCRL(t *testing.T) {
+	tests := map[string]struct {
+		crl     []byte
+		wantErr bool
+	}{
+		"single list": {
+			crl: fixture.CRL(t, fixture.CERTIFICATE),
+		},
+		"multiple lists": {
+			crl: append(fixture.CRL(t, fixture.CERTIFICATE), fixture.CRL(t, fixture.EC_CERTIFICATE)...),
+		},
+		"certificate": {
+			crl:     []byte(fixture.CERTIFICATE),
+			wantErr: true,
+		},
+		"not PEM": {
+			crl:     []byte("crl"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateCRL(tc.crl)
+			assert.Equal(t, tc.wantErr, err != nil, "%v", err)
+		})
+	}
+}
+
 func secretdata(cert, key string) map[string][]byte {
 	return map[string][]byte{
 		v1.TLSCertKey:       []byte(cert),
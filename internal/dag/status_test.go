@@ -15,10 +15,14 @@ package dag
 
 import (
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/fixture"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -29,9 +33,14 @@ import (
 func TestDAGStatus(t *testing.T) {
 
 	type testcase struct {
-		objs                []interface{}
-		fallbackCertificate *types.NamespacedName
-		want                map[types.NamespacedName]contour_api_v1.DetailedCondition
+		objs                      []interface{}
+		fallbackCertificate       *types.NamespacedName
+		enableExternalBackends    bool
+		enableForwardProxy        bool
+		enableOriginalDestination bool
+		namespaceQuota            NamespaceQuota
+		namespaceQuotas           map[string]NamespaceQuota
+		want                      map[types.NamespacedName]contour_api_v1.DetailedCondition
 	}
 
 	run := func(t *testing.T, desc string, tc testcase) {
@@ -45,10 +54,17 @@ func TestDAGStatus(t *testing.T) {
 				},
 				Processors: []Processor{
 					&IngressProcessor{
-						FieldLogger: fixture.NewTestLogger(t),
+						FieldLogger:     fixture.NewTestLogger(t),
+						NamespaceQuota:  tc.namespaceQuota,
+						NamespaceQuotas: tc.namespaceQuotas,
 					},
 					&HTTPProxyProcessor{
-						FallbackCertificate: tc.fallbackCertificate,
+						FallbackCertificate:       tc.fallbackCertificate,
+						EnableExternalBackends:    tc.enableExternalBackends,
+						EnableForwardProxy:        tc.enableForwardProxy,
+						EnableOriginalDestination: tc.enableOriginalDestination,
+						NamespaceQuota:            tc.namespaceQuota,
+						NamespaceQuotas:           tc.namespaceQuotas,
 					},
 					&ListenerProcessor{},
 				},
@@ -1075,6 +1091,38 @@ func TestDAGStatus(t *testing.T) {
 		},
 	})
 
+	proxyTLSConditionWithoutClientValidation := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				Conditions: []contour_api_v1.MatchCondition{{
+					TLS: &contour_api_v1.TLSMatchCondition{
+						ClientCertificatePresented: true,
+					},
+				}},
+				Services: []contour_api_v1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	run(t, "tls route condition without client validation", testcase{
+		objs: []interface{}{proxyTLSConditionWithoutClientValidation, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: proxyTLSConditionWithoutClientValidation.Name, Namespace: proxyTLSConditionWithoutClientValidation.Namespace}: fixture.NewValidCondition().
+				WithGeneration(proxyTLSConditionWithoutClientValidation.Generation).
+				WithError(contour_api_v1.ConditionTypeRouteError, "TLSMatchConditionsNotValid", "tls conditions require the root HTTPProxy to validate client certificates"),
+		},
+	})
+
 	proxyInvalidDuplicateIncludeCondtionHeaders := &contour_api_v1.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: "roots",
@@ -2030,6 +2078,95 @@ func TestDAGStatus(t *testing.T) {
 		},
 	})
 
+	caConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "ca-bundle",
+		},
+		Data: map[string]string{
+			CACertificateKey: fixture.CERTIFICATE,
+		},
+	}
+
+	clientValidationWithConfigMap := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "example",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName: "ssl-cert",
+					ClientValidation: &contour_api_v1.DownstreamValidation{
+						CAConfigMap: caConfigMap.Name,
+					},
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: "home",
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+
+	run(t, "clientValidation with a CA ConfigMap", testcase{
+		objs: []interface{}{clientValidationWithConfigMap, caConfigMap, fixture.SecretRootsCert, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: clientValidationWithConfigMap.Name, Namespace: clientValidationWithConfigMap.Namespace}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "clientValidation with a missing CA ConfigMap", testcase{
+		objs: []interface{}{clientValidationWithConfigMap, fixture.SecretRootsCert, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: clientValidationWithConfigMap.Name, Namespace: clientValidationWithConfigMap.Namespace}: fixture.NewValidCondition().
+				WithError(contour_api_v1.ConditionTypeTLSError, "ClientValidationInvalid", `Spec.VirtualHost.TLS client validation is invalid: invalid CA ConfigMap "roots/ca-bundle": ConfigMap not found`),
+		},
+	})
+
+	clientValidationWithSecretAndConfigMap := clientValidationWithConfigMap.DeepCopy()
+	clientValidationWithSecretAndConfigMap.Spec.VirtualHost.TLS.ClientValidation.CACertificate = "something"
+
+	run(t, "clientValidation with both a CA Secret and a CA ConfigMap", testcase{
+		objs: []interface{}{clientValidationWithSecretAndConfigMap, caConfigMap, fixture.SecretRootsCert, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: clientValidationWithSecretAndConfigMap.Name, Namespace: clientValidationWithSecretAndConfigMap.Namespace}: fixture.NewValidCondition().
+				WithError(contour_api_v1.ConditionTypeTLSError, "ClientValidationInvalid", "Spec.VirtualHost.TLS client validation is invalid: both a CA Secret and a CA ConfigMap are specified"),
+		},
+	})
+
+	crlSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "crl",
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			CRLKey: fixture.CRL(t, fixture.CERTIFICATE),
+		},
+	}
+
+	clientValidationWithCRL := clientValidationWithConfigMap.DeepCopy()
+	clientValidationWithCRL.Spec.VirtualHost.TLS.ClientValidation.CertificateRevocationList = crlSecret.Name
+
+	run(t, "clientValidation with a CRL Secret", testcase{
+		objs: []interface{}{clientValidationWithCRL, caConfigMap, crlSecret, fixture.SecretRootsCert, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: clientValidationWithCRL.Name, Namespace: clientValidationWithCRL.Namespace}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "clientValidation with a missing CRL Secret", testcase{
+		objs: []interface{}{clientValidationWithCRL, caConfigMap, fixture.SecretRootsCert, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: clientValidationWithCRL.Name, Namespace: clientValidationWithCRL.Namespace}: fixture.NewValidCondition().
+				WithError(contour_api_v1.ConditionTypeTLSError, "ClientValidationInvalid", `Spec.VirtualHost.TLS client validation is invalid: invalid CRL Secret "roots/crl": Secret not found`),
+		},
+	})
+
 	tlsPassthroughAndValidation := &contour_api_v1.HTTPProxy{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "invalid",
@@ -2333,4 +2470,1245 @@ func TestDAGStatus(t *testing.T) {
 		},
 	})
 
+	invalidRateLimitEntry := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fixture.ServiceRootsKuard.Namespace,
+			Name:      "invalid-ratelimit",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []contour_api_v1.Route{
+				{
+					Services: []contour_api_v1.Service{
+						{
+							Name: fixture.ServiceRootsKuard.Name,
+						},
+					},
+					RateLimitPolicy: &contour_api_v1.RateLimitPolicy{
+						Global: &contour_api_v1.GlobalRateLimitPolicy{
+							Descriptors: []contour_api_v1.RateLimitDescriptor{
+								{
+									Entries: []contour_api_v1.RateLimitDescriptorEntry{
+										{
+											GenericKey:    &contour_api_v1.GenericKeyDescriptor{Value: "foo"},
+											RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	run(t, "proxy with invalid rate limit descriptor entry is invalid", testcase{
+		objs: []interface{}{invalidRateLimitEntry, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{
+				Name:      invalidRateLimitEntry.Name,
+				Namespace: invalidRateLimitEntry.Namespace,
+			}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "RateLimitPolicyNotValid",
+				"route.rateLimitPolicy is invalid: rate limit descriptor entry must have exactly one field set"),
+		},
+	})
+
+	invalidVirtualHostRateLimit := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fixture.ServiceRootsKuard.Namespace,
+			Name:      "invalid-vhost-ratelimit",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				RateLimitPolicy: &contour_api_v1.RateLimitPolicy{
+					Global: &contour_api_v1.GlobalRateLimitPolicy{
+						Disabled: true,
+						Descriptors: []contour_api_v1.RateLimitDescriptor{
+							{
+								Entries: []contour_api_v1.RateLimitDescriptorEntry{
+									{
+										RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Routes: []contour_api_v1.Route{
+				{
+					Services: []contour_api_v1.Service{
+						{
+							Name: fixture.ServiceRootsKuard.Name,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	run(t, "proxy with disabled virtual host rate limit policy with descriptors is invalid", testcase{
+		objs: []interface{}{invalidVirtualHostRateLimit, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{
+				Name:      invalidVirtualHostRateLimit.Name,
+				Namespace: invalidVirtualHostRateLimit.Namespace,
+			}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "RateLimitPolicyNotValid",
+				"Spec.VirtualHost.RateLimitPolicy is invalid: a disabled rate limit policy cannot have descriptors"),
+		},
+	})
+
+	proxyExternalBackend := func(address string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "external-backend",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: "api",
+						Port: 443,
+						External: &contour_api_v1.ExternalBackend{
+							Address: address,
+						},
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "proxy with external backend is invalid when external backends are disabled", testcase{
+		objs: []interface{}{proxyExternalBackend("api.example.com")},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "external-backend", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeServiceError, "ExternalBackendsDisabled",
+				`service "api": external backends are not enabled`),
+		},
+	})
+
+	run(t, "proxy with external backend is valid", testcase{
+		objs:                   []interface{}{proxyExternalBackend("api.example.com")},
+		enableExternalBackends: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "external-backend", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with external backend IP address is valid", testcase{
+		objs:                   []interface{}{proxyExternalBackend("192.0.2.10")},
+		enableExternalBackends: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "external-backend", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with invalid external backend address is invalid", testcase{
+		objs:                   []interface{}{proxyExternalBackend("not a hostname")},
+		enableExternalBackends: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "external-backend", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeServiceError, "ExternalBackendNotValid",
+				`service "api": invalid address "not a hostname": must be an IP address or a DNS name`),
+		},
+	})
+
+	proxyForwardProxy := func(fqdn string, routes ...contour_api_v1.Route) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "egress",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn:         fqdn,
+					ForwardProxy: &contour_api_v1.ForwardProxy{},
+				},
+				Routes: routes,
+			},
+		}
+	}
+
+	run(t, "forward proxy is invalid when not enabled", testcase{
+		objs: []interface{}{proxyForwardProxy("*.example.com")},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "egress", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ForwardProxyNotEnabled",
+				"Spec.VirtualHost.ForwardProxy is not enabled in the Contour configuration"),
+		},
+	})
+
+	run(t, "forward proxy with a wildcard domain is valid", testcase{
+		objs:               []interface{}{proxyForwardProxy("*.example.com")},
+		enableForwardProxy: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "egress", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "forward proxy with an inner wildcard is invalid", testcase{
+		objs:               []interface{}{proxyForwardProxy("api.*.example.com")},
+		enableForwardProxy: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "egress", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "WildCardNotAllowed",
+				`Spec.VirtualHost.Fqdn "api.*.example.com" can only use a wildcard as its first label`),
+		},
+	})
+
+	run(t, "forward proxy with routes is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyForwardProxy("api.example.com", contour_api_v1.Route{
+				Services: []contour_api_v1.Service{{
+					Name: fixture.ServiceRootsKuard.Name,
+					Port: 8080,
+				}},
+			}),
+		},
+		enableForwardProxy: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "egress", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeSpecError, "ForwardProxyIncompatibleFeatures",
+				"Spec.VirtualHost.ForwardProxy cannot be combined with Routes, Includes or a TCPProxy"),
+		},
+	})
+
+	proxyOriginalDestination := func(services ...contour_api_v1.Service) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "original-destination",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "passthrough.example.com",
+				},
+				Routes: []contour_api_v1.Route{{
+					OriginalDestination: &contour_api_v1.OriginalDestination{},
+					Services:            services,
+				}},
+			},
+		}
+	}
+
+	run(t, "original destination is invalid when not enabled", testcase{
+		objs: []interface{}{proxyOriginalDestination()},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "original-destination", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "OriginalDestinationNotEnabled",
+				"route.originalDestination is not enabled in the Contour configuration"),
+		},
+	})
+
+	run(t, "original destination without services is valid", testcase{
+		objs:                      []interface{}{proxyOriginalDestination()},
+		enableOriginalDestination: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "original-destination", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "original destination with services is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyOriginalDestination(contour_api_v1.Service{
+				Name: fixture.ServiceRootsKuard.Name,
+				Port: 8080,
+			}),
+		},
+		enableOriginalDestination: true,
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "original-destination", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "OriginalDestinationIncompatibleFeatures",
+				"route.originalDestination cannot be combined with route.services"),
+		},
+	})
+
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("helloworld.proto"),
+			Package: proto.String("helloworld"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Greeter"),
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	descriptorSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "descriptor",
+			Namespace: "roots",
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			GRPCDescriptorKey: descriptor,
+		},
+	}
+
+	proxyTranscoder := func(tls *contour_api_v1.TLS, services ...string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "transcoder",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "greeter.example.com",
+					TLS:  tls,
+					GRPCJSONTranscoder: &contour_api_v1.GRPCJSONTranscoder{
+						DescriptorSecretName: descriptorSecret.Name,
+						Services:             services,
+					},
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "grpc json transcoder without tls is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			descriptorSecret,
+			proxyTranscoder(nil, "helloworld.Greeter"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "transcoder", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderRequiresTLS",
+				"Spec.VirtualHost.GRPCJSONTranscoder requires that Spec.VirtualHost.TLS.SecretName be set"),
+		},
+	})
+
+	run(t, "grpc json transcoder with a missing descriptor secret is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			fixture.SecretRootsCert,
+			proxyTranscoder(&contour_api_v1.TLS{SecretName: fixture.SecretRootsCert.Name}, "helloworld.Greeter"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "transcoder", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderNotValid",
+				`Spec.VirtualHost.GRPCJSONTranscoder Secret "descriptor" is invalid: Secret not found`),
+		},
+	})
+
+	run(t, "grpc json transcoder with an undescribed service is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			fixture.SecretRootsCert,
+			descriptorSecret,
+			proxyTranscoder(&contour_api_v1.TLS{SecretName: fixture.SecretRootsCert.Name}, "helloworld.Farewell"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "transcoder", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "GRPCJSONTranscoderNotValid",
+				`Spec.VirtualHost.GRPCJSONTranscoder is invalid: service "helloworld.Farewell" is not in the descriptor set`),
+		},
+	})
+
+	run(t, "grpc json transcoder with tls is valid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			fixture.SecretRootsCert,
+			descriptorSecret,
+			proxyTranscoder(&contour_api_v1.TLS{SecretName: fixture.SecretRootsCert.Name}, "helloworld.Greeter"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "transcoder", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	// proxyQuota returns an HTTPProxy in the roots namespace,
+	// created at the given second, with one route for each
+	// include and each route prefix.
+	proxyQuota := func(name string, created int64, fqdn string, includes []string, prefixes ...string) *contour_api_v1.HTTPProxy {
+		proxy := &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "roots",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Unix(created, 0)),
+			},
+		}
+		if fqdn != "" {
+			proxy.Spec.VirtualHost = &contour_api_v1.VirtualHost{Fqdn: fqdn}
+		}
+		for _, include := range includes {
+			proxy.Spec.Includes = append(proxy.Spec.Includes, contour_api_v1.Include{
+				Name:       include,
+				Conditions: []contour_api_v1.MatchCondition{{Prefix: "/" + include}},
+			})
+		}
+		for _, prefix := range prefixes {
+			proxy.Spec.Routes = append(proxy.Spec.Routes, contour_api_v1.Route{
+				Conditions: []contour_api_v1.MatchCondition{{Prefix: prefix}},
+				Services: []contour_api_v1.Service{{
+					Name: fixture.ServiceRootsKuard.Name,
+					Port: 8080,
+				}},
+			})
+		}
+		return proxy
+	}
+
+	run(t, "root proxies over the namespace virtual host quota are invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyQuota("second", 2, "second.example.com", nil, "/"),
+			proxyQuota("first", 1, "first.example.com", nil, "/"),
+		},
+		namespaceQuota: NamespaceQuota{MaxVirtualHosts: 1},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "first", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+			{Name: "second", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "VirtualHostQuotaExceeded",
+				`namespace "roots" is limited to 1 virtual hosts`),
+		},
+	})
+
+	run(t, "invalid root proxies do not count against the namespace quota", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyQuota("wildcard", 1, "*.example.com", nil, "/"),
+			proxyQuota("valid", 2, "example.com", nil, "/"),
+		},
+		namespaceQuota: NamespaceQuota{MaxVirtualHosts: 1},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "valid", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+			{Name: "wildcard", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "WildCardNotAllowed",
+				`Spec.VirtualHost.Fqdn "*.example.com" cannot use wildcards`),
+		},
+	})
+
+	run(t, "ingress hosts count against the namespace quota", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "roots",
+					Name:              "kuard",
+					CreationTimestamp: metav1.NewTime(time.Unix(1, 0)),
+				},
+				Spec: v1beta1.IngressSpec{
+					Rules: []v1beta1.IngressRule{{
+						Host: "kuard.example.com",
+						IngressRuleValue: v1beta1.IngressRuleValue{
+							HTTP: &v1beta1.HTTPIngressRuleValue{
+								Paths: []v1beta1.HTTPIngressPath{{
+									Backend: v1beta1.IngressBackend{
+										ServiceName: fixture.ServiceRootsKuard.Name,
+										ServicePort: intstr.FromInt(8080),
+									},
+								}},
+							},
+						},
+					}},
+				},
+			},
+			proxyQuota("proxy", 2, "example.com", nil, "/"),
+		},
+		namespaceQuota: NamespaceQuota{MaxVirtualHosts: 1},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "proxy", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "VirtualHostQuotaExceeded",
+				`namespace "roots" is limited to 1 virtual hosts`),
+		},
+	})
+
+	run(t, "newer root proxy with an fqdn differing only in case is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyQuota("newer", 2, "Example.com", nil, "/"),
+			proxyQuota("older", 1, "example.com", nil, "/"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "older", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+			{Name: "newer", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "OverlappingVhost",
+				`fqdn "Example.com" overlaps with fqdn "example.com" of HTTPProxy roots/older`),
+		},
+	})
+
+	run(t, "root proxy with an fqdn overlapping an ingress host is invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "roots",
+					Name:              "kuard",
+					CreationTimestamp: metav1.NewTime(time.Unix(2, 0)),
+				},
+				Spec: v1beta1.IngressSpec{
+					Rules: []v1beta1.IngressRule{{
+						Host: "example.com",
+						IngressRuleValue: v1beta1.IngressRuleValue{
+							HTTP: &v1beta1.HTTPIngressRuleValue{
+								Paths: []v1beta1.HTTPIngressPath{{
+									Backend: v1beta1.IngressBackend{
+										ServiceName: fixture.ServiceRootsKuard.Name,
+										ServicePort: intstr.FromInt(8080),
+									},
+								}},
+							},
+						},
+					}},
+				},
+			},
+			proxyQuota("proxy", 1, "EXAMPLE.COM", nil, "/"),
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "proxy", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "OverlappingVhost",
+				`fqdn "EXAMPLE.COM" overlaps with fqdn "example.com" of Ingress roots/kuard`),
+		},
+	})
+
+	invalidTimeoutHeader := proxyQuota("proxy", 1, "example.com", nil, "/")
+	invalidTimeoutHeader.Spec.VirtualHost.ResponseTimeoutHeader = "x request timeout"
+
+	run(t, "response timeout header must be a valid header name", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			invalidTimeoutHeader,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "proxy", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ResponseTimeoutHeaderInvalid",
+				`Spec.VirtualHost.ResponseTimeoutHeader "x request timeout" is invalid: a valid HTTP header must consist of alphanumeric characters or '-' (e.g. 'X-Header-Name', regex used for validation is '[-A-Za-z0-9]+')`),
+		},
+	})
+
+	run(t, "included proxies over the namespace route quota are invalid", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyQuota("root", 1, "example.com", []string{"first", "second"}, "/"),
+			proxyQuota("first", 2, "", nil, "/", "/a"),
+			proxyQuota("second", 3, "", nil, "/"),
+		},
+		namespaceQuota: NamespaceQuota{MaxRoutes: 3},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "root", Namespace: "roots"}:  fixture.NewValidCondition().Valid(),
+			{Name: "first", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+			{Name: "second", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "RouteQuotaExceeded",
+				`namespace "roots" is limited to 3 routes`),
+		},
+	})
+
+	run(t, "namespace quota replaces the default quota", testcase{
+		objs: []interface{}{
+			fixture.ServiceRootsKuard,
+			proxyQuota("first", 1, "first.example.com", nil, "/"),
+			proxyQuota("second", 2, "second.example.com", nil, "/"),
+		},
+		namespaceQuota:  NamespaceQuota{MaxVirtualHosts: 1},
+		namespaceQuotas: map[string]NamespaceQuota{"roots": {MaxVirtualHosts: 2}},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "first", Namespace: "roots"}:  fixture.NewValidCondition().Valid(),
+			{Name: "second", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	secretRootsECCert := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("roots/ec-cert"),
+		Type:       v1.SecretTypeTLS,
+		Data:       secretdata(fixture.EC_CERTIFICATE, fixture.EC_PRIVATE_KEY),
+	}
+
+	proxySecondarySecret := func(secondary string, passthrough bool) *contour_api_v1.HTTPProxy {
+		tls := &contour_api_v1.TLS{
+			SecretName:          fixture.SecretRootsCert.Name,
+			SecondarySecretName: secondary,
+		}
+		if passthrough {
+			tls = &contour_api_v1.TLS{
+				Passthrough:         true,
+				SecondarySecretName: secondary,
+			}
+		}
+
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "secondary-secret",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "example.com",
+					TLS:  tls,
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "proxy with RSA and ECDSA secrets is valid", testcase{
+		objs: []interface{}{proxySecondarySecret(secretRootsECCert.Name, false), fixture.SecretRootsCert, secretRootsECCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "secondary-secret", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with two RSA secrets is invalid", testcase{
+		objs: []interface{}{proxySecondarySecret(fixture.SecretRootsFallback.Name, false), fixture.SecretRootsCert, fixture.SecretRootsFallback, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "secondary-secret", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeTLSError, "TLSConfigNotValid",
+				`Spec.VirtualHost.TLS Secrets "ssl-cert" and "fallbacksecret" can not be used together: both certificates have RSA keys`),
+		},
+	})
+
+	run(t, "proxy with missing secondary secret is invalid", testcase{
+		objs: []interface{}{proxySecondarySecret("missing", false), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "secondary-secret", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeTLSError, "SecretNotValid",
+				`Spec.VirtualHost.TLS Secret "missing" is invalid: Secret not found`),
+		},
+	})
+
+	run(t, "proxy with passthrough and secondary secret is invalid", testcase{
+		objs: []interface{}{proxySecondarySecret(secretRootsECCert.Name, true), secretRootsECCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "secondary-secret", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeTLSError, "TLSConfigNotValid",
+				"Spec.VirtualHost.TLS: both Passthrough and SecondarySecretName were specified"),
+		},
+	})
+
+	proxyOCSPStaplePolicy := func(policy contour_api_v1.OCSPStaplePolicy) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "ocsp-staple",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "example.com",
+					TLS: &contour_api_v1.TLS{
+						SecretName:       fixture.SecretRootsCert.Name,
+						OCSPStaplePolicy: policy,
+					},
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	secretRootsStapledCert := &v1.Secret{
+		ObjectMeta: fixture.SecretRootsCert.ObjectMeta,
+		Type:       v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey:       []byte(fixture.CERTIFICATE),
+			v1.TLSPrivateKeyKey: []byte(fixture.RSA_PRIVATE_KEY),
+			OCSPStapleKey:       fixture.OCSPStaple(t, fixture.CERTIFICATE),
+		},
+	}
+
+	run(t, "proxy with strict stapling and no OCSP staple is valid", testcase{
+		objs: []interface{}{proxyOCSPStaplePolicy(contour_api_v1.StrictStapling), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "ocsp-staple", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with must staple and an OCSP staple is valid", testcase{
+		objs: []interface{}{proxyOCSPStaplePolicy(contour_api_v1.MustStaple), secretRootsStapledCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "ocsp-staple", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with must staple and no OCSP staple is invalid", testcase{
+		objs: []interface{}{proxyOCSPStaplePolicy(contour_api_v1.MustStaple), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "ocsp-staple", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeTLSError, "OCSPStaplePolicyNotValid",
+				`Spec.VirtualHost.TLS.OCSPStaplePolicy is invalid: Secret "ssl-cert" has no "tls.ocsp-staple" key`),
+		},
+	})
+
+	proxyActiveServiceSet := func(active string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "bluegreen",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []contour_api_v1.Route{{
+					ActiveServiceSet: active,
+					Services: []contour_api_v1.Service{{
+						Name:       fixture.ServiceRootsKuard.Name,
+						Port:       8080,
+						ServiceSet: "blue",
+					}, {
+						Name:       fixture.ServiceRootsHome.Name,
+						Port:       8080,
+						ServiceSet: "green",
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "proxy with active service set is valid", testcase{
+		objs: []interface{}{proxyActiveServiceSet("green"), fixture.ServiceRootsKuard, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "bluegreen", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with unknown active service set is invalid", testcase{
+		objs: []interface{}{proxyActiveServiceSet("red"), fixture.ServiceRootsKuard, fixture.ServiceRootsHome},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "bluegreen", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "ActiveServiceSetNotValid",
+				`route.activeServiceSet "red" does not match the serviceSet of any service`),
+		},
+	})
+
+	proxyShadow := func(name, fqdn, shadowFqdn, shadowSecret string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      name,
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &contour_api_v1.TLS{
+						SecretName: fixture.SecretRootsCert.Name,
+						Shadow: &contour_api_v1.ShadowVirtualHost{
+							Fqdn:       shadowFqdn,
+							SecretName: shadowSecret,
+						},
+					},
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "proxy with shadow virtual host is valid", testcase{
+		objs: []interface{}{proxyShadow("shadow", "example.com", "staging.example.com", fixture.SecretRootsCert.Name), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with shadow virtual host using its own fqdn is invalid", testcase{
+		objs: []interface{}{proxyShadow("shadow", "example.com", "example.com", fixture.SecretRootsCert.Name), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNConflict",
+				`Spec.VirtualHost.TLS.Shadow.Fqdn "example.com" is already used by a virtual host`),
+		},
+	})
+
+	run(t, "proxy with shadow virtual host using another proxy's fqdn is invalid", testcase{
+		objs: []interface{}{
+			proxyShadow("shadow", "example.com", "other.example.com", fixture.SecretRootsCert.Name),
+			proxyShadow("other", "other.example.com", "staging.other.example.com", fixture.SecretRootsCert.Name),
+			fixture.SecretRootsCert, fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNConflict",
+				`Spec.VirtualHost.TLS.Shadow.Fqdn "other.example.com" is already used by a virtual host`),
+			{Name: "other", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with shadow virtual host using another proxy's fqdn in another case is invalid", testcase{
+		objs: []interface{}{
+			proxyShadow("shadow", "example.com", "Other.Example.com", fixture.SecretRootsCert.Name),
+			proxyShadow("other", "other.example.com", "staging.other.example.com", fixture.SecretRootsCert.Name),
+			fixture.SecretRootsCert, fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNConflict",
+				`Spec.VirtualHost.TLS.Shadow.Fqdn "Other.Example.com" is already used by a virtual host`),
+			{Name: "other", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with shadow virtual host using an ingress tls host is invalid", testcase{
+		objs: []interface{}{
+			proxyShadow("shadow", "example.com", "ingress.example.com", fixture.SecretRootsCert.Name),
+			&v1beta1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "roots",
+					Name:      "kuard",
+				},
+				Spec: v1beta1.IngressSpec{
+					TLS: []v1beta1.IngressTLS{{
+						Hosts:      []string{"ingress.example.com"},
+						SecretName: fixture.SecretRootsCert.Name,
+					}},
+					Backend: &v1beta1.IngressBackend{
+						ServiceName: fixture.ServiceRootsKuard.Name,
+						ServicePort: intstr.FromInt(8080),
+					},
+				},
+			},
+			fixture.SecretRootsCert, fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "ShadowFQDNConflict",
+				`Spec.VirtualHost.TLS.Shadow.Fqdn "ingress.example.com" is already used by a virtual host`),
+		},
+	})
+
+	olderShadow := proxyShadow("older", "example.com", "staging.example.com", fixture.SecretRootsCert.Name)
+	olderShadow.CreationTimestamp = metav1.NewTime(time.Unix(1, 0))
+	newerStaging := proxyShadow("newer", "Staging.Example.com", "staging.other.example.com", fixture.SecretRootsCert.Name)
+	newerStaging.CreationTimestamp = metav1.NewTime(time.Unix(2, 0))
+
+	run(t, "newer root proxy with an fqdn overlapping a shadow fqdn is invalid", testcase{
+		objs: []interface{}{olderShadow, newerStaging, fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "older", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+			{Name: "newer", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeVirtualHostError, "OverlappingVhost",
+				`fqdn "Staging.Example.com" overlaps with shadow fqdn "staging.example.com" of HTTPProxy roots/older`),
+		},
+	})
+
+	run(t, "proxy with shadow virtual host missing secret is invalid", testcase{
+		objs: []interface{}{proxyShadow("shadow", "example.com", "staging.example.com", "missing"), fixture.SecretRootsCert, fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "shadow", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeTLSError, "SecretNotValid",
+				`Spec.VirtualHost.TLS.Shadow Secret "missing" is invalid: Secret not found`),
+		},
+	})
+
+	proxyGRPCHealthCheck := func(protocol string, hc *contour_api_v1.HTTPHealthCheckPolicy) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      "grpc",
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []contour_api_v1.Route{{
+					HealthCheckPolicy: hc,
+					GRPCHealthCheckPolicy: &contour_api_v1.GRPCHealthCheckPolicy{
+						ServiceName: "helloworld.Greeter",
+					},
+					Services: []contour_api_v1.Service{{
+						Name:     fixture.ServiceRootsKuard.Name,
+						Port:     8080,
+						Protocol: &protocol,
+					}},
+				}},
+			},
+		}
+	}
+
+	run(t, "proxy with grpc health check on h2c service is valid", testcase{
+		objs: []interface{}{proxyGRPCHealthCheck("h2c", nil), fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "grpc", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+	run(t, "proxy with grpc health check on tls service is invalid", testcase{
+		objs: []interface{}{proxyGRPCHealthCheck("tls", nil), fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "grpc", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeServiceError, "GRPCHealthCheckNotValid",
+				`service "kuard": gRPC health checks require the h2 or h2c protocol`),
+		},
+	})
+
+	run(t, "proxy with grpc and http health checks is invalid", testcase{
+		objs: []interface{}{proxyGRPCHealthCheck("h2c", &contour_api_v1.HTTPHealthCheckPolicy{Path: "/healthz"}), fixture.ServiceRootsKuard},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "grpc", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "HealthCheckPolicyNotValid",
+				"route.healthCheckPolicy and route.grpcHealthCheckPolicy cannot both be specified"),
+		},
+	})
+
+	run(t, "proxy with websocket upgrade type is invalid", testcase{
+		objs: []interface{}{
+			&contour_api_v1.HTTPProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "roots",
+					Name:      "upgrades",
+				},
+				Spec: contour_api_v1.HTTPProxySpec{
+					VirtualHost: &contour_api_v1.VirtualHost{
+						Fqdn: "example.com",
+					},
+					Routes: []contour_api_v1.Route{{
+						UpgradeTypes: []string{"spdy/3.1", "websocket"},
+						Services: []contour_api_v1.Service{{
+							Name: fixture.ServiceRootsKuard.Name,
+							Port: 8080,
+						}},
+					}},
+				},
+			},
+			fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "upgrades", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "UpgradeTypesNotValid",
+				"route.upgradeTypes is invalid: websocket upgrades are enabled with enableWebsockets"),
+		},
+	})
+
+	run(t, "proxy with retry backoff max interval below base interval is invalid", testcase{
+		objs: []interface{}{
+			&contour_api_v1.HTTPProxy{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "roots",
+					Name:      "backoff",
+				},
+				Spec: contour_api_v1.HTTPProxySpec{
+					VirtualHost: &contour_api_v1.VirtualHost{
+						Fqdn: "example.com",
+					},
+					Routes: []contour_api_v1.Route{{
+						RetryPolicy: &contour_api_v1.RetryPolicy{
+							Backoff: &contour_api_v1.RetryBackoff{
+								BaseInterval: "1s",
+								MaxInterval:  "100ms",
+							},
+						},
+						Services: []contour_api_v1.Service{{
+							Name: fixture.ServiceRootsKuard.Name,
+							Port: 8080,
+						}},
+					}},
+				},
+			},
+			fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "backoff", Namespace: "roots"}: fixture.NewValidCondition().WithError(contour_api_v1.ConditionTypeRouteError, "RetryPolicyNotValid",
+				`route.retryPolicy is invalid: backoff max interval "100ms" must not be less than the base interval "1s"`),
+		},
+	})
+
+	wildcardCert, wildcardKey := fixture.Certificate(t, "*.example.com")
+	otherCert, otherKey := fixture.Certificate(t, "other.com")
+	tlsProxy := func(name, fqdn, secretName string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      name,
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: fqdn,
+					TLS: &contour_api_v1.TLS{
+						SecretName: secretName,
+					},
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	coalescingCondition := func(host string) contour_api_v1.DetailedCondition {
+		dc := fixture.NewValidCondition().Valid()
+		dc.AddWarningf(contour_api_v1.ConditionTypeTLSError, "ConnectionCoalescing",
+			"Spec.VirtualHost.TLS Secret %q certificate also covers %s, so HTTP/2 clients may reuse connections for this virtual host to send requests for those hosts, which are answered with 421 Misdirected Request",
+			"wildcard", host)
+		return dc
+	}
+
+	run(t, "proxies sharing a wildcard certificate warn of connection coalescing", testcase{
+		objs: []interface{}{
+			&v1.Secret{
+				ObjectMeta: fixture.ObjectMeta("roots/wildcard"),
+				Type:       v1.SecretTypeTLS,
+				Data:       map[string][]byte{v1.TLSCertKey: wildcardCert, v1.TLSPrivateKeyKey: wildcardKey},
+			},
+			&v1.Secret{
+				ObjectMeta: fixture.ObjectMeta("roots/other"),
+				Type:       v1.SecretTypeTLS,
+				Data:       map[string][]byte{v1.TLSCertKey: otherCert, v1.TLSPrivateKeyKey: otherKey},
+			},
+			tlsProxy("a", "a.example.com", "wildcard"),
+			tlsProxy("b", "b.example.com", "wildcard"),
+			tlsProxy("c", "other.com", "other"),
+			fixture.ServiceRootsKuard,
+		},
+		want: map[types.NamespacedName]contour_api_v1.DetailedCondition{
+			{Name: "a", Namespace: "roots"}: coalescingCondition("b.example.com"),
+			{Name: "b", Namespace: "roots"}: coalescingCondition("a.example.com"),
+			{Name: "c", Namespace: "roots"}: fixture.NewValidCondition().Valid(),
+		},
+	})
+
+}
+
+func TestDAGServiceSetStatus(t *testing.T) {
+	proxy := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "roots",
+			Name:      "bluegreen",
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				Conditions: []contour_api_v1.MatchCondition{{
+					Prefix: "/static",
+				}},
+				Services: []contour_api_v1.Service{{
+					Name: fixture.ServiceRootsKuard.Name,
+					Port: 8080,
+				}},
+			}, {
+				ActiveServiceSet: "green",
+				Services: []contour_api_v1.Service{{
+					Name:       fixture.ServiceRootsKuard.Name,
+					Port:       8080,
+					ServiceSet: "blue",
+				}, {
+					Name:       fixture.ServiceRootsHome.Name,
+					Port:       8080,
+					ServiceSet: "green",
+				}},
+			}},
+		},
+	}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&HTTPProxyProcessor{},
+			&ListenerProcessor{},
+		},
+	}
+	for _, o := range []interface{}{proxy, fixture.ServiceRootsKuard, fixture.ServiceRootsHome} {
+		builder.Source.Insert(o)
+	}
+
+	updates := builder.Build().StatusCache.GetProxyUpdates()
+	require.Len(t, updates, 1)
+	assert.Equal(t, []contour_api_v1.ServiceSetStatus{{
+		Route:    1,
+		Active:   "green",
+		Services: []string{"home"},
+	}}, updates[0].ServiceSets)
+}
+
+func TestDAGDomainSuffixStatus(t *testing.T) {
+	root := func(name, fqdn string, includes ...string) *contour_api_v1.HTTPProxy {
+		proxy := &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      name,
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{
+					Fqdn: fqdn,
+				},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+		for _, inc := range includes {
+			proxy.Spec.Includes = append(proxy.Spec.Includes, contour_api_v1.Include{
+				Name:       inc,
+				Conditions: []contour_api_v1.MatchCondition{{Prefix: "/" + inc}},
+			})
+		}
+		return proxy
+	}
+
+	leaf := func(name string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "roots",
+				Name:      name,
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&HTTPProxyProcessor{
+				DomainSuffixes: []string{"example.com"},
+			},
+			&ListenerProcessor{},
+		},
+	}
+	for _, o := range []interface{}{
+		root("owned", "a.example.com", "owned-child"),
+		root("foreign", "b.example.org", "foreign-child"),
+		leaf("owned-child"),
+		leaf("foreign-child"),
+		leaf("orphan"),
+		fixture.ServiceRootsKuard,
+	} {
+		builder.Source.Insert(o)
+	}
+
+	// Only the owned root, its include, and the orphan, which
+	// no root of any Contour instance includes, get a status.
+	var got []string
+	for _, update := range builder.Build().StatusCache.GetProxyUpdates() {
+		got = append(got, update.Fullname.Name)
+	}
+	assert.ElementsMatch(t, []string{"owned", "owned-child", "orphan"}, got)
+}
+
+func TestDAGNamespaceQuotaEvents(t *testing.T) {
+	ingress := func(name string, created int64, host string) *v1beta1.Ingress {
+		return &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "roots",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Unix(created, 0)),
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{{
+					Host: host,
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{{
+								Backend: v1beta1.IngressBackend{
+									ServiceName: fixture.ServiceRootsKuard.Name,
+									ServicePort: intstr.FromInt(8080),
+								},
+							}},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	proxy := &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "roots",
+			Name:              "proxy",
+			CreationTimestamp: metav1.NewTime(time.Unix(3, 0)),
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "proxy.example.com"},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: fixture.ServiceRootsKuard.Name,
+					Port: 8080,
+				}},
+			}},
+		},
+	}
+	first := ingress("first", 1, "first.example.com")
+	second := ingress("second", 2, "second.example.com")
+
+	quota := NamespaceQuota{MaxVirtualHosts: 1}
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&IngressProcessor{
+				FieldLogger:    fixture.NewTestLogger(t),
+				NamespaceQuota: quota,
+			},
+			&HTTPProxyProcessor{
+				NamespaceQuota: quota,
+			},
+			&ListenerProcessor{},
+		},
+	}
+	for _, o := range []interface{}{fixture.ServiceRootsKuard, first, second, proxy} {
+		builder.Source.Insert(o)
+	}
+	dag := builder.Build()
+
+	message := `namespace "roots" is limited to 1 virtual hosts`
+	assert.ElementsMatch(t, []Event{
+		{Object: second, Reason: "VirtualHostQuotaExceeded", Message: message},
+		{Object: proxy, Reason: "VirtualHostQuotaExceeded", Message: message},
+	}, dag.Events)
+	assert.Equal(t, map[RejectedObject]int{
+		{Kind: "Ingress", Namespace: "roots", Reason: "VirtualHostQuotaExceeded"}: 1,
+	}, dag.RejectedObjects)
+
+	// Only the Ingress within the quota has a virtual host.
+	assert.NotNil(t, dag.GetVirtualHost("first.example.com"))
+	assert.Nil(t, dag.GetVirtualHost("second.example.com"))
+}
+
+func TestDAGOverlappingVhostEvents(t *testing.T) {
+	proxy := func(name string, created int64, fqdn string) *contour_api_v1.HTTPProxy {
+		return &contour_api_v1.HTTPProxy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "roots",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Unix(created, 0)),
+			},
+			Spec: contour_api_v1.HTTPProxySpec{
+				VirtualHost: &contour_api_v1.VirtualHost{Fqdn: fqdn},
+				Routes: []contour_api_v1.Route{{
+					Services: []contour_api_v1.Service{{
+						Name: fixture.ServiceRootsKuard.Name,
+						Port: 8080,
+					}},
+				}},
+			},
+		}
+	}
+
+	older := proxy("older", 1, "example.com")
+	newer := proxy("newer", 2, "Example.com")
+
+	builder := Builder{
+		Source: KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []Processor{
+			&HTTPProxyProcessor{},
+			&ListenerProcessor{},
+		},
+	}
+	for _, o := range []interface{}{fixture.ServiceRootsKuard, older, newer} {
+		builder.Source.Insert(o)
+	}
+	dag := builder.Build()
+
+	// Only the newer HTTPProxy is dropped, and the event
+	// names the HTTPProxy that claimed the fqdn first.
+	assert.Equal(t, []Event{{
+		Object:  newer,
+		Reason:  "OverlappingVhost",
+		Message: `fqdn "Example.com" overlaps with fqdn "example.com" of HTTPProxy roots/older`,
+	}}, dag.Events)
 }
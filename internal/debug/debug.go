@@ -16,11 +16,21 @@
 package debug
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/httpsvc"
+	"github.com/projectcontour/contour/internal/xds"
+	"github.com/sirupsen/logrus"
 )
 
 // Service serves various http endpoints including /debug/pprof.
@@ -28,6 +38,20 @@ type Service struct {
 	httpsvc.Service
 
 	Builder *dag.Builder
+
+	// Streams, if not nil, is served as JSON at
+	// /debug/xds/streams and /debug/xds/nacks.
+	Streams *xds.StreamTracker
+
+	// Resync, if not nil, is called for each POST to /debug/resync
+	// to list every resource from the API server again and push the
+	// resulting configuration to all connected Envoys.
+	Resync func(ctx context.Context) error
+
+	// Pauser, if not nil, is paused by each POST to
+	// /debug/xds/pause and resumed by each POST to
+	// /debug/xds/resume.
+	Pauser *xds.Pauser
 }
 
 // Start fulfills the g.Start contract.
@@ -35,6 +59,15 @@ type Service struct {
 func (svc *Service) Start(stop <-chan struct{}) error {
 	registerProfile(&svc.ServeMux)
 	registerDotWriter(&svc.ServeMux, svc.Builder)
+	if svc.Streams != nil {
+		registerStreams(&svc.ServeMux, svc.Streams)
+	}
+	if svc.Resync != nil {
+		registerResync(&svc.ServeMux, svc.Resync)
+	}
+	if svc.Pauser != nil {
+		registerPauser(&svc.ServeMux, svc.Pauser, svc.FieldLogger)
+	}
 	return svc.Service.Start(stop)
 }
 
@@ -51,10 +84,153 @@ func registerProfile(mux *http.ServeMux) {
 }
 
 func registerDotWriter(mux *http.ServeMux, builder *dag.Builder) {
-	mux.HandleFunc("/debug/dag", func(w http.ResponseWriter, r *http.Request) {
-		dw := &dotWriter{
-			Builder: builder,
+	mux.HandleFunc("/debug/dag", gzipped(func(w http.ResponseWriter, r *http.Request) {
+		root := builder.Build()
+		query := r.URL.Query()
+
+		dw := &dotWriter{}
+		if hosts := query["host"]; len(hosts) > 0 {
+			dw.filters = append(dw.filters, matchHosts(hosts...))
+		}
+		if ns := query.Get("namespace"); ns != "" {
+			dw.filters = append(dw.filters, matchNamespace(ns))
+		}
+
+		// The limit and offset parameters page through
+		// the virtual hosts, in order of their names.
+		if query.Get("limit") != "" || query.Get("offset") != "" {
+			limit, err := queryInt(query, "limit", 100)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			offset, err := queryInt(query, "offset", 0)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			hosts := hostNames(root)
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(hosts)))
+			if offset > len(hosts) {
+				offset = len(hosts)
+			}
+			if limit > len(hosts)-offset {
+				limit = len(hosts) - offset
+			}
+			dw.filters = append(dw.filters, matchHosts(hosts[offset:offset+limit]...))
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		dw.writeDot(w, root)
+	}))
+}
+
+// queryInt returns the value of the named query parameter as a
+// non-negative integer, or def if the parameter is not set.
+func queryInt(query url.Values, name string, def int) (int, error) {
+	v := query.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, v)
+	}
+	return n, nil
+}
+
+func registerStreams(mux *http.ServeMux, streams *xds.StreamTracker) {
+	mux.HandleFunc("/debug/xds/streams", gzipped(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, streams.Streams())
+	}))
+	mux.HandleFunc("/debug/xds/nacks", gzipped(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, streams.NACKs())
+	}))
+}
+
+func registerResync(mux *http.ServeMux, resync func(ctx context.Context) error) {
+	mux.HandleFunc("/debug/resync", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := resync(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		dw.writeDot(w)
+		w.WriteHeader(http.StatusAccepted)
 	})
 }
+
+func registerPauser(mux *http.ServeMux, pauser *xds.Pauser, log logrus.FieldLogger) {
+	handle := func(path, msg string, f func()) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			f()
+			log.Info(msg)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	handle("/debug/xds/pause", "paused xDS pushes", pauser.Pause)
+	handle("/debug/xds/resume", "resumed xDS pushes", pauser.Resume)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// gzipped compresses the responses of h with gzip
+// for the requests that accept it.
+func gzipped(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			h(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+
+		h(&gzipResponseWriter{ResponseWriter: w, Writer: gz}, r)
+	}
+}
+
+// acceptsGzip returns true if the request's Accept-Encoding
+// header lists gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.TrimSpace(enc)
+			if i := strings.IndexByte(enc, ';'); i >= 0 {
+				enc = strings.TrimSpace(enc[:i])
+			}
+			if enc == "gzip" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	io.Writer
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
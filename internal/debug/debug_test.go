@@ -0,0 +1,158 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestDotWriterFilters(t *testing.T) {
+	builder := &dag.Builder{
+		Source: dag.KubernetesCache{
+			FieldLogger: fixture.NewTestLogger(t),
+		},
+		Processors: []dag.Processor{
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{},
+		},
+	}
+
+	for _, name := range []string{"team-a/a", "team-b/b", "team-b/c"} {
+		ns := strings.Split(name, "/")[0]
+		host := strings.Split(name, "/")[1] + ".example.com"
+
+		builder.Source.Insert(fixture.NewService(ns + "/backend").
+			WithPorts(v1.ServicePort{Port: 80}))
+		builder.Source.Insert(fixture.NewProxy(name).WithSpec(contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{Fqdn: host},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{Name: "backend", Port: 80}},
+			}},
+		}))
+	}
+
+	mux := http.NewServeMux()
+	registerDotWriter(mux, builder)
+
+	get := func(target string, header http.Header) *http.Response {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	body := func(target string) string {
+		t.Helper()
+
+		resp := get(target, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	all := body("/debug/dag")
+	for _, s := range []string{"http://a.example.com", "http://b.example.com", "http://c.example.com", "service|team-a/backend:80", "service|team-b/backend:80"} {
+		assert.Contains(t, all, s)
+	}
+
+	host := body("/debug/dag?host=b.example.com")
+	assert.Contains(t, host, "listener|:80")
+	assert.Contains(t, host, "http://b.example.com")
+	assert.Contains(t, host, "service|team-b/backend:80")
+	assert.NotContains(t, host, "http://a.example.com")
+	assert.NotContains(t, host, "http://c.example.com")
+
+	ns := body("/debug/dag?namespace=team-a")
+	assert.Contains(t, ns, "http://a.example.com")
+	assert.Contains(t, ns, "service|team-a/backend:80")
+	assert.NotContains(t, ns, "http://b.example.com")
+	assert.NotContains(t, ns, "service|team-b/backend:80")
+
+	assert.Contains(t, body("/debug/dag?host=a.example.com&namespace=team-b"), "digraph DAG")
+	assert.NotContains(t, body("/debug/dag?host=a.example.com&namespace=team-b"), "example.com")
+
+	resp := get("/debug/dag?limit=2&offset=1", nil)
+	assert.Equal(t, "3", resp.Header.Get("X-Total-Count"))
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "http://a.example.com")
+	assert.Contains(t, string(b), "http://b.example.com")
+	assert.Contains(t, string(b), "http://c.example.com")
+
+	assert.NotContains(t, body("/debug/dag?offset=3"), "example.com")
+	assert.Equal(t, http.StatusBadRequest, get("/debug/dag?limit=-1", nil).StatusCode)
+
+	resp = get("/debug/dag?host=a.example.com", http.Header{"Accept-Encoding": {"deflate, gzip;q=0.8"}})
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	gz, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	b, err = ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "http://a.example.com")
+	assert.NotContains(t, string(b), "http://b.example.com")
+}
+
+func TestResync(t *testing.T) {
+	var calls int
+	var err error
+
+	mux := http.NewServeMux()
+	registerResync(mux, func(context.Context) error {
+		calls++
+		return err
+	})
+
+	do := func(method string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/debug/resync", nil))
+		return rec
+	}
+
+	rec := do(http.MethodGet)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+	assert.Equal(t, 0, calls)
+
+	rec = do(http.MethodPost)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, 1, calls)
+
+	err = errors.New("error listing secrets: forbidden")
+	rec = do(http.MethodPost)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "error listing secrets: forbidden")
+	assert.Equal(t, 2, calls)
+}
@@ -16,6 +16,7 @@ package debug
 import (
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
@@ -24,7 +25,9 @@ import (
 // quick and dirty dot debugging package
 
 type dotWriter struct {
-	*dag.Builder
+	// filters, if any, restrict the graph to the vertices that
+	// every filter keeps. See keep for the vertices a filter keeps.
+	filters []func(dag.Vertex) bool
 }
 
 type pair struct {
@@ -71,7 +74,7 @@ func (c *ctx) writeEdge(parent, child dag.Vertex) {
 	fmt.Fprintf(c.w, `"%p" -> "%p"`+"\n", parent, child)
 }
 
-func (dw *dotWriter) writeDot(w io.Writer) {
+func (dw *dotWriter) writeDot(w io.Writer, root *dag.DAG) {
 	fmt.Fprintln(w, "digraph DAG {\nrankdir=\"LR\"")
 
 	ctx := &ctx{
@@ -80,16 +83,127 @@ func (dw *dotWriter) writeDot(w io.Writer) {
 		edges: make(map[pair]bool),
 	}
 
+	// included returns true if every filter keeps v.
+	var kept []map[dag.Vertex]bool
+	for _, f := range dw.filters {
+		kept = append(kept, keep(root, f))
+	}
+	included := func(v dag.Vertex) bool {
+		for _, k := range kept {
+			if !k[v] {
+				return false
+			}
+		}
+		return true
+	}
+
 	var visit func(dag.Vertex)
 	visit = func(parent dag.Vertex) {
 		ctx.writeVertex(parent)
 		parent.Visit(func(child dag.Vertex) {
+			if !included(child) {
+				return
+			}
 			visit(child)
 			ctx.writeEdge(parent, child)
 		})
 	}
 
-	dw.Builder.Build().Visit(visit)
+	root.Visit(func(v dag.Vertex) {
+		if included(v) {
+			visit(v)
+		}
+	})
 
 	fmt.Fprintln(w, "}")
 }
+
+// keep returns the vertices of the graph that match, together
+// with their ancestors, so that the path to a match is shown,
+// and their descendants, so that everything under a match is.
+func keep(root *dag.DAG, match func(dag.Vertex) bool) map[dag.Vertex]bool {
+	kept := make(map[dag.Vertex]bool)
+
+	// visit returns true if v is kept.
+	var visit func(v dag.Vertex, matched bool) bool
+	visit = func(v dag.Vertex, matched bool) bool {
+		matched = matched || match(v)
+		found := matched
+		v.Visit(func(child dag.Vertex) {
+			if visit(child, matched) {
+				found = true
+			}
+		})
+		if found {
+			kept[v] = true
+		}
+		return found
+	}
+
+	root.Visit(func(v dag.Vertex) {
+		visit(v, false)
+	})
+
+	return kept
+}
+
+// hostNames returns the sorted names of the HTTP and
+// HTTPS virtual hosts of the graph.
+func hostNames(root *dag.DAG) []string {
+	names := make(map[string]bool)
+
+	var visit func(dag.Vertex)
+	visit = func(v dag.Vertex) {
+		switch v := v.(type) {
+		case *dag.VirtualHost:
+			names[v.Name] = true
+		case *dag.SecureVirtualHost:
+			names[v.VirtualHost.Name] = true
+		default:
+			v.Visit(visit)
+		}
+	}
+	root.Visit(visit)
+
+	var hosts []string
+	for name := range names {
+		hosts = append(hosts, name)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// matchHosts returns a filter that matches the HTTP and
+// HTTPS virtual hosts with any of the given names.
+func matchHosts(hosts ...string) func(dag.Vertex) bool {
+	names := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		names[h] = true
+	}
+
+	return func(v dag.Vertex) bool {
+		switch v := v.(type) {
+		case *dag.VirtualHost:
+			return names[v.Name]
+		case *dag.SecureVirtualHost:
+			return names[v.VirtualHost.Name]
+		default:
+			return false
+		}
+	}
+}
+
+// matchNamespace returns a filter that matches the
+// Services and Secrets in the given namespace.
+func matchNamespace(namespace string) func(dag.Vertex) bool {
+	return func(v dag.Vertex) bool {
+		switch v := v.(type) {
+		case *dag.Service:
+			return v.Weighted.ServiceNamespace == namespace
+		case *dag.Secret:
+			return v.Namespace() == namespace
+		default:
+			return false
+		}
+	}
+}
@@ -34,6 +34,12 @@ const SDSTLSCertificateFile = "xds-tls-certificate.json"
 // CA certificates for Envoy to use for the XDS gRPC connection.
 const SDSValidationContextFile = "xds-validation-context.json"
 
+// StatsSinkType is the name of a supported Envoy stats sink.
+type StatsSinkType string
+
+const StatsdStatsSink StatsSinkType = "statsd"
+const DogStatsdStatsSink StatsSinkType = "dogstatsd"
+
 // BootstrapConfig holds configuration values for a Bootstrap configuration.
 type BootstrapConfig struct {
 	// AdminAccessLogPath is the path to write the access log for the administration server.
@@ -48,6 +54,15 @@ type BootstrapConfig struct {
 	// Defaults to 9001.
 	AdminPort int
 
+	// AdminSocketPath is the Unix domain socket path that the administration
+	// server will listen on. If set, AdminAddress and AdminPort are not used
+	// for the administration server.
+	AdminSocketPath string
+
+	// AdminDisabled omits the administration server from the
+	// bootstrap configuration.
+	AdminDisabled bool
+
 	// XDSAddress is the TCP address of the gRPC XDS management server.
 	// Defaults to 127.0.0.1.
 	XDSAddress string
@@ -56,6 +71,53 @@ type BootstrapConfig struct {
 	// Defaults to 8001.
 	XDSGRPCPort int
 
+	// StatsSink is the type of stats sink that Envoy flushes
+	// statistics to. If empty, no stats sink is configured.
+	StatsSink StatsSinkType
+
+	// StatsSinkAddress is the UDP address of the stats sink.
+	// Defaults to 127.0.0.1.
+	StatsSinkAddress string
+
+	// StatsSinkPort is the UDP port of the stats sink.
+	// Defaults to 8125.
+	StatsSinkPort int
+
+	// StatsSinkPrefix is the prefix that the stats sink adds to
+	// statistic names. Defaults to the Envoy default for the sink.
+	StatsSinkPrefix string
+
+	// StatsTags are fixed tags that are added to all statistics.
+	StatsTags map[string]string
+
+	// OverloadMaxHeapBytes is the maximum heap size of the Envoy
+	// process. If set, the overload manager shrinks the heap as
+	// usage approaches the limit, and stops accepting requests
+	// when usage reaches it.
+	OverloadMaxHeapBytes uint64
+
+	// OverloadMaxDownstreamConnections is the maximum number of
+	// active downstream connections across all listeners. If zero,
+	// the number of connections is not limited.
+	OverloadMaxDownstreamConnections uint64
+
+	// ListenerMaxConnections is the maximum number of active
+	// connections to each listener, by listener name.
+	ListenerMaxConnections map[string]uint64
+
+	// SPIREAgentSocketPath is the path of the SPIRE agent's SDS
+	// Unix domain socket. If set, a static cluster for it is
+	// added, so that Envoy can fetch SPIFFE identities from it.
+	SPIREAgentSocketPath string
+
+	// LocalityRegion and LocalityZone are the locality of the
+	// Envoy node. If either is set, Envoy is configured with a
+	// local cluster of the Envoy Service's endpoints, so that
+	// zone aware routing prefers upstream endpoints in the same
+	// zone as the node.
+	LocalityRegion string
+	LocalityZone   string
+
 	// XDSResourceVersion defines the XDS Server Version to use.
 	// Defaults to "v3"
 	XDSResourceVersion config.ResourceVersion
@@ -95,6 +157,10 @@ func (c *BootstrapConfig) GetAdminPort() int { return intOrDefault(c.AdminPort,
 func (c *BootstrapConfig) GetAdminAccessLogPath() string {
 	return stringOrDefault(c.AdminAccessLogPath, "/dev/null")
 }
+func (c *BootstrapConfig) GetStatsSinkAddress() string {
+	return stringOrDefault(c.StatsSinkAddress, "127.0.0.1")
+}
+func (c *BootstrapConfig) GetStatsSinkPort() int { return intOrDefault(c.StatsSinkPort, 8125) }
 
 func stringOrDefault(s, def string) string {
 	if s == "" {
@@ -27,6 +27,13 @@ import (
 func Clustername(cluster *dag.Cluster) string {
 	service := cluster.Upstream
 	buf := cluster.LoadBalancerPolicy
+	if rh := cluster.RingHashConfig; rh != nil {
+		buf += strconv.FormatUint(rh.MinimumRingSize, 10)
+		buf += strconv.FormatUint(rh.MaximumRingSize, 10)
+	}
+	if mc := cluster.MaglevConfig; mc != nil {
+		buf += strconv.FormatUint(mc.TableSize, 10)
+	}
 	if hc := cluster.HTTPHealthCheckPolicy; hc != nil {
 		if hc.Timeout > 0 {
 			buf += hc.Timeout.String()
@@ -42,10 +49,38 @@ func Clustername(cluster *dag.Cluster) string {
 		}
 		buf += hc.Path
 	}
+	if hc := cluster.GRPCHealthCheckPolicy; hc != nil {
+		if hc.Timeout > 0 {
+			buf += hc.Timeout.String()
+		}
+		if hc.Interval > 0 {
+			buf += hc.Interval.String()
+		}
+		if hc.UnhealthyThreshold > 0 {
+			buf += strconv.Itoa(int(hc.UnhealthyThreshold))
+		}
+		if hc.HealthyThreshold > 0 {
+			buf += strconv.Itoa(int(hc.HealthyThreshold))
+		}
+		buf += "grpc" + hc.ServiceName + hc.Authority
+	}
+	if service.External {
+		buf += service.ExternalName
+	}
 	if uv := cluster.UpstreamValidation; uv != nil {
 		buf += uv.CACertificate.Object.ObjectMeta.Name
 		buf += uv.SubjectName
 	}
+	if ut := cluster.UpstreamTLS; ut != nil {
+		buf += ut.SNI
+		if ut.ClientCertificate != nil {
+			buf += ut.ClientCertificate.Namespace() + "/" + ut.ClientCertificate.Name()
+		}
+	}
+	if rb := cluster.RetryBudget; rb != nil {
+		buf += strconv.FormatUint(uint64(rb.BudgetPercent), 10)
+		buf += strconv.FormatUint(uint64(rb.MinRetryConcurrency), 10)
+	}
 
 	// This isn't a crypto hash, we just want a unique name.
 	hash := sha1.Sum([]byte(buf)) // nolint:gosec
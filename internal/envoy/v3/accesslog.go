@@ -14,12 +14,22 @@
 package v3
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	envoy_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_file_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	envoy_grpc_als_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	envoy_type_v3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/projectcontour/contour/pkg/config"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // FileAccessLogEnvoy returns a new file based access log filter
@@ -36,6 +46,48 @@ func FileAccessLogEnvoy(path string) []*envoy_accesslog_v3.AccessLog {
 	}}
 }
 
+// envoyDefaultLogFormat is Envoy's default access log format.
+// See https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log/usage#default-format-string
+const envoyDefaultLogFormat = `[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" ` +
+	`%RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% ` +
+	`"%REQ(X-FORWARDED-FOR)%" "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"`
+
+// FileAccessLogEnvoyWithHeaders returns a new file based access log
+// filter that will output Envoy's default access log format followed
+// by the values of the given request and response headers, each
+// quoted. If no headers are given, Envoy's default format is used.
+func FileAccessLogEnvoyWithHeaders(path string, headers config.AccessLogHeaderParameters) []*envoy_accesslog_v3.AccessLog {
+	if len(headers.RequestHeaders) == 0 && len(headers.ResponseHeaders) == 0 {
+		return FileAccessLogEnvoy(path)
+	}
+
+	var format strings.Builder
+	format.WriteString(envoyDefaultLogFormat)
+	for _, h := range headers.RequestHeaders {
+		fmt.Fprintf(&format, ` "%%REQ(%s)%%"`, h)
+	}
+	for _, h := range headers.ResponseHeaders {
+		fmt.Fprintf(&format, ` "%%RESP(%s)%%"`, h)
+	}
+	format.WriteString("\n")
+
+	return []*envoy_accesslog_v3.AccessLog{{
+		Name: wellknown.FileAccessLog,
+		ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_file_v3.FileAccessLog{
+				Path: path,
+				AccessLogFormat: &envoy_file_v3.FileAccessLog_LogFormat{
+					LogFormat: &envoy_config_core_v3.SubstitutionFormatString{
+						Format: &envoy_config_core_v3.SubstitutionFormatString_TextFormat{
+							TextFormat: format.String(),
+						},
+					},
+				},
+			}),
+		},
+	}}
+}
+
 // FileAccessLogJSON returns a new file based access log filter
 // that will log in JSON format
 func FileAccessLogJSON(path string, fields config.AccessLogFields) []*envoy_accesslog_v3.AccessLog {
@@ -61,6 +113,143 @@ func FileAccessLogJSON(path string, fields config.AccessLogFields) []*envoy_acce
 	}}
 }
 
+// GRPCAccessLog returns a new access log filter that will send HTTP
+// access log entries to the gRPC access log service defined by the
+// extension service in params, or nil if no extension service is
+// configured. If params has no log name, name is used.
+func GRPCAccessLog(name string, params config.AccessLogGRPCParameters) []*envoy_accesslog_v3.AccessLog {
+	if params.ExtensionService.Name == "" {
+		return nil
+	}
+
+	logName := params.LogName
+	if logName == "" {
+		logName = name
+	}
+
+	return []*envoy_accesslog_v3.AccessLog{{
+		Name: wellknown.HTTPGRPCAccessLog,
+		ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_grpc_als_v3.HttpGrpcAccessLogConfig{
+				CommonConfig: &envoy_grpc_als_v3.CommonGrpcAccessLogConfig{
+					LogName: logName,
+					GrpcService: &envoy_config_core_v3.GrpcService{
+						TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+							EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+								ClusterName: dag.ExtensionClusterName(types.NamespacedName{
+									Namespace: params.ExtensionService.Namespace,
+									Name:      params.ExtensionService.Name,
+								}),
+							},
+						},
+					},
+					TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+				},
+				AdditionalRequestHeadersToLog:  params.Headers.RequestHeaders,
+				AdditionalResponseHeadersToLog: params.Headers.ResponseHeaders,
+			}),
+		},
+	}}
+}
+
+// AccessLogFilter returns an access log filter that restricts logging
+// to error responses, optionally with a sample of successful responses,
+// or nil if the parameters do not filter any requests.
+func AccessLogFilter(params config.AccessLogFilterParameters) *envoy_accesslog_v3.AccessLogFilter {
+	errorsFilter := &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_StatusCodeFilter{
+			StatusCodeFilter: &envoy_accesslog_v3.StatusCodeFilter{
+				Comparison: &envoy_accesslog_v3.ComparisonFilter{
+					Op: envoy_accesslog_v3.ComparisonFilter_GE,
+					Value: &envoy_config_core_v3.RuntimeUInt32{
+						DefaultValue: 400,
+						RuntimeKey:   "contour.access_log.min_status_code",
+					},
+				},
+			},
+		},
+	}
+
+	if params.SuccessSamplePercent == 0 {
+		if params.ErrorsOnly {
+			return errorsFilter
+		}
+		return nil
+	}
+
+	return &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_OrFilter{
+			OrFilter: &envoy_accesslog_v3.OrFilter{
+				Filters: []*envoy_accesslog_v3.AccessLogFilter{
+					errorsFilter,
+					{
+						FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_RuntimeFilter{
+							RuntimeFilter: &envoy_accesslog_v3.RuntimeFilter{
+								RuntimeKey: "contour.access_log.success_sample_percent",
+								PercentSampled: &envoy_type_v3.FractionalPercent{
+									Numerator:   params.SuccessSamplePercent,
+									Denominator: envoy_type_v3.FractionalPercent_HUNDRED,
+								},
+								UseIndependentRandomness: true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ExcludePathsAccessLogFilter returns an access log filter that
+// skips requests for any of the given paths, whatever their query
+// string, combined with filter. If there are no paths, filter is
+// returned unchanged.
+func ExcludePathsAccessLogFilter(paths []string, filter *envoy_accesslog_v3.AccessLogFilter) *envoy_accesslog_v3.AccessLogFilter {
+	if len(paths) == 0 {
+		return filter
+	}
+
+	var quoted []string
+	for _, p := range paths {
+		quoted = append(quoted, regexp.QuoteMeta(p))
+	}
+
+	excluded := &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_HeaderFilter{
+			HeaderFilter: &envoy_accesslog_v3.HeaderFilter{
+				Header: &envoy_route_v3.HeaderMatcher{
+					Name: ":path",
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: SafeRegexMatch(`^(?:` + strings.Join(quoted, "|") + `)(?:\?.*)?$`),
+					},
+					InvertMatch: true,
+				},
+			},
+		},
+	}
+
+	if filter == nil {
+		return excluded
+	}
+
+	return &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_AndFilter{
+			AndFilter: &envoy_accesslog_v3.AndFilter{
+				Filters: []*envoy_accesslog_v3.AccessLogFilter{excluded, filter},
+			},
+		},
+	}
+}
+
+// FilterAccessLogs sets the filter on each of the access logs, and
+// returns them.
+func FilterAccessLogs(logs []*envoy_accesslog_v3.AccessLog, filter *envoy_accesslog_v3.AccessLogFilter) []*envoy_accesslog_v3.AccessLog {
+	for _, log := range logs {
+		log.Filter = filter
+	}
+	return logs
+}
+
 func sv(s string) *_struct.Value {
 	return &_struct.Value{
 		Kind: &_struct.Value_StringValue{
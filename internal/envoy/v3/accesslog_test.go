@@ -17,7 +17,11 @@ import (
 	"testing"
 
 	envoy_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_file_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	envoy_grpc_als_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	envoy_type_v3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	_struct "github.com/golang/protobuf/ptypes/struct"
 	"github.com/projectcontour/contour/internal/protobuf"
@@ -49,6 +53,48 @@ func TestFileAccessLog(t *testing.T) {
 	}
 }
 
+func TestFileAccessLogWithHeaders(t *testing.T) {
+	tests := map[string]struct {
+		headers config.AccessLogHeaderParameters
+		want    []*envoy_accesslog_v3.AccessLog
+	}{
+		"no headers": {
+			headers: config.AccessLogHeaderParameters{},
+			want:    FileAccessLogEnvoy("/dev/stdout"),
+		},
+		"request and response headers": {
+			headers: config.AccessLogHeaderParameters{
+				RequestHeaders:  []string{"X-Tenant", "Referer"},
+				ResponseHeaders: []string{"Content-Type"},
+			},
+			want: []*envoy_accesslog_v3.AccessLog{{
+				Name: wellknown.FileAccessLog,
+				ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_file_v3.FileAccessLog{
+						Path: "/dev/stdout",
+						AccessLogFormat: &envoy_file_v3.FileAccessLog_LogFormat{
+							LogFormat: &envoy_config_core_v3.SubstitutionFormatString{
+								Format: &envoy_config_core_v3.SubstitutionFormatString_TextFormat{
+									TextFormat: `[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" ` +
+										`%RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% ` +
+										`"%REQ(X-FORWARDED-FOR)%" "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%" ` +
+										`"%REQ(X-Tenant)%" "%REQ(Referer)%" "%RESP(Content-Type)%"` + "\n",
+								},
+							},
+						},
+					}),
+				},
+			}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := FileAccessLogEnvoyWithHeaders("/dev/stdout", tc.headers)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
+
 func TestJSONFileAccessLog(t *testing.T) {
 	tests := map[string]struct {
 		path    string
@@ -113,3 +159,202 @@ func TestJSONFileAccessLog(t *testing.T) {
 		})
 	}
 }
+
+func TestGRPCAccessLog(t *testing.T) {
+	tests := map[string]struct {
+		params config.AccessLogGRPCParameters
+		want   []*envoy_accesslog_v3.AccessLog
+	}{
+		"no extension service": {
+			params: config.AccessLogGRPCParameters{},
+			want:   nil,
+		},
+		"default log name": {
+			params: config.AccessLogGRPCParameters{
+				ExtensionService: config.NamespacedName{
+					Namespace: "projectcontour",
+					Name:      "als",
+				},
+			},
+			want: []*envoy_accesslog_v3.AccessLog{{
+				Name: wellknown.HTTPGRPCAccessLog,
+				ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_grpc_als_v3.HttpGrpcAccessLogConfig{
+						CommonConfig: &envoy_grpc_als_v3.CommonGrpcAccessLogConfig{
+							LogName: "ingress_http",
+							GrpcService: &envoy_config_core_v3.GrpcService{
+								TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+										ClusterName: "extension/projectcontour/als",
+									},
+								},
+							},
+							TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+						},
+					}),
+				},
+			}},
+		},
+		"log name and headers": {
+			params: config.AccessLogGRPCParameters{
+				ExtensionService: config.NamespacedName{
+					Namespace: "projectcontour",
+					Name:      "als",
+				},
+				LogName: "edge",
+				Headers: config.AccessLogHeaderParameters{
+					RequestHeaders:  []string{"X-Tenant"},
+					ResponseHeaders: []string{"Content-Type"},
+				},
+			},
+			want: []*envoy_accesslog_v3.AccessLog{{
+				Name: wellknown.HTTPGRPCAccessLog,
+				ConfigType: &envoy_accesslog_v3.AccessLog_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_grpc_als_v3.HttpGrpcAccessLogConfig{
+						CommonConfig: &envoy_grpc_als_v3.CommonGrpcAccessLogConfig{
+							LogName: "edge",
+							GrpcService: &envoy_config_core_v3.GrpcService{
+								TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+										ClusterName: "extension/projectcontour/als",
+									},
+								},
+							},
+							TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+						},
+						AdditionalRequestHeadersToLog:  []string{"X-Tenant"},
+						AdditionalResponseHeadersToLog: []string{"Content-Type"},
+					}),
+				},
+			}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := GRPCAccessLog("ingress_http", tc.params)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	errorsFilter := &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_StatusCodeFilter{
+			StatusCodeFilter: &envoy_accesslog_v3.StatusCodeFilter{
+				Comparison: &envoy_accesslog_v3.ComparisonFilter{
+					Op: envoy_accesslog_v3.ComparisonFilter_GE,
+					Value: &envoy_config_core_v3.RuntimeUInt32{
+						DefaultValue: 400,
+						RuntimeKey:   "contour.access_log.min_status_code",
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		params config.AccessLogFilterParameters
+		want   *envoy_accesslog_v3.AccessLogFilter
+	}{
+		"no filtering": {
+			params: config.AccessLogFilterParameters{},
+			want:   nil,
+		},
+		"errors only": {
+			params: config.AccessLogFilterParameters{ErrorsOnly: true},
+			want:   errorsFilter,
+		},
+		"errors and sampled successes": {
+			params: config.AccessLogFilterParameters{SuccessSamplePercent: 10},
+			want: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_OrFilter{
+					OrFilter: &envoy_accesslog_v3.OrFilter{
+						Filters: []*envoy_accesslog_v3.AccessLogFilter{
+							errorsFilter,
+							{
+								FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_RuntimeFilter{
+									RuntimeFilter: &envoy_accesslog_v3.RuntimeFilter{
+										RuntimeKey: "contour.access_log.success_sample_percent",
+										PercentSampled: &envoy_type_v3.FractionalPercent{
+											Numerator:   10,
+											Denominator: envoy_type_v3.FractionalPercent_HUNDRED,
+										},
+										UseIndependentRandomness: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := AccessLogFilter(tc.params)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
+
+func TestFilterAccessLogs(t *testing.T) {
+	filter := AccessLogFilter(config.AccessLogFilterParameters{ErrorsOnly: true})
+
+	want := FileAccessLogEnvoy("/dev/stdout")
+	want[0].Filter = filter
+
+	got := FilterAccessLogs(FileAccessLogEnvoy("/dev/stdout"), filter)
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestExcludePathsAccessLogFilter(t *testing.T) {
+	errorsFilter := AccessLogFilter(config.AccessLogFilterParameters{ErrorsOnly: true})
+
+	excluded := &envoy_accesslog_v3.AccessLogFilter{
+		FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_HeaderFilter{
+			HeaderFilter: &envoy_accesslog_v3.HeaderFilter{
+				Header: &envoy_route_v3.HeaderMatcher{
+					Name: ":path",
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: SafeRegexMatch(`^(?:/healthz|/ready\.json)(?:\?.*)?$`),
+					},
+					InvertMatch: true,
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		paths  []string
+		filter *envoy_accesslog_v3.AccessLogFilter
+		want   *envoy_accesslog_v3.AccessLogFilter
+	}{
+		"no paths": {
+			paths:  nil,
+			filter: errorsFilter,
+			want:   errorsFilter,
+		},
+		"paths without a filter": {
+			paths:  []string{"/healthz", "/ready.json"},
+			filter: nil,
+			want:   excluded,
+		},
+		"paths with a filter": {
+			paths:  []string{"/healthz", "/ready.json"},
+			filter: errorsFilter,
+			want: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_AndFilter{
+					AndFilter: &envoy_accesslog_v3.AndFilter{
+						Filters: []*envoy_accesslog_v3.AccessLogFilter{excluded, errorsFilter},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ExcludePathsAccessLogFilter(tc.paths, tc.filter)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
@@ -81,6 +81,15 @@ func validationContext(ca []byte, subjectName string) *envoy_v3_tls.CommonTlsCon
 	return vc
 }
 
+// TLSCertificateSdsSecretConfig returns a SDS secret config
+// that fetches the TLS certificate in the given secret.
+func TLSCertificateSdsSecretConfig(secret *dag.Secret) *envoy_v3_tls.SdsSecretConfig {
+	return &envoy_v3_tls.SdsSecretConfig{
+		Name:      envoy.Secretname(secret),
+		SdsConfig: ConfigSource("contour"),
+	}
+}
+
 // DownstreamTLSContext creates a new DownstreamTlsContext.
 func DownstreamTLSContext(serverSecret *dag.Secret, tlsMinProtoVersion envoy_v3_tls.TlsParameters_TlsProtocol, peerValidationContext *dag.PeerValidationContext, alpnProtos ...string) *envoy_v3_tls.DownstreamTlsContext {
 	context := &envoy_v3_tls.DownstreamTlsContext{
@@ -90,10 +99,9 @@ func DownstreamTLSContext(serverSecret *dag.Secret, tlsMinProtoVersion envoy_v3_
 				TlsMaximumProtocolVersion: envoy_v3_tls.TlsParameters_TLSv1_3,
 				CipherSuites:              envoy.Ciphers,
 			},
-			TlsCertificateSdsSecretConfigs: []*envoy_v3_tls.SdsSecretConfig{{
-				Name:      envoy.Secretname(serverSecret),
-				SdsConfig: ConfigSource("contour"),
-			}},
+			TlsCertificateSdsSecretConfigs: []*envoy_v3_tls.SdsSecretConfig{
+				TLSCertificateSdsSecretConfig(serverSecret),
+			},
 			AlpnProtocols: alpnProtos,
 		},
 	}
@@ -101,10 +109,43 @@ func DownstreamTLSContext(serverSecret *dag.Secret, tlsMinProtoVersion envoy_v3_
 	if peerValidationContext.GetCACertificate() != nil {
 		vc := validationContext(peerValidationContext.GetCACertificate(), "")
 		if vc != nil {
+			if crl := peerValidationContext.GetCRL(); len(crl) > 0 {
+				vc.ValidationContext.Crl = &envoy_api_v3_core.DataSource{
+					Specifier: &envoy_api_v3_core.DataSource_InlineBytes{
+						InlineBytes: crl,
+					},
+				}
+			}
 			context.CommonTlsContext.ValidationContextType = vc
-			context.RequireClientCertificate = protobuf.Bool(true)
+			context.RequireClientCertificate = protobuf.Bool(!peerValidationContext.OptionalClientCertificate)
 		}
 	}
 
 	return context
 }
+
+// SessionTicketKeysSdsSecretConfig returns the downstream TLS
+// session ticket keys config that fetches the keys in the given
+// secret over SDS, so that the keys can be rotated without
+// updating the listener.
+func SessionTicketKeysSdsSecretConfig(secret *dag.Secret) *envoy_v3_tls.DownstreamTlsContext_SessionTicketKeysSdsSecretConfig {
+	return &envoy_v3_tls.DownstreamTlsContext_SessionTicketKeysSdsSecretConfig{
+		SessionTicketKeysSdsSecretConfig: &envoy_v3_tls.SdsSecretConfig{
+			Name:      envoy.Secretname(secret),
+			SdsConfig: ConfigSource("contour"),
+		},
+	}
+}
+
+// OCSPStaplePolicy returns the Envoy OCSP staple policy
+// for the given policy.
+func OCSPStaplePolicy(policy dag.OCSPStaplePolicy) envoy_v3_tls.DownstreamTlsContext_OcspStaplePolicy {
+	switch policy {
+	case dag.OCSPStrictStapling:
+		return envoy_v3_tls.DownstreamTlsContext_STRICT_STAPLING
+	case dag.OCSPMustStaple:
+		return envoy_v3_tls.DownstreamTlsContext_MUST_STAPLE
+	default:
+		return envoy_v3_tls.DownstreamTlsContext_LENIENT_STAPLING
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -28,13 +29,18 @@ import (
 	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_metrics_v3 "github.com/envoyproxy/go-control-plane/envoy/config/metrics/v3"
+	envoy_overload_v3 "github.com/envoyproxy/go-control-plane/envoy/config/overload/v3"
+	envoy_fixed_heap_v2alpha "github.com/envoyproxy/go-control-plane/envoy/config/resource_monitor/fixed_heap/v2alpha"
 	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	envoy_matcher_v3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/projectcontour/contour/internal/envoy"
 	"github.com/projectcontour/contour/internal/protobuf"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // WriteBootstrap writes bootstrap configuration to files.
@@ -67,6 +73,10 @@ type bootstrapf func(*envoy.BootstrapConfig) (string, proto.Message)
 func bootstrap(c *envoy.BootstrapConfig) ([]bootstrapf, error) {
 	var steps []bootstrapf
 
+	if c.AdminDisabled && c.AdminSocketPath != "" {
+		return nil, fmt.Errorf("%q cannot be used with %q", "--admin-socket-path", "--disable-admin")
+	}
+
 	if c.GrpcClientCert == "" && c.GrpcClientKey == "" && c.GrpcCABundle == "" {
 		steps = append(steps,
 			func(*envoy.BootstrapConfig) (string, proto.Message) {
@@ -148,7 +158,7 @@ func bootstrap(c *envoy.BootstrapConfig) ([]bootstrapf, error) {
 }
 
 func bootstrapConfig(c *envoy.BootstrapConfig) *envoy_bootstrap_v3.Bootstrap {
-	return &envoy_bootstrap_v3.Bootstrap{
+	b := &envoy_bootstrap_v3.Bootstrap{
 		DynamicResources: &envoy_bootstrap_v3.Bootstrap_DynamicResources{
 			LdsConfig: ConfigSource("contour"),
 			CdsConfig: ConfigSource("contour"),
@@ -189,25 +199,256 @@ func bootstrapConfig(c *envoy.BootstrapConfig) *envoy_bootstrap_v3.Bootstrap {
 						MaxRetries:         protobuf.UInt32(50),
 					}},
 				},
-			}, {
-				Name:                 "service-stats",
-				AltStatName:          strings.Join([]string{c.Namespace, "service-stats", strconv.Itoa(c.GetAdminPort())}, "_"),
-				ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
-				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_LOGICAL_DNS),
-				LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
-				LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
-					ClusterName: "service-stats",
-					Endpoints: Endpoints(
-						SocketAddress(c.GetAdminAddress(), c.GetAdminPort()),
-					),
+			}, serviceStatsCluster(c)},
+		},
+		Admin:           adminConfig(c),
+		StatsSinks:      statsSinks(c),
+		StatsConfig:     statsConfig(c),
+		OverloadManager: overloadManager(c),
+		LayeredRuntime:  layeredRuntime(c),
+	}
+
+	if c.SPIREAgentSocketPath != "" {
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, SPIREAgentCluster(c.SPIREAgentSocketPath))
+	}
+
+	if c.LocalityRegion != "" || c.LocalityZone != "" {
+		b.Node = &envoy_core_v3.Node{
+			Locality: &envoy_core_v3.Locality{
+				Region: c.LocalityRegion,
+				Zone:   c.LocalityZone,
+			},
+		}
+		b.ClusterManager = &envoy_bootstrap_v3.ClusterManager{
+			LocalClusterName: LocalClusterName,
+		}
+		b.StaticResources.Clusters = append(b.StaticResources.Clusters, LocalCluster())
+	}
+
+	return b
+}
+
+// overloadManager returns the overload manager configuration that
+// protects Envoy from exhausting its heap, or nil if no maximum
+// heap size is set.
+func overloadManager(c *envoy.BootstrapConfig) *envoy_overload_v3.OverloadManager {
+	if c.OverloadMaxHeapBytes == 0 {
+		return nil
+	}
+
+	const fixedHeap = "envoy.resource_monitors.fixed_heap"
+
+	threshold := func(value float64) []*envoy_overload_v3.Trigger {
+		return []*envoy_overload_v3.Trigger{{
+			Name: fixedHeap,
+			TriggerOneof: &envoy_overload_v3.Trigger_Threshold{
+				Threshold: &envoy_overload_v3.ThresholdTrigger{
+					Value: value,
 				},
-			}},
+			},
+		}}
+	}
+
+	return &envoy_overload_v3.OverloadManager{
+		RefreshInterval: protobuf.Duration(250 * time.Millisecond),
+		ResourceMonitors: []*envoy_overload_v3.ResourceMonitor{{
+			Name: fixedHeap,
+			ConfigType: &envoy_overload_v3.ResourceMonitor_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&envoy_fixed_heap_v2alpha.FixedHeapConfig{
+					MaxHeapSizeBytes: c.OverloadMaxHeapBytes,
+				}),
+			},
+		}},
+		Actions: []*envoy_overload_v3.OverloadAction{{
+			Name:     "envoy.overload_actions.shrink_heap",
+			Triggers: threshold(0.95),
+		}, {
+			Name:     "envoy.overload_actions.stop_accepting_requests",
+			Triggers: threshold(0.98),
+		}},
+	}
+}
+
+// layeredRuntime returns the runtime configuration that limits the
+// number of downstream connections, or nil if there is no limit. The
+// admin layer is kept so that runtime values can still be changed
+// through the administration server.
+func layeredRuntime(c *envoy.BootstrapConfig) *envoy_bootstrap_v3.LayeredRuntime {
+	// Runtime keys are not nested, since listener names may contain dots.
+	values := map[string]interface{}{}
+
+	if c.OverloadMaxDownstreamConnections > 0 {
+		values["overload.global_downstream_max_connections"] = float64(c.OverloadMaxDownstreamConnections)
+	}
+
+	// Listener connection limits are runtime values, since the
+	// connection_limit network filter is not available in the
+	// Envoy versions that Contour supports.
+	for name, limit := range c.ListenerMaxConnections {
+		values["envoy.resource_limits.listener."+name+".connection_limit"] = float64(limit)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	static, err := structpb.NewStruct(values)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return &envoy_bootstrap_v3.LayeredRuntime{
+		Layers: []*envoy_bootstrap_v3.RuntimeLayer{{
+			Name: "static_layer_0",
+			LayerSpecifier: &envoy_bootstrap_v3.RuntimeLayer_StaticLayer{
+				StaticLayer: static,
+			},
+		}, {
+			Name: "admin_layer",
+			LayerSpecifier: &envoy_bootstrap_v3.RuntimeLayer_AdminLayer_{
+				AdminLayer: &envoy_bootstrap_v3.RuntimeLayer_AdminLayer{},
+			},
+		}},
+	}
+}
+
+// statsSinks returns the configured stats sink, if any.
+func statsSinks(c *envoy.BootstrapConfig) []*envoy_metrics_v3.StatsSink {
+	address := &envoy_core_v3.Address{
+		Address: &envoy_core_v3.Address_SocketAddress{
+			SocketAddress: &envoy_core_v3.SocketAddress{
+				Protocol: envoy_core_v3.SocketAddress_UDP,
+				Address:  c.GetStatsSinkAddress(),
+				PortSpecifier: &envoy_core_v3.SocketAddress_PortValue{
+					PortValue: uint32(c.GetStatsSinkPort()),
+				},
+			},
+		},
+	}
+
+	switch c.StatsSink {
+	case envoy.StatsdStatsSink:
+		return []*envoy_metrics_v3.StatsSink{{
+			Name: wellknown.Statsd,
+			ConfigType: &envoy_metrics_v3.StatsSink_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&envoy_metrics_v3.StatsdSink{
+					StatsdSpecifier: &envoy_metrics_v3.StatsdSink_Address{
+						Address: address,
+					},
+					Prefix: c.StatsSinkPrefix,
+				}),
+			},
+		}}
+	case envoy.DogStatsdStatsSink:
+		return []*envoy_metrics_v3.StatsSink{{
+			Name: wellknown.DogStatsd,
+			ConfigType: &envoy_metrics_v3.StatsSink_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&envoy_metrics_v3.DogStatsdSink{
+					DogStatsdSpecifier: &envoy_metrics_v3.DogStatsdSink_Address{
+						Address: address,
+					},
+					Prefix: c.StatsSinkPrefix,
+				}),
+			},
+		}}
+	default:
+		return nil
+	}
+}
+
+// statsConfig returns the stats configuration that adds the fixed
+// stats tags to all statistics, or nil if there are no stats tags.
+func statsConfig(c *envoy.BootstrapConfig) *envoy_metrics_v3.StatsConfig {
+	if len(c.StatsTags) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.StatsTags))
+	for name := range c.StatsTags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tags []*envoy_metrics_v3.TagSpecifier
+	for _, name := range names {
+		tags = append(tags, &envoy_metrics_v3.TagSpecifier{
+			TagName: name,
+			TagValue: &envoy_metrics_v3.TagSpecifier_FixedValue{
+				FixedValue: c.StatsTags[name],
+			},
+		})
+	}
+
+	return &envoy_metrics_v3.StatsConfig{
+		StatsTags: tags,
+	}
+}
+
+// LocalClusterName is the name of the bootstrap cluster of the
+// endpoints of the Envoy Service, which Envoy uses as its local
+// cluster for zone aware routing.
+const LocalClusterName = "envoy"
+
+// LocalCluster returns the local cluster of the Envoy fleet. Its
+// endpoints are discovered from Contour, which serves the endpoints
+// of the Envoy Service under LocalClusterName.
+func LocalCluster() *envoy_cluster_v3.Cluster {
+	return &envoy_cluster_v3.Cluster{
+		Name:                 LocalClusterName,
+		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
+		ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+		EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+			EdsConfig:   ConfigSource("contour"),
+			ServiceName: LocalClusterName,
 		},
-		Admin: &envoy_bootstrap_v3.Admin{
-			AccessLogPath: c.GetAdminAccessLogPath(),
-			Address:       SocketAddress(c.GetAdminAddress(), c.GetAdminPort()),
+	}
+}
+
+// serviceStatsCluster returns the cluster that the stats listener uses
+// to reach the allowed paths of the administration server.
+func serviceStatsCluster(c *envoy.BootstrapConfig) *envoy_cluster_v3.Cluster {
+	cluster := &envoy_cluster_v3.Cluster{
+		Name:                 "service-stats",
+		AltStatName:          strings.Join([]string{c.Namespace, "service-stats", strconv.Itoa(c.GetAdminPort())}, "_"),
+		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
+		ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_LOGICAL_DNS),
+		LbPolicy:             envoy_cluster_v3.Cluster_ROUND_ROBIN,
+		LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "service-stats",
+			Endpoints: Endpoints(
+				SocketAddress(c.GetAdminAddress(), c.GetAdminPort()),
+			),
 		},
 	}
+
+	// Unix domain socket endpoints cannot be resolved
+	// by DNS, so they require a static cluster.
+	if c.AdminSocketPath != "" {
+		cluster.AltStatName = strings.Join([]string{c.Namespace, "service-stats"}, "_")
+		cluster.ClusterDiscoveryType = ClusterDiscoveryType(envoy_cluster_v3.Cluster_STATIC)
+		cluster.LoadAssignment.Endpoints = Endpoints(PipeAddress(c.AdminSocketPath))
+	}
+
+	return cluster
+}
+
+// adminConfig returns the administration server configuration,
+// or nil if the administration server is disabled.
+func adminConfig(c *envoy.BootstrapConfig) *envoy_bootstrap_v3.Admin {
+	if c.AdminDisabled {
+		return nil
+	}
+
+	admin := &envoy_bootstrap_v3.Admin{
+		AccessLogPath: c.GetAdminAccessLogPath(),
+		Address:       SocketAddress(c.GetAdminAddress(), c.GetAdminPort()),
+	}
+
+	if c.AdminSocketPath != "" {
+		admin.Address = PipeAddress(c.AdminSocketPath)
+	}
+
+	return admin
 }
 
 func upstreamFileTLSContext(c *envoy.BootstrapConfig) *envoy_tls_v3.UpstreamTlsContext {
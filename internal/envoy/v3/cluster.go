@@ -14,6 +14,7 @@
 package v3
 
 import (
+	"net"
 	"strings"
 	"time"
 
@@ -25,9 +26,24 @@ import (
 	"github.com/projectcontour/contour/internal/envoy"
 	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/projectcontour/contour/internal/xds"
+	"github.com/projectcontour/contour/pkg/config"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// retryBudget returns the circuit breaker retry budget for the
+// supplied dag.RetryBudget, or nil if no budget is set. When a
+// budget is set, Envoy ignores the max_retries threshold.
+func retryBudget(rb *dag.RetryBudget) *envoy_cluster_v3.CircuitBreakers_Thresholds_RetryBudget {
+	if rb == nil {
+		return nil
+	}
+
+	return &envoy_cluster_v3.CircuitBreakers_Thresholds_RetryBudget{
+		BudgetPercent:       &envoy_type.Percent{Value: float64(rb.BudgetPercent)},
+		MinRetryConcurrency: protobuf.UInt32OrNil(rb.MinRetryConcurrency),
+	}
+}
+
 func clusterDefaults() *envoy_cluster_v3.Cluster {
 	return &envoy_cluster_v3.Cluster{
 		ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
@@ -44,36 +60,48 @@ func Cluster(c *dag.Cluster) *envoy_cluster_v3.Cluster {
 	cluster.Name = envoy.Clustername(c)
 	cluster.AltStatName = envoy.AltStatName(service)
 	cluster.LbPolicy = lbPolicy(c.LoadBalancerPolicy)
+	applyHashLbConfig(cluster, c)
 	cluster.HealthChecks = edshealthcheck(c)
 	cluster.DnsLookupFamily = parseDNSLookupFamily(c.DNSLookupFamily)
 
-	switch len(service.ExternalName) {
-	case 0:
+	switch {
+	case len(service.ExternalName) == 0:
 		// external name not set, cluster will be discovered via EDS
 		cluster.ClusterDiscoveryType = ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS)
 		cluster.EdsClusterConfig = edsconfig("contour", service)
+	case net.ParseIP(service.ExternalName) != nil:
+		// external name is an IP address, so there is nothing to resolve
+		cluster.ClusterDiscoveryType = ClusterDiscoveryType(envoy_cluster_v3.Cluster_STATIC)
+		cluster.LoadAssignment = StaticClusterLoadAssignment(service)
 	default:
 		// external name set, use hard coded DNS name
 		cluster.ClusterDiscoveryType = ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS)
 		cluster.LoadAssignment = StaticClusterLoadAssignment(service)
+		cluster.DnsResolvers = dnsResolvers(c.DNSResolvers)
 	}
 
 	// Drain connections immediately if using healthchecks and the endpoint is known to be removed
-	if c.HTTPHealthCheckPolicy != nil || c.TCPHealthCheckPolicy != nil {
+	if c.HTTPHealthCheckPolicy != nil || c.TCPHealthCheckPolicy != nil || c.GRPCHealthCheckPolicy != nil {
 		cluster.IgnoreHealthOnHostRemoval = true
 	}
 
-	if envoy.AnyPositive(service.MaxConnections, service.MaxPendingRequests, service.MaxRequests, service.MaxRetries) {
+	if envoy.AnyPositive(service.MaxConnections, service.MaxPendingRequests, service.MaxRequests, service.MaxRetries) || c.RetryBudget != nil {
 		cluster.CircuitBreakers = &envoy_cluster_v3.CircuitBreakers{
 			Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
 				MaxConnections:     protobuf.UInt32OrNil(service.MaxConnections),
 				MaxPendingRequests: protobuf.UInt32OrNil(service.MaxPendingRequests),
 				MaxRequests:        protobuf.UInt32OrNil(service.MaxRequests),
 				MaxRetries:         protobuf.UInt32OrNil(service.MaxRetries),
+				RetryBudget:        retryBudget(c.RetryBudget),
 			}},
 		}
 	}
 
+	cluster.MaxRequestsPerConnection = protobuf.UInt32OrNil(service.MaxRequestsPerConnection)
+	if service.ConnectTimeout > 0 {
+		cluster.ConnectTimeout = protobuf.Duration(service.ConnectTimeout)
+	}
+
 	switch c.Protocol {
 	case "tls":
 		cluster.TransportSocket = UpstreamTLSTransportSocket(
@@ -84,7 +112,7 @@ func Cluster(c *dag.Cluster) *envoy_cluster_v3.Cluster {
 			),
 		)
 	case "h2":
-		cluster.Http2ProtocolOptions = &envoy_core_v3.Http2ProtocolOptions{}
+		cluster.Http2ProtocolOptions = http2ProtocolOptions(service)
 		cluster.TransportSocket = UpstreamTLSTransportSocket(
 			UpstreamTLSContext(
 				c.UpstreamValidation,
@@ -94,12 +122,21 @@ func Cluster(c *dag.Cluster) *envoy_cluster_v3.Cluster {
 			),
 		)
 	case "h2c":
-		cluster.Http2ProtocolOptions = &envoy_core_v3.Http2ProtocolOptions{}
+		cluster.Http2ProtocolOptions = http2ProtocolOptions(service)
 	}
 
 	return cluster
 }
 
+// http2ProtocolOptions returns the HTTP/2 options for the upstream
+// connections to the given service.
+func http2ProtocolOptions(service *dag.Service) *envoy_core_v3.Http2ProtocolOptions {
+	return &envoy_core_v3.Http2ProtocolOptions{
+		InitialStreamWindowSize:     protobuf.UInt32OrNil(service.HTTP2InitialStreamWindowSize),
+		InitialConnectionWindowSize: protobuf.UInt32OrNil(service.HTTP2InitialConnectionWindowSize),
+	}
+}
+
 // ExtensionCluster builds a envoy_cluster_v3.Cluster struct for the given extension service.
 func ExtensionCluster(ext *dag.ExtensionCluster) *envoy_cluster_v3.Cluster {
 	cluster := clusterDefaults()
@@ -125,6 +162,17 @@ func ExtensionCluster(ext *dag.ExtensionCluster) *envoy_cluster_v3.Cluster {
 		ServiceName: ext.Upstream.ClusterName,
 	}
 
+	// The weights of the services of the extension are set on
+	// their localities, and Envoy ignores locality weights unless
+	// locality weighted load balancing is enabled. This excludes
+	// zone aware routing, which only applies to single service
+	// clusters.
+	if len(ext.Upstream.Services) > 1 {
+		cluster.CommonLbConfig.LocalityConfigSpecifier = &envoy_cluster_v3.Cluster_CommonLbConfig_LocalityWeightedLbConfig_{
+			LocalityWeightedLbConfig: &envoy_cluster_v3.Cluster_CommonLbConfig_LocalityWeightedLbConfig{},
+		}
+	}
+
 	// TODO(jpeach): Externalname service support in https://github.com/projectcontour/contour/issues/2875
 
 	switch ext.Protocol {
@@ -173,15 +221,38 @@ func lbPolicy(strategy string) envoy_cluster_v3.Cluster_LbPolicy {
 		return envoy_cluster_v3.Cluster_LEAST_REQUEST
 	case "Random":
 		return envoy_cluster_v3.Cluster_RANDOM
-	case "Cookie":
+	case "Cookie", "RequestHash":
 		return envoy_cluster_v3.Cluster_RING_HASH
+	case "Maglev":
+		return envoy_cluster_v3.Cluster_MAGLEV
 	default:
 		return envoy_cluster_v3.Cluster_ROUND_ROBIN
 	}
 }
 
+// applyHashLbConfig sets the ring hash or Maglev tuning from
+// the dag.Cluster on the Envoy cluster, if the cluster uses
+// the matching load balancer.
+func applyHashLbConfig(cluster *envoy_cluster_v3.Cluster, c *dag.Cluster) {
+	switch {
+	case cluster.LbPolicy == envoy_cluster_v3.Cluster_RING_HASH && c.RingHashConfig != nil:
+		cluster.LbConfig = &envoy_cluster_v3.Cluster_RingHashLbConfig_{
+			RingHashLbConfig: &envoy_cluster_v3.Cluster_RingHashLbConfig{
+				MinimumRingSize: protobuf.UInt64OrNil(c.RingHashConfig.MinimumRingSize),
+				MaximumRingSize: protobuf.UInt64OrNil(c.RingHashConfig.MaximumRingSize),
+			},
+		}
+	case cluster.LbPolicy == envoy_cluster_v3.Cluster_MAGLEV && c.MaglevConfig != nil:
+		cluster.LbConfig = &envoy_cluster_v3.Cluster_MaglevLbConfig_{
+			MaglevLbConfig: &envoy_cluster_v3.Cluster_MaglevLbConfig{
+				TableSize: protobuf.UInt64OrNil(c.MaglevConfig.TableSize),
+			},
+		}
+	}
+}
+
 func edshealthcheck(c *dag.Cluster) []*envoy_core_v3.HealthCheck {
-	if c.HTTPHealthCheckPolicy == nil && c.TCPHealthCheckPolicy == nil {
+	if c.HTTPHealthCheckPolicy == nil && c.TCPHealthCheckPolicy == nil && c.GRPCHealthCheckPolicy == nil {
 		return nil
 	}
 
@@ -191,6 +262,12 @@ func edshealthcheck(c *dag.Cluster) []*envoy_core_v3.HealthCheck {
 		}
 	}
 
+	if c.GRPCHealthCheckPolicy != nil {
+		return []*envoy_core_v3.HealthCheck{
+			grpcHealthCheck(c),
+		}
+	}
+
 	return []*envoy_core_v3.HealthCheck{
 		tcpHealthCheck(c),
 	}
@@ -230,6 +307,20 @@ func ClusterDiscoveryType(t envoy_cluster_v3.Cluster_DiscoveryType) *envoy_clust
 	return &envoy_cluster_v3.Cluster_Type{Type: t}
 }
 
+// dnsResolvers returns the addresses of the supplied DNS resolvers,
+// which have already been validated by the Contour configuration.
+func dnsResolvers(resolvers []string) []*envoy_core_v3.Address {
+	var addresses []*envoy_core_v3.Address
+	for _, r := range resolvers {
+		ip, port, err := config.ParseDNSResolver(r)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, SocketAddress(ip.String(), int(port)))
+	}
+	return addresses
+}
+
 // parseDNSLookupFamily parses the dnsLookupFamily string into a envoy_cluster_v3.Cluster_DnsLookupFamily
 func parseDNSLookupFamily(value string) envoy_cluster_v3.Cluster_DnsLookupFamily {
 
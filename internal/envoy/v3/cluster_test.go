@@ -222,6 +222,22 @@ func TestCluster(t *testing.T) {
 				DnsLookupFamily:      envoy_cluster_v3.Cluster_AUTO,
 			},
 		},
+		"externalName service - dns resolvers": {
+			cluster: &dag.Cluster{
+				Upstream:     service(s2),
+				DNSResolvers: []string{"10.96.0.10", "[fd00::10]:5353"},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/da39a3ee5e",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STRICT_DNS),
+				LoadAssignment:       StaticClusterLoadAssignment(service(s2)),
+				DnsResolvers: []*envoy_core_v3.Address{
+					SocketAddress("10.96.0.10", 53),
+					SocketAddress("fd00::10", 5353),
+				},
+			},
+		},
 		"tls upstream": {
 			cluster: &dag.Cluster{
 				Upstream: service(s1, "tls"),
@@ -392,6 +408,95 @@ func TestCluster(t *testing.T) {
 				},
 			},
 		},
+		"retry budget": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					MaxRetries: 7,
+					Weighted: dag.WeightedService{
+						Weight:           1,
+						ServiceName:      s1.Name,
+						ServiceNamespace: s1.Namespace,
+						ServicePort:      s1.Spec.Ports[0],
+					},
+				},
+				RetryBudget: &dag.RetryBudget{
+					BudgetPercent:       20,
+					MinRetryConcurrency: 3,
+				},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/a165fbd61c",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				CircuitBreakers: &envoy_cluster_v3.CircuitBreakers{
+					Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
+						MaxRetries: protobuf.UInt32(7),
+						RetryBudget: &envoy_cluster_v3.CircuitBreakers_Thresholds_RetryBudget{
+							BudgetPercent:       &envoy_type.Percent{Value: 20},
+							MinRetryConcurrency: protobuf.UInt32(3),
+						},
+					}},
+				},
+			},
+		},
+		"connection pool settings": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					MaxRequestsPerConnection: 1,
+					ConnectTimeout:           1500 * time.Millisecond,
+					Weighted: dag.WeightedService{
+						Weight:           1,
+						ServiceName:      s1.Name,
+						ServiceNamespace: s1.Namespace,
+						ServicePort:      s1.Spec.Ports[0],
+					},
+				},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/da39a3ee5e",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				ConnectTimeout:           protobuf.Duration(1500 * time.Millisecond),
+				MaxRequestsPerConnection: protobuf.UInt32(1),
+			},
+		},
+		"h2c upstream with window sizes": {
+			cluster: &dag.Cluster{
+				Upstream: &dag.Service{
+					Protocol:                         "h2c",
+					HTTP2InitialStreamWindowSize:     65536,
+					HTTP2InitialConnectionWindowSize: 1048576,
+					Weighted: dag.WeightedService{
+						Weight:           1,
+						ServiceName:      s1.Name,
+						ServiceNamespace: s1.Namespace,
+						ServicePort:      s1.Spec.Ports[0],
+					},
+				},
+				Protocol: "h2c",
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/da39a3ee5e",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				Http2ProtocolOptions: &envoy_core_v3.Http2ProtocolOptions{
+					InitialStreamWindowSize:     protobuf.UInt32(65536),
+					InitialConnectionWindowSize: protobuf.UInt32(1048576),
+				},
+			},
+		},
 		"cluster with random load balancer policy": {
 			cluster: &dag.Cluster{
 				Upstream:           service(s1),
@@ -424,6 +529,56 @@ func TestCluster(t *testing.T) {
 				LbPolicy: envoy_cluster_v3.Cluster_RING_HASH,
 			},
 		},
+		"cluster with request hash policy and ring size": {
+			cluster: &dag.Cluster{
+				Upstream:           service(s1),
+				LoadBalancerPolicy: "RequestHash",
+				RingHashConfig: &dag.RingHashConfig{
+					MinimumRingSize: 4096,
+					MaximumRingSize: 65536,
+				},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/f04af6889a",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				LbPolicy: envoy_cluster_v3.Cluster_RING_HASH,
+				LbConfig: &envoy_cluster_v3.Cluster_RingHashLbConfig_{
+					RingHashLbConfig: &envoy_cluster_v3.Cluster_RingHashLbConfig{
+						MinimumRingSize: protobuf.UInt64OrNil(4096),
+						MaximumRingSize: protobuf.UInt64OrNil(65536),
+					},
+				},
+			},
+		},
+		"cluster with maglev policy and table size": {
+			cluster: &dag.Cluster{
+				Upstream:           service(s1),
+				LoadBalancerPolicy: "Maglev",
+				MaglevConfig: &dag.MaglevConfig{
+					TableSize: 131071,
+				},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/8d23ae0f28",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				LbPolicy: envoy_cluster_v3.Cluster_MAGLEV,
+				LbConfig: &envoy_cluster_v3.Cluster_MaglevLbConfig_{
+					MaglevLbConfig: &envoy_cluster_v3.Cluster_MaglevLbConfig{
+						TableSize: protobuf.UInt64OrNil(131071),
+					},
+				},
+			},
+		},
 
 		"tcp service": {
 			cluster: &dag.Cluster{
@@ -488,6 +643,30 @@ func TestCluster(t *testing.T) {
 				),
 			},
 		},
+		"per-service upstream tls": {
+			cluster: &dag.Cluster{
+				Upstream:          service(s1, "tls"),
+				Protocol:          "tls",
+				SNI:               "kuard.mesh.local",
+				ClientCertificate: clientSecret,
+				UpstreamTLS: &dag.UpstreamTLS{
+					SNI:               "kuard.mesh.local",
+					ClientCertificate: clientSecret,
+				},
+			},
+			want: &envoy_cluster_v3.Cluster{
+				Name:                 "default/kuard/443/f2ba355fb6",
+				AltStatName:          "default_kuard_443",
+				ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+				EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+					EdsConfig:   ConfigSource("contour"),
+					ServiceName: "default/kuard/http",
+				},
+				TransportSocket: UpstreamTLSTransportSocket(
+					UpstreamTLSContext(nil, "kuard.mesh.local", clientSecret),
+				),
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -626,11 +805,15 @@ func TestLBPolicy(t *testing.T) {
 		"":                     envoy_cluster_v3.Cluster_ROUND_ROBIN,
 		"unknown":              envoy_cluster_v3.Cluster_ROUND_ROBIN,
 		"Cookie":               envoy_cluster_v3.Cluster_RING_HASH,
+		"RequestHash":          envoy_cluster_v3.Cluster_RING_HASH,
 
-		// RingHash and Maglev were removed as options in 0.13.
+		// RingHash was removed as an option in 0.13.
 		// See #1150
 		"RingHash": envoy_cluster_v3.Cluster_ROUND_ROBIN,
-		"Maglev":   envoy_cluster_v3.Cluster_ROUND_ROBIN,
+
+		// Maglev is only selected when the route has
+		// request hash policies.
+		"Maglev": envoy_cluster_v3.Cluster_MAGLEV,
 	}
 
 	for policy, want := range tests {
@@ -0,0 +1,63 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	// Link the messages of the extensions that are only
+	// referred to by type URL, so that they can be described.
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/gzip/compressor/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
+	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ExtensionTypes are the messages that Contour embeds in the
+// typed_config fields of the resources it serves. A message that
+// is added to a typed_config must be listed here, so that it is
+// described by the output schema.
+var ExtensionTypes = []protoreflect.FullName{
+	"envoy.config.trace.v3.OpenCensusConfig",
+	"envoy.extensions.access_loggers.file.v3.FileAccessLog",
+	"envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig",
+	"envoy.extensions.clusters.dynamic_forward_proxy.v3.ClusterConfig",
+	"envoy.extensions.compression.gzip.compressor.v3.Gzip",
+	"envoy.extensions.filters.http.buffer.v3.Buffer",
+	"envoy.extensions.filters.http.buffer.v3.BufferPerRoute",
+	"envoy.extensions.filters.http.cache.v3alpha.CacheConfig",
+	"envoy.extensions.filters.http.compressor.v3.Compressor",
+	"envoy.extensions.filters.http.cors.v3.Cors",
+	"envoy.extensions.filters.http.dynamic_forward_proxy.v3.FilterConfig",
+	"envoy.extensions.filters.http.ext_authz.v3.ExtAuthz",
+	"envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute",
+	"envoy.extensions.filters.http.fault.v3.HTTPFault",
+	"envoy.extensions.filters.http.grpc_json_transcoder.v3.GrpcJsonTranscoder",
+	"envoy.extensions.filters.http.grpc_web.v3.GrpcWeb",
+	"envoy.extensions.filters.http.health_check.v3.HealthCheck",
+	"envoy.extensions.filters.http.lua.v3.Lua",
+	"envoy.extensions.filters.http.ratelimit.v3.RateLimit",
+	"envoy.extensions.filters.http.rbac.v3.RBAC",
+	"envoy.extensions.filters.http.rbac.v3.RBACPerRoute",
+	"envoy.extensions.filters.http.router.v3.Router",
+	"envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+	"envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+	"envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext",
+	"envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
+}
+
+// EmptyExtensionTypes are the messages that Contour embeds in
+// typed_config fields without any configuration, and that are not
+// defined by the version of the Envoy API that Contour is built with.
+var EmptyExtensionTypes = []protoreflect.FullName{
+	"envoy.extensions.cache.simple_http_cache.v3alpha.SimpleHttpCacheConfig",
+}
@@ -0,0 +1,75 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_dfp_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/dynamic_forward_proxy/v3"
+	envoy_dfp_common_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/dynamic_forward_proxy/v3"
+	envoy_dfp_filter_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/dynamic_forward_proxy/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// ForwardProxyClusterName is the name of the cluster that forwards
+// requests to the host named in each request.
+const ForwardProxyClusterName = "dynamic_forward_proxy"
+
+// ForwardProxyCluster returns a dynamic forward proxy cluster, which
+// connects to the hosts resolved by the dynamic forward proxy filter.
+func ForwardProxyCluster(fp *dag.ForwardProxy) *envoy_cluster_v3.Cluster {
+	cluster := clusterDefaults()
+
+	cluster.Name = ForwardProxyClusterName
+	cluster.LbPolicy = envoy_cluster_v3.Cluster_CLUSTER_PROVIDED
+	cluster.ClusterDiscoveryType = &envoy_cluster_v3.Cluster_ClusterType{
+		ClusterType: &envoy_cluster_v3.Cluster_CustomClusterType{
+			Name: "envoy.clusters.dynamic_forward_proxy",
+			TypedConfig: protobuf.MustMarshalAny(&envoy_dfp_cluster_v3.ClusterConfig{
+				DnsCacheConfig: forwardProxyDNSCache(fp),
+			}),
+		},
+	}
+
+	return cluster
+}
+
+// FilterDynamicForwardProxy returns a `dynamic_forward_proxy` filter,
+// which resolves the host of each request routed to the forward
+// proxy cluster.
+func FilterDynamicForwardProxy(fp *dag.ForwardProxy) *http.HttpFilter {
+	if fp == nil {
+		return nil
+	}
+
+	return &http.HttpFilter{
+		Name: "envoy.filters.http.dynamic_forward_proxy",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_dfp_filter_v3.FilterConfig{
+				DnsCacheConfig: forwardProxyDNSCache(fp),
+			}),
+		},
+	}
+}
+
+// forwardProxyDNSCache returns the DNS cache shared by the dynamic
+// forward proxy filter and cluster. Envoy requires every use of a
+// named cache to have the same settings.
+func forwardProxyDNSCache(fp *dag.ForwardProxy) *envoy_dfp_common_v3.DnsCacheConfig {
+	return &envoy_dfp_common_v3.DnsCacheConfig{
+		Name:            ForwardProxyClusterName,
+		DnsLookupFamily: parseDNSLookupFamily(fp.DNSLookupFamily),
+	}
+}
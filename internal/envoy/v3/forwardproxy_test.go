@@ -0,0 +1,83 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_dfp_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/dynamic_forward_proxy/v3"
+	envoy_dfp_common_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/dynamic_forward_proxy/v3"
+	envoy_dfp_filter_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/dynamic_forward_proxy/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+func TestForwardProxyCluster(t *testing.T) {
+	got := ForwardProxyCluster(&dag.ForwardProxy{DNSLookupFamily: "v4"})
+
+	want := &envoy_cluster_v3.Cluster{
+		Name:           "dynamic_forward_proxy",
+		ConnectTimeout: protobuf.Duration(250 * time.Millisecond),
+		CommonLbConfig: ClusterCommonLBConfig(),
+		LbPolicy:       envoy_cluster_v3.Cluster_CLUSTER_PROVIDED,
+		ClusterDiscoveryType: &envoy_cluster_v3.Cluster_ClusterType{
+			ClusterType: &envoy_cluster_v3.Cluster_CustomClusterType{
+				Name: "envoy.clusters.dynamic_forward_proxy",
+				TypedConfig: protobuf.MustMarshalAny(&envoy_dfp_cluster_v3.ClusterConfig{
+					DnsCacheConfig: &envoy_dfp_common_v3.DnsCacheConfig{
+						Name:            "dynamic_forward_proxy",
+						DnsLookupFamily: envoy_cluster_v3.Cluster_V4_ONLY,
+					},
+				}),
+			},
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestFilterDynamicForwardProxy(t *testing.T) {
+	tests := map[string]struct {
+		fp   *dag.ForwardProxy
+		want *http.HttpFilter
+	}{
+		"nil forward proxy produces nil filter": {
+			fp:   nil,
+			want: nil,
+		},
+		"default lookup family": {
+			fp: &dag.ForwardProxy{},
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.dynamic_forward_proxy",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_dfp_filter_v3.FilterConfig{
+						DnsCacheConfig: &envoy_dfp_common_v3.DnsCacheConfig{
+							Name:            "dynamic_forward_proxy",
+							DnsLookupFamily: envoy_cluster_v3.Cluster_AUTO,
+						},
+					}),
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, FilterDynamicForwardProxy(tc.fp))
+		})
+	}
+}
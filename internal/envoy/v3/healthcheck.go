@@ -47,6 +47,24 @@ func httpHealthCheck(cluster *dag.Cluster) *envoy_core_v3.HealthCheck {
 	}
 }
 
+// grpcHealthCheck returns a *envoy_core_v3.HealthCheck value for gRPC Routes
+func grpcHealthCheck(cluster *dag.Cluster) *envoy_core_v3.HealthCheck {
+	hc := cluster.GRPCHealthCheckPolicy
+
+	return &envoy_core_v3.HealthCheck{
+		Timeout:            durationOrDefault(hc.Timeout, envoy.HCTimeout),
+		Interval:           durationOrDefault(hc.Interval, envoy.HCInterval),
+		UnhealthyThreshold: protobuf.UInt32OrDefault(hc.UnhealthyThreshold, envoy.HCUnhealthyThreshold),
+		HealthyThreshold:   protobuf.UInt32OrDefault(hc.HealthyThreshold, envoy.HCHealthyThreshold),
+		HealthChecker: &envoy_core_v3.HealthCheck_GrpcHealthCheck_{
+			GrpcHealthCheck: &envoy_core_v3.HealthCheck_GrpcHealthCheck{
+				ServiceName: hc.ServiceName,
+				Authority:   hc.Authority,
+			},
+		},
+	}
+}
+
 // tcpHealthCheck returns a *envoy_core_v3.HealthCheck value for TCPProxies
 func tcpHealthCheck(cluster *dag.Cluster) *envoy_core_v3.HealthCheck {
 	hc := cluster.TCPHealthCheckPolicy
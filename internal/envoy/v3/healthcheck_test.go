@@ -100,3 +100,56 @@ func TestHealthCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestGRPCHealthCheck(t *testing.T) {
+	tests := map[string]struct {
+		cluster *dag.Cluster
+		want    *envoy_core_v3.HealthCheck
+	}{
+		"blank healthcheck": {
+			cluster: &dag.Cluster{
+				GRPCHealthCheckPolicy: new(dag.GRPCHealthCheckPolicy),
+			},
+			want: &envoy_core_v3.HealthCheck{
+				Timeout:            protobuf.Duration(envoy.HCTimeout),
+				Interval:           protobuf.Duration(envoy.HCInterval),
+				UnhealthyThreshold: protobuf.UInt32(3),
+				HealthyThreshold:   protobuf.UInt32(2),
+				HealthChecker: &envoy_core_v3.HealthCheck_GrpcHealthCheck_{
+					GrpcHealthCheck: &envoy_core_v3.HealthCheck_GrpcHealthCheck{},
+				},
+			},
+		},
+		"explicit healthcheck": {
+			cluster: &dag.Cluster{
+				GRPCHealthCheckPolicy: &dag.GRPCHealthCheckPolicy{
+					ServiceName:        "helloworld.Greeter",
+					Authority:          "grpc.example.com",
+					Timeout:            99 * time.Second,
+					Interval:           98 * time.Second,
+					UnhealthyThreshold: 97,
+					HealthyThreshold:   96,
+				},
+			},
+			want: &envoy_core_v3.HealthCheck{
+				Timeout:            protobuf.Duration(99 * time.Second),
+				Interval:           protobuf.Duration(98 * time.Second),
+				UnhealthyThreshold: protobuf.UInt32(97),
+				HealthyThreshold:   protobuf.UInt32(96),
+				HealthChecker: &envoy_core_v3.HealthCheck_GrpcHealthCheck_{
+					GrpcHealthCheck: &envoy_core_v3.HealthCheck_GrpcHealthCheck{
+						ServiceName: "helloworld.Greeter",
+						Authority:   "grpc.example.com",
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := grpcHealthCheck(tc.cluster)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
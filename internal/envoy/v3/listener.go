@@ -17,22 +17,33 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_trace_v3 "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
+	envoy_config_filter_http_buffer_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/buffer/v3"
+	envoy_config_filter_http_cache_v3alpha "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cache/v3alpha"
 	envoy_compressor_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/compressor/v3"
 	envoy_config_filter_http_ext_authz_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	envoy_config_filter_http_fault_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	envoy_config_filter_http_grpc_json_transcoder_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_json_transcoder/v3"
+	envoy_config_filter_http_health_check_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/health_check/v3"
 	lua "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	envoy_config_filter_http_rbac_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	envoy_extensions_filters_http_router_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
 	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/projectcontour/contour/internal/dag"
@@ -40,6 +51,7 @@ import (
 	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/projectcontour/contour/internal/sorter"
 	"github.com/projectcontour/contour/internal/timeout"
+	"github.com/projectcontour/contour/pkg/config"
 )
 
 type HTTPVersionType = http.HttpConnectionManager_CodecType
@@ -91,6 +103,23 @@ func ProtoNamesForVersions(versions ...HTTPVersionType) []string {
 	return alpn
 }
 
+// VersionsForProtoNames returns the HTTP versions for the given ALPN
+// protocol names. Unknown protocol names are ignored.
+func VersionsForProtoNames(names ...string) []HTTPVersionType {
+	var versions []HTTPVersionType
+
+	for _, n := range names {
+		switch n {
+		case "h2":
+			versions = append(versions, HTTPVersion2)
+		case "http/1.1":
+			versions = append(versions, HTTPVersion1)
+		}
+	}
+
+	return versions
+}
+
 // CodecForVersions determines a single Envoy HTTP codec constant
 // that support all the given HTTP protocol versions.
 func CodecForVersions(versions ...HTTPVersionType) HTTPVersionType {
@@ -120,6 +149,16 @@ func ProxyProtocol() *envoy_listener_v3.ListenerFilter {
 	}
 }
 
+// ListenerDrainType returns the Envoy drain type of the
+// supplied config.ListenerDrainType. If not set, the
+// default drain type is returned.
+func ListenerDrainType(t config.ListenerDrainType) envoy_listener_v3.Listener_DrainType {
+	if t == config.ModifyOnlyListenerDrainType {
+		return envoy_listener_v3.Listener_MODIFY_ONLY
+	}
+	return envoy_listener_v3.Listener_DEFAULT
+}
+
 // Listener returns a new envoy_listener_v3.Listener for the supplied address, port, and filters.
 func Listener(name, address string, port int, lf []*envoy_listener_v3.ListenerFilter, filters ...*envoy_listener_v3.Filter) *envoy_listener_v3.Listener {
 	l := &envoy_listener_v3.Listener{
@@ -150,6 +189,31 @@ type httpConnectionManagerBuilder struct {
 	connectionShutdownGracePeriod timeout.Setting
 	filters                       []*http.HttpFilter
 	codec                         HTTPVersionType // Note the zero value is AUTO, which is the default we want.
+	errorPages                    []ErrorPage
+	rateLimitRetryAfter           time.Duration
+	numTrustedHops                uint32
+	maxRequestHeadersKB           uint32
+	maxRequestHeadersCount        uint32
+	stripMatchingHostPort         bool
+	disableHTTP10                 bool
+	scopedRoutes                  bool
+	http10DefaultHost             string
+	serverHeaderTransformation    http.HttpConnectionManager_ServerHeaderTransformation
+	serverName                    string
+	tracing                       *http.HttpConnectionManager_Tracing
+}
+
+// ErrorPage replaces the body of a local reply that Envoy sends
+// with the given status code.
+type ErrorPage struct {
+	// StatusCode is the HTTP status code of the local reply to match.
+	StatusCode uint32
+
+	// Body is the response body to send instead.
+	Body string
+
+	// ContentType is the content type of Body. If empty, "text/html" is used.
+	ContentType string
 }
 
 // RouteConfigName sets the name of the RDS element that contains
@@ -211,6 +275,77 @@ func (b *httpConnectionManagerBuilder) ConnectionShutdownGracePeriod(timeout tim
 	return b
 }
 
+// ErrorPages sets the local reply bodies sent in place of the
+// bodies Envoy generates for the matching status codes.
+func (b *httpConnectionManagerBuilder) ErrorPages(pages []ErrorPage) *httpConnectionManagerBuilder {
+	b.errorPages = pages
+	return b
+}
+
+// RateLimitRetryAfter sets the Retry-After header, in whole seconds,
+// on responses that are rejected by the global rate limit filter.
+// If zero, no Retry-After header is added.
+func (b *httpConnectionManagerBuilder) RateLimitRetryAfter(d time.Duration) *httpConnectionManagerBuilder {
+	b.rateLimitRetryAfter = d
+	return b
+}
+
+// NumTrustedHops sets the number of additional ingress proxy hops from the right
+// side of the X-Forwarded-For header to trust when determining the client address.
+func (b *httpConnectionManagerBuilder) NumTrustedHops(hops uint32) *httpConnectionManagerBuilder {
+	b.numTrustedHops = hops
+	return b
+}
+
+// RequestHeaderLimits sets the maximum size, in KiB, and the maximum
+// number of the request headers the connection manager accepts.
+// Zero leaves the Envoy default in place.
+func (b *httpConnectionManagerBuilder) RequestHeaderLimits(kb, count uint32) *httpConnectionManagerBuilder {
+	b.maxRequestHeadersKB = kb
+	b.maxRequestHeadersCount = count
+	return b
+}
+
+// StripMatchingHostPort configures the connection manager to remove the
+// port from the Host header when it matches the listener port.
+func (b *httpConnectionManagerBuilder) StripMatchingHostPort(strip bool) *httpConnectionManagerBuilder {
+	b.stripMatchingHostPort = strip
+	return b
+}
+
+// ScopedRoutes configures the connection manager to fetch its
+// routes as scoped route configurations, one for each request host,
+// under the route config name, instead of a single route configuration.
+func (b *httpConnectionManagerBuilder) ScopedRoutes(scoped bool) *httpConnectionManagerBuilder {
+	b.scopedRoutes = scoped
+	return b
+}
+
+// HTTP10 configures how the connection manager handles HTTP/1.0
+// requests. If disable is true they are rejected, otherwise
+// requests without a Host header are given defaultHost, when set.
+func (b *httpConnectionManagerBuilder) HTTP10(disable bool, defaultHost string) *httpConnectionManagerBuilder {
+	b.disableHTTP10 = disable
+	b.http10DefaultHost = defaultHost
+	return b
+}
+
+// ServerHeader sets how the connection manager handles the server
+// response header, and the value it uses for the header. An empty
+// name leaves the Envoy default in place.
+func (b *httpConnectionManagerBuilder) ServerHeader(transformation http.HttpConnectionManager_ServerHeaderTransformation, name string) *httpConnectionManagerBuilder {
+	b.serverHeaderTransformation = transformation
+	b.serverName = name
+	return b
+}
+
+// Tracing sets the tracing configuration of the connection manager.
+// A nil value disables tracing.
+func (b *httpConnectionManagerBuilder) Tracing(tracing *http.HttpConnectionManager_Tracing) *httpConnectionManagerBuilder {
+	b.tracing = tracing
+	return b
+}
+
 func (b *httpConnectionManagerBuilder) DefaultFilters() *httpConnectionManagerBuilder {
 
 	// Add a default set of ordered http filters.
@@ -293,7 +428,7 @@ func (b *httpConnectionManagerBuilder) AddFilter(f *http.HttpFilter) *httpConnec
 		panic("Can't add more than one router to a filter chain")
 	}
 
-	if routerIndex != lastIndex {
+	if routerIndex != -1 && routerIndex != lastIndex {
 		// Move the router to the end of the filters array.
 		routerFilter := b.filters[routerIndex]
 		b.filters = append(b.filters[:routerIndex], b.filters[routerIndex+1])
@@ -346,19 +481,27 @@ func (b *httpConnectionManagerBuilder) Get() *envoy_listener_v3.Filter {
 		},
 		HttpFilters: b.filters,
 		CommonHttpProtocolOptions: &envoy_core_v3.HttpProtocolOptions{
-			IdleTimeout: envoy.Timeout(b.connectionIdleTimeout),
+			IdleTimeout:     envoy.Timeout(b.connectionIdleTimeout),
+			MaxHeadersCount: protobuf.UInt32OrNil(b.maxRequestHeadersCount),
 		},
 		HttpProtocolOptions: &envoy_core_v3.Http1ProtocolOptions{
 			// Enable support for HTTP/1.0 requests that carry
 			// a Host: header. See #537.
-			AcceptHttp_10: true,
+			AcceptHttp_10:         !b.disableHTTP10,
+			DefaultHostForHttp_10: b.http10DefaultHost,
 		},
-		UseRemoteAddress: protobuf.Bool(true),
-		NormalizePath:    protobuf.Bool(true),
+		UseRemoteAddress:    protobuf.Bool(true),
+		XffNumTrustedHops:   b.numTrustedHops,
+		MaxRequestHeadersKb: protobuf.UInt32OrNil(b.maxRequestHeadersKB),
+		NormalizePath:       protobuf.Bool(true),
 
 		// issue #1487 pass through X-Request-Id if provided.
 		PreserveExternalRequestId: true,
 		MergeSlashes:              true,
+		StripMatchingHostPort:     b.stripMatchingHostPort,
+
+		ServerHeaderTransformation: b.serverHeaderTransformation,
+		ServerName:                 b.serverName,
 
 		RequestTimeout:    envoy.Timeout(b.requestTimeout),
 		StreamIdleTimeout: envoy.Timeout(b.streamIdleTimeout),
@@ -373,10 +516,22 @@ func (b *httpConnectionManagerBuilder) Get() *envoy_listener_v3.Filter {
 		cm.CommonHttpProtocolOptions.MaxConnectionDuration = protobuf.Duration(b.maxConnectionDuration.Duration())
 	}
 
+	if b.scopedRoutes {
+		cm.RouteSpecifier = scopedRoutes(b.routeConfigName)
+	}
+
 	if len(b.accessLoggers) > 0 {
 		cm.AccessLog = b.accessLoggers
 	}
 
+	if len(b.errorPages) > 0 || b.rateLimitRetryAfter > 0 {
+		cm.LocalReplyConfig = localReplyConfig(b.errorPages, b.rateLimitRetryAfter)
+	}
+
+	if b.tracing != nil {
+		cm.Tracing = b.tracing
+	}
+
 	// If there's no explicit metrics prefix, default it to the
 	// route config name.
 	if b.metricsPrefix != "" {
@@ -393,6 +548,96 @@ func (b *httpConnectionManagerBuilder) Get() *envoy_listener_v3.Filter {
 	}
 }
 
+// localReplyConfig returns a LocalReplyConfig that maps each
+// ErrorPage status code to its replacement body, and adds a
+// Retry-After header to rate limited responses if retryAfter
+// is non-zero.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/local_reply
+func localReplyConfig(pages []ErrorPage, retryAfter time.Duration) *http.LocalReplyConfig {
+	config := &http.LocalReplyConfig{}
+
+	if retryAfter > 0 {
+		// Round up, so that clients never retry early.
+		seconds := (retryAfter + time.Second - 1) / time.Second
+
+		mapper := &http.ResponseMapper{
+			Filter: &accesslog.AccessLogFilter{
+				FilterSpecifier: &accesslog.AccessLogFilter_ResponseFlagFilter{
+					ResponseFlagFilter: &accesslog.ResponseFlagFilter{
+						// RL is the flag of requests rejected
+						// by the rate limit filter.
+						Flags: []string{"RL"},
+					},
+				},
+			},
+			HeadersToAdd: []*envoy_core_v3.HeaderValueOption{{
+				Header: &envoy_core_v3.HeaderValue{
+					Key:   "Retry-After",
+					Value: strconv.FormatInt(int64(seconds), 10),
+				},
+				Append: protobuf.Bool(false),
+			}},
+		}
+
+		// Envoy only applies the first matching mapper, so the
+		// error page of rate limited (429) responses is merged
+		// into this one rather than being shadowed by it.
+		for _, p := range pages {
+			if p.StatusCode == 429 {
+				mapper.Body, mapper.BodyFormatOverride = errorPageBody(p)
+			}
+		}
+
+		config.Mappers = append(config.Mappers, mapper)
+	}
+
+	for _, p := range pages {
+		mapper := &http.ResponseMapper{
+			Filter: &accesslog.AccessLogFilter{
+				FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+					StatusCodeFilter: &accesslog.StatusCodeFilter{
+						Comparison: &accesslog.ComparisonFilter{
+							Op: accesslog.ComparisonFilter_EQ,
+							Value: &envoy_core_v3.RuntimeUInt32{
+								DefaultValue: p.StatusCode,
+								RuntimeKey:   fmt.Sprintf("contour.error_page.%d", p.StatusCode),
+							},
+						},
+					},
+				},
+			},
+		}
+		mapper.Body, mapper.BodyFormatOverride = errorPageBody(p)
+
+		config.Mappers = append(config.Mappers, mapper)
+	}
+
+	return config
+}
+
+// errorPageBody returns the body of the local replies
+// that p replaces, and the format of that body.
+func errorPageBody(p ErrorPage) (*envoy_core_v3.DataSource, *envoy_core_v3.SubstitutionFormatString) {
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = "text/html"
+	}
+
+	body := &envoy_core_v3.DataSource{
+		Specifier: &envoy_core_v3.DataSource_InlineString{
+			InlineString: p.Body,
+		},
+	}
+	format := &envoy_core_v3.SubstitutionFormatString{
+		Format: &envoy_core_v3.SubstitutionFormatString_TextFormat{
+			TextFormat: "%LOCAL_REPLY_BODY%",
+		},
+		ContentType: contentType,
+	}
+	return body, format
+}
+
 // HTTPConnectionManager creates a new HTTP Connection Manager filter
 // for the supplied route, access log, and client request timeout.
 func HTTPConnectionManager(routename string, accesslogger []*accesslog.AccessLog, requestTimeout time.Duration) *envoy_listener_v3.Filter {
@@ -429,6 +674,7 @@ func TCPProxy(statPrefix string, proxy *dag.TCPProxy, accesslogger []*accesslog.
 					},
 					AccessLog:   accesslogger,
 					IdleTimeout: idleTimeout,
+					HashPolicy:  tcpHashPolicy(proxy),
 				}),
 			},
 		}
@@ -457,12 +703,26 @@ func TCPProxy(statPrefix string, proxy *dag.TCPProxy, accesslogger []*accesslog.
 					},
 					AccessLog:   accesslogger,
 					IdleTimeout: idleTimeout,
+					HashPolicy:  tcpHashPolicy(proxy),
 				}),
 			},
 		}
 	}
 }
 
+// tcpHashPolicy returns a hash policy on the source IP if the
+// TCP proxy hashes its connections, or nil otherwise.
+func tcpHashPolicy(proxy *dag.TCPProxy) []*envoy_type.HashPolicy {
+	if !proxy.HashSourceIP {
+		return nil
+	}
+	return []*envoy_type.HashPolicy{{
+		PolicySpecifier: &envoy_type.HashPolicy_SourceIp_{
+			SourceIp: &envoy_type.HashPolicy_SourceIp{},
+		},
+	}}
+}
+
 // SocketAddress creates a new TCP envoy_core_v3.Address.
 func SocketAddress(address string, port int) *envoy_core_v3.Address {
 	if address == "::" {
@@ -492,6 +752,17 @@ func SocketAddress(address string, port int) *envoy_core_v3.Address {
 	}
 }
 
+// PipeAddress creates a new Unix domain socket envoy_core_v3.Address.
+func PipeAddress(path string) *envoy_core_v3.Address {
+	return &envoy_core_v3.Address{
+		Address: &envoy_core_v3.Address_Pipe{
+			Pipe: &envoy_core_v3.Pipe{
+				Path: path,
+			},
+		},
+	}
+}
+
 // Filters returns a []*envoy_listener_v3.Filter for the supplied filters.
 func Filters(filters ...*envoy_listener_v3.Filter) []*envoy_listener_v3.Filter {
 	if len(filters) == 0 {
@@ -547,7 +818,7 @@ end
 	`
 
 	return &http.HttpFilter{
-		Name: "envoy.filters.http.lua",
+		Name: LuaFilterName,
 		ConfigType: &http.HttpFilter_TypedConfig{
 			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
 				InlineCode: fmt.Sprintf(code, strings.ToLower(fqdn)),
@@ -556,6 +827,34 @@ end
 	}
 }
 
+// Names of the HTTP filters that may be configured per route,
+// which key the filter configs in a route's TypedPerFilterConfig.
+const (
+	ExtAuthzFilterName = "envoy.filters.http.ext_authz"
+	BufferFilterName   = "envoy.filters.http.buffer"
+	RBACFilterName     = "envoy.filters.http.rbac"
+	FaultFilterName    = "envoy.filters.http.fault"
+)
+
+// LuaFilterName is the name of the Lua HTTP filters, which is also
+// the namespace of the route metadata that they read.
+const LuaFilterName = "envoy.filters.http.lua"
+
+// responseTimeoutHeaderKey is the key of the route metadata
+// that names the response timeout header of the route.
+const responseTimeoutHeaderKey = "response-timeout-header"
+
+// cacheTTLKey is the key of the route metadata that holds the
+// cache ttl of the route, in seconds.
+const cacheTTLKey = "cache-ttl"
+
+// cacheBypassAuthorization is the Authorization header value that
+// FilterCacheScope adds to requests for routes without a cache policy.
+// Envoy's cache filter neither serves nor stores requests that carry
+// credentials, and FilterCacheControl removes the header again before
+// the request is routed.
+const cacheBypassAuthorization = "contour-cache-bypass"
+
 // FilterExternalAuthz returns an `ext_authz` filter configured with the
 // requested parameters.
 func FilterExternalAuthz(authzClusterName string, failOpen bool, timeout timeout.Setting) *http.HttpFilter {
@@ -590,13 +889,345 @@ func FilterExternalAuthz(authzClusterName string, failOpen bool, timeout timeout
 	}
 
 	return &http.HttpFilter{
-		Name: "envoy.filters.http.ext_authz",
+		Name: ExtAuthzFilterName,
 		ConfigType: &http.HttpFilter_TypedConfig{
 			TypedConfig: protobuf.MustMarshalAny(&authConfig),
 		},
 	}
 }
 
+// FilterBuffer returns a `buffer` filter. The filter is expected to
+// be configured per route with RouteBuffer, and disabled with
+// RouteBufferDisabled on routes that do not limit request bodies,
+// so the filter level maximum here is not normally applied.
+func FilterBuffer() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: BufferFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_buffer_v3.Buffer{
+				MaxRequestBytes: protobuf.UInt32(1024 * 1024),
+			}),
+		},
+	}
+}
+
+// FilterRBAC returns a `rbac` filter without any rules of its own.
+// The filter is expected to be configured per route with
+// RouteSourceRanges, so routes without a per-route config are not
+// restricted.
+func FilterRBAC() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: RBACFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_rbac_v3.RBAC{}),
+		},
+	}
+}
+
+// FilterFault returns a `fault` filter that injects no faults of
+// its own. The filter is expected to be configured per route with
+// RouteFault, so routes without a per-route config are unaffected.
+func FilterFault() *http.HttpFilter {
+	return &http.HttpFilter{
+		Name: FaultFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_fault_v3.HTTPFault{}),
+		},
+	}
+}
+
+// FilterResponseTimeoutHeader returns a `lua` filter that copies
+// the value of a route's response timeout header, named in the route
+// metadata set by RouteResponseTimeoutHeader, into the
+// x-envoy-upstream-rq-timeout-ms header that the router reads.
+// Only requests from internal clients, which Envoy marks with the
+// x-envoy-internal header, may override their timeout.
+func FilterResponseTimeoutHeader() *http.HttpFilter {
+	code := `
+function envoy_on_request(request_handle)
+	local name = request_handle:metadata():get("%s")
+	if name == nil then
+		return
+	end
+
+	local headers = request_handle:headers()
+	if headers:get("x-envoy-internal") ~= "true" then
+		return
+	end
+
+	local timeout = headers:get(name)
+	if timeout ~= nil then
+		headers:replace("x-envoy-upstream-rq-timeout-ms", timeout)
+	end
+end
+	`
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
+				InlineCode: fmt.Sprintf(code, responseTimeoutHeaderKey),
+			}),
+		},
+	}
+}
+
+// FilterOverwriteForwardedFor returns a Lua filter that replaces the
+// x-forwarded-for header of requests with its last address. When the
+// connection manager trusts no hops, that is the address of the
+// connection, which it has just appended, so any addresses that the
+// client sent itself are dropped.
+func FilterOverwriteForwardedFor() *http.HttpFilter {
+	const code = `
+function envoy_on_request(request_handle)
+	local headers = request_handle:headers()
+	local xff = headers:get("x-forwarded-for")
+	if xff == nil then
+		return
+	end
+
+	local last = string.match(xff, "([^,%s]+)%s*$")
+	if last ~= nil then
+		headers:replace("x-forwarded-for", last)
+	end
+end
+	`
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
+				InlineCode: code,
+			}),
+		},
+	}
+}
+
+// FilterRemoveOriginalDestinationHost returns a Lua filter that
+// removes the x-envoy-original-dst-host header from requests, so
+// that clients cannot choose the address that original destination
+// routes connect to.
+func FilterRemoveOriginalDestinationHost() *http.HttpFilter {
+	const code = `
+function envoy_on_request(request_handle)
+	request_handle:headers():remove("x-envoy-original-dst-host")
+end
+	`
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
+				InlineCode: code,
+			}),
+		},
+	}
+}
+
+// FilterHealthCheck returns a `health_check` filter that answers
+// requests for path itself, without routing them, or nil if path
+// is empty. The filter answers 503 once Envoy starts draining, so
+// load balancers stop sending it new connections.
+func FilterHealthCheck(path string) *http.HttpFilter {
+	if path == "" {
+		return nil
+	}
+
+	return &http.HttpFilter{
+		Name: "envoy.filters.http.health_check",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_health_check_v3.HealthCheck{
+				PassThroughMode: protobuf.Bool(false),
+				Headers: []*envoy_route_v3.HeaderMatcher{{
+					Name: ":path",
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_ExactMatch{
+						ExactMatch: path,
+					},
+				}},
+			}),
+		},
+	}
+}
+
+// FilterGRPCJSONTranscoder returns a `grpc_json_transcoder` filter
+// that translates JSON requests into requests to the methods of
+// the transcoder's gRPC services, or nil if t is nil. Transcoded
+// requests are routed again using their gRPC method path.
+func FilterGRPCJSONTranscoder(t *dag.GRPCJSONTranscoder) *http.HttpFilter {
+	if t == nil {
+		return nil
+	}
+
+	return &http.HttpFilter{
+		Name: "envoy.filters.http.grpc_json_transcoder",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_grpc_json_transcoder_v3.GrpcJsonTranscoder{
+				DescriptorSet: &envoy_config_filter_http_grpc_json_transcoder_v3.GrpcJsonTranscoder_ProtoDescriptorBin{
+					ProtoDescriptorBin: t.Descriptor,
+				},
+				Services: t.Services,
+			}),
+		},
+	}
+}
+
+// simpleHTTPCacheType is the type URL of the configuration of Envoy's
+// in-memory cache storage. go-control-plane does not include this type,
+// but since the message has no fields, an empty Any of the right type is
+// a valid configuration.
+const simpleHTTPCacheType = "type.googleapis.com/envoy.extensions.cache.simple_http_cache.v3alpha.SimpleHttpCacheConfig"
+
+// FilterCache returns a `cache` filter that stores responses in
+// Envoy's in-memory cache, keyed according to params.
+func FilterCache(params config.ResponseCacheParameters) *http.HttpFilter {
+	queryParams := func(names []string) []*envoy_route_v3.QueryParameterMatcher {
+		var matchers []*envoy_route_v3.QueryParameterMatcher
+		for _, name := range names {
+			matchers = append(matchers, &envoy_route_v3.QueryParameterMatcher{Name: name})
+		}
+		return matchers
+	}
+
+	return &http.HttpFilter{
+		Name: "envoy.filters.http.cache",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_cache_v3alpha.CacheConfig{
+				TypedConfig: &any.Any{TypeUrl: simpleHTTPCacheType},
+				KeyCreatorParams: &envoy_config_filter_http_cache_v3alpha.CacheConfig_KeyCreatorParams{
+					ExcludeScheme:           params.ExcludeScheme,
+					ExcludeHost:             params.ExcludeHost,
+					QueryParametersIncluded: queryParams(params.IncludeQueryParameters),
+					QueryParametersExcluded: queryParams(params.ExcludeQueryParameters),
+				},
+				MaxBodyBytes: params.MaxBodyBytes,
+			}),
+		},
+	}
+}
+
+// FilterCacheScope returns a `lua` filter that, placed before the
+// `cache` filter, keeps requests for routes without a cache policy out
+// of the cache. Envoy 1.16's cache filter has no per-route
+// configuration, so the filter marks those requests with an
+// Authorization header, which the cache filter does not serve or store.
+func FilterCacheScope() *http.HttpFilter {
+	code := `
+function envoy_on_request(request_handle)
+	if request_handle:metadata():get("%s") ~= nil then
+		return
+	end
+
+	local headers = request_handle:headers()
+	if headers:get("authorization") == nil then
+		headers:add("authorization", "%s")
+	end
+end
+	`
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
+				InlineCode: fmt.Sprintf(code, cacheTTLKey, cacheBypassAuthorization),
+			}),
+		},
+	}
+}
+
+// FilterCacheControl returns a `lua` filter that, placed after the
+// `cache` filter, removes the Authorization header added by
+// FilterCacheScope and adds a `Cache-Control: max-age` header, using
+// the route's cache ttl, to responses whose upstream did not send
+// Cache-Control. Upstream Cache-Control headers, including `private`
+// and `no-store`, are left untouched.
+func FilterCacheControl() *http.HttpFilter {
+	code := `
+function envoy_on_request(request_handle)
+	local headers = request_handle:headers()
+	if headers:get("authorization") == "%s" then
+		headers:remove("authorization")
+	end
+end
+
+function envoy_on_response(response_handle)
+	local ttl = response_handle:metadata():get("%s")
+	if ttl == nil then
+		return
+	end
+
+	local headers = response_handle:headers()
+	if headers:get("cache-control") == nil then
+		headers:add("cache-control", "max-age=" .. ttl)
+	end
+end
+	`
+
+	return &http.HttpFilter{
+		Name: LuaFilterName,
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&lua.Lua{
+				InlineCode: fmt.Sprintf(code, cacheBypassAuthorization, cacheTTLKey),
+			}),
+		},
+	}
+}
+
+// Tracing returns the connection manager tracing configuration for
+// params, or nil if no trace propagation format is configured.
+//
+// Tracing uses the OpenCensus tracer because it is the only tracer
+// that can read and write both B3 and W3C Trace Context headers. No
+// exporter is configured, so Envoy propagates trace context without
+// reporting spans itself.
+func Tracing(params config.TracingParameters) *http.HttpConnectionManager_Tracing {
+	if len(params.Propagation) == 0 {
+		return nil
+	}
+
+	var traceContext []envoy_trace_v3.OpenCensusConfig_TraceContext
+	for _, p := range params.Propagation {
+		switch p {
+		case config.B3TracePropagation:
+			traceContext = append(traceContext, envoy_trace_v3.OpenCensusConfig_B3)
+		case config.W3CTracePropagation:
+			traceContext = append(traceContext, envoy_trace_v3.OpenCensusConfig_TRACE_CONTEXT)
+		}
+	}
+
+	// Requests without incoming trace context are only
+	// traced if Envoy has been asked to start traces.
+	var randomSampling float64
+	if params.StartTraces {
+		randomSampling = 100
+	}
+
+	return &http.HttpConnectionManager_Tracing{
+		RandomSampling: &envoy_type.Percent{Value: randomSampling},
+		Provider: &envoy_trace_v3.Tracing_Http{
+			Name: "envoy.tracers.opencensus",
+			ConfigType: &envoy_trace_v3.Tracing_Http_TypedConfig{
+				TypedConfig: protobuf.MustMarshalAny(&envoy_trace_v3.OpenCensusConfig{
+					IncomingTraceContext: traceContext,
+					OutgoingTraceContext: traceContext,
+				}),
+			},
+		},
+	}
+}
+
+// ServerHeaderTransformation returns the connection manager server
+// header transformation for the configured transformation type.
+func ServerHeaderTransformation(t config.ServerHeaderTransformationType) http.HttpConnectionManager_ServerHeaderTransformation {
+	switch t {
+	case config.AppendIfAbsentServerHeader:
+		return http.HttpConnectionManager_APPEND_IF_ABSENT
+	case config.PassThroughServerHeader:
+		return http.HttpConnectionManager_PASS_THROUGH
+	default:
+		return http.HttpConnectionManager_OVERWRITE
+	}
+}
+
 // FilterChainTLS returns a TLS enabled envoy_listener_v3.FilterChain.
 func FilterChainTLS(domain string, downstream *envoy_tls_v3.DownstreamTlsContext, filters []*envoy_listener_v3.Filter) *envoy_listener_v3.FilterChain {
 	fc := &envoy_listener_v3.FilterChain{
@@ -629,6 +1260,31 @@ func FilterChainTLSFallback(downstream *envoy_tls_v3.DownstreamTlsContext, filte
 	return fc
 }
 
+// FilterChainSourceRanges returns a copy of the filter chain, with the
+// given filters, that only matches connections from addresses in the
+// supplied ranges. Envoy prefers it to the original filter chain for
+// those connections, since its match is more specific.
+func FilterChainSourceRanges(fc *envoy_listener_v3.FilterChain, ranges []*net.IPNet, filters []*envoy_listener_v3.Filter) *envoy_listener_v3.FilterChain {
+	c := proto.Clone(fc).(*envoy_listener_v3.FilterChain)
+	if c.FilterChainMatch == nil {
+		c.FilterChainMatch = &envoy_listener_v3.FilterChainMatch{}
+	}
+	if c.Name != "" {
+		c.Name += "/source-ranges"
+	}
+	c.Filters = filters
+
+	for _, r := range ranges {
+		prefixLen, _ := r.Mask.Size()
+		c.FilterChainMatch.SourcePrefixRanges = append(c.FilterChainMatch.SourcePrefixRanges, &envoy_core_v3.CidrRange{
+			AddressPrefix: r.IP.String(),
+			PrefixLen:     protobuf.UInt32(uint32(prefixLen)),
+		})
+	}
+
+	return c
+}
+
 // ListenerFilters returns a []*envoy_listener_v3.ListenerFilter for the supplied listener filters.
 func ListenerFilters(filters ...*envoy_listener_v3.ListenerFilter) []*envoy_listener_v3.ListenerFilter {
 	return filters
@@ -14,22 +14,31 @@
 package v3
 
 import (
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
 	envoy_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_trace_v3 "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
+	envoy_config_filter_http_cache_v3alpha "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cache/v3alpha"
 	envoy_compressor_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/compressor/v3"
+	envoy_config_filter_http_grpc_json_transcoder_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_json_transcoder/v3"
+	envoy_config_filter_http_health_check_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/health_check/v3"
 	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	envoy_tcp_proxy_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
 	"github.com/projectcontour/contour/internal/protobuf"
 	"github.com/projectcontour/contour/internal/timeout"
+	"github.com/projectcontour/contour/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
@@ -52,6 +61,13 @@ func TestProtoNamesForVersions(t *testing.T) {
 	assert.Equal(t, ProtoNamesForVersions(HTTPVersion3), []string(nil))
 	assert.Equal(t, ProtoNamesForVersions(HTTPVersion1, HTTPVersion2), []string{"h2", "http/1.1"})
 }
+
+func TestVersionsForProtoNames(t *testing.T) {
+	assert.Equal(t, VersionsForProtoNames(), []HTTPVersionType(nil))
+	assert.Equal(t, VersionsForProtoNames("http/1.1"), []HTTPVersionType{HTTPVersion1})
+	assert.Equal(t, VersionsForProtoNames("h2", "http/1.1"), []HTTPVersionType{HTTPVersion2, HTTPVersion1})
+	assert.Equal(t, VersionsForProtoNames("spdy/3"), []HTTPVersionType(nil))
+}
 func TestListener(t *testing.T) {
 	tests := map[string]struct {
 		name, address string
@@ -269,6 +285,27 @@ func TestDownstreamTLSContext(t *testing.T) {
 		SubjectName: subjectName,
 	}
 
+	peerValidationContextOptional := &dag.PeerValidationContext{
+		CACertificate:             peerValidationContext.CACertificate,
+		OptionalClientCertificate: true,
+	}
+
+	crl := []byte("client-crl")
+	peerValidationContextWithCRL := &dag.PeerValidationContext{
+		CACertificate: peerValidationContext.CACertificate,
+		CRL: &dag.Secret{
+			Object: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "crl",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					dag.CRLKey: crl,
+				},
+			},
+		},
+	}
+
 	tests := map[string]struct {
 		got  *envoy_tls_v3.DownstreamTlsContext
 		want *envoy_tls_v3.DownstreamTlsContext
@@ -295,6 +332,43 @@ func TestDownstreamTLSContext(t *testing.T) {
 				RequireClientCertificate: protobuf.Bool(true),
 			},
 		},
+		"TLS context with client authentication and CRL": {
+			DownstreamTLSContext(serverSecret, envoy_tls_v3.TlsParameters_TLSv1_1, peerValidationContextWithCRL, "h2", "http/1.1"),
+			&envoy_tls_v3.DownstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+					TlsParams:                      tlsParams,
+					TlsCertificateSdsSecretConfigs: tlsCertificateSdsSecretConfigs,
+					AlpnProtocols:                  alpnProtocols,
+					ValidationContextType: &envoy_tls_v3.CommonTlsContext_ValidationContext{
+						ValidationContext: &envoy_tls_v3.CertificateValidationContext{
+							TrustedCa: &envoy_core_v3.DataSource{
+								Specifier: &envoy_core_v3.DataSource_InlineBytes{
+									InlineBytes: ca,
+								},
+							},
+							Crl: &envoy_core_v3.DataSource{
+								Specifier: &envoy_core_v3.DataSource_InlineBytes{
+									InlineBytes: crl,
+								},
+							},
+						},
+					},
+				},
+				RequireClientCertificate: protobuf.Bool(true),
+			},
+		},
+		"TLS context with optional client authentication": {
+			DownstreamTLSContext(serverSecret, envoy_tls_v3.TlsParameters_TLSv1_1, peerValidationContextOptional, "h2", "http/1.1"),
+			&envoy_tls_v3.DownstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+					TlsParams:                      tlsParams,
+					TlsCertificateSdsSecretConfigs: tlsCertificateSdsSecretConfigs,
+					AlpnProtocols:                  alpnProtocols,
+					ValidationContextType:          validationContext,
+				},
+				RequireClientCertificate: protobuf.Bool(false),
+			},
+		},
 		"Downstream validation shall not support subjectName validation": {
 			DownstreamTLSContext(serverSecret, envoy_tls_v3.TlsParameters_TLSv1_1, peerValidationContextWithSubjectName, "h2", "http/1.1"),
 			&envoy_tls_v3.DownstreamTlsContext{
@@ -325,6 +399,9 @@ func TestHTTPConnectionManager(t *testing.T) {
 		streamIdleTimeout             timeout.Setting
 		maxConnectionDuration         timeout.Setting
 		connectionShutdownGracePeriod timeout.Setting
+		numTrustedHops                uint32
+		maxRequestHeadersKB           uint32
+		maxRequestHeadersCount        uint32
 		want                          *envoy_listener_v3.Filter
 	}{
 		"default": {
@@ -886,6 +963,169 @@ func TestHTTPConnectionManager(t *testing.T) {
 				},
 			},
 		},
+		"xff num trusted hops of 1": {
+			routename:      "default/kuard",
+			accesslogger:   FileAccessLogEnvoy("/dev/stdout"),
+			numTrustedHops: 1,
+			want: &envoy_listener_v3.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_core_v3.ConfigSource{
+									ResourceApiVersion: envoy_core_v3.ApiVersion_V3,
+									ConfigSourceSpecifier: &envoy_core_v3.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_core_v3.ApiConfigSource{
+											ApiType:             envoy_core_v3.ApiConfigSource_GRPC,
+											TransportApiVersion: envoy_core_v3.ApiVersion_V3,
+											GrpcServices: []*envoy_core_v3.GrpcService{{
+												TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: "compressor",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: protobuf.MustMarshalAny(&envoy_compressor_v3.Compressor{
+									CompressorLibrary: &envoy_core_v3.TypedExtensionConfig{
+										Name: "gzip",
+										TypedConfig: &any.Any{
+											TypeUrl: HTTPFilterGzip,
+										},
+									},
+								}),
+							},
+						}, {
+							Name: "grpcweb",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterGrpcWeb,
+								},
+							},
+						}, {
+							Name: "cors",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterCORS,
+								},
+							},
+						}, {
+							Name: "router",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterRouter,
+								},
+							},
+						}},
+						HttpProtocolOptions: &envoy_core_v3.Http1ProtocolOptions{
+							// Enable support for HTTP/1.0 requests that carry
+							// a Host: header. See #537.
+							AcceptHttp_10: true,
+						},
+						CommonHttpProtocolOptions: &envoy_core_v3.HttpProtocolOptions{},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						XffNumTrustedHops:         1,
+						NormalizePath:             protobuf.Bool(true),
+						PreserveExternalRequestId: true,
+						MergeSlashes:              true,
+					}),
+				},
+			},
+		},
+		"request header limits": {
+			routename:              "default/kuard",
+			accesslogger:           FileAccessLogEnvoy("/dev/stdout"),
+			maxRequestHeadersKB:    96,
+			maxRequestHeadersCount: 200,
+			want: &envoy_listener_v3.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&http.HttpConnectionManager{
+						StatPrefix: "default/kuard",
+						RouteSpecifier: &http.HttpConnectionManager_Rds{
+							Rds: &http.Rds{
+								RouteConfigName: "default/kuard",
+								ConfigSource: &envoy_core_v3.ConfigSource{
+									ResourceApiVersion: envoy_core_v3.ApiVersion_V3,
+									ConfigSourceSpecifier: &envoy_core_v3.ConfigSource_ApiConfigSource{
+										ApiConfigSource: &envoy_core_v3.ApiConfigSource{
+											ApiType:             envoy_core_v3.ApiConfigSource_GRPC,
+											TransportApiVersion: envoy_core_v3.ApiVersion_V3,
+											GrpcServices: []*envoy_core_v3.GrpcService{{
+												TargetSpecifier: &envoy_core_v3.GrpcService_EnvoyGrpc_{
+													EnvoyGrpc: &envoy_core_v3.GrpcService_EnvoyGrpc{
+														ClusterName: "contour",
+													},
+												},
+											}},
+										},
+									},
+								},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: "compressor",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: protobuf.MustMarshalAny(&envoy_compressor_v3.Compressor{
+									CompressorLibrary: &envoy_core_v3.TypedExtensionConfig{
+										Name: "gzip",
+										TypedConfig: &any.Any{
+											TypeUrl: HTTPFilterGzip,
+										},
+									},
+								}),
+							},
+						}, {
+							Name: "grpcweb",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterGrpcWeb,
+								},
+							},
+						}, {
+							Name: "cors",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterCORS,
+								},
+							},
+						}, {
+							Name: "router",
+							ConfigType: &http.HttpFilter_TypedConfig{
+								TypedConfig: &any.Any{
+									TypeUrl: HTTPFilterRouter,
+								},
+							},
+						}},
+						HttpProtocolOptions: &envoy_core_v3.Http1ProtocolOptions{
+							// Enable support for HTTP/1.0 requests that carry
+							// a Host: header. See #537.
+							AcceptHttp_10: true,
+						},
+						CommonHttpProtocolOptions: &envoy_core_v3.HttpProtocolOptions{
+							MaxHeadersCount: protobuf.UInt32(200),
+						},
+						AccessLog:                 FileAccessLogEnvoy("/dev/stdout"),
+						UseRemoteAddress:          protobuf.Bool(true),
+						MaxRequestHeadersKb:       protobuf.UInt32(96),
+						NormalizePath:             protobuf.Bool(true),
+						PreserveExternalRequestId: true,
+						MergeSlashes:              true,
+					}),
+				},
+			},
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -898,6 +1138,8 @@ func TestHTTPConnectionManager(t *testing.T) {
 				StreamIdleTimeout(tc.streamIdleTimeout).
 				MaxConnectionDuration(tc.maxConnectionDuration).
 				ConnectionShutdownGracePeriod(tc.connectionShutdownGracePeriod).
+				NumTrustedHops(tc.numTrustedHops).
+				RequestHeaderLimits(tc.maxRequestHeadersKB, tc.maxRequestHeadersCount).
 				DefaultFilters().
 				Get()
 
@@ -906,6 +1148,171 @@ func TestHTTPConnectionManager(t *testing.T) {
 	}
 }
 
+func TestLocalReplyConfig(t *testing.T) {
+	got := localReplyConfig([]ErrorPage{{
+		StatusCode: 503,
+		Body:       "<h1>unavailable</h1>",
+	}, {
+		StatusCode:  504,
+		Body:        "timed out",
+		ContentType: "text/plain",
+	}}, 0)
+
+	mapper := func(code uint32, body string, contentType string) *http.ResponseMapper {
+		return &http.ResponseMapper{
+			Filter: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_StatusCodeFilter{
+					StatusCodeFilter: &envoy_accesslog_v3.StatusCodeFilter{
+						Comparison: &envoy_accesslog_v3.ComparisonFilter{
+							Op: envoy_accesslog_v3.ComparisonFilter_EQ,
+							Value: &envoy_core_v3.RuntimeUInt32{
+								DefaultValue: code,
+								RuntimeKey:   fmt.Sprintf("contour.error_page.%d", code),
+							},
+						},
+					},
+				},
+			},
+			Body: &envoy_core_v3.DataSource{
+				Specifier: &envoy_core_v3.DataSource_InlineString{
+					InlineString: body,
+				},
+			},
+			BodyFormatOverride: &envoy_core_v3.SubstitutionFormatString{
+				Format: &envoy_core_v3.SubstitutionFormatString_TextFormat{
+					TextFormat: "%LOCAL_REPLY_BODY%",
+				},
+				ContentType: contentType,
+			},
+		}
+	}
+
+	want := &http.LocalReplyConfig{
+		Mappers: []*http.ResponseMapper{
+			mapper(503, "<h1>unavailable</h1>", "text/html"),
+			mapper(504, "timed out", "text/plain"),
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestLocalReplyConfigRetryAfter(t *testing.T) {
+	got := localReplyConfig([]ErrorPage{{
+		StatusCode: 503,
+		Body:       "unavailable",
+	}}, 1500*time.Millisecond)
+
+	want := &http.LocalReplyConfig{
+		Mappers: []*http.ResponseMapper{{
+			Filter: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_ResponseFlagFilter{
+					ResponseFlagFilter: &envoy_accesslog_v3.ResponseFlagFilter{
+						Flags: []string{"RL"},
+					},
+				},
+			},
+			HeadersToAdd: []*envoy_core_v3.HeaderValueOption{{
+				Header: &envoy_core_v3.HeaderValue{
+					Key:   "Retry-After",
+					Value: "2",
+				},
+				Append: protobuf.Bool(false),
+			}},
+		}, {
+			Filter: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_StatusCodeFilter{
+					StatusCodeFilter: &envoy_accesslog_v3.StatusCodeFilter{
+						Comparison: &envoy_accesslog_v3.ComparisonFilter{
+							Op: envoy_accesslog_v3.ComparisonFilter_EQ,
+							Value: &envoy_core_v3.RuntimeUInt32{
+								DefaultValue: 503,
+								RuntimeKey:   "contour.error_page.503",
+							},
+						},
+					},
+				},
+			},
+			Body: &envoy_core_v3.DataSource{
+				Specifier: &envoy_core_v3.DataSource_InlineString{
+					InlineString: "unavailable",
+				},
+			},
+			BodyFormatOverride: &envoy_core_v3.SubstitutionFormatString{
+				Format: &envoy_core_v3.SubstitutionFormatString_TextFormat{
+					TextFormat: "%LOCAL_REPLY_BODY%",
+				},
+				ContentType: "text/html",
+			},
+		}},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+// Test that the error page of rate limited responses is
+// merged into the mapper that adds the Retry-After header,
+// since Envoy only applies the first matching mapper.
+func TestLocalReplyConfigRetryAfterErrorPage(t *testing.T) {
+	got := localReplyConfig([]ErrorPage{{
+		StatusCode:  429,
+		Body:        "slow down",
+		ContentType: "text/plain",
+	}}, 10*time.Second)
+
+	body := &envoy_core_v3.DataSource{
+		Specifier: &envoy_core_v3.DataSource_InlineString{
+			InlineString: "slow down",
+		},
+	}
+	format := &envoy_core_v3.SubstitutionFormatString{
+		Format: &envoy_core_v3.SubstitutionFormatString_TextFormat{
+			TextFormat: "%LOCAL_REPLY_BODY%",
+		},
+		ContentType: "text/plain",
+	}
+
+	want := &http.LocalReplyConfig{
+		Mappers: []*http.ResponseMapper{{
+			Filter: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_ResponseFlagFilter{
+					ResponseFlagFilter: &envoy_accesslog_v3.ResponseFlagFilter{
+						Flags: []string{"RL"},
+					},
+				},
+			},
+			HeadersToAdd: []*envoy_core_v3.HeaderValueOption{{
+				Header: &envoy_core_v3.HeaderValue{
+					Key:   "Retry-After",
+					Value: "10",
+				},
+				Append: protobuf.Bool(false),
+			}},
+			Body:               body,
+			BodyFormatOverride: format,
+		}, {
+			// Other 429 local replies still get the error page.
+			Filter: &envoy_accesslog_v3.AccessLogFilter{
+				FilterSpecifier: &envoy_accesslog_v3.AccessLogFilter_StatusCodeFilter{
+					StatusCodeFilter: &envoy_accesslog_v3.StatusCodeFilter{
+						Comparison: &envoy_accesslog_v3.ComparisonFilter{
+							Op: envoy_accesslog_v3.ComparisonFilter_EQ,
+							Value: &envoy_core_v3.RuntimeUInt32{
+								DefaultValue: 429,
+								RuntimeKey:   "contour.error_page.429",
+							},
+						},
+					},
+				},
+			},
+			Body:               body,
+			BodyFormatOverride: format,
+		}},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
 func TestTCPProxy(t *testing.T) {
 	const (
 		statPrefix    = "ingress_https"
@@ -963,6 +1370,30 @@ func TestTCPProxy(t *testing.T) {
 				},
 			},
 		},
+		"source ip hash": {
+			proxy: &dag.TCPProxy{
+				Clusters:     []*dag.Cluster{c1},
+				HashSourceIP: true,
+			},
+			want: &envoy_listener_v3.Filter{
+				Name: wellknown.TCPProxy,
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_tcp_proxy_v3.TcpProxy{
+						StatPrefix: statPrefix,
+						ClusterSpecifier: &envoy_tcp_proxy_v3.TcpProxy_Cluster{
+							Cluster: envoy.Clustername(c1),
+						},
+						AccessLog:   FileAccessLogEnvoy(accessLogPath),
+						IdleTimeout: protobuf.Duration(9001 * time.Second),
+						HashPolicy: []*envoy_type.HashPolicy{{
+							PolicySpecifier: &envoy_type.HashPolicy_SourceIp_{
+								SourceIp: &envoy_type.HashPolicy_SourceIp{},
+							},
+						}},
+					}),
+				},
+			},
+		},
 		"multiple cluster": {
 			proxy: &dag.TCPProxy{
 				Clusters: []*dag.Cluster{c2, c1},
@@ -999,6 +1430,37 @@ func TestTCPProxy(t *testing.T) {
 	}
 }
 
+func TestFilterChainSourceRanges(t *testing.T) {
+	filters := Filters(HTTPConnectionManager("fallback", nil, 0))
+	fc := FilterChainTLSFallback(nil, nil)
+
+	got := FilterChainSourceRanges(fc, []*net.IPNet{
+		{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+		{IP: net.ParseIP("fd00::"), Mask: net.CIDRMask(16, 128)},
+	}, filters)
+
+	want := &envoy_listener_v3.FilterChain{
+		Name:    "fallback-certificate/source-ranges",
+		Filters: filters,
+		FilterChainMatch: &envoy_listener_v3.FilterChainMatch{
+			TransportProtocol: "tls",
+			SourcePrefixRanges: []*envoy_core_v3.CidrRange{{
+				AddressPrefix: "10.0.0.0",
+				PrefixLen:     protobuf.UInt32(8),
+			}, {
+				AddressPrefix: "fd00::",
+				PrefixLen:     protobuf.UInt32(16),
+			}},
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+
+	// The original filter chain is not modified.
+	assert.Empty(t, fc.FilterChainMatch.SourcePrefixRanges)
+	assert.Equal(t, "fallback-certificate", fc.Name)
+}
+
 // TestBuilderValidation tests that validation checks that
 // DefaultFilters adds the required HTTP connection manager filters.
 func TestBuilderValidation(t *testing.T) {
@@ -1121,6 +1583,28 @@ func TestAddFilter(t *testing.T) {
 				},
 			},
 		},
+		"Add a filter to a builder without a router": {
+			builder: HTTPConnectionManagerBuilder().AddFilter(FilterHealthCheck("/healthz")),
+			add: &http.HttpFilter{
+				Name: "grpcweb",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: &any.Any{
+						TypeUrl: HTTPFilterGrpcWeb,
+					},
+				},
+			},
+			want: []*http.HttpFilter{
+				FilterHealthCheck("/healthz"),
+				{
+					Name: "grpcweb",
+					ConfigType: &http.HttpFilter_TypedConfig{
+						TypedConfig: &any.Any{
+							TypeUrl: HTTPFilterGrpcWeb,
+						},
+					},
+				},
+			},
+		},
 		"Add to the default filters": {
 			builder: HTTPConnectionManagerBuilder().DefaultFilters(),
 			add:     FilterExternalAuthz("test", false, timeout.Setting{}),
@@ -1185,3 +1669,158 @@ func TestAddFilter(t *testing.T) {
 		})
 	})
 }
+
+func TestFilterCache(t *testing.T) {
+	got := FilterCache(config.ResponseCacheParameters{
+		MaxBodyBytes:           1024,
+		ExcludeHost:            true,
+		IncludeQueryParameters: []string{"page"},
+		ExcludeQueryParameters: []string{"utm_source"},
+	})
+
+	want := &http.HttpFilter{
+		Name: "envoy.filters.http.cache",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_cache_v3alpha.CacheConfig{
+				TypedConfig: &any.Any{
+					TypeUrl: "type.googleapis.com/envoy.extensions.cache.simple_http_cache.v3alpha.SimpleHttpCacheConfig",
+				},
+				KeyCreatorParams: &envoy_config_filter_http_cache_v3alpha.CacheConfig_KeyCreatorParams{
+					ExcludeHost: true,
+					QueryParametersIncluded: []*envoy_route_v3.QueryParameterMatcher{
+						{Name: "page"},
+					},
+					QueryParametersExcluded: []*envoy_route_v3.QueryParameterMatcher{
+						{Name: "utm_source"},
+					},
+				},
+				MaxBodyBytes: 1024,
+			}),
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestFilterHealthCheck(t *testing.T) {
+	tests := map[string]struct {
+		path string
+		want *http.HttpFilter
+	}{
+		"empty path produces nil filter": {
+			path: "",
+			want: nil,
+		},
+		"path": {
+			path: "/healthz",
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.health_check",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_health_check_v3.HealthCheck{
+						PassThroughMode: protobuf.Bool(false),
+						Headers: []*envoy_route_v3.HeaderMatcher{{
+							Name: ":path",
+							HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_ExactMatch{
+								ExactMatch: "/healthz",
+							},
+						}},
+					}),
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, FilterHealthCheck(tc.path))
+		})
+	}
+}
+
+func TestFilterGRPCJSONTranscoder(t *testing.T) {
+	tests := map[string]struct {
+		transcoder *dag.GRPCJSONTranscoder
+		want       *http.HttpFilter
+	}{
+		"nil transcoder produces nil filter": {
+			transcoder: nil,
+			want:       nil,
+		},
+		"transcoder": {
+			transcoder: &dag.GRPCJSONTranscoder{
+				Descriptor: []byte("descriptor"),
+				Services:   []string{"helloworld.Greeter"},
+			},
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.grpc_json_transcoder",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_config_filter_http_grpc_json_transcoder_v3.GrpcJsonTranscoder{
+						DescriptorSet: &envoy_config_filter_http_grpc_json_transcoder_v3.GrpcJsonTranscoder_ProtoDescriptorBin{
+							ProtoDescriptorBin: []byte("descriptor"),
+						},
+						Services: []string{"helloworld.Greeter"},
+					}),
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, FilterGRPCJSONTranscoder(tc.transcoder))
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	tracing := func(sampling float64, traceContext ...envoy_trace_v3.OpenCensusConfig_TraceContext) *http.HttpConnectionManager_Tracing {
+		return &http.HttpConnectionManager_Tracing{
+			RandomSampling: &envoy_type.Percent{Value: sampling},
+			Provider: &envoy_trace_v3.Tracing_Http{
+				Name: "envoy.tracers.opencensus",
+				ConfigType: &envoy_trace_v3.Tracing_Http_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&envoy_trace_v3.OpenCensusConfig{
+						IncomingTraceContext: traceContext,
+						OutgoingTraceContext: traceContext,
+					}),
+				},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		params config.TracingParameters
+		want   *http.HttpConnectionManager_Tracing
+	}{
+		"disabled": {
+			params: config.TracingParameters{},
+			want:   nil,
+		},
+		"b3": {
+			params: config.TracingParameters{
+				Propagation: []config.TracePropagationType{config.B3TracePropagation},
+			},
+			want: tracing(0, envoy_trace_v3.OpenCensusConfig_B3),
+		},
+		"w3c and b3, starting traces": {
+			params: config.TracingParameters{
+				Propagation: []config.TracePropagationType{config.W3CTracePropagation, config.B3TracePropagation},
+				StartTraces: true,
+			},
+			want: tracing(100, envoy_trace_v3.OpenCensusConfig_TRACE_CONTEXT, envoy_trace_v3.OpenCensusConfig_B3),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, Tracing(tc.params))
+		})
+	}
+}
+
+func TestServerHeaderTransformation(t *testing.T) {
+	assert.Equal(t, http.HttpConnectionManager_OVERWRITE, ServerHeaderTransformation(""))
+	assert.Equal(t, http.HttpConnectionManager_OVERWRITE, ServerHeaderTransformation(config.OverwriteServerHeader))
+	assert.Equal(t, http.HttpConnectionManager_APPEND_IF_ABSENT, ServerHeaderTransformation(config.AppendIfAbsentServerHeader))
+	assert.Equal(t, http.HttpConnectionManager_PASS_THROUGH, ServerHeaderTransformation(config.PassThroughServerHeader))
+}
@@ -0,0 +1,40 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+)
+
+// OriginalDestinationClusterName is the name of the cluster that
+// forwards requests to the address named in each request.
+const OriginalDestinationClusterName = "original_destination"
+
+// OriginalDestinationCluster returns an original destination cluster,
+// which connects to the address named in the x-envoy-original-dst-host
+// header of each request.
+func OriginalDestinationCluster() *envoy_cluster_v3.Cluster {
+	cluster := clusterDefaults()
+
+	cluster.Name = OriginalDestinationClusterName
+	cluster.ClusterDiscoveryType = ClusterDiscoveryType(envoy_cluster_v3.Cluster_ORIGINAL_DST)
+	cluster.LbPolicy = envoy_cluster_v3.Cluster_CLUSTER_PROVIDED
+	cluster.LbConfig = &envoy_cluster_v3.Cluster_OriginalDstLbConfig_{
+		OriginalDstLbConfig: &envoy_cluster_v3.Cluster_OriginalDstLbConfig{
+			UseHttpHeader: true,
+		},
+	}
+
+	return cluster
+}
@@ -0,0 +1,41 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+func TestOriginalDestinationCluster(t *testing.T) {
+	got := OriginalDestinationCluster()
+
+	want := &envoy_cluster_v3.Cluster{
+		Name:                 "original_destination",
+		ConnectTimeout:       protobuf.Duration(250 * time.Millisecond),
+		CommonLbConfig:       ClusterCommonLBConfig(),
+		ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_ORIGINAL_DST),
+		LbPolicy:             envoy_cluster_v3.Cluster_CLUSTER_PROVIDED,
+		LbConfig: &envoy_cluster_v3.Cluster_OriginalDstLbConfig_{
+			OriginalDstLbConfig: &envoy_cluster_v3.Cluster_OriginalDstLbConfig{
+				UseHttpHeader: true,
+			},
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
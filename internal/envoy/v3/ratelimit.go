@@ -0,0 +1,133 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimit_config_v3 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	ratelimit_filter_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/timeout"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GlobalRateLimits converts a dag.RateLimitPolicy to a slice of
+// Envoy rate limits, each containing the descriptor actions that
+// Envoy evaluates for the resource.
+func GlobalRateLimits(policy *dag.RateLimitPolicy) []*envoy_route_v3.RateLimit {
+	if policy == nil || policy.Global == nil {
+		return nil
+	}
+
+	var rateLimits []*envoy_route_v3.RateLimit
+	for _, descriptor := range policy.Global.Descriptors {
+		var rl envoy_route_v3.RateLimit
+
+		for _, entry := range descriptor.Entries {
+			switch {
+			case entry.GenericKey != nil:
+				rl.Actions = append(rl.Actions, &envoy_route_v3.RateLimit_Action{
+					ActionSpecifier: &envoy_route_v3.RateLimit_Action_GenericKey_{
+						GenericKey: &envoy_route_v3.RateLimit_Action_GenericKey{
+							DescriptorKey:   entry.GenericKey.Key,
+							DescriptorValue: entry.GenericKey.Value,
+						},
+					},
+				})
+			case entry.HeaderMatch != nil:
+				rl.Actions = append(rl.Actions, &envoy_route_v3.RateLimit_Action{
+					ActionSpecifier: &envoy_route_v3.RateLimit_Action_RequestHeaders_{
+						RequestHeaders: &envoy_route_v3.RateLimit_Action_RequestHeaders{
+							HeaderName:    entry.HeaderMatch.HeaderName,
+							DescriptorKey: entry.HeaderMatch.Key,
+						},
+					},
+				})
+			case entry.RemoteAddress != nil:
+				rl.Actions = append(rl.Actions, &envoy_route_v3.RateLimit_Action{
+					ActionSpecifier: &envoy_route_v3.RateLimit_Action_RemoteAddress_{
+						RemoteAddress: &envoy_route_v3.RateLimit_Action_RemoteAddress{},
+					},
+				})
+			}
+		}
+
+		rateLimits = append(rateLimits, &rl)
+	}
+
+	return rateLimits
+}
+
+// GlobalRateLimitConfig stores configuration for
+// an HTTP global rate limiting filter.
+type GlobalRateLimitConfig struct {
+	// ExtensionService identifies the ExtensionService
+	// that implements the rate limit service.
+	ExtensionService types.NamespacedName
+
+	// FailOpen allows requests through when the rate
+	// limit service can not be reached or returns an error.
+	FailOpen bool
+
+	// Timeout is the timeout for requests to the rate
+	// limit service.
+	Timeout timeout.Setting
+
+	// Domain is passed to the rate limit service.
+	Domain string
+
+	// EnableXRateLimitHeaders adds the X-RateLimit-Limit,
+	// X-RateLimit-Remaining and X-RateLimit-Reset headers
+	// to responses, using the rate limit service's decision.
+	EnableXRateLimitHeaders bool
+}
+
+// GlobalRateLimitFilter returns a configured HTTP global rate limit filter,
+// or nil if config is nil.
+func GlobalRateLimitFilter(config *GlobalRateLimitConfig) *http.HttpFilter {
+	if config == nil {
+		return nil
+	}
+
+	headers := ratelimit_filter_v3.RateLimit_OFF
+	if config.EnableXRateLimitHeaders {
+		headers = ratelimit_filter_v3.RateLimit_DRAFT_VERSION_03
+	}
+
+	return &http.HttpFilter{
+		Name: "envoy.filters.http.ratelimit",
+		ConfigType: &http.HttpFilter_TypedConfig{
+			TypedConfig: protobuf.MustMarshalAny(&ratelimit_filter_v3.RateLimit{
+				Domain:                  config.Domain,
+				Timeout:                 envoy.Timeout(config.Timeout),
+				FailureModeDeny:         !config.FailOpen,
+				EnableXRatelimitHeaders: headers,
+				RateLimitService: &ratelimit_config_v3.RateLimitServiceConfig{
+					GrpcService: &envoy_config_core_v3.GrpcService{
+						TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+							EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+								ClusterName: dag.ExtensionClusterName(config.ExtensionService),
+							},
+						},
+					},
+					TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+				},
+			}),
+		},
+	}
+}
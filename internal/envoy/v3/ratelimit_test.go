@@ -0,0 +1,200 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimit_config_v3 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	ratelimit_filter_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/timeout"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGlobalRateLimits(t *testing.T) {
+	tests := map[string]struct {
+		policy *dag.RateLimitPolicy
+		want   []*envoy_route_v3.RateLimit
+	}{
+		"nil policy": {
+			policy: nil,
+			want:   nil,
+		},
+		"nil global policy": {
+			policy: &dag.RateLimitPolicy{},
+			want:   nil,
+		},
+		"multiple descriptors": {
+			policy: &dag.RateLimitPolicy{
+				Global: &dag.GlobalRateLimitPolicy{
+					Descriptors: []*dag.RateLimitDescriptor{
+						{
+							Entries: []dag.RateLimitDescriptorEntry{
+								{RemoteAddress: &dag.RemoteAddressDescriptorEntry{}},
+								{GenericKey: &dag.GenericKeyDescriptorEntry{Key: "generic-key-key", Value: "generic-key-value"}},
+							},
+						},
+						{
+							Entries: []dag.RateLimitDescriptorEntry{
+								{HeaderMatch: &dag.HeaderMatchDescriptorEntry{HeaderName: "X-Api-Key", Key: "api-key"}},
+							},
+						},
+					},
+				},
+			},
+			want: []*envoy_route_v3.RateLimit{
+				{
+					Actions: []*envoy_route_v3.RateLimit_Action{
+						{
+							ActionSpecifier: &envoy_route_v3.RateLimit_Action_RemoteAddress_{
+								RemoteAddress: &envoy_route_v3.RateLimit_Action_RemoteAddress{},
+							},
+						},
+						{
+							ActionSpecifier: &envoy_route_v3.RateLimit_Action_GenericKey_{
+								GenericKey: &envoy_route_v3.RateLimit_Action_GenericKey{
+									DescriptorKey:   "generic-key-key",
+									DescriptorValue: "generic-key-value",
+								},
+							},
+						},
+					},
+				},
+				{
+					Actions: []*envoy_route_v3.RateLimit_Action{
+						{
+							ActionSpecifier: &envoy_route_v3.RateLimit_Action_RequestHeaders_{
+								RequestHeaders: &envoy_route_v3.RateLimit_Action_RequestHeaders{
+									HeaderName:    "X-Api-Key",
+									DescriptorKey: "api-key",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, GlobalRateLimits(tc.policy))
+		})
+	}
+}
+
+func TestGlobalRateLimitFilter(t *testing.T) {
+	tests := map[string]struct {
+		cfg  *GlobalRateLimitConfig
+		want *http.HttpFilter
+	}{
+		"nil config produces nil filter": {
+			cfg:  nil,
+			want: nil,
+		},
+		"all fields set": {
+			cfg: &GlobalRateLimitConfig{
+				ExtensionService: types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+				FailOpen:         true,
+				Timeout:          timeout.DurationSetting(7 * time.Second),
+				Domain:           "domain",
+			},
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.ratelimit",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&ratelimit_filter_v3.RateLimit{
+						Domain:          "domain",
+						Timeout:         protobuf.Duration(7 * time.Second),
+						FailureModeDeny: false,
+						RateLimitService: &ratelimit_config_v3.RateLimitServiceConfig{
+							GrpcService: &envoy_config_core_v3.GrpcService{
+								TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+										ClusterName: "extension/projectcontour/ratelimit",
+									},
+								},
+							},
+							TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+						},
+					}),
+				},
+			},
+		},
+		"x-ratelimit headers": {
+			cfg: &GlobalRateLimitConfig{
+				ExtensionService:        types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+				Domain:                  "domain",
+				EnableXRateLimitHeaders: true,
+			},
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.ratelimit",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&ratelimit_filter_v3.RateLimit{
+						Domain:                  "domain",
+						FailureModeDeny:         true,
+						EnableXRatelimitHeaders: ratelimit_filter_v3.RateLimit_DRAFT_VERSION_03,
+						RateLimitService: &ratelimit_config_v3.RateLimitServiceConfig{
+							GrpcService: &envoy_config_core_v3.GrpcService{
+								TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+										ClusterName: "extension/projectcontour/ratelimit",
+									},
+								},
+							},
+							TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+						},
+					}),
+				},
+			},
+		},
+		"fail closed": {
+			cfg: &GlobalRateLimitConfig{
+				ExtensionService: types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+				Domain:           "domain",
+			},
+			want: &http.HttpFilter{
+				Name: "envoy.filters.http.ratelimit",
+				ConfigType: &http.HttpFilter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&ratelimit_filter_v3.RateLimit{
+						Domain:          "domain",
+						FailureModeDeny: true,
+						RateLimitService: &ratelimit_config_v3.RateLimitServiceConfig{
+							GrpcService: &envoy_config_core_v3.GrpcService{
+								TargetSpecifier: &envoy_config_core_v3.GrpcService_EnvoyGrpc_{
+									EnvoyGrpc: &envoy_config_core_v3.GrpcService_EnvoyGrpc{
+										ClusterName: "extension/projectcontour/ratelimit",
+									},
+								},
+							},
+							TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
+						},
+					}),
+				},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, GlobalRateLimitFilter(tc.cfg))
+		})
+	}
+}
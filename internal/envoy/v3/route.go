@@ -15,15 +15,25 @@ package v3
 
 import (
 	"fmt"
+	"net"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_config_rbac_v3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_config_filter_fault_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	envoy_config_filter_http_buffer_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/buffer/v3"
 	envoy_config_filter_http_ext_authz_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	envoy_config_filter_http_fault_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	envoy_config_filter_http_rbac_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/protobuf/ptypes/any"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/envoy"
@@ -56,6 +66,162 @@ func RouteAuthzContext(settings map[string]string) *any.Any {
 	)
 }
 
+// RouteBufferDisabled returns a per-route config to disable request buffering.
+func RouteBufferDisabled() *any.Any {
+	return protobuf.MustMarshalAny(
+		&envoy_config_filter_http_buffer_v3.BufferPerRoute{
+			Override: &envoy_config_filter_http_buffer_v3.BufferPerRoute_Disabled{
+				Disabled: true,
+			},
+		},
+	)
+}
+
+// RouteBuffer returns a per-route config to buffer request bodies
+// of up to maxRequestBytes, rejecting larger requests.
+func RouteBuffer(maxRequestBytes uint32) *any.Any {
+	return protobuf.MustMarshalAny(
+		&envoy_config_filter_http_buffer_v3.BufferPerRoute{
+			Override: &envoy_config_filter_http_buffer_v3.BufferPerRoute_Buffer{
+				Buffer: &envoy_config_filter_http_buffer_v3.Buffer{
+					MaxRequestBytes: protobuf.UInt32(maxRequestBytes),
+				},
+			},
+		},
+	)
+}
+
+// RouteSourceRanges returns a per-route config that only allows
+// requests from client addresses in the supplied ranges.
+func RouteSourceRanges(ranges []*net.IPNet) *any.Any {
+	var principals []*envoy_config_rbac_v3.Principal
+	for _, r := range ranges {
+		prefixLen, _ := r.Mask.Size()
+		principals = append(principals, &envoy_config_rbac_v3.Principal{
+			Identifier: &envoy_config_rbac_v3.Principal_RemoteIp{
+				RemoteIp: &envoy_core_v3.CidrRange{
+					AddressPrefix: r.IP.String(),
+					PrefixLen:     protobuf.UInt32(uint32(prefixLen)),
+				},
+			},
+		})
+	}
+
+	return protobuf.MustMarshalAny(
+		&envoy_config_filter_http_rbac_v3.RBACPerRoute{
+			Rbac: &envoy_config_filter_http_rbac_v3.RBAC{
+				Rules: &envoy_config_rbac_v3.RBAC{
+					Action: envoy_config_rbac_v3.RBAC_ALLOW,
+					Policies: map[string]*envoy_config_rbac_v3.Policy{
+						"source-ranges": {
+							Permissions: []*envoy_config_rbac_v3.Permission{{
+								Rule: &envoy_config_rbac_v3.Permission_Any{Any: true},
+							}},
+							Principals: principals,
+						},
+					},
+				},
+			},
+		},
+	)
+}
+
+// RouteFault returns a per-route config that injects the faults
+// of the supplied policy.
+func RouteFault(fp *dag.FaultPolicy) *any.Any {
+	percent := func(p uint32) *envoy_type.FractionalPercent {
+		return &envoy_type.FractionalPercent{
+			Numerator:   p,
+			Denominator: envoy_type.FractionalPercent_HUNDRED,
+		}
+	}
+
+	var fault envoy_config_filter_http_fault_v3.HTTPFault
+
+	if fp.DelayDuration > 0 {
+		fault.Delay = &envoy_config_filter_fault_v3.FaultDelay{
+			FaultDelaySecifier: &envoy_config_filter_fault_v3.FaultDelay_FixedDelay{
+				FixedDelay: protobuf.Duration(fp.DelayDuration),
+			},
+			Percentage: percent(fp.DelayPercent),
+		}
+	}
+
+	if fp.AbortStatusCode > 0 {
+		fault.Abort = &envoy_config_filter_http_fault_v3.FaultAbort{
+			ErrorType: &envoy_config_filter_http_fault_v3.FaultAbort_HttpStatus{
+				HttpStatus: fp.AbortStatusCode,
+			},
+			Percentage: percent(fp.AbortPercent),
+		}
+	}
+
+	return protobuf.MustMarshalAny(&fault)
+}
+
+// RouteResponseTimeoutHeader returns the route metadata that names
+// the request header read by FilterResponseTimeoutHeader, or nil if
+// header is empty.
+func RouteResponseTimeoutHeader(header string) *envoy_core_v3.Metadata {
+	if header == "" {
+		return nil
+	}
+
+	return &envoy_core_v3.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			LuaFilterName: {
+				Fields: map[string]*_struct.Value{
+					responseTimeoutHeaderKey: {
+						Kind: &_struct.Value_StringValue{StringValue: header},
+					},
+				},
+			},
+		},
+	}
+}
+
+// RouteCacheTTL returns the route metadata that holds the cache
+// ttl, in seconds, that FilterCacheControl applies to responses
+// without a Cache-Control header, or nil if ttl is zero.
+func RouteCacheTTL(ttl time.Duration) *envoy_core_v3.Metadata {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &envoy_core_v3.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			LuaFilterName: {
+				Fields: map[string]*_struct.Value{
+					cacheTTLKey: {
+						Kind: &_struct.Value_StringValue{StringValue: strconv.FormatInt(int64(ttl.Seconds()), 10)},
+					},
+				},
+			},
+		},
+	}
+}
+
+// LuaMetadata merges the Lua filter metadata of mds into a single
+// route metadata, or returns nil if none of mds is set.
+func LuaMetadata(mds ...*envoy_core_v3.Metadata) *envoy_core_v3.Metadata {
+	fields := map[string]*_struct.Value{}
+	for _, md := range mds {
+		for k, v := range md.GetFilterMetadata()[LuaFilterName].GetFields() {
+			fields[k] = v
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &envoy_core_v3.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			LuaFilterName: {Fields: fields},
+		},
+	}
+}
+
 // RouteMatch creates a *envoy_route_v3.RouteMatch for the supplied *dag.Route.
 func RouteMatch(route *dag.Route) *envoy_route_v3.RouteMatch {
 	switch c := route.PathMatchCondition.(type) {
@@ -64,22 +230,64 @@ func RouteMatch(route *dag.Route) *envoy_route_v3.RouteMatch {
 			PathSpecifier: &envoy_route_v3.RouteMatch_SafeRegex{
 				SafeRegex: SafeRegexMatch(c.Regex),
 			},
-			Headers: headerMatcher(route.HeaderMatchConditions),
+			Headers:         headerMatcher(route.HeaderMatchConditions),
+			QueryParameters: queryParamMatcher(route.QueryParamMatchConditions),
+			TlsContext:      tlsContextMatch(route.TLSMatchCondition),
 		}
 	case *dag.PrefixMatchCondition:
+		if c.PrefixMatchType == dag.PrefixMatchSegment {
+			// Match the prefix, and guard it with a :path regex
+			// that requires the prefix to be followed by the end
+			// of the path, a path separator or the query string.
+			// Matching a prefix rather than a regex keeps the
+			// route ordered by prefix length among string prefixes.
+			return &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+					Prefix: c.Prefix,
+				},
+				Headers:         append(headerMatcher(route.HeaderMatchConditions), segmentPrefixMatcher(c.Prefix)),
+				QueryParameters: queryParamMatcher(route.QueryParamMatchConditions),
+				TlsContext:      tlsContextMatch(route.TLSMatchCondition),
+			}
+		}
 		return &envoy_route_v3.RouteMatch{
 			PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
 				Prefix: c.Prefix,
 			},
-			Headers: headerMatcher(route.HeaderMatchConditions),
+			Headers:         headerMatcher(route.HeaderMatchConditions),
+			QueryParameters: queryParamMatcher(route.QueryParamMatchConditions),
+			TlsContext:      tlsContextMatch(route.TLSMatchCondition),
+		}
+	case *dag.ExactMatchCondition:
+		return &envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Path{
+				Path: c.Path,
+			},
+			Headers:         headerMatcher(route.HeaderMatchConditions),
+			QueryParameters: queryParamMatcher(route.QueryParamMatchConditions),
+			TlsContext:      tlsContextMatch(route.TLSMatchCondition),
 		}
 	default:
 		return &envoy_route_v3.RouteMatch{
-			Headers: headerMatcher(route.HeaderMatchConditions),
+			Headers:         headerMatcher(route.HeaderMatchConditions),
+			QueryParameters: queryParamMatcher(route.QueryParamMatchConditions),
+			TlsContext:      tlsContextMatch(route.TLSMatchCondition),
 		}
 	}
 }
 
+// tlsContextMatch returns the TLS context match options for the
+// given condition, or nil if there is no condition.
+func tlsContextMatch(tc *dag.TLSMatchCondition) *envoy_route_v3.RouteMatch_TlsContextMatchOptions {
+	if tc == nil {
+		return nil
+	}
+
+	return &envoy_route_v3.RouteMatch_TlsContextMatchOptions{
+		Presented: protobuf.Bool(tc.ClientCertificatePresented),
+	}
+}
+
 // RouteRoute creates a *envoy_route_v3.Route_Route for the services supplied.
 // If len(services) is greater than one, the route's action will be a
 // weighted cluster.
@@ -91,6 +299,18 @@ func RouteRoute(r *dag.Route) *envoy_route_v3.Route_Route {
 		PrefixRewrite:         r.PrefixRewrite,
 		HashPolicy:            hashPolicy(r),
 		RequestMirrorPolicies: mirrorPolicy(r),
+		RateLimits:            GlobalRateLimits(r.RateLimitPolicy),
+	}
+
+	// Segment prefixes are matched by regex, so Envoy would replace
+	// the whole path with the prefix rewrite. Rewrite just the
+	// matched prefix instead.
+	if c, ok := r.PathMatchCondition.(*dag.PrefixMatchCondition); ok && c.PrefixMatchType == dag.PrefixMatchSegment && r.PrefixRewrite != "" {
+		ra.PrefixRewrite = ""
+		ra.RegexRewrite = &matcher.RegexMatchAndSubstitute{
+			Pattern:      SafeRegexMatch("^" + regexp.QuoteMeta(c.Prefix)),
+			Substitution: r.PrefixRewrite,
+		}
 	}
 
 	// Check for host header policy and set if found
@@ -109,11 +329,28 @@ func RouteRoute(r *dag.Route) *envoy_route_v3.Route_Route {
 		)
 	}
 
-	if envoy.SingleSimpleCluster(r.Clusters) {
+	for _, upgrade := range r.UpgradeTypes {
+		ra.UpgradeConfigs = append(ra.UpgradeConfigs,
+			&envoy_route_v3.RouteAction_UpgradeConfig{
+				UpgradeType: upgrade,
+			},
+		)
+	}
+
+	switch {
+	case r.ForwardProxy != nil:
+		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_Cluster{
+			Cluster: ForwardProxyClusterName,
+		}
+	case r.OriginalDestination != nil:
+		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_Cluster{
+			Cluster: OriginalDestinationClusterName,
+		}
+	case envoy.SingleSimpleCluster(r.Clusters):
 		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_Cluster{
 			Cluster: envoy.Clustername(r.Clusters[0]),
 		}
-	} else {
+	default:
 		ra.ClusterSpecifier = &envoy_route_v3.RouteAction_WeightedClusters{
 			WeightedClusters: weightedClusters(r.Clusters),
 		}
@@ -124,7 +361,8 @@ func RouteRoute(r *dag.Route) *envoy_route_v3.Route_Route {
 }
 
 // hashPolicy returns a slice of hash policies iff at least one of the route's
-// clusters supplied uses the `Cookie` load balancing strategy.
+// clusters supplied uses the `Cookie` load balancing strategy, or the route
+// has request hash policies for the `RequestHash` or `Maglev` strategies.
 func hashPolicy(r *dag.Route) []*envoy_route_v3.RouteAction_HashPolicy {
 	for _, c := range r.Clusters {
 		if c.LoadBalancerPolicy == "Cookie" {
@@ -139,7 +377,44 @@ func hashPolicy(r *dag.Route) []*envoy_route_v3.RouteAction_HashPolicy {
 			}}
 		}
 	}
-	return nil
+
+	var hashPolicies []*envoy_route_v3.RouteAction_HashPolicy
+	for _, rhp := range r.RequestHashPolicies {
+		hp := &envoy_route_v3.RouteAction_HashPolicy{
+			Terminal: rhp.Terminal,
+		}
+
+		switch {
+		case rhp.HeaderHashOptions != nil:
+			hp.PolicySpecifier = &envoy_route_v3.RouteAction_HashPolicy_Header_{
+				Header: &envoy_route_v3.RouteAction_HashPolicy_Header{
+					HeaderName: rhp.HeaderHashOptions.HeaderName,
+				},
+			}
+		case rhp.CookieHashOptions != nil:
+			cookie := &envoy_route_v3.RouteAction_HashPolicy_Cookie{
+				Name: rhp.CookieHashOptions.CookieName,
+				Path: rhp.CookieHashOptions.Path,
+			}
+			if rhp.CookieHashOptions.TTL > 0 {
+				cookie.Ttl = protobuf.Duration(rhp.CookieHashOptions.TTL)
+			}
+			hp.PolicySpecifier = &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+				Cookie: cookie,
+			}
+		case rhp.HashSourceIP:
+			hp.PolicySpecifier = &envoy_route_v3.RouteAction_HashPolicy_ConnectionProperties_{
+				ConnectionProperties: &envoy_route_v3.RouteAction_HashPolicy_ConnectionProperties{
+					SourceIp: true,
+				},
+			}
+		default:
+			continue
+		}
+
+		hashPolicies = append(hashPolicies, hp)
+	}
+	return hashPolicies
 }
 
 func mirrorPolicy(r *dag.Route) []*envoy_route_v3.RouteAction_RequestMirrorPolicy {
@@ -169,6 +444,15 @@ func retryPolicy(r *dag.Route) *envoy_route_v3.RetryPolicy {
 	}
 	rp.PerTryTimeout = envoy.Timeout(r.RetryPolicy.PerTryTimeout)
 
+	if b := r.RetryPolicy.Backoff; b != nil {
+		rp.RetryBackOff = &envoy_route_v3.RetryPolicy_RetryBackOff{
+			BaseInterval: protobuf.Duration(b.BaseInterval),
+		}
+		if b.MaxInterval > 0 {
+			rp.RetryBackOff.MaxInterval = protobuf.Duration(b.MaxInterval)
+		}
+	}
+
 	return rp
 }
 
@@ -183,6 +467,30 @@ func UpgradeHTTPS() *envoy_route_v3.Route_Redirect {
 	}
 }
 
+// DirectResponse returns a route action that responds
+// with the given status code without proxying the request.
+func DirectResponse(status uint32) *envoy_route_v3.Route_DirectResponse {
+	return &envoy_route_v3.Route_DirectResponse{
+		DirectResponse: &envoy_route_v3.DirectResponseAction{
+			Status: status,
+		},
+	}
+}
+
+// DirectResponseBody returns a route action that responds with
+// the given status code and body without proxying the request.
+func DirectResponseBody(status uint32, body string) *envoy_route_v3.Route_DirectResponse {
+	resp := DirectResponse(status)
+	if body != "" {
+		resp.DirectResponse.Body = &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineString{
+				InlineString: body,
+			},
+		}
+	}
+	return resp
+}
+
 // HeaderValueList creates a list of Envoy HeaderValueOptions from the provided map.
 func HeaderValueList(hvm map[string]string, app bool) []*envoy_core_v3.HeaderValueOption {
 	var hvs []*envoy_core_v3.HeaderValueOption
@@ -243,7 +551,9 @@ func weightedClusters(clusters []*dag.Cluster) *envoy_route_v3.WeightedCluster {
 // VirtualHost creates a new route.VirtualHost.
 func VirtualHost(hostname string, routes ...*envoy_route_v3.Route) *envoy_route_v3.VirtualHost {
 	domains := []string{hostname}
-	if hostname != "*" {
+	// Envoy only supports one wildcard per domain, so wildcard
+	// hostnames can't also match any port.
+	if !strings.HasPrefix(hostname, "*") {
 		// NOTE(jpeach) see also envoy.FilterMisdirectedRequests().
 		domains = append(domains, hostname+":*")
 	}
@@ -262,6 +572,31 @@ func CORSVirtualHost(hostname string, corspolicy *envoy_route_v3.CorsPolicy, rou
 	return vh
 }
 
+// StrictTransportSecurity returns the Strict-Transport-Security response
+// header for the given HSTS policy, or nil if the policy is nil. The header
+// replaces any value set by the upstream.
+func StrictTransportSecurity(policy *dag.HSTSPolicy) []*envoy_core_v3.HeaderValueOption {
+	if policy == nil {
+		return nil
+	}
+
+	value := fmt.Sprintf("max-age=%d", int64(policy.MaxAge.Seconds()))
+	if policy.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if policy.Preload {
+		value += "; preload"
+	}
+
+	return []*envoy_core_v3.HeaderValueOption{{
+		Header: &envoy_core_v3.HeaderValue{
+			Key:   "Strict-Transport-Security",
+			Value: value,
+		},
+		Append: protobuf.Bool(false),
+	}}
+}
+
 // RouteConfiguration returns a *envoy_route_v3.RouteConfiguration.
 func RouteConfiguration(name string, virtualhosts ...*envoy_route_v3.VirtualHost) *envoy_route_v3.RouteConfiguration {
 	return &envoy_route_v3.RouteConfiguration{
@@ -341,6 +676,62 @@ func headerMatcher(headers []dag.HeaderMatchCondition) []*envoy_route_v3.HeaderM
 	return envoyHeaders
 }
 
+// segmentPrefixMatcher returns a :path header matcher that only
+// matches paths in which prefix is a whole path segment.
+func segmentPrefixMatcher(prefix string) *envoy_route_v3.HeaderMatcher {
+	return &envoy_route_v3.HeaderMatcher{
+		Name: ":path",
+		HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
+			SafeRegexMatch: SafeRegexMatch(regexp.QuoteMeta(prefix) + `([/?].*)?`),
+		},
+	}
+}
+
+func queryParamMatcher(queryParams []dag.QueryParamMatchCondition) []*envoy_route_v3.QueryParameterMatcher {
+	var envoyQueryParamMatchers []*envoy_route_v3.QueryParameterMatcher
+
+	for _, q := range queryParams {
+		queryParam := &envoy_route_v3.QueryParameterMatcher{
+			Name: q.Name,
+		}
+
+		switch q.MatchType {
+		case "present":
+			queryParam.QueryParameterMatchSpecifier = &envoy_route_v3.QueryParameterMatcher_PresentMatch{
+				PresentMatch: true,
+			}
+		default:
+			queryParam.QueryParameterMatchSpecifier = &envoy_route_v3.QueryParameterMatcher_StringMatch{
+				StringMatch: stringMatcher(q.MatchType, q.Value, q.IgnoreCase),
+			}
+		}
+		envoyQueryParamMatchers = append(envoyQueryParamMatchers, queryParam)
+	}
+	return envoyQueryParamMatchers
+}
+
+// stringMatcher returns a StringMatcher of the given match type
+// for the supplied value.
+func stringMatcher(matchType, value string, ignoreCase bool) *matcher.StringMatcher {
+	sm := &matcher.StringMatcher{
+		IgnoreCase: ignoreCase,
+	}
+
+	switch matchType {
+	case "exact":
+		sm.MatchPattern = &matcher.StringMatcher_Exact{Exact: value}
+	case "prefix":
+		sm.MatchPattern = &matcher.StringMatcher_Prefix{Prefix: value}
+	case "suffix":
+		sm.MatchPattern = &matcher.StringMatcher_Suffix{Suffix: value}
+	case "regex":
+		sm.MatchPattern = &matcher.StringMatcher_SafeRegex{SafeRegex: SafeRegexMatch(value)}
+	case "contains":
+		sm.MatchPattern = &matcher.StringMatcher_Contains{Contains: value}
+	}
+	return sm
+}
+
 // containsMatch returns a HeaderMatchSpecifier which will match the
 // supplied substring
 func containsMatch(s string) *envoy_route_v3.HeaderMatcher_SafeRegexMatch {
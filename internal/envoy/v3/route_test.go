@@ -14,12 +14,19 @@
 package v3
 
 import (
+	"net"
 	"testing"
 	"time"
 
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_config_rbac_v3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_config_filter_fault_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	envoy_config_filter_http_fault_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	envoy_config_filter_http_rbac_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/projectcontour/contour/internal/dag"
 	"github.com/projectcontour/contour/internal/fixture"
@@ -87,6 +94,25 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"websocket and spdy": {
+			route: &dag.Route{
+				Websocket:    true,
+				UpgradeTypes: []string{"spdy/3.1"},
+				Clusters:     []*dag.Cluster{c1},
+			},
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					UpgradeConfigs: []*envoy_route_v3.RouteAction_UpgradeConfig{{
+						UpgradeType: "websocket",
+					}, {
+						UpgradeType: "spdy/3.1",
+					}},
+				},
+			},
+		},
 		"multiple": {
 			route: &dag.Route{
 				Clusters: []*dag.Cluster{{
@@ -308,6 +334,55 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"retry backoff": {
+			route: &dag.Route{
+				RetryPolicy: &dag.RetryPolicy{
+					RetryOn:    "5xx",
+					NumRetries: 3,
+					Backoff: &dag.RetryBackoff{
+						BaseInterval: 100 * time.Millisecond,
+						MaxInterval:  2 * time.Second,
+					},
+				},
+				Clusters: []*dag.Cluster{c1},
+			},
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RetryPolicy: &envoy_route_v3.RetryPolicy{
+						RetryOn:    "5xx",
+						NumRetries: protobuf.UInt32(3),
+						RetryBackOff: &envoy_route_v3.RetryPolicy_RetryBackOff{
+							BaseInterval: protobuf.Duration(100 * time.Millisecond),
+							MaxInterval:  protobuf.Duration(2 * time.Second),
+						},
+					},
+				},
+			},
+		},
+		"segment prefix rewrite": {
+			route: &dag.Route{
+				PathMatchCondition: &dag.PrefixMatchCondition{
+					Prefix:          "/api",
+					PrefixMatchType: dag.PrefixMatchSegment,
+				},
+				PrefixRewrite: "/",
+				Clusters:      []*dag.Cluster{c1},
+			},
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					RegexRewrite: &matcher.RegexMatchAndSubstitute{
+						Pattern:      SafeRegexMatch("^/api"),
+						Substitution: "/",
+					},
+				},
+			},
+		},
 		"timeout 90s": {
 			route: &dag.Route{
 				TimeoutPolicy: dag.TimeoutPolicy{
@@ -453,6 +528,54 @@ func TestRouteRoute(t *testing.T) {
 				},
 			},
 		},
+		"single service w/ request hash policies": {
+			route: &dag.Route{
+				Clusters: []*dag.Cluster{c1},
+				RequestHashPolicies: []dag.RequestHashPolicy{{
+					Terminal: true,
+					HeaderHashOptions: &dag.HeaderHashOptions{
+						HeaderName: "X-User-Id",
+					},
+				}, {
+					CookieHashOptions: &dag.CookieHashOptions{
+						CookieName: "session",
+						TTL:        time.Hour,
+						Path:       "/",
+					},
+				}, {
+					HashSourceIP: true,
+				}},
+			},
+			want: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+						Cluster: "default/kuard/8080/da39a3ee5e",
+					},
+					HashPolicy: []*envoy_route_v3.RouteAction_HashPolicy{{
+						Terminal: true,
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Header_{
+							Header: &envoy_route_v3.RouteAction_HashPolicy_Header{
+								HeaderName: "X-User-Id",
+							},
+						},
+					}, {
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Cookie_{
+							Cookie: &envoy_route_v3.RouteAction_HashPolicy_Cookie{
+								Name: "session",
+								Ttl:  protobuf.Duration(time.Hour),
+								Path: "/",
+							},
+						},
+					}, {
+						PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_ConnectionProperties_{
+							ConnectionProperties: &envoy_route_v3.RouteAction_HashPolicy_ConnectionProperties{
+								SourceIp: true,
+							},
+						},
+					}},
+				},
+			},
+		},
 		"host header rewrite": {
 			route: &dag.Route{
 				RequestHeadersPolicy: &dag.HeadersPolicy{
@@ -927,6 +1050,172 @@ func TestCORSPolicy(t *testing.T) {
 	}
 }
 
+func TestStrictTransportSecurity(t *testing.T) {
+	tests := map[string]struct {
+		policy *dag.HSTSPolicy
+		want   string
+	}{
+		"max age only": {
+			policy: &dag.HSTSPolicy{MaxAge: 24 * time.Hour},
+			want:   "max-age=86400",
+		},
+		"include subdomains": {
+			policy: &dag.HSTSPolicy{MaxAge: time.Hour, IncludeSubdomains: true},
+			want:   "max-age=3600; includeSubDomains",
+		},
+		"preload": {
+			policy: &dag.HSTSPolicy{MaxAge: 8760 * time.Hour, IncludeSubdomains: true, Preload: true},
+			want:   "max-age=31536000; includeSubDomains; preload",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			want := []*envoy_core_v3.HeaderValueOption{{
+				Header: &envoy_core_v3.HeaderValue{
+					Key:   "Strict-Transport-Security",
+					Value: tc.want,
+				},
+				Append: &wrappers.BoolValue{Value: false},
+			}}
+			protobuf.ExpectEqual(t, want, StrictTransportSecurity(tc.policy))
+		})
+	}
+
+	assert.Nil(t, StrictTransportSecurity(nil))
+}
+
+func TestRouteCacheTTL(t *testing.T) {
+	want := &envoy_core_v3.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			"envoy.filters.http.lua": {
+				Fields: map[string]*_struct.Value{
+					"cache-ttl": {
+						Kind: &_struct.Value_StringValue{StringValue: "300"},
+					},
+				},
+			},
+		},
+	}
+	protobuf.ExpectEqual(t, want, RouteCacheTTL(5*time.Minute))
+
+	assert.Nil(t, RouteCacheTTL(0))
+}
+
+func TestLuaMetadata(t *testing.T) {
+	want := &envoy_core_v3.Metadata{
+		FilterMetadata: map[string]*_struct.Struct{
+			"envoy.filters.http.lua": {
+				Fields: map[string]*_struct.Value{
+					"response-timeout-header": {
+						Kind: &_struct.Value_StringValue{StringValue: "X-Timeout"},
+					},
+					"cache-ttl": {
+						Kind: &_struct.Value_StringValue{StringValue: "60"},
+					},
+				},
+			},
+		},
+	}
+	protobuf.ExpectEqual(t, want, LuaMetadata(
+		RouteResponseTimeoutHeader("X-Timeout"),
+		RouteCacheTTL(time.Minute),
+	))
+
+	protobuf.ExpectEqual(t, RouteCacheTTL(time.Minute), LuaMetadata(nil, RouteCacheTTL(time.Minute)))
+
+	assert.Nil(t, LuaMetadata(RouteResponseTimeoutHeader(""), RouteCacheTTL(0)))
+}
+
+func TestRouteSourceRanges(t *testing.T) {
+	got := RouteSourceRanges([]*net.IPNet{
+		{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+		{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+	})
+
+	want := protobuf.MustMarshalAny(&envoy_config_filter_http_rbac_v3.RBACPerRoute{
+		Rbac: &envoy_config_filter_http_rbac_v3.RBAC{
+			Rules: &envoy_config_rbac_v3.RBAC{
+				Action: envoy_config_rbac_v3.RBAC_ALLOW,
+				Policies: map[string]*envoy_config_rbac_v3.Policy{
+					"source-ranges": {
+						Permissions: []*envoy_config_rbac_v3.Permission{{
+							Rule: &envoy_config_rbac_v3.Permission_Any{Any: true},
+						}},
+						Principals: []*envoy_config_rbac_v3.Principal{{
+							Identifier: &envoy_config_rbac_v3.Principal_RemoteIp{
+								RemoteIp: &envoy_core_v3.CidrRange{
+									AddressPrefix: "10.0.0.0",
+									PrefixLen:     protobuf.UInt32(8),
+								},
+							},
+						}, {
+							Identifier: &envoy_config_rbac_v3.Principal_RemoteIp{
+								RemoteIp: &envoy_core_v3.CidrRange{
+									AddressPrefix: "2001:db8::",
+									PrefixLen:     protobuf.UInt32(32),
+								},
+							},
+						}},
+					},
+				},
+			},
+		},
+	})
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestRouteFault(t *testing.T) {
+	got := RouteFault(&dag.FaultPolicy{
+		DelayDuration:   2 * time.Second,
+		DelayPercent:    20,
+		AbortStatusCode: 503,
+		AbortPercent:    5,
+	})
+
+	want := protobuf.MustMarshalAny(&envoy_config_filter_http_fault_v3.HTTPFault{
+		Delay: &envoy_config_filter_fault_v3.FaultDelay{
+			FaultDelaySecifier: &envoy_config_filter_fault_v3.FaultDelay_FixedDelay{
+				FixedDelay: protobuf.Duration(2 * time.Second),
+			},
+			Percentage: &envoy_type.FractionalPercent{
+				Numerator:   20,
+				Denominator: envoy_type.FractionalPercent_HUNDRED,
+			},
+		},
+		Abort: &envoy_config_filter_http_fault_v3.FaultAbort{
+			ErrorType: &envoy_config_filter_http_fault_v3.FaultAbort_HttpStatus{
+				HttpStatus: 503,
+			},
+			Percentage: &envoy_type.FractionalPercent{
+				Numerator:   5,
+				Denominator: envoy_type.FractionalPercent_HUNDRED,
+			},
+		},
+	})
+	protobuf.ExpectEqual(t, want, got)
+
+	// A policy without a delay only aborts requests.
+	got = RouteFault(&dag.FaultPolicy{
+		AbortStatusCode: 500,
+		AbortPercent:    100,
+	})
+
+	want = protobuf.MustMarshalAny(&envoy_config_filter_http_fault_v3.HTTPFault{
+		Abort: &envoy_config_filter_http_fault_v3.FaultAbort{
+			ErrorType: &envoy_config_filter_http_fault_v3.FaultAbort_HttpStatus{
+				HttpStatus: 500,
+			},
+			Percentage: &envoy_type.FractionalPercent{
+				Numerator:   100,
+				Denominator: envoy_type.FractionalPercent_HUNDRED,
+			},
+		},
+	})
+	protobuf.ExpectEqual(t, want, got)
+}
+
 func TestUpgradeHTTPS(t *testing.T) {
 	got := UpgradeHTTPS()
 	want := &envoy_route_v3.Route_Redirect{
@@ -945,6 +1234,45 @@ func TestRouteMatch(t *testing.T) {
 		route *dag.Route
 		want  *envoy_route_v3.RouteMatch
 	}{
+		"exact path": {
+			route: &dag.Route{
+				PathMatchCondition: &dag.ExactMatchCondition{Path: "/foo"},
+			},
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Path{
+					Path: "/foo",
+				},
+			},
+		},
+		"string prefix": {
+			route: &dag.Route{
+				PathMatchCondition: &dag.PrefixMatchCondition{Prefix: "/foo"},
+			},
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+					Prefix: "/foo",
+				},
+			},
+		},
+		"segment prefix": {
+			route: &dag.Route{
+				PathMatchCondition: &dag.PrefixMatchCondition{
+					Prefix:          "/foo.bar",
+					PrefixMatchType: dag.PrefixMatchSegment,
+				},
+			},
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+					Prefix: "/foo.bar",
+				},
+				Headers: []*envoy_route_v3.HeaderMatcher{{
+					Name: ":path",
+					HeaderMatchSpecifier: &envoy_route_v3.HeaderMatcher_SafeRegexMatch{
+						SafeRegexMatch: SafeRegexMatch(`/foo\.bar([/?].*)?`),
+					},
+				}},
+			},
+		},
 		"contains match with dashes": {
 			route: &dag.Route{
 				HeaderMatchConditions: []dag.HeaderMatchCondition{{
@@ -1029,6 +1357,63 @@ func TestRouteMatch(t *testing.T) {
 				},
 			},
 		},
+		"query parameter matches": {
+			route: &dag.Route{
+				PathMatchCondition: &dag.PrefixMatchCondition{
+					Prefix: "/",
+				},
+				QueryParamMatchConditions: []dag.QueryParamMatchCondition{{
+					Name:      "variant",
+					Value:     "beta",
+					MatchType: "exact",
+				}, {
+					Name:       "user",
+					Value:      "test",
+					MatchType:  "contains",
+					IgnoreCase: true,
+				}, {
+					Name:      "id",
+					Value:     "^[0-9]+$",
+					MatchType: "regex",
+				}, {
+					Name:      "debug",
+					MatchType: "present",
+				}},
+			},
+			want: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+					Prefix: "/",
+				},
+				QueryParameters: []*envoy_route_v3.QueryParameterMatcher{{
+					Name: "variant",
+					QueryParameterMatchSpecifier: &envoy_route_v3.QueryParameterMatcher_StringMatch{
+						StringMatch: &matcher.StringMatcher{
+							MatchPattern: &matcher.StringMatcher_Exact{Exact: "beta"},
+						},
+					},
+				}, {
+					Name: "user",
+					QueryParameterMatchSpecifier: &envoy_route_v3.QueryParameterMatcher_StringMatch{
+						StringMatch: &matcher.StringMatcher{
+							MatchPattern: &matcher.StringMatcher_Contains{Contains: "test"},
+							IgnoreCase:   true,
+						},
+					},
+				}, {
+					Name: "id",
+					QueryParameterMatchSpecifier: &envoy_route_v3.QueryParameterMatcher_StringMatch{
+						StringMatch: &matcher.StringMatcher{
+							MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: SafeRegexMatch("^[0-9]+$")},
+						},
+					},
+				}, {
+					Name: "debug",
+					QueryParameterMatchSpecifier: &envoy_route_v3.QueryParameterMatcher_PresentMatch{
+						PresentMatch: true,
+					},
+				}},
+			},
+		},
 	}
 
 	for name, tc := range tests {
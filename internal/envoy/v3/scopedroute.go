@@ -0,0 +1,70 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+)
+
+// ScopedRouteType is the type URL of the scoped route configurations
+// served by SRDS. go-control-plane does not define it, since its
+// snapshot cache does not support scoped routes.
+const ScopedRouteType = "type.googleapis.com/envoy.config.route.v3.ScopedRouteConfiguration"
+
+// ScopedRouteConfiguration returns a scoped route configuration that
+// selects the named route configuration for requests to host.
+func ScopedRouteConfiguration(name, host string) *envoy_route_v3.ScopedRouteConfiguration {
+	return &envoy_route_v3.ScopedRouteConfiguration{
+		Name:                   name,
+		RouteConfigurationName: name,
+		Key: &envoy_route_v3.ScopedRouteConfiguration_Key{
+			Fragments: []*envoy_route_v3.ScopedRouteConfiguration_Key_Fragment{{
+				Type: &envoy_route_v3.ScopedRouteConfiguration_Key_Fragment_StringKey{
+					StringKey: host,
+				},
+			}},
+		},
+	}
+}
+
+// scopedRoutes returns a route specifier that fetches the named
+// scoped route configurations over SRDS, and selects the one whose
+// key is the request host, without any port.
+func scopedRoutes(name string) *http.HttpConnectionManager_ScopedRoutes {
+	return &http.HttpConnectionManager_ScopedRoutes{
+		ScopedRoutes: &http.ScopedRoutes{
+			Name: name,
+			ScopeKeyBuilder: &http.ScopedRoutes_ScopeKeyBuilder{
+				Fragments: []*http.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder{{
+					Type: &http.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor_{
+						HeaderValueExtractor: &http.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor{
+							Name:             ":authority",
+							ElementSeparator: ":",
+							ExtractType: &http.ScopedRoutes_ScopeKeyBuilder_FragmentBuilder_HeaderValueExtractor_Index{
+								Index: 0,
+							},
+						},
+					},
+				}},
+			},
+			RdsConfigSource: ConfigSource("contour"),
+			ConfigSpecifier: &http.ScopedRoutes_ScopedRds{
+				ScopedRds: &http.ScopedRds{
+					ScopedRdsConfigSource: ConfigSource("contour"),
+				},
+			},
+		},
+	}
+}
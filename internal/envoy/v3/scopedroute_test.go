@@ -0,0 +1,39 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+func TestScopedRouteConfiguration(t *testing.T) {
+	got := ScopedRouteConfiguration("ingress_http/www.example.com", "www.example.com")
+
+	want := &envoy_route_v3.ScopedRouteConfiguration{
+		Name:                   "ingress_http/www.example.com",
+		RouteConfigurationName: "ingress_http/www.example.com",
+		Key: &envoy_route_v3.ScopedRouteConfiguration_Key{
+			Fragments: []*envoy_route_v3.ScopedRouteConfiguration_Key_Fragment{{
+				Type: &envoy_route_v3.ScopedRouteConfiguration_Key_Fragment_StringKey{
+					StringKey: "www.example.com",
+				},
+			}},
+		},
+	}
+
+	protobuf.ExpectEqual(t, want, got)
+}
@@ -22,20 +22,52 @@ import (
 
 // Secret creates new envoy_tls_v3.Secret from secret.
 func Secret(s *dag.Secret) *envoy_tls_v3.Secret {
+	cert := &envoy_tls_v3.TlsCertificate{
+		PrivateKey: &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineBytes{
+				InlineBytes: s.PrivateKey(),
+			},
+		},
+		CertificateChain: &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineBytes{
+				InlineBytes: s.Cert(),
+			},
+		},
+	}
+
+	if staple := s.OCSPStaple(); len(staple) > 0 {
+		cert.OcspStaple = &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineBytes{
+				InlineBytes: staple,
+			},
+		}
+	}
+
 	return &envoy_tls_v3.Secret{
 		Name: envoy.Secretname(s),
 		Type: &envoy_tls_v3.Secret_TlsCertificate{
-			TlsCertificate: &envoy_tls_v3.TlsCertificate{
-				PrivateKey: &envoy_core_v3.DataSource{
-					Specifier: &envoy_core_v3.DataSource_InlineBytes{
-						InlineBytes: s.PrivateKey(),
-					},
-				},
-				CertificateChain: &envoy_core_v3.DataSource{
-					Specifier: &envoy_core_v3.DataSource_InlineBytes{
-						InlineBytes: s.Cert(),
-					},
-				},
+			TlsCertificate: cert,
+		},
+	}
+}
+
+// SessionTicketKeysSecret creates a new envoy_tls_v3.Secret holding
+// the TLS session ticket keys from secret.
+func SessionTicketKeysSecret(s *dag.Secret) *envoy_tls_v3.Secret {
+	var keys []*envoy_core_v3.DataSource
+	for _, key := range s.SessionTicketKeys() {
+		keys = append(keys, &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineBytes{
+				InlineBytes: key,
+			},
+		})
+	}
+
+	return &envoy_tls_v3.Secret{
+		Name: envoy.Secretname(s),
+		Type: &envoy_tls_v3.Secret_SessionTicketKeys{
+			SessionTicketKeys: &envoy_tls_v3.TlsSessionTicketKeys{
+				Keys: keys,
 			},
 		},
 	}
@@ -0,0 +1,39 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/projectcontour/contour/pkg/config"
+)
+
+// SocketOption returns the socket option for the supplied
+// raw socket option parameters.
+func SocketOption(opt config.SocketOptionParameters) *envoy_core_v3.SocketOption {
+	state := envoy_core_v3.SocketOption_STATE_PREBIND
+	switch opt.State {
+	case config.BoundSocketOptionState:
+		state = envoy_core_v3.SocketOption_STATE_BOUND
+	case config.ListeningSocketOptionState:
+		state = envoy_core_v3.SocketOption_STATE_LISTENING
+	}
+
+	return &envoy_core_v3.SocketOption{
+		Description: opt.Description,
+		Level:       opt.Level,
+		Name:        opt.Name,
+		Value:       &envoy_core_v3.SocketOption_IntValue{IntValue: opt.IntValue},
+		State:       state,
+	}
+}
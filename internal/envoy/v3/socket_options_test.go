@@ -0,0 +1,65 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/pkg/config"
+)
+
+func TestSocketOption(t *testing.T) {
+	tests := map[string]struct {
+		opt  config.SocketOptionParameters
+		want *envoy_core_v3.SocketOption
+	}{
+		"default state": {
+			opt: config.SocketOptionParameters{
+				Description: "SO_REUSEPORT",
+				Level:       1,
+				Name:        15,
+				IntValue:    1,
+			},
+			want: &envoy_core_v3.SocketOption{
+				Description: "SO_REUSEPORT",
+				Level:       1,
+				Name:        15,
+				Value:       &envoy_core_v3.SocketOption_IntValue{IntValue: 1},
+				State:       envoy_core_v3.SocketOption_STATE_PREBIND,
+			},
+		},
+		"listening state": {
+			opt: config.SocketOptionParameters{
+				Level:    6,
+				Name:     23,
+				IntValue: 256,
+				State:    config.ListeningSocketOptionState,
+			},
+			want: &envoy_core_v3.SocketOption{
+				Level: 6,
+				Name:  23,
+				Value: &envoy_core_v3.SocketOption_IntValue{IntValue: 256},
+				State: envoy_core_v3.SocketOption_STATE_LISTENING,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			protobuf.ExpectEqual(t, tc.want, SocketOption(tc.opt))
+		})
+	}
+}
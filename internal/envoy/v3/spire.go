@@ -0,0 +1,83 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+// SPIREAgentClusterName is the name of the bootstrap cluster
+// that connects to the SDS socket of the SPIRE agent.
+const SPIREAgentClusterName = "spire_agent"
+
+// SPIREAgentCluster returns a static cluster that connects to
+// the SPIRE agent's SDS API on the given Unix domain socket.
+func SPIREAgentCluster(socketPath string) *envoy_cluster_v3.Cluster {
+	return &envoy_cluster_v3.Cluster{
+		Name:                 SPIREAgentClusterName,
+		ConnectTimeout:       protobuf.Duration(time.Second),
+		ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STATIC),
+		LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: SPIREAgentClusterName,
+			Endpoints:   Endpoints(PipeAddress(socketPath)),
+		},
+		Http2ProtocolOptions: new(envoy_core_v3.Http2ProtocolOptions), // enables http2
+	}
+}
+
+// SPIRESdsSecretConfig returns a SDS secret config that
+// fetches the named secret from the SPIRE agent.
+func SPIRESdsSecretConfig(name string) *envoy_tls_v3.SdsSecretConfig {
+	return &envoy_tls_v3.SdsSecretConfig{
+		Name:      name,
+		SdsConfig: ConfigSource(SPIREAgentClusterName),
+	}
+}
+
+// SPIREClientCertificate sets the X.509 SVID of the given SPIFFE
+// ID as the client certificate of a cluster that connects to its
+// upstream over TLS, unless the cluster already presents a client
+// certificate. Clusters without TLS are not changed.
+func SPIREClientCertificate(cluster *envoy_cluster_v3.Cluster, identity string) {
+	socket := cluster.GetTransportSocket()
+	if socket.GetTypedConfig() == nil {
+		return
+	}
+
+	var tls envoy_tls_v3.UpstreamTlsContext
+	if err := ptypes.UnmarshalAny(socket.GetTypedConfig(), &tls); err != nil {
+		// Not an upstream TLS context.
+		return
+	}
+
+	if len(tls.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()) > 0 {
+		return
+	}
+
+	if tls.CommonTlsContext == nil {
+		tls.CommonTlsContext = &envoy_tls_v3.CommonTlsContext{}
+	}
+	tls.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*envoy_tls_v3.SdsSecretConfig{
+		SPIRESdsSecretConfig(identity),
+	}
+
+	cluster.TransportSocket = UpstreamTLSTransportSocket(&tls)
+}
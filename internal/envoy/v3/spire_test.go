@@ -0,0 +1,104 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/envoy"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSPIREAgentCluster(t *testing.T) {
+	want := &envoy_cluster_v3.Cluster{
+		Name:                 "spire_agent",
+		ConnectTimeout:       protobuf.Duration(time.Second),
+		ClusterDiscoveryType: ClusterDiscoveryType(envoy_cluster_v3.Cluster_STATIC),
+		LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "spire_agent",
+			Endpoints:   Endpoints(PipeAddress("/run/spire/sockets/agent.sock")),
+		},
+		Http2ProtocolOptions: &envoy_core_v3.Http2ProtocolOptions{},
+	}
+
+	protobuf.ExpectEqual(t, want, SPIREAgentCluster("/run/spire/sockets/agent.sock"))
+
+	b := bootstrapConfig(&envoy.BootstrapConfig{SPIREAgentSocketPath: "/run/spire/sockets/agent.sock"})
+	clusters := b.StaticResources.Clusters
+	protobuf.ExpectEqual(t, want, clusters[len(clusters)-1])
+}
+
+func TestSPIREClientCertificate(t *testing.T) {
+	const identity = "spiffe://example.org/ns/projectcontour/sa/envoy"
+
+	clientSecret := &dag.Secret{
+		Object: &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "clientcert",
+				Namespace: "default",
+			},
+		},
+	}
+
+	tlsCluster := func(ctx *envoy_tls_v3.UpstreamTlsContext) *envoy_cluster_v3.Cluster {
+		return &envoy_cluster_v3.Cluster{
+			Name:            "default/kuard/443/da39a3ee5e",
+			TransportSocket: UpstreamTLSTransportSocket(ctx),
+		}
+	}
+
+	tests := map[string]struct {
+		cluster *envoy_cluster_v3.Cluster
+		want    *envoy_cluster_v3.Cluster
+	}{
+		"plaintext cluster": {
+			cluster: &envoy_cluster_v3.Cluster{Name: "default/kuard/80/da39a3ee5e"},
+			want:    &envoy_cluster_v3.Cluster{Name: "default/kuard/80/da39a3ee5e"},
+		},
+		"TLS cluster": {
+			cluster: tlsCluster(UpstreamTLSContext(nil, "kuard.example.com", nil, "h2")),
+			want: tlsCluster(&envoy_tls_v3.UpstreamTlsContext{
+				CommonTlsContext: &envoy_tls_v3.CommonTlsContext{
+					AlpnProtocols: []string{"h2"},
+					TlsCertificateSdsSecretConfigs: []*envoy_tls_v3.SdsSecretConfig{{
+						Name:      identity,
+						SdsConfig: ConfigSource("spire_agent"),
+					}},
+				},
+				Sni: "kuard.example.com",
+			}),
+		},
+		"TLS cluster with a client certificate": {
+			cluster: tlsCluster(UpstreamTLSContext(nil, "", clientSecret)),
+			want:    tlsCluster(UpstreamTLSContext(nil, "", clientSecret)),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := proto.Clone(tc.cluster).(*envoy_cluster_v3.Cluster)
+			SPIREClientCertificate(got, identity)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
@@ -24,48 +24,56 @@ import (
 // StatsListener returns a *envoy_listener_v3.Listener configured to serve prometheus
 // metrics on /stats.
 func StatsListener(address string, port int) *envoy_listener_v3.Listener {
+	return adminProxyListener("stats-health", "stats", address, port,
+		adminRoute(&envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+				Prefix: "/ready",
+			},
+		}),
+		adminRoute(&envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
+				Prefix: "/stats",
+			},
+		}),
+	)
+}
+
+// MetricsListener returns a *envoy_listener_v3.Listener that serves only the
+// /stats/prometheus and /ready paths, so that metrics scrapers and probes
+// need no access to the other /stats endpoints of the admin interface.
+func MetricsListener(address string, port int) *envoy_listener_v3.Listener {
+	return adminProxyListener("envoy-metrics", "metrics", address, port,
+		adminRoute(&envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Path{
+				Path: "/stats/prometheus",
+			},
+		}),
+		adminRoute(&envoy_route_v3.RouteMatch{
+			PathSpecifier: &envoy_route_v3.RouteMatch_Path{
+				Path: "/ready",
+			},
+		}),
+	)
+}
+
+// adminProxyListener returns a listener that proxies the given
+// routes to the admin interface through the service-stats cluster.
+func adminProxyListener(name, statPrefix, address string, port int, routes ...*envoy_route_v3.Route) *envoy_listener_v3.Listener {
 	return &envoy_listener_v3.Listener{
-		Name:    "stats-health",
+		Name:    name,
 		Address: SocketAddress(address, port),
 		FilterChains: FilterChains(
 			&envoy_listener_v3.Filter{
 				Name: wellknown.HTTPConnectionManager,
 				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
 					TypedConfig: protobuf.MustMarshalAny(&http.HttpConnectionManager{
-						StatPrefix: "stats",
+						StatPrefix: statPrefix,
 						RouteSpecifier: &http.HttpConnectionManager_RouteConfig{
 							RouteConfig: &envoy_route_v3.RouteConfiguration{
 								VirtualHosts: []*envoy_route_v3.VirtualHost{{
 									Name:    "backend",
 									Domains: []string{"*"},
-									Routes: []*envoy_route_v3.Route{{
-										Match: &envoy_route_v3.RouteMatch{
-											PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
-												Prefix: "/ready",
-											},
-										},
-										Action: &envoy_route_v3.Route_Route{
-											Route: &envoy_route_v3.RouteAction{
-												ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
-													Cluster: "service-stats",
-												},
-											},
-										},
-									}, {
-										Match: &envoy_route_v3.RouteMatch{
-											PathSpecifier: &envoy_route_v3.RouteMatch_Prefix{
-												Prefix: "/stats",
-											},
-										},
-										Action: &envoy_route_v3.Route_Route{
-											Route: &envoy_route_v3.RouteAction{
-												ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
-													Cluster: "service-stats",
-												},
-											},
-										},
-									},
-									},
+									Routes:  routes,
 								}},
 							},
 						},
@@ -80,3 +88,18 @@ func StatsListener(address string, port int) *envoy_listener_v3.Listener {
 		SocketOptions: TCPKeepaliveSocketOptions(),
 	}
 }
+
+// adminRoute returns a route that sends requests
+// matching match to the service-stats cluster.
+func adminRoute(match *envoy_route_v3.RouteMatch) *envoy_route_v3.Route {
+	return &envoy_route_v3.Route{
+		Match: match,
+		Action: &envoy_route_v3.Route_Route{
+			Route: &envoy_route_v3.RouteAction{
+				ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+					Cluster: "service-stats",
+				},
+			},
+		},
+	}
+}
@@ -96,3 +96,56 @@ func TestStatsListener(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsListener(t *testing.T) {
+	route := func(path string) *envoy_route_v3.Route {
+		return &envoy_route_v3.Route{
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Path{
+					Path: path,
+				},
+			},
+			Action: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+						Cluster: "service-stats",
+					},
+				},
+			},
+		}
+	}
+
+	want := &envoy_listener_v3.Listener{
+		Name:    "envoy-metrics",
+		Address: SocketAddress("127.0.0.127", 8123),
+		FilterChains: FilterChains(
+			&envoy_listener_v3.Filter{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &envoy_listener_v3.Filter_TypedConfig{
+					TypedConfig: protobuf.MustMarshalAny(&http.HttpConnectionManager{
+						StatPrefix: "metrics",
+						RouteSpecifier: &http.HttpConnectionManager_RouteConfig{
+							RouteConfig: &envoy_route_v3.RouteConfiguration{
+								VirtualHosts: []*envoy_route_v3.VirtualHost{{
+									Name:    "backend",
+									Domains: []string{"*"},
+									Routes: []*envoy_route_v3.Route{
+										route("/stats/prometheus"),
+										route("/ready"),
+									},
+								}},
+							},
+						},
+						HttpFilters: []*http.HttpFilter{{
+							Name: wellknown.Router,
+						}},
+						NormalizePath: protobuf.Bool(true),
+					}),
+				},
+			},
+		),
+		SocketOptions: TCPKeepaliveSocketOptions(),
+	}
+
+	protobuf.ExpectEqual(t, want, MetricsListener("127.0.0.127", 8123))
+}
@@ -234,3 +234,61 @@ func TestBackendClientAuthenticationWithExtensionService(t *testing.T) {
 		TypeUrl:   clusterType,
 	})
 }
+
+func TestBackendUpstreamTLSWithHTTPProxy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	sec1 := clientSecret()
+	rh.OnAdd(sec1)
+
+	svc := fixture.NewService("backend").
+		WithPorts(v1.ServicePort{Name: "http", Port: 443})
+	rh.OnAdd(svc)
+
+	proxy := func(protocol string) *projcontour.HTTPProxy {
+		return fixture.NewProxy("authenticated").WithSpec(
+			projcontour.HTTPProxySpec{
+				VirtualHost: &projcontour.VirtualHost{
+					Fqdn: "www.example.com",
+				},
+				Routes: []projcontour.Route{{
+					Services: []projcontour.Service{{
+						Name:     svc.Name,
+						Port:     443,
+						Protocol: pointer.StringPtr(protocol),
+						UpstreamTLS: &projcontour.UpstreamTLS{
+							SNI:               "backend.mesh.local",
+							ClientCertificate: sec1.Name,
+						},
+					}},
+				}},
+			})
+	}
+
+	p1 := proxy("tls")
+	rh.OnAdd(p1)
+
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			tlsClusterWithoutValidation(cluster("default/backend/443/8624e6fbd7", "default/backend/http", "default_backend_443"), "backend.mesh.local", sec1),
+		),
+		TypeUrl: clusterType,
+	})
+
+	// upstreamTLS is not valid for a cleartext protocol.
+	p2 := proxy("h2c")
+	rh.OnUpdate(p1, p2)
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: nil,
+		TypeUrl:   clusterType,
+	})
+
+	// Test the error branch when the client certificate secret does not exist.
+	rh.OnUpdate(p2, p1)
+	rh.OnDelete(sec1)
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: nil,
+		TypeUrl:   clusterType,
+	})
+}
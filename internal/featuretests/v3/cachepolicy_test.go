@@ -0,0 +1,118 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/pkg/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCachePolicy(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(fixture.NewService("s1").
+		WithPorts(v1.ServicePort{Port: 80}),
+	)
+
+	p := fixture.NewProxy("proxy").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "foo.com"},
+		Routes: []contour_api_v1.Route{{
+			Conditions: matchconditions(prefixMatchCondition("/static")),
+			Services:   []contour_api_v1.Service{{Name: "s1", Port: 80}},
+			CachePolicy: &contour_api_v1.CachePolicy{
+				TTL: "5m",
+			},
+		}, {
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	})
+	rh.OnAdd(p)
+
+	// The cache filter is added to the connection manager
+	// once any route has a cache policy.
+	c.Request(listenerType, "ingress_http").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManagerBuilder().
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy_v3.FileAccessLogEnvoy("/dev/stdout")).
+						DefaultFilters().
+						AddFilter(envoy_v3.FilterCacheScope()).
+						AddFilter(envoy_v3.FilterCache(config.ResponseCacheParameters{})).
+						AddFilter(envoy_v3.FilterCacheControl()).
+						Get(),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// Only the route with the policy carries the cache ttl, so
+	// requests for other routes bypass the cache.
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("foo.com",
+					&envoy_route_v3.Route{
+						Match:    routePrefix("/static"),
+						Action:   routeCluster("default/s1/80/da39a3ee5e"),
+						Metadata: envoy_v3.RouteCacheTTL(5 * time.Minute),
+					},
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/s1/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// Removing the policy removes the cache filter.
+	rh.OnUpdate(p, fixture.NewProxy("proxy").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "foo.com"},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	}))
+
+	c.Request(listenerType, "ingress_http").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManager("ingress_http", envoy_v3.FileAccessLogEnvoy("/dev/stdout"), 0),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
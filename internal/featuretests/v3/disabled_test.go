@@ -0,0 +1,144 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDisabledVirtualHost(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Name: "http", Port: 80}))
+
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	})
+
+	spec := contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn: "secure.example.com",
+			TLS:  &contour_api_v1.TLS{SecretName: "secret"},
+		},
+		Routes: []contour_api_v1.Route{{
+			Conditions: matchconditions(prefixMatchCondition("/api")),
+			Services: []contour_api_v1.Service{{
+				Name: "kuard",
+				Port: 80,
+			}},
+		}},
+	}
+
+	proxy := fixture.NewProxy("secure").WithSpec(spec)
+	rh.OnAdd(proxy)
+
+	ing := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "insecure",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"projectcontour.io/disabled": "true",
+			},
+		},
+		Spec: v1beta1.IngressSpec{
+			Rules: []v1beta1.IngressRule{{
+				Host: "insecure.example.com",
+				IngressRuleValue: v1beta1.IngressRuleValue{
+					HTTP: &v1beta1.HTTPIngressRuleValue{
+						Paths: []v1beta1.HTTPIngressPath{{
+							Backend: v1beta1.IngressBackend{
+								ServiceName: "kuard",
+								ServicePort: intstr.FromInt(80),
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	rh.OnAdd(ing)
+
+	disabled := &envoy_route_v3.Route{
+		Match:  routePrefix("/"),
+		Action: envoy_v3.DirectResponse(503),
+	}
+
+	// The disabled Ingress answers every request with a 503.
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("insecure.example.com", disabled),
+				envoy_v3.VirtualHost("secure.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/api"),
+						Action: envoy_v3.UpgradeHTTPS(),
+					},
+				),
+			),
+			envoy_v3.RouteConfiguration("https/secure.example.com",
+				envoy_v3.VirtualHost("secure.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/api"),
+						Action: routeCluster("default/kuard/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// Disabling the HTTPProxy disables both its insecure and secure
+	// virtual hosts, and removing the Ingress annotation restores it.
+	rh.OnUpdate(proxy, fixture.NewProxy("secure").Annotate("projectcontour.io/disabled", "true").WithSpec(spec))
+
+	enabled := ing.DeepCopy()
+	enabled.Annotations = nil
+	rh.OnUpdate(ing, enabled)
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("insecure.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/80/da39a3ee5e"),
+					},
+				),
+				envoy_v3.VirtualHost("secure.example.com", disabled),
+			),
+			envoy_v3.RouteConfiguration("https/secure.example.com",
+				envoy_v3.VirtualHost("secure.example.com", disabled),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
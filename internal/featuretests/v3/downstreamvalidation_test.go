@@ -17,6 +17,7 @@ import (
 	"testing"
 
 	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/dag"
@@ -120,3 +121,119 @@ func TestDownstreamTLSCertificateValidation(t *testing.T) {
 	)
 
 }
+
+func TestDownstreamTLSOptionalClientCertificate(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	serverTLSSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "serverTLSSecret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(serverTLSSecret)
+
+	clientCASecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "clientCASecret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			dag.CACertificateKey: []byte(featuretests.CERTIFICATE),
+		},
+	}
+	rh.OnAdd(clientCASecret)
+
+	rh.OnAdd(fixture.NewService("authenticated").
+		WithPorts(v1.ServicePort{Name: "http", Port: 8080, TargetPort: intstr.FromInt(8080)}))
+	rh.OnAdd(fixture.NewService("anonymous").
+		WithPorts(v1.ServicePort{Name: "http", Port: 8080, TargetPort: intstr.FromInt(8080)}))
+
+	proxy := fixture.NewProxy("example.com").
+		WithSpec(contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName: serverTLSSecret.Name,
+					ClientValidation: &contour_api_v1.DownstreamValidation{
+						CACertificate:             clientCASecret.Name,
+						OptionalClientCertificate: true,
+					},
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Conditions: []contour_api_v1.MatchCondition{{
+					TLS: &contour_api_v1.TLSMatchCondition{
+						ClientCertificatePresented: true,
+					},
+				}},
+				Services: []contour_api_v1.Service{{
+					Name: "authenticated",
+					Port: 8080,
+				}},
+			}, {
+				Services: []contour_api_v1.Service{{
+					Name: "anonymous",
+					Port: 8080,
+				}},
+			}},
+		})
+
+	rh.OnAdd(proxy)
+
+	// Clients without a certificate can connect, and are
+	// routed apart from the clients that present one.
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("example.com", serverTLSSecret,
+						httpsFilterFor("example.com"),
+						&dag.PeerValidationContext{
+							CACertificate: &dag.Secret{
+								Object: clientCASecret,
+							},
+							OptionalClientCertificate: true,
+						},
+						"h2", "http/1.1",
+					),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	}).Status(proxy).Like(
+		contour_api_v1.HTTPProxyStatus{CurrentStatus: string(status.ProxyStatusValid)},
+	)
+
+	c.Request(routeType, "https/example.com").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("https/example.com",
+				envoy_v3.VirtualHost("example.com",
+					&envoy_route_v3.Route{
+						Match: envoy_v3.RouteMatch(&dag.Route{
+							PathMatchCondition: &dag.PrefixMatchCondition{Prefix: "/"},
+							TLSMatchCondition: &dag.TLSMatchCondition{
+								ClientCertificatePresented: true,
+							},
+						}),
+						Action: routeCluster("default/authenticated/8080/da39a3ee5e"),
+					},
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/anonymous/8080/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -184,6 +184,14 @@ func h2cCluster(c *envoy_cluster_v3.Cluster) *envoy_cluster_v3.Cluster {
 	return c
 }
 
+func localityWeighted(c *envoy_cluster_v3.Cluster) *envoy_cluster_v3.Cluster {
+	c.CommonLbConfig = envoy_v3.ClusterCommonLBConfig()
+	c.CommonLbConfig.LocalityConfigSpecifier = &envoy_cluster_v3.Cluster_CommonLbConfig_LocalityWeightedLbConfig_{
+		LocalityWeightedLbConfig: &envoy_cluster_v3.Cluster_CommonLbConfig_LocalityWeightedLbConfig{},
+	}
+	return c
+}
+
 func withResponseTimeout(route *envoy_route_v3.Route_Route, timeout time.Duration) *envoy_route_v3.Route_Route {
 	route.Route.Timeout = protobuf.Duration(timeout)
 	return route
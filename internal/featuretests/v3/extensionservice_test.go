@@ -48,7 +48,7 @@ func extBasic(t *testing.T, rh cache.ResourceEventHandler, c *Contour) {
 		TypeUrl: clusterType,
 		Resources: resources(t,
 			DefaultCluster(
-				h2cCluster(cluster("extension/ns/ext", "extension/ns/ext", "extension_ns_ext")),
+				localityWeighted(h2cCluster(cluster("extension/ns/ext", "extension/ns/ext", "extension_ns_ext"))),
 				&envoy_cluster_v3.Cluster{
 					TransportSocket: envoy_v3.UpstreamTLSTransportSocket(
 						&envoy_v3_tls.UpstreamTlsContext{
@@ -91,7 +91,7 @@ func extCleartext(t *testing.T, rh cache.ResourceEventHandler, c *Contour) {
 		TypeUrl: clusterType,
 		Resources: resources(t,
 			DefaultCluster(
-				h2cCluster(cluster("extension/ns/ext", "extension/ns/ext", "extension_ns_ext")),
+				localityWeighted(h2cCluster(cluster("extension/ns/ext", "extension/ns/ext", "extension_ns_ext"))),
 			),
 		),
 	})
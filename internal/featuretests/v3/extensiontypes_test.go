@@ -0,0 +1,55 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/fixture"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	"github.com/projectcontour/contour/pkg/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Test that the listener features that no other feature test
+// enables only embed the extension messages that are described
+// by the output schema. Every response is checked as it is
+// received, so the requests have no further assertions.
+func TestExtensionTypes(t *testing.T) {
+	rh, c, done := setup(t, func(conf *xdscache_v3.ListenerConfig) {
+		conf.Tracing = config.TracingParameters{
+			Propagation: []config.TracePropagationType{config.B3TracePropagation},
+		}
+		conf.AccessLogGRPC = config.AccessLogGRPCParameters{
+			ExtensionService: config.NamespacedName{Namespace: "projectcontour", Name: "als"},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("default/backend").
+		WithPorts(v1.ServicePort{Port: 80}))
+
+	rh.OnAdd(fixture.NewProxy("default/simple").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "example.com"},
+		Routes: []contour_api_v1.Route{{
+			Conditions: matchconditions(prefixMatchCondition("/")),
+			Services:   []contour_api_v1.Service{{Name: "backend", Port: 80}},
+		}},
+	}))
+
+	for _, typeURL := range []string{listenerType, routeType, clusterType, endpointType, secretType} {
+		c.Request(typeURL)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+)
+
+// Assert that HTTPProxy services can proxy to backends
+// outside of the cluster that are not Kubernetes Services.
+func TestExternalBackend(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Processors = []dag.Processor{
+			&dag.HTTPProxyProcessor{
+				EnableExternalBackends: true,
+			},
+			&dag.ListenerProcessor{},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewProxy("external").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "foo.com"},
+		Routes: []contour_api_v1.Route{{
+			Conditions: matchconditions(prefixMatchCondition("/api")),
+			Services: []contour_api_v1.Service{{
+				Name: "api",
+				Port: 80,
+				External: &contour_api_v1.ExternalBackend{
+					Address: "api.example.com",
+				},
+			}},
+		}, {
+			Services: []contour_api_v1.Service{{
+				Name: "legacy",
+				Port: 8080,
+				External: &contour_api_v1.ExternalBackend{
+					Address: "192.0.2.10",
+				},
+			}},
+		}},
+	}))
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("foo.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/api"),
+						Action: routeCluster("default/api/80/1bc412bb08"),
+					},
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/legacy/8080/56adbd712c"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// DNS names are resolved by Envoy, IP addresses are used as is.
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			externalNameCluster("default/api/80/1bc412bb08", "default/api", "default_api_80", "api.example.com", 80),
+			DefaultCluster(&envoy_cluster_v3.Cluster{
+				Name:                 "default/legacy/8080/56adbd712c",
+				ClusterDiscoveryType: envoy_v3.ClusterDiscoveryType(envoy_cluster_v3.Cluster_STATIC),
+				AltStatName:          "default_legacy_8080",
+				LoadAssignment: &envoy_endpoint_v3.ClusterLoadAssignment{
+					ClusterName: "default/legacy",
+					Endpoints: envoy_v3.Endpoints(
+						envoy_v3.SocketAddress("192.0.2.10", 8080),
+					),
+				},
+			}),
+		),
+		TypeUrl: clusterType,
+	})
+}
@@ -21,8 +21,11 @@ import (
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
 	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
 	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/pkg/config"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -266,3 +269,86 @@ func TestExternalNameService(t *testing.T) {
 		),
 	})
 }
+
+// Assert that the configured DNS lookup family and resolvers
+// apply to external names referenced by an Ingress or HTTPProxy.
+func TestExternalNameServiceDNSResolvers(t *testing.T) {
+	resolvers := []string{"10.96.0.10", "[fd00::10]:5353"}
+
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Processors = []dag.Processor{
+			&dag.IngressProcessor{
+				FieldLogger:     fixture.NewTestLogger(t),
+				DNSLookupFamily: config.IPv6ClusterDNSFamily,
+				DNSResolvers:    resolvers,
+			},
+			&dag.HTTPProxyProcessor{
+				DNSLookupFamily: config.IPv6ClusterDNSFamily,
+				DNSResolvers:    resolvers,
+			},
+			&dag.ListenerProcessor{},
+		}
+	})
+	defer done()
+
+	s1 := fixture.NewService("kuard").
+		WithSpec(v1.ServiceSpec{
+			Ports: []v1.ServicePort{{
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}},
+			ExternalName: "foo.io",
+			Type:         v1.ServiceTypeExternalName,
+		})
+	rh.OnAdd(s1)
+
+	withResolvers := func(c *envoy_cluster_v3.Cluster) *envoy_cluster_v3.Cluster {
+		c.DnsLookupFamily = envoy_cluster_v3.Cluster_V6_ONLY
+		c.DnsResolvers = []*envoy_core_v3.Address{
+			envoy_v3.SocketAddress("10.96.0.10", 53),
+			envoy_v3.SocketAddress("fd00::10", 5353),
+		}
+		return c
+	}
+
+	i1 := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kuard",
+			Namespace: s1.Namespace,
+		},
+		Spec: v1beta1.IngressSpec{
+			Backend: &v1beta1.IngressBackend{
+				ServiceName: s1.Name,
+				ServicePort: intstr.FromInt(80),
+			},
+		},
+	}
+	rh.OnAdd(i1)
+
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			withResolvers(externalNameCluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80", "foo.io", 80)),
+		),
+		TypeUrl: clusterType,
+	})
+
+	rh.OnDelete(i1)
+	rh.OnAdd(fixture.NewProxy("kuard").
+		WithFQDN("kuard.projectcontour.io").
+		WithSpec(contour_api_v1.HTTPProxySpec{
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		}),
+	)
+
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			withResolvers(externalNameCluster("default/kuard/80/da39a3ee5e", "default/kuard", "default_kuard_80", "foo.io", 80)),
+		),
+		TypeUrl: clusterType,
+	})
+}
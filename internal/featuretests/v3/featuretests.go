@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
@@ -35,6 +36,7 @@ import (
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
 	"github.com/projectcontour/contour/internal/fixture"
 	"github.com/projectcontour/contour/internal/k8s"
 	"github.com/projectcontour/contour/internal/metrics"
@@ -50,7 +52,10 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	rpc_status "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -136,8 +141,10 @@ func setup(t *testing.T, opts ...interface{}) (cache.ResourceEventHandler, *Cont
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
 
+	streams := &xds.StreamTracker{}
+
 	srv := xds.NewServer(registry)
-	contour_xds_v3.RegisterServer(contour_xds_v3.NewContourServer(log, xdscache.ResourcesOf(resources)...), srv)
+	contour_xds_v3.RegisterServer(contour_xds_v3.NewContourServer(log, streams, 0, nil, xdscache.ResourcesOf(resources)...), srv)
 
 	var g workgroup.Group
 
@@ -170,6 +177,7 @@ func setup(t *testing.T, opts ...interface{}) (cache.ResourceEventHandler, *Cont
 	return rh, &Contour{
 			T:                 t,
 			ClientConn:        cc,
+			Streams:           streams,
 			statusUpdateCache: statusUpdateCacher,
 		}, func() {
 			// close client connection
@@ -335,6 +343,9 @@ type Contour struct {
 	*grpc.ClientConn
 	*testing.T
 
+	// Streams tracks the xDS streams open to the server.
+	Streams *xds.StreamTracker
+
 	statusUpdateCache *k8s.StatusUpdateCacher
 }
 
@@ -361,45 +372,85 @@ func (c *Contour) NoStatus(obj interface{}) *Contour {
 
 func (c *Contour) Request(typeurl string, names ...string) *Response {
 	c.Helper()
-	var st grpcStream
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	st := c.openStream(ctx, typeurl)
+	resp := c.sendRequest(st, &envoy_discovery_v3.DiscoveryRequest{
+		TypeUrl:       typeurl,
+		ResourceNames: names,
+	})
+	c.checkExtensionTypes(resp)
+	return &Response{
+		Contour:           c,
+		DiscoveryResponse: resp,
+	}
+}
+
+// Stream opens a long lived xDS stream for typeurl that behaves like
+// Envoy, so tests can assert on each response pushed to the stream
+// as the Kubernetes objects change.
+func (c *Contour) Stream(typeurl string, names ...string) *Stream {
+	c.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	st := &Stream{
+		Contour:   c,
+		typeurl:   typeurl,
+		names:     names,
+		cancel:    cancel,
+		responses: make(chan *envoy_discovery_v3.DiscoveryResponse, 1),
+		errors:    make(chan error, 1),
+	}
+	st.grpcStream = c.openStream(ctx, typeurl)
+	st.send(&envoy_discovery_v3.DiscoveryRequest{
+		TypeUrl:       typeurl,
+		ResourceNames: names,
+	})
+
+	go func() {
+		for {
+			resp, err := st.grpcStream.Recv()
+			if err != nil {
+				st.errors <- err
+				return
+			}
+			st.responses <- resp
+		}
+	}()
+
+	return st
+}
+
+func (c *Contour) openStream(ctx context.Context, typeurl string) grpcStream {
+	c.Helper()
 	switch typeurl {
 	case secretType:
 		sds := envoy_service_secret_v3.NewSecretDiscoveryServiceClient(c.ClientConn)
 		sts, err := sds.StreamSecrets(ctx)
 		require.NoError(c, err)
-		st = sts
+		return sts
 	case routeType:
 		rds := envoy_service_route_v3.NewRouteDiscoveryServiceClient(c.ClientConn)
 		str, err := rds.StreamRoutes(ctx)
 		require.NoError(c, err)
-		st = str
+		return str
 	case clusterType:
 		cds := envoy_service_cluster_v3.NewClusterDiscoveryServiceClient(c.ClientConn)
 		stc, err := cds.StreamClusters(ctx)
 		require.NoError(c, err)
-		st = stc
+		return stc
 	case listenerType:
 		lds := envoy_service_listener_v3.NewListenerDiscoveryServiceClient(c.ClientConn)
 		stl, err := lds.StreamListeners(ctx)
 		require.NoError(c, err)
-		st = stl
+		return stl
 	case endpointType:
 		eds := envoy_service_endpoint_v3.NewEndpointDiscoveryServiceClient(c.ClientConn)
 		ste, err := eds.StreamEndpoints(ctx)
 		require.NoError(c, err)
-		st = ste
+		return ste
 	default:
 		c.Fatal("unknown typeURL:", typeurl)
-	}
-	resp := c.sendRequest(st, &envoy_discovery_v3.DiscoveryRequest{
-		TypeUrl:       typeurl,
-		ResourceNames: names,
-	})
-	return &Response{
-		Contour:           c,
-		DiscoveryResponse: resp,
+		return nil
 	}
 }
 
@@ -411,6 +462,64 @@ func (c *Contour) sendRequest(stream grpcStream, req *envoy_discovery_v3.Discove
 	return resp
 }
 
+// checkExtensionTypes fails the test if a resource of resp embeds
+// a message in a typed_config that is not in envoy_v3.ExtensionTypes,
+// or a configured message that is in envoy_v3.EmptyExtensionTypes.
+func (c *Contour) checkExtensionTypes(resp *envoy_discovery_v3.DiscoveryResponse) {
+	c.Helper()
+
+	known := map[protoreflect.FullName]bool{}
+	for _, name := range envoy_v3.ExtensionTypes {
+		known[name] = true
+	}
+	empty := map[protoreflect.FullName]bool{}
+	for _, name := range envoy_v3.EmptyExtensionTypes {
+		empty[name] = true
+	}
+
+	var walk func(protoreflect.Message)
+	walk = func(m protoreflect.Message) {
+		if a, ok := m.Interface().(*any.Any); ok {
+			switch {
+			case empty[a.MessageName()]:
+				if len(a.Value) > 0 {
+					c.Errorf("%s: %s is configured, but is in envoy_v3.EmptyExtensionTypes", resp.TypeUrl, a.MessageName())
+				}
+			case !known[a.MessageName()]:
+				c.Errorf("%s: %s is missing from envoy_v3.ExtensionTypes", resp.TypeUrl, a.MessageName())
+			default:
+				msg, err := a.UnmarshalNew()
+				require.NoError(c, err)
+				walk(msg.ProtoReflect())
+			}
+			return
+		}
+
+		m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			switch {
+			case fd.IsList() && fd.Message() != nil:
+				for i := 0; i < v.List().Len(); i++ {
+					walk(v.List().Get(i).Message())
+				}
+			case fd.IsMap() && fd.MapValue().Message() != nil:
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					walk(v.Message())
+					return true
+				})
+			case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+				walk(v.Message())
+			}
+			return true
+		})
+	}
+
+	for _, r := range resp.Resources {
+		m, err := r.UnmarshalNew()
+		require.NoError(c, err)
+		walk(m.ProtoReflect())
+	}
+}
+
 type Response struct {
 	*Contour
 	*envoy_discovery_v3.DiscoveryResponse
@@ -425,3 +534,101 @@ func (r *Response) Equals(want *envoy_discovery_v3.DiscoveryResponse) *Contour {
 
 	return r.Contour
 }
+
+// streamTimeout is how long a Stream waits for Contour to push a response.
+const streamTimeout = 5 * time.Second
+
+// Stream is an xDS stream opened by Contour.Stream. Like Envoy, it must
+// acknowledge or reject each response before Contour sends the next one.
+type Stream struct {
+	*Contour
+	grpcStream
+
+	typeurl string
+	names   []string
+	cancel  func()
+
+	responses chan *envoy_discovery_v3.DiscoveryResponse
+	errors    chan error
+
+	// last is the most recently received response.
+	last *envoy_discovery_v3.DiscoveryResponse
+}
+
+// Recv waits for the next response pushed to the stream.
+func (s *Stream) Recv() *Response {
+	s.Helper()
+
+	select {
+	case resp := <-s.responses:
+		s.last = resp
+		s.checkExtensionTypes(resp)
+		return &Response{
+			Contour:           s.Contour,
+			DiscoveryResponse: resp,
+		}
+	case err := <-s.errors:
+		s.Fatalf("stream terminated: %v", err)
+	case <-time.After(streamTimeout):
+		s.Fatalf("timed out waiting for %s response", s.typeurl)
+	}
+
+	return nil
+}
+
+// NoResponse asserts that nothing is pushed to the stream within d.
+func (s *Stream) NoResponse(d time.Duration) *Stream {
+	s.Helper()
+
+	select {
+	case resp := <-s.responses:
+		s.Errorf("unexpected %s response, version %q", s.typeurl, resp.VersionInfo)
+	case err := <-s.errors:
+		s.Fatalf("stream terminated: %v", err)
+	case <-time.After(d):
+	}
+
+	return s
+}
+
+// ACK acknowledges the last response received on the stream.
+func (s *Stream) ACK() *Stream {
+	s.Helper()
+	require.NotNil(s, s.last, "no response to acknowledge")
+
+	s.send(&envoy_discovery_v3.DiscoveryRequest{
+		VersionInfo:   s.last.VersionInfo,
+		ResourceNames: s.names,
+		TypeUrl:       s.typeurl,
+		ResponseNonce: s.last.Nonce,
+	})
+	return s
+}
+
+// NACK rejects the last response received on the stream with the given message.
+func (s *Stream) NACK(message string) *Stream {
+	s.Helper()
+	require.NotNil(s, s.last, "no response to reject")
+
+	s.send(&envoy_discovery_v3.DiscoveryRequest{
+		ResourceNames: s.names,
+		TypeUrl:       s.typeurl,
+		ResponseNonce: s.last.Nonce,
+		ErrorDetail: &rpc_status.Status{
+			Code:    int32(codes.InvalidArgument),
+			Message: message,
+		},
+	})
+	return s
+}
+
+// Close closes the stream.
+func (s *Stream) Close() {
+	s.cancel()
+}
+
+func (s *Stream) send(req *envoy_discovery_v3.DiscoveryRequest) {
+	s.Helper()
+	req.Node = &envoy_core_v3.Node{Id: s.Name()}
+	require.NoError(s, s.grpcStream.Send(req))
+}
@@ -0,0 +1,233 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGlobalRateLimiting(t *testing.T) {
+	rh, c, done := setup(t, func(conf *xdscache_v3.ListenerConfig) {
+		conf.RateLimitConfig = &xdscache_v3.RateLimitConfig{
+			ExtensionService: types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+			Domain:           "contour",
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("s1").
+		WithPorts(v1.ServicePort{Port: 80}),
+	)
+
+	p := fixture.NewProxy("proxy").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "foo.com"},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+			RateLimitPolicy: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Descriptors: []contour_api_v1.RateLimitDescriptor{{
+						Entries: []contour_api_v1.RateLimitDescriptorEntry{
+							{GenericKey: &contour_api_v1.GenericKeyDescriptor{Value: "foo"}},
+							{RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{}},
+						},
+					}},
+				},
+			},
+		}},
+	})
+	rh.OnAdd(p)
+
+	c.Request(listenerType, "ingress_http").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManagerBuilder().
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy_v3.FileAccessLogEnvoy("/dev/stdout")).
+						DefaultFilters().
+						AddFilter(envoy_v3.GlobalRateLimitFilter(&envoy_v3.GlobalRateLimitConfig{
+							ExtensionService: types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+							Domain:           "contour",
+						})).
+						Get(),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	route := routeCluster("default/s1/80/da39a3ee5e")
+	route.Route.RateLimits = []*envoy_route_v3.RateLimit{{
+		Actions: []*envoy_route_v3.RateLimit_Action{
+			{
+				ActionSpecifier: &envoy_route_v3.RateLimit_Action_GenericKey_{
+					GenericKey: &envoy_route_v3.RateLimit_Action_GenericKey{
+						DescriptorValue: "foo",
+					},
+				},
+			},
+			{
+				ActionSpecifier: &envoy_route_v3.RateLimit_Action_RemoteAddress_{
+					RemoteAddress: &envoy_route_v3.RateLimit_Action_RemoteAddress{},
+				},
+			},
+		},
+	}}
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("foo.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: route,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
+
+func TestGlobalRateLimitingResponseHeaders(t *testing.T) {
+	rh, c, done := setup(t, func(conf *xdscache_v3.ListenerConfig) {
+		conf.RateLimitConfig = &xdscache_v3.RateLimitConfig{
+			ExtensionService:        types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+			Domain:                  "contour",
+			EnableXRateLimitHeaders: true,
+			RetryAfter:              30 * time.Second,
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("s1").
+		WithPorts(v1.ServicePort{Port: 80}),
+	)
+
+	rh.OnAdd(fixture.NewProxy("proxy").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "foo.com"},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	}))
+
+	c.Request(listenerType, "ingress_http").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManagerBuilder().
+						RouteConfigName("ingress_http").
+						MetricsPrefix("ingress_http").
+						AccessLoggers(envoy_v3.FileAccessLogEnvoy("/dev/stdout")).
+						DefaultFilters().
+						AddFilter(envoy_v3.GlobalRateLimitFilter(&envoy_v3.GlobalRateLimitConfig{
+							ExtensionService:        types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+							Domain:                  "contour",
+							EnableXRateLimitHeaders: true,
+						})).
+						RateLimitRetryAfter(30 * time.Second).
+						Get(),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
+
+func TestGlobalRateLimitingVirtualHostPolicy(t *testing.T) {
+	rh, c, done := setup(t, func(conf *xdscache_v3.ListenerConfig) {
+		conf.RateLimitConfig = &xdscache_v3.RateLimitConfig{
+			ExtensionService: types.NamespacedName{Namespace: "projectcontour", Name: "ratelimit"},
+			Domain:           "contour",
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("s1").
+		WithPorts(v1.ServicePort{Port: 80}),
+	)
+
+	// The policy of the virtual host applies to every
+	// route, except those that disable rate limiting.
+	rh.OnAdd(fixture.NewProxy("proxy").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn: "foo.com",
+			RateLimitPolicy: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Descriptors: []contour_api_v1.RateLimitDescriptor{{
+						Entries: []contour_api_v1.RateLimitDescriptorEntry{
+							{RemoteAddress: &contour_api_v1.RemoteAddressDescriptor{}},
+						},
+					}},
+				},
+			},
+		},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}, {
+			Conditions: matchconditions(prefixMatchCondition("/login")),
+			Services:   []contour_api_v1.Service{{Name: "s1", Port: 80}},
+			RateLimitPolicy: &contour_api_v1.RateLimitPolicy{
+				Global: &contour_api_v1.GlobalRateLimitPolicy{
+					Disabled: true,
+				},
+			},
+		}},
+	}))
+
+	limited := routeCluster("default/s1/80/da39a3ee5e")
+	limited.Route.RateLimits = []*envoy_route_v3.RateLimit{{
+		Actions: []*envoy_route_v3.RateLimit_Action{{
+			ActionSpecifier: &envoy_route_v3.RateLimit_Action_RemoteAddress_{
+				RemoteAddress: &envoy_route_v3.RateLimit_Action_RemoteAddress{},
+			},
+		}},
+	}}
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("foo.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/login"),
+						Action: routeCluster("default/s1/80/da39a3ee5e"),
+					},
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: limited,
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
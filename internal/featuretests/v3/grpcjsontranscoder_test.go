@@ -0,0 +1,115 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"path"
+	"testing"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/proto"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGRPCJSONTranscoder(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	descriptor, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("helloworld.proto"),
+			Package: proto.String("helloworld"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Greeter"),
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	cert := &v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("certificate"),
+		Type:       v1.SecretTypeTLS,
+		Data:       featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+
+	rh.OnAdd(cert)
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: fixture.ObjectMeta("descriptor"),
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			dag.GRPCDescriptorKey: descriptor,
+		},
+	})
+	rh.OnAdd(fixture.NewService("greeter").
+		WithPorts(v1.ServicePort{Name: "grpc", Port: 50051}))
+
+	rh.OnAdd(fixture.NewProxy("greeter").WithSpec(
+		contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "greeter.example.com",
+				TLS:  &contour_api_v1.TLS{SecretName: "certificate"},
+				GRPCJSONTranscoder: &contour_api_v1.GRPCJSONTranscoder{
+					DescriptorSecretName: "descriptor",
+					Services:             []string{"helloworld.Greeter"},
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: "greeter",
+					Port: 50051,
+				}},
+			}},
+		}),
+	)
+
+	httpsFilter := envoy_v3.HTTPConnectionManagerBuilder().
+		AddFilter(envoy_v3.FilterMisdirectedRequests("greeter.example.com")).
+		DefaultFilters().
+		AddFilter(envoy_v3.FilterGRPCJSONTranscoder(&dag.GRPCJSONTranscoder{
+			Descriptor: descriptor,
+			Services:   []string{"helloworld.Greeter"},
+		})).
+		RouteConfigName(path.Join("https", "greeter.example.com")).
+		MetricsPrefix(xdscache_v3.ENVOY_HTTPS_LISTENER).
+		AccessLoggers(envoy_v3.FileAccessLogEnvoy("/dev/stdout")).
+		Get()
+
+	c.Request(listenerType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			defaultHTTPListener(),
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: []*envoy_listener_v3.FilterChain{
+					filterchaintls("greeter.example.com", cert, httpsFilter, nil, "h2", "http/1.1"),
+				},
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+			staticListener(),
+		),
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+	"time"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Assert that the configured HSTS policy adds a Strict-Transport-Security
+// header to TLS virtual hosts only.
+func TestHSTSPolicy(t *testing.T) {
+	policy := &dag.HSTSPolicy{
+		MaxAge:            8760 * time.Hour,
+		IncludeSubdomains: true,
+	}
+
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Processors = []dag.Processor{
+			&dag.IngressProcessor{
+				HSTSPolicy: policy,
+			},
+			&dag.HTTPProxyProcessor{
+				HSTSPolicy: policy,
+			},
+			&dag.ListenerProcessor{},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Name: "http", Port: 80}))
+
+	rh.OnAdd(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	})
+
+	rh.OnAdd(fixture.NewProxy("secure").WithSpec(
+		contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "secure.example.com",
+				TLS:  &contour_api_v1.TLS{SecretName: "secret"},
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: "kuard",
+					Port: 80,
+				}},
+			}},
+		}),
+	)
+
+	rh.OnAdd(fixture.NewProxy("insecure").WithSpec(
+		contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "insecure.example.com",
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: "kuard",
+					Port: 80,
+				}},
+			}},
+		}),
+	)
+
+	secure := envoy_v3.VirtualHost("secure.example.com",
+		&envoy_route_v3.Route{
+			Match:  routePrefix("/"),
+			Action: routeCluster("default/kuard/80/da39a3ee5e"),
+		},
+	)
+	secure.ResponseHeadersToAdd = envoy_v3.StrictTransportSecurity(policy)
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("insecure.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/80/da39a3ee5e"),
+					},
+				),
+				envoy_v3.VirtualHost("secure.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: envoy_v3.UpgradeHTTPS(),
+					},
+				),
+			),
+			envoy_v3.RouteConfiguration("https/secure.example.com", secure),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package v3
+
+import (
+	"testing"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+	"github.com/projectcontour/contour/pkg/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestAdditionalHTTPListener(t *testing.T) {
+	rh, c, done := setup(t,
+		func(conf *xdscache_v3.ListenerConfig) {
+			conf.HTTPListeners = []config.HTTPListenerParameters{{
+				Name: "ingress_internal",
+				Port: 8081,
+			}}
+		},
+		func(eh *contour.EventHandler) {
+			eh.Builder.Processors = []dag.Processor{
+				&dag.HTTPProxyProcessor{
+					Listeners: []string{"ingress_internal"},
+				},
+				&dag.ListenerProcessor{},
+			}
+		},
+	)
+	defer done()
+
+	rh.OnAdd(fixture.NewService("s1").
+		WithPorts(v1.ServicePort{Port: 80}),
+	)
+
+	rh.OnAdd(fixture.NewProxy("public").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "www.example.com"},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	}))
+
+	internal := fixture.NewProxy("internal").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn:     "admin.example.com",
+			Listener: "ingress_internal",
+		},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	})
+	rh.OnAdd(internal)
+
+	// The additional listener fetches the route
+	// configuration of the same name.
+	c.Request(listenerType, "ingress_http", "ingress_internal").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManager("ingress_http", envoy_v3.FileAccessLogEnvoy("/dev/stdout"), 0),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_internal",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8081),
+				FilterChains: envoy_v3.FilterChains(
+					envoy_v3.HTTPConnectionManager("ingress_internal", envoy_v3.FileAccessLogEnvoy("/dev/stdout"), 0),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+
+	// Each listener serves only its own virtual hosts.
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("www.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/s1/80/da39a3ee5e"),
+					},
+				),
+			),
+			envoy_v3.RouteConfiguration("ingress_internal",
+				envoy_v3.VirtualHost("admin.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/s1/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// Binding a virtual host to a listener that is
+	// not configured is an error.
+	missing := fixture.NewProxy("internal").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn:     "admin.example.com",
+			Listener: "ingress_missing",
+		},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{Name: "s1", Port: 80}},
+		}},
+	})
+	rh.OnUpdate(internal, missing)
+
+	c.Request(listenerType, "ingress_internal").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+	}).Status(missing).HasError(contour_api_v1.ConditionTypeVirtualHostError, "ListenerNotFound",
+		`Spec.VirtualHost.Listener "ingress_missing" is not a configured HTTP listener`)
+}
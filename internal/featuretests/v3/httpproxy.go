@@ -73,3 +73,12 @@ func headerPresentMatchCondition(name string) contour_api_v1.MatchCondition {
 		},
 	}
 }
+
+func queryParamExactMatchCondition(name, value string) contour_api_v1.MatchCondition {
+	return contour_api_v1.MatchCondition{
+		QueryParameter: &contour_api_v1.QueryParameterMatchCondition{
+			Name:  name,
+			Exact: value,
+		},
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// TestIngressPathTypeConformance checks the routes generated for
+// Ingress paths against the examples in the Kubernetes Ingress
+// documentation, by matching request paths the way Envoy does.
+func TestIngressPathTypeConformance(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	type path struct {
+		pathType v1beta1.PathType
+		path     string
+	}
+
+	prefix := func(p string) path { return path{v1beta1.PathTypePrefix, p} }
+	exact := func(p string) path { return path{v1beta1.PathTypeExact, p} }
+	implementationSpecific := func(p string) path { return path{v1beta1.PathTypeImplementationSpecific, p} }
+
+	// Each case is translated to an Ingress whose paths route to
+	// backend-0, backend-1, ... in order. want maps each request path
+	// to the index of the backend it should be routed to, or -1 if
+	// it should not match any path.
+	tests := []struct {
+		paths []path
+		want  map[string]int
+	}{
+		{paths: []path{prefix("/")}, want: map[string]int{"/": 0, "/foo": 0, "/foo/bar": 0}},
+		{paths: []path{exact("/foo")}, want: map[string]int{"/foo": 0, "/bar": -1, "/foo/": -1}},
+		{paths: []path{exact("/foo/")}, want: map[string]int{"/foo": -1}},
+		{paths: []path{prefix("/foo")}, want: map[string]int{"/foo": 0, "/foo/": 0}},
+		{paths: []path{prefix("/foo/")}, want: map[string]int{"/foo": 0, "/foo/": 0}},
+		{paths: []path{prefix("/aaa/bb")}, want: map[string]int{"/aaa/bbb": -1}},
+		{paths: []path{prefix("/aaa/bbb")}, want: map[string]int{"/aaa/bbb": 0, "/aaa/bbb/": 0, "/aaa/bbb/ccc": 0, "/aaa/bbbxyz": -1}},
+		{paths: []path{prefix("/aaa/bbb/")}, want: map[string]int{"/aaa/bbb": 0}},
+		{paths: []path{prefix("/"), prefix("/aaa")}, want: map[string]int{"/aaa/ccc": 1}},
+		{paths: []path{prefix("/"), prefix("/aaa"), prefix("/aaa/bbb")}, want: map[string]int{"/aaa/bbb": 2, "/ccc": 0}},
+		{paths: []path{prefix("/aaa")}, want: map[string]int{"/ccc": -1}},
+		{paths: []path{prefix("/foo"), exact("/foo")}, want: map[string]int{"/foo": 1, "/foo/bar": 0}},
+		{paths: []path{prefix("/foo.bar")}, want: map[string]int{"/foo.bar": 0, "/fooxbar": -1}},
+		{paths: []path{prefix("/foo")}, want: map[string]int{"/foo?bar=baz": 0, "/foobar?baz": -1}},
+		// Longer string prefixes take precedence over shorter
+		// segment prefixes.
+		{paths: []path{prefix("/api"), implementationSpecific("/api/v2/")}, want: map[string]int{"/api/v2/users": 1, "/api/v2": 0, "/api/v1/users": 0}},
+	}
+
+	for i := 0; i < 3; i++ {
+		rh.OnAdd(fixture.NewService(fmt.Sprintf("backend-%d", i)).
+			WithPorts(v1.ServicePort{Port: 80}))
+	}
+
+	for i, tc := range tests {
+		var paths []v1beta1.HTTPIngressPath
+		for j, p := range tc.paths {
+			pathType := p.pathType
+			paths = append(paths, v1beta1.HTTPIngressPath{
+				Path:     p.path,
+				PathType: &pathType,
+				Backend: v1beta1.IngressBackend{
+					ServiceName: fmt.Sprintf("backend-%d", j),
+					ServicePort: intstr.FromInt(80),
+				},
+			})
+		}
+
+		rh.OnAdd(&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("case-%d", i),
+				Namespace: "default",
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{{
+					Host: fmt.Sprintf("case-%d.example.com", i),
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{Paths: paths},
+					},
+				}},
+			},
+		})
+	}
+
+	resp := c.Request(routeType, "ingress_http")
+	require.Len(t, resp.Resources, 1)
+
+	var rc envoy_route_v3.RouteConfiguration
+	require.NoError(t, ptypes.UnmarshalAny(resp.Resources[0], &rc))
+
+	vhosts := map[string]*envoy_route_v3.VirtualHost{}
+	for _, vh := range rc.VirtualHosts {
+		vhosts[vh.Name] = vh
+	}
+
+	for i, tc := range tests {
+		vh, ok := vhosts[fmt.Sprintf("case-%d.example.com", i)]
+		require.True(t, ok, "case %d: missing virtual host", i)
+
+		for reqPath, want := range tc.want {
+			got := -1
+			for _, r := range vh.Routes {
+				if routeMatches(t, r.Match, reqPath) {
+					cluster := r.GetRoute().GetCluster()
+					_, err := fmt.Sscanf(strings.TrimPrefix(cluster, "default/backend-"), "%d", &got)
+					require.NoError(t, err)
+					break
+				}
+			}
+			assert.Equal(t, want, got, "case %d %v: request path %q", i, tc.paths, reqPath)
+		}
+	}
+}
+
+// routeMatches reports whether Envoy would match the request path with m.
+func routeMatches(t *testing.T, m *envoy_route_v3.RouteMatch, path string) bool {
+	for _, h := range m.Headers {
+		require.Equal(t, ":path", h.Name)
+		re, err := regexp.Compile("^(?:" + h.GetSafeRegexMatch().Regex + ")$")
+		require.NoError(t, err)
+		if !re.MatchString(path) {
+			return false
+		}
+	}
+
+	switch ps := m.PathSpecifier.(type) {
+	case *envoy_route_v3.RouteMatch_Path:
+		return ps.Path == path
+	case *envoy_route_v3.RouteMatch_Prefix:
+		return strings.HasPrefix(path, ps.Prefix)
+	case *envoy_route_v3.RouteMatch_SafeRegex:
+		// Envoy requires the regex to match the whole path.
+		re, err := regexp.Compile("^(?:" + ps.SafeRegex.Regex + ")$")
+		require.NoError(t, err)
+		return re.MatchString(path)
+	default:
+		t.Fatalf("unexpected path specifier %T", ps)
+		return false
+	}
+}
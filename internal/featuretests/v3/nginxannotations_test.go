@@ -0,0 +1,142 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_config_filter_http_buffer_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/buffer/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNginxAnnotations(t *testing.T) {
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Processors = []dag.Processor{
+			&dag.IngressProcessor{
+				FieldLogger:      fixture.NewTestLogger(t),
+				NginxAnnotations: true,
+			},
+			&dag.ListenerProcessor{},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Port: 80}))
+
+	ingress := func(name, host string, annotations map[string]string) *v1beta1.Ingress {
+		return &v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{{
+					Host: host,
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{{
+								Path: "/app",
+								Backend: v1beta1.IngressBackend{
+									ServiceName: "kuard",
+									ServicePort: intstr.FromInt(80),
+								},
+							}},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	rh.OnAdd(ingress("rewrite", "rewrite.example.com", map[string]string{
+		"nginx.ingress.kubernetes.io/rewrite-target":  "/",
+		"nginx.ingress.kubernetes.io/proxy-body-size": "8m",
+		"nginx.ingress.kubernetes.io/affinity":        "cookie",
+	}))
+	rh.OnAdd(ingress("redirect", "redirect.example.com", map[string]string{
+		"nginx.ingress.kubernetes.io/ssl-redirect": "true",
+	}))
+	rh.OnAdd(ingress("hash", "hash.example.com", map[string]string{
+		"nginx.ingress.kubernetes.io/upstream-hash-by": "$http_x_user_id",
+	}))
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("hash.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/app"),
+						Action: withHeaderHash(routeCluster("default/kuard/80/1a2ffc1fef"), "x-user-id"),
+						TypedPerFilterConfig: withFilterConfig("envoy.filters.http.buffer",
+							&envoy_config_filter_http_buffer_v3.BufferPerRoute{
+								Override: &envoy_config_filter_http_buffer_v3.BufferPerRoute_Disabled{
+									Disabled: true,
+								},
+							}),
+					},
+				),
+				envoy_v3.VirtualHost("redirect.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/app"),
+						Action: withRedirect(),
+						TypedPerFilterConfig: withFilterConfig("envoy.filters.http.buffer",
+							&envoy_config_filter_http_buffer_v3.BufferPerRoute{
+								Override: &envoy_config_filter_http_buffer_v3.BufferPerRoute_Disabled{
+									Disabled: true,
+								},
+							}),
+					},
+				),
+				envoy_v3.VirtualHost("rewrite.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/app"),
+						Action: withSessionAffinity(withPrefixRewrite(routeCluster("default/kuard/80/e4f81994fe"), "/")),
+						TypedPerFilterConfig: withFilterConfig("envoy.filters.http.buffer",
+							&envoy_config_filter_http_buffer_v3.BufferPerRoute{
+								Override: &envoy_config_filter_http_buffer_v3.BufferPerRoute_Buffer{
+									Buffer: &envoy_config_filter_http_buffer_v3.Buffer{
+										MaxRequestBytes: protobuf.UInt32(8 << 20),
+									},
+								},
+							}),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
+
+func withHeaderHash(route *envoy_route_v3.Route_Route, header string) *envoy_route_v3.Route_Route {
+	route.Route.HashPolicy = append(route.Route.HashPolicy, &envoy_route_v3.RouteAction_HashPolicy{
+		PolicySpecifier: &envoy_route_v3.RouteAction_HashPolicy_Header_{
+			Header: &envoy_route_v3.RouteAction_HashPolicy_Header{
+				HeaderName: header,
+			},
+		},
+	})
+	return route
+}
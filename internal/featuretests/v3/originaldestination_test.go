@@ -0,0 +1,123 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"net"
+	"testing"
+
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/protobuf"
+	xdscache_v3 "github.com/projectcontour/contour/internal/xdscache/v3"
+)
+
+func TestOriginalDestination(t *testing.T) {
+	rh, c, done := setup(t,
+		func(eh *contour.EventHandler) {
+			eh.Builder.Processors = []dag.Processor{
+				&dag.HTTPProxyProcessor{
+					EnableOriginalDestination: true,
+				},
+				&dag.ListenerProcessor{},
+			}
+		},
+		func(conf *xdscache_v3.ListenerConfig) {
+			conf.TrustedProxyCIDRs = []*net.IPNet{{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}}
+		},
+	)
+	defer done()
+
+	rh.OnAdd(fixture.NewProxy("passthrough").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn: "passthrough.example.com",
+		},
+		Routes: []contour_api_v1.Route{{
+			Conditions:          matchconditions(prefixMatchCondition("/")),
+			OriginalDestination: &contour_api_v1.OriginalDestination{},
+		}},
+	}))
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("passthrough.example.com",
+					&envoy_route_v3.Route{
+						Match: routePrefix("/"),
+						Action: &envoy_route_v3.Route_Route{
+							Route: &envoy_route_v3.RouteAction{
+								ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{
+									Cluster: envoy_v3.OriginalDestinationClusterName,
+								},
+							},
+						},
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.OriginalDestinationCluster(),
+		),
+		TypeUrl: clusterType,
+	})
+	// Only trusted proxies may name the original destination,
+	// the header is removed from the requests of anyone else.
+	httpFilters := func(trusted bool) []*envoy_listener_v3.Filter {
+		b := envoy_v3.HTTPConnectionManagerBuilder()
+		if !trusted {
+			b = b.AddFilter(envoy_v3.FilterOverwriteForwardedFor()).
+				AddFilter(envoy_v3.FilterRemoveOriginalDestinationHost())
+		}
+		return envoy_v3.Filters(b.
+			DefaultFilters().
+			RouteConfigName("ingress_http").
+			MetricsPrefix("ingress_http").
+			AccessLoggers(envoy_v3.FileAccessLogEnvoy("/dev/stdout")).
+			Get(),
+		)
+	}
+
+	c.Request(listenerType, "ingress_http").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_http",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8080),
+				FilterChains: []*envoy_listener_v3.FilterChain{{
+					Filters: httpFilters(false),
+				}, {
+					FilterChainMatch: &envoy_listener_v3.FilterChainMatch{
+						SourcePrefixRanges: []*envoy_core_v3.CidrRange{{
+							AddressPrefix: "10.0.0.0",
+							PrefixLen:     protobuf.UInt32(8),
+						}},
+					},
+					Filters: httpFilters(true),
+				}},
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/protobuf"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Assert that the configured retry budget is applied to clusters,
+// and that a route's retry policy may override it.
+func TestRetryBudget(t *testing.T) {
+	budget := &dag.RetryBudget{BudgetPercent: 20}
+
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Processors = []dag.Processor{
+			&dag.IngressProcessor{
+				RetryBudget: budget,
+			},
+			&dag.HTTPProxyProcessor{
+				RetryBudget: budget,
+			},
+			&dag.ListenerProcessor{},
+		}
+	})
+	defer done()
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Name: "http", Port: 80}))
+
+	rh.OnAdd(fixture.NewProxy("simple").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "example.com"},
+		Routes: []contour_api_v1.Route{{
+			Conditions: matchconditions(prefixMatchCondition("/")),
+			Services: []contour_api_v1.Service{{
+				Name: "kuard",
+				Port: 80,
+			}},
+		}, {
+			Conditions: matchconditions(prefixMatchCondition("/api")),
+			RetryPolicy: &contour_api_v1.RetryPolicy{
+				NumRetries: 3,
+				Budget: &contour_api_v1.RetryBudget{
+					BudgetPercent:       50,
+					MinRetryConcurrency: 5,
+				},
+			},
+			Services: []contour_api_v1.Service{{
+				Name: "kuard",
+				Port: 80,
+			}},
+		}},
+	}))
+
+	withBudget := func(c *envoy_cluster_v3.Cluster, percent float64, min uint32) *envoy_cluster_v3.Cluster {
+		c.CircuitBreakers = &envoy_cluster_v3.CircuitBreakers{
+			Thresholds: []*envoy_cluster_v3.CircuitBreakers_Thresholds{{
+				RetryBudget: &envoy_cluster_v3.CircuitBreakers_Thresholds_RetryBudget{
+					BudgetPercent:       &envoy_type.Percent{Value: percent},
+					MinRetryConcurrency: protobuf.UInt32OrNil(min),
+				},
+			}},
+		}
+		return c
+	}
+
+	c.Request(clusterType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			withBudget(cluster("default/kuard/80/3ead28f890", "default/kuard/http", "default_kuard_80"), 50, 5),
+			withBudget(cluster("default/kuard/80/9f9af02958", "default/kuard/http", "default_kuard_80"), 20, 0),
+		),
+		TypeUrl: clusterType,
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/status"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecondaryTLSCertificate(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rsaSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rsa",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(rsaSecret)
+
+	ecSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ecdsa",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(fixture.EC_CERTIFICATE, fixture.EC_PRIVATE_KEY),
+	}
+	rh.OnAdd(ecSecret)
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Name: "http", Port: 8080}))
+
+	proxy := fixture.NewProxy("example.com").
+		WithSpec(contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName:          rsaSecret.Name,
+					SecondarySecretName: ecSecret.Name,
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: "kuard",
+					Port: 8080,
+				}},
+			}},
+		})
+	rh.OnAdd(proxy)
+
+	// Both certificates are served from the same filter chain.
+	tlsContext := envoy_v3.DownstreamTLSContext(
+		&dag.Secret{Object: rsaSecret},
+		envoy_tls_v3.TlsParameters_TLSv1_2,
+		nil,
+		"h2", "http/1.1")
+	tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(
+		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs,
+		envoy_v3.TLSCertificateSdsSecretConfig(&dag.Secret{Object: ecSecret}),
+	)
+
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					envoy_v3.FilterChainTLS(
+						"example.com",
+						tlsContext,
+						envoy_v3.Filters(httpsFilterFor("example.com")),
+					),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+		TypeUrl: listenerType,
+	}).Status(proxy).Like(
+		contour_api_v1.HTTPProxyStatus{CurrentStatus: string(status.ProxyStatusValid)},
+	)
+
+	c.Request(secretType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			secret(ecSecret),
+			secret(rsaSecret),
+		),
+		TypeUrl: secretType,
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/fixture"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestActiveServiceSet(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	rh.OnAdd(fixture.NewService("app-blue").
+		WithPorts(v1.ServicePort{Port: 80}))
+	rh.OnAdd(fixture.NewService("app-green-1").
+		WithPorts(v1.ServicePort{Port: 80}))
+	rh.OnAdd(fixture.NewService("app-green-2").
+		WithPorts(v1.ServicePort{Port: 80}))
+
+	proxy := func(active string) *contour_api_v1.HTTPProxy {
+		return fixture.NewProxy("app").WithSpec(contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{Fqdn: "app.example.com"},
+			Routes: []contour_api_v1.Route{{
+				ActiveServiceSet: active,
+				Services: []contour_api_v1.Service{{
+					Name:       "app-blue",
+					Port:       80,
+					ServiceSet: "blue",
+				}, {
+					Name:       "app-green-1",
+					Port:       80,
+					Weight:     80,
+					ServiceSet: "green",
+				}, {
+					Name:       "app-green-2",
+					Port:       80,
+					Weight:     20,
+					ServiceSet: "green",
+				}},
+			}},
+		})
+	}
+
+	p1 := proxy("blue")
+	rh.OnAdd(p1)
+
+	// All traffic goes to the blue service set.
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("app.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/app-blue/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+
+	// Flipping the active set moves all traffic to the green
+	// service set, weighted between its services.
+	rh.OnUpdate(p1, proxy("green"))
+
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: resources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("app.example.com",
+					&envoy_route_v3.Route{
+						Match: routePrefix("/"),
+						Action: routeWeightedCluster(
+							weightedCluster{"default/app-green-1/80/da39a3ee5e", 80},
+							weightedCluster{"default/app-green-2/80/da39a3ee5e", 20},
+						),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,233 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"bytes"
+	"testing"
+
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSessionTicketKeys(t *testing.T) {
+	ticketKeys := types.NamespacedName{Name: "ticketkeys", Namespace: "admin"}
+
+	rh, c, done := setup(t, func(eh *contour.EventHandler) {
+		eh.Builder.Source.ConfiguredSecretRefs = []*types.NamespacedName{&ticketKeys}
+		eh.Builder.Processors = []dag.Processor{
+			&dag.IngressProcessor{},
+			&dag.HTTPProxyProcessor{},
+			&dag.ListenerProcessor{
+				FieldLogger:       fixture.NewTestLogger(t),
+				SessionTicketKeys: &ticketKeys,
+			},
+		}
+	})
+	defer done()
+
+	sec1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "default",
+		},
+		Type: "kubernetes.io/tls",
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(sec1)
+
+	s1 := fixture.NewService("backend").
+		WithPorts(v1.ServicePort{Name: "http", Port: 80})
+	rh.OnAdd(s1)
+
+	rh.OnAdd(fixture.NewProxy("simple").WithSpec(
+		contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "tickets.example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName: "secret",
+				},
+			},
+			Routes: []contour_api_v1.Route{{
+				Services: []contour_api_v1.Service{{
+					Name: s1.Name,
+					Port: 80,
+				}},
+			}},
+		}),
+	)
+
+	// Without the session ticket keys Secret, the listener
+	// leaves Envoy to generate its own keys.
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("tickets.example.com", sec1,
+						httpsFilterFor("tickets.example.com"),
+						nil, "h2", "http/1.1"),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+	})
+
+	keys1 := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ticketkeys",
+			Namespace: "admin",
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"key-2": ticketKey('2'),
+			"key-1": ticketKey('1'),
+		},
+	}
+	rh.OnAdd(keys1)
+
+	// With the keys, every TLS filter chain fetches them over SDS.
+	ticketChain := envoy_v3.FilterChainTLS(
+		"tickets.example.com",
+		withSessionTicketKeys(
+			envoy_v3.DownstreamTLSContext(
+				&dag.Secret{Object: sec1},
+				envoy_tls_v3.TlsParameters_TLSv1_2,
+				nil,
+				"h2", "http/1.1"),
+			keys1,
+		),
+		envoy_v3.Filters(httpsFilterFor("tickets.example.com")),
+	)
+
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains:  appendFilterChains(ticketChain),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+	})
+
+	// The keys are ordered by their names.
+	c.Request(secretType, "admin/ticketkeys/da39a3ee5e").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: secretType,
+		Resources: resources(t,
+			sessionTicketKeysSecret("admin/ticketkeys/da39a3ee5e", ticketKey('1'), ticketKey('2')),
+		),
+	})
+
+	// Rotating the keys only updates the secret.
+	keys2 := &v1.Secret{
+		ObjectMeta: keys1.ObjectMeta,
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"key-0": ticketKey('0'),
+			"key-1": ticketKey('1'),
+		},
+	}
+	rh.OnUpdate(keys1, keys2)
+
+	c.Request(secretType, "admin/ticketkeys/da39a3ee5e").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: secretType,
+		Resources: resources(t,
+			sessionTicketKeysSecret("admin/ticketkeys/da39a3ee5e", ticketKey('0'), ticketKey('1')),
+		),
+	})
+
+	// Keys of the wrong length are rejected.
+	keys3 := &v1.Secret{
+		ObjectMeta: keys1.ObjectMeta,
+		Type:       v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"key-0": []byte("short"),
+		},
+	}
+	rh.OnUpdate(keys2, keys3)
+
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("tickets.example.com", sec1,
+						httpsFilterFor("tickets.example.com"),
+						nil, "h2", "http/1.1"),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+	})
+
+	c.Request(secretType, "admin/ticketkeys/da39a3ee5e").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: secretType,
+	})
+}
+
+// ticketKey returns a session ticket key filled with b.
+func ticketKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dag.SessionTicketKeyLength)
+}
+
+func withSessionTicketKeys(tls *envoy_tls_v3.DownstreamTlsContext, keys *v1.Secret) *envoy_tls_v3.DownstreamTlsContext {
+	tls.SessionTicketKeysType = envoy_v3.SessionTicketKeysSdsSecretConfig(&dag.Secret{Object: keys})
+	return tls
+}
+
+func sessionTicketKeysSecret(name string, keys ...[]byte) *envoy_tls_v3.Secret {
+	var sources []*envoy_core_v3.DataSource
+	for _, k := range keys {
+		sources = append(sources, &envoy_core_v3.DataSource{
+			Specifier: &envoy_core_v3.DataSource_InlineBytes{
+				InlineBytes: k,
+			},
+		})
+	}
+
+	return &envoy_tls_v3.Secret{
+		Name: name,
+		Type: &envoy_tls_v3.Secret_SessionTicketKeys{
+			SessionTicketKeys: &envoy_tls_v3.TlsSessionTicketKeys{
+				Keys: sources,
+			},
+		},
+	}
+}
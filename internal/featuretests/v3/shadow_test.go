@@ -0,0 +1,130 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	envoy_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/featuretests"
+	"github.com/projectcontour/contour/internal/fixture"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShadowVirtualHost(t *testing.T) {
+	rh, c, done := setup(t)
+	defer done()
+
+	production := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "production",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(production)
+
+	staging := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "staging",
+			Namespace: "default",
+		},
+		Type: v1.SecretTypeTLS,
+		Data: featuretests.Secretdata(featuretests.CERTIFICATE, featuretests.RSA_PRIVATE_KEY),
+	}
+	rh.OnAdd(staging)
+
+	rh.OnAdd(fixture.NewService("kuard").
+		WithPorts(v1.ServicePort{Name: "http", Port: 80}))
+
+	rh.OnAdd(fixture.NewProxy("app").WithSpec(contour_api_v1.HTTPProxySpec{
+		VirtualHost: &contour_api_v1.VirtualHost{
+			Fqdn: "app.example.com",
+			TLS: &contour_api_v1.TLS{
+				SecretName: production.Name,
+				Shadow: &contour_api_v1.ShadowVirtualHost{
+					Fqdn:       "staging.app.example.com",
+					SecretName: staging.Name,
+				},
+			},
+		},
+		Routes: []contour_api_v1.Route{{
+			Services: []contour_api_v1.Service{{
+				Name: "kuard",
+				Port: 80,
+			}},
+		}},
+	}))
+
+	// The shadow FQDN gets its own filter chain, serving the
+	// staging certificate.
+	c.Request(listenerType, "ingress_https").Equals(&envoy_discovery_v3.DiscoveryResponse{
+		TypeUrl: listenerType,
+		Resources: resources(t,
+			&envoy_listener_v3.Listener{
+				Name:    "ingress_https",
+				Address: envoy_v3.SocketAddress("0.0.0.0", 8443),
+				ListenerFilters: envoy_v3.ListenerFilters(
+					envoy_v3.TLSInspector(),
+				),
+				FilterChains: appendFilterChains(
+					filterchaintls("app.example.com", production,
+						httpsFilterFor("app.example.com"),
+						nil, "h2", "http/1.1"),
+					filterchaintls("staging.app.example.com", staging,
+						httpsFilterFor("staging.app.example.com"),
+						nil, "h2", "http/1.1"),
+				),
+				SocketOptions: envoy_v3.TCPKeepaliveSocketOptions(),
+			},
+		),
+	})
+
+	// The shadow FQDN serves the same routes over TLS only.
+	c.Request(routeType).Equals(&envoy_discovery_v3.DiscoveryResponse{
+		Resources: routeResources(t,
+			envoy_v3.RouteConfiguration("ingress_http",
+				envoy_v3.VirtualHost("app.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: envoy_v3.UpgradeHTTPS(),
+					},
+				),
+			),
+			envoy_v3.RouteConfiguration("https/app.example.com",
+				envoy_v3.VirtualHost("app.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/80/da39a3ee5e"),
+					},
+				),
+			),
+			envoy_v3.RouteConfiguration("https/staging.app.example.com",
+				envoy_v3.VirtualHost("staging.app.example.com",
+					&envoy_route_v3.Route{
+						Match:  routePrefix("/"),
+						Action: routeCluster("default/kuard/80/da39a3ee5e"),
+					},
+				),
+			),
+		),
+		TypeUrl: routeType,
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+// CRL returns a PEM encoded certificate revocation list, issued by
+// the first certificate in the given PEM data, that revokes that
+// certificate. The list is signed by a throwaway key, so its
+// signature does not verify.
+func CRL(t *testing.T, cert string) []byte {
+	t.Helper()
+
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil {
+		t.Fatal("failed to decode certificate")
+	}
+
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	crl, err := c.CreateCRL(rand.Reader, key, []pkix.RevokedCertificate{{
+		SerialNumber:   c.SerialNumber,
+		RevocationTime: now,
+	}}, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})
+}
@@ -0,0 +1,61 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSPStaple returns a DER encoded OCSP response, reporting the
+// first certificate in the given PEM data as good. The response
+// is signed by a throwaway key, so its signature does not verify.
+func OCSPStaple(t *testing.T, cert string) []byte {
+	t.Helper()
+
+	block, _ := pem.Decode([]byte(cert))
+	if block == nil {
+		t.Fatal("failed to decode certificate")
+	}
+
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	staple, err := ocsp.CreateResponse(c, c, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: c.SerialNumber,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(time.Hour),
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return staple
+}
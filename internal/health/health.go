@@ -35,3 +35,17 @@ func Handler(client *kubernetes.Clientset) http.Handler {
 		fmt.Fprintln(w, "OK")
 	})
 }
+
+// ReadyHandler returns a http Handler for a readiness endpoint,
+// which fails until the ready channel is closed.
+func ReadyHandler(ready <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-ready:
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintln(w, "OK")
+		default:
+			http.Error(w, "Waiting for the first snapshot", http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/annotation"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// +kubebuilder:rbac:groups="cert-manager.io",resources=certificates,verbs=get;create;update
+
+const (
+	// CertManagerIssuerAnnotation names a namespaced cert-manager
+	// Issuer to request the HTTPProxy TLS certificate from.
+	CertManagerIssuerAnnotation = "cert-manager.io/issuer"
+
+	// CertManagerClusterIssuerAnnotation names a cert-manager
+	// ClusterIssuer to request the HTTPProxy TLS certificate from.
+	CertManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+)
+
+// CertificateGVR is the GroupVersionResource of cert-manager Certificates.
+var CertificateGVR = schema.GroupVersionResource{
+	Group:    "cert-manager.io",
+	Version:  "v1",
+	Resource: "certificates",
+}
+
+// CertificateResources returns the resources that must be present on the
+// API server for Contour to request certificates from cert-manager.
+func CertificateResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{CertificateGVR}
+}
+
+// CertificateWriter observes HTTPProxy informer events and creates a
+// cert-manager Certificate for each root HTTPProxy that is annotated with
+// an issuer. The Certificate is written to the Secret named by the
+// HTTPProxy's TLS stanza, so once cert-manager issues it the Secret is
+// picked up by the DAG like any other TLS Secret.
+//
+// Certificates are owned by the HTTPProxy that requested them, so they
+// are garbage collected when the HTTPProxy is deleted.
+type CertificateWriter struct {
+	Log           logrus.FieldLogger
+	Client        dynamic.Interface
+	LeaderElected chan struct{}
+	IngressClass  string
+	UpdateChannel chan *unstructured.Unstructured
+}
+
+// OnAdd queues a Certificate for the given HTTPProxy if it requests one.
+func (c *CertificateWriter) OnAdd(obj interface{}) {
+	proxy, ok := obj.(*contour_api_v1.HTTPProxy)
+	if !ok {
+		return
+	}
+
+	if !annotation.MatchesIngressClass(proxy, c.IngressClass) {
+		return
+	}
+
+	cert, err := certificateFor(proxy)
+	if err != nil {
+		c.Log.WithError(err).
+			WithField("name", proxy.Name).
+			WithField("namespace", proxy.Namespace).
+			Error("unable to request certificate")
+		return
+	}
+
+	if cert == nil {
+		return
+	}
+
+	c.UpdateChannel <- cert
+}
+
+func (c *CertificateWriter) OnUpdate(oldObj, newObj interface{}) {
+	c.OnAdd(newObj)
+}
+
+func (c *CertificateWriter) OnDelete(obj interface{}) {
+	// Certificates are owned by their HTTPProxy, so
+	// Kubernetes garbage collects them for us.
+}
+
+// Start runs the goroutine that writes Certificates. Certificates that
+// are requested before Contour is elected leader are held until the
+// election, latest request per HTTPProxy winning.
+func (c *CertificateWriter) Start(stop <-chan struct{}) error {
+	pending := map[types.NamespacedName]*unstructured.Unstructured{}
+	isLeader := false
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-c.LeaderElected:
+			isLeader = true
+			// disable this case
+			c.LeaderElected = nil
+
+			for _, cert := range pending {
+				c.apply(cert)
+			}
+			pending = nil
+		case cert := <-c.UpdateChannel:
+			if !isLeader {
+				pending[types.NamespacedName{Namespace: cert.GetNamespace(), Name: cert.GetName()}] = cert
+				continue
+			}
+
+			c.apply(cert)
+		}
+	}
+}
+
+// apply creates the given Certificate, or updates the spec of an
+// existing Certificate that is owned by the same HTTPProxy.
+func (c *CertificateWriter) apply(cert *unstructured.Unstructured) {
+	log := c.Log.WithField("name", cert.GetName()).WithField("namespace", cert.GetNamespace())
+	client := c.Client.Resource(CertificateGVR).Namespace(cert.GetNamespace())
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		current, err := client.Get(context.Background(), cert.GetName(), metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			log.Info("creating certificate")
+			_, err = client.Create(context.Background(), cert, metav1.CreateOptions{})
+			return err
+		case err != nil:
+			return err
+		}
+
+		if !ownedBySameObject(current, cert) {
+			return errors.New("existing certificate is not owned by this HTTPProxy")
+		}
+
+		if reflect.DeepEqual(current.Object["spec"], cert.Object["spec"]) {
+			log.Debug("certificate is up to date")
+			return nil
+		}
+
+		log.Info("updating certificate")
+		current.Object["spec"] = cert.Object["spec"]
+		_, err = client.Update(context.Background(), current, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.WithError(err).Error("unable to write certificate")
+	}
+}
+
+func ownedBySameObject(a, b *unstructured.Unstructured) bool {
+	for _, x := range a.GetOwnerReferences() {
+		for _, y := range b.GetOwnerReferences() {
+			if x.UID == y.UID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// certificateFor returns the cert-manager Certificate requested by the
+// given HTTPProxy, or nil if the HTTPProxy does not request one.
+func certificateFor(proxy *contour_api_v1.HTTPProxy) (*unstructured.Unstructured, error) {
+	issuer, kind := proxy.Annotations[CertManagerIssuerAnnotation], "Issuer"
+	if clusterIssuer := proxy.Annotations[CertManagerClusterIssuerAnnotation]; clusterIssuer != "" {
+		if issuer != "" {
+			return nil, fmt.Errorf("only one of %q and %q may be set",
+				CertManagerIssuerAnnotation, CertManagerClusterIssuerAnnotation)
+		}
+		issuer, kind = clusterIssuer, "ClusterIssuer"
+	}
+
+	if issuer == "" {
+		return nil, nil
+	}
+
+	vhost := proxy.Spec.VirtualHost
+	if vhost == nil || vhost.TLS == nil || vhost.TLS.SecretName == "" {
+		return nil, errors.New("certificate issuer is set but spec.virtualhost.tls.secretName is not")
+	}
+
+	if strings.Contains(vhost.TLS.SecretName, "/") {
+		return nil, fmt.Errorf("certificates can only be issued to Secrets in the HTTPProxy namespace, not %q", vhost.TLS.SecretName)
+	}
+
+	cert := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"secretName": vhost.TLS.SecretName,
+				"dnsNames":   []interface{}{vhost.Fqdn},
+				"issuerRef": map[string]interface{}{
+					"name":  issuer,
+					"kind":  kind,
+					"group": CertificateGVR.Group,
+				},
+			},
+		},
+	}
+
+	cert.SetAPIVersion(CertificateGVR.GroupVersion().String())
+	cert.SetKind("Certificate")
+	cert.SetNamespace(proxy.Namespace)
+	cert.SetName(proxy.Name)
+	cert.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(proxy, contour_api_v1.GroupVersion.WithKind("HTTPProxy")),
+	})
+
+	return cert, nil
+}
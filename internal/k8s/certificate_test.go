@@ -0,0 +1,168 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func certProxy(annotations map[string]string, secretName string) *contour_api_v1.HTTPProxy {
+	return &contour_api_v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example",
+			Namespace:   "default",
+			UID:         "6f05e1d8-a1b3-4c44-8a73-5f0b4f2fb4b1",
+			Annotations: annotations,
+		},
+		Spec: contour_api_v1.HTTPProxySpec{
+			VirtualHost: &contour_api_v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &contour_api_v1.TLS{
+					SecretName: secretName,
+				},
+			},
+		},
+	}
+}
+
+func TestCertificateFor(t *testing.T) {
+	tests := map[string]struct {
+		proxy   *contour_api_v1.HTTPProxy
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		"no issuer": {
+			proxy: certProxy(nil, "example-tls"),
+			want:  nil,
+		},
+		"issuer": {
+			proxy: certProxy(map[string]string{
+				CertManagerIssuerAnnotation: "letsencrypt",
+			}, "example-tls"),
+			want: map[string]interface{}{
+				"secretName": "example-tls",
+				"dnsNames":   []interface{}{"example.com"},
+				"issuerRef": map[string]interface{}{
+					"name":  "letsencrypt",
+					"kind":  "Issuer",
+					"group": "cert-manager.io",
+				},
+			},
+		},
+		"cluster issuer": {
+			proxy: certProxy(map[string]string{
+				CertManagerClusterIssuerAnnotation: "letsencrypt-prod",
+			}, "example-tls"),
+			want: map[string]interface{}{
+				"secretName": "example-tls",
+				"dnsNames":   []interface{}{"example.com"},
+				"issuerRef": map[string]interface{}{
+					"name":  "letsencrypt-prod",
+					"kind":  "ClusterIssuer",
+					"group": "cert-manager.io",
+				},
+			},
+		},
+		"both issuers": {
+			proxy: certProxy(map[string]string{
+				CertManagerIssuerAnnotation:        "letsencrypt",
+				CertManagerClusterIssuerAnnotation: "letsencrypt-prod",
+			}, "example-tls"),
+			wantErr: true,
+		},
+		"no secret name": {
+			proxy: certProxy(map[string]string{
+				CertManagerIssuerAnnotation: "letsencrypt",
+			}, ""),
+			wantErr: true,
+		},
+		"delegated secret": {
+			proxy: certProxy(map[string]string{
+				CertManagerIssuerAnnotation: "letsencrypt",
+			}, "certs/example-tls"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := certificateFor(tc.proxy)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tc.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.Equal(t, "cert-manager.io/v1", got.GetAPIVersion())
+			assert.Equal(t, "Certificate", got.GetKind())
+			assert.Equal(t, "default", got.GetNamespace())
+			assert.Equal(t, "example", got.GetName())
+			assert.Equal(t, tc.proxy.UID, got.GetOwnerReferences()[0].UID)
+			assert.Equal(t, tc.want, got.Object["spec"])
+		})
+	}
+}
+
+func TestCertificateWriterApply(t *testing.T) {
+	proxy := certProxy(map[string]string{
+		CertManagerClusterIssuerAnnotation: "letsencrypt-prod",
+	}, "example-tls")
+
+	want, err := certificateFor(proxy)
+	require.NoError(t, err)
+
+	get := func(cw *CertificateWriter) *unstructured.Unstructured {
+		t.Helper()
+		cert, err := cw.Client.Resource(CertificateGVR).Namespace("default").Get(context.Background(), "example", metav1.GetOptions{})
+		require.NoError(t, err)
+		return cert
+	}
+
+	// A missing Certificate is created.
+	cw := &CertificateWriter{
+		Log:    fixture.NewTestLogger(t),
+		Client: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+	}
+	cw.apply(want)
+	assert.Equal(t, want.Object["spec"], get(cw).Object["spec"])
+
+	// An owned Certificate with a stale spec is updated.
+	stale := want.DeepCopy()
+	stale.Object["spec"].(map[string]interface{})["secretName"] = "old-tls"
+	cw.Client = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), stale)
+	cw.apply(want)
+	assert.Equal(t, want.Object["spec"], get(cw).Object["spec"])
+
+	// A Certificate owned by something else is left alone.
+	foreign := stale.DeepCopy()
+	foreign.SetOwnerReferences(nil)
+	cw.Client = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), foreign)
+	cw.apply(want)
+	assert.Equal(t, foreign.Object["spec"], get(cw).Object["spec"])
+}
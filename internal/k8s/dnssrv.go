@@ -0,0 +1,289 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/projectcontour/contour/internal/annotation"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SRVLookup resolves DNS SRV records and the addresses of
+// their targets. It is satisfied by *net.Resolver.
+type SRVLookup interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// SRVResolver observes ExternalName Services that have the
+// projectcontour.io/dns-srv annotation, and periodically resolves
+// their external names with DNS SRV records. The targets of the
+// records are passed to the Next handler as Endpoints of the
+// Service, as if they had been read from the API server.
+type SRVResolver struct {
+	// Next is the handler that receives the resolved Endpoints.
+	Next cache.ResourceEventHandler
+
+	// Interval is the time between two resolutions of the
+	// external names.
+	Interval time.Duration
+
+	// Resolver resolves the SRV records. If nil,
+	// net.DefaultResolver is used.
+	Resolver SRVLookup
+
+	logrus.FieldLogger
+
+	mu        sync.Mutex
+	services  map[types.NamespacedName]*v1.Service
+	endpoints map[types.NamespacedName]*v1.Endpoints
+	resolve   chan struct{}
+}
+
+// OnAdd starts resolving the Service if it is an ExternalName
+// Service with the projectcontour.io/dns-srv annotation.
+func (r *SRVResolver) OnAdd(obj interface{}) {
+	if svc, ok := obj.(*v1.Service); ok {
+		r.update(svc)
+	}
+}
+
+// OnUpdate starts resolving the Service if it is an ExternalName
+// Service with the projectcontour.io/dns-srv annotation, and stops
+// resolving it otherwise.
+func (r *SRVResolver) OnUpdate(oldObj, newObj interface{}) {
+	if svc, ok := newObj.(*v1.Service); ok {
+		r.update(svc)
+	}
+}
+
+// OnDelete stops resolving the Service.
+func (r *SRVResolver) OnDelete(obj interface{}) {
+	switch obj := obj.(type) {
+	case *v1.Service:
+		r.remove(NamespacedNameOf(obj))
+	case cache.DeletedFinalStateUnknown:
+		r.OnDelete(obj.Obj)
+	}
+}
+
+func (r *SRVResolver) update(svc *v1.Service) {
+	if svc.Spec.Type != v1.ServiceTypeExternalName || !annotation.DNSSRV(svc) {
+		r.remove(NamespacedNameOf(svc))
+		return
+	}
+
+	r.mu.Lock()
+	if r.services == nil {
+		r.services = map[types.NamespacedName]*v1.Service{}
+	}
+	r.services[NamespacedNameOf(svc)] = svc
+	r.mu.Unlock()
+
+	r.trigger()
+}
+
+func (r *SRVResolver) remove(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.services[name]; !ok {
+		return
+	}
+	delete(r.services, name)
+
+	if ep, ok := r.endpoints[name]; ok {
+		delete(r.endpoints, name)
+		r.Next.OnDelete(ep)
+	}
+}
+
+// trigger requests that the Services are resolved without
+// waiting for the next interval.
+func (r *SRVResolver) trigger() {
+	r.mu.Lock()
+	if r.resolve == nil {
+		r.resolve = make(chan struct{}, 1)
+	}
+	resolve := r.resolve
+	r.mu.Unlock()
+
+	select {
+	case resolve <- struct{}{}:
+	default:
+	}
+}
+
+// Start resolves the Services every Interval, and whenever a
+// Service is added or changed, until stop is closed.
+func (r *SRVResolver) Start(stop <-chan struct{}) error {
+	r.mu.Lock()
+	if r.resolve == nil {
+		r.resolve = make(chan struct{}, 1)
+	}
+	resolve := r.resolve
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		case <-resolve:
+		}
+
+		r.ResolveAll()
+	}
+}
+
+// ResolveAll resolves the external names of all the Services,
+// and passes the Endpoints that changed to the Next handler.
+// If the name of a Service cannot be resolved, its previous
+// Endpoints are kept.
+func (r *SRVResolver) ResolveAll() {
+	r.mu.Lock()
+	services := make([]*v1.Service, 0, len(r.services))
+	for _, svc := range r.services {
+		services = append(services, svc)
+	}
+	r.mu.Unlock()
+
+	for _, svc := range services {
+		ep, err := r.resolveService(svc)
+		if err != nil {
+			r.WithError(err).WithField("name", svc.Name).WithField("namespace", svc.Namespace).
+				Errorf("failed to resolve SRV records of %q", svc.Spec.ExternalName)
+			continue
+		}
+
+		r.mu.Lock()
+		name := NamespacedNameOf(svc)
+		if _, ok := r.services[name]; !ok {
+			// The Service was removed while it was resolved.
+			r.mu.Unlock()
+			continue
+		}
+
+		old, ok := r.endpoints[name]
+		switch {
+		case !ok:
+			r.Next.OnAdd(ep)
+		case !reflect.DeepEqual(old.Subsets, ep.Subsets):
+			r.Next.OnUpdate(old, ep)
+		}
+
+		if r.endpoints == nil {
+			r.endpoints = map[types.NamespacedName]*v1.Endpoints{}
+		}
+		r.endpoints[name] = ep
+		r.mu.Unlock()
+	}
+}
+
+// resolveService returns the Endpoints of the Service, holding the
+// addresses of the targets of the SRV records of its external name.
+// Only the records with the lowest priority are used, and their
+// weights are ignored. Each port of the Service uses the port of
+// the SRV record.
+func (r *SRVResolver) resolveService(svc *v1.Service) (*v1.Endpoints, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, records, err := resolver.LookupSRV(ctx, "", "", svc.Spec.ExternalName)
+	if err != nil {
+		return nil, err
+	}
+
+	var priority uint16
+	for i, rec := range records {
+		if i == 0 || rec.Priority < priority {
+			priority = rec.Priority
+		}
+	}
+
+	addresses := map[uint16]map[string]bool{}
+	for _, rec := range records {
+		if rec.Priority != priority {
+			continue
+		}
+
+		addrs, err := resolver.LookupIPAddr(ctx, rec.Target)
+		if err != nil {
+			return nil, err
+		}
+
+		if addresses[rec.Port] == nil {
+			addresses[rec.Port] = map[string]bool{}
+		}
+		for _, addr := range addrs {
+			addresses[rec.Port][addr.IP.String()] = true
+		}
+	}
+
+	var ports []uint16
+	for port := range addresses {
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	ep := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      svc.Name,
+			Namespace: svc.Namespace,
+		},
+	}
+
+	for _, port := range ports {
+		var subset v1.EndpointSubset
+
+		var ips []string
+		for ip := range addresses[port] {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		for _, ip := range ips {
+			subset.Addresses = append(subset.Addresses, v1.EndpointAddress{IP: ip})
+		}
+
+		for _, sp := range svc.Spec.Ports {
+			subset.Ports = append(subset.Ports, v1.EndpointPort{
+				Name:     sp.Name,
+				Port:     int32(port),
+				Protocol: v1.ProtocolTCP,
+			})
+		}
+
+		ep.Subsets = append(ep.Subsets, subset)
+	}
+
+	return ep, nil
+}
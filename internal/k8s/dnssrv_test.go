@@ -0,0 +1,162 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeSRVLookup resolves SRV records and addresses from maps.
+type fakeSRVLookup struct {
+	srv   map[string][]*net.SRV
+	addrs map[string][]net.IPAddr
+}
+
+func (f *fakeSRVLookup) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	records, ok := f.srv[name]
+	if !ok {
+		return "", nil, errors.New("no such host")
+	}
+	return name, records, nil
+}
+
+func (f *fakeSRVLookup) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := f.addrs[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+// endpointsRecorder records the Endpoints passed to it.
+type endpointsRecorder struct {
+	added   []*v1.Endpoints
+	updated []*v1.Endpoints
+	deleted []*v1.Endpoints
+}
+
+func (e *endpointsRecorder) OnAdd(obj interface{}) {
+	e.added = append(e.added, obj.(*v1.Endpoints))
+}
+
+func (e *endpointsRecorder) OnUpdate(_, newObj interface{}) {
+	e.updated = append(e.updated, newObj.(*v1.Endpoints))
+}
+
+func (e *endpointsRecorder) OnDelete(obj interface{}) {
+	e.deleted = append(e.deleted, obj.(*v1.Endpoints))
+}
+
+func TestSRVResolver(t *testing.T) {
+	lookup := &fakeSRVLookup{
+		srv: map[string][]*net.SRV{
+			"_http._tcp.backend.example.com": {
+				{Target: "a.example.com.", Port: 8080, Priority: 10},
+				{Target: "b.example.com.", Port: 9090, Priority: 10},
+				{Target: "backup.example.com.", Port: 8080, Priority: 20},
+			},
+		},
+		addrs: map[string][]net.IPAddr{
+			"a.example.com.": {{IP: net.ParseIP("192.0.2.2")}, {IP: net.ParseIP("192.0.2.1")}},
+			"b.example.com.": {{IP: net.ParseIP("192.0.2.3")}},
+		},
+	}
+
+	recorder := &endpointsRecorder{}
+	r := &SRVResolver{
+		Next:        recorder,
+		Resolver:    lookup,
+		FieldLogger: fixture.NewTestLogger(t),
+	}
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "backend",
+			Namespace:   "default",
+			Annotations: map[string]string{"projectcontour.io/dns-srv": "true"},
+		},
+		Spec: v1.ServiceSpec{
+			Type:         v1.ServiceTypeExternalName,
+			ExternalName: "_http._tcp.backend.example.com",
+			Ports:        []v1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+
+	// Services without the annotation are not resolved.
+	plain := svc.DeepCopy()
+	plain.Name = "plain"
+	plain.Annotations = nil
+	r.OnAdd(plain)
+
+	r.OnAdd(svc)
+	r.ResolveAll()
+
+	// Only the targets with the lowest priority are used,
+	// grouped by the port of their SRV record.
+	want := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backend",
+			Namespace: "default",
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}},
+			Ports:     []v1.EndpointPort{{Name: "http", Port: 8080, Protocol: v1.ProtocolTCP}},
+		}, {
+			Addresses: []v1.EndpointAddress{{IP: "192.0.2.3"}},
+			Ports:     []v1.EndpointPort{{Name: "http", Port: 9090, Protocol: v1.ProtocolTCP}},
+		}},
+	}
+	assert.Equal(t, []*v1.Endpoints{want}, recorder.added)
+
+	// Resolving the same records again does not update the Endpoints.
+	r.ResolveAll()
+	assert.Empty(t, recorder.updated)
+
+	// A failed lookup keeps the previous Endpoints.
+	lookup.srv["_http._tcp.backend.example.com"] = append(lookup.srv["_http._tcp.backend.example.com"],
+		&net.SRV{Target: "missing.example.com.", Port: 8080, Priority: 10})
+	r.ResolveAll()
+	assert.Empty(t, recorder.updated)
+	assert.Empty(t, recorder.deleted)
+
+	// Changed records update the Endpoints.
+	lookup.srv["_http._tcp.backend.example.com"] = []*net.SRV{
+		{Target: "b.example.com.", Port: 9090, Priority: 10},
+	}
+	r.ResolveAll()
+	assert.Equal(t, []*v1.Endpoints{{
+		ObjectMeta: want.ObjectMeta,
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "192.0.2.3"}},
+			Ports:     []v1.EndpointPort{{Name: "http", Port: 9090, Protocol: v1.ProtocolTCP}},
+		}},
+	}}, recorder.updated)
+
+	// Removing the annotation deletes the Endpoints.
+	r.OnUpdate(svc, func() *v1.Service {
+		s := svc.DeepCopy()
+		s.Annotations = nil
+		return s
+	}())
+	assert.Len(t, recorder.deleted, 1)
+	assert.Equal(t, "backend", recorder.deleted[0].Name)
+}
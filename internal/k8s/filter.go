@@ -14,6 +14,8 @@
 package k8s
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -70,3 +72,22 @@ func (e *namespaceFilter) OnDelete(obj interface{}) {
 		e.next.OnDelete(obj)
 	}
 }
+
+// MatchesDomainSuffix returns true if there are no domain suffixes,
+// or if host is equal to, or a subdomain of, one of the suffixes.
+// Contour instances that are configured with different suffixes
+// use it to share out virtual hosts between them.
+func MatchesDomainSuffix(host string, suffixes []string) bool {
+	if len(suffixes) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -68,3 +68,42 @@ func TestNamespaceFilter(t *testing.T) {
 	assert.Equal(t, 1, counter.deleted)
 
 }
+
+func TestMatchesDomainSuffix(t *testing.T) {
+	tests := map[string]struct {
+		host     string
+		suffixes []string
+		want     bool
+	}{
+		"no suffixes": {
+			host: "a.example.com",
+			want: true,
+		},
+		"subdomain": {
+			host:     "a.example.com",
+			suffixes: []string{"example.org", "example.com"},
+			want:     true,
+		},
+		"exact host, ignoring case and dots": {
+			host:     "Example.com",
+			suffixes: []string{".example.COM."},
+			want:     true,
+		},
+		"suffix that is not a domain boundary": {
+			host:     "notexample.com",
+			suffixes: []string{"example.com"},
+			want:     false,
+		},
+		"default host": {
+			host:     "*",
+			suffixes: []string{"example.com"},
+			want:     false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, MatchesDomainSuffix(tc.host, tc.suffixes))
+		})
+	}
+}
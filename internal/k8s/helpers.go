@@ -17,6 +17,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 )
 
@@ -24,11 +26,20 @@ import (
 // have equivalent Status structs.
 //
 // Currently supports:
+// networking.k8s.io/ingress/v1
 // networking.k8s.io/ingress/v1beta1
+// extensions/ingress/v1beta1
 // projectcontour.io/v1
 func IsStatusEqual(objA, objB interface{}) bool {
 
 	switch a := objA.(type) {
+	case *networking_v1.Ingress:
+		switch b := objB.(type) {
+		case *networking_v1.Ingress:
+			if cmp.Equal(a.Status, b.Status) {
+				return true
+			}
+		}
 	case *v1beta1.Ingress:
 		switch b := objB.(type) {
 		case *v1beta1.Ingress:
@@ -36,6 +47,13 @@ func IsStatusEqual(objA, objB interface{}) bool {
 				return true
 			}
 		}
+	case *extensions_v1beta1.Ingress:
+		switch b := objB.(type) {
+		case *extensions_v1beta1.Ingress:
+			if cmp.Equal(a.Status, b.Status) {
+				return true
+			}
+		}
 	case *contour_api_v1.HTTPProxy:
 		switch b := objB.(type) {
 		case *contour_api_v1.HTTPProxy:
@@ -17,14 +17,14 @@ import (
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	contour_api_v1alpha1 "github.com/projectcontour/contour/apis/projectcontour/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	discovery_v1beta1 "k8s.io/api/discovery/v1beta1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	serviceapis "sigs.k8s.io/service-apis/api/v1alpha1"
 )
 
-// +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;list;watch
-// +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses/status,verbs=create;get;update
-
 // +kubebuilder:rbac:groups="projectcontour.io",resources=httpproxies;tlscertificatedelegations,verbs=get;list;watch
 // +kubebuilder:rbac:groups="projectcontour.io",resources=httpproxies/status,verbs=create;get;update
 // +kubebuilder:rbac:groups="projectcontour.io",resources=extensionservices,verbs=get;list;watch
@@ -37,7 +37,22 @@ func DefaultResources() []schema.GroupVersionResource {
 		contour_api_v1.TLSCertificateDelegationGVR,
 		contour_api_v1alpha1.ExtensionServiceGVR,
 		corev1.SchemeGroupVersion.WithResource("services"),
+	}
+}
+
+// +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;list;watch
+// +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses/status,verbs=create;get;update
+// +kubebuilder:rbac:groups="extensions",resources=ingresses,verbs=get;list;watch
+// +kubebuilder:rbac:groups="extensions",resources=ingresses/status,verbs=create;get;update
+
+// IngressResources returns the Ingress resources in order of
+// preference. Only the first resource that the API server
+// serves should be watched.
+func IngressResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		networking_v1.SchemeGroupVersion.WithResource("ingresses"),
 		v1beta1.SchemeGroupVersion.WithResource("ingresses"),
+		extensions_v1beta1.SchemeGroupVersion.WithResource("ingresses"),
 	}
 }
 
@@ -62,6 +77,15 @@ func SecretsResources() []schema.GroupVersionResource {
 	}
 }
 
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// ConfigMapsResources ...
+func ConfigMapsResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		corev1.SchemeGroupVersion.WithResource("configmaps"),
+	}
+}
+
 // +kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
 
 // EndpointsResources ...
@@ -71,6 +95,15 @@ func EndpointsResources() []schema.GroupVersionResource {
 	}
 }
 
+// +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=get;list;watch
+
+// EndpointSliceResources ...
+func EndpointSliceResources() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{
+		discovery_v1beta1.SchemeGroupVersion.WithResource("endpointslices"),
+	}
+}
+
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
 
 // ServicesResources ...
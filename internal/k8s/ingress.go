@@ -0,0 +1,169 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ConvertIngress returns the networking.k8s.io/v1beta1 representation
+// of an Ingress of any supported API version. The networking.k8s.io/v1beta1
+// Ingress is the version that Contour uses internally, since it can
+// represent all the fields of the other versions. ConvertIngress returns
+// nil if obj is not an Ingress.
+func ConvertIngress(obj interface{}) *v1beta1.Ingress {
+	switch ing := obj.(type) {
+	case *v1beta1.Ingress:
+		return ing
+	case *networking_v1.Ingress:
+		return convertNetworkingV1Ingress(ing)
+	case *extensions_v1beta1.Ingress:
+		return convertExtensionsV1beta1Ingress(ing)
+	default:
+		return nil
+	}
+}
+
+func convertNetworkingV1Ingress(ing *networking_v1.Ingress) *v1beta1.Ingress {
+	converted := &v1beta1.Ingress{
+		ObjectMeta: *ing.ObjectMeta.DeepCopy(),
+		Spec: v1beta1.IngressSpec{
+			IngressClassName: ing.Spec.IngressClassName,
+			Backend:          convertNetworkingV1Backend(ing.Spec.DefaultBackend),
+		},
+		Status: v1beta1.IngressStatus{
+			LoadBalancer: *ing.Status.LoadBalancer.DeepCopy(),
+		},
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		converted.Spec.TLS = append(converted.Spec.TLS, v1beta1.IngressTLS{
+			Hosts:      tls.Hosts,
+			SecretName: tls.SecretName,
+		})
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		r := v1beta1.IngressRule{
+			Host: rule.Host,
+		}
+
+		if rule.HTTP != nil {
+			r.HTTP = &v1beta1.HTTPIngressRuleValue{}
+
+			for _, path := range rule.HTTP.Paths {
+				p := v1beta1.HTTPIngressPath{
+					Path:    path.Path,
+					Backend: *convertNetworkingV1Backend(&path.Backend),
+				}
+
+				if path.PathType != nil {
+					pathType := v1beta1.PathType(*path.PathType)
+					p.PathType = &pathType
+				}
+
+				r.HTTP.Paths = append(r.HTTP.Paths, p)
+			}
+		}
+
+		converted.Spec.Rules = append(converted.Spec.Rules, r)
+	}
+
+	return converted
+}
+
+func convertNetworkingV1Backend(backend *networking_v1.IngressBackend) *v1beta1.IngressBackend {
+	if backend == nil {
+		return nil
+	}
+
+	converted := &v1beta1.IngressBackend{
+		Resource: backend.Resource.DeepCopy(),
+	}
+
+	if svc := backend.Service; svc != nil {
+		converted.ServiceName = svc.Name
+
+		if svc.Port.Name != "" {
+			converted.ServicePort = intstr.FromString(svc.Port.Name)
+		} else {
+			converted.ServicePort = intstr.FromInt(int(svc.Port.Number))
+		}
+	}
+
+	return converted
+}
+
+func convertExtensionsV1beta1Ingress(ing *extensions_v1beta1.Ingress) *v1beta1.Ingress {
+	converted := &v1beta1.Ingress{
+		ObjectMeta: *ing.ObjectMeta.DeepCopy(),
+		Spec: v1beta1.IngressSpec{
+			IngressClassName: ing.Spec.IngressClassName,
+			Backend:          convertExtensionsV1beta1Backend(ing.Spec.Backend),
+		},
+		Status: v1beta1.IngressStatus{
+			LoadBalancer: *ing.Status.LoadBalancer.DeepCopy(),
+		},
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		converted.Spec.TLS = append(converted.Spec.TLS, v1beta1.IngressTLS{
+			Hosts:      tls.Hosts,
+			SecretName: tls.SecretName,
+		})
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		r := v1beta1.IngressRule{
+			Host: rule.Host,
+		}
+
+		if rule.HTTP != nil {
+			r.HTTP = &v1beta1.HTTPIngressRuleValue{}
+
+			for _, path := range rule.HTTP.Paths {
+				p := v1beta1.HTTPIngressPath{
+					Path:    path.Path,
+					Backend: *convertExtensionsV1beta1Backend(&path.Backend),
+				}
+
+				if path.PathType != nil {
+					pathType := v1beta1.PathType(*path.PathType)
+					p.PathType = &pathType
+				}
+
+				r.HTTP.Paths = append(r.HTTP.Paths, p)
+			}
+		}
+
+		converted.Spec.Rules = append(converted.Spec.Rules, r)
+	}
+
+	return converted
+}
+
+func convertExtensionsV1beta1Backend(backend *extensions_v1beta1.IngressBackend) *v1beta1.IngressBackend {
+	if backend == nil {
+		return nil
+	}
+
+	return &v1beta1.IngressBackend{
+		ServiceName: backend.ServiceName,
+		ServicePort: backend.ServicePort,
+		Resource:    backend.Resource.DeepCopy(),
+	}
+}
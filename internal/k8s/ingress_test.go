@@ -0,0 +1,192 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestConvertIngress(t *testing.T) {
+	prefix := v1beta1.PathTypePrefix
+	networkingPrefix := networking_v1.PathTypePrefix
+	extensionsExact := extensions_v1beta1.PathTypeExact
+	exact := v1beta1.PathTypeExact
+
+	meta := metav1.ObjectMeta{
+		Name:      "kuard",
+		Namespace: "default",
+		Annotations: map[string]string{
+			"kubernetes.io/ingress.class": "contour",
+		},
+	}
+
+	status := v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{{IP: "127.0.0.1"}},
+	}
+
+	tests := map[string]struct {
+		obj  interface{}
+		want *v1beta1.Ingress
+	}{
+		"not an ingress": {
+			obj:  &v1.Service{},
+			want: nil,
+		},
+		"networking.k8s.io/v1beta1": {
+			obj: &v1beta1.Ingress{
+				ObjectMeta: meta,
+				Spec: v1beta1.IngressSpec{
+					Backend: &v1beta1.IngressBackend{
+						ServiceName: "kuard",
+						ServicePort: intstr.FromInt(8080),
+					},
+				},
+			},
+			want: &v1beta1.Ingress{
+				ObjectMeta: meta,
+				Spec: v1beta1.IngressSpec{
+					Backend: &v1beta1.IngressBackend{
+						ServiceName: "kuard",
+						ServicePort: intstr.FromInt(8080),
+					},
+				},
+			},
+		},
+		"networking.k8s.io/v1": {
+			obj: &networking_v1.Ingress{
+				ObjectMeta: meta,
+				Spec: networking_v1.IngressSpec{
+					IngressClassName: pointer.StringPtr("contour"),
+					DefaultBackend: &networking_v1.IngressBackend{
+						Service: &networking_v1.IngressServiceBackend{
+							Name: "kuard",
+							Port: networking_v1.ServiceBackendPort{Number: 8080},
+						},
+					},
+					TLS: []networking_v1.IngressTLS{{
+						Hosts:      []string{"kuard.example.com"},
+						SecretName: "kuard-tls",
+					}},
+					Rules: []networking_v1.IngressRule{{
+						Host: "kuard.example.com",
+						IngressRuleValue: networking_v1.IngressRuleValue{
+							HTTP: &networking_v1.HTTPIngressRuleValue{
+								Paths: []networking_v1.HTTPIngressPath{{
+									Path:     "/api",
+									PathType: &networkingPrefix,
+									Backend: networking_v1.IngressBackend{
+										Service: &networking_v1.IngressServiceBackend{
+											Name: "api",
+											Port: networking_v1.ServiceBackendPort{Name: "http"},
+										},
+									},
+								}},
+							},
+						},
+					}},
+				},
+				Status: networking_v1.IngressStatus{LoadBalancer: status},
+			},
+			want: &v1beta1.Ingress{
+				ObjectMeta: meta,
+				Spec: v1beta1.IngressSpec{
+					IngressClassName: pointer.StringPtr("contour"),
+					Backend: &v1beta1.IngressBackend{
+						ServiceName: "kuard",
+						ServicePort: intstr.FromInt(8080),
+					},
+					TLS: []v1beta1.IngressTLS{{
+						Hosts:      []string{"kuard.example.com"},
+						SecretName: "kuard-tls",
+					}},
+					Rules: []v1beta1.IngressRule{{
+						Host: "kuard.example.com",
+						IngressRuleValue: v1beta1.IngressRuleValue{
+							HTTP: &v1beta1.HTTPIngressRuleValue{
+								Paths: []v1beta1.HTTPIngressPath{{
+									Path:     "/api",
+									PathType: &prefix,
+									Backend: v1beta1.IngressBackend{
+										ServiceName: "api",
+										ServicePort: intstr.FromString("http"),
+									},
+								}},
+							},
+						},
+					}},
+				},
+				Status: v1beta1.IngressStatus{LoadBalancer: status},
+			},
+		},
+		"extensions/v1beta1": {
+			obj: &extensions_v1beta1.Ingress{
+				ObjectMeta: meta,
+				Spec: extensions_v1beta1.IngressSpec{
+					Rules: []extensions_v1beta1.IngressRule{{
+						Host: "kuard.example.com",
+						IngressRuleValue: extensions_v1beta1.IngressRuleValue{
+							HTTP: &extensions_v1beta1.HTTPIngressRuleValue{
+								Paths: []extensions_v1beta1.HTTPIngressPath{{
+									Path:     "/",
+									PathType: &extensionsExact,
+									Backend: extensions_v1beta1.IngressBackend{
+										ServiceName: "kuard",
+										ServicePort: intstr.FromInt(80),
+									},
+								}},
+							},
+						},
+					}},
+				},
+				Status: extensions_v1beta1.IngressStatus{LoadBalancer: status},
+			},
+			want: &v1beta1.Ingress{
+				ObjectMeta: meta,
+				Spec: v1beta1.IngressSpec{
+					Rules: []v1beta1.IngressRule{{
+						Host: "kuard.example.com",
+						IngressRuleValue: v1beta1.IngressRuleValue{
+							HTTP: &v1beta1.HTTPIngressRuleValue{
+								Paths: []v1beta1.HTTPIngressPath{{
+									Path:     "/",
+									PathType: &exact,
+									Backend: v1beta1.IngressBackend{
+										ServiceName: "kuard",
+										ServicePort: intstr.FromInt(80),
+									},
+								}},
+							},
+						},
+					}},
+				},
+				Status: v1beta1.IngressStatus{LoadBalancer: status},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ConvertIngress(tc.obj))
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/projectcontour/contour/internal/annotation"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	extensions_v1beta1 "k8s.io/api/extensions/v1beta1"
+	networking_v1 "k8s.io/api/networking/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -37,6 +39,11 @@ type StatusAddressUpdater struct {
 	StatusUpdater StatusUpdater
 	Converter     Converter
 
+	// DomainSuffixes, if not empty, restricts the updates to
+	// the objects with at least one host that is equal to, or
+	// a subdomain of, one of the suffixes.
+	DomainSuffixes []string
+
 	// mu guards the LBStatus field, which can be updated dynamically.
 	mu sync.Mutex
 }
@@ -73,6 +80,7 @@ func (s *StatusAddressUpdater) OnAdd(obj interface{}) {
 	var typed Object
 	var gvr schema.GroupVersionResource
 	var kind string
+	var hosts []string
 
 	switch o := obj.(type) {
 	case *v1beta1.Ingress:
@@ -80,11 +88,33 @@ func (s *StatusAddressUpdater) OnAdd(obj interface{}) {
 		typed = o.DeepCopy()
 		gvr = v1beta1.SchemeGroupVersion.WithResource("ingresses")
 		kind = "ingress"
+		for _, rule := range o.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+	case *networking_v1.Ingress:
+		o.GetObjectKind().SetGroupVersionKind(networking_v1.SchemeGroupVersion.WithKind("ingress"))
+		typed = o.DeepCopy()
+		gvr = networking_v1.SchemeGroupVersion.WithResource("ingresses")
+		kind = "ingress"
+		for _, rule := range o.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
+	case *extensions_v1beta1.Ingress:
+		o.GetObjectKind().SetGroupVersionKind(extensions_v1beta1.SchemeGroupVersion.WithKind("ingress"))
+		typed = o.DeepCopy()
+		gvr = extensions_v1beta1.SchemeGroupVersion.WithResource("ingresses")
+		kind = "ingress"
+		for _, rule := range o.Spec.Rules {
+			hosts = append(hosts, rule.Host)
+		}
 	case *contour_api_v1.HTTPProxy:
 		o.GetObjectKind().SetGroupVersionKind(contour_api_v1.SchemeGroupVersion.WithKind("httpproxy"))
 		typed = o.DeepCopy()
 		gvr = contour_api_v1.SchemeGroupVersion.WithResource("httpproxies")
 		kind = "httpproxy"
+		if o.Spec.VirtualHost != nil {
+			hosts = append(hosts, o.Spec.VirtualHost.Fqdn)
+		}
 	default:
 		s.Logger.
 			Debug("unsupported type received")
@@ -102,6 +132,15 @@ func (s *StatusAddressUpdater) OnAdd(obj interface{}) {
 		return
 	}
 
+	if !s.ownsAnyHost(hosts) {
+		s.Logger.
+			WithField("name", typed.GetObjectMeta().GetName()).
+			WithField("namespace", typed.GetObjectMeta().GetNamespace()).
+			WithField("kind", kind).
+			Debug("unmatched domain suffix, skipping status address update")
+		return
+	}
+
 	s.Logger.
 		WithField("name", typed.GetObjectMeta().GetName()).
 		WithField("namespace", typed.GetObjectMeta().GetNamespace()).
@@ -120,6 +159,14 @@ func (s *StatusAddressUpdater) OnAdd(obj interface{}) {
 				dco := o.DeepCopy()
 				dco.Status.LoadBalancer = loadBalancerStatus
 				return dco
+			case *networking_v1.Ingress:
+				dco := o.DeepCopy()
+				dco.Status.LoadBalancer = loadBalancerStatus
+				return dco
+			case *extensions_v1beta1.Ingress:
+				dco := o.DeepCopy()
+				dco.Status.LoadBalancer = loadBalancerStatus
+				return dco
 			case *contour_api_v1.HTTPProxy:
 				dco := o.DeepCopy()
 				dco.Status.LoadBalancer = loadBalancerStatus
@@ -133,6 +180,21 @@ func (s *StatusAddressUpdater) OnAdd(obj interface{}) {
 	))
 }
 
+// ownsAnyHost returns true if there are no domain suffixes, or if
+// any of hosts matches one of them.
+func (s *StatusAddressUpdater) ownsAnyHost(hosts []string) bool {
+	if len(s.DomainSuffixes) == 0 {
+		return true
+	}
+
+	for _, host := range hosts {
+		if MatchesDomainSuffix(host, s.DomainSuffixes) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *StatusAddressUpdater) OnUpdate(oldObj, newObj interface{}) {
 
 	// We only care about the new object, because we're only updating its status.
@@ -169,7 +231,7 @@ func (s *ServiceStatusLoadBalancerWatcher) OnAdd(obj interface{}) {
 		WithField("namespace", svc.Namespace).
 		Debug("received new service address")
 
-	s.notify(svc.Status.LoadBalancer)
+	s.notify(serviceLoadBalancerStatus(svc))
 }
 
 func (s *ServiceStatusLoadBalancerWatcher) OnUpdate(oldObj, newObj interface{}) {
@@ -185,7 +247,7 @@ func (s *ServiceStatusLoadBalancerWatcher) OnUpdate(oldObj, newObj interface{})
 		WithField("namespace", svc.Namespace).
 		Debug("received new service address")
 
-	s.notify(svc.Status.LoadBalancer)
+	s.notify(serviceLoadBalancerStatus(svc))
 }
 
 func (s *ServiceStatusLoadBalancerWatcher) OnDelete(obj interface{}) {
@@ -202,6 +264,22 @@ func (s *ServiceStatusLoadBalancerWatcher) OnDelete(obj interface{}) {
 	})
 }
 
+// serviceLoadBalancerStatus returns the external addresses of svc. These
+// are the load balancer addresses, if any have been assigned. Otherwise,
+// the Service's external IPs are used, so that Services which are exposed
+// without a cloud load balancer still publish an address.
+func serviceLoadBalancerStatus(svc *v1.Service) v1.LoadBalancerStatus {
+	if len(svc.Status.LoadBalancer.Ingress) > 0 || len(svc.Spec.ExternalIPs) == 0 {
+		return svc.Status.LoadBalancer
+	}
+
+	var lbs v1.LoadBalancerStatus
+	for _, ip := range svc.Spec.ExternalIPs {
+		lbs.Ingress = append(lbs.Ingress, v1.LoadBalancerIngress{IP: ip})
+	}
+	return lbs
+}
+
 func (s *ServiceStatusLoadBalancerWatcher) notify(lbstatus v1.LoadBalancerStatus) {
 	s.LBStatus <- lbstatus
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/projectcontour/contour/internal/fixture"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -72,6 +73,20 @@ func TestServiceStatusLoadBalancerWatcherOnAdd(t *testing.T) {
 		Ingress: []v1.LoadBalancerIngress{{Hostname: "projectcontour.io"}},
 	}
 	assert.Equal(t, got, want)
+
+	// assert that external IPs are used when the service has
+	// no load balancer address.
+	svc.Status.LoadBalancer.Ingress = nil
+	svc.Spec.ExternalIPs = []string{"192.0.2.1", "192.0.2.2"}
+	sw.OnAdd(&svc)
+	got, ok = recv()
+	if !ok {
+		t.Fatalf("expected result when adding a service with external IPs")
+	}
+	want = v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}},
+	}
+	assert.Equal(t, got, want)
 }
 
 func TestServiceStatusLoadBalancerWatcherOnUpdate(t *testing.T) {
@@ -317,6 +332,99 @@ func TestStatusAddressUpdater_OnAdd(t *testing.T) {
 
 }
 
+func TestStatusAddressUpdaterDomainSuffixes(t *testing.T) {
+	ingressGVR := v1beta1.SchemeGroupVersion.WithResource("ingresses")
+	proxyGVR := contour_api_v1.SchemeGroupVersion.WithResource("httpproxies")
+
+	converter, err := NewUnstructuredConverter()
+	require.NoError(t, err)
+
+	IPLBStatus := v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{{IP: "127.0.0.1"}},
+	}
+
+	ingress := func(name string, hosts ...string) *v1beta1.Ingress {
+		ing := simpleIngressGenerator(name, "", v1.LoadBalancerStatus{})
+		for _, host := range hosts {
+			ing.Spec.Rules = append(ing.Spec.Rules, v1beta1.IngressRule{Host: host})
+		}
+		return ing
+	}
+
+	proxy := func(name, fqdn string) *contour_api_v1.HTTPProxy {
+		p := simpleProxyGenerator(name, "", v1.LoadBalancerStatus{})
+		if fqdn != "" {
+			p.Spec.VirtualHost = &contour_api_v1.VirtualHost{Fqdn: fqdn}
+		}
+		return p
+	}
+
+	tests := map[string]struct {
+		gvr    schema.GroupVersionResource
+		obj    interface{}
+		update bool
+	}{
+		"ingress with an owned host": {
+			gvr:    ingressGVR,
+			obj:    ingress("owned", "b.example.org", "a.example.com"),
+			update: true,
+		},
+		"ingress without an owned host": {
+			gvr: ingressGVR,
+			obj: ingress("foreign", "b.example.org"),
+		},
+		"ingress without hosts": {
+			gvr: ingressGVR,
+			obj: ingress("nohosts"),
+		},
+		"root proxy with an owned fqdn": {
+			gvr:    proxyGVR,
+			obj:    proxy("owned", "a.example.com"),
+			update: true,
+		},
+		"root proxy with another fqdn": {
+			gvr: proxyGVR,
+			obj: proxy("foreign", "b.example.org"),
+		},
+		"included proxy": {
+			gvr: proxyGVR,
+			obj: proxy("included", ""),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			objname := tc.obj.(metav1.Object).GetName()
+
+			suc := StatusUpdateCacher{}
+			require.True(t, suc.Add(objname, objname, tc.gvr, tc.obj))
+
+			isu := StatusAddressUpdater{
+				Logger:         fixture.NewTestLogger(t),
+				LBStatus:       IPLBStatus,
+				StatusUpdater:  &suc,
+				Converter:      converter,
+				DomainSuffixes: []string{"example.com"},
+			}
+			isu.OnAdd(tc.obj)
+
+			var got v1.LoadBalancerStatus
+			switch o := suc.Get(objname, objname, tc.gvr).(type) {
+			case *v1beta1.Ingress:
+				got = o.Status.LoadBalancer
+			case *contour_api_v1.HTTPProxy:
+				got = o.Status.LoadBalancer
+			}
+
+			if tc.update {
+				assert.Equal(t, IPLBStatus, got)
+			} else {
+				assert.Empty(t, got.Ingress)
+			}
+		})
+	}
+}
+
 func TestStatusAddressUpdater_OnUpdate(t *testing.T) {
 
 	ingressGVR := v1beta1.SchemeGroupVersion.WithResource("ingresses")
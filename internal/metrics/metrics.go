@@ -16,6 +16,7 @@ package metrics
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/projectcontour/contour/internal/build"
@@ -33,12 +34,33 @@ type Metrics struct {
 	proxyValidGauge     *prometheus.GaugeVec
 	proxyOrphanedGauge  *prometheus.GaugeVec
 
+	rejectedObjectsGauge *prometheus.GaugeVec
+
+	virtualHostRoutesGauge *prometheus.GaugeVec
+	clusterEndpointsGauge  *prometheus.GaugeVec
+	listenerSecretsGauge   *prometheus.GaugeVec
+
 	dagRebuildGauge             *prometheus.GaugeVec
 	CacheHandlerOnUpdateSummary prometheus.Summary
 	EventHandlerOperations      *prometheus.CounterVec
 
+	xdsStreamsGauge           *prometheus.GaugeVec
+	xdsConnectedEnvoysGauge   *prometheus.GaugeVec
+	xdsStreamConnectTimeGauge *prometheus.GaugeVec
+	xdsNACKCounter            *prometheus.CounterVec
+
 	// Keep a local cache of metrics for comparison on updates
 	proxyMetricCache *RouteMetric
+	rejectedCache    map[RejectedMeta]int
+	routesCache      map[VirtualHostMeta]int
+	secretsCache     map[string]int
+	xdsNodeCache     map[string]time.Time
+
+	// endpointsMu protects endpointsCache, since the endpoints
+	// metric is set both when the DAG is rebuilt and when the
+	// endpoints change.
+	endpointsMu    sync.Mutex
+	endpointsCache map[string]int
 }
 
 // RouteMetric stores various metrics for HTTPProxy objects
@@ -55,6 +77,18 @@ type Meta struct {
 	VHost, Namespace string
 }
 
+// RejectedMeta holds the kind, namespace and rejection
+// reason of a rejected object metric.
+type RejectedMeta struct {
+	Kind, Namespace, Reason string
+}
+
+// VirtualHostMeta holds the listener and
+// name of a virtual host metric object.
+type VirtualHostMeta struct {
+	Listener, VHost string
+}
+
 const (
 	BuildInfoGauge = "contour_build_info"
 
@@ -64,9 +98,20 @@ const (
 	HTTPProxyValidGauge     = "contour_httpproxy_valid_total"
 	HTTPProxyOrphanedGauge  = "contour_httpproxy_orphaned_total"
 
+	RejectedObjectsGauge = "contour_rejected_objects_total"
+
+	VirtualHostRoutesGauge = "contour_virtualhost_routes"
+	ClusterEndpointsGauge  = "contour_cluster_endpoints"
+	ListenerSecretsGauge   = "contour_listener_secrets"
+
 	DAGRebuildGauge             = "contour_dagrebuild_timestamp"
 	cacheHandlerOnUpdateSummary = "contour_cachehandler_onupdate_duration_seconds"
 	eventHandlerOperations      = "contour_eventhandler_operation_total"
+
+	XDSStreamsGauge           = "contour_xds_streams"
+	XDSConnectedEnvoysGauge   = "contour_xds_connected_envoys"
+	XDSStreamConnectTimeGauge = "contour_xds_stream_connect_timestamp"
+	XDSNACKCounter            = "contour_xds_nack_total"
 )
 
 // NewMetrics creates a new set of metrics and registers them with
@@ -120,6 +165,34 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 			},
 			[]string{"namespace"},
 		),
+		rejectedObjectsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: RejectedObjectsGauge,
+				Help: "Total number of objects skipped in whole or in part during the last DAG rebuild, by kind and reason.",
+			},
+			[]string{"kind", "namespace", "reason"},
+		),
+		virtualHostRoutesGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: VirtualHostRoutesGauge,
+				Help: "Number of routes of each virtual host in the last DAG rebuild, by listener.",
+			},
+			[]string{"listener", "vhost"},
+		),
+		clusterEndpointsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: ClusterEndpointsGauge,
+				Help: "Number of endpoints served over EDS for each cluster.",
+			},
+			[]string{"cluster"},
+		),
+		listenerSecretsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: ListenerSecretsGauge,
+				Help: "Number of distinct Secrets that each listener serves certificates from, in the last DAG rebuild.",
+			},
+			[]string{"listener"},
+		),
 		dagRebuildGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: DAGRebuildGauge,
@@ -139,6 +212,34 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 			},
 			[]string{"op", "kind"},
 		),
+		xdsStreamsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSStreamsGauge,
+				Help: "Total number of open xDS streams.",
+			},
+			[]string{},
+		),
+		xdsConnectedEnvoysGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSConnectedEnvoysGauge,
+				Help: "Total number of distinct Envoy nodes with open xDS streams.",
+			},
+			[]string{},
+		),
+		xdsStreamConnectTimeGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: XDSStreamConnectTimeGauge,
+				Help: "Timestamp at which the oldest open xDS stream of an Envoy node connected.",
+			},
+			[]string{"node_id"},
+		),
+		xdsNACKCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: XDSNACKCounter,
+				Help: "Total number of xDS responses that Envoy rejected, by resource type.",
+			},
+			[]string{"type_url"},
+		),
 	}
 	m.buildInfoGauge.WithLabelValues(build.Branch, build.Sha, build.Version).Set(1)
 	m.register(registry)
@@ -154,9 +255,17 @@ func (m *Metrics) register(registry *prometheus.Registry) {
 		m.proxyInvalidGauge,
 		m.proxyValidGauge,
 		m.proxyOrphanedGauge,
+		m.rejectedObjectsGauge,
+		m.virtualHostRoutesGauge,
+		m.clusterEndpointsGauge,
+		m.listenerSecretsGauge,
 		m.dagRebuildGauge,
 		m.CacheHandlerOnUpdateSummary,
 		m.EventHandlerOperations,
+		m.xdsStreamsGauge,
+		m.xdsConnectedEnvoysGauge,
+		m.xdsStreamConnectTimeGauge,
+		m.xdsNACKCounter,
 	)
 }
 
@@ -179,6 +288,12 @@ func (m *Metrics) Zero() {
 
 	m.SetDAGLastRebuilt(time.Now())
 	m.SetHTTPProxyMetric(zeroes)
+	m.SetRejectedObjectsMetric(map[RejectedMeta]int{{}: 0})
+	m.SetVirtualHostRoutesMetric(map[VirtualHostMeta]int{{}: 0})
+	m.SetClusterEndpointsMetric(map[string]int{"": 0})
+	m.SetListenerSecretsMetric(map[string]int{"": 0})
+	m.SetXDSStreamMetric(0, map[string]time.Time{"": time.Now()})
+	m.xdsNACKCounter.WithLabelValues("")
 
 	m.EventHandlerOperations.WithLabelValues("add", "Secret").Inc()
 
@@ -240,6 +355,98 @@ func (m *Metrics) SetHTTPProxyMetric(metrics RouteMetric) {
 	}
 }
 
+// SetRejectedObjectsMetric sets the number of rejected objects
+// for each kind, namespace and reason.
+func (m *Metrics) SetRejectedObjectsMetric(rejected map[RejectedMeta]int) {
+	for meta, value := range rejected {
+		m.rejectedObjectsGauge.WithLabelValues(meta.Kind, meta.Namespace, meta.Reason).Set(float64(value))
+		delete(m.rejectedCache, meta)
+	}
+
+	// Objects that are no longer rejected are removed.
+	for meta := range m.rejectedCache {
+		m.rejectedObjectsGauge.DeleteLabelValues(meta.Kind, meta.Namespace, meta.Reason)
+	}
+
+	m.rejectedCache = rejected
+}
+
+// SetVirtualHostRoutesMetric sets the number of
+// routes of each virtual host on each listener.
+func (m *Metrics) SetVirtualHostRoutesMetric(routes map[VirtualHostMeta]int) {
+	for meta, value := range routes {
+		m.virtualHostRoutesGauge.WithLabelValues(meta.Listener, meta.VHost).Set(float64(value))
+		delete(m.routesCache, meta)
+	}
+
+	// Virtual hosts that no longer exist are removed.
+	for meta := range m.routesCache {
+		m.virtualHostRoutesGauge.DeleteLabelValues(meta.Listener, meta.VHost)
+	}
+
+	m.routesCache = routes
+}
+
+// SetClusterEndpointsMetric sets the number
+// of endpoints of each cluster.
+func (m *Metrics) SetClusterEndpointsMetric(endpoints map[string]int) {
+	m.endpointsMu.Lock()
+	defer m.endpointsMu.Unlock()
+
+	for cluster, value := range endpoints {
+		m.clusterEndpointsGauge.WithLabelValues(cluster).Set(float64(value))
+		delete(m.endpointsCache, cluster)
+	}
+
+	// Clusters that no longer exist are removed.
+	for cluster := range m.endpointsCache {
+		m.clusterEndpointsGauge.DeleteLabelValues(cluster)
+	}
+
+	m.endpointsCache = endpoints
+}
+
+// SetListenerSecretsMetric sets the number of
+// Secrets that each listener serves.
+func (m *Metrics) SetListenerSecretsMetric(secrets map[string]int) {
+	for listener, value := range secrets {
+		m.listenerSecretsGauge.WithLabelValues(listener).Set(float64(value))
+		delete(m.secretsCache, listener)
+	}
+
+	// Listeners that no longer exist are removed.
+	for listener := range m.secretsCache {
+		m.listenerSecretsGauge.DeleteLabelValues(listener)
+	}
+
+	m.secretsCache = secrets
+}
+
+// SetXDSStreamMetric sets the number of open xDS streams, and the
+// connect time of the oldest open stream of each Envoy node.
+func (m *Metrics) SetXDSStreamMetric(streams int, connected map[string]time.Time) {
+	m.xdsStreamsGauge.WithLabelValues().Set(float64(streams))
+	m.xdsConnectedEnvoysGauge.WithLabelValues().Set(float64(len(connected)))
+
+	for node, ts := range connected {
+		m.xdsStreamConnectTimeGauge.WithLabelValues(node).Set(float64(ts.Unix()))
+		delete(m.xdsNodeCache, node)
+	}
+
+	// Nodes that are no longer connected are removed.
+	for node := range m.xdsNodeCache {
+		m.xdsStreamConnectTimeGauge.DeleteLabelValues(node)
+	}
+
+	m.xdsNodeCache = connected
+}
+
+// IncXDSNACK counts a response of the given
+// resource type that Envoy rejected.
+func (m *Metrics) IncXDSNACK(typeURL string) {
+	m.xdsNACKCounter.WithLabelValues(typeURL).Inc()
+}
+
 // Handler returns a http Handler for a metrics endpoint.
 func Handler(registry *prometheus.Registry) http.Handler {
 	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
@@ -690,3 +690,170 @@ func TestRemoveProxyMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveRejectedObjectsMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	m.SetRejectedObjectsMetric(map[RejectedMeta]int{
+		{Kind: "Ingress", Namespace: "testns", Reason: "SecretNotValid"}:             2,
+		{Kind: "Ingress", Namespace: "testns", Reason: "ServiceUnresolvedReference"}: 1,
+	})
+
+	// The missing secret is fixed.
+	m.SetRejectedObjectsMetric(map[RejectedMeta]int{
+		{Kind: "Ingress", Namespace: "testns", Reason: "ServiceUnresolvedReference"}: 1,
+	})
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []*io_prometheus_client.Metric{}
+	for _, mf := range gathering {
+		if mf.GetName() == RejectedObjectsGauge {
+			got = mf.Metric
+		}
+	}
+
+	want := []*io_prometheus_client.Metric{{
+		Label: []*io_prometheus_client.LabelPair{{
+			Name:  func() *string { i := "kind"; return &i }(),
+			Value: func() *string { i := "Ingress"; return &i }(),
+		}, {
+			Name:  func() *string { i := "namespace"; return &i }(),
+			Value: func() *string { i := "testns"; return &i }(),
+		}, {
+			Name:  func() *string { i := "reason"; return &i }(),
+			Value: func() *string { i := "ServiceUnresolvedReference"; return &i }(),
+		}},
+		Gauge: &io_prometheus_client.Gauge{
+			Value: func() *float64 { i := float64(1); return &i }(),
+		},
+	}}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRemoveClusterEndpointsMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	m.SetClusterEndpointsMetric(map[string]int{
+		"default/kuard/80": 3,
+		"default/httpbin":  2,
+	})
+
+	// The kuard cluster scales to zero, and httpbin is removed.
+	m.SetClusterEndpointsMetric(map[string]int{
+		"default/kuard/80": 0,
+	})
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []*io_prometheus_client.Metric{}
+	for _, mf := range gathering {
+		if mf.GetName() == ClusterEndpointsGauge {
+			got = mf.Metric
+		}
+	}
+
+	want := []*io_prometheus_client.Metric{{
+		Label: []*io_prometheus_client.LabelPair{{
+			Name:  func() *string { i := "cluster"; return &i }(),
+			Value: func() *string { i := "default/kuard/80"; return &i }(),
+		}},
+		Gauge: &io_prometheus_client.Gauge{
+			Value: func() *float64 { i := float64(0); return &i }(),
+		},
+	}}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRemoveXDSStreamMetric(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	connected := time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+	m.SetXDSStreamMetric(3, map[string]time.Time{
+		"envoy-1": connected,
+		"envoy-2": connected,
+	})
+
+	// envoy-1 disconnects.
+	m.SetXDSStreamMetric(1, map[string]time.Time{
+		"envoy-2": connected,
+	})
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string][]*io_prometheus_client.Metric{}
+	for _, mf := range gathering {
+		switch mf.GetName() {
+		case XDSStreamsGauge, XDSConnectedEnvoysGauge, XDSStreamConnectTimeGauge:
+			got[mf.GetName()] = mf.Metric
+		}
+	}
+
+	gauge := func(v float64) *io_prometheus_client.Gauge {
+		return &io_prometheus_client.Gauge{Value: &v}
+	}
+
+	want := map[string][]*io_prometheus_client.Metric{
+		XDSStreamsGauge: {{
+			Gauge: gauge(1),
+		}},
+		XDSConnectedEnvoysGauge: {{
+			Gauge: gauge(1),
+		}},
+		XDSStreamConnectTimeGauge: {{
+			Label: []*io_prometheus_client.LabelPair{{
+				Name:  func() *string { i := "node_id"; return &i }(),
+				Value: func() *string { i := "envoy-2"; return &i }(),
+			}},
+			Gauge: gauge(float64(connected.Unix())),
+		}},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestIncXDSNACK(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m := NewMetrics(r)
+
+	m.IncXDSNACK("type.googleapis.com/envoy.config.route.v3.RouteConfiguration")
+	m.IncXDSNACK("type.googleapis.com/envoy.config.route.v3.RouteConfiguration")
+
+	gathering, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []*io_prometheus_client.Metric{}
+	for _, mf := range gathering {
+		if mf.GetName() == XDSNACKCounter {
+			got = mf.Metric
+		}
+	}
+
+	want := []*io_prometheus_client.Metric{{
+		Label: []*io_prometheus_client.LabelPair{{
+			Name:  func() *string { i := "type_url"; return &i }(),
+			Value: func() *string { i := "type.googleapis.com/envoy.config.route.v3.RouteConfiguration"; return &i }(),
+		}},
+		Counter: &io_prometheus_client.Counter{
+			Value: func() *float64 { i := float64(2); return &i }(),
+		},
+	}}
+
+	assert.Equal(t, want, got)
+}
@@ -21,10 +21,11 @@ import (
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
-	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // Duration converts a time.Duration to a pointer to a duration.Duration.
@@ -62,6 +63,16 @@ func UInt32OrNil(val uint32) *wrappers.UInt32Value {
 	}
 }
 
+// UInt64OrNil returns a wrapped UInt64Value. If val is 0, nil is returned
+func UInt64OrNil(val uint64) *wrappers.UInt64Value {
+	switch val {
+	case 0:
+		return nil
+	default:
+		return &wrappers.UInt64Value{Value: val}
+	}
+}
+
 // Bool converts a bool to a pointer to a wrappers.BoolValue.
 func Bool(val bool) *wrappers.BoolValue {
 	return &wrappers.BoolValue{
@@ -88,10 +99,11 @@ func AsMessages(messages interface{}) []proto.Message {
 }
 
 // MustMarshalAny marshals a protobug into an any.Any type, panicking
-// if that operation fails.
+// if that operation fails. Map fields are marshaled in key order so
+// that the same message always produces the same bytes.
 func MustMarshalAny(pb proto.Message) *any.Any {
-	a, err := ptypes.MarshalAny(pb)
-	if err != nil {
+	a := &any.Any{}
+	if err := anypb.MarshalFrom(a, proto.MessageV2(pb), protov2.MarshalOptions{Deterministic: true}); err != nil {
 		panic(err.Error())
 	}
 
@@ -33,6 +33,13 @@ func (s routeConfigurationSorter) Len() int           { return len(s) }
 func (s routeConfigurationSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s routeConfigurationSorter) Less(i, j int) bool { return s[i].Name < s[j].Name }
 
+// Sorts the given scoped route configuration values by name.
+type scopedRouteConfigurationSorter []*envoy_route_v3.ScopedRouteConfiguration
+
+func (s scopedRouteConfigurationSorter) Len() int           { return len(s) }
+func (s scopedRouteConfigurationSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s scopedRouteConfigurationSorter) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
 // Sorts the given host values by name.
 type virtualHostSorter []*envoy_route_v3.VirtualHost
 
@@ -79,20 +86,68 @@ func longestRouteByHeaders(lhs, rhs *envoy_route_v3.Route) bool {
 	return len(lhs.Match.Headers) > len(rhs.Match.Headers)
 }
 
+// longestRouteByConditions compares the header, query parameter and
+// TLS conditions for lhs and rhs and returns true if lhs is more
+// specific. Header conditions are compared first, then the length of
+// the QueryParameterMatcher slices, then whether there is a TLS
+// context match.
+func longestRouteByConditions(lhs, rhs *envoy_route_v3.Route) bool {
+	switch {
+	case longestRouteByHeaders(lhs, rhs):
+		return true
+	case longestRouteByHeaders(rhs, lhs):
+		return false
+	case len(lhs.Match.QueryParameters) != len(rhs.Match.QueryParameters):
+		return len(lhs.Match.QueryParameters) > len(rhs.Match.QueryParameters)
+	default:
+		return lhs.Match.TlsContext != nil && rhs.Match.TlsContext == nil
+	}
+}
+
+// compareLongestFirst compares a and b like strings.Compare, except
+// that a longer string is always greater than a shorter one.
+func compareLongestFirst(a, b string) int {
+	switch {
+	case len(a) > len(b):
+		return 1
+	case len(a) < len(b):
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
 // Sorts the given Route slice in place. Routes are ordered first by
-// longest prefix (or regex), then by the length of the HeaderMatch
-// slice (if any). The HeaderMatch slice is also ordered by the matching
-// header name.
+// exact path, then by regex, then by longest prefix, then by the length
+// of the HeaderMatch slice (if any), then by the length of the
+// QueryParameterMatcher slice (if any). The HeaderMatch slice is also
+// ordered by the matching header name.
 type routeSorter []*envoy_route_v3.Route
 
 func (s routeSorter) Len() int      { return len(s) }
 func (s routeSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s routeSorter) Less(i, j int) bool {
 	switch a := s[i].Match.PathSpecifier.(type) {
+	case *envoy_route_v3.RouteMatch_Path:
+		switch b := s[j].Match.PathSpecifier.(type) {
+		case *envoy_route_v3.RouteMatch_Path:
+			cmp := strings.Compare(a.Path, b.Path)
+			switch cmp {
+			case 1:
+				return true
+			case -1:
+				return false
+			default:
+				return longestRouteByConditions(s[i], s[j])
+			}
+		default:
+			// Exact paths are more specific than prefixes or regexes.
+			return true
+		}
 	case *envoy_route_v3.RouteMatch_Prefix:
 		switch b := s[j].Match.PathSpecifier.(type) {
 		case *envoy_route_v3.RouteMatch_Prefix:
-			cmp := strings.Compare(a.Prefix, b.Prefix)
+			cmp := compareLongestFirst(a.Prefix, b.Prefix)
 			switch cmp {
 			case 1:
 				// Sort longest prefix first.
@@ -100,7 +155,7 @@ func (s routeSorter) Less(i, j int) bool {
 			case -1:
 				return false
 			default:
-				return longestRouteByHeaders(s[i], s[j])
+				return longestRouteByConditions(s[i], s[j])
 			}
 		}
 	case *envoy_route_v3.RouteMatch_SafeRegex:
@@ -114,7 +169,7 @@ func (s routeSorter) Less(i, j int) bool {
 			case -1:
 				return false
 			default:
-				return longestRouteByHeaders(s[i], s[j])
+				return longestRouteByConditions(s[i], s[j])
 			}
 		case *envoy_route_v3.RouteMatch_Prefix:
 			return true
@@ -210,6 +265,8 @@ func For(v interface{}) sort.Interface {
 		return secretSorter(v)
 	case []*envoy_route_v3.RouteConfiguration:
 		return routeConfigurationSorter(v)
+	case []*envoy_route_v3.ScopedRouteConfiguration:
+		return scopedRouteConfigurationSorter(v)
 	case []*envoy_route_v3.VirtualHost:
 		return virtualHostSorter(v)
 	case []*envoy_route_v3.Route:
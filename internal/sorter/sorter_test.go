@@ -66,6 +66,23 @@ func TestSortRouteConfiguration(t *testing.T) {
 	assert.Equal(t, have, want)
 }
 
+func TestSortScopedRouteConfiguration(t *testing.T) {
+	want := []*envoy_route_v3.ScopedRouteConfiguration{
+		{Name: "bar"},
+		{Name: "baz"},
+		{Name: "foo"},
+	}
+
+	have := []*envoy_route_v3.ScopedRouteConfiguration{
+		want[2],
+		want[0],
+		want[1],
+	}
+
+	sort.Stable(For(have))
+	assert.Equal(t, have, want)
+}
+
 func TestSortVirtualHosts(t *testing.T) {
 	want := []*envoy_route_v3.VirtualHost{
 		{Name: "bar"},
@@ -121,6 +138,17 @@ func presentHeader(name string) *envoy_route_v3.HeaderMatcher {
 
 func TestSortRoutesLongestPath(t *testing.T) {
 	want := []*envoy_route_v3.Route{
+		// Note that exact matches sort before regex and prefix matches.
+		{
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Path{Path: "/path/exact"},
+			}},
+
+		{
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: &envoy_route_v3.RouteMatch_Path{Path: "/"},
+			}},
+
 		{
 			Match: &envoy_route_v3.RouteMatch{
 				PathSpecifier: matchRegex("/this/is/the/longest"),
@@ -188,6 +216,64 @@ func TestSortRoutesLongestHeaders(t *testing.T) {
 	assert.Equal(t, want, have)
 }
 
+func TestSortRoutesQueryParams(t *testing.T) {
+	want := []*envoy_route_v3.Route{
+		{
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+				Headers: []*envoy_route_v3.HeaderMatcher{
+					presentHeader("header-name"),
+				},
+			},
+		}, {
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+				QueryParameters: []*envoy_route_v3.QueryParameterMatcher{
+					{Name: "variant"},
+				},
+			},
+		}, {
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+			},
+		},
+	}
+
+	have := shuffleRoutes(want)
+
+	sort.Stable(For(have))
+	assert.Equal(t, want, have)
+}
+
+func TestSortRoutesTLSContext(t *testing.T) {
+	want := []*envoy_route_v3.Route{
+		{
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+				QueryParameters: []*envoy_route_v3.QueryParameterMatcher{
+					{Name: "variant"},
+				},
+			},
+		}, {
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+				TlsContext: &envoy_route_v3.RouteMatch_TlsContextMatchOptions{
+					Presented: protobuf.Bool(true),
+				},
+			},
+		}, {
+			Match: &envoy_route_v3.RouteMatch{
+				PathSpecifier: matchPrefix("/path"),
+			},
+		},
+	}
+
+	have := shuffleRoutes(want)
+
+	sort.Stable(For(have))
+	assert.Equal(t, want, have)
+}
+
 func TestSortSecrets(t *testing.T) {
 	want := []*envoy_tls_v3.Secret{
 		{Name: "first"},
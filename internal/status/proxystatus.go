@@ -39,6 +39,10 @@ type ProxyUpdate struct {
 	// keyed by the Type (since that's what the apiserver will end up
 	// doing.)
 	Conditions map[ConditionType]*projectcontour.DetailedCondition
+
+	// ServiceSets holds the active service set of each
+	// route that selects one.
+	ServiceSets []projectcontour.ServiceSetStatus
 }
 
 // ConditionFor returns a DetailedCondition for a given ConditionType.
@@ -63,6 +67,29 @@ func (pu *ProxyUpdate) ConditionFor(cond ConditionType) *projectcontour.Detailed
 
 }
 
+// AddServiceSet records the active service set of a route. A route
+// reached through several includes is only recorded once, and each
+// service name is only listed once.
+func (pu *ProxyUpdate) AddServiceSet(set projectcontour.ServiceSetStatus) {
+	services := []string{}
+	seen := map[string]bool{}
+	for _, name := range set.Services {
+		if !seen[name] {
+			seen[name] = true
+			services = append(services, name)
+		}
+	}
+	set.Services = services
+
+	for i := range pu.ServiceSets {
+		if pu.ServiceSets[i].Route == set.Route {
+			pu.ServiceSets[i] = set
+			return
+		}
+	}
+	pu.ServiceSets = append(pu.ServiceSets, set)
+}
+
 func (pu *ProxyUpdate) Mutate(obj interface{}) interface{} {
 	o, ok := obj.(*projectcontour.HTTPProxy)
 	if !ok {
@@ -92,6 +119,8 @@ func (pu *ProxyUpdate) Mutate(obj interface{}) interface{} {
 
 	}
 
+	proxy.Status.ServiceSets = pu.ServiceSets
+
 	// Set the old status fields using the Valid DetailedCondition's details.
 	// Other conditions are not relevant for these two fields.
 	validCond := proxy.Status.GetConditionFor(projectcontour.ValidConditionType)
@@ -58,6 +58,36 @@ func TestConditionFor(t *testing.T) {
 
 }
 
+func TestAddServiceSet(t *testing.T) {
+	var pu ProxyUpdate
+
+	pu.AddServiceSet(contour_api_v1.ServiceSetStatus{
+		Route:    0,
+		Active:   "blue",
+		Services: []string{"home", "home", "api"},
+	})
+	pu.AddServiceSet(contour_api_v1.ServiceSetStatus{
+		Route:    1,
+		Active:   "green",
+		Services: []string{"home"},
+	})
+	pu.AddServiceSet(contour_api_v1.ServiceSetStatus{
+		Route:    0,
+		Active:   "blue",
+		Services: []string{"home", "api"},
+	})
+
+	assert.Equal(t, []contour_api_v1.ServiceSetStatus{{
+		Route:    0,
+		Active:   "blue",
+		Services: []string{"home", "api"},
+	}, {
+		Route:    1,
+		Active:   "green",
+		Services: []string{"home"},
+	}}, pu.ServiceSets)
+}
+
 func TestStatusMutator(t *testing.T) {
 	type testcase struct {
 		testProxy         contour_api_v1.HTTPProxy
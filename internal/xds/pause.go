@@ -0,0 +1,118 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"sync"
+)
+
+// Pauser holds back pushes of new configuration to Envoy while it
+// is paused. Envoy can still acknowledge the configuration it has
+// already been sent. The zero value is not paused, and a nil Pauser
+// is never paused.
+type Pauser struct {
+	mu          sync.Mutex
+	paused      bool
+	resumed     chan struct{}
+	resumeHooks []func()
+	pauseHooks  []func()
+}
+
+// Pause stops new configuration being pushed until Resume is called,
+// and calls the functions registered with OnPause.
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	if p.paused {
+		p.mu.Unlock()
+		return
+	}
+	p.paused = true
+	p.resumed = make(chan struct{})
+	hooks := p.pauseHooks
+	p.mu.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+}
+
+// Resume lets the configuration that changed while paused be pushed,
+// and calls the functions registered with OnResume.
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return
+	}
+	p.paused = false
+	close(p.resumed)
+	hooks := p.resumeHooks
+	p.mu.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+}
+
+// Paused returns true if pushes are paused.
+func (p *Pauser) Paused() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.paused
+}
+
+// OnPause registers f to be called each time pushes are paused.
+func (p *Pauser) OnPause(f func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pauseHooks = append(p.pauseHooks, f)
+}
+
+// OnResume registers f to be called each time pushes are resumed.
+func (p *Pauser) OnResume(f func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resumeHooks = append(p.resumeHooks, f)
+}
+
+// Wait blocks until pushes are not paused, returning nil, or
+// until ctx is done, returning its error.
+func (p *Pauser) Wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	paused, resumed := p.paused, p.resumed
+	p.mu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauser(t *testing.T) {
+	var p Pauser
+
+	pauses, resumes := 0, 0
+	p.OnPause(func() { pauses++ })
+	p.OnResume(func() { resumes++ })
+
+	assert.False(t, p.Paused())
+	assert.NoError(t, p.Wait(context.Background()))
+
+	p.Pause()
+	p.Pause()
+	assert.True(t, p.Paused())
+	assert.Equal(t, 1, pauses)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Wait(ctx))
+
+	done := make(chan error)
+	go func() {
+		done <- p.Wait(context.Background())
+	}()
+
+	p.Resume()
+	assert.NoError(t, <-done)
+	assert.False(t, p.Paused())
+	assert.Equal(t, 1, resumes)
+
+	// Resuming when not paused does nothing.
+	p.Resume()
+	assert.Equal(t, 1, resumes)
+}
+
+func TestNilPauser(t *testing.T) {
+	var p *Pauser
+
+	assert.False(t, p.Paused())
+	assert.NoError(t, p.Wait(context.Background()))
+}
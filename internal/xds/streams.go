@@ -0,0 +1,263 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/projectcontour/contour/internal/metrics"
+)
+
+// StreamRequest holds the details of a DiscoveryRequest
+// received on an xDS stream.
+type StreamRequest struct {
+	NodeID        string
+	TypeURL       string
+	VersionInfo   string
+	ResponseNonce string
+
+	// Rejected is true if the request carries error
+	// details, i.e. Envoy rejected the last response.
+	Rejected bool
+
+	// ErrorMessage is the reason Envoy gave for
+	// rejecting the last response.
+	ErrorMessage string
+}
+
+// StreamStatus describes an open xDS stream.
+type StreamStatus struct {
+	ID          int64     `json:"id"`
+	NodeID      string    `json:"node_id,omitempty"`
+	ConnectTime time.Time `json:"connect_time"`
+
+	// Resources holds the status of each resource
+	// type requested on the stream, by type URL.
+	Resources map[string]*ResourceStatus `json:"resources,omitempty"`
+}
+
+// ResourceStatus describes the state of a resource type
+// on an xDS stream.
+type ResourceStatus struct {
+	// VersionInfo is the last version that Envoy accepted.
+	VersionInfo string `json:"version_info,omitempty"`
+
+	LastACK  *time.Time `json:"last_ack,omitempty"`
+	LastNACK *NACK      `json:"last_nack,omitempty"`
+}
+
+// NACK describes a response that Envoy rejected.
+type NACK struct {
+	NodeID  string    `json:"node_id,omitempty"`
+	TypeURL string    `json:"type_url"`
+	Time    time.Time `json:"time"`
+
+	// ResponseNonce is the nonce of the rejected response.
+	ResponseNonce string `json:"response_nonce"`
+
+	// Message is the reason Envoy gave for the rejection.
+	Message string `json:"message,omitempty"`
+}
+
+// StreamTracker records the open xDS streams and the
+// responses that Envoy acknowledged or rejected on them.
+// The zero value is ready to use, and a nil StreamTracker
+// ignores all calls.
+type StreamTracker struct {
+	// Metrics, if not nil, is updated whenever the set
+	// of open streams changes or Envoy rejects a response.
+	Metrics *metrics.Metrics
+
+	// now returns the current time. It is
+	// replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	streams map[int64]*StreamStatus
+}
+
+// Open records that stream id has been opened.
+func (t *StreamTracker) Open(id int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.streams == nil {
+		t.streams = make(map[int64]*StreamStatus)
+	}
+	t.streams[id] = &StreamStatus{
+		ID:          id,
+		ConnectTime: t.clock(),
+		Resources:   make(map[string]*ResourceStatus),
+	}
+	t.updateMetrics()
+}
+
+// Close records that stream id has been closed.
+func (t *StreamTracker) Close(id int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.streams, id)
+	t.updateMetrics()
+}
+
+// Request records a DiscoveryRequest received on stream id.
+// A request with a response nonce acknowledges or rejects
+// the response with that nonce.
+func (t *StreamTracker) Request(id int64, req StreamRequest) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[id]
+	if !ok {
+		return
+	}
+
+	nodeChanged := s.NodeID != req.NodeID && req.NodeID != ""
+	if nodeChanged {
+		s.NodeID = req.NodeID
+	}
+
+	rs, ok := s.Resources[req.TypeURL]
+	if !ok {
+		rs = &ResourceStatus{}
+		s.Resources[req.TypeURL] = rs
+	}
+
+	if req.ResponseNonce != "" {
+		now := t.clock()
+		if req.Rejected {
+			rs.LastNACK = &NACK{
+				NodeID:        s.NodeID,
+				TypeURL:       req.TypeURL,
+				Time:          now,
+				ResponseNonce: req.ResponseNonce,
+				Message:       req.ErrorMessage,
+			}
+			if t.Metrics != nil {
+				t.Metrics.IncXDSNACK(req.TypeURL)
+			}
+		} else {
+			rs.LastACK = &now
+			rs.VersionInfo = req.VersionInfo
+		}
+	}
+
+	if nodeChanged {
+		t.updateMetrics()
+	}
+}
+
+// Streams returns the status of the open streams, ordered by ID.
+func (t *StreamTracker) Streams() []StreamStatus {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	streams := make([]StreamStatus, 0, len(t.streams))
+	for _, s := range t.streams {
+		status := *s
+		status.Resources = make(map[string]*ResourceStatus, len(s.Resources))
+		for typeURL, rs := range s.Resources {
+			r := *rs
+			status.Resources[typeURL] = &r
+		}
+		streams = append(streams, status)
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].ID < streams[j].ID
+	})
+
+	return streams
+}
+
+// NACKs returns the most recent NACK of each node
+// with open streams, ordered by node ID.
+func (t *StreamTracker) NACKs() []NACK {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := make(map[string]*NACK)
+	for _, s := range t.streams {
+		for _, rs := range s.Resources {
+			n := rs.LastNACK
+			if n == nil {
+				continue
+			}
+			if l, ok := last[n.NodeID]; !ok || n.Time.After(l.Time) {
+				last[n.NodeID] = n
+			}
+		}
+	}
+
+	nacks := make([]NACK, 0, len(last))
+	for _, n := range last {
+		nacks = append(nacks, *n)
+	}
+
+	sort.Slice(nacks, func(i, j int) bool {
+		return nacks[i].NodeID < nacks[j].NodeID
+	})
+
+	return nacks
+}
+
+func (t *StreamTracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// updateMetrics sets the stream metrics. The caller must hold t.mu.
+func (t *StreamTracker) updateMetrics() {
+	if t.Metrics == nil {
+		return
+	}
+
+	// Record the connect time of the oldest stream from each node.
+	connected := make(map[string]time.Time)
+	for _, s := range t.streams {
+		if s.NodeID == "" {
+			continue
+		}
+		if ct, ok := connected[s.NodeID]; !ok || s.ConnectTime.Before(ct) {
+			connected[s.NodeID] = s.ConnectTime
+		}
+	}
+
+	t.Metrics.SetXDSStreamMetric(len(t.streams), connected)
+}
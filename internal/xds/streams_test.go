@@ -0,0 +1,85 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamTracker(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	tracker := StreamTracker{
+		now: func() time.Time { return now },
+	}
+
+	tracker.Open(1)
+	tracker.Open(2)
+
+	// Initial requests subscribe, but do not ACK anything.
+	tracker.Request(1, StreamRequest{NodeID: "envoy-1", TypeURL: "clusters"})
+	tracker.Request(2, StreamRequest{NodeID: "envoy-2", TypeURL: "routes"})
+
+	connected := now
+	now = now.Add(time.Second)
+
+	tracker.Request(1, StreamRequest{NodeID: "envoy-1", TypeURL: "clusters", VersionInfo: "1", ResponseNonce: "1"})
+	tracker.Request(2, StreamRequest{NodeID: "envoy-2", TypeURL: "routes", VersionInfo: "", ResponseNonce: "1", Rejected: true, ErrorMessage: "invalid route"})
+
+	nack := NACK{
+		NodeID:        "envoy-2",
+		TypeURL:       "routes",
+		Time:          now,
+		ResponseNonce: "1",
+		Message:       "invalid route",
+	}
+
+	// Requests on unknown streams are ignored.
+	tracker.Request(3, StreamRequest{NodeID: "envoy-3", TypeURL: "routes"})
+
+	assert.Equal(t, []StreamStatus{{
+		ID:          1,
+		NodeID:      "envoy-1",
+		ConnectTime: connected,
+		Resources: map[string]*ResourceStatus{
+			"clusters": {VersionInfo: "1", LastACK: &now},
+		},
+	}, {
+		ID:          2,
+		NodeID:      "envoy-2",
+		ConnectTime: connected,
+		Resources: map[string]*ResourceStatus{
+			"routes": {LastNACK: &nack},
+		},
+	}}, tracker.Streams())
+
+	assert.Equal(t, []NACK{nack}, tracker.NACKs())
+
+	tracker.Close(1)
+	tracker.Close(2)
+	assert.Empty(t, tracker.Streams())
+	assert.Empty(t, tracker.NACKs())
+}
+
+func TestNilStreamTracker(t *testing.T) {
+	var tracker *StreamTracker
+
+	tracker.Open(1)
+	tracker.Request(1, StreamRequest{NodeID: "envoy-1"})
+	tracker.Close(1)
+	assert.Nil(t, tracker.Streams())
+	assert.Nil(t, tracker.NACKs())
+}
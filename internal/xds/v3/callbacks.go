@@ -14,26 +14,49 @@
 package v3
 
 import (
+	"context"
 	"fmt"
 
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	envoy_server_v3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/projectcontour/contour/internal/xds"
 	"github.com/sirupsen/logrus"
 )
 
 // NewRequestLoggingCallbacks returns an implementation of the Envoy xDS server
 // callbacks for use when Contour is run in Envoy xDS server mode to provide
-// request detail logging. Currently only the xDS State of the World callback
-// OnStreamRequest is implemented.
-func NewRequestLoggingCallbacks(log logrus.FieldLogger) envoy_server_v3.Callbacks {
+// request detail logging. If streams is not nil, the open streams are recorded
+// in it. Currently only the xDS State of the World callbacks are implemented.
+func NewRequestLoggingCallbacks(log logrus.FieldLogger, streams *xds.StreamTracker) envoy_server_v3.Callbacks {
 	return &envoy_server_v3.CallbackFuncs{
+		StreamOpenFunc: func(_ context.Context, streamID int64, _ string) error {
+			streams.Open(streamID)
+			return nil
+		},
+		StreamClosedFunc: func(streamID int64) {
+			streams.Close(streamID)
+		},
 		StreamRequestFunc: func(streamID int64, req *envoy_service_discovery_v3.DiscoveryRequest) error {
 			logDiscoveryRequestDetails(log, req)
+			streams.Request(streamID, streamRequest(req))
 			return nil
 		},
 	}
 }
 
+// streamRequest returns the details of req that are recorded
+// by a StreamTracker.
+func streamRequest(req *envoy_service_discovery_v3.DiscoveryRequest) xds.StreamRequest {
+	return xds.StreamRequest{
+		NodeID:        req.GetNode().GetId(),
+		TypeURL:       req.GetTypeUrl(),
+		VersionInfo:   req.GetVersionInfo(),
+		ResponseNonce: req.GetResponseNonce(),
+		Rejected:      req.GetErrorDetail() != nil,
+		ErrorMessage:  req.GetErrorDetail().GetMessage(),
+	}
+}
+
 // Helper function for use in the Envoy xDS server callbacks and the Contour
 // xDS server to log request details. Returns logger with fields added for any
 // subsequent error handling and logging.
@@ -47,13 +70,16 @@ func logDiscoveryRequestDetails(l logrus.FieldLogger, req *envoy_service_discove
 		}
 	}
 
+	log = log.WithField("resource_names", req.ResourceNames).WithField("type_url", req.GetTypeUrl())
+
 	if status := req.ErrorDetail; status != nil {
-		// if Envoy rejected the last update log the details here.
-		// TODO(dfc) issue 1176: handle xDS ACK/NACK
-		log.WithField("code", status.Code).Error(status.Message)
+		// Envoy rejected the response with the nonce
+		// ResponseNonce, and kept the version VersionInfo.
+		log.WithField("code", status.Code).
+			WithField("error_detail", status.Message).
+			Error("Envoy rejected xDS response")
 	}
 
-	log = log.WithField("resource_names", req.ResourceNames).WithField("type_url", req.GetTypeUrl())
 	log.Info("handling v3 xDS resource request")
 
 	return log
@@ -107,11 +107,14 @@ func TestLogDiscoveryRequestDetails(t *testing.T) {
 					Message: "error message from request",
 				},
 			},
-			expectedLogMsg: "error message from request",
+			expectedLogMsg: "Envoy rejected xDS response",
 			expectedLogData: logrus.Fields{
 				"version_info":   "req-version",
 				"response_nonce": "resp-nonce",
+				"resource_names": []string(nil),
+				"type_url":       "",
 				"code":           int32(code.Code_INTERNAL),
+				"error_detail":   "error message from request",
 			},
 		},
 	}
@@ -135,7 +138,7 @@ func TestLogDiscoveryRequestDetails(t *testing.T) {
 
 func TestOnStreamRequestCallbackLogs(t *testing.T) {
 	log, logHook := test.NewNullLogger()
-	callbacks := NewRequestLoggingCallbacks(log)
+	callbacks := NewRequestLoggingCallbacks(log, nil)
 	err := callbacks.OnStreamRequest(999, &envoy_service_discovery_v3.DiscoveryRequest{
 		VersionInfo:   "req-version",
 		ResponseNonce: "resp-nonce",
@@ -17,6 +17,8 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
@@ -39,17 +41,30 @@ type grpcStream interface {
 
 // NewContourServer creates an internally implemented Server that streams the
 // provided set of Resource objects. The returned Server implements the xDS
-// State of the World (SotW) variant.
-func NewContourServer(log logrus.FieldLogger, resources ...xds.Resource) Server {
+// State of the World (SotW) variant. If streams is not nil, the open streams
+// are recorded in it. If pushInterval is greater than zero, each stream is sent
+// at most one response per pushInterval, and the changes made in between are
+// coalesced into the next response. If pauser is not nil, no responses are
+// sent while it is paused, and the changes made in between are coalesced into
+// a single response once it is resumed. Streams opened while it is paused are
+// sent the configuration as it was when pushes were paused.
+func NewContourServer(log logrus.FieldLogger, streams *xds.StreamTracker, pushInterval time.Duration, pauser *xds.Pauser, resources ...xds.Resource) Server {
 	c := contourServer{
-		FieldLogger: log,
-		resources:   map[string]xds.Resource{},
+		FieldLogger:  log,
+		resources:    map[string]xds.Resource{},
+		streams:      streams,
+		pushInterval: pushInterval,
+		pauser:       pauser,
 	}
 
 	for i, r := range resources {
 		c.resources[r.TypeURL()] = resources[i]
 	}
 
+	if pauser != nil {
+		pauser.OnPause(c.snapshot)
+	}
+
 	return &c
 }
 
@@ -60,6 +75,7 @@ type contourServer struct {
 	envoy_service_discovery_v3.UnimplementedAggregatedDiscoveryServiceServer
 	envoy_service_secret_v3.UnimplementedSecretDiscoveryServiceServer
 	envoy_service_route_v3.UnimplementedRouteDiscoveryServiceServer
+	envoy_service_route_v3.UnimplementedScopedRoutesDiscoveryServiceServer
 	envoy_service_endpoint_v3.UnimplementedEndpointDiscoveryServiceServer
 	envoy_service_cluster_v3.UnimplementedClusterDiscoveryServiceServer
 	envoy_service_listener_v3.UnimplementedListenerDiscoveryServiceServer
@@ -67,12 +83,112 @@ type contourServer struct {
 	logrus.FieldLogger
 	resources   map[string]xds.Resource
 	connections xds.Counter
+	streams     *xds.StreamTracker
+
+	// pushInterval is the minimum time between
+	// two responses on a stream.
+	pushInterval time.Duration
+
+	// pauser holds back responses while it is paused.
+	pauser *xds.Pauser
+
+	// snapshots holds a map of the contents of each resource,
+	// by type URL, as they were when pushes were last paused.
+	snapshots atomic.Value
+}
+
+// resourceSnapshot is the contents of a resource at a version.
+type resourceSnapshot struct {
+	version  int
+	contents []proto.Message
+}
+
+// query returns the contents with the given names, or all the
+// contents if no names are given.
+func (r resourceSnapshot) query(names []string) []proto.Message {
+	if len(names) == 0 {
+		return r.contents
+	}
+
+	wanted := map[string]bool{}
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var resources []proto.Message
+	for _, m := range r.contents {
+		var name string
+		switch m := m.(type) {
+		case interface{ GetClusterName() string }:
+			name = m.GetClusterName()
+		case interface{ GetName() string }:
+			name = m.GetName()
+		}
+		if wanted[name] {
+			resources = append(resources, m)
+		}
+	}
+	return resources
+}
+
+// sameNames returns true if a and b hold the same resource names.
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := map[string]bool{}
+	for _, n := range a {
+		set[n] = true
+	}
+	for _, n := range b {
+		if !set[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot records the contents of every resource when pushes are
+// paused, so that Envoys that connect while paused, for example after
+// a restart, are sent the configuration that the others already have.
+func (s *contourServer) snapshot() {
+	snapshots := map[string]resourceSnapshot{}
+	for url, r := range s.resources {
+		// Registering below any version is notified at once with
+		// the current version. It is read before the contents, so
+		// that changes made in between are pushed again on resume.
+		ch := make(chan int, 1)
+		r.Register(ch, -1)
+		snapshots[url] = resourceSnapshot{
+			version:  <-ch,
+			contents: r.Contents(),
+		}
+	}
+
+	s.snapshots.Store(snapshots)
+}
+
+// pausedSnapshot returns the snapshot of the resource with the given
+// type URL if pushes are paused.
+func (s *contourServer) pausedSnapshot(typeURL string) (resourceSnapshot, bool) {
+	if !s.pauser.Paused() {
+		return resourceSnapshot{}, false
+	}
+
+	snapshots, _ := s.snapshots.Load().(map[string]resourceSnapshot)
+	r, ok := snapshots[typeURL]
+	return r, ok
 }
 
 // stream processes a stream of DiscoveryRequests.
 func (s *contourServer) stream(st grpcStream) error {
 	// Bump connection counter and set it as a field on the logger.
-	log := s.WithField("connection", s.connections.Next())
+	id := s.connections.Next()
+	log := s.WithField("connection", id)
+
+	s.streams.Open(int64(id))
+	defer s.streams.Close(int64(id))
 
 	// Notify whether the stream terminated on error.
 	done := func(log logrus.FieldLogger, err error) error {
@@ -93,6 +209,12 @@ func (s *contourServer) stream(st grpcStream) error {
 	last := -1
 	ctx := st.Context()
 
+	// lastPush is the time the last response was sent.
+	var lastPush time.Time
+
+	// names are the resource names of the last response.
+	var names []string
+
 	// now stick in this loop until the client disconnects.
 	for {
 		// first we wait for the request from Envoy, this is part of
@@ -104,6 +226,7 @@ func (s *contourServer) stream(st grpcStream) error {
 
 		// Note: redeclare log in this scope so the next time around the loop all is forgotten.
 		log := logDiscoveryRequestDetails(log, req)
+		s.streams.Request(int64(id), streamRequest(req))
 
 		// From the request we derive the resource to stream which have
 		// been registered according to the typeURL.
@@ -112,16 +235,55 @@ func (s *contourServer) stream(st grpcStream) error {
 			return done(log, fmt.Errorf("no resource registered for typeURL %q", req.GetTypeUrl()))
 		}
 
-		// now we wait for a notification, if this is the first request received on this
-		// connection last will be less than zero and that will trigger a response immediately.
-		r.Register(ch, last, req.ResourceNames...)
-		select {
-		case last = <-ch:
+		// wait until the next response is allowed before registering. Changes
+		// made while waiting are coalesced, since registering notifies with the
+		// latest version.
+		if wait := s.pushInterval - time.Since(lastPush); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return done(log, ctx.Err())
+			}
+		}
+
+		var version int
+		var resources []proto.Message
+
+		if snapshot, ok := s.pausedSnapshot(req.GetTypeUrl()); ok && (last < 0 || !sameNames(names, req.ResourceNames)) {
+			// A stream opened while pushes are paused, or that asks
+			// for other resources, is sent them as they were when
+			// pushes were paused, rather than nothing until they are
+			// resumed. Other responses are held back as usual.
+			version = snapshot.version
+			resources = snapshot.query(req.ResourceNames)
+		} else {
+			// now we wait for a notification, if this is the first request received on this
+			// connection last will be less than zero and that will trigger a response immediately.
+			// A notification that arrives while pushes are paused is held back until they are
+			// resumed, then registering again picks up every change made in between.
+			for {
+				if err := s.pauser.Wait(ctx); err != nil {
+					return done(log, err)
+				}
+
+				r.Register(ch, last, req.ResourceNames...)
+				select {
+				case version = <-ch:
+				case <-ctx.Done():
+					return done(log, ctx.Err())
+				}
+
+				if !s.pauser.Paused() {
+					break
+				}
+			}
+
 			// boom, something in the cache has changed.
 			// TODO(dfc) the thing that has changed may not be in the scope of the filter
 			// so we're going to be sending an update that is a no-op. See #426
 
-			var resources []proto.Message
 			switch len(req.ResourceNames) {
 			case 0:
 				// no resource hints supplied, return the full
@@ -131,30 +293,30 @@ func (s *contourServer) stream(st grpcStream) error {
 				// resource hints supplied, return exactly those
 				resources = r.Query(req.ResourceNames)
 			}
+		}
+		last = version
+		names = req.ResourceNames
 
-			any := make([]*any.Any, 0, len(resources))
-			for _, r := range resources {
-				a, err := ptypes.MarshalAny(r)
-				if err != nil {
-					return done(log, err)
-				}
-				any = append(any, a)
-			}
-
-			resp := &envoy_service_discovery_v3.DiscoveryResponse{
-				VersionInfo: strconv.Itoa(last),
-				Resources:   any,
-				TypeUrl:     req.GetTypeUrl(),
-				Nonce:       strconv.Itoa(last),
-			}
-
-			if err := st.Send(resp); err != nil {
+		any := make([]*any.Any, 0, len(resources))
+		for _, r := range resources {
+			a, err := ptypes.MarshalAny(r)
+			if err != nil {
 				return done(log, err)
 			}
+			any = append(any, a)
+		}
+
+		resp := &envoy_service_discovery_v3.DiscoveryResponse{
+			VersionInfo: strconv.Itoa(last),
+			Resources:   any,
+			TypeUrl:     req.GetTypeUrl(),
+			Nonce:       strconv.Itoa(last),
+		}
 
-		case <-ctx.Done():
-			return done(log, ctx.Err())
+		if err := st.Send(resp); err != nil {
+			return done(log, err)
 		}
+		lastPush = time.Now()
 	}
 }
 
@@ -174,6 +336,10 @@ func (s *contourServer) StreamRoutes(srv envoy_service_route_v3.RouteDiscoverySe
 	return s.stream(srv)
 }
 
+func (s *contourServer) StreamScopedRoutes(srv envoy_service_route_v3.ScopedRoutesDiscoveryService_StreamScopedRoutesServer) error {
+	return s.stream(srv)
+}
+
 func (s *contourServer) StreamSecrets(srv envoy_service_secret_v3.SecretDiscoveryService_StreamSecretsServer) error {
 	return s.stream(srv)
 }
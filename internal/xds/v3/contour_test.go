@@ -18,11 +18,15 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync"
 	"testing"
+	"time"
 
 	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/xds"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -151,6 +155,230 @@ func TestXDSHandlerStream(t *testing.T) {
 	}
 }
 
+func TestXDSHandlerStreamPushInterval(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	const pushInterval = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	xh := contourServer{
+		FieldLogger:  log,
+		pushInterval: pushInterval,
+		resources: map[string]xds.Resource{
+			"io.projectcontour.potato": &mockResource{
+				register: func(ch chan int, i int) {
+					// every registration sees a new version.
+					ch <- i + 1
+				},
+				contents: func() []proto.Message {
+					return []proto.Message{new(envoy_endpoint_v3.ClusterLoadAssignment)}
+				},
+				typeurl: func() string { return "io.projectcontour.potato" },
+			},
+		},
+	}
+
+	var sent []time.Time
+	stream := &mockStream{
+		context: func() context.Context { return ctx },
+		recv: func() (*envoy_service_discovery_v3.DiscoveryRequest, error) {
+			return &envoy_service_discovery_v3.DiscoveryRequest{
+				TypeUrl: "io.projectcontour.potato",
+			}, nil
+		},
+		send: func(resp *envoy_service_discovery_v3.DiscoveryResponse) error {
+			sent = append(sent, time.Now())
+			if len(sent) == 3 {
+				cancel()
+			}
+			return nil
+		},
+	}
+
+	assert.Equal(t, context.Canceled, xh.stream(stream))
+	assert.Len(t, sent, 3)
+	for i := 1; i < len(sent); i++ {
+		assert.GreaterOrEqual(t, int64(sent[i].Sub(sent[i-1])), int64(pushInterval))
+	}
+}
+
+func TestXDSHandlerStreamPaused(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var pauser xds.Pauser
+	pauser.Pause()
+
+	var cond contour.Cond
+	xh := contourServer{
+		FieldLogger: log,
+		pauser:      &pauser,
+		resources: map[string]xds.Resource{
+			"io.projectcontour.potato": &mockResource{
+				register: func(ch chan int, i int) { cond.Register(ch, i) },
+				contents: func() []proto.Message {
+					return []proto.Message{new(envoy_endpoint_v3.ClusterLoadAssignment)}
+				},
+				typeurl: func() string { return "io.projectcontour.potato" },
+			},
+		},
+	}
+
+	requests := make(chan struct{}, 1)
+	var sent []string
+	stream := &mockStream{
+		context: func() context.Context { return ctx },
+		recv: func() (*envoy_service_discovery_v3.DiscoveryRequest, error) {
+			requests <- struct{}{}
+			return &envoy_service_discovery_v3.DiscoveryRequest{
+				TypeUrl: "io.projectcontour.potato",
+			}, nil
+		},
+		send: func(resp *envoy_service_discovery_v3.DiscoveryResponse) error {
+			sent = append(sent, resp.VersionInfo)
+			if len(sent) == 2 {
+				cancel()
+			}
+			return nil
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- xh.stream(stream)
+	}()
+
+	// Changes made while paused are held back.
+	<-requests
+	cond.Notify()
+	cond.Notify()
+	time.Sleep(10 * time.Millisecond)
+
+	// Resuming sends the latest version once.
+	pauser.Resume()
+	<-requests
+
+	// Pausing again holds back changes made after registering.
+	pauser.Pause()
+	cond.Notify()
+	cond.Notify()
+	time.Sleep(10 * time.Millisecond)
+	pauser.Resume()
+
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Equal(t, []string{"2", "4"}, sent)
+}
+
+func TestXDSHandlerStreamOpenedWhilePaused(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cond contour.Cond
+	var mu sync.Mutex
+	clusters := []string{"a"}
+
+	contents := func() []proto.Message {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var messages []proto.Message
+		for _, c := range clusters {
+			messages = append(messages, &envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: c})
+		}
+		return messages
+	}
+
+	var pauser xds.Pauser
+	xh := NewContourServer(log, nil, 0, &pauser, &mockResource{
+		register: func(ch chan int, i int) { cond.Register(ch, i) },
+		contents: contents,
+		query:    func([]string) []proto.Message { return contents() },
+		typeurl:  func() string { return "io.projectcontour.potato" },
+	}).(*contourServer)
+
+	// Changes made after pausing are not in the snapshot.
+	cond.Notify()
+	pauser.Pause()
+	mu.Lock()
+	clusters = []string{"a", "b"}
+	mu.Unlock()
+	cond.Notify()
+
+	requests := make(chan []string, 3)
+	requests <- []string{"a"}
+	requests <- []string{"a", "b"}
+	requests <- []string{"a", "b"}
+
+	sent := make(chan *envoy_service_discovery_v3.DiscoveryResponse, 3)
+	stream := &mockStream{
+		context: func() context.Context { return ctx },
+		recv: func() (*envoy_service_discovery_v3.DiscoveryRequest, error) {
+			select {
+			case names := <-requests:
+				return &envoy_service_discovery_v3.DiscoveryRequest{
+					TypeUrl:       "io.projectcontour.potato",
+					ResourceNames: names,
+				}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+		send: func(resp *envoy_service_discovery_v3.DiscoveryResponse) error {
+			sent <- resp
+			return nil
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- xh.stream(stream)
+	}()
+
+	names := func(resp *envoy_service_discovery_v3.DiscoveryResponse) []string {
+		var names []string
+		for _, r := range resp.Resources {
+			var cla envoy_endpoint_v3.ClusterLoadAssignment
+			assert.NoError(t, ptypes.UnmarshalAny(r, &cla))
+			names = append(names, cla.ClusterName)
+		}
+		return names
+	}
+
+	// The first request, and a request for other resources,
+	// are answered from the snapshot taken when paused.
+	resp := <-sent
+	assert.Equal(t, "1", resp.VersionInfo)
+	assert.Equal(t, []string{"a"}, names(resp))
+
+	resp = <-sent
+	assert.Equal(t, "1", resp.VersionInfo)
+	assert.Equal(t, []string{"a"}, names(resp))
+
+	// The acknowledgement waits for pushes to be resumed.
+	select {
+	case resp := <-sent:
+		t.Fatalf("unexpected response while paused: %v", resp)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	pauser.Resume()
+	resp = <-sent
+	assert.Equal(t, "2", resp.VersionInfo)
+	assert.Equal(t, []string{"a", "b"}, names(resp))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
 type mockStream struct {
 	context func() context.Context
 	send    func(*envoy_service_discovery_v3.DiscoveryResponse) error
@@ -43,4 +43,9 @@ func RegisterServer(srv Server, g *grpc.Server) {
 	envoy_service_endpoint_v3.RegisterEndpointDiscoveryServiceServer(g, srv)
 	envoy_service_listener_v3.RegisterListenerDiscoveryServiceServer(g, srv)
 	envoy_service_route_v3.RegisterRouteDiscoveryServiceServer(g, srv)
+
+	// Only the contour server implements SRDS.
+	if srds, ok := srv.(envoy_service_route_v3.ScopedRoutesDiscoveryServiceServer); ok {
+		envoy_service_route_v3.RegisterScopedRoutesDiscoveryServiceServer(g, srds)
+	}
 }
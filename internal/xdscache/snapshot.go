@@ -14,15 +14,19 @@
 package xdscache
 
 import (
-	"math"
+	"encoding/binary"
+	"hash/fnv"
 	"reflect"
 	"strconv"
 	"sync"
 
 	envoy_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/xds"
 	"github.com/sirupsen/logrus"
+	protov2 "google.golang.org/protobuf/proto"
 )
 
 type Snapshotter interface {
@@ -36,12 +40,15 @@ type SnapshotHandler struct {
 	// resources holds the cache of xDS contents.
 	resources map[envoy_types.ResponseType]ResourceCache
 
-	// snapshotVersion holds the current version of the snapshot.
-	snapshotVersion int64
-
 	snapshotters []Snapshotter
 	snapLock     sync.Mutex
 
+	// Pauser, if not nil, holds back new snapshots while it
+	// is paused. Envoy is served the last snapshot generated
+	// before the pause, and Refresh should be registered to
+	// run when it resumes.
+	Pauser *xds.Pauser
+
 	logrus.FieldLogger
 }
 
@@ -74,15 +81,21 @@ func (s *SnapshotHandler) OnChange(root *dag.DAG) {
 // generateNewSnapshot creates a new snapshot against
 // the Contour XDS caches.
 func (s *SnapshotHandler) generateNewSnapshot() {
-	// Generate new snapshot version.
-	version := s.newSnapshotVersion()
-
-	resources := map[envoy_types.ResponseType][]envoy_types.Resource{
-		envoy_types.Endpoint: asResources(s.resources[envoy_types.Endpoint].Contents()),
-		envoy_types.Cluster:  asResources(s.resources[envoy_types.Cluster].Contents()),
-		envoy_types.Route:    asResources(s.resources[envoy_types.Route].Contents()),
-		envoy_types.Listener: asResources(s.resources[envoy_types.Listener].Contents()),
-		envoy_types.Secret:   asResources(s.resources[envoy_types.Secret].Contents()),
+	if s.Pauser.Paused() {
+		return
+	}
+
+	// Types without a cache, such as all but endpoints in
+	// endpoints-only mode, have no resources.
+	resources := map[envoy_types.ResponseType][]envoy_types.Resource{}
+	for t, r := range s.resources {
+		resources[t] = asResources(r.Contents())
+	}
+
+	version, err := snapshotVersion(resources)
+	if err != nil {
+		s.WithError(err).Error("failed to compute snapshot version")
+		return
 	}
 
 	s.snapLock.Lock()
@@ -95,18 +108,38 @@ func (s *SnapshotHandler) generateNewSnapshot() {
 	}
 }
 
-// newSnapshotVersion increments the current snapshotVersion
-// and returns as a string.
-func (s *SnapshotHandler) newSnapshotVersion() string {
-
-	// Reset the snapshotVersion if it ever hits max size.
-	if s.snapshotVersion == math.MaxInt64 {
-		s.snapshotVersion = 0
+// snapshotVersion returns a hash of the given resources. Since the
+// caches return their contents in sorted order and the resources are
+// marshaled deterministically, the same resources always produce the
+// same version, even across Contour restarts.
+func snapshotVersion(resources map[envoy_types.ResponseType][]envoy_types.Resource) (string, error) {
+	h := fnv.New64a()
+	opts := protov2.MarshalOptions{Deterministic: true}
+
+	for _, t := range []envoy_types.ResponseType{
+		envoy_types.Endpoint,
+		envoy_types.Cluster,
+		envoy_types.Route,
+		envoy_types.Listener,
+		envoy_types.Secret,
+	} {
+		h.Write([]byte{byte(t)})
+
+		for _, r := range resources[t] {
+			b, err := opts.Marshal(proto.MessageV2(r))
+			if err != nil {
+				return "", err
+			}
+
+			// Length prefix each resource so that resource
+			// boundaries contribute to the hash.
+			var n [binary.MaxVarintLen64]byte
+			h.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+			h.Write(b)
+		}
 	}
 
-	// Increment the snapshot version & return as string.
-	s.snapshotVersion++
-	return strconv.FormatInt(s.snapshotVersion, 10)
+	return strconv.FormatUint(h.Sum64(), 16), nil
 }
 
 // asResources casts the given slice of values (that implement the envoy_types.Resource
@@ -14,44 +14,110 @@
 package xdscache
 
 import (
-	"math"
 	"testing"
 
+	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/xds"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGetNewSnapshotVersion(t *testing.T) {
-	type testcase struct {
-		startingVersion int64
-		want            string
-	}
-
-	run := func(t *testing.T, name string, tc testcase) {
+func TestSnapshotVersion(t *testing.T) {
+	version := func(clusters ...string) string {
 		t.Helper()
 
-		t.Run(name, func(t *testing.T) {
-			t.Helper()
+		var resources []envoy_types.Resource
+		for _, c := range clusters {
+			resources = append(resources, &envoy_cluster_v3.Cluster{Name: c})
+		}
 
-			sh := SnapshotHandler{
-				snapshotVersion: tc.startingVersion,
-			}
-			got := sh.newSnapshotVersion()
-			assert.Equal(t, tc.want, got)
+		v, err := snapshotVersion(map[envoy_types.ResponseType][]envoy_types.Resource{
+			envoy_types.Cluster: resources,
 		})
+		require.NoError(t, err)
+		return v
 	}
 
-	run(t, "simple", testcase{
-		startingVersion: 0,
-		want:            "1",
-	})
+	// The same resources always produce the same version.
+	assert.Equal(t, version("default/a", "default/b"), version("default/a", "default/b"))
 
-	run(t, "big version", testcase{
-		startingVersion: math.MaxInt64 - 1,
-		want:            "9223372036854775807",
-	})
+	// Different resources produce different versions.
+	assert.NotEqual(t, version("default/a", "default/b"), version("default/a"))
+	assert.NotEqual(t, version("default/ab"), version("default/a", "b"))
+	assert.NotEqual(t, version(), version(""))
 
-	run(t, "resets if max hit", testcase{
-		startingVersion: math.MaxInt64,
-		want:            "1",
+	// Resources of a different type produce a different version.
+	v, err := snapshotVersion(map[envoy_types.ResponseType][]envoy_types.Resource{
+		envoy_types.Listener: {&envoy_cluster_v3.Cluster{Name: "default/a"}},
 	})
+	require.NoError(t, err)
+	assert.NotEqual(t, version("default/a"), v)
+}
+
+type staticCache struct {
+	typeURL  string
+	contents []proto.Message
+	contour.Cond
+}
+
+func (c *staticCache) Contents() []proto.Message      { return c.contents }
+func (c *staticCache) Query([]string) []proto.Message { return c.contents }
+func (c *staticCache) TypeURL() string                { return c.typeURL }
+func (c *staticCache) OnChange(*dag.DAG)              {}
+
+type recordingSnapshotter struct {
+	versions []string
+}
+
+func (r *recordingSnapshotter) Generate(version string, _ map[envoy_types.ResponseType][]envoy_types.Resource) error {
+	r.versions = append(r.versions, version)
+	return nil
+}
+
+func TestSnapshotHandlerPause(t *testing.T) {
+	clusters := &staticCache{typeURL: resource.ClusterType}
+	var resources []ResourceCache
+	for _, typeURL := range []string{resource.EndpointType, resource.RouteType, resource.ListenerType, resource.SecretType} {
+		resources = append(resources, &staticCache{typeURL: typeURL})
+	}
+	resources = append(resources, clusters)
+
+	var pauser xds.Pauser
+	snap := &recordingSnapshotter{}
+	sh := NewSnapshotHandler(resources, fixture.NewTestLogger(t))
+	sh.Pauser = &pauser
+	sh.AddSnapshotter(snap)
+	pauser.OnResume(sh.Refresh)
+
+	sh.OnChange(nil)
+	require.Len(t, snap.versions, 1)
+
+	// Changes made while paused are not snapshotted.
+	pauser.Pause()
+	clusters.contents = []proto.Message{&envoy_cluster_v3.Cluster{Name: "default/a"}}
+	sh.OnChange(nil)
+	clusters.contents = append(clusters.contents, &envoy_cluster_v3.Cluster{Name: "default/b"})
+	sh.Refresh()
+	assert.Len(t, snap.versions, 1)
+
+	// Resuming generates a single snapshot of the latest contents.
+	pauser.Resume()
+	require.Len(t, snap.versions, 2)
+	assert.NotEqual(t, snap.versions[0], snap.versions[1])
+}
+
+func TestSnapshotHandlerEndpointsOnly(t *testing.T) {
+	snap := &recordingSnapshotter{}
+	sh := NewSnapshotHandler([]ResourceCache{&staticCache{typeURL: resource.EndpointType}}, fixture.NewTestLogger(t))
+	sh.AddSnapshotter(snap)
+
+	// Types without a cache are snapshotted without resources.
+	sh.OnChange(nil)
+	assert.Len(t, snap.versions, 1)
 }
@@ -16,8 +16,10 @@ package v3
 import (
 	"sort"
 	"sync"
+	"time"
 
 	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/projectcontour/contour/internal/contour"
@@ -30,6 +32,26 @@ import (
 
 // ClusterCache manages the contents of the gRPC CDS cache.
 type ClusterCache struct {
+	// PerConnectionBufferLimitBytes sets the soft limit on
+	// the connection buffers of every cluster. If not set,
+	// Envoy's default is used.
+	PerConnectionBufferLimitBytes uint32
+
+	// ConnectTimeout is the connect timeout of clusters whose
+	// Service does not set its own. If not set, the default
+	// of 250ms is used.
+	ConnectTimeout time.Duration
+
+	// HealthyPanicThreshold is the percentage of healthy
+	// endpoints below which clusters balance requests across
+	// all endpoints. If not set, panic mode is disabled.
+	HealthyPanicThreshold uint32
+
+	// SPIREIdentity is the SPIFFE ID whose X.509 SVID is presented
+	// to upstreams over TLS by clusters that do not present a
+	// client certificate of their own. If not set, no SVID is used.
+	SPIREIdentity string
+
 	mu     sync.Mutex
 	values map[string]*envoy_cluster_v3.Cluster
 	contour.Cond
@@ -77,18 +99,31 @@ func (c *ClusterCache) Query(names []string) []proto.Message {
 func (*ClusterCache) TypeURL() string { return resource.ClusterType }
 
 func (c *ClusterCache) OnChange(root *dag.DAG) {
-	clusters := visitClusters(root)
+	clusters := visitClusters(root, c.ConnectTimeout)
+	for _, cluster := range clusters {
+		cluster.PerConnectionBufferLimitBytes = protobuf.UInt32OrNil(c.PerConnectionBufferLimitBytes)
+		if c.HealthyPanicThreshold > 0 && cluster.CommonLbConfig != nil {
+			cluster.CommonLbConfig.HealthyPanicThreshold = &envoy_type.Percent{
+				Value: float64(c.HealthyPanicThreshold),
+			}
+		}
+		if c.SPIREIdentity != "" {
+			envoy_v3.SPIREClientCertificate(cluster, c.SPIREIdentity)
+		}
+	}
 	c.Update(clusters)
 }
 
 type clusterVisitor struct {
-	clusters map[string]*envoy_cluster_v3.Cluster
+	clusters       map[string]*envoy_cluster_v3.Cluster
+	connectTimeout time.Duration
 }
 
 // visitCluster produces a map of *envoy_cluster_v3.Clusters.
-func visitClusters(root dag.Vertex) map[string]*envoy_cluster_v3.Cluster {
+func visitClusters(root dag.Vertex, connectTimeout time.Duration) map[string]*envoy_cluster_v3.Cluster {
 	cv := clusterVisitor{
-		clusters: make(map[string]*envoy_cluster_v3.Cluster),
+		clusters:       make(map[string]*envoy_cluster_v3.Cluster),
+		connectTimeout: connectTimeout,
 	}
 	cv.visit(root)
 	return cv.clusters
@@ -99,13 +134,25 @@ func (v *clusterVisitor) visit(vertex dag.Vertex) {
 	case *dag.Cluster:
 		name := envoy.Clustername(cluster)
 		if _, ok := v.clusters[name]; !ok {
-			v.clusters[name] = envoy_v3.Cluster(cluster)
+			c := envoy_v3.Cluster(cluster)
+			if v.connectTimeout > 0 && cluster.Upstream.ConnectTimeout == 0 {
+				c.ConnectTimeout = protobuf.Duration(v.connectTimeout)
+			}
+			v.clusters[name] = c
 		}
 	case *dag.ExtensionCluster:
 		name := cluster.Name
 		if _, ok := v.clusters[name]; !ok {
 			v.clusters[name] = envoy_v3.ExtensionCluster(cluster)
 		}
+	case *dag.ForwardProxy:
+		if _, ok := v.clusters[envoy_v3.ForwardProxyClusterName]; !ok {
+			v.clusters[envoy_v3.ForwardProxyClusterName] = envoy_v3.ForwardProxyCluster(cluster)
+		}
+	case *dag.OriginalDestination:
+		if _, ok := v.clusters[envoy_v3.OriginalDestinationClusterName]; !ok {
+			v.clusters[envoy_v3.OriginalDestinationClusterName] = envoy_v3.OriginalDestinationCluster()
+		}
 	}
 
 	// recurse into children of v
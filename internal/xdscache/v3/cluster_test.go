@@ -19,6 +19,7 @@ import (
 
 	envoy_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/duration"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
@@ -154,6 +155,160 @@ func TestClusterCacheQuery(t *testing.T) {
 	}
 }
 
+func TestClusterCacheOnChangePerConnectionBufferLimit(t *testing.T) {
+	root := buildDAG(t,
+		&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+			Spec: v1beta1.IngressSpec{
+				Backend: backend("kuard", 443),
+			},
+		},
+		service("default", "kuard",
+			v1.ServicePort{
+				Protocol:   "TCP",
+				Port:       443,
+				TargetPort: intstr.FromInt(8443),
+			},
+		),
+	)
+
+	cc := ClusterCache{PerConnectionBufferLimitBytes: 32768}
+	cc.OnChange(root)
+
+	want := []proto.Message{
+		cluster(&envoy_cluster_v3.Cluster{
+			Name:                 "default/kuard/443/da39a3ee5e",
+			AltStatName:          "default_kuard_443",
+			ClusterDiscoveryType: envoy_v3.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+			EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+				EdsConfig:   envoy_v3.ConfigSource("contour"),
+				ServiceName: "default/kuard",
+			},
+			PerConnectionBufferLimitBytes: protobuf.UInt32(32768),
+		}),
+	}
+
+	protobuf.ExpectEqual(t, want, cc.Contents())
+}
+
+func TestClusterCacheOnChangeHealthyPanicThreshold(t *testing.T) {
+	root := buildDAG(t,
+		&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+			Spec: v1beta1.IngressSpec{
+				Backend: backend("kuard", 443),
+			},
+		},
+		service("default", "kuard",
+			v1.ServicePort{
+				Protocol:   "TCP",
+				Port:       443,
+				TargetPort: intstr.FromInt(8443),
+			},
+		),
+	)
+
+	cc := ClusterCache{HealthyPanicThreshold: 50}
+	cc.OnChange(root)
+
+	kuard := cluster(&envoy_cluster_v3.Cluster{
+		Name:                 "default/kuard/443/da39a3ee5e",
+		AltStatName:          "default_kuard_443",
+		ClusterDiscoveryType: envoy_v3.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+		EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+			EdsConfig:   envoy_v3.ConfigSource("contour"),
+			ServiceName: "default/kuard",
+		},
+	})
+	kuard.CommonLbConfig.HealthyPanicThreshold = &envoy_type.Percent{Value: 50}
+
+	want := []proto.Message{kuard}
+
+	protobuf.ExpectEqual(t, want, cc.Contents())
+}
+
+func TestClusterCacheOnChangeConnectTimeout(t *testing.T) {
+	s2 := service("default", "slow",
+		v1.ServicePort{
+			Protocol:   "TCP",
+			Port:       80,
+			TargetPort: intstr.FromInt(8080),
+		},
+	)
+	s2.Annotations = map[string]string{
+		"projectcontour.io/connect-timeout": "5s",
+	}
+
+	root := buildDAG(t,
+		&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{{
+					IngressRuleValue: v1beta1.IngressRuleValue{
+						HTTP: &v1beta1.HTTPIngressRuleValue{
+							Paths: []v1beta1.HTTPIngressPath{{
+								Path:    "/",
+								Backend: *backend("kuard", 443),
+							}, {
+								Path:    "/slow",
+								Backend: *backend("slow", 80),
+							}},
+						},
+					},
+				}},
+			},
+		},
+		service("default", "kuard",
+			v1.ServicePort{
+				Protocol:   "TCP",
+				Port:       443,
+				TargetPort: intstr.FromInt(8443),
+			},
+		),
+		s2,
+	)
+
+	cc := ClusterCache{ConnectTimeout: time.Second}
+	cc.OnChange(root)
+
+	kuard := cluster(&envoy_cluster_v3.Cluster{
+		Name:                 "default/kuard/443/da39a3ee5e",
+		AltStatName:          "default_kuard_443",
+		ClusterDiscoveryType: envoy_v3.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+		EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+			EdsConfig:   envoy_v3.ConfigSource("contour"),
+			ServiceName: "default/kuard",
+		},
+	})
+	kuard.ConnectTimeout = protobuf.Duration(time.Second)
+
+	// The Service annotation takes precedence over the
+	// configured connect timeout.
+	slow := cluster(&envoy_cluster_v3.Cluster{
+		Name:                 "default/slow/80/da39a3ee5e",
+		AltStatName:          "default_slow_80",
+		ClusterDiscoveryType: envoy_v3.ClusterDiscoveryType(envoy_cluster_v3.Cluster_EDS),
+		EdsClusterConfig: &envoy_cluster_v3.Cluster_EdsClusterConfig{
+			EdsConfig:   envoy_v3.ConfigSource("contour"),
+			ServiceName: "default/slow",
+		},
+	})
+	slow.ConnectTimeout = protobuf.Duration(5 * time.Second)
+
+	want := []proto.Message{kuard, slow}
+
+	protobuf.ExpectEqual(t, want, cc.Contents())
+}
+
 func TestClusterVisit(t *testing.T) {
 	tests := map[string]struct {
 		objs []interface{}
@@ -748,7 +903,7 @@ func TestClusterVisit(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			root := buildDAG(t, tc.objs...)
-			got := visitClusters(root)
+			got := visitClusters(root, 0)
 			protobuf.ExpectEqual(t, tc.want, got)
 		})
 	}
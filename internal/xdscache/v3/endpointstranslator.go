@@ -15,9 +15,12 @@ package v3
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"sync"
+	"time"
 
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
@@ -29,6 +32,7 @@ import (
 	"github.com/projectcontour/contour/internal/sorter"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	discovery_v1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
@@ -44,7 +48,14 @@ func RecalculateEndpoints(port v1.ServicePort, ep *v1.Endpoints) []*LoadBalancin
 		return nil
 	}
 
-	var lb []*LoadBalancingEndpoint
+	type endpoint struct {
+		ip   string
+		port int
+	}
+
+	var addrs []endpoint
+	seen := map[endpoint]bool{}
+
 	for _, s := range ep.Subsets {
 		// Skip subsets without ready addresses.
 		if len(s.Addresses) < 1 {
@@ -65,17 +76,32 @@ func RecalculateEndpoints(port v1.ServicePort, ep *v1.Endpoints) []*LoadBalancin
 				continue
 			}
 
-			// If we matched this port, collect Envoy endpoints for all the ready addresses.
-			addresses := append([]v1.EndpointAddress{}, s.Addresses...) // Shallow copy.
-			sort.Slice(addresses, func(i, j int) bool { return addresses[i].IP < addresses[j].IP })
-
-			for _, a := range addresses {
-				addr := envoy_v3.SocketAddress(a.IP, int(p.Port))
-				lb = append(lb, envoy_v3.LBEndpoint(addr))
+			// If we matched this port, collect all the ready addresses,
+			// dropping any that appear in more than one subset.
+			for _, a := range s.Addresses {
+				e := endpoint{ip: a.IP, port: int(p.Port)}
+				if !seen[e] {
+					seen[e] = true
+					addrs = append(addrs, e)
+				}
 			}
 		}
 	}
 
+	// Sort the addresses so that the order of subsets, and of the
+	// addresses within them, doesn't change the load assignment.
+	sort.Slice(addrs, func(i, j int) bool {
+		if addrs[i].ip != addrs[j].ip {
+			return addrs[i].ip < addrs[j].ip
+		}
+		return addrs[i].port < addrs[j].port
+	})
+
+	var lb []*LoadBalancingEndpoint
+	for _, a := range addrs {
+		lb = append(lb, envoy_v3.LBEndpoint(envoy_v3.SocketAddress(a.ip, a.port)))
+	}
+
 	return lb
 }
 
@@ -95,6 +121,11 @@ type EndpointsCache struct {
 
 	// Cache of endpoints, indexed by name.
 	endpoints map[types.NamespacedName]*v1.Endpoints
+
+	// Cache of EndpointSlices, indexed by the name of their
+	// Service and then by their own name. The slices of each
+	// Service are merged into its entry in endpoints.
+	slices map[types.NamespacedName]map[string]*discovery_v1beta1.EndpointSlice
 }
 
 // Recalculate regenerates all the ClusterLoadAssignments from the
@@ -121,23 +152,29 @@ func (c *EndpointsCache) Recalculate() map[string]*envoy_endpoint_v3.ClusterLoad
 		}
 
 		// Look up each service, and if we have endpoints for that service,
-		// attach them as a new LocalityEndpoints resource2.
+		// attach them as new LocalityEndpoints resources, one for each
+		// locality of the service's EndpointSlices.
+		var services [][]*LocalityEndpoints
+		split := false
 		for _, w := range cluster.Services {
 			n := types.NamespacedName{Namespace: w.ServiceNamespace, Name: w.ServiceName}
 			if lb := RecalculateEndpoints(w.ServicePort, c.endpoints[n]); lb != nil {
-				// Append the new set of endpoints. Users are allowed to set the load
-				// balancing weight to 0, which we reflect to Envoy as nil in order to
-				// assign no load to that locality.
-				cla.Endpoints = append(
-					cla.Endpoints,
-					&LocalityEndpoints{
-						LbEndpoints:         lb,
-						LoadBalancingWeight: protobuf.UInt32OrNil(w.Weight),
-					},
-				)
+				groups := localityEndpoints(lb, endpointLocalities(c.slices[n]))
+				split = split || len(groups) > 1
+				services = append(services, groups)
+			} else {
+				services = append(services, nil)
 			}
 		}
 
+		for i, w := range cluster.Services {
+			// Append the new set of endpoints. Users are allowed to set the load
+			// balancing weight to 0, which we reflect to Envoy as nil in order to
+			// assign no load to that locality.
+			weighLocalities(services[i], w.Weight, split)
+			cla.Endpoints = append(cla.Endpoints, services[i]...)
+		}
+
 		assignments[cla.ClusterName] = &cla
 	}
 
@@ -219,6 +256,256 @@ func (c *EndpointsCache) DeleteEndpoint(ep *v1.Endpoints) {
 	}
 }
 
+// UpdateEndpointSlice adds slice to the cache, or replaces it if it
+// is already cached, and merges it with the other slices of its
+// Service. Any ServiceClusters that are backed by that Service
+// become stale.
+func (c *EndpointsCache) UpdateEndpointSlice(slice *discovery_v1beta1.EndpointSlice) {
+	name, ok := endpointSliceServiceName(slice)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slices[name] == nil {
+		c.slices[name] = map[string]*discovery_v1beta1.EndpointSlice{}
+	}
+	c.slices[name][slice.Name] = slice.DeepCopy()
+	c.endpoints[name] = endpointsFromSlices(name, c.slices[name])
+
+	if affected := c.services[name]; len(affected) > 0 {
+		c.stale = append(c.stale, affected...)
+	}
+}
+
+// DeleteEndpointSlice deletes slice from the cache. Any
+// ServiceClusters that are backed by its Service become stale.
+func (c *EndpointsCache) DeleteEndpointSlice(slice *discovery_v1beta1.EndpointSlice) {
+	name, ok := endpointSliceServiceName(slice)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.slices[name], slice.Name)
+	if len(c.slices[name]) == 0 {
+		delete(c.slices, name)
+		delete(c.endpoints, name)
+	} else {
+		c.endpoints[name] = endpointsFromSlices(name, c.slices[name])
+	}
+
+	if affected := c.services[name]; len(affected) > 0 {
+		c.stale = append(c.stale, affected...)
+	}
+}
+
+// Replace replaces the cached Endpoints and EndpointSlices with
+// objs. Any ServiceClusters that are backed by a Service whose
+// endpoints were cached, or are in objs, become stale, so the
+// endpoints of Services that are no longer in objs are removed.
+func (c *EndpointsCache) Replace(objs []interface{}) {
+	endpoints := map[types.NamespacedName]*v1.Endpoints{}
+	slices := map[types.NamespacedName]map[string]*discovery_v1beta1.EndpointSlice{}
+
+	for _, obj := range objs {
+		switch obj := obj.(type) {
+		case *v1.Endpoints:
+			endpoints[k8s.NamespacedNameOf(obj)] = obj.DeepCopy()
+		case *discovery_v1beta1.EndpointSlice:
+			name, ok := endpointSliceServiceName(obj)
+			if !ok {
+				continue
+			}
+			if slices[name] == nil {
+				slices[name] = map[string]*discovery_v1beta1.EndpointSlice{}
+			}
+			slices[name][obj.Name] = obj.DeepCopy()
+		}
+	}
+	for name, s := range slices {
+		endpoints[name] = endpointsFromSlices(name, s)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cached := range []map[types.NamespacedName]*v1.Endpoints{c.endpoints, endpoints} {
+		for name := range cached {
+			if affected := c.services[name]; len(affected) > 0 {
+				c.stale = append(c.stale, affected...)
+			}
+		}
+	}
+
+	c.endpoints = endpoints
+	c.slices = slices
+}
+
+// endpointSliceServiceName returns the name of the Service
+// that owns slice, if it has one.
+func endpointSliceServiceName(slice *discovery_v1beta1.EndpointSlice) (types.NamespacedName, bool) {
+	service := slice.Labels[discovery_v1beta1.LabelServiceName]
+	if service == "" {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: slice.Namespace, Name: service}, true
+}
+
+// endpointsFromSlices merges the EndpointSlices of a Service into a
+// single v1.Endpoints, with a subset for each slice, so that slices
+// of every address family are load balanced together. Slices of FQDN
+// addresses, and endpoints that are not ready, are skipped.
+func endpointsFromSlices(name types.NamespacedName, slices map[string]*discovery_v1beta1.EndpointSlice) *v1.Endpoints {
+	ep := &v1.Endpoints{}
+	ep.Namespace = name.Namespace
+	ep.Name = name.Name
+
+	for _, slice := range slices {
+		if slice.AddressType != discovery_v1beta1.AddressTypeIPv4 && slice.AddressType != discovery_v1beta1.AddressTypeIPv6 {
+			continue
+		}
+
+		var subset v1.EndpointSubset
+		for _, e := range slice.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+			for _, a := range e.Addresses {
+				subset.Addresses = append(subset.Addresses, v1.EndpointAddress{IP: a})
+			}
+		}
+
+		for _, p := range slice.Ports {
+			if p.Port == nil {
+				continue
+			}
+
+			port := v1.EndpointPort{
+				Port:     *p.Port,
+				Protocol: v1.ProtocolTCP,
+			}
+			if p.Name != nil {
+				port.Name = *p.Name
+			}
+			if p.Protocol != nil {
+				port.Protocol = *p.Protocol
+			}
+			subset.Ports = append(subset.Ports, port)
+		}
+
+		ep.Subsets = append(ep.Subsets, subset)
+	}
+
+	return ep
+}
+
+// endpointLocalities returns the locality of each address of the
+// EndpointSlices of a Service, from the region and zone in the
+// topology of its endpoints. Addresses whose endpoints have neither
+// have no locality.
+func endpointLocalities(slices map[string]*discovery_v1beta1.EndpointSlice) map[string]*envoy_core_v3.Locality {
+	localities := map[string]*envoy_core_v3.Locality{}
+	for _, slice := range slices {
+		for _, e := range slice.Endpoints {
+			region := e.Topology[v1.LabelZoneRegionStable]
+			zone := e.Topology[v1.LabelZoneFailureDomainStable]
+			if region == "" && zone == "" {
+				continue
+			}
+			for _, a := range e.Addresses {
+				localities[a] = &envoy_core_v3.Locality{
+					Region: region,
+					Zone:   zone,
+				}
+			}
+		}
+	}
+
+	return localities
+}
+
+// localityEndpoints groups lb into a LocalityEndpoints for each of
+// their localities, ordered by region and zone, so that Envoy can
+// prefer the endpoints in its own zone. Endpoints without a locality
+// are grouped together first.
+func localityEndpoints(lb []*LoadBalancingEndpoint, localities map[string]*envoy_core_v3.Locality) []*LocalityEndpoints {
+	type key struct {
+		region, zone string
+	}
+
+	var groups []*LocalityEndpoints
+	index := map[key]*LocalityEndpoints{}
+
+	for _, e := range lb {
+		locality := localities[e.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()]
+		k := key{region: locality.GetRegion(), zone: locality.GetZone()}
+
+		group, ok := index[k]
+		if !ok {
+			group = &LocalityEndpoints{
+				Locality: locality,
+			}
+			index[k] = group
+			groups = append(groups, group)
+		}
+		group.LbEndpoints = append(group.LbEndpoints, e)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Locality.GetRegion() != groups[j].Locality.GetRegion() {
+			return groups[i].Locality.GetRegion() < groups[j].Locality.GetRegion()
+		}
+		return groups[i].Locality.GetZone() < groups[j].Locality.GetZone()
+	})
+
+	return groups
+}
+
+// localityWeightScale is the factor by which the service weights of
+// a cluster are multiplied when one of its services has endpoints in
+// several localities, so that the weight of the service can be split
+// between them.
+const localityWeightScale = 1000
+
+// weighLocalities sets the load balancing weight of the locality
+// groups of a service. If split is false, every group has the weight
+// of the service. Otherwise, the scaled weight of the service is
+// split between the groups in proportion to their endpoints, so that
+// a service does not receive more load because its endpoints are
+// spread across more localities.
+func weighLocalities(groups []*LocalityEndpoints, weight uint32, split bool) {
+	if !split {
+		for _, g := range groups {
+			g.LoadBalancingWeight = protobuf.UInt32OrNil(weight)
+		}
+		return
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += len(g.LbEndpoints)
+	}
+
+	for _, g := range groups {
+		share := uint64(weight) * localityWeightScale * uint64(len(g.LbEndpoints)) / uint64(total)
+		switch {
+		case share == 0 && weight > 0:
+			// Keep a locality with endpoints from being
+			// excluded by rounding.
+			share = 1
+		case share > math.MaxUint32:
+			share = math.MaxUint32
+		}
+		g.LoadBalancingWeight = protobuf.UInt32OrNil(uint32(share))
+	}
+}
+
 // NewEndpointsTranslator allocates a new endpoints translator.
 func NewEndpointsTranslator(log logrus.FieldLogger) *EndpointsTranslator {
 	return &EndpointsTranslator{
@@ -229,6 +516,7 @@ func NewEndpointsTranslator(log logrus.FieldLogger) *EndpointsTranslator {
 			stale:     nil,
 			services:  map[types.NamespacedName][]*dag.ServiceCluster{},
 			endpoints: map[types.NamespacedName]*v1.Endpoints{},
+			slices:    map[types.NamespacedName]map[string]*discovery_v1beta1.EndpointSlice{},
 		},
 	}
 }
@@ -239,6 +527,16 @@ type EndpointsTranslator struct {
 	// Observer notifies when the endpoints cache has been updated.
 	Observer contour.Observer
 
+	// HoldoffDelay is the window over which Endpoints changes are
+	// batched before the affected ClusterLoadAssignments are
+	// recalculated. If zero, every change is recalculated immediately.
+	HoldoffDelay time.Duration
+
+	// LocalCluster is the ServiceCluster of the Envoy fleet's own
+	// endpoints, which Envoy uses as its local cluster for zone
+	// aware routing. If nil, no local cluster is served.
+	LocalCluster *dag.ServiceCluster
+
 	contour.Cond
 	logrus.FieldLogger
 
@@ -246,17 +544,75 @@ type EndpointsTranslator struct {
 
 	mu      sync.Mutex // Protects entries.
 	entries map[string]*envoy_endpoint_v3.ClusterLoadAssignment
+
+	// recalculateMu serializes the recalculations of the holdoff
+	// timer with those of DAG rebuilds, so that neither drains the
+	// stale clusters of the other, or merges load assignments for
+	// clusters that the other has already removed.
+	recalculateMu sync.Mutex
+
+	timerMu sync.Mutex // Protects timer.
+	timer   *time.Timer
 }
 
 // Merge combines the given entries with the existing entries in the
 // EndpointsTranslator. If the same key exists in both maps, an existing entry
-// is replaced.
-func (e *EndpointsTranslator) Merge(entries map[string]*envoy_endpoint_v3.ClusterLoadAssignment) {
+// is replaced. Merge returns true if any entry was added or changed.
+func (e *EndpointsTranslator) Merge(entries map[string]*envoy_endpoint_v3.ClusterLoadAssignment) bool {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	changed := false
 	for k, v := range entries {
+		if old, ok := e.entries[k]; ok && proto.Equal(old, v) {
+			continue
+		}
+
 		e.entries[k] = v
+		changed = true
+	}
+
+	return changed
+}
+
+// recalculate regenerates the stale ClusterLoadAssignments and, if
+// any of them changed, notifies watchers and the Observer.
+func (e *EndpointsTranslator) recalculate() {
+	e.timerMu.Lock()
+	e.timer = nil
+	e.timerMu.Unlock()
+
+	e.recalculateMu.Lock()
+	changed := e.Merge(e.cache.Recalculate())
+	e.recalculateMu.Unlock()
+
+	if !changed {
+		e.Debug("cluster load assignments did not change")
+		return
+	}
+
+	e.Notify()
+	if e.Observer != nil {
+		e.Observer.Refresh()
+	}
+}
+
+// scheduleRecalculate recalculates the stale ClusterLoadAssignments,
+// either immediately or, if HoldoffDelay is set, once the holdoff
+// window that started with the first outstanding change expires.
+// Changes that arrive within the window are coalesced, so each stale
+// cluster is only recalculated once.
+func (e *EndpointsTranslator) scheduleRecalculate() {
+	if e.HoldoffDelay <= 0 {
+		e.recalculate()
+		return
+	}
+
+	e.timerMu.Lock()
+	defer e.timerMu.Unlock()
+
+	if e.timer == nil {
+		e.timer = time.AfterFunc(e.HoldoffDelay, e.recalculate)
 	}
 }
 
@@ -285,6 +641,13 @@ func (e *EndpointsTranslator) OnChange(d *dag.DAG) {
 	// Collect all the service clusters from the DAG.
 	d.Visit(visitor)
 
+	if e.LocalCluster != nil {
+		visitor(e.LocalCluster)
+	}
+
+	e.recalculateMu.Lock()
+	defer e.recalculateMu.Unlock()
+
 	// Update the cache with the new clusters.
 	if err := e.cache.SetClusters(clusters); err != nil {
 		e.WithError(err).Error("failed to cache service clusters")
@@ -339,11 +702,10 @@ func (e *EndpointsTranslator) OnAdd(obj interface{}) {
 	switch obj := obj.(type) {
 	case *v1.Endpoints:
 		e.cache.UpdateEndpoint(obj)
-		e.Merge(e.cache.Recalculate())
-		e.Notify()
-		if e.Observer != nil {
-			e.Observer.Refresh()
-		}
+		e.scheduleRecalculate()
+	case *discovery_v1beta1.EndpointSlice:
+		e.cache.UpdateEndpointSlice(obj)
+		e.scheduleRecalculate()
 	default:
 		e.Errorf("OnAdd unexpected type %T: %#v", obj, obj)
 	}
@@ -372,11 +734,14 @@ func (e *EndpointsTranslator) OnUpdate(oldObj, newObj interface{}) {
 		}
 
 		e.cache.UpdateEndpoint(newObj)
-		e.Merge(e.cache.Recalculate())
-		e.Notify()
-		if e.Observer != nil {
-			e.Observer.Refresh()
+		e.scheduleRecalculate()
+	case *discovery_v1beta1.EndpointSlice:
+		if oldObj == newObj {
+			return
 		}
+
+		e.cache.UpdateEndpointSlice(newObj)
+		e.scheduleRecalculate()
 	default:
 		e.Errorf("OnUpdate unexpected type %T: %#v", newObj, newObj)
 	}
@@ -386,11 +751,10 @@ func (e *EndpointsTranslator) OnDelete(obj interface{}) {
 	switch obj := obj.(type) {
 	case *v1.Endpoints:
 		e.cache.DeleteEndpoint(obj)
-		e.Merge(e.cache.Recalculate())
-		e.Notify()
-		if e.Observer != nil {
-			e.Observer.Refresh()
-		}
+		e.scheduleRecalculate()
+	case *discovery_v1beta1.EndpointSlice:
+		e.cache.DeleteEndpointSlice(obj)
+		e.scheduleRecalculate()
 	case cache.DeletedFinalStateUnknown:
 		e.OnDelete(obj.Obj) // recurse into ourselves with the tombstoned value
 	default:
@@ -398,6 +762,14 @@ func (e *EndpointsTranslator) OnDelete(obj interface{}) {
 	}
 }
 
+// Resync replaces the cached Endpoints or EndpointSlices with objs,
+// a fresh listing of every one of them, so that the endpoints that
+// were deleted without a notification are removed as well.
+func (e *EndpointsTranslator) Resync(objs []interface{}) {
+	e.cache.Replace(objs)
+	e.scheduleRecalculate()
+}
+
 // Contents returns a copy of the contents of the cache.
 func (e *EndpointsTranslator) Contents() []proto.Message {
 	e.mu.Lock()
@@ -412,6 +784,23 @@ func (e *EndpointsTranslator) Contents() []proto.Message {
 	return protobuf.AsMessages(values)
 }
 
+// EndpointCounts returns the number of endpoints
+// of each cluster load assignment in the cache.
+func (e *EndpointsTranslator) EndpointCounts() map[string]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	counts := make(map[string]int, len(e.entries))
+	for name, cla := range e.entries {
+		n := 0
+		for _, lle := range cla.Endpoints {
+			n += len(lle.LbEndpoints)
+		}
+		counts[name] = n
+	}
+	return counts
+}
+
 func (e *EndpointsTranslator) Query(names []string) []proto.Message {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -14,17 +14,26 @@
 package v3
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/contour"
 	"github.com/projectcontour/contour/internal/dag"
 	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
 	"github.com/projectcontour/contour/internal/fixture"
+	"github.com/projectcontour/contour/internal/metrics"
 	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	discovery_v1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 func TestEndpointsTranslatorContents(t *testing.T) {
@@ -520,6 +529,33 @@ func TestEndpointsTranslatorRecomputeClusterLoadAssignment(t *testing.T) {
 				},
 			},
 		},
+		"addresses sorted and deduplicated across subsets": {
+			cluster: dag.ServiceCluster{
+				ClusterName: "default/httpbin-org",
+				Services: []dag.WeightedService{{
+					Weight:           1,
+					ServiceName:      "httpbin-org",
+					ServiceNamespace: "default",
+				}},
+			},
+			ep: endpoints("default", "httpbin-org", v1.EndpointSubset{
+				Addresses: addresses("50.17.206.192", "23.23.247.89"),
+				Ports:     ports(port("", 80)),
+			}, v1.EndpointSubset{
+				Addresses: addresses("50.17.192.147", "23.23.247.89"),
+				Ports:     ports(port("", 80)),
+			}),
+			want: []proto.Message{
+				&envoy_endpoint_v3.ClusterLoadAssignment{
+					ClusterName: "default/httpbin-org",
+					Endpoints: envoy_v3.WeightedEndpoints(1,
+						envoy_v3.SocketAddress("23.23.247.89", 80),
+						envoy_v3.SocketAddress("50.17.192.147", 80),
+						envoy_v3.SocketAddress("50.17.206.192", 80),
+					),
+				},
+			},
+		},
 		"named container port": {
 			cluster: dag.ServiceCluster{
 				ClusterName: "default/secure/https",
@@ -590,6 +626,7 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	}
 
 	protobuf.RequireEqual(t, want, et.Contents())
+	assert.Equal(t, map[string]int{"default/simple": 1}, et.EndpointCounts())
 
 	// e2 is the same as e1, but without endpoint subsets
 	e2 := endpoints("default", "simple")
@@ -601,6 +638,324 @@ func TestEndpointsTranslatorScaleToZeroEndpoints(t *testing.T) {
 	}
 
 	protobuf.RequireEqual(t, want, et.Contents())
+	assert.Equal(t, map[string]int{"default/simple": 0}, et.EndpointCounts())
+}
+
+// Test that a resync removes the endpoints of the Services
+// that are no longer listed, and updates the others.
+func TestEndpointsTranslatorResync(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+
+	cluster := func(name string) *dag.ServiceCluster {
+		return &dag.ServiceCluster{
+			ClusterName: "default/" + name,
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      name,
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{},
+			}},
+		}
+	}
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		cluster("deleted"),
+		cluster("dual"),
+		cluster("simple"),
+	}))
+
+	et.OnAdd(endpoints("default", "deleted", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24"),
+		Ports:     ports(port("", 8080)),
+	}))
+	et.OnAdd(endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.25"),
+		Ports:     ports(port("", 8080)),
+	}))
+	et.OnAdd(endpointSlice("default", "dual-ipv4", "dual", discovery_v1beta1.AddressTypeIPv4, 8080,
+		discovery_v1beta1.Endpoint{Addresses: []string{"10.0.0.1"}},
+	))
+	et.OnAdd(endpointSlice("default", "dual-ipv6", "dual", discovery_v1beta1.AddressTypeIPv6, 8080,
+		discovery_v1beta1.Endpoint{Addresses: []string{"fd00::1"}},
+	))
+
+	// The deleted Endpoints and the IPv6 slice are missing
+	// from the listing, and the simple Endpoints changed.
+	et.Resync([]interface{}{
+		endpoints("default", "simple", v1.EndpointSubset{
+			Addresses: addresses("192.168.183.26"),
+			Ports:     ports(port("", 8080)),
+		}),
+		endpointSlice("default", "dual-ipv4", "dual", discovery_v1beta1.AddressTypeIPv4, 8080,
+			discovery_v1beta1.Endpoint{Addresses: []string{"10.0.0.1"}},
+		),
+	})
+
+	want := []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/deleted"},
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/dual",
+			Endpoints:   envoy_v3.WeightedEndpoints(1, envoy_v3.SocketAddress("10.0.0.1", 8080)),
+		},
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/simple",
+			Endpoints:   envoy_v3.WeightedEndpoints(1, envoy_v3.SocketAddress("192.168.183.26", 8080)),
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+}
+
+// Test that the EndpointSlices of each address family are merged
+// into the load assignment of their Service.
+func TestEndpointsTranslatorEndpointSlices(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		{
+			ClusterName: "default/dual/http",
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      "dual",
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{Name: "http"},
+			}},
+		},
+	}))
+
+	ipv4 := endpointSlice("default", "dual-ipv4", "dual", discovery_v1beta1.AddressTypeIPv4, 8080,
+		discovery_v1beta1.Endpoint{Addresses: []string{"10.0.0.1"}},
+		discovery_v1beta1.Endpoint{
+			Addresses:  []string{"10.0.0.2"},
+			Conditions: discovery_v1beta1.EndpointConditions{Ready: pointer.BoolPtr(false)},
+		},
+	)
+	ipv6 := endpointSlice("default", "dual-ipv6", "dual", discovery_v1beta1.AddressTypeIPv6, 8080,
+		discovery_v1beta1.Endpoint{Addresses: []string{"fd00::1"}},
+	)
+	et.OnAdd(ipv4)
+	et.OnAdd(ipv6)
+
+	// Endpoints that are not ready are skipped.
+	want := []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/dual/http",
+			Endpoints: envoy_v3.WeightedEndpoints(1,
+				envoy_v3.SocketAddress("10.0.0.1", 8080),
+				envoy_v3.SocketAddress("fd00::1", 8080),
+			),
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+
+	et.OnDelete(ipv4)
+
+	want = []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/dual/http",
+			Endpoints: envoy_v3.WeightedEndpoints(1,
+				envoy_v3.SocketAddress("fd00::1", 8080),
+			),
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+
+	et.OnDelete(ipv6)
+
+	want = []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{ClusterName: "default/dual/http"},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+}
+
+func TestEndpointsTranslatorEndpointSliceLocalities(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		{
+			ClusterName: "default/zoned/http",
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      "zoned",
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{Name: "http"},
+			}},
+		},
+	}))
+
+	et.OnAdd(endpointSlice("default", "zoned-ipv4", "zoned", discovery_v1beta1.AddressTypeIPv4, 8080,
+		discovery_v1beta1.Endpoint{
+			Addresses: []string{"10.0.0.1"},
+			Topology: map[string]string{
+				v1.LabelZoneRegionStable:        "us-east-1",
+				v1.LabelZoneFailureDomainStable: "us-east-1b",
+			},
+		},
+		discovery_v1beta1.Endpoint{
+			Addresses: []string{"10.0.0.2"},
+			Topology: map[string]string{
+				v1.LabelZoneRegionStable:        "us-east-1",
+				v1.LabelZoneFailureDomainStable: "us-east-1a",
+			},
+		},
+		discovery_v1beta1.Endpoint{
+			Addresses: []string{"10.0.0.3"},
+			Topology: map[string]string{
+				v1.LabelZoneRegionStable:        "us-east-1",
+				v1.LabelZoneFailureDomainStable: "us-east-1b",
+			},
+		},
+		discovery_v1beta1.Endpoint{Addresses: []string{"10.0.0.4"}},
+	))
+
+	// Endpoints are grouped by locality, and endpoints without a
+	// locality are grouped first. The weight of the service is split
+	// between the localities in proportion to their endpoints.
+	want := []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/zoned/http",
+			Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{
+				LbEndpoints: []*envoy_endpoint_v3.LbEndpoint{
+					envoy_v3.LBEndpoint(envoy_v3.SocketAddress("10.0.0.4", 8080)),
+				},
+				LoadBalancingWeight: protobuf.UInt32(250),
+			}, {
+				Locality: &envoy_core_v3.Locality{Region: "us-east-1", Zone: "us-east-1a"},
+				LbEndpoints: []*envoy_endpoint_v3.LbEndpoint{
+					envoy_v3.LBEndpoint(envoy_v3.SocketAddress("10.0.0.2", 8080)),
+				},
+				LoadBalancingWeight: protobuf.UInt32(250),
+			}, {
+				Locality: &envoy_core_v3.Locality{Region: "us-east-1", Zone: "us-east-1b"},
+				LbEndpoints: []*envoy_endpoint_v3.LbEndpoint{
+					envoy_v3.LBEndpoint(envoy_v3.SocketAddress("10.0.0.1", 8080)),
+					envoy_v3.LBEndpoint(envoy_v3.SocketAddress("10.0.0.3", 8080)),
+				},
+				LoadBalancingWeight: protobuf.UInt32(500),
+			}},
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+}
+
+// Test that the weight of each service of a cluster is split between
+// its localities, so that spreading the endpoints of a service across
+// more localities does not give it more load.
+func TestEndpointsTranslatorLocalityWeights(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		{
+			ClusterName: "default/weighted",
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      "zoned",
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{Name: "http"},
+			}, {
+				Weight:           3,
+				ServiceName:      "unzoned",
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{Name: "http"},
+			}, {
+				Weight:           0,
+				ServiceName:      "drained",
+				ServiceNamespace: "default",
+				ServicePort:      v1.ServicePort{Name: "http"},
+			}},
+		},
+	}))
+
+	zoned := func(address, zone string) discovery_v1beta1.Endpoint {
+		return discovery_v1beta1.Endpoint{
+			Addresses: []string{address},
+			Topology: map[string]string{
+				v1.LabelZoneRegionStable:        "us-east-1",
+				v1.LabelZoneFailureDomainStable: zone,
+			},
+		}
+	}
+
+	et.OnAdd(endpointSlice("default", "zoned-ipv4", "zoned", discovery_v1beta1.AddressTypeIPv4, 8080,
+		zoned("10.0.0.1", "us-east-1a"),
+		zoned("10.0.0.2", "us-east-1b"),
+		zoned("10.0.0.3", "us-east-1c"),
+	))
+	et.OnAdd(endpointSlice("default", "unzoned-ipv4", "unzoned", discovery_v1beta1.AddressTypeIPv4, 8080,
+		discovery_v1beta1.Endpoint{Addresses: []string{"10.0.1.1"}},
+	))
+	et.OnAdd(endpointSlice("default", "drained-ipv4", "drained", discovery_v1beta1.AddressTypeIPv4, 8080,
+		zoned("10.0.2.1", "us-east-1a"),
+		zoned("10.0.2.2", "us-east-1b"),
+	))
+
+	locality := func(zone string, weight uint32, addresses ...string) *envoy_endpoint_v3.LocalityLbEndpoints {
+		lle := &envoy_endpoint_v3.LocalityLbEndpoints{
+			LoadBalancingWeight: protobuf.UInt32OrNil(weight),
+		}
+		if zone != "" {
+			lle.Locality = &envoy_core_v3.Locality{Region: "us-east-1", Zone: zone}
+		}
+		for _, a := range addresses {
+			lle.LbEndpoints = append(lle.LbEndpoints, envoy_v3.LBEndpoint(envoy_v3.SocketAddress(a, 8080)))
+		}
+		return lle
+	}
+
+	// The zoned service keeps a quarter of the load of the
+	// cluster, and the drained service receives none.
+	want := []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/weighted",
+			Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{
+				locality("us-east-1a", 333, "10.0.0.1"),
+				locality("us-east-1b", 333, "10.0.0.2"),
+				locality("us-east-1c", 333, "10.0.0.3"),
+				locality("", 3000, "10.0.1.1"),
+				locality("us-east-1a", 0, "10.0.2.1"),
+				locality("us-east-1b", 0, "10.0.2.2"),
+			},
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
+}
+
+// Test that the local cluster is served along with the
+// ServiceClusters of the DAG.
+func TestEndpointsTranslatorLocalCluster(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+	et.LocalCluster = &dag.ServiceCluster{
+		ClusterName: "envoy",
+		Services: []dag.WeightedService{{
+			Weight:           1,
+			ServiceName:      "envoy",
+			ServiceNamespace: "projectcontour",
+			ServicePort:      v1.ServicePort{Name: "http"},
+		}},
+	}
+
+	et.OnAdd(endpointSlice("projectcontour", "envoy-ipv4", "envoy", discovery_v1beta1.AddressTypeIPv4, 8080,
+		discovery_v1beta1.Endpoint{
+			Addresses: []string{"10.0.0.1"},
+			Topology: map[string]string{
+				v1.LabelZoneRegionStable:        "us-east-1",
+				v1.LabelZoneFailureDomainStable: "us-east-1a",
+			},
+		},
+	))
+	et.OnChange(&dag.DAG{})
+
+	want := []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "envoy",
+			Endpoints: []*envoy_endpoint_v3.LocalityLbEndpoints{{
+				Locality: &envoy_core_v3.Locality{Region: "us-east-1", Zone: "us-east-1a"},
+				LbEndpoints: []*envoy_endpoint_v3.LbEndpoint{
+					envoy_v3.LBEndpoint(envoy_v3.SocketAddress("10.0.0.1", 8080)),
+				},
+				LoadBalancingWeight: protobuf.UInt32(1),
+			}},
+		},
+	}
+	protobuf.RequireEqual(t, want, et.Contents())
 }
 
 // Test that a cluster with weighted services propagates the weights.
@@ -812,6 +1167,173 @@ func TestEqual(t *testing.T) {
 	}
 }
 
+func TestEndpointsTranslatorCoalescesIdenticalUpdates(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+
+	refreshes := 0
+	et.Observer = contour.ObserverFunc(func() { refreshes++ })
+
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		{
+			ClusterName: "default/simple",
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      "simple",
+				ServiceNamespace: "default",
+			}},
+		},
+	}))
+
+	e1 := endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24"),
+		Ports:     ports(port("", 8080)),
+	})
+	et.OnAdd(e1)
+	assert.Equal(t, 1, refreshes)
+
+	// e2 only differs by a not ready address, so
+	// the load assignment is unchanged.
+	e2 := endpoints("default", "simple", v1.EndpointSubset{
+		Addresses:         addresses("192.168.183.24"),
+		NotReadyAddresses: addresses("192.168.183.25"),
+		Ports:             ports(port("", 8080)),
+	})
+	et.OnUpdate(e1, e2)
+	assert.Equal(t, 1, refreshes)
+
+	// e3 adds a ready address.
+	e3 := endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24", "192.168.183.25"),
+		Ports:     ports(port("", 8080)),
+	})
+	et.OnUpdate(e2, e3)
+	assert.Equal(t, 2, refreshes)
+}
+
+func TestEndpointsTranslatorHoldoff(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+	et.HoldoffDelay = 50 * time.Millisecond
+
+	refreshed := make(chan struct{}, 10)
+	et.Observer = contour.ObserverFunc(func() { refreshed <- struct{}{} })
+
+	require.NoError(t, et.cache.SetClusters([]*dag.ServiceCluster{
+		{
+			ClusterName: "default/simple",
+			Services: []dag.WeightedService{{
+				Weight:           1,
+				ServiceName:      "simple",
+				ServiceNamespace: "default",
+			}},
+		},
+	}))
+
+	// Simulate a rollout replacing the endpoint addresses.
+	var prev *v1.Endpoints
+	for _, ip := range []string{"192.168.183.24", "192.168.183.25", "192.168.183.26"} {
+		ep := endpoints("default", "simple", v1.EndpointSubset{
+			Addresses: addresses(ip),
+			Ports:     ports(port("", 8080)),
+		})
+		if prev == nil {
+			et.OnAdd(ep)
+		} else {
+			et.OnUpdate(prev, ep)
+		}
+		prev = ep
+	}
+
+	// Nothing is recalculated until the holdoff expires.
+	assert.Empty(t, et.Contents())
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for endpoints refresh")
+	}
+
+	protobuf.ExpectEqual(t, []proto.Message{
+		&envoy_endpoint_v3.ClusterLoadAssignment{
+			ClusterName: "default/simple",
+			Endpoints:   envoy_v3.WeightedEndpoints(1, envoy_v3.SocketAddress("192.168.183.26", 8080)),
+		},
+	}, et.Contents())
+
+	// The batched changes were pushed once.
+	select {
+	case <-refreshed:
+		t.Fatal("unexpected second endpoints refresh")
+	case <-time.After(2 * et.HoldoffDelay):
+	}
+}
+
+// Test that a DAG rebuild keeps the load assignments of its clusters
+// when the holdoff timer recalculates Endpoints changes at the same
+// time. The timer has to fire between the rebuild replacing the
+// clusters and recalculating them, so the rebuild is repeated to
+// make that likely.
+func TestEndpointsTranslatorHoldoffDuringDAGRebuild(t *testing.T) {
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+	et.HoldoffDelay = time.Hour
+	et.LocalCluster = &dag.ServiceCluster{
+		ClusterName: "default/simple",
+		Services: []dag.WeightedService{{
+			Weight:           1,
+			ServiceName:      "simple",
+			ServiceNamespace: "default",
+		}},
+	}
+
+	et.OnAdd(endpoints("default", "simple", v1.EndpointSubset{
+		Addresses: addresses("192.168.183.24"),
+		Ports:     ports(port("", 8080)),
+	}))
+
+	// Fire the holdoff timer continuously, so that it
+	// recalculates while the DAG rebuilds are in progress.
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				et.recalculate()
+			}
+		}
+	}()
+
+	for i := 0; i < 10000; i++ {
+		et.OnChange(&dag.DAG{})
+		if !assert.Len(t, et.Contents(), 1, "rebuild %d dropped the load assignment", i) {
+			break
+		}
+	}
+
+	close(done)
+	<-stopped
+}
+
+func endpointSlice(ns, name, service string, addressType discovery_v1beta1.AddressType, port int32, endpoints ...discovery_v1beta1.Endpoint) *discovery_v1beta1.EndpointSlice {
+	return &discovery_v1beta1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+			Labels: map[string]string{
+				discovery_v1beta1.LabelServiceName: service,
+			},
+		},
+		AddressType: addressType,
+		Endpoints:   endpoints,
+		Ports: []discovery_v1beta1.EndpointPort{{
+			Name: pointer.StringPtr("http"),
+			Port: pointer.Int32Ptr(port),
+		}},
+	}
+}
+
 func ports(eps ...v1.EndpointPort) []v1.EndpointPort {
 	return eps
 }
@@ -831,3 +1353,53 @@ func clusterloadassignments(clas ...*envoy_endpoint_v3.ClusterLoadAssignment) ma
 	}
 	return m
 }
+
+// Test that the endpoints metric can be set both by the holdoff timer
+// of the EndpointsTranslator and by DAG rebuilds, as serve does. The
+// race detector catches unsynchronized access to the metrics.
+func TestEndpointsTranslatorEndpointsMetric(t *testing.T) {
+	m := metrics.NewMetrics(prometheus.NewRegistry())
+
+	et := NewEndpointsTranslator(fixture.NewTestLogger(t))
+	et.HoldoffDelay = time.Millisecond
+	et.LocalCluster = &dag.ServiceCluster{
+		ClusterName: "default/simple",
+		Services: []dag.WeightedService{{
+			Weight:           1,
+			ServiceName:      "simple",
+			ServiceNamespace: "default",
+		}},
+	}
+
+	setEndpointsMetric := func() {
+		m.SetClusterEndpointsMetric(et.EndpointCounts())
+	}
+	et.Observer = contour.ObserverFunc(setEndpointsMetric)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			et.OnChange(&dag.DAG{})
+			setEndpointsMetric()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var prev *v1.Endpoints
+	for i := 0; i < 50; i++ {
+		ep := endpoints("default", "simple", v1.EndpointSubset{
+			Addresses: addresses(fmt.Sprintf("192.168.183.%d", i)),
+			Ports:     ports(port("", 8080)),
+		})
+		if prev == nil {
+			et.OnAdd(ep)
+		} else {
+			et.OnUpdate(prev, ep)
+		}
+		prev = ep
+		time.Sleep(time.Millisecond)
+	}
+
+	<-done
+}
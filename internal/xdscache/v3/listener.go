@@ -14,11 +14,14 @@
 package v3
 
 import (
+	"net"
 	"path"
 	"sort"
 	"sync"
+	"time"
 
 	envoy_accesslog_v3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	http "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	envoy_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
@@ -31,6 +34,7 @@ import (
 	"github.com/projectcontour/contour/internal/sorter"
 	"github.com/projectcontour/contour/internal/timeout"
 	"github.com/projectcontour/contour/pkg/config"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // nolint:golint
@@ -44,6 +48,8 @@ const (
 	DEFAULT_HTTPS_ACCESS_LOG       = "/dev/stdout"
 	DEFAULT_HTTPS_LISTENER_ADDRESS = DEFAULT_HTTP_LISTENER_ADDRESS
 	DEFAULT_HTTPS_LISTENER_PORT    = 8443
+
+	DEFAULT_METRICS_LISTENER_ADDRESS = DEFAULT_HTTP_LISTENER_ADDRESS
 )
 
 // ListenerConfig holds configuration parameters for building Envoy Listeners.
@@ -72,6 +78,38 @@ type ListenerConfig struct {
 	// If not set, defaults to DEFAULT_HTTPS_ACCESS_LOG.
 	HTTPSAccessLog string
 
+	// Envoy's metrics listener address.
+	// If not set, defaults to DEFAULT_METRICS_LISTENER_ADDRESS.
+	MetricsAddress string
+
+	// Envoy's metrics listener port. The metrics
+	// listener is only generated if the port is set.
+	MetricsPort int
+
+	// PerConnectionBufferLimitBytes sets the soft limit on the
+	// connection buffers of the HTTP and HTTPS listeners.
+	// If not set, Envoy's default is used.
+	PerConnectionBufferLimitBytes uint32
+
+	// SocketOptions are raw socket options that are set on the
+	// sockets of the HTTP and HTTPS listeners.
+	SocketOptions []config.SocketOptionParameters
+
+	// HTTPListeners are the additional HTTP listeners. Each is
+	// added if any virtual hosts are bound to it, and fetches
+	// the route configuration of the same name.
+	HTTPListeners []config.HTTPListenerParameters
+
+	// DrainType configures when Envoy drains the connections
+	// of the HTTP and HTTPS listeners. If not set, connections
+	// are drained on listener changes and on shutdown.
+	DrainType config.ListenerDrainType
+
+	// ScopedRoutes configures the HTTP listener to fetch a route
+	// configuration for each request host over SRDS, rather than
+	// a single route configuration for all virtual hosts.
+	ScopedRoutes bool
+
 	// UseProxyProto configures all listeners to expect a PROXY
 	// V1 or V2 preamble.
 	// If not set, defaults to false.
@@ -87,6 +125,12 @@ type ListenerConfig struct {
 	// HTTPS, because we don't support h2c.
 	DefaultHTTPVersions []envoy_v3.HTTPVersionType
 
+	// ALPNProtocols is the optional list of ALPN protocol
+	// names offered on the HTTPS listener, in order of
+	// preference. If not specified, the protocols are derived
+	// from DefaultHTTPVersions.
+	ALPNProtocols []string
+
 	// AccessLogType defines if Envoy logs should be output as Envoy's default or JSON.
 	// Valid values: 'envoy', 'json'
 	// If not set, defaults to 'envoy'
@@ -97,6 +141,31 @@ type ListenerConfig struct {
 	// Defaults to a particular set of fields.
 	AccessLogFields config.AccessLogFields
 
+	// AccessLogFilter restricts which requests are written to the
+	// access logs.
+	//
+	// If not set, every request is logged.
+	AccessLogFilter config.AccessLogFilterParameters
+
+	// HealthCheckPaths are the request paths of health
+	// checks, which are not written to the access logs.
+	HealthCheckPaths []string
+
+	// HealthCheckVirtualHostPath is the request path of health
+	// checks that Envoy answers itself on the HTTP and HTTPS
+	// listeners. If set, the HTTP listener is added even if no
+	// virtual hosts are bound to it.
+	HealthCheckVirtualHostPath string
+
+	// AccessLogHeaders lists request and response headers that
+	// are added to the access logs, in either format.
+	AccessLogHeaders config.AccessLogHeaderParameters
+
+	// AccessLogGRPC optionally configures a gRPC access log
+	// service that receives the HTTP access logs in addition
+	// to the files.
+	AccessLogGRPC config.AccessLogGRPCParameters
+
 	// RequestTimeout configures the request_timeout for all Connection Managers.
 	RequestTimeout timeout.Setting
 
@@ -113,6 +182,135 @@ type ListenerConfig struct {
 
 	// ConnectionShutdownGracePeriod configures the drain_timeout for all Connection Managers.
 	ConnectionShutdownGracePeriod timeout.Setting
+
+	// ErrorPages configures the local reply bodies for all Connection Managers.
+	ErrorPages []envoy_v3.ErrorPage
+
+	// XffNumTrustedHops sets the number of additional ingress proxy hops from the
+	// right side of the x-forwarded-for HTTP header to trust.
+	XffNumTrustedHops uint32
+
+	// TrustedProxyCIDRs, if not empty, restricts XffNumTrustedHops to
+	// connections from these ranges. Connections from other addresses
+	// trust no hops, and have their x-forwarded-for header overwritten.
+	// The x-envoy-original-dst-host header is only accepted from these
+	// ranges, and removed from the requests of any other address.
+	TrustedProxyCIDRs []*net.IPNet
+
+	// MaxRequestHeadersKB and MaxRequestHeadersCount limit the size
+	// and number of request headers on all Connection Managers.
+	// Zero means the Envoy default applies.
+	MaxRequestHeadersKB    uint32
+	MaxRequestHeadersCount uint32
+
+	// StripMatchingHostPort removes the port from the Host header
+	// when it matches the listener port for all Connection Managers.
+	StripMatchingHostPort bool
+
+	// DisableHTTP10 rejects HTTP/1.0 requests on all
+	// Connection Managers.
+	DisableHTTP10 bool
+
+	// HTTP10DefaultHost is the host given to HTTP/1.0 requests
+	// without a Host header. If not set, such requests are rejected.
+	HTTP10DefaultHost string
+
+	// ServerHeader configures the server response header for all
+	// Connection Managers.
+	ServerHeader config.ServerHeaderParameters
+
+	// RateLimitConfig optionally configures the global Rate Limit Service to be
+	// used.
+	RateLimitConfig *RateLimitConfig
+
+	// ResponseCache configures the cache filter that is added
+	// when any route has a cache policy.
+	ResponseCache config.ResponseCacheParameters
+
+	// Tracing configures trace context propagation
+	// on the HTTP connection managers.
+	Tracing config.TracingParameters
+}
+
+// RateLimitConfig holds configuration for the global Rate Limit Service.
+type RateLimitConfig struct {
+	ExtensionService        types.NamespacedName
+	Domain                  string
+	Timeout                 timeout.Setting
+	FailOpen                bool
+	EnableXRateLimitHeaders bool
+
+	// RetryAfter is the value of the Retry-After header
+	// added to rate limited responses. If zero, no header
+	// is added.
+	RetryAfter time.Duration
+}
+
+// numTrustedHops returns the number of x-forwarded-for hops to trust
+// on connections from the trusted proxy CIDRs, if trusted is true, or
+// on all other connections.
+func (lvc *ListenerConfig) numTrustedHops(trusted bool) uint32 {
+	if trusted || len(lvc.TrustedProxyCIDRs) == 0 {
+		return lvc.XffNumTrustedHops
+	}
+	return 0
+}
+
+// forwardedForFilter returns the filter that overwrites x-forwarded-for
+// on connections that are not from the trusted proxy CIDRs, or nil if
+// trusted is true or there are no trusted proxy CIDRs.
+func (lvc *ListenerConfig) forwardedForFilter(trusted bool) *http.HttpFilter {
+	if trusted || len(lvc.TrustedProxyCIDRs) == 0 {
+		return nil
+	}
+	return envoy_v3.FilterOverwriteForwardedFor()
+}
+
+// originalDestinationFilter returns the filter that removes the
+// x-envoy-original-dst-host header on connections that are not from
+// the trusted proxy CIDRs, or nil if trusted is true or no route
+// forwards requests to their original destination.
+func (v *listenerVisitor) originalDestinationFilter(trusted bool) *http.HttpFilter {
+	if trusted {
+		return nil
+	}
+	return v.originalDstFilter
+}
+
+// trustedProxyFilterChain returns a copy of fc, with the filters built
+// for trusted connections, that matches connections from the trusted
+// proxy CIDRs, or nil if there are no trusted proxy CIDRs.
+func (lvc *ListenerConfig) trustedProxyFilterChain(fc *envoy_listener_v3.FilterChain, filters func(trusted bool) []*envoy_listener_v3.Filter) *envoy_listener_v3.FilterChain {
+	if len(lvc.TrustedProxyCIDRs) == 0 {
+		return nil
+	}
+	return envoy_v3.FilterChainSourceRanges(fc, lvc.TrustedProxyCIDRs, filters(true))
+}
+
+// globalRateLimitConfig returns the global rate limit filter
+// configuration, or nil if no rate limit service is configured.
+func (lvc *ListenerConfig) globalRateLimitConfig() *envoy_v3.GlobalRateLimitConfig {
+	if lvc.RateLimitConfig == nil {
+		return nil
+	}
+
+	return &envoy_v3.GlobalRateLimitConfig{
+		ExtensionService:        lvc.RateLimitConfig.ExtensionService,
+		FailOpen:                lvc.RateLimitConfig.FailOpen,
+		Timeout:                 lvc.RateLimitConfig.Timeout,
+		Domain:                  lvc.RateLimitConfig.Domain,
+		EnableXRateLimitHeaders: lvc.RateLimitConfig.EnableXRateLimitHeaders,
+	}
+}
+
+// rateLimitRetryAfter returns the Retry-After value for rate
+// limited responses, or zero if no rate limit service is
+// configured.
+func (lvc *ListenerConfig) rateLimitRetryAfter() time.Duration {
+	if lvc.RateLimitConfig == nil {
+		return 0
+	}
+	return lvc.RateLimitConfig.RetryAfter
 }
 
 // httpAddress returns the port for the HTTP (non TLS)
@@ -160,6 +358,15 @@ func (lvc *ListenerConfig) httpsPort() int {
 	return DEFAULT_HTTPS_LISTENER_PORT
 }
 
+// metricsAddress returns the address for the metrics listener
+// or DEFAULT_METRICS_LISTENER_ADDRESS if not configured.
+func (lvc *ListenerConfig) metricsAddress() string {
+	if lvc.MetricsAddress != "" {
+		return lvc.MetricsAddress
+	}
+	return DEFAULT_METRICS_LISTENER_ADDRESS
+}
+
 // httpsAccessLog returns the access log for the HTTPS (TLS)
 // listener or DEFAULT_HTTPS_ACCESS_LOG if not configured.
 func (lvc *ListenerConfig) httpsAccessLog() string {
@@ -179,21 +386,32 @@ func (lvc *ListenerConfig) accesslogType() string {
 }
 
 // accesslogFields returns the access log fields that should be configured
-// for Envoy, or a default set if not configured.
+// for Envoy, or a default set if not configured, followed by the fields
+// for any access log headers.
 func (lvc *ListenerConfig) accesslogFields() config.AccessLogFields {
+	fields := config.DefaultFields
 	if lvc.AccessLogFields != nil {
-		return lvc.AccessLogFields
+		fields = lvc.AccessLogFields
+	}
+
+	headers := lvc.AccessLogHeaders.AsFields()
+	if len(headers) == 0 {
+		return fields
 	}
-	return config.DefaultFields
+
+	return append(append(config.AccessLogFields{}, fields...), headers...)
 }
 
 func (lvc *ListenerConfig) newInsecureAccessLog() []*envoy_accesslog_v3.AccessLog {
+	var logs []*envoy_accesslog_v3.AccessLog
 	switch lvc.accesslogType() {
 	case string(config.JSONAccessLog):
-		return envoy_v3.FileAccessLogJSON(lvc.httpAccessLog(), lvc.accesslogFields())
+		logs = envoy_v3.FileAccessLogJSON(lvc.httpAccessLog(), lvc.accesslogFields())
 	default:
-		return envoy_v3.FileAccessLogEnvoy(lvc.httpAccessLog())
+		logs = envoy_v3.FileAccessLogEnvoyWithHeaders(lvc.httpAccessLog(), lvc.AccessLogHeaders)
 	}
+	logs = append(logs, envoy_v3.GRPCAccessLog(ENVOY_HTTP_LISTENER, lvc.AccessLogGRPC)...)
+	return envoy_v3.FilterAccessLogs(logs, lvc.httpAccessLogFilter())
 }
 
 func (lvc *ListenerConfig) newSecureAccessLog() []*envoy_accesslog_v3.AccessLog {
@@ -201,8 +419,28 @@ func (lvc *ListenerConfig) newSecureAccessLog() []*envoy_accesslog_v3.AccessLog
 	case "json":
 		return envoy_v3.FileAccessLogJSON(lvc.httpsAccessLog(), lvc.accesslogFields())
 	default:
-		return envoy_v3.FileAccessLogEnvoy(lvc.httpsAccessLog())
+		return envoy_v3.FileAccessLogEnvoyWithHeaders(lvc.httpsAccessLog(), lvc.AccessLogHeaders)
+	}
+}
+
+// newSecureHTTPAccessLog returns the secure access log, and the gRPC
+// access log if configured, with the access log filter applied. The
+// filter is based on HTTP response codes, so it is not applied to
+// TCP proxy access logs.
+func (lvc *ListenerConfig) newSecureHTTPAccessLog() []*envoy_accesslog_v3.AccessLog {
+	logs := append(lvc.newSecureAccessLog(), envoy_v3.GRPCAccessLog(ENVOY_HTTPS_LISTENER, lvc.AccessLogGRPC)...)
+	return envoy_v3.FilterAccessLogs(logs, lvc.httpAccessLogFilter())
+}
+
+// httpAccessLogFilter returns the filter for HTTP access logs,
+// which also skips requests for the health check paths.
+func (lvc *ListenerConfig) httpAccessLogFilter() *envoy_accesslog_v3.AccessLogFilter {
+	paths := lvc.HealthCheckPaths
+	if lvc.HealthCheckVirtualHostPath != "" {
+		paths = append(append([]string{}, paths...), lvc.HealthCheckVirtualHostPath)
 	}
+
+	return envoy_v3.ExcludePathsAccessLogFilter(paths, envoy_v3.AccessLogFilter(lvc.AccessLogFilter))
 }
 
 // minTLSVersion returns the requested minimum TLS protocol
@@ -215,6 +453,22 @@ func (lvc *ListenerConfig) minTLSVersion() envoy_tls_v3.TlsParameters_TlsProtoco
 	return envoy_tls_v3.TlsParameters_TLSv1_2
 }
 
+// httpsProtocols returns the HTTP versions and ALPN protocol names
+// offered on HTTPS filter chains. The per-host ALPN protocols, if
+// any, take precedence over the listener ALPN protocols, which in
+// turn take precedence over the default HTTP versions.
+func (lvc *ListenerConfig) httpsProtocols(hostProtocols []string) ([]envoy_v3.HTTPVersionType, []string) {
+	switch {
+	case len(hostProtocols) > 0:
+		versions := envoy_v3.VersionsForProtoNames(hostProtocols...)
+		return versions, envoy_v3.ProtoNamesForVersions(versions...)
+	case len(lvc.ALPNProtocols) > 0:
+		return envoy_v3.VersionsForProtoNames(lvc.ALPNProtocols...), lvc.ALPNProtocols
+	default:
+		return lvc.DefaultHTTPVersions, envoy_v3.ProtoNamesForVersions(lvc.DefaultHTTPVersions...)
+	}
+}
+
 // ListenerCache manages the contents of the gRPC LDS cache.
 type ListenerCache struct {
 	mu           sync.Mutex
@@ -228,12 +482,19 @@ type ListenerCache struct {
 // NewListenerCache returns an instance of a ListenerCache
 func NewListenerCache(config ListenerConfig, address string, port int) *ListenerCache {
 	stats := envoy_v3.StatsListener(address, port)
-	return &ListenerCache{
+	c := &ListenerCache{
 		Config: config,
 		staticValues: map[string]*envoy_listener_v3.Listener{
 			stats.Name: stats,
 		},
 	}
+
+	if config.MetricsPort > 0 {
+		metrics := envoy_v3.MetricsListener(config.metricsAddress(), config.MetricsPort)
+		c.staticValues[metrics.Name] = metrics
+	}
+
+	return c
 }
 
 // Update replaces the contents of the cache with the supplied map.
@@ -295,13 +556,27 @@ func (c *ListenerCache) OnChange(root *dag.DAG) {
 type listenerVisitor struct {
 	*ListenerConfig
 
-	listeners map[string]*envoy_listener_v3.Listener
-	http      bool // at least one dag.VirtualHost encountered
+	listeners    map[string]*envoy_listener_v3.Listener
+	http         bool             // at least one dag.VirtualHost encountered
+	httpNamed    map[string]bool  // additional HTTP listeners with a dag.VirtualHost
+	bufferFilter *http.HttpFilter // set if at least one dag.Route limits request bodies
+	cacheFilter  *http.HttpFilter // set if at least one dag.Route has a cache policy
+	rbacFilter   *http.HttpFilter // set if at least one dag.Route restricts source ranges
+	faultFilter  *http.HttpFilter // set if at least one dag.Route has a fault policy
+
+	healthCheckFilter   *http.HttpFilter // set if Envoy answers health checks itself
+	timeoutHeaderFilter *http.HttpFilter // set if at least one dag.Route has a response timeout header
+	cacheScopeFilter    *http.HttpFilter // set with cacheFilter, runs before it
+	cacheControlFilter  *http.HttpFilter // set with cacheFilter, runs after it
+	originalDstFilter   *http.HttpFilter // set if at least one dag.Route forwards to its original destination
+
+	sessionTicketKeys *dag.Secret // set while visiting a dag.Listener with session ticket keys
 }
 
 func visitListeners(root dag.Vertex, lvc *ListenerConfig) map[string]*envoy_listener_v3.Listener {
 	lv := listenerVisitor{
 		ListenerConfig: lvc,
+		httpNamed:      map[string]bool{},
 		listeners: map[string]*envoy_listener_v3.Listener{
 			ENVOY_HTTPS_LISTENER: envoy_v3.Listener(
 				ENVOY_HTTPS_LISTENER,
@@ -312,30 +587,119 @@ func visitListeners(root dag.Vertex, lvc *ListenerConfig) map[string]*envoy_list
 		},
 	}
 
+	if requestBodyLimited(root) {
+		lv.bufferFilter = envoy_v3.FilterBuffer()
+	}
+
+	if responsesCached(root) {
+		lv.cacheScopeFilter = envoy_v3.FilterCacheScope()
+		lv.cacheFilter = envoy_v3.FilterCache(lvc.ResponseCache)
+		lv.cacheControlFilter = envoy_v3.FilterCacheControl()
+	}
+
+	if sourceRangesRestricted(root) {
+		lv.rbacFilter = envoy_v3.FilterRBAC()
+	}
+
+	if faultsInjected(root) {
+		lv.faultFilter = envoy_v3.FilterFault()
+	}
+
+	if responseTimeoutHeaders(root) {
+		lv.timeoutHeaderFilter = envoy_v3.FilterResponseTimeoutHeader()
+	}
+
+	if originalDestinations(root) {
+		lv.originalDstFilter = envoy_v3.FilterRemoveOriginalDestinationHost()
+	}
+
+	lv.healthCheckFilter = envoy_v3.FilterHealthCheck(lvc.HealthCheckVirtualHostPath)
+
 	lv.visit(root)
 
-	if lv.http {
-		// Add a listener if there are vhosts bound to http.
-		cm := envoy_v3.HTTPConnectionManagerBuilder().
-			Codec(envoy_v3.CodecForVersions(lv.DefaultHTTPVersions...)).
-			DefaultFilters().
-			RouteConfigName(ENVOY_HTTP_LISTENER).
-			MetricsPrefix(ENVOY_HTTP_LISTENER).
-			AccessLoggers(lvc.newInsecureAccessLog()).
-			RequestTimeout(lvc.RequestTimeout).
-			ConnectionIdleTimeout(lvc.ConnectionIdleTimeout).
-			StreamIdleTimeout(lvc.StreamIdleTimeout).
-			MaxConnectionDuration(lvc.MaxConnectionDuration).
-			ConnectionShutdownGracePeriod(lvc.ConnectionShutdownGracePeriod).
-			Get()
+	// httpFilters builds the filters of an HTTP listener that fetches
+	// the named route configuration, for connections from trusted
+	// proxies, or from anywhere else.
+	httpFilters := func(name string, scoped bool) func(trusted bool) []*envoy_listener_v3.Filter {
+		return func(trusted bool) []*envoy_listener_v3.Filter {
+			return envoy_v3.Filters(envoy_v3.HTTPConnectionManagerBuilder().
+				Codec(envoy_v3.CodecForVersions(lv.DefaultHTTPVersions...)).
+				AddFilter(lvc.forwardedForFilter(trusted)).
+				AddFilter(lv.originalDestinationFilter(trusted)).
+				AddFilter(lv.healthCheckFilter).
+				DefaultFilters().
+				AddFilter(lv.rbacFilter).
+				AddFilter(lv.faultFilter).
+				AddFilter(lv.timeoutHeaderFilter).
+				AddFilter(envoy_v3.GlobalRateLimitFilter(lvc.globalRateLimitConfig())).
+				AddFilter(lv.bufferFilter).
+				AddFilter(lv.cacheScopeFilter).
+				AddFilter(lv.cacheFilter).
+				AddFilter(lv.cacheControlFilter).
+				AddFilter(envoy_v3.FilterDynamicForwardProxy(forwardProxyOf(root))).
+				RouteConfigName(name).
+				ScopedRoutes(scoped).
+				MetricsPrefix(name).
+				AccessLoggers(lvc.newInsecureAccessLog()).
+				RequestTimeout(lvc.RequestTimeout).
+				ConnectionIdleTimeout(lvc.ConnectionIdleTimeout).
+				StreamIdleTimeout(lvc.StreamIdleTimeout).
+				MaxConnectionDuration(lvc.MaxConnectionDuration).
+				ConnectionShutdownGracePeriod(lvc.ConnectionShutdownGracePeriod).
+				ErrorPages(lvc.ErrorPages).
+				RateLimitRetryAfter(lvc.rateLimitRetryAfter()).
+				NumTrustedHops(lvc.numTrustedHops(trusted)).
+				RequestHeaderLimits(lvc.MaxRequestHeadersKB, lvc.MaxRequestHeadersCount).
+				StripMatchingHostPort(lvc.StripMatchingHostPort).
+				HTTP10(lvc.DisableHTTP10, lvc.HTTP10DefaultHost).
+				ServerHeader(envoy_v3.ServerHeaderTransformation(lvc.ServerHeader.Transformation), lvc.ServerHeader.ServerName).
+				Tracing(envoy_v3.Tracing(lvc.Tracing)).
+				Get())
+		}
+	}
+
+	if lv.http || lv.healthCheckFilter != nil {
+		// Add a listener if there are vhosts bound to http, or
+		// health checks to answer on it.
+		cm := httpFilters(ENVOY_HTTP_LISTENER, lvc.ScopedRoutes)
 
 		lv.listeners[ENVOY_HTTP_LISTENER] = envoy_v3.Listener(
 			ENVOY_HTTP_LISTENER,
 			lvc.httpAddress(),
 			lvc.httpPort(),
 			proxyProtocol(lvc.UseProxyProto),
-			cm,
+			cm(false)...,
+		)
+
+		if fc := lvc.trustedProxyFilterChain(lv.listeners[ENVOY_HTTP_LISTENER].FilterChains[0], cm); fc != nil {
+			lv.listeners[ENVOY_HTTP_LISTENER].FilterChains = append(lv.listeners[ENVOY_HTTP_LISTENER].FilterChains, fc)
+		}
+	}
+
+	// Add the additional HTTP listeners that vhosts are bound to.
+	for _, hl := range lvc.HTTPListeners {
+		if !lv.httpNamed[hl.Name] {
+			continue
+		}
+
+		cm := httpFilters(hl.Name, false)
+
+		address := hl.Address
+		if address == "" {
+			address = DEFAULT_HTTP_LISTENER_ADDRESS
+		}
+
+		lv.listeners[hl.Name] = envoy_v3.Listener(
+			hl.Name,
+			address,
+			hl.Port,
+			proxyProtocol(lvc.UseProxyProto),
+			cm(false)...,
 		)
+
+		if fc := lvc.trustedProxyFilterChain(lv.listeners[hl.Name].FilterChains[0], cm); fc != nil {
+			lv.listeners[hl.Name].FilterChains = append(lv.listeners[hl.Name].FilterChains, fc)
+		}
 	}
 
 	// Remove the https listener if there are no vhosts bound to it.
@@ -347,9 +711,34 @@ func visitListeners(root dag.Vertex, lvc *ListenerConfig) map[string]*envoy_list
 		sort.Stable(sorter.For(lv.listeners[ENVOY_HTTPS_LISTENER].FilterChains))
 	}
 
+	for name, l := range lv.listeners {
+		l.PerConnectionBufferLimitBytes = protobuf.UInt32OrNil(lvc.PerConnectionBufferLimitBytes)
+		l.SocketOptions = append(l.SocketOptions, lvc.socketOptions(name)...)
+		l.DrainType = envoy_v3.ListenerDrainType(lvc.DrainType)
+	}
+
 	return lv.listeners
 }
 
+// socketOptions returns the configured socket
+// options that apply to the named listener.
+func (lvc *ListenerConfig) socketOptions(listener string) []*envoy_core_v3.SocketOption {
+	var opts []*envoy_core_v3.SocketOption
+	for _, o := range lvc.SocketOptions {
+		if len(o.Listeners) == 0 {
+			opts = append(opts, envoy_v3.SocketOption(o))
+			continue
+		}
+		for _, l := range o.Listeners {
+			if l == listener {
+				opts = append(opts, envoy_v3.SocketOption(o))
+				break
+			}
+		}
+	}
+	return opts
+}
+
 func proxyProtocol(useProxy bool) []*envoy_listener_v3.ListenerFilter {
 	if useProxy {
 		return envoy_v3.ListenerFilters(
@@ -372,6 +761,19 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 	}
 
 	switch vh := vertex.(type) {
+	case *dag.Listener:
+		// The virtual hosts of additional HTTP listeners
+		// have a route configuration of their own.
+		if vh.Name != "" {
+			v.httpNamed[vh.Name] = true
+			return
+		}
+
+		// The session ticket keys apply to every TLS
+		// virtual host on the listener.
+		v.sessionTicketKeys = vh.SessionTicketKeys
+		vertex.Visit(v.visit)
+		v.sessionTicketKeys = nil
 	case *dag.VirtualHost:
 		// we only create on http listener so record the fact
 		// that we need to then double back at the end and add
@@ -381,6 +783,10 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 		var alpnProtos []string
 		var filters []*envoy_listener_v3.Filter
 
+		// httpFilters builds the filters of HTTPS virtual hosts for
+		// connections from trusted proxies, or from anywhere else.
+		var httpFilters func(trusted bool) []*envoy_listener_v3.Filter
+
 		if vh.TCPProxy == nil {
 			var authFilter *http.HttpFilter
 
@@ -392,6 +798,12 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 				)
 			}
 
+			// The HTTP versions, and hence the codec and ALPN
+			// protocols, of each TLS virtual host may differ
+			// from the listener defaults.
+			var versions []envoy_v3.HTTPVersionType
+			versions, alpnProtos = v.ListenerConfig.httpsProtocols(vh.ALPNProtocols)
+
 			// Create a uniquely named HTTP connection manager for
 			// this vhost, so that the SNI name the client requests
 			// only grants access to that host. See RFC 6066 for
@@ -399,24 +811,46 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 			// metrics prefix to keep compatibility with previous
 			// Contour versions since the metrics prefix will be
 			// coded into monitoring dashboards.
-			filters = envoy_v3.Filters(
-				envoy_v3.HTTPConnectionManagerBuilder().
-					Codec(envoy_v3.CodecForVersions(v.DefaultHTTPVersions...)).
-					AddFilter(envoy_v3.FilterMisdirectedRequests(vh.VirtualHost.Name)).
-					DefaultFilters().
-					AddFilter(authFilter).
-					RouteConfigName(path.Join("https", vh.VirtualHost.Name)).
-					MetricsPrefix(ENVOY_HTTPS_LISTENER).
-					AccessLoggers(v.ListenerConfig.newSecureAccessLog()).
-					RequestTimeout(v.ListenerConfig.RequestTimeout).
-					ConnectionIdleTimeout(v.ListenerConfig.ConnectionIdleTimeout).
-					StreamIdleTimeout(v.ListenerConfig.StreamIdleTimeout).
-					MaxConnectionDuration(v.ListenerConfig.MaxConnectionDuration).
-					ConnectionShutdownGracePeriod(v.ListenerConfig.ConnectionShutdownGracePeriod).
-					Get(),
-			)
+			httpFilters = func(trusted bool) []*envoy_listener_v3.Filter {
+				return envoy_v3.Filters(
+					envoy_v3.HTTPConnectionManagerBuilder().
+						Codec(envoy_v3.CodecForVersions(versions...)).
+						AddFilter(v.ListenerConfig.forwardedForFilter(trusted)).
+						AddFilter(v.originalDestinationFilter(trusted)).
+						AddFilter(v.healthCheckFilter).
+						AddFilter(envoy_v3.FilterMisdirectedRequests(vh.VirtualHost.Name)).
+						AddFilter(v.rbacFilter).
+						AddFilter(v.faultFilter).
+						AddFilter(v.timeoutHeaderFilter).
+						DefaultFilters().
+						AddFilter(authFilter).
+						AddFilter(envoy_v3.GlobalRateLimitFilter(v.ListenerConfig.globalRateLimitConfig())).
+						AddFilter(v.bufferFilter).
+						AddFilter(v.cacheScopeFilter).
+						AddFilter(v.cacheFilter).
+						AddFilter(v.cacheControlFilter).
+						AddFilter(envoy_v3.FilterGRPCJSONTranscoder(vh.GRPCJSONTranscoder)).
+						RouteConfigName(path.Join("https", vh.VirtualHost.Name)).
+						MetricsPrefix(ENVOY_HTTPS_LISTENER).
+						AccessLoggers(v.ListenerConfig.newSecureHTTPAccessLog()).
+						RequestTimeout(v.ListenerConfig.RequestTimeout).
+						ConnectionIdleTimeout(v.ListenerConfig.ConnectionIdleTimeout).
+						StreamIdleTimeout(v.ListenerConfig.StreamIdleTimeout).
+						MaxConnectionDuration(v.ListenerConfig.MaxConnectionDuration).
+						ConnectionShutdownGracePeriod(v.ListenerConfig.ConnectionShutdownGracePeriod).
+						ErrorPages(v.ListenerConfig.ErrorPages).
+						RateLimitRetryAfter(v.ListenerConfig.rateLimitRetryAfter()).
+						NumTrustedHops(v.ListenerConfig.numTrustedHops(trusted)).
+						RequestHeaderLimits(v.ListenerConfig.MaxRequestHeadersKB, v.ListenerConfig.MaxRequestHeadersCount).
+						StripMatchingHostPort(v.ListenerConfig.StripMatchingHostPort).
+						HTTP10(v.ListenerConfig.DisableHTTP10, v.ListenerConfig.HTTP10DefaultHost).
+						ServerHeader(envoy_v3.ServerHeaderTransformation(v.ListenerConfig.ServerHeader.Transformation), v.ListenerConfig.ServerHeader.ServerName).
+						Tracing(envoy_v3.Tracing(v.ListenerConfig.Tracing)).
+						Get(),
+				)
+			}
+			filters = httpFilters(false)
 
-			alpnProtos = envoy_v3.ProtoNamesForVersions(v.DefaultHTTPVersions...)
 		} else {
 			filters = envoy_v3.Filters(
 				envoy_v3.TCPProxy(ENVOY_HTTPS_LISTENER,
@@ -441,10 +875,29 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 				vers,
 				vh.DownstreamValidation,
 				alpnProtos...)
+
+			// Envoy selects between certificates with different
+			// key types based on what the client supports.
+			if vh.SecondarySecret != nil {
+				downstreamTLS.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(
+					downstreamTLS.CommonTlsContext.TlsCertificateSdsSecretConfigs,
+					envoy_v3.TLSCertificateSdsSecretConfig(vh.SecondarySecret))
+			}
+
+			downstreamTLS.OcspStaplePolicy = envoy_v3.OCSPStaplePolicy(vh.OCSPStaplePolicy)
+
+			if v.sessionTicketKeys != nil {
+				downstreamTLS.SessionTicketKeysType = envoy_v3.SessionTicketKeysSdsSecretConfig(v.sessionTicketKeys)
+			}
 		}
 
-		v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains,
-			envoy_v3.FilterChainTLS(vh.VirtualHost.Name, downstreamTLS, filters))
+		fc := envoy_v3.FilterChainTLS(vh.VirtualHost.Name, downstreamTLS, filters)
+		v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains, fc)
+		if httpFilters != nil {
+			if trustedFC := v.ListenerConfig.trustedProxyFilterChain(fc, httpFilters); trustedFC != nil {
+				v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains, trustedFC)
+			}
+		}
 
 		// If this VirtualHost has enabled the fallback certificate then set a default
 		// FilterChain which will allow routes with this vhost to accept non-SNI TLS requests.
@@ -454,29 +907,58 @@ func (v *listenerVisitor) visit(vertex dag.Vertex) {
 		if vh.FallbackCertificate != nil && !envoy_v3.ContainsFallbackFilterChain(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains) {
 			// Construct the downstreamTLSContext passing the configured fallbackCertificate. The TLS minProtocolVersion will use
 			// the value defined in the Contour Configuration file if defined.
+			_, fallbackProtos := v.ListenerConfig.httpsProtocols(nil)
 			downstreamTLS = envoy_v3.DownstreamTLSContext(
 				vh.FallbackCertificate,
 				v.ListenerConfig.minTLSVersion(),
 				vh.DownstreamValidation,
-				alpnProtos...)
+				fallbackProtos...)
+
+			if v.sessionTicketKeys != nil {
+				downstreamTLS.SessionTicketKeysType = envoy_v3.SessionTicketKeysSdsSecretConfig(v.sessionTicketKeys)
+			}
 
 			// Default filter chain
-			filters = envoy_v3.Filters(
-				envoy_v3.HTTPConnectionManagerBuilder().
-					DefaultFilters().
-					RouteConfigName(ENVOY_FALLBACK_ROUTECONFIG).
-					MetricsPrefix(ENVOY_HTTPS_LISTENER).
-					AccessLoggers(v.ListenerConfig.newSecureAccessLog()).
-					RequestTimeout(v.ListenerConfig.RequestTimeout).
-					ConnectionIdleTimeout(v.ListenerConfig.ConnectionIdleTimeout).
-					StreamIdleTimeout(v.ListenerConfig.StreamIdleTimeout).
-					MaxConnectionDuration(v.ListenerConfig.MaxConnectionDuration).
-					ConnectionShutdownGracePeriod(v.ListenerConfig.ConnectionShutdownGracePeriod).
-					Get(),
-			)
+			fallbackFilters := func(trusted bool) []*envoy_listener_v3.Filter {
+				return envoy_v3.Filters(
+					envoy_v3.HTTPConnectionManagerBuilder().
+						AddFilter(v.ListenerConfig.forwardedForFilter(trusted)).
+						AddFilter(v.originalDestinationFilter(trusted)).
+						AddFilter(v.healthCheckFilter).
+						DefaultFilters().
+						AddFilter(v.rbacFilter).
+						AddFilter(v.faultFilter).
+						AddFilter(v.timeoutHeaderFilter).
+						AddFilter(envoy_v3.GlobalRateLimitFilter(v.ListenerConfig.globalRateLimitConfig())).
+						AddFilter(v.bufferFilter).
+						AddFilter(v.cacheScopeFilter).
+						AddFilter(v.cacheFilter).
+						AddFilter(v.cacheControlFilter).
+						RouteConfigName(ENVOY_FALLBACK_ROUTECONFIG).
+						MetricsPrefix(ENVOY_HTTPS_LISTENER).
+						AccessLoggers(v.ListenerConfig.newSecureHTTPAccessLog()).
+						RequestTimeout(v.ListenerConfig.RequestTimeout).
+						ConnectionIdleTimeout(v.ListenerConfig.ConnectionIdleTimeout).
+						StreamIdleTimeout(v.ListenerConfig.StreamIdleTimeout).
+						MaxConnectionDuration(v.ListenerConfig.MaxConnectionDuration).
+						ConnectionShutdownGracePeriod(v.ListenerConfig.ConnectionShutdownGracePeriod).
+						ErrorPages(v.ListenerConfig.ErrorPages).
+						RateLimitRetryAfter(v.ListenerConfig.rateLimitRetryAfter()).
+						NumTrustedHops(v.ListenerConfig.numTrustedHops(trusted)).
+						RequestHeaderLimits(v.ListenerConfig.MaxRequestHeadersKB, v.ListenerConfig.MaxRequestHeadersCount).
+						StripMatchingHostPort(v.ListenerConfig.StripMatchingHostPort).
+						HTTP10(v.ListenerConfig.DisableHTTP10, v.ListenerConfig.HTTP10DefaultHost).
+						ServerHeader(envoy_v3.ServerHeaderTransformation(v.ListenerConfig.ServerHeader.Transformation), v.ListenerConfig.ServerHeader.ServerName).
+						Tracing(envoy_v3.Tracing(v.ListenerConfig.Tracing)).
+						Get(),
+				)
+			}
 
-			v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains,
-				envoy_v3.FilterChainTLSFallback(downstreamTLS, filters))
+			fallbackFC := envoy_v3.FilterChainTLSFallback(downstreamTLS, fallbackFilters(false))
+			v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains, fallbackFC)
+			if trustedFC := v.ListenerConfig.trustedProxyFilterChain(fallbackFC, fallbackFilters); trustedFC != nil {
+				v.listeners[ENVOY_HTTPS_LISTENER].FilterChains = append(v.listeners[ENVOY_HTTPS_LISTENER].FilterChains, trustedFC)
+			}
 		}
 
 	default:
@@ -14,10 +14,14 @@
 package v3
 
 import (
+	"net/http"
 	"path"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
 
+	envoy_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/golang/protobuf/proto"
@@ -31,11 +35,61 @@ import (
 
 // RouteCache manages the contents of the gRPC RDS cache.
 type RouteCache struct {
+	// HealthCheckPaths are the request paths of health
+	// checks, which bypass external authorization and
+	// global rate limiting.
+	HealthCheckPaths []string
+
+	// ScopedRoutes puts each virtual host of the HTTP listener
+	// in its own route configuration, which is selected by a
+	// scoped route configuration from the ScopedRouteCache.
+	ScopedRoutes bool
+
+	// Maintenance optionally answers every request to some
+	// or all virtual hosts with a static response.
+	Maintenance *Maintenance
+
 	mu     sync.Mutex
 	values map[string]*envoy_route_v3.RouteConfiguration
 	contour.Cond
 }
 
+// Maintenance configures the static response that answers every
+// request to the virtual hosts under maintenance.
+type Maintenance struct {
+	// Hosts are the names of the virtual hosts under maintenance.
+	// If empty, every virtual host is under maintenance.
+	Hosts []string
+
+	// StatusCode is the status code of the response.
+	StatusCode uint32
+
+	// Body is the optional body of the response.
+	Body string
+
+	// ContentType is the optional content type of Body.
+	ContentType string
+}
+
+// covers returns true if the named virtual host is under maintenance.
+func (m *Maintenance) covers(host string) bool {
+	if m == nil {
+		return false
+	}
+
+	if len(m.Hosts) == 0 {
+		return true
+	}
+
+	for _, h := range m.Hosts {
+		if h == host {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Update replaces the contents of the cache with the supplied map.
 func (c *RouteCache) Update(v map[string]*envoy_route_v3.RouteConfiguration) {
 	c.mu.Lock()
@@ -89,15 +143,45 @@ func (c *RouteCache) Query(names []string) []proto.Message {
 func (*RouteCache) TypeURL() string { return resource.RouteType }
 
 func (c *RouteCache) OnChange(root *dag.DAG) {
-	routes := visitRoutes(root)
+	routes := visitRoutes(root, c.HealthCheckPaths, c.ScopedRoutes, c.Maintenance)
 	c.Update(routes)
 }
 
 type routeVisitor struct {
 	routes map[string]*envoy_route_v3.RouteConfiguration
+
+	// bufferRequests is set if the buffer filter is present
+	// on the connection managers, in which case every route
+	// must either configure or disable it.
+	bufferRequests bool
+
+	// healthCheckPaths are exempt from authorization
+	// and rate limiting.
+	healthCheckPaths []string
+
+	// scopedRoutes is set if each virtual host of the
+	// HTTP listener has its own route configuration.
+	scopedRoutes bool
+
+	// maintenance is the optional static response for
+	// virtual hosts under maintenance.
+	maintenance *Maintenance
+
+	// perFilterConfigs produce the per-route configs of
+	// the HTTP filters on the connection managers.
+	perFilterConfigs []perFilterConfig
 }
 
-func visitRoutes(root dag.Vertex) map[string]*envoy_route_v3.RouteConfiguration {
+// perFilterConfig produces the per-route config of the named HTTP
+// filter. config returns nil if the route needs no config for the
+// filter; authorized is set if the route's virtual host has an
+// authorization service.
+type perFilterConfig struct {
+	filter string
+	config func(route *dag.Route, authorized bool) *any.Any
+}
+
+func visitRoutes(root dag.Vertex, healthCheckPaths []string, scopedRoutes bool, maintenance *Maintenance) map[string]*envoy_route_v3.RouteConfiguration {
 	// Collect the route configurations for all the routes we can
 	// find. For HTTP hosts, the routes will all be collected on the
 	// well-known ENVOY_HTTP_LISTENER, but for HTTPS hosts, we will
@@ -107,6 +191,21 @@ func visitRoutes(root dag.Vertex) map[string]*envoy_route_v3.RouteConfiguration
 		routes: map[string]*envoy_route_v3.RouteConfiguration{
 			ENVOY_HTTP_LISTENER: envoy_v3.RouteConfiguration(ENVOY_HTTP_LISTENER),
 		},
+		bufferRequests:   requestBodyLimited(root),
+		healthCheckPaths: healthCheckPaths,
+		scopedRoutes:     scopedRoutes,
+		maintenance:      maintenance,
+	}
+
+	if scopedRoutes {
+		delete(rv.routes, ENVOY_HTTP_LISTENER)
+	}
+
+	rv.perFilterConfigs = []perFilterConfig{
+		{filter: envoy_v3.ExtAuthzFilterName, config: authzConfig},
+		{filter: envoy_v3.BufferFilterName, config: rv.requestBodyLimitConfig},
+		{filter: envoy_v3.RBACFilterName, config: sourceRangesConfig},
+		{filter: envoy_v3.FaultFilterName, config: faultConfig},
 	}
 
 	rv.visit(root)
@@ -121,8 +220,8 @@ func visitRoutes(root dag.Vertex) map[string]*envoy_route_v3.RouteConfiguration
 func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 	var routes []*envoy_route_v3.Route
 
-	vh.Visit(func(v dag.Vertex) {
-		route, ok := v.(*dag.Route)
+	vh.Visit(func(vertex dag.Vertex) {
+		route, ok := vertex.(*dag.Route)
 		if !ok {
 			return
 		}
@@ -131,10 +230,12 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 			// TODO(dfc) if we ensure the builder never returns a dag.Route connected
 			// to a SecureVirtualHost that requires upgrade, this logic can move to
 			// envoy.RouteRoute.
-			routes = append(routes, &envoy_route_v3.Route{
+			rt := &envoy_route_v3.Route{
 				Match:  envoy_v3.RouteMatch(route),
 				Action: envoy_v3.UpgradeHTTPS(),
-			})
+			}
+			rt.TypedPerFilterConfig = v.typedPerFilterConfig(route, false)
+			routes = append(routes, rt)
 		} else {
 			rt := &envoy_route_v3.Route{
 				Match:  envoy_v3.RouteMatch(route),
@@ -148,12 +249,22 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 				rt.ResponseHeadersToAdd = envoy_v3.HeaderValueList(route.ResponseHeadersPolicy.Set, false)
 				rt.ResponseHeadersToRemove = route.ResponseHeadersPolicy.Remove
 			}
+			rt.TypedPerFilterConfig = v.typedPerFilterConfig(route, false)
+			rt.Metadata = routeMetadata(route)
 			routes = append(routes, rt)
 		}
 	})
 
 	if len(routes) > 0 {
+		switch {
+		case vh.Disabled:
+			routes = v.disabledRoutes(false)
+		case v.maintenance.covers(vh.Name):
+			routes = v.maintenanceRoutes(false)
+		}
+
 		sortRoutes(routes)
+		routes = v.healthCheckRoutes(routes, false)
 
 		var evh *envoy_route_v3.VirtualHost
 		if cp := envoy_v3.CORSPolicy(vh.CORSPolicy); cp != nil {
@@ -162,6 +273,19 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 			evh = envoy_v3.VirtualHost(vh.Name, routes...)
 		}
 
+		// Virtual hosts of additional HTTP listeners are
+		// served in the route configuration of their listener.
+		if vh.Listener != "" {
+			v.routes[vh.Listener].VirtualHosts = append(v.routes[vh.Listener].VirtualHosts, evh)
+			return
+		}
+
+		if v.scopedRoutes {
+			name := scopedRouteConfigName(vh.Name)
+			v.routes[name] = envoy_v3.RouteConfiguration(name, evh)
+			return
+		}
+
 		v.routes[ENVOY_HTTP_LISTENER].VirtualHosts = append(v.routes[ENVOY_HTTP_LISTENER].VirtualHosts, evh)
 	}
 }
@@ -169,8 +293,8 @@ func (v *routeVisitor) onVirtualHost(vh *dag.VirtualHost) {
 func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 	var routes []*envoy_route_v3.Route
 
-	svh.Visit(func(v dag.Vertex) {
-		route, ok := v.(*dag.Route)
+	svh.Visit(func(vertex dag.Vertex) {
+		route, ok := vertex.(*dag.Route)
 		if !ok {
 			return
 		}
@@ -187,28 +311,21 @@ func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 			rt.ResponseHeadersToAdd = envoy_v3.HeaderValueList(route.ResponseHeadersPolicy.Set, false)
 			rt.ResponseHeadersToRemove = route.ResponseHeadersPolicy.Remove
 		}
-
-		// If authorization is enabled on this host, we may need to set per-route filter overrides.
-		if svh.AuthorizationService != nil {
-			// Apply per-route authorization policy modifications.
-			if route.AuthDisabled {
-				rt.TypedPerFilterConfig = map[string]*any.Any{
-					"envoy.filters.http.ext_authz": envoy_v3.RouteAuthzDisabled(),
-				}
-			} else {
-				if len(route.AuthContext) > 0 {
-					rt.TypedPerFilterConfig = map[string]*any.Any{
-						"envoy.filters.http.ext_authz": envoy_v3.RouteAuthzContext(route.AuthContext),
-					}
-				}
-			}
-		}
-
+		rt.TypedPerFilterConfig = v.typedPerFilterConfig(route, svh.AuthorizationService != nil)
+		rt.Metadata = routeMetadata(route)
 		routes = append(routes, rt)
 	})
 
 	if len(routes) > 0 {
+		switch {
+		case svh.Disabled:
+			routes = v.disabledRoutes(svh.AuthorizationService != nil)
+		case v.maintenance.covers(svh.Name):
+			routes = v.maintenanceRoutes(svh.AuthorizationService != nil)
+		}
+
 		sortRoutes(routes)
+		routes = v.healthCheckRoutes(routes, svh.AuthorizationService != nil)
 
 		name := path.Join("https", svh.VirtualHost.Name)
 
@@ -222,6 +339,7 @@ func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 		} else {
 			evh = envoy_v3.VirtualHost(svh.VirtualHost.Name, routes...)
 		}
+		evh.ResponseHeadersToAdd = envoy_v3.StrictTransportSecurity(svh.HSTSPolicy)
 
 		v.routes[name].VirtualHosts = append(v.routes[name].VirtualHosts, evh)
 
@@ -240,15 +358,339 @@ func (v *routeVisitor) onSecureVirtualHost(svh *dag.SecureVirtualHost) {
 			} else {
 				fvh = envoy_v3.VirtualHost(svh.Name, routes...)
 			}
+			fvh.ResponseHeadersToAdd = envoy_v3.StrictTransportSecurity(svh.HSTSPolicy)
 
 			v.routes[ENVOY_FALLBACK_ROUTECONFIG].VirtualHosts = append(v.routes[ENVOY_FALLBACK_ROUTECONFIG].VirtualHosts, fvh)
 		}
 	}
 }
 
+// healthCheckRoutes prepends an exact match route for each health
+// check path to the sorted routes, which bypasses authorization and
+// global rate limiting. The route for a path is a copy of each route
+// that may match it, in order, so its requests are routed as before.
+// If none of those routes are authorized or rate limited, the path
+// needs no route of its own.
+func (v *routeVisitor) healthCheckRoutes(routes []*envoy_route_v3.Route, authorized bool) []*envoy_route_v3.Route {
+	var exempt []*envoy_route_v3.Route
+
+	for _, p := range v.healthCheckPaths {
+		var copies []*envoy_route_v3.Route
+		changed := false
+
+		for _, rt := range routes {
+			if !routeMatchesPath(rt.Match, p) {
+				continue
+			}
+
+			c := proto.Clone(rt).(*envoy_route_v3.Route)
+			c.Match.PathSpecifier = &envoy_route_v3.RouteMatch_Path{Path: p}
+
+			if ra := c.GetRoute(); ra != nil && len(ra.RateLimits) > 0 {
+				ra.RateLimits = nil
+				changed = true
+			}
+
+			authzDisabled := envoy_v3.RouteAuthzDisabled()
+			if authorized && !proto.Equal(c.TypedPerFilterConfig[envoy_v3.ExtAuthzFilterName], authzDisabled) {
+				if c.TypedPerFilterConfig == nil {
+					c.TypedPerFilterConfig = map[string]*any.Any{}
+				}
+				c.TypedPerFilterConfig[envoy_v3.ExtAuthzFilterName] = authzDisabled
+				changed = true
+			}
+
+			copies = append(copies, c)
+
+			// Routes after one without header or query
+			// conditions are never used for this path.
+			if len(rt.Match.Headers) == 0 && len(rt.Match.QueryParameters) == 0 {
+				break
+			}
+		}
+
+		if changed {
+			exempt = append(exempt, copies...)
+		}
+	}
+
+	return append(exempt, routes...)
+}
+
+// routeMatchesPath returns true if the path condition of match
+// matches the request path p.
+func routeMatchesPath(match *envoy_route_v3.RouteMatch, p string) bool {
+	switch m := match.PathSpecifier.(type) {
+	case *envoy_route_v3.RouteMatch_Prefix:
+		return strings.HasPrefix(p, m.Prefix)
+	case *envoy_route_v3.RouteMatch_Path:
+		return m.Path == p
+	case *envoy_route_v3.RouteMatch_SafeRegex:
+		re, err := regexp.Compile(`^(?:` + m.SafeRegex.GetRegex() + `)$`)
+		return err == nil && re.MatchString(p)
+	default:
+		return false
+	}
+}
+
+// disabledRoutes returns the routes for a disabled virtual host,
+// which answer every request with a 503 without contacting any
+// upstream or authorization server.
+func (v *routeVisitor) disabledRoutes(authorized bool) []*envoy_route_v3.Route {
+	return v.directResponseRoutes(envoy_v3.DirectResponse(http.StatusServiceUnavailable), authorized)
+}
+
+// maintenanceRoutes returns the routes for a virtual host under
+// maintenance, which answer every request with the maintenance
+// response without contacting any upstream or authorization server.
+func (v *routeVisitor) maintenanceRoutes(authorized bool) []*envoy_route_v3.Route {
+	m := v.maintenance
+	routes := v.directResponseRoutes(envoy_v3.DirectResponseBody(m.StatusCode, m.Body), authorized)
+
+	if m.ContentType != "" {
+		routes[0].ResponseHeadersToAdd = envoy_v3.HeaderValueList(map[string]string{
+			"Content-Type": m.ContentType,
+		}, false)
+	}
+
+	return routes
+}
+
+// directResponseRoutes returns a single route that answers every
+// request with the given direct response action.
+func (v *routeVisitor) directResponseRoutes(action *envoy_route_v3.Route_DirectResponse, authorized bool) []*envoy_route_v3.Route {
+	route := &dag.Route{
+		PathMatchCondition: &dag.PrefixMatchCondition{Prefix: "/"},
+		AuthDisabled:       true,
+	}
+
+	rt := &envoy_route_v3.Route{
+		Match:                envoy_v3.RouteMatch(route),
+		Action:               action,
+		TypedPerFilterConfig: v.typedPerFilterConfig(route, authorized),
+	}
+
+	return []*envoy_route_v3.Route{rt}
+}
+
+// routeMetadata returns the route metadata read by the Lua filters
+// for route, or nil if none of them needs any.
+func routeMetadata(route *dag.Route) *envoy_core_v3.Metadata {
+	return envoy_v3.LuaMetadata(
+		envoy_v3.RouteResponseTimeoutHeader(route.ResponseTimeoutHeader),
+		envoy_v3.RouteCacheTTL(route.CacheTTL),
+	)
+}
+
+// typedPerFilterConfig returns the per-route configs of the HTTP
+// filters for route, keyed by filter name, or nil if no filter
+// needs a config for the route.
+func (v *routeVisitor) typedPerFilterConfig(route *dag.Route, authorized bool) map[string]*any.Any {
+	var configs map[string]*any.Any
+
+	for _, f := range v.perFilterConfigs {
+		c := f.config(route, authorized)
+		if c == nil {
+			continue
+		}
+
+		if configs == nil {
+			configs = map[string]*any.Any{}
+		}
+		configs[f.filter] = c
+	}
+
+	return configs
+}
+
+// authzConfig returns the ext_authz filter config of a route on
+// an authorized virtual host that disables authorization or adds
+// to its context.
+func authzConfig(route *dag.Route, authorized bool) *any.Any {
+	switch {
+	case !authorized:
+		return nil
+	case route.AuthDisabled:
+		return envoy_v3.RouteAuthzDisabled()
+	case len(route.AuthContext) > 0:
+		return envoy_v3.RouteAuthzContext(route.AuthContext)
+	default:
+		return nil
+	}
+}
+
+// requestBodyLimitConfig returns the buffer filter config of a route.
+// Routes that do not limit their request bodies disable the filter so
+// that their requests are streamed to the upstream unbuffered.
+func (v *routeVisitor) requestBodyLimitConfig(route *dag.Route, _ bool) *any.Any {
+	switch {
+	case !v.bufferRequests:
+		return nil
+	case route.MaxRequestBodyBytes > 0:
+		return envoy_v3.RouteBuffer(route.MaxRequestBodyBytes)
+	default:
+		return envoy_v3.RouteBufferDisabled()
+	}
+}
+
+// sourceRangesConfig returns the rbac filter config of a route
+// that only allows requests from some source ranges.
+func sourceRangesConfig(route *dag.Route, _ bool) *any.Any {
+	if len(route.AllowedSourceRanges) == 0 {
+		return nil
+	}
+
+	return envoy_v3.RouteSourceRanges(route.AllowedSourceRanges)
+}
+
+// faultConfig returns the fault filter config of a route
+// that has a fault policy.
+func faultConfig(route *dag.Route, _ bool) *any.Any {
+	if route.FaultPolicy == nil {
+		return nil
+	}
+
+	return envoy_v3.RouteFault(route.FaultPolicy)
+}
+
+// requestBodyLimited returns true if any route in the DAG limits
+// the size of its request bodies.
+func requestBodyLimited(root dag.Vertex) bool {
+	limited := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.MaxRequestBodyBytes > 0 {
+			limited = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return limited
+}
+
+// responsesCached returns true if any route in the DAG
+// has a cache policy.
+func responsesCached(root dag.Vertex) bool {
+	cached := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.CacheTTL > 0 {
+			cached = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return cached
+}
+
+// originalDestinations returns true if any route in the DAG
+// forwards requests to their original destination.
+func originalDestinations(root dag.Vertex) bool {
+	found := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.OriginalDestination != nil {
+			found = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return found
+}
+
+// sourceRangesRestricted returns true if any route in the DAG
+// only allows requests from some source ranges.
+func sourceRangesRestricted(root dag.Vertex) bool {
+	restricted := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && len(route.AllowedSourceRanges) > 0 {
+			restricted = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return restricted
+}
+
+// faultsInjected returns true if any route in the DAG
+// has a fault policy.
+func faultsInjected(root dag.Vertex) bool {
+	injected := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.FaultPolicy != nil {
+			injected = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return injected
+}
+
+// responseTimeoutHeaders returns true if any route in the DAG
+// has a response timeout header.
+func responseTimeoutHeaders(root dag.Vertex) bool {
+	found := false
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.ResponseTimeoutHeader != "" {
+			found = true
+			return
+		}
+		vertex.Visit(visit)
+	}
+	visit(root)
+
+	return found
+}
+
+// forwardProxyOf returns the first dag.ForwardProxy found in
+// the DAG, or nil if no route is a forward proxy.
+func forwardProxyOf(root dag.Vertex) *dag.ForwardProxy {
+	var fp *dag.ForwardProxy
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		if route, ok := vertex.(*dag.Route); ok && route.ForwardProxy != nil {
+			fp = route.ForwardProxy
+			return
+		}
+		if fp == nil {
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+
+	return fp
+}
+
 func (v *routeVisitor) visit(vertex dag.Vertex) {
 	switch l := vertex.(type) {
 	case *dag.Listener:
+		// Additional HTTP listeners fetch their route
+		// configuration even if none of their virtual
+		// hosts have routes.
+		if l.Name != "" {
+			v.routes[l.Name] = envoy_v3.RouteConfiguration(l.Name)
+		}
+
 		l.Visit(func(vertex dag.Vertex) {
 			switch vh := vertex.(type) {
 			case *dag.VirtualHost:
@@ -21,6 +21,7 @@ import (
 	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	contour_api_v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"github.com/projectcontour/contour/internal/dag"
@@ -2824,7 +2825,192 @@ func TestRouteVisit(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			root := buildDAGFallback(t, tc.fallbackCertificate, tc.objs...)
-			got := visitRoutes(root)
+			got := visitRoutes(root, nil, false, nil)
+			protobuf.ExpectEqual(t, tc.want, got)
+		})
+	}
+}
+
+func TestRouteVisitScopedRoutes(t *testing.T) {
+	root := buildDAG(t, scopedRoutesObjects()...)
+	got := visitRoutes(root, nil, true, nil)
+
+	want := routeConfigurations(
+		envoy_v3.RouteConfiguration("ingress_http/a.example.com",
+			envoy_v3.VirtualHost("a.example.com",
+				&envoy_route_v3.Route{
+					Match:  routePrefix("/"),
+					Action: routecluster("default/kuard/8080/da39a3ee5e"),
+				},
+			),
+		),
+		envoy_v3.RouteConfiguration("ingress_http/b.example.com",
+			envoy_v3.VirtualHost("b.example.com",
+				&envoy_route_v3.Route{
+					Match:  routePrefix("/"),
+					Action: routecluster("default/kuard/8080/da39a3ee5e"),
+				},
+			),
+		),
+	)
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+func TestRouteVisitMaintenance(t *testing.T) {
+	root := buildDAG(t, scopedRoutesObjects()...)
+	got := visitRoutes(root, nil, false, &Maintenance{
+		Hosts:       []string{"a.example.com"},
+		StatusCode:  503,
+		Body:        "down for maintenance",
+		ContentType: "text/plain",
+	})
+
+	want := routeConfigurations(
+		envoy_v3.RouteConfiguration("ingress_http",
+			envoy_v3.VirtualHost("a.example.com",
+				&envoy_route_v3.Route{
+					Match:  routePrefix("/"),
+					Action: envoy_v3.DirectResponseBody(503, "down for maintenance"),
+					ResponseHeadersToAdd: envoy_v3.HeaderValueList(map[string]string{
+						"Content-Type": "text/plain",
+					}, false),
+				},
+			),
+			envoy_v3.VirtualHost("b.example.com",
+				&envoy_route_v3.Route{
+					Match:  routePrefix("/"),
+					Action: routecluster("default/kuard/8080/da39a3ee5e"),
+				},
+			),
+		),
+	)
+
+	protobuf.ExpectEqual(t, want, got)
+}
+
+// scopedRoutesObjects returns an Ingress with rules for two hosts
+// and its backend Service.
+func scopedRoutesObjects() []interface{} {
+	rule := func(host string) v1beta1.IngressRule {
+		return v1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: v1beta1.IngressRuleValue{
+				HTTP: &v1beta1.HTTPIngressRuleValue{
+					Paths: []v1beta1.HTTPIngressPath{{
+						Backend: *backend("kuard", 8080),
+					}},
+				},
+			},
+		}
+	}
+
+	return []interface{}{
+		&v1beta1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+			Spec: v1beta1.IngressSpec{
+				Rules: []v1beta1.IngressRule{
+					rule("a.example.com"),
+					rule("b.example.com"),
+				},
+			},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kuard",
+				Namespace: "default",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{
+					Protocol:   "TCP",
+					Port:       8080,
+					TargetPort: intstr.FromInt(8080),
+				}},
+			},
+		},
+	}
+}
+
+func TestHealthCheckRoutes(t *testing.T) {
+	limited := func(match *envoy_route_v3.RouteMatch, cluster string) *envoy_route_v3.Route {
+		return &envoy_route_v3.Route{
+			Match: match,
+			Action: &envoy_route_v3.Route_Route{
+				Route: &envoy_route_v3.RouteAction{
+					ClusterSpecifier: &envoy_route_v3.RouteAction_Cluster{Cluster: cluster},
+					RateLimits: []*envoy_route_v3.RateLimit{{
+						Actions: []*envoy_route_v3.RateLimit_Action{{
+							ActionSpecifier: &envoy_route_v3.RateLimit_Action_RemoteAddress_{
+								RemoteAddress: &envoy_route_v3.RateLimit_Action_RemoteAddress{},
+							},
+						}},
+					}},
+				},
+			},
+		}
+	}
+	authzDisabled := map[string]*any.Any{
+		"envoy.filters.http.ext_authz": envoy_v3.RouteAuthzDisabled(),
+	}
+	headerMatch := dag.HeaderMatchCondition{Name: "x-canary", MatchType: "present"}
+
+	tests := map[string]struct {
+		routes     []*envoy_route_v3.Route
+		authorized bool
+		want       []*envoy_route_v3.Route
+	}{
+		"unrestricted routes are unchanged": {
+			routes: []*envoy_route_v3.Route{
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e")},
+			},
+			want: []*envoy_route_v3.Route{
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e")},
+			},
+		},
+		"rate limits are removed": {
+			routes: []*envoy_route_v3.Route{
+				limited(routePrefix("/api"), "default/api/80/da39a3ee5e"),
+				limited(routePrefix("/"), "default/app/80/da39a3ee5e"),
+			},
+			want: []*envoy_route_v3.Route{
+				{Match: routeExact("/healthz"), Action: routecluster("default/app/80/da39a3ee5e")},
+				limited(routePrefix("/api"), "default/api/80/da39a3ee5e"),
+				limited(routePrefix("/"), "default/app/80/da39a3ee5e"),
+			},
+		},
+		"authorization is disabled on every route that may match": {
+			routes: []*envoy_route_v3.Route{
+				{Match: routePrefix("/", headerMatch), Action: routecluster("default/canary/80/da39a3ee5e")},
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e")},
+				{Match: routePrefix("/"), Action: routecluster("default/unreachable/80/da39a3ee5e")},
+			},
+			authorized: true,
+			want: []*envoy_route_v3.Route{
+				{Match: routeExact("/healthz", headerMatch), Action: routecluster("default/canary/80/da39a3ee5e"), TypedPerFilterConfig: authzDisabled},
+				{Match: routeExact("/healthz"), Action: routecluster("default/app/80/da39a3ee5e"), TypedPerFilterConfig: authzDisabled},
+				{Match: routePrefix("/", headerMatch), Action: routecluster("default/canary/80/da39a3ee5e")},
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e")},
+				{Match: routePrefix("/"), Action: routecluster("default/unreachable/80/da39a3ee5e")},
+			},
+		},
+		"routes with authorization already disabled are unchanged": {
+			routes: []*envoy_route_v3.Route{
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e"), TypedPerFilterConfig: authzDisabled},
+			},
+			authorized: true,
+			want: []*envoy_route_v3.Route{
+				{Match: routePrefix("/"), Action: routecluster("default/app/80/da39a3ee5e"), TypedPerFilterConfig: authzDisabled},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := routeVisitor{healthCheckPaths: []string{"/healthz"}}
+			got := v.healthCheckRoutes(tc.routes, tc.authorized)
 			protobuf.ExpectEqual(t, tc.want, got)
 		})
 	}
@@ -2847,6 +3033,26 @@ func TestSortLongestRouteFirst(t *testing.T) {
 				Match: routePrefix("/"),
 			}},
 		},
+		"prefixes sort longest first": {
+			routes: []*envoy_route_v3.Route{{
+				Match: routePrefix("/b"),
+			}, {
+				Match: routePrefix("/aa/"),
+			}, {
+				Match: routePrefix("/aaaa"),
+			}, {
+				Match: routePrefix("/bb/"),
+			}},
+			want: []*envoy_route_v3.Route{{
+				Match: routePrefix("/aaaa"),
+			}, {
+				Match: routePrefix("/bb/"),
+			}, {
+				Match: routePrefix("/aa/"),
+			}, {
+				Match: routePrefix("/b"),
+			}},
+		},
 		"two regexes": {
 			routes: []*envoy_route_v3.Route{{
 				Match: routeRegex("/v2"),
@@ -3050,6 +3256,15 @@ func routeRegex(regex string, headers ...dag.HeaderMatchCondition) *envoy_route_
 	})
 }
 
+func routeExact(path string, headers ...dag.HeaderMatchCondition) *envoy_route_v3.RouteMatch {
+	return envoy_v3.RouteMatch(&dag.Route{
+		PathMatchCondition: &dag.ExactMatchCondition{
+			Path: path,
+		},
+		HeaderMatchConditions: headers,
+	})
+}
+
 func routePrefix(prefix string, headers ...dag.HeaderMatchCondition) *envoy_route_v3.RouteMatch {
 	return envoy_v3.RouteMatch(&dag.Route{
 		PathMatchCondition: &dag.PrefixMatchCondition{
@@ -0,0 +1,130 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"path"
+	"sort"
+	"sync"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/projectcontour/contour/internal/contour"
+	"github.com/projectcontour/contour/internal/dag"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/protobuf"
+	"github.com/projectcontour/contour/internal/sorter"
+)
+
+// ScopedRouteCache manages the contents of the gRPC SRDS cache.
+// It holds a scoped route configuration for each virtual host of
+// the HTTP listener, which selects the route configuration of the
+// virtual host generated by a RouteCache with ScopedRoutes set.
+type ScopedRouteCache struct {
+	mu     sync.Mutex
+	values map[string]*envoy_route_v3.ScopedRouteConfiguration
+	contour.Cond
+}
+
+// Update replaces the contents of the cache with the supplied map.
+func (c *ScopedRouteCache) Update(v map[string]*envoy_route_v3.ScopedRouteConfiguration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = v
+	c.Cond.Notify()
+}
+
+// Contents returns a copy of the cache's contents.
+func (c *ScopedRouteCache) Contents() []proto.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var values []*envoy_route_v3.ScopedRouteConfiguration
+	for _, v := range c.values {
+		values = append(values, v)
+	}
+
+	sort.Stable(sorter.For(values))
+	return protobuf.AsMessages(values)
+}
+
+// Query searches the ScopedRouteCache for the named ScopedRouteConfiguration entries.
+func (c *ScopedRouteCache) Query(names []string) []proto.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var values []*envoy_route_v3.ScopedRouteConfiguration
+	for _, n := range names {
+		if v, ok := c.values[n]; ok {
+			values = append(values, v)
+		}
+	}
+
+	sort.Stable(sorter.For(values))
+	return protobuf.AsMessages(values)
+}
+
+// TypeURL returns the string type of ScopedRouteCache Resource.
+func (*ScopedRouteCache) TypeURL() string { return envoy_v3.ScopedRouteType }
+
+func (c *ScopedRouteCache) OnChange(root *dag.DAG) {
+	scopes := visitScopedRoutes(root)
+	c.Update(scopes)
+}
+
+// visitScopedRoutes produces a scoped route configuration for each
+// virtual host of the HTTP listener that has routes.
+func visitScopedRoutes(root dag.Vertex) map[string]*envoy_route_v3.ScopedRouteConfiguration {
+	scopes := map[string]*envoy_route_v3.ScopedRouteConfiguration{}
+
+	var visit func(dag.Vertex)
+	visit = func(vertex dag.Vertex) {
+		switch vh := vertex.(type) {
+		case *dag.VirtualHost:
+			// Virtual hosts of additional HTTP listeners are
+			// served by the route configuration of their listener.
+			if vh.Listener == "" && hasRoutes(vh) {
+				name := scopedRouteConfigName(vh.Name)
+				scopes[name] = envoy_v3.ScopedRouteConfiguration(name, vh.Name)
+			}
+		case *dag.SecureVirtualHost:
+			// HTTPS virtual hosts already have a route
+			// configuration of their own.
+		default:
+			vertex.Visit(visit)
+		}
+	}
+	visit(root)
+
+	return scopes
+}
+
+// hasRoutes returns true if the virtual host has any routes.
+func hasRoutes(vh *dag.VirtualHost) bool {
+	found := false
+	vh.Visit(func(vertex dag.Vertex) {
+		if _, ok := vertex.(*dag.Route); ok {
+			found = true
+		}
+	})
+	return found
+}
+
+// scopedRouteConfigName returns the name of the route configuration,
+// and the scoped route configuration, of an HTTP virtual host when
+// scoped routes are enabled.
+func scopedRouteConfigName(host string) string {
+	return path.Join(ENVOY_HTTP_LISTENER, host)
+}
@@ -0,0 +1,63 @@
+// Copyright Project Contour Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"testing"
+
+	envoy_route_v3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/golang/protobuf/proto"
+	envoy_v3 "github.com/projectcontour/contour/internal/envoy/v3"
+	"github.com/projectcontour/contour/internal/protobuf"
+)
+
+func TestScopedRouteCacheQuery(t *testing.T) {
+	var c ScopedRouteCache
+	c.Update(map[string]*envoy_route_v3.ScopedRouteConfiguration{
+		"ingress_http/a.example.com": envoy_v3.ScopedRouteConfiguration("ingress_http/a.example.com", "a.example.com"),
+		"ingress_http/b.example.com": envoy_v3.ScopedRouteConfiguration("ingress_http/b.example.com", "b.example.com"),
+	})
+
+	want := []proto.Message{
+		envoy_v3.ScopedRouteConfiguration("ingress_http/b.example.com", "b.example.com"),
+	}
+
+	protobuf.ExpectEqual(t, want, c.Query([]string{"ingress_http/b.example.com", "ingress_http/c.example.com"}))
+}
+
+func TestScopedRouteVisit(t *testing.T) {
+	tests := map[string]struct {
+		objs []interface{}
+		want map[string]*envoy_route_v3.ScopedRouteConfiguration
+	}{
+		"nothing": {
+			objs: nil,
+			want: map[string]*envoy_route_v3.ScopedRouteConfiguration{},
+		},
+		"ingress with two hosts": {
+			objs: scopedRoutesObjects(),
+			want: map[string]*envoy_route_v3.ScopedRouteConfiguration{
+				"ingress_http/a.example.com": envoy_v3.ScopedRouteConfiguration("ingress_http/a.example.com", "a.example.com"),
+				"ingress_http/b.example.com": envoy_v3.ScopedRouteConfiguration("ingress_http/b.example.com", "b.example.com"),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := buildDAG(t, tc.objs...)
+			protobuf.ExpectEqual(t, tc.want, visitScopedRoutes(root))
+		})
+	}
+}
@@ -102,10 +102,19 @@ func (v *secretVisitor) addSecret(s *dag.Secret) {
 
 func (v *secretVisitor) visit(vertex dag.Vertex) {
 	switch obj := vertex.(type) {
+	case *dag.Listener:
+		if obj.SessionTicketKeys != nil {
+			envoySecret := envoy_v3.SessionTicketKeysSecret(obj.SessionTicketKeys)
+			v.secrets[envoySecret.Name] = envoySecret
+		}
+		vertex.Visit(v.visit)
 	case *dag.SecureVirtualHost:
 		if obj.Secret != nil {
 			v.addSecret(obj.Secret)
 		}
+		if obj.SecondarySecret != nil {
+			v.addSecret(obj.SecondarySecret)
+		}
 		if obj.FallbackCertificate != nil {
 			v.addSecret(obj.FallbackCertificate)
 		}
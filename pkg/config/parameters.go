@@ -17,9 +17,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -74,6 +76,49 @@ const AutoClusterDNSFamily ClusterDNSFamilyType = "auto"
 const IPv4ClusterDNSFamily ClusterDNSFamilyType = "v4"
 const IPv6ClusterDNSFamily ClusterDNSFamilyType = "v6"
 
+// ParseDNSResolver parses a DNS resolver address, which is an
+// IP address with an optional port. If the port is omitted, the
+// DNS port, 53, is returned.
+func ParseDNSResolver(address string) (net.IP, uint32, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, 53, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cluster DNS resolver %q: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid cluster DNS resolver %q: %q is not an IP address", address, host)
+	}
+
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || n == 0 {
+		return nil, 0, fmt.Errorf("invalid cluster DNS resolver %q: invalid port %q", address, port)
+	}
+
+	return ip, uint32(n), nil
+}
+
+// ServerHeaderTransformationType describes how Envoy handles the
+// server header on responses.
+type ServerHeaderTransformationType string
+
+func (s ServerHeaderTransformationType) Validate() error {
+	switch s {
+	case "", OverwriteServerHeader, AppendIfAbsentServerHeader, PassThroughServerHeader:
+		return nil
+	default:
+		return fmt.Errorf("invalid server header transformation %q", s)
+	}
+}
+
+const OverwriteServerHeader ServerHeaderTransformationType = "overwrite"
+const AppendIfAbsentServerHeader ServerHeaderTransformationType = "append-if-absent"
+const PassThroughServerHeader ServerHeaderTransformationType = "pass-through"
+
 // AccessLogType is the name of a supported access logging mechanism.
 type AccessLogType string
 
@@ -173,6 +218,112 @@ func (a AccessLogFields) AsFieldMap() map[string]string {
 	return fieldMap
 }
 
+// AccessLogFilterParameters holds the configuration for limiting
+// which requests are written to the Envoy access logs.
+type AccessLogFilterParameters struct {
+	// ErrorsOnly restricts the access logs to responses with
+	// a status code of 400 or greater.
+	ErrorsOnly bool `yaml:"errors-only,omitempty"`
+
+	// SuccessSamplePercent is the percentage of responses with a
+	// status code below 400 that are logged. Error responses are
+	// always logged when this is set.
+	SuccessSamplePercent uint32 `yaml:"success-sample-percent,omitempty"`
+}
+
+// Validate the access log filter parameters.
+func (a AccessLogFilterParameters) Validate() error {
+	if a.SuccessSamplePercent > 100 {
+		return fmt.Errorf("invalid access log success sample percent %d: must be between 0 and 100", a.SuccessSamplePercent)
+	}
+
+	return nil
+}
+
+// AccessLogHeaderParameters lists HTTP headers whose values are
+// added to the access logs.
+type AccessLogHeaderParameters struct {
+	// RequestHeaders are the request headers to log.
+	RequestHeaders []string `yaml:"request,omitempty"`
+
+	// ResponseHeaders are the response headers to log.
+	ResponseHeaders []string `yaml:"response,omitempty"`
+}
+
+// Validate the access log header parameters.
+func (a AccessLogHeaderParameters) Validate() error {
+	re := regexp.MustCompile(`^[-A-Za-z0-9]+$`)
+
+	for kind, headers := range map[string][]string{"request": a.RequestHeaders, "response": a.ResponseHeaders} {
+		seen := map[string]bool{}
+		for _, h := range headers {
+			if !re.MatchString(h) {
+				return fmt.Errorf("invalid access log %s header %q", kind, h)
+			}
+
+			if seen[strings.ToLower(h)] {
+				return fmt.Errorf("duplicate access log %s header %q", kind, h)
+			}
+			seen[strings.ToLower(h)] = true
+		}
+	}
+
+	return nil
+}
+
+// AsFields returns the JSON access log fields that log the headers.
+// Request headers are logged in fields named "req.<header>" and
+// response headers in fields named "resp.<header>", with the header
+// name in lower case.
+func (a AccessLogHeaderParameters) AsFields() AccessLogFields {
+	var fields AccessLogFields
+
+	for _, h := range a.RequestHeaders {
+		fields = append(fields, fmt.Sprintf("req.%s=%%REQ(%s)%%", strings.ToLower(h), h))
+	}
+
+	for _, h := range a.ResponseHeaders {
+		fields = append(fields, fmt.Sprintf("resp.%s=%%RESP(%s)%%", strings.ToLower(h), h))
+	}
+
+	return fields
+}
+
+// AccessLogGRPCParameters configures an access log sink that sends
+// HTTP access log entries to a gRPC access log service (ALS). The
+// sink is used alongside the file based access logs, so that both
+// receive every logged request.
+type AccessLogGRPCParameters struct {
+	// ExtensionService identifies the extension service
+	// defining the gRPC access log service.
+	ExtensionService NamespacedName `yaml:"extension-service,omitempty"`
+
+	// LogName is passed to the access log service to tell
+	// the logs of different Envoy listeners apart. Defaults
+	// to the name of the listener.
+	LogName string `yaml:"log-name,omitempty"`
+
+	// Headers lists request and response headers to add to
+	// the log entries sent to the access log service. They
+	// are independent of the headers in the file based logs.
+	Headers AccessLogHeaderParameters `yaml:"headers,omitempty"`
+}
+
+// Validate the gRPC access log parameters.
+func (a AccessLogGRPCParameters) Validate() error {
+	if err := a.ExtensionService.Validate(); err != nil {
+		return fmt.Errorf("invalid gRPC access log extension service: %w", err)
+	}
+
+	if len(strings.TrimSpace(a.ExtensionService.Name)) == 0 {
+		if a.LogName != "" || len(a.Headers.RequestHeaders) > 0 || len(a.Headers.ResponseHeaders) > 0 {
+			return errors.New("invalid gRPC access log parameters: extension-service must be defined")
+		}
+	}
+
+	return a.Headers.Validate()
+}
+
 // HTTPVersionType is the name of a supported HTTP version.
 type HTTPVersionType string
 
@@ -188,6 +339,22 @@ func (h HTTPVersionType) Validate() error {
 const HTTPVersion1 HTTPVersionType = "http/1.1"
 const HTTPVersion2 HTTPVersionType = "http/2"
 
+// ALPNProtocolType is the name of an ALPN protocol offered on the
+// HTTPS listener.
+type ALPNProtocolType string
+
+func (a ALPNProtocolType) Validate() error {
+	switch a {
+	case H2ALPNProtocol, HTTP11ALPNProtocol:
+		return nil
+	default:
+		return fmt.Errorf("invalid ALPN protocol %q", a)
+	}
+}
+
+const H2ALPNProtocol ALPNProtocolType = "h2"
+const HTTP11ALPNProtocol ALPNProtocolType = "http/1.1"
+
 // NamespacedName defines the namespace/name of the Kubernetes resource referred from the configuration file.
 // Used for Contour configuration YAML file parsing, otherwise we could use K8s types.NamespacedName.
 type NamespacedName struct {
@@ -225,6 +392,78 @@ type TLSParameters struct {
 	// to be used when establishing TLS connection to upstream
 	// cluster.
 	ClientCertificate NamespacedName `yaml:"envoy-client-certificate,omitempty"`
+
+	// SessionTicketKeys defines the namespace/name of the Kubernetes
+	// secret containing the keys used to encrypt and decrypt TLS
+	// session tickets. Sharing the keys lets clients resume sessions
+	// on any Envoy replica.
+	SessionTicketKeys NamespacedName `yaml:"session-ticket-keys,omitempty"`
+
+	// HSTS defines the Strict-Transport-Security header added
+	// to responses from TLS virtual hosts.
+	HSTS HSTSParameters `yaml:"hsts,omitempty"`
+
+	// SPIRE configures Envoy to present the X.509 SVID of a SPIFFE
+	// identity, fetched from the SPIRE agent, as its client
+	// certificate when establishing TLS connections to upstream
+	// clusters.
+	SPIRE SPIREParameters `yaml:"spire,omitempty"`
+
+	// ALPNProtocols is the list of ALPN protocols offered by the
+	// HTTPS listener, in order of preference. Omitting "h2"
+	// disables HTTP/2 for clients or middleboxes that misbehave
+	// with it. If not set, the protocols are derived from the
+	// default HTTP versions.
+	ALPNProtocols []ALPNProtocolType `yaml:"alpn-protocols,omitempty"`
+}
+
+// HSTSParameters holds the configuration for the HTTP Strict
+// Transport Security header.
+type HSTSParameters struct {
+	// MaxAge is how long clients should only access the
+	// virtual host over HTTPS. The header is only added
+	// when MaxAge is set.
+	MaxAge time.Duration `yaml:"max-age,omitempty"`
+
+	// IncludeSubdomains applies the policy to all
+	// subdomains of the virtual host.
+	IncludeSubdomains bool `yaml:"include-subdomains,omitempty"`
+
+	// Preload signals consent to be included in
+	// browser HSTS preload lists.
+	Preload bool `yaml:"preload,omitempty"`
+}
+
+// Validate the HSTS parameters.
+func (h HSTSParameters) Validate() error {
+	if h.MaxAge < 0 {
+		return fmt.Errorf("invalid HSTS max-age %q: must not be negative", h.MaxAge)
+	}
+
+	if h.Preload && (h.MaxAge == 0 || !h.IncludeSubdomains) {
+		return fmt.Errorf("invalid HSTS preload: max-age and include-subdomains must be set")
+	}
+
+	return nil
+}
+
+// SPIREParameters holds the SPIRE workload identity that Envoy
+// presents to upstream clusters.
+type SPIREParameters struct {
+	// Identity is the SPIFFE ID whose X.509 SVID Envoy fetches from
+	// the SPIRE agent's SDS socket. The socket must be added to the
+	// Envoy bootstrap configuration with "contour bootstrap
+	// --spire-agent-socket".
+	Identity string `yaml:"identity,omitempty"`
+}
+
+// Validate the SPIRE parameters.
+func (s SPIREParameters) Validate() error {
+	if s.Identity != "" && !strings.HasPrefix(s.Identity, "spiffe://") {
+		return fmt.Errorf("invalid SPIRE identity %q: must be a spiffe:// URI", s.Identity)
+	}
+
+	return nil
 }
 
 // ServerParameters holds the configuration for the Contour xDS server.
@@ -232,6 +471,61 @@ type ServerParameters struct {
 	// Defines the XDSServer to use for `contour serve`.
 	// Defaults to "contour"
 	XDSServerType ServerType `yaml:"xds-server-type,omitempty"`
+
+	// PushInterval is the minimum time between two responses
+	// on an xDS stream. Changes made in between are coalesced
+	// into the next response. Zero means that responses are
+	// not rate limited. Only supported by the "contour" server.
+	PushInterval time.Duration `yaml:"push-interval,omitempty"`
+
+	// EndpointsHoldoffDelay is the window over which Endpoints
+	// changes are batched before the load assignments of the
+	// affected clusters are recalculated. Zero means that each
+	// change is recalculated immediately. Defaults to 100ms.
+	EndpointsHoldoffDelay time.Duration `yaml:"endpoints-holdoff-delay,omitempty"`
+
+	// ScopedRoutes serves each virtual host of the HTTP listener
+	// in its own route configuration, which Envoy selects by the
+	// request host using scoped RDS. This keeps the size of each
+	// route configuration bounded when there are very many virtual
+	// hosts. Only supported by the "contour" server.
+	ScopedRoutes bool `yaml:"scoped-routes,omitempty"`
+
+	// EndpointsOnly serves only EDS, with the endpoints of every
+	// port of every Kubernetes Service, so that another control
+	// plane can serve the listeners, routes and clusters of
+	// Envoy while delegating endpoint discovery to Contour.
+	EndpointsOnly bool `yaml:"endpoints-only,omitempty"`
+
+	// WaitForFirstSnapshot delays accepting xDS streams until the
+	// first snapshot has been built from the synced informer caches,
+	// so that Envoy never receives a partial configuration on start.
+	WaitForFirstSnapshot bool `yaml:"wait-for-first-snapshot,omitempty"`
+}
+
+// Validate ensures that the server parameters are valid.
+func (s ServerParameters) Validate() error {
+	if err := s.XDSServerType.Validate(); err != nil {
+		return err
+	}
+
+	if s.PushInterval < 0 {
+		return fmt.Errorf("invalid xDS push interval %q: must not be negative", s.PushInterval)
+	}
+
+	if s.EndpointsHoldoffDelay < 0 {
+		return fmt.Errorf("invalid endpoints holdoff delay %q: must not be negative", s.EndpointsHoldoffDelay)
+	}
+
+	if s.ScopedRoutes && s.XDSServerType == EnvoyServerType {
+		return fmt.Errorf("invalid xDS server type %q: scoped routes require the %q server", s.XDSServerType, ContourServerType)
+	}
+
+	if s.ScopedRoutes && s.EndpointsOnly {
+		return errors.New("scoped routes can't be combined with endpoints-only mode")
+	}
+
+	return nil
 }
 
 // LeaderElectionParameters holds the config bits for leader election
@@ -328,6 +622,282 @@ func (t TimeoutParameters) Validate() error {
 	return nil
 }
 
+// NetworkParameters hold various configurable network values.
+type NetworkParameters struct {
+	// XffNumTrustedHops defines the number of additional ingress proxy hops from the
+	// right side of the x-forwarded-for HTTP header to trust when determining the origin
+	// client's IP address. The origin client address is used for access logging,
+	// source IP request hashing and authorization.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/network/http_connection_manager/v3/http_connection_manager.proto#envoy-v3-api-field-extensions-filters-network-http-connection-manager-v3-httpconnectionmanager-xff-num-trusted-hops
+	// for more information.
+	XffNumTrustedHops uint32 `yaml:"num-trusted-hops,omitempty"`
+
+	// TrustedProxyCIDRs restricts the trust in num-trusted-hops to
+	// connections from these address ranges. Connections from any
+	// other address have their x-forwarded-for header overwritten
+	// with the connection's address. If empty, num-trusted-hops
+	// applies to every connection.
+	TrustedProxyCIDRs []string `yaml:"trusted-proxy-cidrs,omitempty"`
+
+	// StripMatchingHostPort removes the port from the Host header before
+	// route matching when it matches the port of the Envoy listener.
+	// Envoy compares the Host port with the port it listens on, not with
+	// the port of the Service in front of it, so with the default
+	// listener ports of 8080 and 8443 a request for "example.com:443" is
+	// left unchanged. The option only takes effect when clients connect
+	// to the listener port directly, for example when Envoy uses the host
+	// network with listener ports 80 and 443.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/network/http_connection_manager/v3/http_connection_manager.proto#envoy-v3-api-field-extensions-filters-network-http-connection-manager-v3-httpconnectionmanager-strip-matching-host-port
+	// for more information.
+	StripMatchingHostPort bool `yaml:"strip-matching-host-port,omitempty"`
+
+	// ServerHeader configures the server header on responses.
+	ServerHeader ServerHeaderParameters `yaml:"server-header,omitempty"`
+
+	// DisableHTTP10 rejects HTTP/1.0 requests with a 426
+	// Upgrade Required response. By default, HTTP/1.0
+	// requests that carry a Host header are accepted.
+	DisableHTTP10 bool `yaml:"disable-http10,omitempty"`
+
+	// HTTP10DefaultHost is the host used to route HTTP/1.0
+	// requests that have no Host header, for legacy clients.
+	// If not set, such requests are rejected.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/protocol.proto#envoy-v3-api-field-config-core-v3-http1protocoloptions-default-host-for-http-10
+	// for more information.
+	HTTP10DefaultHost string `yaml:"http10-default-host,omitempty"`
+
+	// DualStack binds the Envoy listeners that use the default
+	// address of 0.0.0.0 to :: instead, with IPv4 compatibility,
+	// and reads Service endpoints from EndpointSlices so that
+	// the addresses of both families are load balanced.
+	DualStack bool `yaml:"dual-stack,omitempty"`
+
+	// PerConnectionBufferLimitBytes is the soft limit on the size
+	// of the read and write buffers of each downstream connection
+	// to the Envoy listeners. If not set, Envoy's default of 1MiB
+	// is used.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/listener/v3/listener.proto#envoy-v3-api-field-config-listener-v3-listener-per-connection-buffer-limit-bytes
+	// for more information.
+	PerConnectionBufferLimitBytes uint32 `yaml:"per-connection-buffer-limit-bytes,omitempty"`
+
+	// SocketOptions are raw socket options that are set on the
+	// sockets of the Envoy listeners, in addition to the TCP
+	// keep-alive options that Contour always sets.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/socket_option.proto
+	// for more information.
+	SocketOptions []SocketOptionParameters `yaml:"socket-options,omitempty"`
+
+	// MaxRequestHeadersKB is the maximum size, in KiB, of the
+	// request headers accepted by the Envoy listeners. Larger
+	// requests are rejected with a 431 response. It may be at
+	// most 96. If not set, Envoy's default of 60KiB is used.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/network/http_connection_manager/v3/http_connection_manager.proto#envoy-v3-api-field-extensions-filters-network-http-connection-manager-v3-httpconnectionmanager-max-request-headers-kb
+	// for more information.
+	MaxRequestHeadersKB uint32 `yaml:"max-request-headers-kb,omitempty"`
+
+	// MaxRequestHeadersCount is the maximum number of request
+	// headers accepted by the Envoy listeners. If not set,
+	// Envoy's default of 100 is used.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/core/v3/protocol.proto#envoy-v3-api-field-config-core-v3-httpprotocoloptions-max-headers-count
+	// for more information.
+	MaxRequestHeadersCount uint32 `yaml:"max-request-headers-count,omitempty"`
+
+	// ListenerDrainType configures when the connections of the
+	// Envoy listeners are drained. When a listener's filter chains
+	// change, Envoy drains the connections of the old listener for
+	// the drain time set by its --drain-time-s option, which cannot
+	// be configured over xDS, and the HTTP connections for
+	// timeouts.connection-shutdown-grace-period.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/listener/v3/listener.proto#envoy-v3-api-enum-config-listener-v3-listener-draintype
+	// for more information.
+	ListenerDrainType ListenerDrainType `yaml:"listener-drain-type,omitempty"`
+
+	// HTTPListeners are additional HTTP listeners, each with a
+	// route configuration of its own, that HTTPProxy virtual hosts
+	// are bound to by name instead of the default HTTP listener.
+	HTTPListeners []HTTPListenerParameters `yaml:"http-listeners,omitempty"`
+}
+
+// HTTPListenerParameters configure an additional HTTP listener.
+type HTTPListenerParameters struct {
+	// Name is the name of the listener, and of its route
+	// configuration, which virtual hosts refer to.
+	Name string `yaml:"name"`
+
+	// Address is the address the listener binds to.
+	// If not set, 0.0.0.0 is used.
+	Address string `yaml:"address,omitempty"`
+
+	// Port is the port the listener binds to.
+	Port int `yaml:"port"`
+}
+
+// Validate ensures that the listener parameters are valid.
+func (h HTTPListenerParameters) Validate() error {
+	// The name is also the name of the route configuration of the
+	// listener, so it may not be that of a Contour listener or route
+	// configuration. HTTPS route configurations are named after their
+	// host with a "https/" prefix, which the name cannot contain.
+	switch h.Name {
+	case "":
+		return errors.New("invalid network http-listeners: name must be set")
+	case "ingress_http", "ingress_https", "ingress_fallbackcert", "stats-health", "envoy-metrics":
+		return fmt.Errorf("invalid network http-listeners name %q: reserved for a Contour listener", h.Name)
+	}
+
+	re := regexp.MustCompile(`^[a-z0-9_-]+$`)
+	if !re.MatchString(h.Name) {
+		return fmt.Errorf("invalid network http-listeners name %q: must consist of lower case alphanumeric characters, '_' or '-'", h.Name)
+	}
+
+	if h.Address != "" && net.ParseIP(h.Address) == nil {
+		return fmt.Errorf("invalid network http-listeners %q address %q", h.Name, h.Address)
+	}
+
+	if h.Port < 1 || h.Port > 65535 {
+		return fmt.Errorf("invalid network http-listeners %q port %d: must be between 1 and 65535", h.Name, h.Port)
+	}
+
+	return nil
+}
+
+// TrustedProxyNetworks returns the parsed TrustedProxyCIDRs.
+// Invalid ranges, which Validate rejects, are skipped.
+func (p NetworkParameters) TrustedProxyNetworks() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range p.TrustedProxyCIDRs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ListenerDrainType describes when Envoy drains
+// the connections of a listener.
+type ListenerDrainType string
+
+func (l ListenerDrainType) Validate() error {
+	switch l {
+	case "", DefaultListenerDrainType, ModifyOnlyListenerDrainType:
+		return nil
+	default:
+		return fmt.Errorf("invalid network listener-drain-type %q", l)
+	}
+}
+
+// DefaultListenerDrainType drains connections when the listener
+// is modified or removed, and when Envoy is shutting down or its
+// health check is failed, as the shutdown-manager does.
+const DefaultListenerDrainType ListenerDrainType = "default"
+
+// ModifyOnlyListenerDrainType drains connections only when the
+// listener is modified or removed.
+const ModifyOnlyListenerDrainType ListenerDrainType = "modify-only"
+
+// SocketOptionState is the state of a listener socket
+// in which a socket option is set.
+type SocketOptionState string
+
+func (s SocketOptionState) Validate() error {
+	switch s {
+	case "", PrebindSocketOptionState, BoundSocketOptionState, ListeningSocketOptionState:
+		return nil
+	default:
+		return fmt.Errorf("invalid socket option state %q", s)
+	}
+}
+
+const PrebindSocketOptionState SocketOptionState = "prebind"
+const BoundSocketOptionState SocketOptionState = "bound"
+const ListeningSocketOptionState SocketOptionState = "listening"
+
+// SocketOptionParameters holds a raw socket option, which is
+// passed to setsockopt(2) with an integer value.
+type SocketOptionParameters struct {
+	// Description is a description of the option,
+	// for example "SO_REUSEPORT".
+	Description string `yaml:"description,omitempty"`
+
+	// Level is the protocol level of the option,
+	// for example 1 for SOL_SOCKET on Linux.
+	Level int64 `yaml:"level"`
+
+	// Name is the option number, for example 15
+	// for SO_REUSEPORT on Linux.
+	Name int64 `yaml:"name"`
+
+	// IntValue is the value of the option.
+	IntValue int64 `yaml:"int-value"`
+
+	// State is the state of the socket when the option is set,
+	// one of "prebind", "bound" or "listening". If not set,
+	// the option is set before the socket is bound.
+	State SocketOptionState `yaml:"state,omitempty"`
+
+	// Listeners are the names of the listeners that the option
+	// is set on, "ingress_http" or "ingress_https". If empty,
+	// the option is set on both.
+	Listeners []string `yaml:"listeners,omitempty"`
+}
+
+// Validate the socket option parameters.
+func (s SocketOptionParameters) Validate() error {
+	if err := s.State.Validate(); err != nil {
+		return err
+	}
+
+	for _, l := range s.Listeners {
+		switch l {
+		case "ingress_http", "ingress_https":
+		default:
+			return fmt.Errorf("invalid socket option listener %q", l)
+		}
+	}
+
+	return nil
+}
+
+// ServerHeaderParameters holds the configuration for the server
+// header that Envoy sends on responses.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/extensions/filters/network/http_connection_manager/v3/http_connection_manager.proto#envoy-v3-api-field-extensions-filters-network-http-connection-manager-v3-httpconnectionmanager-server-header-transformation
+// for more information.
+type ServerHeaderParameters struct {
+	// Transformation defines how the server header is handled.
+	// When configured as overwrite, Envoy replaces any server header
+	// with its own value. If append-if-absent is configured, Envoy only
+	// sets the header when the upstream did not. If pass-through is
+	// configured, Envoy never sets the header and the upstream value,
+	// if any, is returned unchanged. Defaults to overwrite.
+	Transformation ServerHeaderTransformationType `yaml:"transformation,omitempty"`
+
+	// ServerName is the value Envoy uses for the server header.
+	// Defaults to "envoy".
+	ServerName string `yaml:"server-name,omitempty"`
+}
+
+// Validate the server header parameters.
+func (s ServerHeaderParameters) Validate() error {
+	if err := s.Transformation.Validate(); err != nil {
+		return err
+	}
+
+	if s.Transformation == PassThroughServerHeader && s.ServerName != "" {
+		return fmt.Errorf("invalid server header server-name: not used with %q transformation", PassThroughServerHeader)
+	}
+
+	return nil
+}
+
 // ClusterParameters holds various configurable cluster values.
 type ClusterParameters struct {
 	// DNSLookupFamily defines how external names are looked up
@@ -342,6 +912,496 @@ type ClusterParameters struct {
 	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/cluster.proto.html#envoy-v3-api-enum-config-cluster-v3-cluster-dnslookupfamily
 	// for more information.
 	DNSLookupFamily ClusterDNSFamilyType `yaml:"dns-lookup-family"`
+
+	// DNSResolvers are the addresses of the DNS servers used
+	// to resolve external names, as an IP address with an
+	// optional port. If empty, Envoy uses the resolvers of
+	// the host it runs on.
+	DNSResolvers []string `yaml:"dns-resolvers,omitempty"`
+
+	// RetryBudget limits the share of active requests to
+	// each upstream cluster that may be retries. Routes may
+	// override the budget in their retry policy.
+	RetryBudget RetryBudgetParameters `yaml:"retry-budget,omitempty"`
+
+	// PerConnectionBufferLimitBytes is the soft limit on the size
+	// of the read and write buffers of each upstream connection.
+	// If not set, Envoy's default of 1MiB is used.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/cluster.proto#envoy-v3-api-field-config-cluster-v3-cluster-per-connection-buffer-limit-bytes
+	// for more information.
+	PerConnectionBufferLimitBytes uint32 `yaml:"per-connection-buffer-limit-bytes,omitempty"`
+
+	// ConnectTimeout is the timeout for new connections to
+	// upstream clusters. Services may override it with the
+	// projectcontour.io/connect-timeout annotation. If not
+	// set, the timeout is 250ms.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/cluster.proto#envoy-v3-api-field-config-cluster-v3-cluster-connect-timeout
+	// for more information.
+	ConnectTimeout string `yaml:"connect-timeout,omitempty"`
+
+	// HealthyPanicThreshold is the percentage of healthy
+	// endpoints below which a cluster enters panic mode, and
+	// balances requests across all of its endpoints, healthy
+	// or not. If not set, panic mode is disabled, and requests
+	// fail when no endpoint is healthy.
+	//
+	// See https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/upstream/load_balancing/panic_threshold
+	// for more information.
+	HealthyPanicThreshold uint32 `yaml:"healthy-panic-threshold,omitempty"`
+
+	// DNSSRVRefreshInterval is the time between two DNS SRV
+	// lookups of the external names of Services with the
+	// projectcontour.io/dns-srv annotation. If not set, the
+	// names are looked up every 30s.
+	DNSSRVRefreshInterval string `yaml:"dns-srv-refresh-interval,omitempty"`
+}
+
+// ConnectTimeoutDuration returns the parsed connect timeout,
+// or zero if it is not set.
+func (c ClusterParameters) ConnectTimeoutDuration() (time.Duration, error) {
+	if c.ConnectTimeout == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(c.ConnectTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cluster connect-timeout %q: %w", c.ConnectTimeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid cluster connect-timeout %q: must be positive", c.ConnectTimeout)
+	}
+
+	return d, nil
+}
+
+// DNSSRVRefreshIntervalDuration returns the parsed DNS SRV
+// refresh interval, or 30s if it is not set.
+func (c ClusterParameters) DNSSRVRefreshIntervalDuration() (time.Duration, error) {
+	if c.DNSSRVRefreshInterval == "" {
+		return 30 * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(c.DNSSRVRefreshInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cluster dns-srv-refresh-interval %q: %w", c.DNSSRVRefreshInterval, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid cluster dns-srv-refresh-interval %q: must be positive", c.DNSSRVRefreshInterval)
+	}
+
+	return d, nil
+}
+
+// RetryBudgetParameters holds the configuration for an
+// Envoy cluster retry budget.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/config/cluster/v3/circuit_breaker.proto#config-cluster-v3-circuitbreakers-thresholds-retrybudget
+// for more information.
+type RetryBudgetParameters struct {
+	// BudgetPercent is the percentage of active requests
+	// that may be retries. The budget is only applied
+	// when BudgetPercent is set.
+	BudgetPercent uint32 `yaml:"budget-percent,omitempty"`
+
+	// MinRetryConcurrency is the number of concurrent
+	// retries always allowed, regardless of the budget.
+	MinRetryConcurrency uint32 `yaml:"min-retry-concurrency,omitempty"`
+}
+
+// Validate the retry budget parameters.
+func (r RetryBudgetParameters) Validate() error {
+	if r.BudgetPercent > 100 {
+		return fmt.Errorf("invalid retry budget-percent %d: must be between 0 and 100", r.BudgetPercent)
+	}
+
+	if r.MinRetryConcurrency > 0 && r.BudgetPercent == 0 {
+		return fmt.Errorf("invalid retry min-retry-concurrency: budget-percent must be set")
+	}
+
+	return nil
+}
+
+// ErrorPageParameters maps a status code of a response generated by
+// Envoy to a replacement response body stored in a Kubernetes ConfigMap.
+type ErrorPageParameters struct {
+	// StatusCode is the HTTP status code to replace the body of.
+	// Valid values are 502, 503 and 504.
+	StatusCode int `yaml:"status-code"`
+
+	// ConfigMap is the namespace/name of the ConfigMap that holds
+	// the response body.
+	ConfigMap NamespacedName `yaml:"configmap"`
+
+	// Key is the ConfigMap data key that holds the response body.
+	Key string `yaml:"key"`
+
+	// ContentType is the content type of the response body.
+	// Defaults to "text/html".
+	ContentType string `yaml:"content-type,omitempty"`
+}
+
+// Validate the error page parameters.
+func (e ErrorPageParameters) Validate() error {
+	switch e.StatusCode {
+	case 502, 503, 504:
+	default:
+		return fmt.Errorf("invalid error page status code %d", e.StatusCode)
+	}
+
+	if len(strings.TrimSpace(e.ConfigMap.Name)) == 0 && len(strings.TrimSpace(e.ConfigMap.Namespace)) == 0 {
+		return fmt.Errorf("error page for status code %d: configmap must be defined", e.StatusCode)
+	}
+
+	if err := e.ConfigMap.Validate(); err != nil {
+		return fmt.Errorf("error page for status code %d: invalid configmap: %w", e.StatusCode, err)
+	}
+
+	if len(strings.TrimSpace(e.Key)) == 0 {
+		return fmt.Errorf("error page for status code %d: key must be defined", e.StatusCode)
+	}
+
+	return nil
+}
+
+// RateLimitServiceParameters holds the configuration for the global
+// rate limit service.
+type RateLimitServiceParameters struct {
+	// ExtensionService identifies the extension service defining the RLS.
+	ExtensionService NamespacedName `yaml:"extension-service,omitempty"`
+
+	// Domain is passed to the Rate Limit Service.
+	Domain string `yaml:"domain,omitempty"`
+
+	// FailOpen defines whether to allow requests to proceed when the
+	// Rate Limit Service fails to respond with a valid rate limit
+	// decision within the timeout defined on the extension service.
+	FailOpen bool `yaml:"fail-open,omitempty"`
+
+	// EnableXRateLimitHeaders adds the X-RateLimit-Limit,
+	// X-RateLimit-Remaining and X-RateLimit-Reset headers, as
+	// defined by draft 03 of the IETF RateLimit header fields,
+	// to responses to rate limited routes.
+	EnableXRateLimitHeaders bool `yaml:"enable-x-ratelimit-headers,omitempty"`
+
+	// RetryAfter is the duration that is sent, rounded up to
+	// whole seconds, in the Retry-After header of responses
+	// rejected by the rate limit service. If not set, no
+	// Retry-After header is sent.
+	RetryAfter string `yaml:"retry-after,omitempty"`
+}
+
+// RetryAfterDuration returns the parsed Retry-After duration,
+// or zero if it is not set.
+func (r RateLimitServiceParameters) RetryAfterDuration() (time.Duration, error) {
+	if r.RetryAfter == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(r.RetryAfter)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate limit service retry-after %q: %w", r.RetryAfter, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid rate limit service retry-after %q: must be positive", r.RetryAfter)
+	}
+
+	return d, nil
+}
+
+// Validate the rate limit service parameters.
+func (r RateLimitServiceParameters) Validate() error {
+	if err := r.ExtensionService.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limit service extension service: %w", err)
+	}
+
+	if _, err := r.RetryAfterDuration(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ResponseCacheParameters configures Envoy's HTTP cache filter, which
+// is enabled on routes with a cache policy.
+type ResponseCacheParameters struct {
+	// MaxBodyBytes is the largest response body that is
+	// cached. If zero, there is no limit.
+	MaxBodyBytes uint32 `yaml:"max-body-bytes,omitempty"`
+
+	// ExcludeScheme leaves the request scheme out of the cache key.
+	ExcludeScheme bool `yaml:"exclude-scheme,omitempty"`
+
+	// ExcludeHost leaves the request host out of the cache key.
+	ExcludeHost bool `yaml:"exclude-host,omitempty"`
+
+	// IncludeQueryParameters lists the query parameters that are
+	// part of the cache key. If empty, all query parameters are
+	// included.
+	IncludeQueryParameters []string `yaml:"include-query-parameters,omitempty"`
+
+	// ExcludeQueryParameters lists query parameters that are
+	// left out of the cache key.
+	ExcludeQueryParameters []string `yaml:"exclude-query-parameters,omitempty"`
+}
+
+// Validate the response cache parameters.
+func (r ResponseCacheParameters) Validate() error {
+	for _, params := range [][]string{r.IncludeQueryParameters, r.ExcludeQueryParameters} {
+		for _, q := range params {
+			if len(strings.TrimSpace(q)) == 0 {
+				return errors.New("invalid response cache parameters: query parameter names must not be empty")
+			}
+		}
+	}
+
+	return nil
+}
+
+// TracePropagationType is the name of a trace context header format.
+type TracePropagationType string
+
+func (t TracePropagationType) Validate() error {
+	switch t {
+	case B3TracePropagation, W3CTracePropagation:
+		return nil
+	default:
+		return fmt.Errorf("invalid trace propagation format %q", t)
+	}
+}
+
+// B3TracePropagation propagates trace context in the X-B3-* headers.
+const B3TracePropagation TracePropagationType = "b3"
+
+// W3CTracePropagation propagates trace context in the W3C
+// Trace Context "traceparent" header.
+const W3CTracePropagation TracePropagationType = "w3c"
+
+// TracingParameters configures how Envoy participates in
+// distributed traces.
+type TracingParameters struct {
+	// Propagation lists the trace context header formats that Envoy
+	// reads from requests and writes to upstream requests. If more
+	// than one format is given, incoming requests are checked in the
+	// order listed and all formats are written upstream. If empty,
+	// tracing is disabled.
+	Propagation []TracePropagationType `yaml:"propagation,omitempty"`
+
+	// StartTraces makes Envoy start a new trace for requests that
+	// do not already carry trace context. If false, Envoy only
+	// propagates traces started by clients.
+	StartTraces bool `yaml:"start-traces,omitempty"`
+}
+
+// Validate the tracing parameters.
+func (t TracingParameters) Validate() error {
+	seen := map[TracePropagationType]bool{}
+	for _, p := range t.Propagation {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		if seen[p] {
+			return fmt.Errorf("duplicate trace propagation format %q", p)
+		}
+		seen[p] = true
+	}
+
+	if t.StartTraces && len(t.Propagation) == 0 {
+		return errors.New("invalid tracing parameters: start-traces requires a propagation format")
+	}
+
+	return nil
+}
+
+// MaintenanceParameters holds the configuration of maintenance mode.
+type MaintenanceParameters struct {
+	// Enabled answers every request to the virtual hosts
+	// in Hosts with the maintenance response.
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Hosts are the fully qualified domain names of the
+	// virtual hosts under maintenance. If empty, every
+	// virtual host is under maintenance.
+	Hosts []string `yaml:"hosts,omitempty"`
+
+	// StatusCode is the status code of the maintenance
+	// response. Defaults to 503.
+	StatusCode uint32 `yaml:"status-code,omitempty"`
+
+	// Body is the optional body of the maintenance response.
+	Body string `yaml:"body,omitempty"`
+
+	// ContentType is the content type of Body.
+	// Defaults to "text/plain".
+	ContentType string `yaml:"content-type,omitempty"`
+}
+
+// Validate the maintenance parameters.
+func (m MaintenanceParameters) Validate() error {
+	if m.StatusCode != 0 && (m.StatusCode < 200 || m.StatusCode > 599) {
+		return fmt.Errorf("invalid maintenance status-code %d: must be between 200 and 599", m.StatusCode)
+	}
+
+	return nil
+}
+
+// HealthCheckVirtualHostParameters configures health checks that
+// Envoy answers itself on the HTTP and HTTPS listeners.
+type HealthCheckVirtualHostParameters struct {
+	// Path is the request path of the health checks, such as
+	// "/healthz". Requests for this path, on any host, are
+	// answered by Envoy without being routed to a backend.
+	// If not set, no such health checks are answered.
+	Path string `yaml:"path,omitempty"`
+}
+
+// Validate the health check virtual host parameters.
+func (h HealthCheckVirtualHostParameters) Validate() error {
+	if h.Path != "" && !strings.HasPrefix(h.Path, "/") {
+		return fmt.Errorf("invalid health-check-virtual-host path %q: must start with \"/\"", h.Path)
+	}
+
+	return nil
+}
+
+// AnnotationParameters holds the prefixes of Contour annotations.
+type AnnotationParameters struct {
+	// Prefix is the prefix of Contour annotations, for example
+	// "projectcontour.io" for "projectcontour.io/max-requests".
+	// If not set, "projectcontour.io" is used.
+	Prefix string `yaml:"prefix,omitempty"`
+
+	// LegacyPrefixes are prefixes whose annotations are honored,
+	// in order, on objects that do not have the annotation with
+	// Prefix, so that annotations can be migrated to a new prefix.
+	LegacyPrefixes []string `yaml:"legacy-prefixes,omitempty"`
+}
+
+// Validate ensures that the annotation prefixes are DNS names.
+func (a AnnotationParameters) Validate() error {
+	re := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+	if a.Prefix != "" && !re.MatchString(a.Prefix) {
+		return fmt.Errorf("invalid annotation prefix %q", a.Prefix)
+	}
+
+	for _, p := range a.LegacyPrefixes {
+		if !re.MatchString(p) {
+			return fmt.Errorf("invalid annotation legacy prefix %q", p)
+		}
+	}
+
+	return nil
+}
+
+// NamespaceQuotasParameters holds the quotas that limit
+// the HTTPProxies and Ingresses of each namespace.
+type NamespaceQuotasParameters struct {
+	// Default is the quota of namespaces that are
+	// not listed in Namespaces.
+	Default NamespaceQuotaParameters `yaml:"default,omitempty"`
+
+	// Namespaces holds the quotas of individual namespaces,
+	// which replace the default quota.
+	Namespaces map[string]NamespaceQuotaParameters `yaml:"namespaces,omitempty"`
+}
+
+// NamespaceQuotaParameters limits the HTTPProxies and Ingresses
+// of a namespace. A zero limit means that there is no limit.
+type NamespaceQuotaParameters struct {
+	// MaxVirtualHosts is the number of root HTTPProxies
+	// and Ingress hosts the namespace may have.
+	MaxVirtualHosts uint32 `yaml:"max-virtual-hosts,omitempty"`
+
+	// MaxRoutes is the number of routes the HTTPProxies and
+	// Ingress paths of the namespace may have in total.
+	MaxRoutes uint32 `yaml:"max-routes,omitempty"`
+}
+
+// RouteDefaultsParameters holds the policies applied to the
+// routes of a namespace's Ingresses and HTTPProxies that do
+// not set their own.
+type RouteDefaultsParameters struct {
+	// TimeoutPolicy is the default timeout policy.
+	TimeoutPolicy RouteTimeoutParameters `yaml:"timeout-policy,omitempty"`
+
+	// RetryPolicy is the default retry policy.
+	RetryPolicy RouteRetryParameters `yaml:"retry-policy,omitempty"`
+
+	// RequestHeaders is the default request headers policy.
+	RequestHeaders HeadersPolicyParameters `yaml:"request-headers,omitempty"`
+
+	// ResponseHeaders is the default response headers policy.
+	ResponseHeaders HeadersPolicyParameters `yaml:"response-headers,omitempty"`
+}
+
+// RouteTimeoutParameters holds the timeouts of a route.
+type RouteTimeoutParameters struct {
+	// Response is the timeout for a response from the
+	// upstream service, or "infinity" to disable it.
+	Response string `yaml:"response,omitempty"`
+
+	// Idle is the timeout after which an idle request is
+	// closed, or "infinity" to disable it.
+	Idle string `yaml:"idle,omitempty"`
+}
+
+// RouteRetryParameters holds the retry policy of a route.
+type RouteRetryParameters struct {
+	// RetryOn is the comma separated list of conditions
+	// under which requests are retried, as for the
+	// projectcontour.io/retry-on annotation. If empty,
+	// requests are not retried.
+	RetryOn string `yaml:"retry-on,omitempty"`
+
+	// NumRetries is the number of retries. If not set,
+	// requests are retried once.
+	NumRetries uint32 `yaml:"num-retries,omitempty"`
+
+	// PerTryTimeout is the timeout of each attempt.
+	PerTryTimeout string `yaml:"per-try-timeout,omitempty"`
+}
+
+// HeadersPolicyParameters holds headers that are set or
+// removed on requests or responses.
+type HeadersPolicyParameters struct {
+	// Set holds the values of headers to set.
+	Set map[string]string `yaml:"set,omitempty"`
+
+	// Remove holds the names of headers to remove.
+	Remove []string `yaml:"remove,omitempty"`
+}
+
+// Validate ensures that the timeouts of the route defaults
+// are valid durations, or "infinity".
+func (r RouteDefaultsParameters) Validate() error {
+	v := func(str string) error {
+		switch str {
+		case "", "infinity", "infinite":
+			return nil
+		default:
+			_, err := time.ParseDuration(str)
+			return err
+		}
+	}
+
+	if err := v(r.TimeoutPolicy.Response); err != nil {
+		return fmt.Errorf("invalid response timeout %q: %w", r.TimeoutPolicy.Response, err)
+	}
+
+	if err := v(r.TimeoutPolicy.Idle); err != nil {
+		return fmt.Errorf("invalid idle timeout %q: %w", r.TimeoutPolicy.Idle, err)
+	}
+
+	if r.RetryPolicy.PerTryTimeout != "" {
+		if _, err := time.ParseDuration(r.RetryPolicy.PerTryTimeout); err != nil {
+			return fmt.Errorf("invalid per-try timeout %q: %w", r.RetryPolicy.PerTryTimeout, err)
+		}
+	}
+
+	return nil
 }
 
 // Parameters contains the configuration file parameters for the
@@ -370,6 +1430,29 @@ type Parameters struct {
 	// output when AccessLogFormat is json.
 	AccessLogFields AccessLogFields `yaml:"json-fields,omitempty"`
 
+	// AccessLogFilter limits which requests are written
+	// to the access logs.
+	AccessLogFilter AccessLogFilterParameters `yaml:"accesslog-filter,omitempty"`
+
+	// AccessLogHeaders lists request and response headers
+	// to add to the access logs.
+	AccessLogHeaders AccessLogHeaderParameters `yaml:"accesslog-headers,omitempty"`
+
+	// AccessLogGRPC optionally sends the HTTP access logs
+	// to a gRPC access log service as well as to the files.
+	AccessLogGRPC AccessLogGRPCParameters `yaml:"accesslog-grpc,omitempty"`
+
+	// HealthCheckPaths are the request paths of health checks,
+	// such as "/healthz". Requests for these paths are not written
+	// to the access logs, and bypass external authorization and
+	// global rate limiting.
+	HealthCheckPaths []string `yaml:"health-check-paths,omitempty"`
+
+	// HealthCheckVirtualHost configures health checks, such as
+	// those of load balancer target groups, that are answered
+	// by Envoy independently of any backend.
+	HealthCheckVirtualHost HealthCheckVirtualHostParameters `yaml:"health-check-virtual-host,omitempty"`
+
 	// TLS contains TLS policy parameters.
 	TLS TLSParameters `yaml:"tls,omitempty"`
 
@@ -377,6 +1460,36 @@ type Parameters struct {
 	// permitInsecure field in HTTPProxy.
 	DisablePermitInsecure bool `yaml:"disablePermitInsecure,omitempty"`
 
+	// EnableExternalBackends allows HTTPProxy services to proxy
+	// to backends outside of the cluster by address.
+	EnableExternalBackends bool `yaml:"enable-external-backends,omitempty"`
+
+	// EnableForwardProxy allows root HTTPProxies to configure
+	// virtual hosts as forward proxies, which forward requests
+	// to the host named in each request.
+	EnableForwardProxy bool `yaml:"enable-forward-proxy,omitempty"`
+
+	// EnableOriginalDestination allows HTTPProxy routes to
+	// forward requests to the address named in each request's
+	// x-envoy-original-dst-host header. The header is only
+	// accepted on connections from the network trusted-proxy-cidrs,
+	// which must be set, and removed from all other requests.
+	EnableOriginalDestination bool `yaml:"enable-original-destination,omitempty"`
+
+	// EnableNginxAnnotations translates a subset of the
+	// nginx-ingress annotations on Ingress objects to
+	// their Contour equivalents.
+	EnableNginxAnnotations bool `yaml:"enable-nginx-annotations,omitempty"`
+
+	// WatchDomainSuffixes restricts the virtual hosts that Contour
+	// programs to those whose names are equal to, or a subdomain
+	// of, one of the suffixes. This allows a large set of virtual
+	// hosts to be partitioned across several Contour and Envoy
+	// fleets. Contour also only writes the status of the HTTPProxies
+	// and Ingresses of its own virtual hosts. If empty, all virtual
+	// hosts are programmed.
+	WatchDomainSuffixes []string `yaml:"watch-domain-suffixes,omitempty"`
+
 	// LeaderElection contains leader election parameters.
 	LeaderElection LeaderElectionParameters `yaml:"leaderelection,omitempty"`
 
@@ -401,6 +1514,43 @@ type Parameters struct {
 	// Cluster holds various configurable Envoy cluster values that can
 	// be set in the config file.
 	Cluster ClusterParameters `yaml:"cluster,omitempty"`
+
+	// ErrorPages replaces the bodies of the 502, 503 and 504
+	// responses that Envoy generates itself, for example when
+	// no upstream endpoints are available.
+	ErrorPages []ErrorPageParameters `yaml:"error-pages,omitempty"`
+
+	// RateLimitService optionally holds properties of the Rate Limit Service
+	// to be used for global rate limiting.
+	RateLimitService RateLimitServiceParameters `yaml:"rate-limit-service,omitempty"`
+
+	// Network holds various configurable Envoy network values.
+	Network NetworkParameters `yaml:"network,omitempty"`
+
+	// ResponseCache configures the cache used by routes
+	// with a cache policy.
+	ResponseCache ResponseCacheParameters `yaml:"response-cache,omitempty"`
+
+	// Tracing configures trace context propagation.
+	Tracing TracingParameters `yaml:"tracing,omitempty"`
+
+	// NamespaceQuotas limits the virtual hosts and routes
+	// that the HTTPProxies and Ingresses of a namespace may define.
+	NamespaceQuotas NamespaceQuotasParameters `yaml:"namespace-quotas,omitempty"`
+
+	// NamespaceDefaults holds the policies applied to the routes
+	// of each listed namespace that do not set their own, keyed
+	// by namespace name.
+	NamespaceDefaults map[string]RouteDefaultsParameters `yaml:"namespace-defaults,omitempty"`
+
+	// Maintenance configures a static response that answers
+	// every request to some or all virtual hosts, without
+	// the Ingresses or HTTPProxies having to be deleted.
+	Maintenance MaintenanceParameters `yaml:"maintenance,omitempty"`
+
+	// Annotations configures the prefixes of the annotations
+	// that Contour reads from Kubernetes objects.
+	Annotations AnnotationParameters `yaml:"annotations,omitempty"`
 }
 
 // Validate verifies that the parameter values do not have any syntax errors.
@@ -409,7 +1559,87 @@ func (p *Parameters) Validate() error {
 		return err
 	}
 
-	if err := p.Server.XDSServerType.Validate(); err != nil {
+	for _, r := range p.Cluster.DNSResolvers {
+		if _, _, err := ParseDNSResolver(r); err != nil {
+			return err
+		}
+	}
+
+	if err := p.Cluster.RetryBudget.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := p.Cluster.ConnectTimeoutDuration(); err != nil {
+		return err
+	}
+
+	if _, err := p.Cluster.DNSSRVRefreshIntervalDuration(); err != nil {
+		return err
+	}
+
+	if p.Cluster.HealthyPanicThreshold > 100 {
+		return fmt.Errorf("invalid cluster healthy-panic-threshold %d: must be between 0 and 100", p.Cluster.HealthyPanicThreshold)
+	}
+
+	if err := p.Network.ServerHeader.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Maintenance.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Annotations.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Network.ListenerDrainType.Validate(); err != nil {
+		return err
+	}
+
+	listeners := map[string]bool{}
+	listenerPorts := map[int]bool{}
+	for _, l := range p.Network.HTTPListeners {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		if listeners[l.Name] {
+			return fmt.Errorf("invalid network http-listeners: duplicate name %q", l.Name)
+		}
+		if listenerPorts[l.Port] {
+			return fmt.Errorf("invalid network http-listeners: duplicate port %d", l.Port)
+		}
+		listeners[l.Name] = true
+		listenerPorts[l.Port] = true
+	}
+
+	for _, o := range p.Network.SocketOptions {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, cidr := range p.Network.TrustedProxyCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid network trusted-proxy-cidrs %q: %w", cidr, err)
+		}
+	}
+
+	// The original destination header is only accepted from trusted
+	// proxies, so without any the routes would have nowhere to go.
+	if p.EnableOriginalDestination && len(p.Network.TrustedProxyCIDRs) == 0 {
+		return errors.New("enable-original-destination requires network trusted-proxy-cidrs")
+	}
+
+	if p.Network.MaxRequestHeadersKB > 96 {
+		return fmt.Errorf("invalid network max-request-headers-kb %d: must be at most 96", p.Network.MaxRequestHeadersKB)
+	}
+
+	if p.Network.DisableHTTP10 && p.Network.HTTP10DefaultHost != "" {
+		return fmt.Errorf("invalid network http10-default-host: HTTP/1.0 is disabled")
+	}
+
+	if err := p.Server.Validate(); err != nil {
 		return err
 	}
 
@@ -421,6 +1651,28 @@ func (p *Parameters) Validate() error {
 		return err
 	}
 
+	for _, path := range p.HealthCheckPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid health check path %q: must start with \"/\"", path)
+		}
+	}
+
+	if err := p.HealthCheckVirtualHost.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.AccessLogFilter.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.AccessLogHeaders.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.AccessLogGRPC.Validate(); err != nil {
+		return err
+	}
+
 	// Check TLS secret names.
 	if err := p.TLS.FallbackCertificate.Validate(); err != nil {
 		return fmt.Errorf("invalid TLS fallback certificate: %w", err)
@@ -430,6 +1682,34 @@ func (p *Parameters) Validate() error {
 		return fmt.Errorf("invalid TLS client certificate: %w", err)
 	}
 
+	if err := p.TLS.SessionTicketKeys.Validate(); err != nil {
+		return fmt.Errorf("invalid TLS session ticket keys: %w", err)
+	}
+
+	if err := p.TLS.HSTS.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.TLS.SPIRE.Validate(); err != nil {
+		return err
+	}
+
+	if p.TLS.SPIRE.Identity != "" && p.TLS.ClientCertificate.Name != "" {
+		return fmt.Errorf("invalid TLS configuration: envoy-client-certificate and spire identity cannot both be set")
+	}
+
+	alpnProtocols := map[ALPNProtocolType]bool{}
+	for _, a := range p.TLS.ALPNProtocols {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+
+		if alpnProtocols[a] {
+			return fmt.Errorf("duplicate ALPN protocol %q", a)
+		}
+		alpnProtocols[a] = true
+	}
+
 	if err := p.Timeouts.Validate(); err != nil {
 		return err
 	}
@@ -440,6 +1720,42 @@ func (p *Parameters) Validate() error {
 		}
 	}
 
+	if err := p.RateLimitService.Validate(); err != nil {
+		return err
+	}
+
+	for _, suffix := range p.WatchDomainSuffixes {
+		if strings.Trim(strings.TrimSpace(suffix), ".") == "" {
+			return errors.New("invalid watch domain suffix: suffixes must not be empty")
+		}
+	}
+
+	if err := p.ResponseCache.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	for ns, d := range p.NamespaceDefaults {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("namespace defaults for %q: %w", ns, err)
+		}
+	}
+
+	statusCodes := map[int]bool{}
+	for _, e := range p.ErrorPages {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+
+		if statusCodes[e.StatusCode] {
+			return fmt.Errorf("duplicate error page for status code %d", e.StatusCode)
+		}
+		statusCodes[e.StatusCode] = true
+	}
+
 	return nil
 }
 
@@ -452,7 +1768,8 @@ func Defaults() Parameters {
 		InCluster:  false,
 		Kubeconfig: filepath.Join(os.Getenv("HOME"), ".kube", "config"),
 		Server: ServerParameters{
-			XDSServerType: ContourServerType,
+			XDSServerType:         ContourServerType,
+			EndpointsHoldoffDelay: 100 * time.Millisecond,
 		},
 		IngressStatusAddress:  "",
 		AccessLogFormat:       DEFAULT_ACCESS_LOG_TYPE,
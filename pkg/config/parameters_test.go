@@ -14,6 +14,7 @@
 package config
 
 import (
+	"net"
 	"os"
 	"strings"
 	"testing"
@@ -49,6 +50,7 @@ debug: false
 kubeconfig: TestParseDefaults/.kube/config
 server:
   xds-server-type: contour
+  endpoints-holdoff-delay: 100ms
 accesslog-format: envoy
 json-fields:
 - '@timestamp'
@@ -114,6 +116,27 @@ func TestValidateClusterDNSFamilyType(t *testing.T) {
 	assert.NoError(t, IPv6ClusterDNSFamily.Validate())
 }
 
+func TestParseDNSResolver(t *testing.T) {
+	ip, port, err := ParseDNSResolver("10.96.0.10")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.96.0.10", ip.String())
+	assert.Equal(t, uint32(53), port)
+
+	ip, port, err = ParseDNSResolver("[fd00::10]:5353")
+	assert.NoError(t, err)
+	assert.Equal(t, "fd00::10", ip.String())
+	assert.Equal(t, uint32(5353), port)
+
+	_, _, err = ParseDNSResolver("kube-dns.kube-system")
+	assert.Error(t, err)
+	_, _, err = ParseDNSResolver("kube-dns:53")
+	assert.Error(t, err)
+	_, _, err = ParseDNSResolver("10.96.0.10:0")
+	assert.Error(t, err)
+	_, _, err = ParseDNSResolver("10.96.0.10:dns")
+	assert.Error(t, err)
+}
+
 func TestValidateNamespacedName(t *testing.T) {
 	assert.NoErrorf(t, NamespacedName{}.Validate(), "empty name should be OK")
 	assert.NoError(t, NamespacedName{Name: "name", Namespace: "ns"}.Validate())
@@ -180,6 +203,47 @@ func TestValidateAccessLogFields(t *testing.T) {
 	}
 }
 
+func TestValidateAccessLogHeaders(t *testing.T) {
+	assert.NoError(t, AccessLogHeaderParameters{}.Validate())
+	assert.NoError(t, AccessLogHeaderParameters{
+		RequestHeaders:  []string{"X-Request-Id", "User-Agent"},
+		ResponseHeaders: []string{"X-Request-Id"},
+	}.Validate())
+
+	assert.Error(t, AccessLogHeaderParameters{RequestHeaders: []string{""}}.Validate())
+	assert.Error(t, AccessLogHeaderParameters{RequestHeaders: []string{"X-Foo)%"}}.Validate())
+	assert.Error(t, AccessLogHeaderParameters{ResponseHeaders: []string{"Server", "server"}}.Validate())
+}
+
+func TestValidateAccessLogGRPC(t *testing.T) {
+	assert.NoError(t, AccessLogGRPCParameters{}.Validate())
+	assert.NoError(t, AccessLogGRPCParameters{
+		ExtensionService: NamespacedName{Namespace: "projectcontour", Name: "als"},
+		LogName:          "edge",
+		Headers:          AccessLogHeaderParameters{RequestHeaders: []string{"X-Tenant"}},
+	}.Validate())
+
+	assert.Error(t, AccessLogGRPCParameters{ExtensionService: NamespacedName{Name: "als"}}.Validate())
+	assert.Error(t, AccessLogGRPCParameters{LogName: "edge"}.Validate())
+	assert.Error(t, AccessLogGRPCParameters{
+		ExtensionService: NamespacedName{Namespace: "projectcontour", Name: "als"},
+		Headers:          AccessLogHeaderParameters{ResponseHeaders: []string{"Server", "server"}},
+	}.Validate())
+}
+
+func TestAccessLogHeadersAsFields(t *testing.T) {
+	fields := AccessLogHeaderParameters{
+		RequestHeaders:  []string{"X-Request-Id"},
+		ResponseHeaders: []string{"Content-Type"},
+	}.AsFields()
+
+	assert.Equal(t, AccessLogFields{
+		"req.x-request-id=%REQ(X-Request-Id)%",
+		"resp.content-type=%RESP(Content-Type)%",
+	}, fields)
+	assert.NoError(t, fields.Validate())
+}
+
 func TestValidateHTTPVersionType(t *testing.T) {
 	assert.Error(t, HTTPVersionType("").Validate())
 	assert.Error(t, HTTPVersionType("foo").Validate())
@@ -190,6 +254,15 @@ func TestValidateHTTPVersionType(t *testing.T) {
 	assert.NoError(t, HTTPVersion2.Validate())
 }
 
+func TestValidateALPNProtocolType(t *testing.T) {
+	assert.Error(t, ALPNProtocolType("").Validate())
+	assert.Error(t, ALPNProtocolType("http/2").Validate())
+	assert.Error(t, ALPNProtocolType("spdy/3").Validate())
+
+	assert.NoError(t, H2ALPNProtocol.Validate())
+	assert.NoError(t, HTTP11ALPNProtocol.Validate())
+}
+
 func TestValidateTimeoutParams(t *testing.T) {
 	assert.NoError(t, TimeoutParameters{}.Validate())
 	assert.NoError(t, TimeoutParameters{
@@ -215,6 +288,98 @@ func TestValidateTimeoutParams(t *testing.T) {
 
 }
 
+func TestValidateErrorPageParams(t *testing.T) {
+	page := ErrorPageParameters{
+		StatusCode: 503,
+		ConfigMap:  NamespacedName{Name: "error-pages", Namespace: "projectcontour"},
+		Key:        "503.html",
+	}
+	assert.NoError(t, page.Validate())
+
+	bad := page
+	bad.StatusCode = 404
+	assert.Error(t, bad.Validate())
+
+	bad = page
+	bad.ConfigMap = NamespacedName{}
+	assert.Error(t, bad.Validate())
+
+	bad = page
+	bad.ConfigMap = NamespacedName{Name: "error-pages"}
+	assert.Error(t, bad.Validate())
+
+	bad = page
+	bad.Key = ""
+	assert.Error(t, bad.Validate())
+}
+
+func TestValidateHSTSParams(t *testing.T) {
+	assert.NoError(t, HSTSParameters{}.Validate())
+	assert.NoError(t, HSTSParameters{MaxAge: time.Hour}.Validate())
+	assert.NoError(t, HSTSParameters{MaxAge: time.Hour, IncludeSubdomains: true, Preload: true}.Validate())
+
+	assert.Error(t, HSTSParameters{MaxAge: -time.Hour}.Validate())
+	assert.Error(t, HSTSParameters{MaxAge: time.Hour, Preload: true}.Validate())
+	assert.Error(t, HSTSParameters{IncludeSubdomains: true, Preload: true}.Validate())
+}
+
+func TestValidateResponseCacheParams(t *testing.T) {
+	assert.NoError(t, ResponseCacheParameters{}.Validate())
+	assert.NoError(t, ResponseCacheParameters{
+		IncludeQueryParameters: []string{"page"},
+		ExcludeQueryParameters: []string{"utm_source"},
+	}.Validate())
+
+	assert.Error(t, ResponseCacheParameters{IncludeQueryParameters: []string{""}}.Validate())
+	assert.Error(t, ResponseCacheParameters{ExcludeQueryParameters: []string{" "}}.Validate())
+}
+
+func TestValidateTracingParams(t *testing.T) {
+	assert.NoError(t, TracingParameters{}.Validate())
+	assert.NoError(t, TracingParameters{
+		Propagation: []TracePropagationType{W3CTracePropagation, B3TracePropagation},
+		StartTraces: true,
+	}.Validate())
+
+	assert.Error(t, TracingParameters{Propagation: []TracePropagationType{"jaeger"}}.Validate())
+	assert.Error(t, TracingParameters{Propagation: []TracePropagationType{B3TracePropagation, B3TracePropagation}}.Validate())
+	assert.Error(t, TracingParameters{StartTraces: true}.Validate())
+}
+
+func TestValidateRetryBudgetParams(t *testing.T) {
+	assert.NoError(t, RetryBudgetParameters{}.Validate())
+	assert.NoError(t, RetryBudgetParameters{BudgetPercent: 20}.Validate())
+	assert.NoError(t, RetryBudgetParameters{BudgetPercent: 100, MinRetryConcurrency: 3}.Validate())
+
+	assert.Error(t, RetryBudgetParameters{BudgetPercent: 101}.Validate())
+	assert.Error(t, RetryBudgetParameters{MinRetryConcurrency: 3}.Validate())
+}
+
+func TestClusterConnectTimeoutDuration(t *testing.T) {
+	d, err := ClusterParameters{}.ConnectTimeoutDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	d, err = ClusterParameters{ConnectTimeout: "2s"}.ConnectTimeoutDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, d)
+
+	_, err = ClusterParameters{ConnectTimeout: "soon"}.ConnectTimeoutDuration()
+	assert.Error(t, err)
+	_, err = ClusterParameters{ConnectTimeout: "0s"}.ConnectTimeoutDuration()
+	assert.Error(t, err)
+}
+
+func TestValidateServerHeaderParams(t *testing.T) {
+	assert.NoError(t, ServerHeaderParameters{}.Validate())
+	assert.NoError(t, ServerHeaderParameters{ServerName: "example"}.Validate())
+	assert.NoError(t, ServerHeaderParameters{Transformation: AppendIfAbsentServerHeader, ServerName: "example"}.Validate())
+	assert.NoError(t, ServerHeaderParameters{Transformation: PassThroughServerHeader}.Validate())
+
+	assert.Error(t, ServerHeaderParameters{Transformation: "remove"}.Validate())
+	assert.Error(t, ServerHeaderParameters{Transformation: PassThroughServerHeader, ServerName: "example"}.Validate())
+}
+
 func TestConfigFileValidation(t *testing.T) {
 	check := func(yamlIn string) {
 		t.Helper()
@@ -229,11 +394,226 @@ cluster:
   dns-lookup-family: stone
 `)
 
+	check(`
+cluster:
+  dns-resolvers:
+  - kube-dns.kube-system
+`)
+
+	check(`
+health-check-paths:
+- healthz
+`)
+
+	check(`
+health-check-virtual-host:
+  path: healthz
+`)
+
+	check(`
+cluster:
+  retry-budget:
+    budget-percent: 120
+`)
+
+	check(`
+cluster:
+  connect-timeout: infinity
+`)
+
+	check(`
+cluster:
+  healthy-panic-threshold: 101
+`)
+
+	check(`
+cluster:
+  dns-srv-refresh-interval: 0s
+`)
+
+	check(`
+accesslog-grpc:
+  log-name: edge
+`)
+
+	check(`
+annotations:
+  prefix: example.com/
+`)
+
+	check(`
+annotations:
+  legacy-prefixes:
+  - Contour.Heptio.com
+`)
+
+	check(`
+network:
+  socket-options:
+  - level: 1
+    name: 15
+    int-value: 1
+    state: connected
+`)
+
+	check(`
+network:
+  socket-options:
+  - level: 1
+    name: 15
+    int-value: 1
+    listeners:
+    - stats
+`)
+
+	check(`
+network:
+  max-request-headers-kb: 97
+`)
+
+	check(`
+network:
+  listener-drain-type: never
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: ingress_internal
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: ingress_https
+    port: 8443
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: ingress_internal
+    address: internal
+    port: 8081
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: ingress_internal
+    port: 8081
+  - name: ingress_internal
+    port: 8082
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: ingress_internal
+    port: 8081
+  - name: ingress_admin
+    port: 8081
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: Ingress_Internal
+    port: 8081
+`)
+
+	check(`
+network:
+  http-listeners:
+  - name: https/ingress.example.com
+    port: 8081
+`)
+
+	check(`
+network:
+  trusted-proxy-cidrs:
+  - 10.0.0.0/8
+  - 192.168.0.1
+`)
+
+	check(`
+enable-original-destination: true
+`)
+
+	check(`
+namespace-defaults:
+  team-a:
+    timeout-policy:
+      response: forever
+`)
+
+	check(`
+namespace-defaults:
+  team-a:
+    retry-policy:
+      retry-on: 5xx
+      per-try-timeout: infinity
+`)
+
+	check(`
+tls:
+  alpn-protocols:
+  - h2
+  - spdy/3
+`)
+
+	check(`
+tls:
+  alpn-protocols:
+  - http/1.1
+  - http/1.1
+`)
+
+	check(`
+network:
+  server-header:
+    transformation: drop
+`)
+
+	check(`
+server:
+  xds-server-type: envoy
+  scoped-routes: true
+`)
+
+	check(`
+server:
+  scoped-routes: true
+  endpoints-only: true
+`)
+
+	check(`
+maintenance:
+  enabled: true
+  status-code: 100
+`)
+
+	check(`
+network:
+  disable-http10: true
+  http10-default-host: example.com
+`)
+
 	check(`
 server:
   xds-server-type: magic
 `)
 
+	check(`
+server:
+  push-interval: -1s
+`)
+
+	check(`
+server:
+  endpoints-holdoff-delay: -1s
+`)
+
 	check(`
 accesslog-format: /dev/null
 `)
@@ -243,6 +623,11 @@ json-fields:
 - one
 `)
 
+	check(`
+accesslog-filter:
+  success-sample-percent: 101
+`)
+
 	check(`
 tls:
   fallback-certificate:
@@ -255,6 +640,21 @@ tls:
     name: foo
 `)
 
+	check(`
+tls:
+  spire:
+    identity: example.org/envoy
+`)
+
+	check(`
+tls:
+  envoy-client-certificate:
+    name: foo
+    namespace: projectcontour
+  spire:
+    identity: spiffe://example.org/envoy
+`)
+
 	check(`
 timeouts:
   request-timeout: none
@@ -265,6 +665,57 @@ default-http-versions:
 - http/0.9
 `)
 
+	check(`
+rate-limit-service:
+  extension-service:
+    name: ratelimit
+`)
+
+	check(`
+rate-limit-service:
+  extension-service:
+    name: ratelimit
+    namespace: projectcontour
+  retry-after: 0s
+`)
+
+	check(`
+rate-limit-service:
+  extension-service:
+    name: ratelimit
+    namespace: projectcontour
+  retry-after: soon
+`)
+
+	check(`
+watch-domain-suffixes:
+- example.com
+- "."
+`)
+
+	check(`
+error-pages:
+- status-code: 500
+  configmap:
+    name: error-pages
+    namespace: projectcontour
+  key: 500.html
+`)
+
+	check(`
+error-pages:
+- status-code: 503
+  configmap:
+    name: error-pages
+    namespace: projectcontour
+  key: 503.html
+- status-code: 503
+  configmap:
+    name: error-pages
+    namespace: projectcontour
+  key: other.html
+`)
+
 }
 
 func TestConfigFileDefaultOverrideImport(t *testing.T) {
@@ -304,6 +755,20 @@ leaderelection:
 tls:
 `)
 
+	check(func(t *testing.T, conf *Parameters) {
+		assert.True(t, conf.Server.WaitForFirstSnapshot)
+	}, `
+server:
+  wait-for-first-snapshot: true
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, time.Duration(0), conf.Server.EndpointsHoldoffDelay)
+	}, `
+server:
+  endpoints-holdoff-delay: 0s
+`)
+
 	check(func(t *testing.T, conf *Parameters) {
 		assert.Equal(t, "1.2", conf.TLS.MinimumProtocolVersion)
 	}, `
@@ -348,5 +813,252 @@ default-http-versions:
 - http/2
 - HTTP/2
 - HTTP/1.1
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, RateLimitServiceParameters{
+			ExtensionService: NamespacedName{Name: "ratelimit", Namespace: "projectcontour"},
+			Domain:           "contour",
+			FailOpen:         true,
+		}, conf.RateLimitService)
+	}, `
+rate-limit-service:
+  extension-service:
+    name: ratelimit
+    namespace: projectcontour
+  domain: contour
+  fail-open: true
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.True(t, conf.RateLimitService.EnableXRateLimitHeaders)
+
+		d, err := conf.RateLimitService.RetryAfterDuration()
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, d)
+	}, `
+rate-limit-service:
+  extension-service:
+    name: ratelimit
+    namespace: projectcontour
+  enable-x-ratelimit-headers: true
+  retry-after: 30s
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, AccessLogFilterParameters{
+			ErrorsOnly:           true,
+			SuccessSamplePercent: 5,
+		}, conf.AccessLogFilter)
+	}, `
+accesslog-filter:
+  errors-only: true
+  success-sample-percent: 5
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, HSTSParameters{
+			MaxAge:            8760 * time.Hour,
+			IncludeSubdomains: true,
+			Preload:           true,
+		}, conf.TLS.HSTS)
+	}, `
+tls:
+  hsts:
+    max-age: 8760h
+    include-subdomains: true
+    preload: true
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, "spiffe://example.org/ns/projectcontour/sa/envoy", conf.TLS.SPIRE.Identity)
+	}, `
+tls:
+  spire:
+    identity: spiffe://example.org/ns/projectcontour/sa/envoy
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.True(t, conf.EnableExternalBackends)
+	}, `
+enable-external-backends: true
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, uint32(1), conf.Network.XffNumTrustedHops)
+	}, `
+network:
+  num-trusted-hops: 1
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, OverwriteServerHeader, conf.Network.ServerHeader.Transformation)
+		assert.Equal(t, "example", conf.Network.ServerHeader.ServerName)
+	}, `
+network:
+  server-header:
+    transformation: overwrite
+    server-name: example
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, uint32(10), conf.NamespaceQuotas.Default.MaxVirtualHosts)
+		assert.Equal(t, uint32(500), conf.NamespaceQuotas.Namespaces["platform"].MaxRoutes)
+	}, `
+namespace-quotas:
+  default:
+    max-virtual-hosts: 10
+    max-routes: 100
+  namespaces:
+    platform:
+      max-routes: 500
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		d := conf.NamespaceDefaults["team-a"]
+		assert.Equal(t, "10s", d.TimeoutPolicy.Response)
+		assert.Equal(t, "5xx,gateway-error", d.RetryPolicy.RetryOn)
+		assert.Equal(t, uint32(3), d.RetryPolicy.NumRetries)
+		assert.Equal(t, map[string]string{"X-Team": "a"}, d.RequestHeaders.Set)
+		assert.Equal(t, []string{"Server"}, d.ResponseHeaders.Remove)
+	}, `
+namespace-defaults:
+  team-a:
+    timeout-policy:
+      response: 10s
+    retry-policy:
+      retry-on: 5xx,gateway-error
+      num-retries: 3
+    request-headers:
+      set:
+        X-Team: a
+    response-headers:
+      remove:
+      - Server
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, "example.com", conf.Network.HTTP10DefaultHost)
+	}, `
+network:
+  http10-default-host: example.com
+`)
+
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, "example.com", conf.Annotations.Prefix)
+		assert.Equal(t, []string{"projectcontour.io", "contour.heptio.com"}, conf.Annotations.LegacyPrefixes)
+	}, `
+annotations:
+  prefix: example.com
+  legacy-prefixes:
+  - projectcontour.io
+  - contour.heptio.com
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, []SocketOptionParameters{{
+			Description: "IP_TRANSPARENT",
+			Level:       0,
+			Name:        19,
+			IntValue:    1,
+			State:       BoundSocketOptionState,
+			Listeners:   []string{"ingress_https"},
+		}}, conf.Network.SocketOptions)
+	}, `
+network:
+  socket-options:
+  - description: IP_TRANSPARENT
+    level: 0
+    name: 19
+    int-value: 1
+    state: bound
+    listeners:
+    - ingress_https
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, uint32(96), conf.Network.MaxRequestHeadersKB)
+		assert.Equal(t, uint32(200), conf.Network.MaxRequestHeadersCount)
+	}, `
+network:
+  max-request-headers-kb: 96
+  max-request-headers-count: 200
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, ModifyOnlyListenerDrainType, conf.Network.ListenerDrainType)
+	}, `
+network:
+  listener-drain-type: modify-only
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, []HTTPListenerParameters{
+			{Name: "ingress_internal", Port: 8081},
+			{Name: "ingress_admin", Address: "127.0.0.1", Port: 8082},
+		}, conf.Network.HTTPListeners)
+	}, `
+network:
+  http-listeners:
+  - name: ingress_internal
+    port: 8081
+  - name: ingress_admin
+    address: 127.0.0.1
+    port: 8082
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, []string{"10.0.0.0/8", "fd00::/8"}, conf.Network.TrustedProxyCIDRs)
+		assert.Equal(t, []*net.IPNet{
+			{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+			{IP: net.ParseIP("fd00::"), Mask: net.CIDRMask(8, 128)},
+		}, conf.Network.TrustedProxyNetworks())
+	}, `
+network:
+  num-trusted-hops: 1
+  trusted-proxy-cidrs:
+  - 10.0.0.0/8
+  - fd00::/8
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.True(t, conf.EnableOriginalDestination)
+	}, `
+enable-original-destination: true
+network:
+  trusted-proxy-cidrs:
+  - 10.0.0.0/8
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, "/healthz", conf.HealthCheckVirtualHost.Path)
+	}, `
+health-check-virtual-host:
+  path: /healthz
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, uint32(50), conf.Cluster.HealthyPanicThreshold)
+	}, `
+cluster:
+  healthy-panic-threshold: 50
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		d, err := conf.Cluster.DNSSRVRefreshIntervalDuration()
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, d)
+	}, `
+cluster:
+  dns-srv-refresh-interval: 5m
+`)
+	check(func(t *testing.T, conf *Parameters) {
+		assert.Equal(t, AccessLogGRPCParameters{
+			ExtensionService: NamespacedName{Namespace: "projectcontour", Name: "als"},
+			LogName:          "edge",
+			Headers: AccessLogHeaderParameters{
+				RequestHeaders: []string{"X-Tenant"},
+			},
+		}, conf.AccessLogGRPC)
+	}, `
+accesslog-grpc:
+  extension-service:
+    namespace: projectcontour
+    name: als
+  log-name: edge
+  headers:
+    request:
+    - X-Tenant
 `)
 }